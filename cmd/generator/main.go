@@ -21,7 +21,21 @@ func main() {
 	}()
 
 	ruleRepo := repository.NewRuleRepository(db, logger)
-	pacSrvc := service.NewPACService(ruleRepo, "./data/proxy.pac", logger)
+	profileRepo := repository.NewProxyProfileRepository(db, logger)
+	bypassRepo := repository.NewBypassRepository(db, logger)
+	// Health of the profiles is known only to the running server, so all of them are considered up. Versions
+	// of the configuration are recorded by the server as well, so the current configuration is rendered even if it
+	// is a draft yet to be published.
+	pacSrvc := service.NewPACService(
+		ruleRepo,
+		profileRepo,
+		bypassRepo,
+		nil,
+		nil,
+		"./data/proxy.pac",
+		service.VersionOptions{},
+		logger,
+	)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/nnemirovsky/pacgen/internal/repository"
+	"github.com/nnemirovsky/pacgen/internal/service"
+	"github.com/nnemirovsky/pacgen/pkg/logutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Lints enabled rules and prints the problems found, one per line. Exits with code 1 if there are any.
+func main() {
+	logger := logutil.Logger
+
+	db := sqlx.MustConnect("sqlite3", "./data/data.db")
+	defer func() {
+		if err := db.Close(); err != nil {
+			logger.Fatal().Err(err).Send()
+		}
+	}()
+
+	ruleRepo := repository.NewRuleRepository(db, logger)
+	profileRepo := repository.NewProxyProfileRepository(db, logger)
+	listRepo := repository.NewDomainListRepository(db, logger)
+	lintSrvc := service.NewLintService(ruleRepo, profileRepo, listRepo, logger)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	findings, err := lintSrvc.Lint(ctx)
+	if err != nil {
+		logger.Fatal().Err(err).Send()
+	}
+
+	for _, finding := range findings {
+		related := make([]string, 0, len(finding.RelatedIDs))
+		for _, id := range finding.RelatedIDs {
+			related = append(related, strconv.Itoa(id))
+		}
+		fmt.Printf("rule %d: %s: %s (fix: %s)", finding.RuleID, finding.Kind, finding.Message, finding.Fix)
+		if len(related) > 0 {
+			fmt.Printf(" [related: %s]", strings.Join(related, ", "))
+		}
+		fmt.Println()
+	}
+
+	if len(findings) > 0 {
+		// Deferred close is skipped by os.Exit, the process is short-lived anyway.
+		os.Exit(1)
+	}
+
+	logger.Info().Msg("No problems found")
+}
@@ -4,7 +4,11 @@ import (
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/sqlite3"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jmoiron/sqlx"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/nnemirovsky/pacgen/pkg/domain"
 	"github.com/nnemirovsky/pacgen/pkg/logutil"
+	"github.com/nnemirovsky/pacgen/pkg/regexp"
 	"os"
 	"strings"
 )
@@ -54,6 +58,47 @@ func up(m *migrate.Migrate) {
 	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
 		logger.Fatal().Err(err).Msg("Error occurred while migrating all the way up")
 	}
+
+	db, err := sqlx.Connect("sqlite3", "./data/data.db")
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Error occurred while opening database to normalize data")
+	}
+	defer db.Close()
+
+	normalizeAddresses(db)
+	normalizeDomains(db)
+}
+
+// normalizeAddresses moves addresses of proxy profiles created before they were validated into host and port.
+// Addresses that cannot be parsed are reported and kept as they are, such profiles are left out of PAC file
+// until they are updated through the API.
+func normalizeAddresses(db *sqlx.DB) {
+	var profiles []struct {
+		ID      int    `db:"id"`
+		Name    string `db:"name"`
+		Address string `db:"address"`
+	}
+	query := `SELECT id, name, address
+			  FROM proxy_profiles
+			  WHERE host IS NULL AND coalesce(address, '') <> '' AND type NOT IN (?, ?)`
+	if err := db.Select(&profiles, query, model.Direct, model.Pool); err != nil {
+		logger.Fatal().Err(err).Msg("Error occurred while getting proxy profiles to normalize addresses")
+	}
+
+	for _, profile := range profiles {
+		host, port, err := model.ParseAddress(profile.Address)
+		if err != nil {
+			logger.Warn().Err(err).Int("profile-id", profile.ID).Str("name", profile.Name).
+				Str("address", profile.Address).Msg("Address of proxy profile cannot be normalized, update the profile")
+			continue
+		}
+		cmd := `UPDATE proxy_profiles SET host = ?, port = ?, address = NULL WHERE id = ?`
+		if _, err := db.Exec(cmd, host, port, profile.ID); err != nil {
+			logger.Fatal().Err(err).Int("profile-id", profile.ID).Msg("Error occurred while normalizing address")
+		}
+		logger.Info().Int("profile-id", profile.ID).Str("address", profile.Address).
+			Str("normalized", model.FormatAddress(host, port)).Msg("Address of proxy profile normalized")
+	}
 }
 
 func down(m *migrate.Migrate) {
@@ -61,3 +106,67 @@ func down(m *migrate.Migrate) {
 		logger.Fatal().Err(err).Msg("Error occurred while migrating all the way down")
 	}
 }
+
+// normalizeDomains converts domains of rules and domain lists created before they were normalized into punycode.
+// Domains that cannot be normalized are reported and kept as they are.
+func normalizeDomains(db *sqlx.DB) {
+	var rules []struct {
+		ID    int    `db:"id"`
+		Regex string `db:"regex"`
+	}
+	if err := db.Select(&rules, `SELECT id, regex FROM rules WHERE regex IS NOT NULL`); err != nil {
+		logger.Fatal().Err(err).Msg("Error occurred while getting rules to normalize domains")
+	}
+
+	for _, rule := range rules {
+		d, withSubdomains, ok := regexp.ParseDomain(rule.Regex)
+		if !ok {
+			continue
+		}
+		ascii, err := domain.Normalize(d)
+		if err != nil {
+			logger.Warn().Err(err).Int("rule-id", rule.ID).Str("domain", d).
+				Msg("Domain of rule cannot be normalized, update the rule")
+			continue
+		}
+		if ascii == d {
+			continue
+		}
+		regex := regexp.Domain(ascii)
+		if withSubdomains {
+			regex = regexp.DomainAndSubdomains(ascii)
+		}
+		if _, err := db.Exec(`UPDATE rules SET regex = ? WHERE id = ?`, regex, rule.ID); err != nil {
+			logger.Fatal().Err(err).Int("rule-id", rule.ID).Msg("Error occurred while normalizing domain of rule")
+		}
+		logger.Info().Int("rule-id", rule.ID).Str("domain", d).Str("normalized", ascii).
+			Msg("Domain of rule normalized")
+	}
+
+	var entries []struct {
+		ListID int    `db:"list_id"`
+		Domain string `db:"domain"`
+	}
+	if err := db.Select(&entries, `SELECT list_id, domain FROM domain_list_entries`); err != nil {
+		logger.Fatal().Err(err).Msg("Error occurred while getting domain list entries to normalize")
+	}
+
+	for _, entry := range entries {
+		ascii, err := domain.Normalize(entry.Domain)
+		if err == nil && ascii == entry.Domain {
+			continue
+		}
+		if err == nil {
+			cmd := `UPDATE domain_list_entries SET domain = ? WHERE list_id = ? AND domain = ?`
+			_, err = db.Exec(cmd, ascii, entry.ListID, entry.Domain)
+		}
+		// Update fails if the normalized domain is already in the list.
+		if err != nil {
+			logger.Warn().Err(err).Int("list-id", entry.ListID).Str("domain", entry.Domain).
+				Msg("Domain list entry cannot be normalized, update the list")
+			continue
+		}
+		logger.Info().Int("list-id", entry.ListID).Str("domain", entry.Domain).Str("normalized", ascii).
+			Msg("Domain list entry normalized")
+	}
+}
@@ -7,10 +7,13 @@ import (
 	"github.com/jmoiron/sqlx"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/nnemirovsky/pacgen/internal/handler"
+	"github.com/nnemirovsky/pacgen/internal/model"
 	"github.com/nnemirovsky/pacgen/internal/repository"
 	"github.com/nnemirovsky/pacgen/internal/router"
 	"github.com/nnemirovsky/pacgen/internal/service"
 	"github.com/nnemirovsky/pacgen/pkg/logutil"
+	"github.com/nnemirovsky/pacgen/pkg/provision"
+	"github.com/nnemirovsky/pacgen/pkg/rest"
 	"github.com/rs/zerolog"
 	"net/http"
 	"os"
@@ -27,21 +30,80 @@ var (
 	server         *http.Server
 	ruleRepo       *repository.RuleRepository
 	profileRepo    *repository.ProxyProfileRepository
+	listRepo       *repository.DomainListRepository
+	bypassRepo     *repository.BypassRepository
+	idemRepo       *repository.IdempotencyRepository
+	auditRepo      *repository.AuditRepository
+	versionRepo    *repository.VersionRepository
+	userRepo       *repository.UserRepository
+	tokenRepo      *repository.TokenRepository
 	ruleService    *service.RuleService
 	profileService *service.ProxyProfileService
+	listService    *service.DomainListService
+	bypassService  *service.BypassService
 	pacService     *service.PACService
+	prober         *service.Prober
+	scheduler      *service.RuleScheduler
+	exportService  *service.ExportService
+	lintService    *service.LintService
+	auditService   *service.AuditService
+	auditPruner    *service.AuditPruner
+	versionService *service.VersionService
+	draftService   *service.DraftService
+	userService    *service.UserService
+	tokenService   *service.TokenService
 	ruleHandler    *handler.RuleHandler
 	profileHandler *handler.ProxyProfileHandler
+	healthHandler  *handler.ProfileHealthHandler
+	listHandler    *handler.DomainListHandler
+	bypassHandler  *handler.BypassHandler
+	auditHandler   *handler.AuditHandler
+	versionHandler *handler.VersionHandler
+	draftHandler   *handler.DraftHandler
+	userHandler    *handler.UserHandler
+	tokenHandler   *handler.TokenHandler
 	pacFileHandler *handler.PACFileHandler
+	exportHandler  *handler.ExportHandler
+	provHandler    *handler.ProvisioningHandler
 	mux            http.Handler
 	pacFilePath    = "./data/proxy.pac"
+	stopProber     = func() {}
+	stopScheduler  = func() {}
+	stopPruner     = func() {}
 )
 
 type options struct {
 	LogLevel string `short:"l" long:"loglevel" env:"APP_LOG_LEVEL" choice:"trace" choice:"debug" choice:"info" choice:"warn" choice:"error" description:"Log level" default:"info"`
 	Port     int    `short:"p" long:"port" env:"APP_PORT" description:"Http port to listen on" default:"8080"`
-	User     string `short:"U" long:"user" env:"APP_USER" description:"User for http basic auth" default:"admin"`
-	Password string `short:"P" long:"password" env:"APP_PASSWORD" description:"Password for http basic auth" default:"admin"`
+	User     string `short:"U" long:"user" env:"APP_USER" description:"Name of the admin created on startup if there are no users" default:"admin"`
+	Password string `short:"P" long:"password" env:"APP_PASSWORD" description:"Password of the admin created on startup if there are no users" default:"admin"`
+	SignCert string `long:"sign-cert" env:"APP_SIGN_CERT" description:"PEM certificate for signing Apple configuration profiles"`
+	SignKey  string `long:"sign-key" env:"APP_SIGN_KEY" description:"PEM private key for signing Apple configuration profiles"`
+	IfMatch  string `long:"if-match" env:"APP_IF_MATCH" choice:"required" choice:"optional" description:"Whether changes of rules and profiles require If-Match header with the ETag of the entity" default:"required"`
+	Health   struct {
+		Interval      time.Duration `long:"interval" env:"INTERVAL" description:"Interval between proxy health checks, 0 disables them" default:"30s"`
+		Timeout       time.Duration `long:"timeout" env:"TIMEOUT" description:"Timeout of a single proxy health check" default:"5s"`
+		Handshake     bool          `long:"handshake" env:"HANDSHAKE" description:"Check HTTP and SOCKS5 proxies with a protocol handshake, not only with TCP connect"`
+		ConnectTarget string        `long:"connect-target" env:"CONNECT_TARGET" description:"Host and port requested by HTTP CONNECT handshake" default:"example.com:443"`
+	} `group:"Health check options" namespace:"health" env-namespace:"APP_HEALTH"`
+	Expiry struct {
+		Policy   string        `long:"policy" env:"POLICY" choice:"keep" choice:"archive" choice:"delete" description:"What happens to expired rules: kept as they are, disabled and tagged as expired, or deleted" default:"keep"`
+		Interval time.Duration `long:"interval" env:"INTERVAL" description:"Longest interval between checks for rule activations and expirations" default:"1m"`
+	} `group:"Rule expiry options" namespace:"expiry" env-namespace:"APP_EXPIRY"`
+	Idempotency struct {
+		TTL time.Duration `long:"ttl" env:"TTL" description:"How long responses to requests with Idempotency-Key header are replayed for their repeats, 0 disables the header" default:"24h"`
+	} `group:"Idempotency options" namespace:"idempotency" env-namespace:"APP_IDEMPOTENCY"`
+	Audit struct {
+		Retention     time.Duration `long:"retention" env:"RETENTION" description:"How long audit log entries are kept for, 0 keeps them forever" default:"2160h"`
+		PruneInterval time.Duration `long:"prune-interval" env:"PRUNE_INTERVAL" description:"Interval between deletions of audit log entries past the retention" default:"1h"`
+	} `group:"Audit log options" namespace:"audit" env-namespace:"APP_AUDIT"`
+	Versions struct {
+		Keep int `long:"keep" env:"KEEP" description:"Number of configuration versions kept, 0 disables recording them" default:"50"`
+	} `group:"Configuration version options" namespace:"versions" env-namespace:"APP_VERSIONS"`
+	Publish struct {
+		Mode     string `long:"mode" env:"MODE" choice:"auto" choice:"manual" description:"Whether changes are published right away or make up a draft published on request, which requires configuration versions" default:"auto"`
+		Approval bool   `long:"approval" env:"APPROVAL" description:"Require the draft to be approved by another user before it is published"`
+	} `group:"Publish options" namespace:"publish" env-namespace:"APP_PUBLISH"`
 }
 
 func main() {
@@ -50,9 +112,13 @@ func main() {
 	initDB()
 	initRepositories()
 	initServices()
+	bootstrapAdmin()
 	initHandlers()
 	initRouter()
 	initServer()
+	runProber()
+	runScheduler()
+	runPruner()
 
 	logger.Info().Str("addr", server.Addr).Msg("Application started")
 
@@ -62,29 +128,162 @@ func main() {
 
 	logger.Info().Msg("Application is shutting down...")
 
+	stopProber()
+	stopScheduler()
+	stopPruner()
 	shutdownServer()
 	shutdownDB()
 }
 
 func initRouter() {
-	mux = router.New(ruleHandler, profileHandler, pacFileHandler, logger, map[string]string{opts.User: opts.Password})
+	var idempotency func(next http.Handler) http.Handler
+	if opts.Idempotency.TTL > 0 {
+		// Requests are authenticated ahead of idempotency, so the keys are scoped to the actor.
+		idempotency = rest.Idempotency(idemRepo, opts.Idempotency.TTL, func(r *http.Request) string {
+			return model.ActorFromContext(r.Context()).Principal()
+		})
+	}
+	// Draft routes exist only in manual publish mode.
+	var drafts router.DraftHandler
+	if draftHandler != nil {
+		drafts = draftHandler
+	}
+	mux = router.New(
+		ruleHandler,
+		profileHandler,
+		healthHandler,
+		listHandler,
+		bypassHandler,
+		auditHandler,
+		versionHandler,
+		drafts,
+		userHandler,
+		tokenHandler,
+		pacFileHandler,
+		exportHandler,
+		provHandler,
+		idempotency,
+		logger,
+		userService,
+		tokenService,
+	)
 }
 
 func initHandlers() {
-	ruleHandler = handler.NewRuleHandler(ruleService, logutil.WithLayer[handler.RuleHandler](logger))
-	profileHandler = handler.NewProxyProfileHandler(profileService, logutil.WithLayer[handler.ProxyProfileHandler](logger))
-	pacFileHandler = handler.NewPACFileHandler(pacFilePath, logutil.WithLayer[handler.PACFileHandler](logger))
+	requireIfMatch := opts.IfMatch == "required"
+	ruleHandler = handler.NewRuleHandler(
+		ruleService,
+		profileService,
+		lintService,
+		pacService,
+		requireIfMatch,
+		logutil.WithLayer[handler.RuleHandler](logger),
+	)
+	profileHandler = handler.NewProxyProfileHandler(
+		profileService,
+		requireIfMatch,
+		logutil.WithLayer[handler.ProxyProfileHandler](logger),
+	)
+	healthHandler = handler.NewProfileHealthHandler(prober, logutil.WithLayer[handler.ProfileHealthHandler](logger))
+	listHandler = handler.NewDomainListHandler(listService, logutil.WithLayer[handler.DomainListHandler](logger))
+	bypassHandler = handler.NewBypassHandler(bypassService, logutil.WithLayer[handler.BypassHandler](logger))
+	auditHandler = handler.NewAuditHandler(auditService, logutil.WithLayer[handler.AuditHandler](logger))
+	versionHandler = handler.NewVersionHandler(versionService, logutil.WithLayer[handler.VersionHandler](logger))
+	if draftService != nil {
+		draftHandler = handler.NewDraftHandler(draftService, logutil.WithLayer[handler.DraftHandler](logger))
+	}
+	userHandler = handler.NewUserHandler(userService, logutil.WithLayer[handler.UserHandler](logger))
+	tokenHandler = handler.NewTokenHandler(tokenService, logutil.WithLayer[handler.TokenHandler](logger))
+	pacFileHandler = handler.NewPACFileHandler(
+		versionService,
+		pacFilePath,
+		logutil.WithLayer[handler.PACFileHandler](logger),
+	)
+	exportHandler = handler.NewExportHandler(exportService, logutil.WithLayer[handler.ExportHandler](logger))
+
+	var signer *provision.Signer
+	if opts.SignCert != "" || opts.SignKey != "" {
+		var err error
+		if signer, err = provision.LoadSigner(opts.SignCert, opts.SignKey); err != nil {
+			logger.Fatal().Err(err).Msg("Failed to load configuration profile signer")
+		}
+	}
+	provHandler = handler.NewProvisioningHandler(signer, logutil.WithLayer[handler.ProvisioningHandler](logger))
 }
 
 func initServices() {
-	pacService = service.NewPACService(ruleRepo, pacFilePath, logutil.WithLayer[service.PACService](logger))
+	prober = service.NewProber(
+		profileRepo,
+		service.ProbeOptions{
+			Interval:      opts.Health.Interval,
+			Timeout:       opts.Health.Timeout,
+			Handshake:     opts.Health.Handshake,
+			ConnectTarget: opts.Health.ConnectTarget,
+		},
+		logutil.WithLayer[service.Prober](logger),
+	)
+	policy, err := model.ParseExpiryPolicy(opts.Expiry.Policy)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Failed to parse expiry policy")
+	}
+	scheduler = service.NewRuleScheduler(
+		ruleRepo,
+		service.ScheduleOptions{Interval: opts.Expiry.Interval, Policy: policy},
+		logutil.WithLayer[service.RuleScheduler](logger),
+	)
+	var versions service.VersionRepository
+	if opts.Versions.Keep > 0 {
+		versions = versionRepo
+	}
+	drafts := opts.Publish.Mode == "manual"
+	if drafts && versions == nil {
+		logger.Fatal().Msg("Manual publish mode requires configuration versions, which are disabled")
+	}
+	pacService = service.NewPACService(
+		ruleRepo,
+		profileRepo,
+		bypassRepo,
+		versions,
+		prober,
+		pacFilePath,
+		service.VersionOptions{Keep: opts.Versions.Keep, Drafts: drafts},
+		logutil.WithLayer[service.PACService](logger),
+	)
 	ruleService = service.NewRuleService(ruleRepo, pacService, logutil.WithLayer[service.RuleService](logger))
 	profileService = service.NewProxyProfileService(profileRepo, pacService, logutil.WithLayer[service.ProxyProfileService](logger))
+	listService = service.NewDomainListService(listRepo, pacService, logutil.WithLayer[service.DomainListService](logger))
+	bypassService = service.NewBypassService(bypassRepo, pacService, logutil.WithLayer[service.BypassService](logger))
+	exportService = service.NewExportService(pacService, logutil.WithLayer[service.ExportService](logger))
+	lintService = service.NewLintService(ruleRepo, profileRepo, listRepo, logutil.WithLayer[service.LintService](logger))
+	versionService = service.NewVersionService(versionRepo, pacService, logutil.WithLayer[service.VersionService](logger))
+	if drafts {
+		draftService = service.NewDraftService(
+			versionRepo,
+			pacService,
+			opts.Publish.Approval,
+			logutil.WithLayer[service.DraftService](logger),
+		)
+	}
+	userService = service.NewUserService(userRepo, logutil.WithLayer[service.UserService](logger))
+	tokenService = service.NewTokenService(tokenRepo, logutil.WithLayer[service.TokenService](logger))
+	auditService = service.NewAuditService(auditRepo, logutil.WithLayer[service.AuditService](logger))
+	auditPruner = service.NewAuditPruner(
+		auditRepo,
+		service.AuditPruneOptions{Retention: opts.Audit.Retention, Interval: opts.Audit.PruneInterval},
+		logutil.WithLayer[service.AuditPruner](logger),
+	)
 }
 
 func initRepositories() {
 	ruleRepo = repository.NewRuleRepository(db, logutil.WithLayer[repository.RuleRepository](logger))
 	profileRepo = repository.NewProxyProfileRepository(db, logutil.WithLayer[repository.ProxyProfileRepository](logger))
+	listRepo = repository.NewDomainListRepository(db, logutil.WithLayer[repository.DomainListRepository](logger))
+	bypassRepo = repository.NewBypassRepository(db, logutil.WithLayer[repository.BypassRepository](logger))
+	idemRepo = repository.NewIdempotencyRepository(db, logutil.WithLayer[repository.IdempotencyRepository](logger))
+	auditRepo = repository.NewAuditRepository(db, logutil.WithLayer[repository.AuditRepository](logger))
+	versionRepo = repository.NewVersionRepository(db, logutil.WithLayer[repository.VersionRepository](logger))
+	userRepo = repository.NewUserRepository(db, logutil.WithLayer[repository.UserRepository](logger))
+	tokenRepo = repository.NewTokenRepository(db, logutil.WithLayer[repository.TokenRepository](logger))
 }
 
 func initOpts() {
@@ -105,10 +304,53 @@ func initLogger() {
 
 func initDB() {
 	var err error
-	if db, err = sqlx.Connect("sqlite3", "./data/data.db"); err != nil {
+	// Foreign keys are enabled in DSN, so every connection of the pool enforces them.
+	if db, err = sqlx.Connect("sqlite3", "./data/data.db?_foreign_keys=on"); err != nil {
 		logger.Fatal().Err(err).Msg("Failed to open db connection")
 	}
-	db.MustExec("PRAGMA foreign_keys = ON")
+}
+
+// bootstrapAdmin creates the admin with the credentials from the options unless there are users already,
+// so the API is accessible right after the first start.
+func bootstrapAdmin() {
+	if err := userService.Bootstrap(context.Background(), opts.User, opts.Password); err != nil {
+		logger.Fatal().Err(err).Msg("Failed to bootstrap admin")
+	}
+}
+
+// runProber starts background health checks of proxy profiles.
+func runProber() {
+	if opts.Health.Interval <= 0 {
+		logger.Info().Msg("Proxy health checks are disabled")
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stopProber = cancel
+	go prober.Run(ctx, pacService)
+}
+
+// runScheduler starts putting activation and expiration times of rules into effect.
+func runScheduler() {
+	if opts.Expiry.Interval <= 0 {
+		logger.Fatal().Msg("Expiry interval must be positive")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stopScheduler = cancel
+	go scheduler.Run(ctx, pacService)
+}
+
+// runPruner starts deleting audit log entries past the retention.
+func runPruner() {
+	if opts.Audit.Retention <= 0 {
+		logger.Info().Msg("Audit log entries are kept forever")
+		return
+	}
+	if opts.Audit.PruneInterval <= 0 {
+		logger.Fatal().Msg("Audit prune interval must be positive")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stopPruner = cancel
+	go auditPruner.Run(ctx)
 }
 
 func shutdownDB() {
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/jessevdk/go-flags"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/nnemirovsky/pacgen/internal/repository"
+	"github.com/nnemirovsky/pacgen/internal/service"
+	"github.com/nnemirovsky/pacgen/pkg/logutil"
+	"os"
+	"sort"
+	"time"
+)
+
+var logger = logutil.Logger
+
+type createCommand struct {
+	Name    string        `short:"n" long:"name" description:"Name of the token" required:"true"`
+	User    string        `short:"u" long:"user" description:"Name of the user the personal token belongs to"`
+	Service bool          `short:"s" long:"service" description:"Create a service token acting as admin narrowed down by its scopes"`
+	Scopes  []string      `long:"scope" description:"Scope granted to the token, e.g. rules:write, can be repeated" required:"true"`
+	Expires time.Duration `short:"e" long:"expires" description:"How long the token is valid for, 0 never expires it" default:"0"`
+}
+
+// Execute mints the token right in the database and prints it to stdout, it is not shown anywhere else.
+func (c *createCommand) Execute([]string) error {
+	if (c.User == "") == !c.Service {
+		return fmt.Errorf("specify either --user or --service")
+	}
+	token := model.APIToken{Name: c.Name}
+	for _, s := range c.Scopes {
+		scope, err := model.ParseScope(s)
+		if err != nil {
+			return err
+		}
+		if !contains(token.Scopes, scope) {
+			token.Scopes = append(token.Scopes, scope)
+		}
+	}
+	sort.Strings(token.Scopes)
+	if c.Expires > 0 {
+		expiresAt := time.Now().Add(c.Expires).UTC()
+		token.ExpiresAt = &expiresAt
+	}
+
+	db := sqlx.MustConnect("sqlite3", "./data/data.db?_foreign_keys=on")
+	defer func() {
+		if err := db.Close(); err != nil {
+			logger.Fatal().Err(err).Send()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	ctx = model.WithActor(ctx, model.Actor{User: model.CLIActor})
+
+	if c.User != "" {
+		user, err := repository.NewUserRepository(db, logger).GetByName(ctx, c.User)
+		if err != nil {
+			return err
+		}
+		token.UserID = user.ID
+	}
+
+	plain, err := service.NewTokenService(repository.NewTokenRepository(db, logger), logger).Mint(ctx, &token)
+	if err != nil {
+		return err
+	}
+	fmt.Println(plain)
+	return nil
+}
+
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Mints API tokens offline, e.g. for the scripts deployed along with the server:
+//
+//	tokens create --name ci --service --scope rules:write --scope pac:read --expires 720h
+func main() {
+	parser := flags.NewParser(nil, flags.Default)
+	if _, err := parser.AddCommand(
+		"create",
+		"Create API token",
+		"Create API token and print it, it is shown only once",
+		&createCommand{},
+	); err != nil {
+		logger.Fatal().Err(err).Send()
+	}
+
+	// Errors, including the ones returned by the commands, are printed by the parser.
+	if _, err := parser.Parse(); err != nil {
+		if flagsErr, ok := err.(*flags.Error); ok && flagsErr.Type == flags.ErrHelp {
+			return
+		}
+		os.Exit(1)
+	}
+}
@@ -9,6 +9,14 @@ var (
 	RepositoryUnknownError = errors.New("unknown error in the repository, please check the logs")
 	ServiceUnknownError    = errors.New("unknown error in the service, please check the logs")
 	InvalidReferenceError  = errors.New("invalid reference")
+	// DraftNotApprovedError is returned when the draft is published without the approval of another user.
+	DraftNotApprovedError = errors.New("draft must be approved by another user before it is published")
+	// LastAdminError is returned when the change would leave no admins.
+	LastAdminError = errors.New("the last admin can be neither deleted nor demoted")
+	// InvalidCredentialsError is returned when there is no user with the name and the password.
+	InvalidCredentialsError = errors.New("invalid user name or password")
+	// TokenNotAllowedError is returned when the token would be allowed more than its creator is.
+	TokenNotAllowedError = errors.New("API token cannot be allowed more than its creator is")
 )
 
 type EntityNotFoundError struct {
@@ -70,3 +78,26 @@ func (e *EntityStillReferencedError) Error() string {
 	}
 	return fmt.Sprintf("%s with %s %v is still referenced", e.Name, e.Key, e.Value)
 }
+
+// EntityVersionConflictError is returned when the entity has been changed since the version the change is based on.
+type EntityVersionConflictError struct {
+	// Name of entity
+	Name string
+	// Key of entity identifier (e.g. "name", "id"). Will be used in error message.
+	Key string
+	// Value of entity identifier (e.g. "John", 123). Will be used in error message.
+	Value any
+	// Version is the current version of the entity.
+	Version int
+}
+
+func (e *EntityVersionConflictError) Error() string {
+	if e.Name == "" {
+		e.Name = "entity"
+	}
+
+	if e.Key == "" || e.Value == nil {
+		return fmt.Sprintf("%s has been changed, current version is %d", e.Name, e.Version)
+	}
+	return fmt.Sprintf("%s with %s %v has been changed, current version is %d", e.Name, e.Key, e.Value, e.Version)
+}
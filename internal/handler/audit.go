@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/go-chi/render"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/nnemirovsky/pacgen/pkg/rest"
+	"github.com/rs/zerolog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type AuditHandler struct {
+	logger  zerolog.Logger
+	service AuditService
+}
+
+func NewAuditHandler(service AuditService, logger zerolog.Logger) *AuditHandler {
+	return &AuditHandler{
+		logger:  logger,
+		service: service,
+	}
+}
+
+// GetAll responds with a page of the audit log entries matching the filter given in the query, newest first.
+func (h *AuditHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	filter, ok := h.getFilter(w, r)
+	if !ok {
+		return
+	}
+	if filter.Page, ok = getPage(w, r, h.logger, defaultPageLimit); !ok {
+		return
+	}
+
+	entries, total, err := h.service.GetAll(r.Context(), filter)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Error occurred while getting audit log entries")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return
+	}
+
+	entryEntities := make([]AuditEntryR, 0, len(entries))
+	for _, entry := range entries {
+		entryR := AuditEntryR{}
+		entryR.FromModel(entry)
+		entryEntities = append(entryEntities, entryR)
+	}
+
+	setPageHeaders(w, r, filter.Page, total)
+	render.JSON(w, r, entryEntities)
+	w.WriteHeader(http.StatusOK)
+}
+
+// getFilter returns the filter given in query parameters: actor, entity, entity_id, action, request_id, and since
+// and until in RFC 3339.
+func (h *AuditHandler) getFilter(w http.ResponseWriter, r *http.Request) (filter model.AuditFilter, ok bool) {
+	query := r.URL.Query()
+	filter.Actor, filter.RequestID = query.Get("actor"), query.Get("request_id")
+
+	enums := []struct {
+		name   string
+		value  *string
+		values []string
+	}{
+		{"entity", &filter.Entity, model.AuditEntities},
+		{"action", (*string)(&filter.Action), model.AuditActions},
+	}
+	for _, e := range enums {
+		*e.value = query.Get(e.name)
+		if *e.value != "" && !contains(e.values, *e.value) {
+			h.logger.Debug().Str(e.name, *e.value).Msg("Unknown value in query parameter")
+			Render(w, r, rest.BadRequestResponse(
+				fmt.Sprintf("Query parameter '%s' must be one of: %s", e.name, strings.Join(e.values, ", ")),
+			), h.logger)
+			return model.AuditFilter{}, false
+		}
+	}
+
+	if value := query.Get("entity_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil || id < 1 {
+			h.logger.Debug().Err(err).Str("entity_id", value).Msg("Invalid entity_id query parameter")
+			Render(w, r, rest.BadRequestResponse("Query parameter 'entity_id' must be a positive integer"), h.logger)
+			return model.AuditFilter{}, false
+		}
+		filter.EntityID = id
+	}
+
+	bounds := []struct {
+		name  string
+		value *time.Time
+	}{
+		{"since", &filter.Since},
+		{"until", &filter.Until},
+	}
+	for _, b := range bounds {
+		value := query.Get(b.name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			h.logger.Debug().Err(err).Str("param", b.name).Msg("Invalid time in query parameter")
+			Render(w, r, rest.BadRequestResponse(
+				fmt.Sprintf("Query parameter '%s' must be in RFC 3339 format", b.name),
+			), h.logger)
+			return model.AuditFilter{}, false
+		}
+		*b.value = t
+	}
+	return filter, true
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/nnemirovsky/pacgen/internal/handler/mock"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/nnemirovsky/pacgen/pkg/logutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testPrepareAuditHandler(t *testing.T) (*AuditHandler, *mock.AuditService) {
+	ctrl := gomock.NewController(t)
+	auditSrvcMock := mock.NewAuditService(ctrl)
+
+	return NewAuditHandler(auditSrvcMock, logutil.DiscardLogger), auditSrvcMock
+}
+
+func TestAuditHandler_GetAll_OK(t *testing.T) {
+	t.Parallel()
+
+	auditHandler, auditSrvcMock := testPrepareAuditHandler(t)
+
+	entries := []model.AuditEntry{
+		{
+			ID:         8,
+			Actor:      "admin",
+			RequestID:  "cht4bqg2ohc7aq8b7n0g",
+			RemoteAddr: "10.0.0.1:51234",
+			Entity:     model.AuditRule,
+			EntityID:   3,
+			Action:     model.AuditUpdate,
+			Before:     `{"id":3,"enabled":true}`,
+			After:      `{"id":3,"enabled":false}`,
+			CreatedAt:  time.Date(2023, 6, 6, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			ID:        7,
+			Actor:     "scheduler",
+			Entity:    model.AuditRule,
+			EntityID:  2,
+			Action:    model.AuditDelete,
+			Before:    `{"id":2}`,
+			CreatedAt: time.Date(2023, 6, 6, 9, 0, 0, 0, time.UTC),
+		},
+	}
+
+	want := `[{"id":8,"actor":"admin","request_id":"cht4bqg2ohc7aq8b7n0g","remote_addr":"10.0.0.1:51234",` +
+		`"entity":"rule","entity_id":3,"action":"update","before":{"id":3,"enabled":true},` +
+		`"after":{"id":3,"enabled":false},"created_at":"2023-06-06T10:00:00Z"},` +
+		`{"id":7,"actor":"scheduler","entity":"rule","entity_id":2,"action":"delete","before":{"id":2},` +
+		`"created_at":"2023-06-06T09:00:00Z"}]`
+
+	filter := model.AuditFilter{
+		Actor:    "admin",
+		Entity:   model.AuditRule,
+		EntityID: 3,
+		Since:    time.Date(2023, 6, 6, 0, 0, 0, 0, time.UTC),
+		Page:     model.Page{Limit: 100},
+	}
+	auditSrvcMock.EXPECT().GetAll(gomock.Any(), filter).Return(entries, 2, nil)
+
+	target := "/audit?actor=admin&entity=rule&entity_id=3&since=2023-06-06T00:00:00Z"
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(auditHandler.GetAll)
+
+	handler.ServeHTTP(rr, req)
+
+	got := strings.TrimSuffix(rr.Body.String(), "\n")
+
+	assert.Equal(t, rr.Code, http.StatusOK)
+
+	assert.Equal(t, got, want)
+
+	assert.Equal(t, rr.Header().Get("X-Total-Count"), "2")
+}
+
+func TestAuditHandler_GetAll_BadRequest(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		target string
+		detail string
+	}{
+		"unknown entity": {
+			target: "/audit?entity=tag",
+			detail: "Query parameter 'entity' must be one of: rule, proxy_profile, domain_list, bypass, user, api_token",
+		},
+		"unknown action": {
+			target: "/audit?action=read",
+			detail: "Query parameter 'action' must be one of: create, update, delete",
+		},
+		"invalid entity id": {
+			target: "/audit?entity_id=0",
+			detail: "Query parameter 'entity_id' must be a positive integer",
+		},
+		"invalid time": {
+			target: "/audit?until=yesterday",
+			detail: "Query parameter 'until' must be in RFC 3339 format",
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			auditHandler, _ := testPrepareAuditHandler(t)
+
+			req, err := http.NewRequest(http.MethodGet, c.target, nil)
+			if err != nil {
+				t.Errorf("Unexpected error: %#v", err)
+			}
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(auditHandler.GetAll)
+
+			handler.ServeHTTP(rr, req)
+
+			want := `{"type":"about:blank","title":"Bad Request","status":400,"detail":"` + c.detail +
+				`","instance":"/audit"}`
+
+			assert.Equal(t, rr.Code, http.StatusBadRequest)
+
+			assert.Equal(t, strings.TrimSuffix(rr.Body.String(), "\n"), want)
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"github.com/go-chi/render"
+	"github.com/nnemirovsky/pacgen/pkg/rest"
+	"github.com/rs/zerolog"
+	"net/http"
+)
+
+type BypassHandler struct {
+	logger  zerolog.Logger
+	service BypassService
+}
+
+func NewBypassHandler(service BypassService, logger zerolog.Logger) *BypassHandler {
+	return &BypassHandler{
+		logger:  logger,
+		service: service,
+	}
+}
+
+func (h *BypassHandler) Get(w http.ResponseWriter, r *http.Request) {
+	entries, err := h.service.GetAll(r.Context())
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Error occurred while getting bypass list")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return
+	}
+
+	bypassR := BypassR{}
+	bypassR.FromModel(entries)
+
+	render.JSON(w, r, bypassR)
+	w.WriteHeader(http.StatusOK)
+}
+
+// Update replaces the whole bypass list.
+func (h *BypassHandler) Update(w http.ResponseWriter, r *http.Request) {
+	bypass := BypassCU{}
+	if ok := getFromBodyAndValidate(w, r, h.logger, &bypass); !ok {
+		return
+	}
+
+	entries, err := bypass.ToModel()
+	if err != nil {
+		renderConversionError(w, r, h.logger, err)
+		return
+	}
+
+	if err := h.service.Replace(r.Context(), entries); err != nil {
+		h.logger.Error().Err(err).Msg("Error occurred while updating bypass list")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return
+	}
+
+	render.NoContent(w, r)
+}
+
+// Import adds entries given in the bypass syntax of browsers and operating systems to the bypass list
+// and responds with the resulting list.
+func (h *BypassHandler) Import(w http.ResponseWriter, r *http.Request) {
+	bypass := BypassImportC{}
+	if ok := getFromBodyAndValidate(w, r, h.logger, &bypass); !ok {
+		return
+	}
+
+	entries, err := bypass.ToModel()
+	if err != nil {
+		renderConversionError(w, r, h.logger, err)
+		return
+	}
+
+	entries, err = h.service.Import(r.Context(), entries)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Error occurred while importing bypass list")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return
+	}
+
+	bypassR := BypassR{}
+	bypassR.FromModel(entries)
+
+	render.JSON(w, r, bypassR)
+	w.WriteHeader(http.StatusOK)
+}
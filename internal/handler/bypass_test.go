@@ -0,0 +1,205 @@
+package handler
+
+import (
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/nnemirovsky/pacgen/internal/handler/mock"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/nnemirovsky/pacgen/pkg/logutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func testPrepareBypassHandler(t *testing.T) (*BypassHandler, *mock.BypassService) {
+	ctrl := gomock.NewController(t)
+	bypassSrvcMock := mock.NewBypassService(ctrl)
+
+	return NewBypassHandler(bypassSrvcMock, logutil.DiscardLogger), bypassSrvcMock
+}
+
+func TestBypassHandler_Get_OK(t *testing.T) {
+	t.Parallel()
+
+	bypassHandler, bypassSrvcMock := testPrepareBypassHandler(t)
+
+	entries := []model.BypassEntry{
+		{Kind: model.BypassPreset, Value: model.PresetPlainHostNames},
+		{Kind: model.BypassNetwork, Value: "10.0.0.0/8"},
+	}
+
+	want := `{"entries":[{"kind":"preset","value":"plain_hostnames"},{"kind":"network","value":"10.0.0.0/8"}]}`
+
+	bypassSrvcMock.EXPECT().GetAll(gomock.Any()).Return(entries, nil)
+
+	req, err := http.NewRequest(http.MethodGet, "/bypass", nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(bypassHandler.Get)
+
+	handler.ServeHTTP(rr, req)
+
+	got := strings.TrimSuffix(rr.Body.String(), "\n")
+
+	assert.Equal(t, rr.Code, http.StatusOK)
+
+	assert.Equal(t, got, want)
+}
+
+func TestBypassHandler_Update_OK(t *testing.T) {
+	t.Parallel()
+
+	bypassHandler, bypassSrvcMock := testPrepareBypassHandler(t)
+
+	entries := []model.BypassEntry{
+		{Kind: model.BypassPreset, Value: model.PresetLocalDomains},
+		{Kind: model.BypassDomainAndSubdomains, Value: "xn--bcher-kva.de"},
+		{Kind: model.BypassNetwork, Value: "192.168.0.0/16"},
+	}
+
+	bypassSrvcMock.EXPECT().Replace(gomock.Any(), entries).Return(nil)
+
+	body := `{"entries":[{"kind":"preset","value":"local_domains"},` +
+		`{"kind":"domain_and_subdomains","value":"Bücher.de"},{"kind":"network","value":"192.168.1.1/16"}]}`
+	req, err := http.NewRequest(http.MethodPut, "/bypass", strings.NewReader(body))
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(bypassHandler.Update)
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, rr.Code, http.StatusNoContent)
+}
+
+func TestBypassHandler_Update_UnprocessableEntity(t *testing.T) {
+	t.Parallel()
+
+	bypassHandler, _ := testPrepareBypassHandler(t)
+
+	cases := map[string]struct {
+		body string
+		want string
+	}{
+		"unknown kind": {
+			body: `{"entries":[{"kind":"regex","value":".*"}]}`,
+			want: `[{"field":"entries[0].kind",` +
+				`"message":"unknown kind, possible values: preset, domain, domain_and_subdomains, network"}]`,
+		},
+		"unknown preset": {
+			body: `{"entries":[{"kind":"preset","value":"intranet"}]}`,
+			want: `[{"field":"entries[0].value",` +
+				`"message":"unknown preset, possible values: plain_hostnames, private_networks, local_domains"}]`,
+		},
+		"IPv6 network": {
+			body: `{"entries":[{"kind":"network","value":"fc00::/7"}]}`,
+			want: `[{"field":"entries[0].value","message":"network must be an IPv4 range in CIDR notation"}]`,
+		},
+		"duplicate": {
+			body: `{"entries":[{"kind":"domain","value":"a.com"},{"kind":"domain","value":"A.com."}]}`,
+			want: `[{"field":"entries[1].value","message":"domain a.com is already in the list"}]`,
+		},
+		"empty value": {
+			body: `{"entries":[{"kind":"domain","value":""}]}`,
+			want: `[{"field":"entries[0].value","code":"required","message":"is required"}]`,
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(http.MethodPut, "/bypass", strings.NewReader(c.body))
+			if err != nil {
+				t.Errorf("Unexpected error: %#v", err)
+			}
+
+			req.Header.Set("Content-Type", "application/json")
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(bypassHandler.Update)
+
+			handler.ServeHTTP(rr, req)
+
+			got := strings.TrimSuffix(rr.Body.String(), "\n")
+
+			assert.Equal(t, rr.Code, http.StatusUnprocessableEntity)
+
+			assert.Equal(t, got, `{"type":"urn:pacgen:problem:validation","title":"Unprocessable Entity","status":422,`+
+				`"detail":"Request body is invalid","instance":"/bypass","errors":`+c.want+`}`)
+		})
+	}
+}
+
+func TestBypassHandler_Import_OK(t *testing.T) {
+	t.Parallel()
+
+	bypassHandler, bypassSrvcMock := testPrepareBypassHandler(t)
+
+	imported := []model.BypassEntry{
+		{Kind: model.BypassPreset, Value: model.PresetPlainHostNames},
+		{Kind: model.BypassDomainAndSubdomains, Value: "corp.example.com"},
+		{Kind: model.BypassNetwork, Value: "10.0.0.0/8"},
+	}
+	result := append([]model.BypassEntry{{Kind: model.BypassDomain, Value: "a.com"}}, imported...)
+
+	want := `{"entries":[{"kind":"domain","value":"a.com"},{"kind":"preset","value":"plain_hostnames"},` +
+		`{"kind":"domain_and_subdomains","value":"corp.example.com"},{"kind":"network","value":"10.0.0.0/8"}]}`
+
+	bypassSrvcMock.EXPECT().Import(gomock.Any(), imported).Return(result, nil)
+
+	body := `{"list":"<local>, *.corp.example.com; 10.*"}`
+	req, err := http.NewRequest(http.MethodPost, "/bypass/import", strings.NewReader(body))
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(bypassHandler.Import)
+
+	handler.ServeHTTP(rr, req)
+
+	got := strings.TrimSuffix(rr.Body.String(), "\n")
+
+	assert.Equal(t, rr.Code, http.StatusOK)
+
+	assert.Equal(t, got, want)
+}
+
+func TestBypassHandler_Import_UnprocessableEntity(t *testing.T) {
+	t.Parallel()
+
+	bypassHandler, _ := testPrepareBypassHandler(t)
+
+	body := `{"list":"localhost, http://proxy.example.com"}`
+	req, err := http.NewRequest(http.MethodPost, "/bypass/import", strings.NewReader(body))
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(bypassHandler.Import)
+
+	handler.ServeHTTP(rr, req)
+
+	got := strings.TrimSuffix(rr.Body.String(), "\n")
+
+	assert.Equal(t, rr.Code, http.StatusUnprocessableEntity)
+
+	assert.Equal(t, got, `{"type":"urn:pacgen:problem:validation","title":"Unprocessable Entity","status":422,`+
+		`"detail":"Request body is invalid","instance":"/bypass/import",`+
+		`"errors":[{"field":"list","message":"http://proxy.example.com: schemes are not supported"}]}`)
+}
@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"github.com/go-chi/render"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/pkg/rest"
+	"github.com/rs/zerolog"
+	"net/http"
+)
+
+type DomainListHandler struct {
+	logger  zerolog.Logger
+	service DomainListService
+}
+
+func NewDomainListHandler(service DomainListService, logger zerolog.Logger) *DomainListHandler {
+	return &DomainListHandler{
+		logger:  logger,
+		service: service,
+	}
+}
+
+func (h *DomainListHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	lists, err := h.service.GetAll(r.Context())
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Error occurred while getting all domain lists")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return
+	}
+
+	listEntities := make([]DomainListR, 0)
+	for _, list := range lists {
+		listR := DomainListR{}
+		listR.FromModel(list)
+		listEntities = append(listEntities, listR)
+	}
+
+	render.JSON(w, r, listEntities)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *DomainListHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := getIDFromURL(w, r, h.logger)
+	if !ok {
+		return
+	}
+
+	list, err := h.service.GetByID(r.Context(), id)
+	if err != nil {
+		if _, ok := err.(*errs.EntityNotFoundError); ok {
+			h.logger.Debug().Err(err).Send()
+			Render(w, r, errorResponse(err), h.logger)
+			return
+		}
+		h.logger.Error().Err(err).Msg("Error occurred while getting domain list by id")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return
+	}
+
+	listR := DomainListR{}
+	listR.FromModel(list)
+
+	render.JSON(w, r, listR)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *DomainListHandler) Create(w http.ResponseWriter, r *http.Request) {
+	listCU := DomainListCU{}
+	if ok := getFromBodyAndValidate(w, r, h.logger, &listCU); !ok {
+		return
+	}
+
+	listModel, err := listCU.ToModel()
+	if err != nil {
+		renderConversionError(w, r, h.logger, err)
+		return
+	}
+
+	if err := h.service.Create(r.Context(), &listModel); err != nil {
+		if _, ok := err.(*errs.EntityAlreadyExistsError); ok {
+			h.logger.Debug().Err(err).Send()
+			Render(w, r, errorResponse(err), h.logger)
+			return
+		}
+		h.logger.Error().Err(err).Msg("Error occurred while creating domain list")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return
+	}
+
+	rest.Created(w, r, listModel.ID)
+}
+
+func (h *DomainListHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, ok := getIDFromURL(w, r, h.logger)
+	if !ok {
+		return
+	}
+
+	list := DomainListCU{}
+	if ok := getFromBodyAndValidate(w, r, h.logger, &list); !ok {
+		return
+	}
+
+	listModel, err := list.ToModel()
+	if err != nil {
+		renderConversionError(w, r, h.logger, err)
+		return
+	}
+	listModel.ID = id
+
+	if err := h.service.Update(r.Context(), listModel); err != nil {
+		switch err.(type) {
+		case *errs.EntityAlreadyExistsError:
+			h.logger.Debug().Err(err).Send()
+			Render(w, r, errorResponse(err), h.logger)
+			return
+		case *errs.EntityNotFoundError:
+			h.logger.Debug().Err(err).Send()
+			Render(w, r, errorResponse(err), h.logger)
+			return
+		default:
+			h.logger.Error().Err(err).Msg("Error occurred while updating domain list")
+			Render(w, r, rest.InternalErrorResponse(), h.logger)
+			return
+		}
+	}
+
+	render.NoContent(w, r)
+}
+
+func (h *DomainListHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := getIDFromURL(w, r, h.logger)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(r.Context(), id); err != nil {
+		switch err.(type) {
+		case *errs.EntityNotFoundError:
+			h.logger.Debug().Err(err).Send()
+			Render(w, r, errorResponse(err), h.logger)
+			return
+		case *errs.EntityStillReferencedError:
+			h.logger.Debug().Err(err).Send()
+			Render(w, r, errorResponse(err), h.logger)
+			return
+		default:
+			h.logger.Error().Err(err).Msg("Error occurred while deleting domain list")
+			Render(w, r, rest.InternalErrorResponse(), h.logger)
+			return
+		}
+	}
+
+	render.NoContent(w, r)
+}
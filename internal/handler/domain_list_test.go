@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"context"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/handler/mock"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/nnemirovsky/pacgen/pkg/logutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func testPrepareDomainListHandler(t *testing.T) (*DomainListHandler, *mock.DomainListService) {
+	ctrl := gomock.NewController(t)
+	listSrvcMock := mock.NewDomainListService(ctrl)
+
+	return NewDomainListHandler(listSrvcMock, logutil.DiscardLogger), listSrvcMock
+}
+
+func TestDomainListHandler_GetByID_OK(t *testing.T) {
+	t.Parallel()
+
+	listHandler, listSrvcMock := testPrepareDomainListHandler(t)
+
+	list := model.DomainList{
+		ID:   1,
+		Name: "video cdn",
+		Entries: []model.DomainListEntry{
+			{Domain: "googlevideo.com", Mode: model.DomainAndSubdomains},
+			{Domain: "vimeo.com", Mode: model.ExactDomain},
+		},
+	}
+
+	want := `{"id":1,"name":"video cdn","entries":[{"domain":"googlevideo.com","mode":"domain_and_subdomains"},` +
+		`{"domain":"vimeo.com","mode":"domain"}]}`
+
+	listSrvcMock.EXPECT().GetByID(gomock.Any(), 1).Return(list, nil)
+
+	req, err := http.NewRequest(http.MethodGet, "/domain-lists/1", nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "1")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(listHandler.GetByID)
+
+	handler.ServeHTTP(rr, req)
+
+	got := strings.TrimSuffix(rr.Body.String(), "\n")
+
+	assert.Equal(t, rr.Code, http.StatusOK)
+
+	assert.Equal(t, got, want)
+}
+
+func TestDomainListHandler_Create_OK(t *testing.T) {
+	t.Parallel()
+
+	listHandler, listSrvcMock := testPrepareDomainListHandler(t)
+
+	list := model.DomainList{
+		Name: "video cdn",
+		Entries: []model.DomainListEntry{
+			{Domain: "googlevideo.com", Mode: model.DomainAndSubdomains},
+			{Domain: "vimeo.com", Mode: model.ExactDomain},
+			{Domain: "xn--bcher-kva.de", Mode: model.ExactDomain},
+		},
+	}
+
+	listSrvcMock.EXPECT().Create(gomock.Any(), &list).DoAndReturn(
+		func(ctx context.Context, l *model.DomainList) error {
+			l.ID = 3
+			return nil
+		},
+	)
+
+	body := `{"name":"video cdn","entries":[{"domain":"googlevideo.com","mode":"domain_and_subdomains"},` +
+		`{"domain":"Vimeo.com.","mode":"domain"},{"domain":"bücher.de","mode":"domain"}]}`
+
+	req, err := http.NewRequest(http.MethodPost, "http://localhost/domain-lists", strings.NewReader(body))
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(listHandler.Create)
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, rr.Code, http.StatusCreated)
+
+	assert.Equal(t, rr.Header().Get("Location"), "http://localhost/domain-lists/3")
+}
+
+func TestDomainListHandler_Create_UnprocessableEntity(t *testing.T) {
+	t.Parallel()
+
+	listHandler, _ := testPrepareDomainListHandler(t)
+
+	cases := map[string]string{
+		"missing name":     `{"entries":[]}`,
+		"invalid mode":     `{"name":"cdn","entries":[{"domain":"vimeo.com","mode":"subdomains"}]}`,
+		"empty domain":     `{"name":"cdn","entries":[{"domain":"","mode":"domain"}]}`,
+		"duplicate domain": `{"name":"cdn","entries":[{"domain":"a.com","mode":"domain"},{"domain":"a.com","mode":"domain"}]}`,
+		"same after normalization": `{"name":"cdn","entries":[{"domain":"A.com","mode":"domain"},` +
+			`{"domain":"a.com.","mode":"domain"}]}`,
+		"invalid domain": `{"name":"cdn","entries":[{"domain":"*.a.com","mode":"domain"}]}`,
+	}
+
+	for name, body := range cases {
+		body := body
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(http.MethodPost, "/domain-lists", strings.NewReader(body))
+			if err != nil {
+				t.Errorf("Unexpected error: %#v", err)
+			}
+
+			req.Header.Set("Content-Type", "application/json")
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(listHandler.Create)
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, rr.Code, http.StatusUnprocessableEntity)
+		})
+	}
+}
+
+func TestDomainListHandler_Update_NotFound(t *testing.T) {
+	t.Parallel()
+
+	listHandler, listSrvcMock := testPrepareDomainListHandler(t)
+
+	list := model.DomainList{ID: 4, Name: "video cdn", Entries: []model.DomainListEntry{}}
+
+	listSrvcMock.EXPECT().Update(gomock.Any(), list).Return(&errs.EntityNotFoundError{})
+
+	req, err := http.NewRequest(http.MethodPut, "/domain-lists/4", strings.NewReader(`{"name":"video cdn"}`))
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "4")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(listHandler.Update)
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, rr.Code, http.StatusNotFound)
+}
+
+func TestDomainListHandler_Delete_Conflict(t *testing.T) {
+	t.Parallel()
+
+	listHandler, listSrvcMock := testPrepareDomainListHandler(t)
+
+	listSrvcMock.EXPECT().Delete(gomock.Any(), 1).Return(&errs.EntityStillReferencedError{})
+
+	req, err := http.NewRequest(http.MethodDelete, "/domain-lists/1", nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "1")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(listHandler.Delete)
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, rr.Code, http.StatusConflict)
+}
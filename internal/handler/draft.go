@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"errors"
+	"github.com/go-chi/render"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/pkg/rest"
+	"github.com/rs/zerolog"
+	"net/http"
+)
+
+type DraftHandler struct {
+	logger  zerolog.Logger
+	service DraftService
+}
+
+func NewDraftHandler(service DraftService, logger zerolog.Logger) *DraftHandler {
+	return &DraftHandler{
+		logger:  logger,
+		service: service,
+	}
+}
+
+// Get responds with the changes of the draft since the published version and the diff of their PAC files.
+func (h *DraftHandler) Get(w http.ResponseWriter, r *http.Request) {
+	draft, err := h.service.Get(r.Context())
+	if err != nil {
+		if _, ok := err.(*errs.EntityNotFoundError); ok {
+			h.logger.Debug().Err(err).Send()
+			Render(w, r, errorResponse(err), h.logger)
+			return
+		}
+		h.logger.Error().Err(err).Msg("Error occurred while getting draft")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return
+	}
+
+	draftR := DraftR{}
+	draftR.FromModel(draft)
+
+	render.JSON(w, r, draftR)
+	w.WriteHeader(http.StatusOK)
+}
+
+// Approve approves the draft as it is now on behalf of the authenticated user.
+func (h *DraftHandler) Approve(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.Approve(r.Context()); err != nil {
+		h.logger.Error().Err(err).Msg("Error occurred while approving draft")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return
+	}
+
+	render.NoContent(w, r)
+}
+
+// Publish publishes the draft and responds with the new published version.
+func (h *DraftHandler) Publish(w http.ResponseWriter, r *http.Request) {
+	version, err := h.service.Publish(r.Context())
+	if err != nil {
+		var notFound *errs.EntityNotFoundError
+		if errors.As(err, &notFound) || errors.Is(err, errs.DraftNotApprovedError) {
+			h.logger.Debug().Err(err).Send()
+			Render(w, r, errorResponse(err), h.logger)
+			return
+		}
+		h.logger.Error().Err(err).Msg("Error occurred while publishing draft")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return
+	}
+
+	versionR := VersionR{}
+	versionR.FromModel(version)
+
+	render.JSON(w, r, versionR)
+	w.WriteHeader(http.StatusOK)
+}
+
+// Discard replaces the draft with the published version and responds with the changes it has made.
+func (h *DraftHandler) Discard(w http.ResponseWriter, r *http.Request) {
+	published, changes, err := h.service.Discard(r.Context())
+	if err != nil {
+		if _, ok := err.(*errs.EntityNotFoundError); ok {
+			h.logger.Debug().Err(err).Send()
+			Render(w, r, errorResponse(err), h.logger)
+			return
+		}
+		h.logger.Error().Err(err).Msg("Error occurred while discarding draft")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return
+	}
+
+	render.JSON(w, r, RollbackR{Version: published, Changes: versionChanges(changes)})
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/handler/mock"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/nnemirovsky/pacgen/pkg/logutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testPrepareDraftHandler(t *testing.T) (*DraftHandler, *mock.DraftService) {
+	ctrl := gomock.NewController(t)
+	draftSrvcMock := mock.NewDraftService(ctrl)
+
+	return NewDraftHandler(draftSrvcMock, logutil.DiscardLogger), draftSrvcMock
+}
+
+func testDraftRequest(t *testing.T, method, target string) *http.Request {
+	req, err := http.NewRequest(method, target, nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	return req
+}
+
+func TestDraftHandler_Get_OK(t *testing.T) {
+	t.Parallel()
+
+	draftHandler, draftSrvcMock := testPrepareDraftHandler(t)
+
+	draft := model.Draft{
+		Published: 3,
+		Changes: []model.VersionChange{
+			{Entity: model.AuditRule, EntityID: 1, Action: model.AuditCreate, After: `{"id":1}`},
+		},
+		PACDiff:   "--- published\n+++ draft\n@@ -1 +1 @@\n-a\n+b\n",
+		Approvers: []string{"alice"},
+	}
+	draftSrvcMock.EXPECT().Get(gomock.Any()).Return(draft, nil)
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(draftHandler.Get)
+
+	handler.ServeHTTP(rr, testDraftRequest(t, http.MethodGet, "/draft"))
+
+	got := strings.TrimSuffix(rr.Body.String(), "\n")
+	want := `{"published_version":3,"changes":[{"entity":"rule","entity_id":1,"action":"create","after":{"id":1}}],` +
+		`"pac_diff":"--- published\n+++ draft\n@@ -1 +1 @@\n-a\n+b\n","approved_by":["alice"]}`
+
+	assert.Equal(t, rr.Code, http.StatusOK)
+	assert.Equal(t, got, want)
+}
+
+func TestDraftHandler_Approve_NoContent(t *testing.T) {
+	t.Parallel()
+
+	draftHandler, draftSrvcMock := testPrepareDraftHandler(t)
+
+	draftSrvcMock.EXPECT().Approve(gomock.Any()).Return(nil)
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(draftHandler.Approve)
+
+	handler.ServeHTTP(rr, testDraftRequest(t, http.MethodPost, "/draft/approve"))
+
+	assert.Equal(t, rr.Code, http.StatusNoContent)
+}
+
+func TestDraftHandler_Publish_OK(t *testing.T) {
+	t.Parallel()
+
+	draftHandler, draftSrvcMock := testPrepareDraftHandler(t)
+
+	createdAt := time.Date(2023, 6, 6, 10, 0, 0, 0, time.UTC)
+	version := model.ConfigVersion{ID: 4, RuleCount: 2, ProfileCount: 1, CreatedAt: createdAt}
+	draftSrvcMock.EXPECT().Publish(gomock.Any()).Return(version, nil)
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(draftHandler.Publish)
+
+	handler.ServeHTTP(rr, testDraftRequest(t, http.MethodPost, "/draft/publish"))
+
+	got := strings.TrimSuffix(rr.Body.String(), "\n")
+	want := `{"id":4,"rule_count":2,"profile_count":1,"created_at":"2023-06-06T10:00:00Z"}`
+
+	assert.Equal(t, rr.Code, http.StatusOK)
+	assert.Equal(t, got, want)
+}
+
+func TestDraftHandler_Publish_NotApproved(t *testing.T) {
+	t.Parallel()
+
+	draftHandler, draftSrvcMock := testPrepareDraftHandler(t)
+
+	draftSrvcMock.EXPECT().Publish(gomock.Any()).Return(model.ConfigVersion{}, errs.DraftNotApprovedError)
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(draftHandler.Publish)
+
+	handler.ServeHTTP(rr, testDraftRequest(t, http.MethodPost, "/draft/publish"))
+
+	got := strings.TrimSuffix(rr.Body.String(), "\n")
+	want := `{"type":"urn:pacgen:problem:approval-required","title":"Conflict","status":409,` +
+		`"detail":"draft must be approved by another user before it is published","instance":"/draft/publish"}`
+
+	assert.Equal(t, rr.Code, http.StatusConflict)
+	assert.Equal(t, got, want)
+}
+
+func TestDraftHandler_Discard_OK(t *testing.T) {
+	t.Parallel()
+
+	draftHandler, draftSrvcMock := testPrepareDraftHandler(t)
+
+	changes := []model.VersionChange{
+		{Entity: model.AuditRule, EntityID: 1, Action: model.AuditDelete, Before: `{"id":1}`},
+	}
+	draftSrvcMock.EXPECT().Discard(gomock.Any()).Return(3, changes, nil)
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(draftHandler.Discard)
+
+	handler.ServeHTTP(rr, testDraftRequest(t, http.MethodPost, "/draft/discard"))
+
+	got := strings.TrimSuffix(rr.Body.String(), "\n")
+	want := `{"version":3,"changes":[{"entity":"rule","entity_id":1,"action":"delete","before":{"id":1}}]}`
+
+	assert.Equal(t, rr.Code, http.StatusOK)
+	assert.Equal(t, got, want)
+}
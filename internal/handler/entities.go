@@ -1,62 +1,313 @@
 package handler
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/nnemirovsky/pacgen/pkg/domain"
 	"github.com/nnemirovsky/pacgen/pkg/regexp"
+	"github.com/nnemirovsky/pacgen/pkg/rest"
+	"sort"
+	"time"
 )
 
+// FieldError is returned by conversions of request entities when a field holds an invalid value.
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// MetadataR is the metadata of rules and proxy profiles, timestamps are omitted for entities created before
+// they were tracked.
+type MetadataR struct {
+	Description string     `json:"description,omitempty"`
+	Owner       string     `json:"owner,omitempty"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+}
+
+func (m *MetadataR) FromModel(metadata model.Metadata) {
+	m.Description = metadata.Description
+	m.Owner = metadata.Owner
+	m.CreatedAt = metadata.CreatedAt
+	m.UpdatedAt = metadata.UpdatedAt
+}
+
+// MetadataCU holds the editable metadata. Empty owner defaults to the authenticated user on creation
+// and is left intact on update.
+type MetadataCU struct {
+	Description string `json:"description" validate:"max=1000"`
+	Owner       string `json:"owner" validate:"max=64"`
+}
+
+func (m *MetadataCU) ToModel() model.Metadata {
+	return model.Metadata{Description: m.Description, Owner: m.Owner}
+}
+
 type RuleR struct {
-	ID             int    `json:"id"`
-	Regexp         string `json:"regexp"`
-	ProxyProfileID int    `json:"proxy_profile_id"`
+	ID     int    `json:"id"`
+	Regexp string `json:"regexp,omitempty"`
+	// Domain is the punycode form of the domain matched by the rule, DomainUnicode is its display form.
+	// Both are empty if the regex was not created from a domain.
+	Domain         string     `json:"domain,omitempty"`
+	DomainUnicode  string     `json:"domain_unicode,omitempty"`
+	Mode           string     `json:"mode,omitempty"`
+	DomainListID   int        `json:"domain_list_id,omitempty"`
+	ProxyProfileID int        `json:"proxy_profile_id"`
+	Enabled        bool       `json:"enabled"`
+	Tags           []string   `json:"tags"`
+	ActiveFrom     *time.Time `json:"active_from,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	MetadataR
+	// ProxyProfile is present if the profile is expanded.
+	ProxyProfile *ProxyProfileR `json:"proxy_profile,omitempty"`
+}
+
+// ruleFields maps the fields of RuleR to the fields of model.RuleFields they are made of.
+var ruleFields = map[string][]string{
+	"id":               {"id"},
+	"regexp":           {"regex"},
+	"domain":           {"regex"},
+	"domain_unicode":   {"regex"},
+	"mode":             {"regex"},
+	"domain_list_id":   {"domain_list_id"},
+	"proxy_profile_id": {"proxy_profile_id"},
+	"enabled":          {"enabled"},
+	"tags":             {"tags"},
+	"active_from":      {"active_from"},
+	"expires_at":       {"expires_at"},
+	"description":      {"description"},
+	"owner":            {"owner"},
+	"created_at":       {"created_at"},
+	"updated_at":       {"updated_at"},
 }
 
 func (r *RuleR) FromModel(rule model.Rule) {
 	r.ID = rule.ID
 	r.Regexp = rule.Regex
-	r.ProxyProfileID = rule.ProxyProfile.ID
+	if d, withSubdomains, ok := regexp.ParseDomain(rule.Regex); ok {
+		r.Domain, r.DomainUnicode, r.Mode = d, domain.ToUnicode(d), model.ExactDomain.String()
+		if withSubdomains {
+			r.Mode = model.DomainAndSubdomains.String()
+		}
+	}
+	r.DomainListID = rule.DomainListID
+	if rule.ProxyProfile != nil {
+		r.ProxyProfileID = rule.ProxyProfile.ID
+	}
+	r.Enabled = rule.Enabled
+	r.Tags = make([]string, 0, len(rule.Tags))
+	r.Tags = append(r.Tags, rule.Tags...)
+	r.ActiveFrom = rule.ActiveFrom
+	r.ExpiresAt = rule.ExpiresAt
+	r.MetadataR.FromModel(rule.Metadata)
 }
 
+// RuleCU matches either the domain in the given mode or all the domains of the list. The domain is normalized
+// by domain.Normalize, so Unicode names match the punycode hosts passed by browsers. The rule is in effect
+// from ActiveFrom until ExpiresAt, the times are truncated to seconds.
 type RuleCU struct {
-	Domain         string `json:"domain" validate:"required"`
-	Mode           string `json:"mode" validate:"required,oneof=domain domain_and_subdomains"`
-	ProxyProfileID int    `json:"proxy_profile_id" validate:"required"`
+	Domain         string     `json:"domain" validate:"required_without=DomainListID,excluded_with=DomainListID"`
+	Mode           string     `json:"mode" validate:"required_with=Domain,excluded_with=DomainListID,omitempty,oneof=domain domain_and_subdomains"`
+	DomainListID   int        `json:"domain_list_id" validate:"omitempty,min=1"`
+	ProxyProfileID int        `json:"proxy_profile_id" validate:"required"`
+	Enabled        *bool      `json:"enabled"`
+	Tags           []string   `json:"tags" validate:"max=32,dive,required,max=64,excludesall=0x2C0x20"`
+	ActiveFrom     *time.Time `json:"active_from"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+	MetadataCU
+	// regex is the regex of a patched rule which is not made of a domain, it is kept unless the patch changes
+	// what the rule matches.
+	regex string
+}
+
+// rulePatchFields maps the fields of RuleCU to the fields of model.RuleFields they change.
+var rulePatchFields = map[string][]string{
+	"domain":           {"regex", "domain_list_id"},
+	"mode":             {"regex", "domain_list_id"},
+	"domain_list_id":   {"regex", "domain_list_id"},
+	"proxy_profile_id": {"proxy_profile_id"},
+	"enabled":          {"enabled"},
+	"tags":             {"tags"},
+	"active_from":      {"active_from"},
+	"expires_at":       {"expires_at"},
+	"description":      {"description"},
+	"owner":            {"owner"},
+}
+
+// FromModel fills the entity with the rule, so a merge patch can be applied to it.
+func (r *RuleCU) FromModel(rule model.Rule) {
+	if d, withSubdomains, ok := regexp.ParseDomain(rule.Regex); ok {
+		r.Domain, r.Mode = d, model.ExactDomain.String()
+		if withSubdomains {
+			r.Mode = model.DomainAndSubdomains.String()
+		}
+	} else if rule.DomainListID == 0 {
+		r.regex = rule.Regex
+	}
+	r.DomainListID = rule.DomainListID
+	if rule.ProxyProfile != nil {
+		r.ProxyProfileID = rule.ProxyProfile.ID
+	}
+	enabled := rule.Enabled
+	r.Enabled = &enabled
+	r.Tags = append(make([]string, 0, len(rule.Tags)), rule.Tags...)
+	r.ActiveFrom = rule.ActiveFrom
+	r.ExpiresAt = rule.ExpiresAt
+	r.MetadataCU = MetadataCU{Description: rule.Metadata.Description, Owner: rule.Metadata.Owner}
 }
 
 func (r *RuleCU) ToModel() (model.Rule, error) {
-	var regex string
-	switch r.Mode {
-	case "domain":
-		regex = regexp.Domain(r.Domain)
-	case "domain_and_subdomains":
-		regex = regexp.DomainAndSubdomains(r.Domain)
-	default:
-		return model.Rule{}, errors.New("invalid mode")
+	regex := r.regex
+	if r.DomainListID == 0 && regex == "" {
+		ascii, err := domain.Normalize(r.Domain)
+		if err != nil {
+			return model.Rule{}, &FieldError{Field: "domain", Err: err}
+		}
+		switch r.Mode {
+		case "domain":
+			regex = regexp.Domain(ascii)
+		case "domain_and_subdomains":
+			regex = regexp.DomainAndSubdomains(ascii)
+		default:
+			return model.Rule{}, errors.New("invalid mode")
+		}
 	}
 
-	return model.Rule{Regex: regex, ProxyProfile: &model.ProxyProfile{ID: r.ProxyProfileID}}, nil
+	activeFrom, expiresAt := truncateTime(r.ActiveFrom), truncateTime(r.ExpiresAt)
+	if activeFrom != nil && expiresAt != nil && !expiresAt.After(*activeFrom) {
+		return model.Rule{}, &FieldError{Field: "expires_at", Err: errors.New("must be after active_from")}
+	}
+
+	rule := model.Rule{
+		Regex:        regex,
+		DomainListID: r.DomainListID,
+		Enabled:      r.Enabled == nil || *r.Enabled,
+		Tags:         make(model.Tags, 0, len(r.Tags)),
+		ProxyProfile: &model.ProxyProfile{ID: r.ProxyProfileID},
+		ActiveFrom:   activeFrom,
+		ExpiresAt:    expiresAt,
+		Metadata:     r.MetadataCU.ToModel(),
+	}
+	seen := make(map[string]struct{}, len(r.Tags))
+	for _, tag := range r.Tags {
+		if _, ok := seen[tag]; !ok {
+			seen[tag] = struct{}{}
+			rule.Tags = append(rule.Tags, tag)
+		}
+	}
+	sort.Strings(rule.Tags)
+	return rule, nil
+}
+
+// truncateTime returns the time in UTC truncated to seconds, the precision it is stored with.
+func truncateTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	truncated := t.UTC().Truncate(time.Second)
+	return &truncated
+}
+
+// RuleBulkU is a set of changes applied to all the rules with a tag.
+type RuleBulkU struct {
+	ProxyProfileID *int  `json:"proxy_profile_id" validate:"omitempty,min=1"`
+	Enabled        *bool `json:"enabled"`
+}
+
+func (r *RuleBulkU) ToModel() model.RuleBulkUpdate {
+	return model.RuleBulkUpdate{ProxyProfileID: r.ProxyProfileID, Enabled: r.Enabled}
+}
+
+type BulkResultR struct {
+	Affected int `json:"affected"`
 }
 
 type ProxyProfileR struct {
-	ID      int    `json:"id"`
-	Name    string `json:"name"`
-	Type    string `json:"type"`
-	Address string `json:"address"`
+	ID               int            `json:"id"`
+	Name             string         `json:"name"`
+	Type             string         `json:"type"`
+	Address          string         `json:"address"`
+	StandbyProfileID int            `json:"standby_profile_id,omitempty"`
+	Members          []PoolMemberRW `json:"members,omitempty"`
+	// RuleCount is present if the number of rules is included.
+	RuleCount *int `json:"rule_count,omitempty"`
+	MetadataR
+}
+
+// profileFields maps the fields of ProxyProfileR to the fields of model.ProxyProfileFields they are made of.
+var profileFields = map[string][]string{
+	"id":                 {"id"},
+	"name":               {"name"},
+	"type":               {"type"},
+	"address":            {"address"},
+	"standby_profile_id": {"standby_id"},
+	"members":            {"id", "type", "members"},
+	"description":        {"description"},
+	"owner":              {"owner"},
+	"created_at":         {"created_at"},
+	"updated_at":         {"updated_at"},
 }
 
 func (p *ProxyProfileR) FromModel(profile model.ProxyProfile) {
-	p.Address = profile.Address
+	p.Address = profile.Address()
 	p.ID = profile.ID
 	p.Name = profile.Name
 	p.Type = profile.Type.String()
+	p.StandbyProfileID = profile.StandbyID
+	for _, member := range profile.Members {
+		p.Members = append(p.Members, PoolMemberRW{ProfileID: member.ProfileID, Weight: member.Weight})
+	}
+	p.MetadataR.FromModel(profile.Metadata)
+}
+
+// PoolMemberRW is a member of the pool, weight defaults to 1.
+type PoolMemberRW struct {
+	ProfileID int `json:"profile_id" validate:"required,min=1"`
+	Weight    int `json:"weight" validate:"omitempty,min=1,max=100"`
 }
 
 type ProxyProfileCU struct {
-	Name    string `json:"name" validate:"required"`
-	Type    string `json:"type" validate:"required,oneof=HTTP http HTTPS https SOCKS4 socks4 SOCKS5 socks5"`
-	Address string `json:"address" validate:"required"`
+	Name string `json:"name" validate:"required"`
+	Type string `json:"type" validate:"required,oneof=HTTP http HTTPS https SOCKS4 socks4 SOCKS5 socks5 DIRECT direct POOL pool"`
+	// Address is required for all the types except direct and pool.
+	Address          string         `json:"address" validate:"required_unless=Type DIRECT Type direct Type POOL Type pool"`
+	StandbyProfileID int            `json:"standby_profile_id" validate:"omitempty,min=1"`
+	Members          []PoolMemberRW `json:"members" validate:"max=64,unique=ProfileID,dive"`
+	MetadataCU
+}
+
+// profilePatchFields maps the fields of ProxyProfileCU to the fields of model.ProxyProfileFields they change.
+var profilePatchFields = map[string][]string{
+	"name":               {"name"},
+	"type":               {"type"},
+	"address":            {"address"},
+	"standby_profile_id": {"standby_id"},
+	"members":            {"members"},
+	"description":        {"description"},
+	"owner":              {"owner"},
+}
+
+// FromModel fills the entity with the profile, so a merge patch can be applied to it.
+func (p *ProxyProfileCU) FromModel(profile model.ProxyProfile) {
+	p.Name = profile.Name
+	p.Type = profile.Type.String()
+	p.Address = profile.Address()
+	p.StandbyProfileID = profile.StandbyID
+	p.Members = nil
+	for _, member := range profile.Members {
+		p.Members = append(p.Members, PoolMemberRW{ProfileID: member.ProfileID, Weight: member.Weight})
+	}
+	p.MetadataCU = MetadataCU{Description: profile.Metadata.Description, Owner: profile.Metadata.Owner}
 }
 
 func (p *ProxyProfileCU) ToModel() (model.ProxyProfile, error) {
@@ -64,10 +315,468 @@ func (p *ProxyProfileCU) ToModel() (model.ProxyProfile, error) {
 	if err != nil {
 		return model.ProxyProfile{}, fmt.Errorf("invalid profile type: %w", err)
 	}
+	if t == model.Direct && p.Address != "" {
+		return model.ProxyProfile{}, errors.New("direct profile must not have an address")
+	}
+	if t == model.Pool && p.Address != "" {
+		return model.ProxyProfile{}, errors.New("pool must not have an address")
+	}
+	if t == model.Pool && len(p.Members) == 0 {
+		return model.ProxyProfile{}, errors.New("pool must have members")
+	}
+	if t != model.Pool && len(p.Members) != 0 {
+		return model.ProxyProfile{}, errors.New("only pools have members")
+	}
+
+	profile := model.ProxyProfile{
+		Name:      p.Name,
+		Type:      t,
+		StandbyID: p.StandbyProfileID,
+		Metadata:  p.MetadataCU.ToModel(),
+	}
+	if t != model.Direct && t != model.Pool {
+		if profile.Host, profile.Port, err = model.ParseAddress(p.Address); err != nil {
+			return model.ProxyProfile{}, &FieldError{Field: "address", Err: err}
+		}
+	}
+	if t == model.Pool {
+		profile.Members = make([]model.PoolMember, 0, len(p.Members))
+		for _, member := range p.Members {
+			weight := member.Weight
+			if weight == 0 {
+				weight = 1
+			}
+			profile.Members = append(profile.Members, model.PoolMember{ProfileID: member.ProfileID, Weight: weight})
+		}
+	}
+	return profile, nil
+}
+
+type DomainListEntryRW struct {
+	Domain string `json:"domain" validate:"required"`
+	Mode   string `json:"mode" validate:"required,oneof=domain domain_and_subdomains"`
+}
+
+type DomainListR struct {
+	ID      int                 `json:"id"`
+	Name    string              `json:"name"`
+	Entries []DomainListEntryRW `json:"entries"`
+}
+
+func (l *DomainListR) FromModel(list model.DomainList) {
+	l.ID = list.ID
+	l.Name = list.Name
+	l.Entries = make([]DomainListEntryRW, 0, len(list.Entries))
+	for _, entry := range list.Entries {
+		l.Entries = append(l.Entries, DomainListEntryRW{Domain: entry.Domain, Mode: entry.Mode.String()})
+	}
+}
+
+// DomainListCU holds domains normalized by domain.Normalize on conversion into the model.
+type DomainListCU struct {
+	Name    string              `json:"name" validate:"required"`
+	Entries []DomainListEntryRW `json:"entries" validate:"max=10000,unique=Domain,dive"`
+}
+
+func (l *DomainListCU) ToModel() (model.DomainList, error) {
+	list := model.DomainList{
+		Name:    l.Name,
+		Entries: make([]model.DomainListEntry, 0, len(l.Entries)),
+	}
+	seen := make(map[string]struct{}, len(l.Entries))
+	for i, entry := range l.Entries {
+		mode, err := model.ParseDomainMode(entry.Mode)
+		if err != nil {
+			return model.DomainList{}, fmt.Errorf("invalid mode of domain %s: %w", entry.Domain, err)
+		}
+		ascii, err := domain.Normalize(entry.Domain)
+		if err != nil {
+			return model.DomainList{}, &FieldError{Field: fmt.Sprintf("entries[%d].domain", i), Err: err}
+		}
+		// Different spellings of the same domain are duplicates after normalization.
+		if _, ok := seen[ascii]; ok {
+			err := fmt.Errorf("domain %s is already in the list", ascii)
+			return model.DomainList{}, &FieldError{Field: fmt.Sprintf("entries[%d].domain", i), Err: err}
+		}
+		seen[ascii] = struct{}{}
+		list.Entries = append(list.Entries, model.DomainListEntry{Domain: ascii, Mode: mode})
+	}
+	return list, nil
+}
+
+type BypassEntryRW struct {
+	Kind  string `json:"kind" validate:"required"`
+	Value string `json:"value" validate:"required"`
+}
+
+type BypassR struct {
+	Entries []BypassEntryRW `json:"entries"`
+}
+
+func (b *BypassR) FromModel(entries []model.BypassEntry) {
+	b.Entries = make([]BypassEntryRW, 0, len(entries))
+	for _, entry := range entries {
+		b.Entries = append(b.Entries, BypassEntryRW{Kind: entry.Kind.String(), Value: entry.Value})
+	}
+}
+
+// BypassCU holds values brought into canonical form by model.NewBypassEntry on conversion into the model.
+type BypassCU struct {
+	Entries []BypassEntryRW `json:"entries" validate:"max=1000,dive"`
+}
+
+func (b *BypassCU) ToModel() ([]model.BypassEntry, error) {
+	entries := make([]model.BypassEntry, 0, len(b.Entries))
+	seen := make(map[model.BypassEntry]struct{}, len(b.Entries))
+	for i, e := range b.Entries {
+		kind, err := model.ParseBypassKind(e.Kind)
+		if err != nil {
+			return nil, &FieldError{Field: fmt.Sprintf("entries[%d].kind", i), Err: err}
+		}
+		entry, err := model.NewBypassEntry(kind, e.Value)
+		if err != nil {
+			return nil, &FieldError{Field: fmt.Sprintf("entries[%d].value", i), Err: err}
+		}
+		if _, ok := seen[entry]; ok {
+			err := fmt.Errorf("%s %s is already in the list", entry.Kind, entry.Value)
+			return nil, &FieldError{Field: fmt.Sprintf("entries[%d].value", i), Err: err}
+		}
+		seen[entry] = struct{}{}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// BypassImportC holds the bypass list in the syntax of browsers and operating systems, see model.ParseBypassList.
+type BypassImportC struct {
+	List string `json:"list" validate:"required"`
+}
+
+func (b *BypassImportC) ToModel() ([]model.BypassEntry, error) {
+	entries, err := model.ParseBypassList(b.List)
+	if err != nil {
+		return nil, &FieldError{Field: "list", Err: err}
+	}
+	return entries, nil
+}
+
+type LintFindingR struct {
+	Kind string `json:"kind"`
+	// RuleID is omitted for the rule that is being created.
+	RuleID     int    `json:"rule_id,omitempty"`
+	RelatedIDs []int  `json:"related_rule_ids"`
+	Message    string `json:"message"`
+	Fix        string `json:"fix"`
+}
+
+func (f *LintFindingR) FromModel(finding model.LintFinding) {
+	f.Kind = finding.Kind.String()
+	f.RuleID = finding.RuleID
+	f.RelatedIDs = make([]int, 0, len(finding.RelatedIDs))
+	f.RelatedIDs = append(f.RelatedIDs, finding.RelatedIDs...)
+	f.Message = finding.Message
+	f.Fix = finding.Fix
+}
+
+func lintFindingEntities(findings []model.LintFinding) []LintFindingR {
+	entities := make([]LintFindingR, 0, len(findings))
+	for _, finding := range findings {
+		findingR := LintFindingR{}
+		findingR.FromModel(finding)
+		entities = append(entities, findingR)
+	}
+	return entities
+}
+
+// LintConflictR is the problem of a rule rejected in strict mode because of its findings.
+type LintConflictR struct {
+	rest.ErrorResponse
+	Findings []LintFindingR `json:"findings"`
+}
+
+type ProfileHealthR struct {
+	ProfileID int        `json:"profile_id"`
+	Status    string     `json:"status"`
+	LatencyMS *float64   `json:"latency_ms,omitempty"`
+	CheckedAt *time.Time `json:"checked_at,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+func (h *ProfileHealthR) FromModel(health model.ProfileHealth) {
+	h.ProfileID = health.ProfileID
+	h.Status = health.Status.String()
+	if health.Status == model.HealthUp {
+		latency := float64(health.Latency.Microseconds()) / 1000
+		h.LatencyMS = &latency
+	}
+	if !health.CheckedAt.IsZero() {
+		checkedAt := health.CheckedAt.UTC()
+		h.CheckedAt = &checkedAt
+	}
+	h.Error = health.Error
+}
+
+// HostMatchR lists the rules matching the host in evaluation order. WinnerRuleID is the rule applied to the host,
+// it is omitted if the host is bypassed or no rule matches. Proxy is what pac file returns for the host.
+type HostMatchR struct {
+	Host         string       `json:"host"`
+	Bypassed     bool         `json:"bypassed"`
+	WinnerRuleID int          `json:"winner_rule_id,omitempty"`
+	Proxy        string       `json:"proxy"`
+	Matches      []RuleMatchR `json:"matches"`
+}
+
+type RuleMatchR struct {
+	RuleID         int    `json:"rule_id"`
+	Regexp         string `json:"regexp,omitempty"`
+	Domain         string `json:"domain,omitempty"`
+	DomainUnicode  string `json:"domain_unicode,omitempty"`
+	Mode           string `json:"mode,omitempty"`
+	DomainListID   int    `json:"domain_list_id,omitempty"`
+	ProxyProfileID int    `json:"proxy_profile_id"`
+	Proxy          string `json:"proxy"`
+}
+
+func (m *HostMatchR) FromModel(match model.HostMatch) {
+	m.Host = match.Host
+	m.Bypassed = match.Bypassed
+	m.Proxy = match.Proxy
+	m.Matches = make([]RuleMatchR, 0, len(match.Matches))
+	for _, ruleMatch := range match.Matches {
+		ruleR := RuleR{}
+		ruleR.FromModel(ruleMatch.Rule)
+		m.Matches = append(m.Matches, RuleMatchR{
+			RuleID:         ruleR.ID,
+			Regexp:         ruleR.Regexp,
+			Domain:         ruleR.Domain,
+			DomainUnicode:  ruleR.DomainUnicode,
+			Mode:           ruleR.Mode,
+			DomainListID:   ruleR.DomainListID,
+			ProxyProfileID: ruleR.ProxyProfileID,
+			Proxy:          ruleMatch.Proxy,
+		})
+	}
+	if !match.Bypassed && len(m.Matches) > 0 {
+		m.WinnerRuleID = m.Matches[0].RuleID
+	}
+}
+
+// AuditEntryR is a change recorded in the audit log. Before is omitted for created entities and After for deleted
+// ones, EntityID is omitted for the bypass list.
+type AuditEntryR struct {
+	ID         int             `json:"id"`
+	Actor      string          `json:"actor"`
+	RequestID  string          `json:"request_id,omitempty"`
+	RemoteAddr string          `json:"remote_addr,omitempty"`
+	Entity     string          `json:"entity"`
+	EntityID   int             `json:"entity_id,omitempty"`
+	Action     string          `json:"action"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+func (e *AuditEntryR) FromModel(entry model.AuditEntry) {
+	e.ID = entry.ID
+	e.Actor = entry.Actor
+	e.RequestID = entry.RequestID
+	e.RemoteAddr = entry.RemoteAddr
+	e.Entity = entry.Entity
+	e.EntityID = entry.EntityID
+	e.Action = string(entry.Action)
+	if entry.Before != "" {
+		e.Before = json.RawMessage(entry.Before)
+	}
+	if entry.After != "" {
+		e.After = json.RawMessage(entry.After)
+	}
+	e.CreatedAt = entry.CreatedAt.UTC()
+}
+
+type VersionR struct {
+	ID           int       `json:"id"`
+	RuleCount    int       `json:"rule_count"`
+	ProfileCount int       `json:"profile_count"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (v *VersionR) FromModel(version model.ConfigVersion) {
+	v.ID = version.ID
+	v.RuleCount = version.RuleCount
+	v.ProfileCount = version.ProfileCount
+	v.CreatedAt = version.CreatedAt.UTC()
+}
+
+// VersionChangeR is a change of the entity between versions in the form of AuditEntryR.
+type VersionChangeR struct {
+	Entity   string          `json:"entity"`
+	EntityID int             `json:"entity_id,omitempty"`
+	Action   string          `json:"action"`
+	Before   json.RawMessage `json:"before,omitempty"`
+	After    json.RawMessage `json:"after,omitempty"`
+}
+
+func (c *VersionChangeR) FromModel(change model.VersionChange) {
+	c.Entity = change.Entity
+	c.EntityID = change.EntityID
+	c.Action = string(change.Action)
+	if change.Before != "" {
+		c.Before = json.RawMessage(change.Before)
+	}
+	if change.After != "" {
+		c.After = json.RawMessage(change.After)
+	}
+}
+
+func versionChanges(changes []model.VersionChange) []VersionChangeR {
+	changesR := make([]VersionChangeR, 0, len(changes))
+	for _, change := range changes {
+		changeR := VersionChangeR{}
+		changeR.FromModel(change)
+		changesR = append(changesR, changeR)
+	}
+	return changesR
+}
+
+type VersionDiffR struct {
+	From       int              `json:"from"`
+	To         int              `json:"to"`
+	PACChanged bool             `json:"pac_changed"`
+	Changes    []VersionChangeR `json:"changes"`
+}
+
+func (d *VersionDiffR) FromModel(diff model.VersionDiff) {
+	d.From = diff.From
+	d.To = diff.To
+	d.PACChanged = diff.PACChanged
+	d.Changes = versionChanges(diff.Changes)
+}
+
+// DraftR is the draft compared with the published version.
+type DraftR struct {
+	PublishedVersion int              `json:"published_version"`
+	Changes          []VersionChangeR `json:"changes"`
+	PACDiff          string           `json:"pac_diff"`
+	ApprovedBy       []string         `json:"approved_by"`
+}
+
+func (d *DraftR) FromModel(draft model.Draft) {
+	d.PublishedVersion = draft.Published
+	d.Changes = versionChanges(draft.Changes)
+	d.PACDiff = draft.PACDiff
+	d.ApprovedBy = draft.Approvers
+}
+
+// RollbackR lists the changes made by restoring the version.
+type RollbackR struct {
+	Version int              `json:"version"`
+	Changes []VersionChangeR `json:"changes"`
+}
+
+// UserR is the user without the password hash.
+type UserR struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (u *UserR) FromModel(user model.User) {
+	u.ID = user.ID
+	u.Name = user.Name
+	u.Role = user.Role.String()
+	u.CreatedAt = user.CreatedAt.UTC()
+	u.UpdatedAt = user.UpdatedAt.UTC()
+}
+
+// UserC holds the password in plain text, bcrypt takes up to 72 bytes of it.
+type UserC struct {
+	Name     string `json:"name" validate:"required,max=64,excludes=:"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+	Role     string `json:"role" validate:"required,oneof=viewer editor admin"`
+}
+
+func (u *UserC) ToModel() (model.User, error) {
+	role, err := model.ParseRole(u.Role)
+	if err != nil {
+		return model.User{}, &FieldError{Field: "role", Err: err}
+	}
+	return model.User{Name: u.Name, Role: role}, nil
+}
+
+// UserU keeps the password of the user unless a new one is given.
+type UserU struct {
+	Name     string `json:"name" validate:"required,max=64,excludes=:"`
+	Password string `json:"password" validate:"omitempty,min=8,max=72"`
+	Role     string `json:"role" validate:"required,oneof=viewer editor admin"`
+}
+
+func (u *UserU) ToModel() (model.User, error) {
+	role, err := model.ParseRole(u.Role)
+	if err != nil {
+		return model.User{}, &FieldError{Field: "role", Err: err}
+	}
+	return model.User{Name: u.Name, Role: role}, nil
+}
+
+// TokenR is the API token without its hash. Kind is personal or service, User is omitted for service tokens.
+type TokenR struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Kind       string     `json:"kind"`
+	User       string     `json:"user,omitempty"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (t *TokenR) FromModel(token model.APIToken) {
+	t.ID = token.ID
+	t.Name = token.Name
+	t.Kind = "personal"
+	if token.UserID == 0 {
+		t.Kind = "service"
+	}
+	t.User = token.UserName
+	t.Scopes = token.Scopes
+	t.ExpiresAt = truncateTime(token.ExpiresAt)
+	t.LastUsedAt = truncateTime(token.LastUsedAt)
+	t.CreatedAt = token.CreatedAt.UTC()
+}
+
+// TokenCreatedR is the created API token along with the token itself, which is never shown again.
+type TokenCreatedR struct {
+	TokenR
+	Token string `json:"token"`
+}
+
+// TokenC creates a personal token of the authenticated user, or a service token if Service is true.
+type TokenC struct {
+	Name      string     `json:"name" validate:"required,max=64"`
+	Scopes    []string   `json:"scopes" validate:"required,max=32,dive,required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	Service   bool       `json:"service"`
+}
+
+func (t *TokenC) ToModel() (model.APIToken, error) {
+	expiresAt := truncateTime(t.ExpiresAt)
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return model.APIToken{}, &FieldError{Field: "expires_at", Err: errors.New("must be in the future")}
+	}
+
+	scopes := make(model.Scopes, 0, len(t.Scopes))
+	for i, s := range t.Scopes {
+		scope, err := model.ParseScope(s)
+		if err != nil {
+			return model.APIToken{}, &FieldError{Field: fmt.Sprintf("scopes[%d]", i), Err: err}
+		}
+		if !contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	sort.Strings(scopes)
 
-	return model.ProxyProfile{
-		Name:    p.Name,
-		Type:    t,
-		Address: p.Address,
-	}, nil
+	return model.APIToken{Name: t.Name, Scopes: scopes, ExpiresAt: expiresAt}, nil
 }
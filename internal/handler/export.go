@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"bytes"
+	"github.com/go-chi/chi/v5"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/pkg/export"
+	"github.com/nnemirovsky/pacgen/pkg/rest"
+	"github.com/rs/zerolog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// SkippedRulesHeader lists ids of the rules that cannot be expressed in the requested export format.
+const SkippedRulesHeader = "X-Skipped-Rules"
+
+type ExportHandler struct {
+	logger  zerolog.Logger
+	service ExportService
+}
+
+func NewExportHandler(service ExportService, logger zerolog.Logger) *ExportHandler {
+	return &ExportHandler{
+		logger:  logger,
+		service: service,
+	}
+}
+
+func (h *ExportHandler) Serve(w http.ResponseWriter, r *http.Request) {
+	opts := export.Options{
+		PACURL:       rest.GetExternalURL(r, "/proxy.pac"),
+		DefaultProxy: r.URL.Query().Get("default_proxy"),
+	}
+
+	buf := bytes.Buffer{}
+	contentType, skipped, err := h.service.Export(r.Context(), chi.URLParam(r, "name"), opts, &buf)
+	if err != nil {
+		if _, ok := err.(*errs.EntityNotFoundError); ok {
+			h.logger.Debug().Err(err).Send()
+			Render(w, r, errorResponse(err), h.logger)
+			return
+		}
+		if err == errs.InvalidReferenceError {
+			h.logger.Debug().Err(err).Send()
+			Render(w, r, rest.BadRequestResponse("Unknown or unsupported default proxy"), h.logger)
+			return
+		}
+		h.logger.Error().Err(err).Msg("Error occurred while exporting rules")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return
+	}
+
+	ids := make([]string, 0, len(skipped))
+	for _, s := range skipped {
+		if s.RuleID != 0 {
+			ids = append(ids, strconv.Itoa(s.RuleID))
+		}
+	}
+	if len(ids) > 0 {
+		w.Header().Set(SkippedRulesHeader, strings.Join(ids, ","))
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	if _, err := buf.WriteTo(w); err != nil {
+		h.logger.Error().Err(err).Msg("Error occurred while writing exported rules")
+	}
+}
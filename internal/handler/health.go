@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"github.com/go-chi/render"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/pkg/rest"
+	"github.com/rs/zerolog"
+	"net/http"
+)
+
+type ProfileHealthHandler struct {
+	logger  zerolog.Logger
+	service ProfileHealthService
+}
+
+func NewProfileHealthHandler(service ProfileHealthService, logger zerolog.Logger) *ProfileHealthHandler {
+	return &ProfileHealthHandler{
+		logger:  logger,
+		service: service,
+	}
+}
+
+// Serve responds with the result of the last health check of the profile.
+func (h *ProfileHealthHandler) Serve(w http.ResponseWriter, r *http.Request) {
+	id, ok := getIDFromURL(w, r, h.logger)
+	if !ok {
+		return
+	}
+
+	health, err := h.service.Health(r.Context(), id)
+	if err != nil {
+		if _, ok := err.(*errs.EntityNotFoundError); ok {
+			h.logger.Debug().Err(err).Send()
+			Render(w, r, errorResponse(err), h.logger)
+			return
+		}
+		h.logger.Error().Err(err).Msg("Error occurred while getting proxy profile health")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return
+	}
+
+	healthR := ProfileHealthR{}
+	healthR.FromModel(health)
+
+	render.JSON(w, r, healthR)
+	w.WriteHeader(http.StatusOK)
+}
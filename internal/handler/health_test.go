@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"context"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/handler/mock"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/nnemirovsky/pacgen/pkg/logutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testPrepareProfileHealthHandler(t *testing.T) (*ProfileHealthHandler, *mock.ProfileHealthService) {
+	ctrl := gomock.NewController(t)
+	healthSrvcMock := mock.NewProfileHealthService(ctrl)
+
+	return NewProfileHealthHandler(healthSrvcMock, logutil.DiscardLogger), healthSrvcMock
+}
+
+func TestProfileHealthHandler_Serve_OK(t *testing.T) {
+	t.Parallel()
+
+	healthHandler, healthSrvcMock := testPrepareProfileHealthHandler(t)
+
+	health := model.ProfileHealth{
+		ProfileID: 1,
+		Status:    model.HealthUp,
+		Latency:   1500 * time.Microsecond,
+		CheckedAt: time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	want := `{"profile_id":1,"status":"up","latency_ms":1.5,"checked_at":"2022-10-01T12:00:00Z"}`
+
+	healthSrvcMock.EXPECT().Health(gomock.Any(), 1).Return(health, nil)
+
+	req, err := http.NewRequest(http.MethodGet, "/profiles/1/health", nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "1")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(healthHandler.Serve)
+
+	handler.ServeHTTP(rr, req)
+
+	got := strings.TrimSuffix(rr.Body.String(), "\n")
+
+	assert.Equal(t, rr.Code, http.StatusOK)
+
+	assert.Equal(t, got, want)
+}
+
+func TestProfileHealthHandler_Serve_NotFound(t *testing.T) {
+	t.Parallel()
+
+	healthHandler, healthSrvcMock := testPrepareProfileHealthHandler(t)
+
+	healthSrvcMock.EXPECT().Health(gomock.Any(), 1).Return(model.ProfileHealth{}, &errs.EntityNotFoundError{})
+
+	req, err := http.NewRequest(http.MethodGet, "/profiles/1/health", nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "1")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(healthHandler.Serve)
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, rr.Code, http.StatusNotFound)
+}
@@ -3,21 +3,97 @@ package handler
 import (
 	"context"
 	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/nnemirovsky/pacgen/pkg/export"
+	"io"
+	"time"
 )
 
 type ProxyProfileService interface {
-	GetAll(ctx context.Context) ([]model.ProxyProfile, error)
+	GetAll(ctx context.Context, filter model.ProxyProfileFilter) ([]model.ProxyProfile, int, error)
 	GetByID(ctx context.Context, id int) (model.ProxyProfile, error)
 	Create(ctx context.Context, profile *model.ProxyProfile) error
 	Update(ctx context.Context, profile model.ProxyProfile) error
-	Delete(ctx context.Context, id int) error
+	Patch(ctx context.Context, profile model.ProxyProfile, fields []string) error
+	Delete(ctx context.Context, id int, version int) error
 }
 
 type RuleService interface {
-	GetAll(ctx context.Context) ([]model.Rule, error)
+	GetAll(ctx context.Context, filter model.RuleFilter) ([]model.Rule, int, error)
 	GetAllWithProfiles(ctx context.Context) ([]model.Rule, error)
 	GetByID(ctx context.Context, id int) (model.Rule, error)
 	Create(ctx context.Context, rule *model.Rule) error
 	Update(ctx context.Context, rule model.Rule) error
+	Patch(ctx context.Context, rule model.Rule, fields []string) error
+	Delete(ctx context.Context, id int, version int) error
+	UpdateByTag(ctx context.Context, tag string, changes model.RuleBulkUpdate) (int, error)
+	DeleteByTag(ctx context.Context, tag string) (int, error)
+	GetExpiring(ctx context.Context, until time.Time) ([]model.Rule, error)
+}
+
+type RuleLinter interface {
+	Lint(ctx context.Context) ([]model.LintFinding, error)
+	Check(ctx context.Context, rule model.Rule) ([]model.LintFinding, error)
+}
+
+type HostMatcher interface {
+	Match(ctx context.Context, host string) (model.HostMatch, error)
+}
+
+type DomainListService interface {
+	GetAll(ctx context.Context) ([]model.DomainList, error)
+	GetByID(ctx context.Context, id int) (model.DomainList, error)
+	Create(ctx context.Context, list *model.DomainList) error
+	Update(ctx context.Context, list model.DomainList) error
+	Delete(ctx context.Context, id int) error
+}
+
+type BypassService interface {
+	GetAll(ctx context.Context) ([]model.BypassEntry, error)
+	Replace(ctx context.Context, entries []model.BypassEntry) error
+	Import(ctx context.Context, entries []model.BypassEntry) ([]model.BypassEntry, error)
+}
+
+type ProfileHealthService interface {
+	Health(ctx context.Context, id int) (model.ProfileHealth, error)
+}
+
+type ExportService interface {
+	Export(
+		ctx context.Context,
+		name string,
+		opts export.Options,
+		wr io.Writer,
+	) (contentType string, skipped []export.Skipped, err error)
+}
+
+type AuditService interface {
+	GetAll(ctx context.Context, filter model.AuditFilter) ([]model.AuditEntry, int, error)
+}
+
+type VersionService interface {
+	GetAll(ctx context.Context, page model.Page) ([]model.ConfigVersion, int, error)
+	GetByID(ctx context.Context, id int) (model.ConfigVersion, error)
+	Diff(ctx context.Context, from, to int) (model.VersionDiff, error)
+	Rollback(ctx context.Context, id int) ([]model.VersionChange, error)
+}
+
+type DraftService interface {
+	Get(ctx context.Context) (model.Draft, error)
+	Approve(ctx context.Context) error
+	Publish(ctx context.Context) (model.ConfigVersion, error)
+	Discard(ctx context.Context) (int, []model.VersionChange, error)
+}
+
+type UserService interface {
+	GetAll(ctx context.Context) ([]model.User, error)
+	GetByID(ctx context.Context, id int) (model.User, error)
+	Create(ctx context.Context, user *model.User, password string) error
+	Update(ctx context.Context, user model.User, password string) error
+	Delete(ctx context.Context, id int) error
+}
+
+type TokenService interface {
+	GetAll(ctx context.Context) ([]model.APIToken, error)
+	Create(ctx context.Context, token *model.APIToken, service bool) (string, error)
 	Delete(ctx context.Context, id int) error
 }
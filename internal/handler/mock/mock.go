@@ -6,10 +6,13 @@ package mock
 
 import (
 	context "context"
+	io "io"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	model "github.com/nnemirovsky/pacgen/internal/model"
+	export "github.com/nnemirovsky/pacgen/pkg/export"
 )
 
 // ProxyProfileService is a mock of ProxyProfileService interface.
@@ -50,32 +53,33 @@ func (mr *ProxyProfileServiceMockRecorder) Create(ctx, profile interface{}) *gom
 }
 
 // Delete mocks base method.
-func (m *ProxyProfileService) Delete(ctx context.Context, id int) error {
+func (m *ProxyProfileService) Delete(ctx context.Context, id, version int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", ctx, id)
+	ret := m.ctrl.Call(m, "Delete", ctx, id, version)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *ProxyProfileServiceMockRecorder) Delete(ctx, id interface{}) *gomock.Call {
+func (mr *ProxyProfileServiceMockRecorder) Delete(ctx, id, version interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*ProxyProfileService)(nil).Delete), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*ProxyProfileService)(nil).Delete), ctx, id, version)
 }
 
 // GetAll mocks base method.
-func (m *ProxyProfileService) GetAll(ctx context.Context) ([]model.ProxyProfile, error) {
+func (m *ProxyProfileService) GetAll(ctx context.Context, filter model.ProxyProfileFilter) ([]model.ProxyProfile, int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAll", ctx)
+	ret := m.ctrl.Call(m, "GetAll", ctx, filter)
 	ret0, _ := ret[0].([]model.ProxyProfile)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret1, _ := ret[1].(int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
 }
 
 // GetAll indicates an expected call of GetAll.
-func (mr *ProxyProfileServiceMockRecorder) GetAll(ctx interface{}) *gomock.Call {
+func (mr *ProxyProfileServiceMockRecorder) GetAll(ctx, filter interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*ProxyProfileService)(nil).GetAll), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*ProxyProfileService)(nil).GetAll), ctx, filter)
 }
 
 // GetByID mocks base method.
@@ -93,6 +97,20 @@ func (mr *ProxyProfileServiceMockRecorder) GetByID(ctx, id interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*ProxyProfileService)(nil).GetByID), ctx, id)
 }
 
+// Patch mocks base method.
+func (m *ProxyProfileService) Patch(ctx context.Context, profile model.ProxyProfile, fields []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Patch", ctx, profile, fields)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Patch indicates an expected call of Patch.
+func (mr *ProxyProfileServiceMockRecorder) Patch(ctx, profile, fields interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Patch", reflect.TypeOf((*ProxyProfileService)(nil).Patch), ctx, profile, fields)
+}
+
 // Update mocks base method.
 func (m *ProxyProfileService) Update(ctx context.Context, profile model.ProxyProfile) error {
 	m.ctrl.T.Helper()
@@ -145,32 +163,48 @@ func (mr *RuleServiceMockRecorder) Create(ctx, rule interface{}) *gomock.Call {
 }
 
 // Delete mocks base method.
-func (m *RuleService) Delete(ctx context.Context, id int) error {
+func (m *RuleService) Delete(ctx context.Context, id, version int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", ctx, id)
+	ret := m.ctrl.Call(m, "Delete", ctx, id, version)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *RuleServiceMockRecorder) Delete(ctx, id interface{}) *gomock.Call {
+func (mr *RuleServiceMockRecorder) Delete(ctx, id, version interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*RuleService)(nil).Delete), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*RuleService)(nil).Delete), ctx, id, version)
 }
 
-// GetAll mocks base method.
-func (m *RuleService) GetAll(ctx context.Context) ([]model.Rule, error) {
+// DeleteByTag mocks base method.
+func (m *RuleService) DeleteByTag(ctx context.Context, tag string) (int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAll", ctx)
-	ret0, _ := ret[0].([]model.Rule)
+	ret := m.ctrl.Call(m, "DeleteByTag", ctx, tag)
+	ret0, _ := ret[0].(int)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
+// DeleteByTag indicates an expected call of DeleteByTag.
+func (mr *RuleServiceMockRecorder) DeleteByTag(ctx, tag interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteByTag", reflect.TypeOf((*RuleService)(nil).DeleteByTag), ctx, tag)
+}
+
+// GetAll mocks base method.
+func (m *RuleService) GetAll(ctx context.Context, filter model.RuleFilter) ([]model.Rule, int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll", ctx, filter)
+	ret0, _ := ret[0].([]model.Rule)
+	ret1, _ := ret[1].(int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
 // GetAll indicates an expected call of GetAll.
-func (mr *RuleServiceMockRecorder) GetAll(ctx interface{}) *gomock.Call {
+func (mr *RuleServiceMockRecorder) GetAll(ctx, filter interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*RuleService)(nil).GetAll), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*RuleService)(nil).GetAll), ctx, filter)
 }
 
 // GetAllWithProfiles mocks base method.
@@ -203,6 +237,35 @@ func (mr *RuleServiceMockRecorder) GetByID(ctx, id interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*RuleService)(nil).GetByID), ctx, id)
 }
 
+// GetExpiring mocks base method.
+func (m *RuleService) GetExpiring(ctx context.Context, until time.Time) ([]model.Rule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExpiring", ctx, until)
+	ret0, _ := ret[0].([]model.Rule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetExpiring indicates an expected call of GetExpiring.
+func (mr *RuleServiceMockRecorder) GetExpiring(ctx, until interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExpiring", reflect.TypeOf((*RuleService)(nil).GetExpiring), ctx, until)
+}
+
+// Patch mocks base method.
+func (m *RuleService) Patch(ctx context.Context, rule model.Rule, fields []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Patch", ctx, rule, fields)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Patch indicates an expected call of Patch.
+func (mr *RuleServiceMockRecorder) Patch(ctx, rule, fields interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Patch", reflect.TypeOf((*RuleService)(nil).Patch), ctx, rule, fields)
+}
+
 // Update mocks base method.
 func (m *RuleService) Update(ctx context.Context, rule model.Rule) error {
 	m.ctrl.T.Helper()
@@ -216,3 +279,716 @@ func (mr *RuleServiceMockRecorder) Update(ctx, rule interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*RuleService)(nil).Update), ctx, rule)
 }
+
+// UpdateByTag mocks base method.
+func (m *RuleService) UpdateByTag(ctx context.Context, tag string, changes model.RuleBulkUpdate) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateByTag", ctx, tag, changes)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateByTag indicates an expected call of UpdateByTag.
+func (mr *RuleServiceMockRecorder) UpdateByTag(ctx, tag, changes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateByTag", reflect.TypeOf((*RuleService)(nil).UpdateByTag), ctx, tag, changes)
+}
+
+// RuleLinter is a mock of RuleLinter interface.
+type RuleLinter struct {
+	ctrl     *gomock.Controller
+	recorder *RuleLinterMockRecorder
+}
+
+// RuleLinterMockRecorder is the mock recorder for RuleLinter.
+type RuleLinterMockRecorder struct {
+	mock *RuleLinter
+}
+
+// NewRuleLinter creates a new mock instance.
+func NewRuleLinter(ctrl *gomock.Controller) *RuleLinter {
+	mock := &RuleLinter{ctrl: ctrl}
+	mock.recorder = &RuleLinterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *RuleLinter) EXPECT() *RuleLinterMockRecorder {
+	return m.recorder
+}
+
+// Check mocks base method.
+func (m *RuleLinter) Check(ctx context.Context, rule model.Rule) ([]model.LintFinding, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Check", ctx, rule)
+	ret0, _ := ret[0].([]model.LintFinding)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Check indicates an expected call of Check.
+func (mr *RuleLinterMockRecorder) Check(ctx, rule interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Check", reflect.TypeOf((*RuleLinter)(nil).Check), ctx, rule)
+}
+
+// Lint mocks base method.
+func (m *RuleLinter) Lint(ctx context.Context) ([]model.LintFinding, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Lint", ctx)
+	ret0, _ := ret[0].([]model.LintFinding)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Lint indicates an expected call of Lint.
+func (mr *RuleLinterMockRecorder) Lint(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Lint", reflect.TypeOf((*RuleLinter)(nil).Lint), ctx)
+}
+
+// HostMatcher is a mock of HostMatcher interface.
+type HostMatcher struct {
+	ctrl     *gomock.Controller
+	recorder *HostMatcherMockRecorder
+}
+
+// HostMatcherMockRecorder is the mock recorder for HostMatcher.
+type HostMatcherMockRecorder struct {
+	mock *HostMatcher
+}
+
+// NewHostMatcher creates a new mock instance.
+func NewHostMatcher(ctrl *gomock.Controller) *HostMatcher {
+	mock := &HostMatcher{ctrl: ctrl}
+	mock.recorder = &HostMatcherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *HostMatcher) EXPECT() *HostMatcherMockRecorder {
+	return m.recorder
+}
+
+// Match mocks base method.
+func (m *HostMatcher) Match(ctx context.Context, host string) (model.HostMatch, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Match", ctx, host)
+	ret0, _ := ret[0].(model.HostMatch)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Match indicates an expected call of Match.
+func (mr *HostMatcherMockRecorder) Match(ctx, host interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Match", reflect.TypeOf((*HostMatcher)(nil).Match), ctx, host)
+}
+
+// DomainListService is a mock of DomainListService interface.
+type DomainListService struct {
+	ctrl     *gomock.Controller
+	recorder *DomainListServiceMockRecorder
+}
+
+// DomainListServiceMockRecorder is the mock recorder for DomainListService.
+type DomainListServiceMockRecorder struct {
+	mock *DomainListService
+}
+
+// NewDomainListService creates a new mock instance.
+func NewDomainListService(ctrl *gomock.Controller) *DomainListService {
+	mock := &DomainListService{ctrl: ctrl}
+	mock.recorder = &DomainListServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *DomainListService) EXPECT() *DomainListServiceMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *DomainListService) Create(ctx context.Context, list *model.DomainList) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, list)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *DomainListServiceMockRecorder) Create(ctx, list interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*DomainListService)(nil).Create), ctx, list)
+}
+
+// Delete mocks base method.
+func (m *DomainListService) Delete(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *DomainListServiceMockRecorder) Delete(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*DomainListService)(nil).Delete), ctx, id)
+}
+
+// GetAll mocks base method.
+func (m *DomainListService) GetAll(ctx context.Context) ([]model.DomainList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll", ctx)
+	ret0, _ := ret[0].([]model.DomainList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAll indicates an expected call of GetAll.
+func (mr *DomainListServiceMockRecorder) GetAll(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*DomainListService)(nil).GetAll), ctx)
+}
+
+// GetByID mocks base method.
+func (m *DomainListService) GetByID(ctx context.Context, id int) (model.DomainList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByID", ctx, id)
+	ret0, _ := ret[0].(model.DomainList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByID indicates an expected call of GetByID.
+func (mr *DomainListServiceMockRecorder) GetByID(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*DomainListService)(nil).GetByID), ctx, id)
+}
+
+// Update mocks base method.
+func (m *DomainListService) Update(ctx context.Context, list model.DomainList) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, list)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *DomainListServiceMockRecorder) Update(ctx, list interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*DomainListService)(nil).Update), ctx, list)
+}
+
+// BypassService is a mock of BypassService interface.
+type BypassService struct {
+	ctrl     *gomock.Controller
+	recorder *BypassServiceMockRecorder
+}
+
+// BypassServiceMockRecorder is the mock recorder for BypassService.
+type BypassServiceMockRecorder struct {
+	mock *BypassService
+}
+
+// NewBypassService creates a new mock instance.
+func NewBypassService(ctrl *gomock.Controller) *BypassService {
+	mock := &BypassService{ctrl: ctrl}
+	mock.recorder = &BypassServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *BypassService) EXPECT() *BypassServiceMockRecorder {
+	return m.recorder
+}
+
+// GetAll mocks base method.
+func (m *BypassService) GetAll(ctx context.Context) ([]model.BypassEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll", ctx)
+	ret0, _ := ret[0].([]model.BypassEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAll indicates an expected call of GetAll.
+func (mr *BypassServiceMockRecorder) GetAll(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*BypassService)(nil).GetAll), ctx)
+}
+
+// Import mocks base method.
+func (m *BypassService) Import(ctx context.Context, entries []model.BypassEntry) ([]model.BypassEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Import", ctx, entries)
+	ret0, _ := ret[0].([]model.BypassEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Import indicates an expected call of Import.
+func (mr *BypassServiceMockRecorder) Import(ctx, entries interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Import", reflect.TypeOf((*BypassService)(nil).Import), ctx, entries)
+}
+
+// Replace mocks base method.
+func (m *BypassService) Replace(ctx context.Context, entries []model.BypassEntry) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Replace", ctx, entries)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Replace indicates an expected call of Replace.
+func (mr *BypassServiceMockRecorder) Replace(ctx, entries interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Replace", reflect.TypeOf((*BypassService)(nil).Replace), ctx, entries)
+}
+
+// ProfileHealthService is a mock of ProfileHealthService interface.
+type ProfileHealthService struct {
+	ctrl     *gomock.Controller
+	recorder *ProfileHealthServiceMockRecorder
+}
+
+// ProfileHealthServiceMockRecorder is the mock recorder for ProfileHealthService.
+type ProfileHealthServiceMockRecorder struct {
+	mock *ProfileHealthService
+}
+
+// NewProfileHealthService creates a new mock instance.
+func NewProfileHealthService(ctrl *gomock.Controller) *ProfileHealthService {
+	mock := &ProfileHealthService{ctrl: ctrl}
+	mock.recorder = &ProfileHealthServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *ProfileHealthService) EXPECT() *ProfileHealthServiceMockRecorder {
+	return m.recorder
+}
+
+// Health mocks base method.
+func (m *ProfileHealthService) Health(ctx context.Context, id int) (model.ProfileHealth, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Health", ctx, id)
+	ret0, _ := ret[0].(model.ProfileHealth)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Health indicates an expected call of Health.
+func (mr *ProfileHealthServiceMockRecorder) Health(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Health", reflect.TypeOf((*ProfileHealthService)(nil).Health), ctx, id)
+}
+
+// ExportService is a mock of ExportService interface.
+type ExportService struct {
+	ctrl     *gomock.Controller
+	recorder *ExportServiceMockRecorder
+}
+
+// ExportServiceMockRecorder is the mock recorder for ExportService.
+type ExportServiceMockRecorder struct {
+	mock *ExportService
+}
+
+// NewExportService creates a new mock instance.
+func NewExportService(ctrl *gomock.Controller) *ExportService {
+	mock := &ExportService{ctrl: ctrl}
+	mock.recorder = &ExportServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *ExportService) EXPECT() *ExportServiceMockRecorder {
+	return m.recorder
+}
+
+// Export mocks base method.
+func (m *ExportService) Export(ctx context.Context, name string, opts export.Options, wr io.Writer) (string, []export.Skipped, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Export", ctx, name, opts, wr)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].([]export.Skipped)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Export indicates an expected call of Export.
+func (mr *ExportServiceMockRecorder) Export(ctx, name, opts, wr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Export", reflect.TypeOf((*ExportService)(nil).Export), ctx, name, opts, wr)
+}
+
+// AuditService is a mock of AuditService interface.
+type AuditService struct {
+	ctrl     *gomock.Controller
+	recorder *AuditServiceMockRecorder
+}
+
+// AuditServiceMockRecorder is the mock recorder for AuditService.
+type AuditServiceMockRecorder struct {
+	mock *AuditService
+}
+
+// NewAuditService creates a new mock instance.
+func NewAuditService(ctrl *gomock.Controller) *AuditService {
+	mock := &AuditService{ctrl: ctrl}
+	mock.recorder = &AuditServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *AuditService) EXPECT() *AuditServiceMockRecorder {
+	return m.recorder
+}
+
+// GetAll mocks base method.
+func (m *AuditService) GetAll(ctx context.Context, filter model.AuditFilter) ([]model.AuditEntry, int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll", ctx, filter)
+	ret0, _ := ret[0].([]model.AuditEntry)
+	ret1, _ := ret[1].(int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetAll indicates an expected call of GetAll.
+func (mr *AuditServiceMockRecorder) GetAll(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*AuditService)(nil).GetAll), ctx, filter)
+}
+
+// VersionService is a mock of VersionService interface.
+type VersionService struct {
+	ctrl     *gomock.Controller
+	recorder *VersionServiceMockRecorder
+}
+
+// VersionServiceMockRecorder is the mock recorder for VersionService.
+type VersionServiceMockRecorder struct {
+	mock *VersionService
+}
+
+// NewVersionService creates a new mock instance.
+func NewVersionService(ctrl *gomock.Controller) *VersionService {
+	mock := &VersionService{ctrl: ctrl}
+	mock.recorder = &VersionServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *VersionService) EXPECT() *VersionServiceMockRecorder {
+	return m.recorder
+}
+
+// Diff mocks base method.
+func (m *VersionService) Diff(ctx context.Context, from, to int) (model.VersionDiff, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Diff", ctx, from, to)
+	ret0, _ := ret[0].(model.VersionDiff)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Diff indicates an expected call of Diff.
+func (mr *VersionServiceMockRecorder) Diff(ctx, from, to interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Diff", reflect.TypeOf((*VersionService)(nil).Diff), ctx, from, to)
+}
+
+// GetAll mocks base method.
+func (m *VersionService) GetAll(ctx context.Context, page model.Page) ([]model.ConfigVersion, int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll", ctx, page)
+	ret0, _ := ret[0].([]model.ConfigVersion)
+	ret1, _ := ret[1].(int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetAll indicates an expected call of GetAll.
+func (mr *VersionServiceMockRecorder) GetAll(ctx, page interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*VersionService)(nil).GetAll), ctx, page)
+}
+
+// GetByID mocks base method.
+func (m *VersionService) GetByID(ctx context.Context, id int) (model.ConfigVersion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByID", ctx, id)
+	ret0, _ := ret[0].(model.ConfigVersion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByID indicates an expected call of GetByID.
+func (mr *VersionServiceMockRecorder) GetByID(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*VersionService)(nil).GetByID), ctx, id)
+}
+
+// Rollback mocks base method.
+func (m *VersionService) Rollback(ctx context.Context, id int) ([]model.VersionChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rollback", ctx, id)
+	ret0, _ := ret[0].([]model.VersionChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Rollback indicates an expected call of Rollback.
+func (mr *VersionServiceMockRecorder) Rollback(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rollback", reflect.TypeOf((*VersionService)(nil).Rollback), ctx, id)
+}
+
+// DraftService is a mock of DraftService interface.
+type DraftService struct {
+	ctrl     *gomock.Controller
+	recorder *DraftServiceMockRecorder
+}
+
+// DraftServiceMockRecorder is the mock recorder for DraftService.
+type DraftServiceMockRecorder struct {
+	mock *DraftService
+}
+
+// NewDraftService creates a new mock instance.
+func NewDraftService(ctrl *gomock.Controller) *DraftService {
+	mock := &DraftService{ctrl: ctrl}
+	mock.recorder = &DraftServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *DraftService) EXPECT() *DraftServiceMockRecorder {
+	return m.recorder
+}
+
+// Approve mocks base method.
+func (m *DraftService) Approve(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Approve", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Approve indicates an expected call of Approve.
+func (mr *DraftServiceMockRecorder) Approve(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Approve", reflect.TypeOf((*DraftService)(nil).Approve), ctx)
+}
+
+// Discard mocks base method.
+func (m *DraftService) Discard(ctx context.Context) (int, []model.VersionChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Discard", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].([]model.VersionChange)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Discard indicates an expected call of Discard.
+func (mr *DraftServiceMockRecorder) Discard(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Discard", reflect.TypeOf((*DraftService)(nil).Discard), ctx)
+}
+
+// Get mocks base method.
+func (m *DraftService) Get(ctx context.Context) (model.Draft, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx)
+	ret0, _ := ret[0].(model.Draft)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *DraftServiceMockRecorder) Get(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*DraftService)(nil).Get), ctx)
+}
+
+// Publish mocks base method.
+func (m *DraftService) Publish(ctx context.Context) (model.ConfigVersion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Publish", ctx)
+	ret0, _ := ret[0].(model.ConfigVersion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Publish indicates an expected call of Publish.
+func (mr *DraftServiceMockRecorder) Publish(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Publish", reflect.TypeOf((*DraftService)(nil).Publish), ctx)
+}
+
+// UserService is a mock of UserService interface.
+type UserService struct {
+	ctrl     *gomock.Controller
+	recorder *UserServiceMockRecorder
+}
+
+// UserServiceMockRecorder is the mock recorder for UserService.
+type UserServiceMockRecorder struct {
+	mock *UserService
+}
+
+// NewUserService creates a new mock instance.
+func NewUserService(ctrl *gomock.Controller) *UserService {
+	mock := &UserService{ctrl: ctrl}
+	mock.recorder = &UserServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *UserService) EXPECT() *UserServiceMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *UserService) Create(ctx context.Context, user *model.User, password string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, user, password)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *UserServiceMockRecorder) Create(ctx, user, password interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*UserService)(nil).Create), ctx, user, password)
+}
+
+// Delete mocks base method.
+func (m *UserService) Delete(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *UserServiceMockRecorder) Delete(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*UserService)(nil).Delete), ctx, id)
+}
+
+// GetAll mocks base method.
+func (m *UserService) GetAll(ctx context.Context) ([]model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll", ctx)
+	ret0, _ := ret[0].([]model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAll indicates an expected call of GetAll.
+func (mr *UserServiceMockRecorder) GetAll(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*UserService)(nil).GetAll), ctx)
+}
+
+// GetByID mocks base method.
+func (m *UserService) GetByID(ctx context.Context, id int) (model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByID", ctx, id)
+	ret0, _ := ret[0].(model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByID indicates an expected call of GetByID.
+func (mr *UserServiceMockRecorder) GetByID(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*UserService)(nil).GetByID), ctx, id)
+}
+
+// Update mocks base method.
+func (m *UserService) Update(ctx context.Context, user model.User, password string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, user, password)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *UserServiceMockRecorder) Update(ctx, user, password interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*UserService)(nil).Update), ctx, user, password)
+}
+
+// TokenService is a mock of TokenService interface.
+type TokenService struct {
+	ctrl     *gomock.Controller
+	recorder *TokenServiceMockRecorder
+}
+
+// TokenServiceMockRecorder is the mock recorder for TokenService.
+type TokenServiceMockRecorder struct {
+	mock *TokenService
+}
+
+// NewTokenService creates a new mock instance.
+func NewTokenService(ctrl *gomock.Controller) *TokenService {
+	mock := &TokenService{ctrl: ctrl}
+	mock.recorder = &TokenServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *TokenService) EXPECT() *TokenServiceMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *TokenService) Create(ctx context.Context, token *model.APIToken, service bool) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, token, service)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *TokenServiceMockRecorder) Create(ctx, token, service interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*TokenService)(nil).Create), ctx, token, service)
+}
+
+// Delete mocks base method.
+func (m *TokenService) Delete(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *TokenServiceMockRecorder) Delete(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*TokenService)(nil).Delete), ctx, id)
+}
+
+// GetAll mocks base method.
+func (m *TokenService) GetAll(ctx context.Context) ([]model.APIToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll", ctx)
+	ret0, _ := ret[0].([]model.APIToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAll indicates an expected call of GetAll.
+func (mr *TokenServiceMockRecorder) GetAll(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*TokenService)(nil).GetAll), ctx)
+}
@@ -1,23 +1,58 @@
 package handler
 
 import (
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/pkg/rest"
 	"github.com/rs/zerolog"
+	"io"
 	"net/http"
+	"strconv"
 )
 
 type PACFileHandler struct {
 	logger   zerolog.Logger
+	versions VersionService
 	filePath string
 }
 
-func NewPACFileHandler(filePath string, logger zerolog.Logger) *PACFileHandler {
+func NewPACFileHandler(versions VersionService, filePath string, logger zerolog.Logger) *PACFileHandler {
 	return &PACFileHandler{
 		logger:   logger,
+		versions: versions,
 		filePath: filePath,
 	}
 }
 
+// Serve serves pac file, or pac file of the configuration version given in version query parameter.
 func (h *PACFileHandler) Serve(w http.ResponseWriter, r *http.Request) {
+	value := r.URL.Query().Get("version")
+	if value == "" {
+		w.Header().Set("Content-Type", "application/x-ns-proxy-autoconfig")
+		http.ServeFile(w, r, h.filePath)
+		return
+	}
+
+	id, err := strconv.Atoi(value)
+	if err != nil || id < 1 {
+		h.logger.Debug().Err(err).Str("version", value).Msg("Invalid version query parameter")
+		Render(w, r, rest.BadRequestResponse("Query parameter 'version' must be a positive integer"), h.logger)
+		return
+	}
+
+	version, err := h.versions.GetByID(r.Context(), id)
+	if err != nil {
+		if _, ok := err.(*errs.EntityNotFoundError); ok {
+			h.logger.Debug().Err(err).Send()
+			Render(w, r, errorResponse(err), h.logger)
+			return
+		}
+		h.logger.Error().Err(err).Msg("Error occurred while getting configuration version")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/x-ns-proxy-autoconfig")
-	http.ServeFile(w, r, h.filePath)
+	if _, err := io.WriteString(w, version.PAC); err != nil {
+		h.logger.Error().Err(err).Msg("Error occurred while writing pac file of configuration version")
+	}
 }
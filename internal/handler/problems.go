@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/pkg/rest"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Problem types identify the kind of error regardless of its message. Errors without a type of their own
+// are about:blank, their status code says it all.
+const (
+	ProblemValidation            = "urn:pacgen:problem:validation"
+	ProblemEntityNotFound        = "urn:pacgen:problem:entity-not-found"
+	ProblemEntityAlreadyExists   = "urn:pacgen:problem:entity-already-exists"
+	ProblemEntityStillReferenced = "urn:pacgen:problem:entity-still-referenced"
+	ProblemInvalidReference      = "urn:pacgen:problem:invalid-reference"
+	ProblemLintConflict          = "urn:pacgen:problem:lint-conflict"
+	ProblemVersionConflict       = "urn:pacgen:problem:version-conflict"
+	ProblemPreconditionRequired  = "urn:pacgen:problem:precondition-required"
+	ProblemApprovalRequired      = "urn:pacgen:problem:approval-required"
+	ProblemLastAdmin             = "urn:pacgen:problem:last-admin"
+)
+
+// invalidBody is the detail of the problems with field errors.
+const invalidBody = "Request body is invalid"
+
+// errorResponse converts the error of the service into the problem of its kind. Errors of unknown kinds
+// are internal errors.
+func errorResponse(err error) *rest.ErrorResponse {
+	var (
+		notFound      *errs.EntityNotFoundError
+		alreadyExists *errs.EntityAlreadyExistsError
+		referenced    *errs.EntityStillReferencedError
+		conflict      *errs.EntityVersionConflictError
+	)
+	switch {
+	case errors.As(err, &notFound):
+		return rest.NotFoundResponse(err.Error()).WithType(ProblemEntityNotFound)
+	case errors.As(err, &alreadyExists):
+		return rest.ConflictResponse(err.Error()).WithType(ProblemEntityAlreadyExists)
+	case errors.As(err, &referenced):
+		return rest.ConflictResponse(err.Error()).WithType(ProblemEntityStillReferenced)
+	case errors.As(err, &conflict):
+		return rest.PreconditionFailedResponse(err.Error()).WithType(ProblemVersionConflict)
+	case errors.Is(err, errs.InvalidReferenceError):
+		return rest.ConflictResponse(err.Error()).WithType(ProblemInvalidReference)
+	case errors.Is(err, errs.DraftNotApprovedError):
+		return rest.ConflictResponse(err.Error()).WithType(ProblemApprovalRequired)
+	case errors.Is(err, errs.LastAdminError):
+		return rest.ConflictResponse(err.Error()).WithType(ProblemLastAdmin)
+	case errors.Is(err, errs.TokenNotAllowedError):
+		return rest.ForbiddenResponse(err.Error())
+	default:
+		return rest.InternalErrorResponse()
+	}
+}
+
+// validationResponse is the problem with the given invalid fields of the request body.
+func validationResponse(fieldErrs ...rest.FieldError) *rest.ErrorResponse {
+	return rest.UnprocessableEntityResponse(invalidBody, fieldErrs...).WithType(ProblemValidation)
+}
+
+// fieldErrors translates the validation errors into the errors of the fields with their JSON paths.
+func fieldErrors(validationErrs validator.ValidationErrors) []rest.FieldError {
+	fieldErrs := make([]rest.FieldError, 0, len(validationErrs))
+	for _, e := range validationErrs {
+		fieldErrs = append(fieldErrs, rest.FieldError{
+			Field:   fieldPath(e.Namespace()),
+			Code:    e.Tag(),
+			Message: validationMessage(e),
+		})
+	}
+	return fieldErrs
+}
+
+// fieldPath converts the namespace of the field into its JSON path. The namespace starts with the name of
+// the validated struct and has the segments of embedded structs named after their types, both are dropped
+// since JSON names of the API are lowercase.
+func fieldPath(namespace string) string {
+	segments := strings.Split(namespace, ".")[1:]
+	path := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		if segment != "" && unicode.IsUpper(rune(segment[0])) {
+			continue
+		}
+		path = append(path, segment)
+	}
+	return strings.Join(path, ".")
+}
+
+// validationMessage describes the constraint the field does not satisfy.
+func validationMessage(e validator.FieldError) string {
+	param := e.Param()
+	switch e.Tag() {
+	case "required":
+		return "is required"
+	case "required_with":
+		return fmt.Sprintf("is required along with %s", jsonName(param))
+	case "required_without":
+		return fmt.Sprintf("is required unless %s is given", jsonName(param))
+	case "required_unless":
+		fields := strings.Fields(param)
+		values := make([]string, 0, len(fields)/2)
+		for i := 1; i < len(fields); i += 2 {
+			values = append(values, fields[i])
+		}
+		return fmt.Sprintf("is required unless %s is one of: %s", jsonName(fields[0]), strings.Join(values, ", "))
+	case "excluded_with":
+		return fmt.Sprintf("must not be given along with %s", jsonName(param))
+	case "oneof":
+		return "must be one of: " + strings.Join(strings.Fields(param), ", ")
+	case "unique":
+		return fmt.Sprintf("must not have items with the same %s", jsonName(param))
+	case "excludes":
+		return fmt.Sprintf("must not contain '%s'", param)
+	case "excludesall":
+		return "must not contain commas or spaces"
+	case "min", "max":
+		bound := "at least"
+		if e.Tag() == "max" {
+			bound = "at most"
+		}
+		switch e.Kind() {
+		case reflect.String:
+			return fmt.Sprintf("must be %s %s characters long", bound, param)
+		case reflect.Slice, reflect.Map, reflect.Array:
+			return fmt.Sprintf("must have %s %s items", bound, param)
+		default:
+			return fmt.Sprintf("must be %s %s", bound, param)
+		}
+	default:
+		return fmt.Sprintf("failed on the '%s' validation", e.Tag())
+	}
+}
+
+// jsonName converts the name of a struct field given in a validation tag into the JSON name of the field,
+// e.g. DomainListID into domain_list_id.
+func jsonName(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 &&
+			(unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// jsonType names the JSON type the values of the Go type are decoded from.
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return jsonType(t.Elem())
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Struct, reflect.Map:
+		if t == reflect.TypeOf(time.Time{}) {
+			return "a string"
+		}
+		return "an object"
+	default:
+		return "a number"
+	}
+}
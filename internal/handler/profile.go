@@ -3,6 +3,7 @@ package handler
 import (
 	"github.com/go-chi/render"
 	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/model"
 	"github.com/nnemirovsky/pacgen/pkg/rest"
 	"github.com/rs/zerolog"
 	"net/http"
@@ -11,31 +12,65 @@ import (
 type ProxyProfileHandler struct {
 	logger  zerolog.Logger
 	service ProxyProfileService
+	// requireIfMatch makes changes of a profile without If-Match header fail with 428 Precondition Required.
+	requireIfMatch bool
 }
 
-func NewProxyProfileHandler(service ProxyProfileService, logger zerolog.Logger) *ProxyProfileHandler {
+func NewProxyProfileHandler(service ProxyProfileService, requireIfMatch bool, logger zerolog.Logger) *ProxyProfileHandler {
 	return &ProxyProfileHandler{
-		logger:  logger,
-		service: service,
+		logger:         logger,
+		service:        service,
+		requireIfMatch: requireIfMatch,
 	}
 }
 
+// profileInclusions are the values profiles can be given along with.
+var profileInclusions = []string{"rule_count"}
+
+// GetAll responds with a page of the proxy profiles matching the filter given in the query.
 func (h *ProxyProfileHandler) GetAll(w http.ResponseWriter, r *http.Request) {
-	profiles, err := h.service.GetAll(r.Context())
+	filter := model.ProxyProfileFilter{}
+	var ok bool
+	if filter.MetadataFilter, filter.Sort, ok = getListQuery(w, r, h.logger, model.ProxyProfileSortFields); !ok {
+		return
+	}
+	if filter.Page, ok = getPage(w, r, h.logger, 0); !ok {
+		return
+	}
+	var fields []string
+	if fields, filter.Fields, ok = getFields(w, r, h.logger, profileFields); !ok {
+		return
+	}
+	include, ok := getRelated(w, r, h.logger, "include", profileInclusions)
+	if !ok {
+		return
+	}
+	filter.WithRuleCount = contains(include, "rule_count")
+	// Included values are kept by the projection.
+	if len(fields) != 0 {
+		fields = append(fields, include...)
+	}
+
+	profiles, total, err := h.service.GetAll(r.Context(), filter)
 	if err != nil {
 		h.logger.Error().Err(err).Msg("Error occurred while getting all proxy profiles")
-		w.WriteHeader(http.StatusInternalServerError)
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
 		return
 	}
 
 	profileEntities := make([]ProxyProfileR, 0)
 	for _, profile := range profiles {
-		profileR := ProxyProfileR{}
-		profileR.FromModel(profile)
-		profileEntities = append(profileEntities, profileR)
+		profileEntities = append(profileEntities, profileEntity(profile, filter.WithRuleCount))
+	}
+	projected, err := project(profileEntities, fields)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Error occurred while projecting proxy profiles")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return
 	}
 
-	render.JSON(w, r, profileEntities)
+	setPageHeaders(w, r, filter.Page, total)
+	render.JSON(w, r, projected)
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -45,25 +80,41 @@ func (h *ProxyProfileHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	include, ok := getRelated(w, r, h.logger, "include", profileInclusions)
+	if !ok {
+		return
+	}
+
 	profile, err := h.service.GetByID(r.Context(), id)
 	if err != nil {
 		if _, ok := err.(*errs.EntityNotFoundError); ok {
 			h.logger.Debug().Err(err).Send()
-			Render(w, r, rest.NotFoundResponse(err.Error()), h.logger)
+			Render(w, r, errorResponse(err), h.logger)
 			return
 		}
 		h.logger.Error().Err(err).Msg("Error occurred while getting proxy profile by id")
-		w.WriteHeader(http.StatusInternalServerError)
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
 		return
 	}
 
-	profileR := ProxyProfileR{}
-	profileR.FromModel(profile)
+	profileR := profileEntity(profile, contains(include, "rule_count"))
 
+	setETag(w, profile.Version)
 	render.JSON(w, r, profileR)
 	w.WriteHeader(http.StatusOK)
 }
 
+// profileEntity converts the profile into its entity, along with the number of its rules if it is included.
+func profileEntity(profile model.ProxyProfile, withRuleCount bool) ProxyProfileR {
+	profileR := ProxyProfileR{}
+	profileR.FromModel(profile)
+	if withRuleCount {
+		count := profile.RuleCount
+		profileR.RuleCount = &count
+	}
+	return profileR
+}
+
 func (h *ProxyProfileHandler) Create(w http.ResponseWriter, r *http.Request) {
 	profileCU := ProxyProfileCU{}
 	if ok := getFromBodyAndValidate(w, r, h.logger, &profileCU); !ok {
@@ -72,19 +123,27 @@ func (h *ProxyProfileHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 	profileModel, err := profileCU.ToModel()
 	if err != nil {
-		h.logger.Debug().Err(err).Msg("Error occurred while converting profile entity to corresponding model")
-		w.WriteHeader(http.StatusUnprocessableEntity)
+		renderConversionError(w, r, h.logger, err)
 		return
 	}
 
+	if profileModel.Owner == "" {
+		profileModel.Owner = requestUser(r)
+	}
+
 	if err := h.service.Create(r.Context(), &profileModel); err != nil {
+		if err == errs.InvalidReferenceError {
+			h.logger.Debug().Err(err).Send()
+			Render(w, r, errorResponse(err), h.logger)
+			return
+		}
 		if _, ok := err.(*errs.EntityAlreadyExistsError); ok {
 			h.logger.Debug().Err(err).Send()
-			Render(w, r, rest.ConflictResponse(err.Error()), h.logger)
+			Render(w, r, errorResponse(err), h.logger)
 			return
 		}
 		h.logger.Error().Err(err).Msg("Error occurred while creating profile")
-		w.WriteHeader(http.StatusInternalServerError)
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
 		return
 	}
 
@@ -97,6 +156,11 @@ func (h *ProxyProfileHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	version, ok := getIfMatch(w, r, h.logger, h.requireIfMatch)
+	if !ok {
+		return
+	}
+
 	profile := ProxyProfileCU{}
 	if ok := getFromBodyAndValidate(w, r, h.logger, &profile); !ok {
 		return
@@ -104,29 +168,115 @@ func (h *ProxyProfileHandler) Update(w http.ResponseWriter, r *http.Request) {
 
 	profileModel, err := profile.ToModel()
 	if err != nil {
-		h.logger.Debug().Err(err).Msg("Error occurred while converting profile entity to corresponding model")
-		w.WriteHeader(http.StatusUnprocessableEntity)
+		renderConversionError(w, r, h.logger, err)
 		return
 	}
 	profileModel.ID = id
+	profileModel.Version = version
 
 	if err := h.service.Update(r.Context(), profileModel); err != nil {
+		if err == errs.InvalidReferenceError {
+			h.logger.Debug().Err(err).Send()
+			Render(w, r, errorResponse(err), h.logger)
+			return
+		}
 		switch err.(type) {
 		case *errs.EntityAlreadyExistsError:
 			h.logger.Debug().Err(err).Send()
-			Render(w, r, rest.ConflictResponse(err.Error()), h.logger)
+			Render(w, r, errorResponse(err), h.logger)
 			return
-		case *errs.EntityNotFoundError:
+		case *errs.EntityNotFoundError, *errs.EntityVersionConflictError:
 			h.logger.Debug().Err(err).Send()
-			Render(w, r, rest.NotFoundResponse(err.Error()), h.logger)
+			Render(w, r, errorResponse(err), h.logger)
 			return
 		default:
 			h.logger.Error().Err(err).Msg("Error occurred while updating profile")
-			w.WriteHeader(http.StatusInternalServerError)
+			Render(w, r, rest.InternalErrorResponse(), h.logger)
+			return
+		}
+	}
+
+	if version != 0 {
+		setETag(w, version+1)
+	}
+	render.NoContent(w, r)
+}
+
+// Patch applies JSON merge patch (RFC 7396) to the profile. Only the supplied fields are validated and updated.
+func (h *ProxyProfileHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	id, ok := getIDFromURL(w, r, h.logger)
+	if !ok {
+		return
+	}
+
+	version, ok := getIfMatch(w, r, h.logger, h.requireIfMatch)
+	if !ok {
+		return
+	}
+
+	patch, ok := getPatch(w, r, h.logger, profilePatchFields)
+	if !ok {
+		return
+	}
+
+	current, err := h.service.GetByID(r.Context(), id)
+	if err != nil {
+		if _, ok := err.(*errs.EntityNotFoundError); ok {
+			h.logger.Debug().Err(err).Send()
+			Render(w, r, errorResponse(err), h.logger)
 			return
 		}
+		h.logger.Error().Err(err).Msg("Error occurred while getting profile to patch")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return
+	}
+
+	if version != 0 && version != current.Version {
+		err = &errs.EntityVersionConflictError{Name: "proxy profile", Key: "id", Value: id, Version: current.Version}
+		h.logger.Debug().Err(err).Send()
+		Render(w, r, errorResponse(err), h.logger)
+		return
+	}
+
+	currentR := ProxyProfileCU{}
+	currentR.FromModel(current)
+	profile, ok := applyPatch(w, r, h.logger, currentR, patch)
+	if !ok {
+		return
 	}
 
+	profileModel, err := profile.ToModel()
+	if err != nil {
+		renderConversionError(w, r, h.logger, err)
+		return
+	}
+	profileModel.ID = id
+	// The patch is merged into the current profile, so it is applied to the current version only.
+	profileModel.Version = current.Version
+
+	if err := h.service.Patch(r.Context(), profileModel, patchedFields(patch, profilePatchFields)); err != nil {
+		if err == errs.InvalidReferenceError {
+			h.logger.Debug().Err(err).Send()
+			Render(w, r, errorResponse(err), h.logger)
+			return
+		}
+		switch err.(type) {
+		case *errs.EntityAlreadyExistsError:
+			h.logger.Debug().Err(err).Send()
+			Render(w, r, errorResponse(err), h.logger)
+			return
+		case *errs.EntityNotFoundError, *errs.EntityVersionConflictError:
+			h.logger.Debug().Err(err).Send()
+			Render(w, r, errorResponse(err), h.logger)
+			return
+		default:
+			h.logger.Error().Err(err).Msg("Error occurred while patching profile")
+			Render(w, r, rest.InternalErrorResponse(), h.logger)
+			return
+		}
+	}
+
+	setETag(w, current.Version+1)
 	render.NoContent(w, r)
 }
 
@@ -136,19 +286,24 @@ func (h *ProxyProfileHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.service.Delete(r.Context(), id); err != nil {
+	version, ok := getIfMatch(w, r, h.logger, h.requireIfMatch)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(r.Context(), id, version); err != nil {
 		switch err.(type) {
-		case *errs.EntityNotFoundError:
+		case *errs.EntityNotFoundError, *errs.EntityVersionConflictError:
 			h.logger.Debug().Err(err).Send()
-			Render(w, r, rest.NotFoundResponse(err.Error()), h.logger)
+			Render(w, r, errorResponse(err), h.logger)
 			return
 		case *errs.EntityStillReferencedError:
 			h.logger.Debug().Err(err).Send()
-			Render(w, r, rest.ConflictResponse(err.Error()), h.logger)
+			Render(w, r, errorResponse(err), h.logger)
 			return
 		default:
 			h.logger.Error().Err(err).Msg("Error occurred while deleting profile")
-			w.WriteHeader(http.StatusInternalServerError)
+			Render(w, r, rest.InternalErrorResponse(), h.logger)
 			return
 		}
 	}
@@ -2,6 +2,8 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/assert/v2"
 	"github.com/golang/mock/gomock"
@@ -13,13 +15,14 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 func testPrepareProfileHandler(t *testing.T) (*ProxyProfileHandler, *mock.ProxyProfileService) {
 	ctrl := gomock.NewController(t)
 	profileSrvcMock := mock.NewProxyProfileService(ctrl)
 
-	return NewProxyProfileHandler(profileSrvcMock, logutil.DiscardLogger), profileSrvcMock
+	return NewProxyProfileHandler(profileSrvcMock, false, logutil.DiscardLogger), profileSrvcMock
 }
 
 func TestProxyProfileHandler_GetAll_OK(t *testing.T) {
@@ -29,23 +32,33 @@ func TestProxyProfileHandler_GetAll_OK(t *testing.T) {
 
 	profiles := []model.ProxyProfile{
 		{
-			ID:      1,
-			Name:    "shadowsocks",
-			Type:    model.Socks5,
-			Address: "localhost:1080",
+			ID:   1,
+			Name: "shadowsocks",
+			Type: model.Socks5,
+			Host: "localhost",
+			Port: 1080,
 		},
 		{
-			ID:      2,
-			Name:    "some http proxy",
-			Type:    model.Http,
-			Address: "::1:8080",
+			ID:   2,
+			Name: "some http proxy",
+			Type: model.Http,
+			Host: "::1",
+			Port: 8080,
+		},
+		{
+			ID:      3,
+			Name:    "egress",
+			Type:    model.Pool,
+			Members: []model.PoolMember{{ProfileID: 1, Weight: 3}, {ProfileID: 2, Weight: 1}},
 		},
 	}
 
 	want := `[{"id":1,"name":"shadowsocks","type":"SOCKS5","address":"localhost:1080"},` +
-		`{"id":2,"name":"some http proxy","type":"HTTP","address":"::1:8080"}]`
+		`{"id":2,"name":"some http proxy","type":"HTTP","address":"[::1]:8080"},` +
+		`{"id":3,"name":"egress","type":"POOL","address":"","members":[{"profile_id":1,"weight":3},{"profile_id":2,"weight":1}]}]`
 
-	profileSrvcMock.EXPECT().GetAll(gomock.Any()).Return(profiles, nil)
+	profileSrvcMock.EXPECT().GetAll(gomock.Any(), model.ProxyProfileFilter{}).
+		Return(profiles, 3, nil)
 
 	req, err := http.NewRequest(http.MethodGet, "/rules", nil)
 	if err != nil {
@@ -64,12 +77,132 @@ func TestProxyProfileHandler_GetAll_OK(t *testing.T) {
 	assert.Equal(t, got, want)
 }
 
+func TestProxyProfileHandler_GetAll_Sorted(t *testing.T) {
+	t.Parallel()
+
+	profileHandler, profileSrvcMock := testPrepareProfileHandler(t)
+
+	updated := time.Date(2022, 9, 2, 8, 30, 0, 0, time.UTC)
+	profiles := []model.ProxyProfile{
+		{
+			ID:       1,
+			Name:     "tor",
+			Type:     model.Socks5,
+			Host:     "localhost",
+			Port:     9050,
+			Metadata: model.Metadata{Owner: "bob", UpdatedAt: &updated},
+		},
+	}
+
+	want := `[{"id":1,"name":"tor","type":"SOCKS5","address":"localhost:9050","owner":"bob",` +
+		`"updated_at":"2022-09-02T08:30:00Z"}]`
+
+	filter := model.ProxyProfileFilter{
+		MetadataFilter: model.MetadataFilter{Owner: "bob"},
+		Sort:           model.Sort{Field: "name"},
+	}
+	profileSrvcMock.EXPECT().GetAll(gomock.Any(), filter).Return(profiles, 1, nil)
+
+	req, err := http.NewRequest(http.MethodGet, "/profiles?owner=bob&sort=name", nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(profileHandler.GetAll)
+
+	handler.ServeHTTP(rr, req)
+
+	got := strings.TrimSuffix(rr.Body.String(), "\n")
+
+	assert.Equal(t, rr.Code, http.StatusOK)
+
+	assert.Equal(t, got, want)
+}
+
+func TestProxyProfileHandler_GetAll_Projected(t *testing.T) {
+	t.Parallel()
+
+	profileHandler, profileSrvcMock := testPrepareProfileHandler(t)
+
+	profiles := []model.ProxyProfile{
+		{ID: 2, Type: model.Http},
+		{ID: 3, Type: model.Pool, Members: []model.PoolMember{{ProfileID: 2, Weight: 1}}},
+	}
+
+	want := `[{"address":""},{"address":"","members":[{"profile_id":2,"weight":1}]}]`
+
+	filter := model.ProxyProfileFilter{
+		Page:   model.Page{Limit: 2, Offset: 1},
+		Fields: []string{"address", "id", "type", "members"},
+	}
+	profileSrvcMock.EXPECT().GetAll(gomock.Any(), filter).Return(profiles, 3, nil)
+
+	req, err := http.NewRequest(http.MethodGet, "/profiles?fields=address,members&limit=2&offset=1", nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(profileHandler.GetAll)
+
+	handler.ServeHTTP(rr, req)
+
+	got := strings.TrimSuffix(rr.Body.String(), "\n")
+
+	assert.Equal(t, rr.Code, http.StatusOK)
+
+	assert.Equal(t, got, want)
+
+	assert.Equal(t, rr.Header().Get("X-Total-Count"), "3")
+	assert.Equal(t, rr.Header().Get("Link"), `</profiles?fields=address%2Cmembers&limit=2&offset=0>; rel="first", `+
+		`</profiles?fields=address%2Cmembers&limit=2&offset=0>; rel="prev", `+
+		`</profiles?fields=address%2Cmembers&limit=2&offset=2>; rel="last"`)
+}
+
+func TestProxyProfileHandler_GetAll_Unpaged(t *testing.T) {
+	t.Parallel()
+
+	profileHandler, profileSrvcMock := testPrepareProfileHandler(t)
+
+	// More profiles than a page of the audit log holds are all returned without limit in the query.
+	profiles := make([]model.ProxyProfile, 0, 150)
+	for i := 1; i <= 150; i++ {
+		profiles = append(profiles, model.ProxyProfile{ID: i, Name: fmt.Sprintf("proxy-%d", i), Type: model.Direct})
+	}
+
+	profileSrvcMock.EXPECT().GetAll(gomock.Any(), model.ProxyProfileFilter{}).Return(profiles, 150, nil)
+
+	req, err := http.NewRequest(http.MethodGet, "/profiles", nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(profileHandler.GetAll)
+
+	handler.ServeHTTP(rr, req)
+
+	var got []ProxyProfileR
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, rr.Code, http.StatusOK)
+
+	assert.Equal(t, len(got), 150)
+
+	assert.Equal(t, rr.Header().Get("X-Total-Count"), "150")
+	assert.Equal(t, rr.Header().Get("Link"), "")
+}
+
 func TestProxyProfileHandler_GetAll_InternalServerError(t *testing.T) {
 	t.Parallel()
 
 	profileHandler, profileSrvcMock := testPrepareProfileHandler(t)
 
-	profileSrvcMock.EXPECT().GetAll(gomock.Any()).Return(nil, errs.ServiceUnknownError)
+	profileSrvcMock.EXPECT().GetAll(gomock.Any(), model.ProxyProfileFilter{}).
+		Return(nil, 0, errs.ServiceUnknownError)
 
 	req, err := http.NewRequest(http.MethodGet, "/rules", nil)
 	if err != nil {
@@ -90,11 +223,13 @@ func TestProxyProfileHandler_GetByID_OK(t *testing.T) {
 	profileHandler, profileSrvcMock := testPrepareProfileHandler(t)
 
 	profile := model.ProxyProfile{
-		ID:      1,
-		Name:    "shadowsocks",
-		Type:    model.Socks5,
-		Address: "localhost:1080",
+		ID:   1,
+		Name: "shadowsocks",
+		Type: model.Socks5,
+		Host: "localhost",
+		Port: 1080,
 	}
+	profile.Version = 4
 
 	want := `{"id":1,"name":"shadowsocks","type":"SOCKS5","address":"localhost:1080"}`
 
@@ -119,6 +254,68 @@ func TestProxyProfileHandler_GetByID_OK(t *testing.T) {
 	assert.Equal(t, rr.Code, http.StatusOK)
 
 	assert.Equal(t, got, want)
+
+	assert.Equal(t, rr.Header().Get("ETag"), `"4"`)
+}
+
+func TestProxyProfileHandler_GetByID_RuleCount(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		target     string
+		want       string
+		wantStatus int
+	}{
+		"included": {
+			target:     "/profiles/1?include=rule_count",
+			want:       `{"id":1,"name":"squid","type":"HTTP","address":"localhost:3128","rule_count":0}`,
+			wantStatus: http.StatusOK,
+		},
+		"not included": {
+			target:     "/profiles/1",
+			want:       `{"id":1,"name":"squid","type":"HTTP","address":"localhost:3128"}`,
+			wantStatus: http.StatusOK,
+		},
+		"unknown": {
+			target: "/profiles/1?include=rules",
+			want: `{"type":"about:blank","title":"Bad Request","status":400,` +
+				`"detail":"Query parameter 'include' must be a comma separated list of: rule_count",` +
+				`"instance":"/profiles/1"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			profileHandler, profileSrvcMock := testPrepareProfileHandler(t)
+
+			profile := model.ProxyProfile{ID: 1, Name: "squid", Type: model.Http, Host: "localhost", Port: 3128}
+			profileSrvcMock.EXPECT().GetByID(gomock.Any(), 1).Return(profile, nil).AnyTimes()
+
+			req, err := http.NewRequest(http.MethodGet, c.target, nil)
+			if err != nil {
+				t.Errorf("Unexpected error: %#v", err)
+			}
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "1")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(profileHandler.GetByID)
+
+			handler.ServeHTTP(rr, req)
+
+			got := strings.TrimSuffix(rr.Body.String(), "\n")
+
+			assert.Equal(t, rr.Code, c.wantStatus)
+
+			assert.Equal(t, got, c.want)
+		})
+	}
 }
 
 func TestProxyProfileHandler_GetByID_NotFound(t *testing.T) {
@@ -175,9 +372,10 @@ func TestProxyProfileHandler_Create_OK(t *testing.T) {
 	profileHandler, profileSrvcMock := testPrepareProfileHandler(t)
 
 	profile := model.ProxyProfile{
-		Name:    "shadowsocks",
-		Type:    model.Socks5,
-		Address: "localhost:1080",
+		Name: "shadowsocks",
+		Type: model.Socks5,
+		Host: "localhost",
+		Port: 1080,
 	}
 
 	profileSrvcMock.EXPECT().Create(gomock.Any(), &profile).DoAndReturn(
@@ -206,17 +404,98 @@ func TestProxyProfileHandler_Create_OK(t *testing.T) {
 	assert.Equal(t, rr.Header().Get("Location"), "http://localhost/rules/17")
 }
 
+func TestProxyProfileHandler_Create_Direct_OK(t *testing.T) {
+	t.Parallel()
+
+	profileHandler, profileSrvcMock := testPrepareProfileHandler(t)
+
+	profile := model.ProxyProfile{
+		Name: "intranet",
+		Type: model.Direct,
+	}
+
+	profileSrvcMock.EXPECT().Create(gomock.Any(), &profile).DoAndReturn(
+		func(ctx context.Context, p *model.ProxyProfile) error {
+			p.ID = 18
+			return nil
+		},
+	)
+
+	body := `{"name":"intranet","type":"DIRECT"}`
+	req, err := http.NewRequest(http.MethodPost, "http://localhost/rules", strings.NewReader(body))
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(profileHandler.Create)
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, rr.Code, http.StatusCreated)
+	assert.Equal(t, rr.Header().Get("Location"), "http://localhost/rules/18")
+}
+
+func TestProxyProfileHandler_Create_Pool_OK(t *testing.T) {
+	t.Parallel()
+
+	profileHandler, profileSrvcMock := testPrepareProfileHandler(t)
+
+	profile := model.ProxyProfile{
+		Name:    "egress",
+		Type:    model.Pool,
+		Members: []model.PoolMember{{ProfileID: 1, Weight: 3}, {ProfileID: 2, Weight: 1}},
+	}
+
+	profileSrvcMock.EXPECT().Create(gomock.Any(), &profile).DoAndReturn(
+		func(ctx context.Context, p *model.ProxyProfile) error {
+			p.ID = 19
+			return nil
+		},
+	)
+
+	body := `{"name":"egress","type":"POOL","members":[{"profile_id":1,"weight":3},{"profile_id":2}]}`
+	req, err := http.NewRequest(http.MethodPost, "http://localhost/profiles", strings.NewReader(body))
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(profileHandler.Create)
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, rr.Code, http.StatusCreated)
+	assert.Equal(t, rr.Header().Get("Location"), "http://localhost/profiles/19")
+}
+
 func TestProxyProfileHandler_Create_UnprocessableEntity(t *testing.T) {
 	t.Parallel()
 
 	profileHandler, _ := testPrepareProfileHandler(t)
 
 	cases := map[string]string{
-		"missing address": `{"name":"shadowsocks","type":"SOCKS5"}`,
-		"invalid type":    `{"name":"shadowsocks","type":"qwerty","address":"localhost:1080"}`,
+		"missing address":      `{"name":"shadowsocks","type":"SOCKS5"}`,
+		"invalid type":         `{"name":"shadowsocks","type":"qwerty","address":"localhost:1080"}`,
+		"direct with address":  `{"name":"direct","type":"DIRECT","address":"localhost:1080"}`,
+		"pool with address":    `{"name":"egress","type":"POOL","address":"localhost:1080","members":[{"profile_id":1}]}`,
+		"pool without members": `{"name":"egress","type":"POOL"}`,
+		"members of non-pool":  `{"name":"socks","type":"SOCKS5","address":"localhost:1080","members":[{"profile_id":1}]}`,
+		"duplicate member":     `{"name":"egress","type":"POOL","members":[{"profile_id":1},{"profile_id":1}]}`,
+		"invalid weight":       `{"name":"egress","type":"POOL","members":[{"profile_id":1,"weight":101}]}`,
+		"address without port": `{"name":"socks","type":"SOCKS5","address":"localhost"}`,
+		"unbracketed ipv6":     `{"name":"socks","type":"SOCKS5","address":"::1:1080"}`,
+		"address with scheme":  `{"name":"socks","type":"SOCKS5","address":"socks5://localhost:1080"}`,
+		"address with path":    `{"name":"socks","type":"SOCKS5","address":"localhost:1080/socks"}`,
+		"port out of range":    `{"name":"socks","type":"SOCKS5","address":"localhost:65536"}`,
 	}
 
 	for name, body := range cases {
+		body := body
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
 
@@ -243,9 +522,10 @@ func TestProxyProfileHandler_Create_Conflict(t *testing.T) {
 	profileHandler, profileSrvcMock := testPrepareProfileHandler(t)
 
 	profile := model.ProxyProfile{
-		Name:    "shadowsocks",
-		Type:    model.Socks5,
-		Address: "localhost:1080",
+		Name: "shadowsocks",
+		Type: model.Socks5,
+		Host: "localhost",
+		Port: 1080,
 	}
 
 	profileSrvcMock.EXPECT().Create(gomock.Any(), &profile).Return(&errs.EntityAlreadyExistsError{})
@@ -267,15 +547,48 @@ func TestProxyProfileHandler_Create_Conflict(t *testing.T) {
 	assert.Equal(t, rr.Code, http.StatusConflict)
 }
 
+func TestProxyProfileHandler_Create_InvalidStandby(t *testing.T) {
+	t.Parallel()
+
+	profileHandler, profileSrvcMock := testPrepareProfileHandler(t)
+
+	profile := model.ProxyProfile{
+		Name:      "shadowsocks",
+		Type:      model.Socks5,
+		Host:      "localhost",
+		Port:      1080,
+		StandbyID: 42,
+	}
+
+	profileSrvcMock.EXPECT().Create(gomock.Any(), &profile).Return(errs.InvalidReferenceError)
+
+	body := `{"name":"shadowsocks","type":"SOCKS5","address":"localhost:1080","standby_profile_id":42}`
+
+	req, err := http.NewRequest(http.MethodPost, "/profiles", strings.NewReader(body))
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(profileHandler.Create)
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, rr.Code, http.StatusConflict)
+}
+
 func TestProxyProfileHandler_Create_InternalServerError(t *testing.T) {
 	t.Parallel()
 
 	profileHandler, profileSrvcMock := testPrepareProfileHandler(t)
 
 	profile := model.ProxyProfile{
-		Name:    "shadowsocks",
-		Type:    model.Socks5,
-		Address: "localhost:1080",
+		Name: "shadowsocks",
+		Type: model.Socks5,
+		Host: "localhost",
+		Port: 1080,
 	}
 
 	profileSrvcMock.EXPECT().Create(gomock.Any(), &profile).Return(errs.ServiceUnknownError)
@@ -303,10 +616,11 @@ func TestProxyProfileHandler_Update_OK(t *testing.T) {
 	profileHandler, profileSrvcMock := testPrepareProfileHandler(t)
 
 	profile := model.ProxyProfile{
-		ID:      1,
-		Name:    "shadowsocks",
-		Type:    model.Socks5,
-		Address: "localhost:1080",
+		ID:   1,
+		Name: "shadowsocks",
+		Type: model.Socks5,
+		Host: "localhost",
+		Port: 1080,
 	}
 
 	profileSrvcMock.EXPECT().Update(gomock.Any(), profile).Return(nil)
@@ -332,6 +646,159 @@ func TestProxyProfileHandler_Update_OK(t *testing.T) {
 	assert.Equal(t, rr.Code, http.StatusNoContent)
 }
 
+func TestProxyProfileHandler_Patch_OK(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		current model.ProxyProfile
+		body    string
+		want    model.ProxyProfile
+		fields  []string
+	}{
+		"type": {
+			current: model.ProxyProfile{ID: 12, Name: "ss", Type: model.Socks5, Host: "127.0.0.1", Port: 1080,
+				Metadata: model.Metadata{Owner: "alice"}},
+			body: `{"type":"SOCKS4"}`,
+			want: model.ProxyProfile{ID: 12, Name: "ss", Type: model.Socks4, Host: "127.0.0.1", Port: 1080,
+				Metadata: model.Metadata{Owner: "alice"}},
+			fields: []string{"type"},
+		},
+		"proxy to pool": {
+			current: model.ProxyProfile{ID: 12, Name: "ss", Type: model.Socks5, Host: "127.0.0.1", Port: 1080},
+			body:    `{"type":"POOL","address":null,"members":[{"profile_id":3},{"profile_id":4,"weight":2}]}`,
+			want: model.ProxyProfile{ID: 12, Name: "ss", Type: model.Pool, Members: []model.PoolMember{
+				{ProfileID: 3, Weight: 1}, {ProfileID: 4, Weight: 2},
+			}},
+			fields: []string{"address", "members", "type"},
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			profileHandler, profileSrvcMock := testPrepareProfileHandler(t)
+
+			profileSrvcMock.EXPECT().GetByID(gomock.Any(), 12).Return(c.current, nil)
+			profileSrvcMock.EXPECT().Patch(gomock.Any(), c.want, c.fields).Return(nil)
+
+			req, err := http.NewRequest(http.MethodPatch, "/profiles/12", strings.NewReader(c.body))
+			if err != nil {
+				t.Errorf("Unexpected error: %#v", err)
+			}
+
+			req.Header.Set("Content-Type", "application/merge-patch+json")
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "12")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(profileHandler.Patch)
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, rr.Code, http.StatusNoContent)
+		})
+	}
+}
+
+func TestProxyProfileHandler_Patch_VersionConflict(t *testing.T) {
+	t.Parallel()
+
+	profileHandler, profileSrvcMock := testPrepareProfileHandler(t)
+
+	current := model.ProxyProfile{ID: 12, Name: "ss", Type: model.Socks5, Host: "127.0.0.1", Port: 1080}
+	current.Version = 3
+
+	profileSrvcMock.EXPECT().GetByID(gomock.Any(), 12).Return(current, nil)
+
+	req, err := http.NewRequest(http.MethodPatch, "/profiles/12", strings.NewReader(`{"type":"SOCKS4"}`))
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	req.Header.Set("If-Match", `"2"`)
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "12")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(profileHandler.Patch)
+
+	handler.ServeHTTP(rr, req)
+
+	got := strings.TrimSuffix(rr.Body.String(), "\n")
+
+	want := `{"type":"urn:pacgen:problem:version-conflict","title":"Precondition Failed","status":412,` +
+		`"detail":"proxy profile with id 12 has been changed, current version is 3","instance":"/profiles/12"}`
+
+	assert.Equal(t, rr.Code, http.StatusPreconditionFailed)
+
+	assert.Equal(t, got, want)
+}
+
+func TestProxyProfileHandler_Patch_UnprocessableEntity(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		body string
+		want string
+	}{
+		"invalid type": {
+			body: `{"type":"FTP"}`,
+			want: `[{"field":"type","code":"oneof","message":"must be one of: ` +
+				`HTTP, http, HTTPS, https, SOCKS4, socks4, SOCKS5, socks5, DIRECT, direct, POOL, pool"}]`,
+		},
+		"invalid weight": {
+			body: `{"members":[{"profile_id":3,"weight":101}]}`,
+			want: `[{"field":"members[0].weight","code":"max","message":"must be at most 100"}]`,
+		},
+		"address": {
+			body: `{"address":"127.0.0.1:port"}`,
+			want: `[{"field":"address","message":"port must be a number between 1 and 65535"}]`,
+		},
+	}
+
+	current := model.ProxyProfile{ID: 12, Name: "ss", Type: model.Socks5, Host: "127.0.0.1", Port: 1080}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			profileHandler, profileSrvcMock := testPrepareProfileHandler(t)
+			profileSrvcMock.EXPECT().GetByID(gomock.Any(), 12).Return(current, nil)
+
+			req, err := http.NewRequest(http.MethodPatch, "/profiles/12", strings.NewReader(c.body))
+			if err != nil {
+				t.Errorf("Unexpected error: %#v", err)
+			}
+
+			req.Header.Set("Content-Type", "application/merge-patch+json")
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "12")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(profileHandler.Patch)
+
+			handler.ServeHTTP(rr, req)
+
+			got := strings.TrimSuffix(rr.Body.String(), "\n")
+
+			assert.Equal(t, rr.Code, http.StatusUnprocessableEntity)
+
+			assert.Equal(t, got, `{"type":"urn:pacgen:problem:validation","title":"Unprocessable Entity","status":422,`+
+				`"detail":"Request body is invalid","instance":"/profiles/12","errors":`+c.want+`}`)
+		})
+	}
+}
+
 func TestProxyProfileHandler_Update_BadRequest(t *testing.T) {
 	t.Parallel()
 
@@ -369,6 +836,7 @@ func TestProxyProfileHandler_Update_UnprocessableEntity(t *testing.T) {
 	}
 
 	for name, body := range cases {
+		body := body
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
 
@@ -399,10 +867,11 @@ func TestProxyProfileHandler_Update_NotFound(t *testing.T) {
 	profileHandler, profileSrvcMock := testPrepareProfileHandler(t)
 
 	profile := model.ProxyProfile{
-		ID:      1,
-		Name:    "shadowsocks",
-		Type:    model.Socks5,
-		Address: "localhost:1080",
+		ID:   1,
+		Name: "shadowsocks",
+		Type: model.Socks5,
+		Host: "localhost",
+		Port: 1080,
 	}
 
 	profileSrvcMock.EXPECT().Update(gomock.Any(), profile).Return(&errs.EntityNotFoundError{})
@@ -435,10 +904,11 @@ func TestProxyProfileHandler_Update_AlreadyExists(t *testing.T) {
 	profileHandler, profileSrvcMock := testPrepareProfileHandler(t)
 
 	profile := model.ProxyProfile{
-		ID:      1,
-		Name:    "shadowsocks",
-		Type:    model.Socks5,
-		Address: "localhost:1080",
+		ID:   1,
+		Name: "shadowsocks",
+		Type: model.Socks5,
+		Host: "localhost",
+		Port: 1080,
 	}
 
 	profileSrvcMock.EXPECT().Update(gomock.Any(), profile).Return(&errs.EntityAlreadyExistsError{})
@@ -470,10 +940,11 @@ func TestProxyProfileHandler_Update_InternalServerError(t *testing.T) {
 	profileHandler, profileSrvcMock := testPrepareProfileHandler(t)
 
 	profile := model.ProxyProfile{
-		ID:      1,
-		Name:    "shadowsocks",
-		Type:    model.Socks5,
-		Address: "localhost:1080",
+		ID:   1,
+		Name: "shadowsocks",
+		Type: model.Socks5,
+		Host: "localhost",
+		Port: 1080,
 	}
 
 	profileSrvcMock.EXPECT().Update(gomock.Any(), profile).Return(errs.ServiceUnknownError)
@@ -504,7 +975,7 @@ func TestProxyProfileHandler_Delete_OK(t *testing.T) {
 
 	profileHandler, profileSrvcMock := testPrepareProfileHandler(t)
 
-	profileSrvcMock.EXPECT().Delete(gomock.Any(), 1).Return(nil)
+	profileSrvcMock.EXPECT().Delete(gomock.Any(), 1, 0).Return(nil)
 
 	req, err := http.NewRequest(http.MethodDelete, "/rules/1", nil)
 	if err != nil {
@@ -550,7 +1021,7 @@ func TestProxyProfileHandler_Delete_NotFound(t *testing.T) {
 
 	profileHandler, profileSrvcMock := testPrepareProfileHandler(t)
 
-	profileSrvcMock.EXPECT().Delete(gomock.Any(), 1).Return(&errs.EntityNotFoundError{})
+	profileSrvcMock.EXPECT().Delete(gomock.Any(), 1, 0).Return(&errs.EntityNotFoundError{})
 
 	req, err := http.NewRequest(http.MethodDelete, "/rules/1", nil)
 	if err != nil {
@@ -574,7 +1045,7 @@ func TestProxyProfileHandler_Delete_Conflict(t *testing.T) {
 
 	profileHandler, profileSrvcMock := testPrepareProfileHandler(t)
 
-	profileSrvcMock.EXPECT().Delete(gomock.Any(), 1).Return(&errs.EntityStillReferencedError{})
+	profileSrvcMock.EXPECT().Delete(gomock.Any(), 1, 0).Return(&errs.EntityStillReferencedError{})
 
 	req, err := http.NewRequest(http.MethodDelete, "/rules/1", nil)
 	if err != nil {
@@ -598,7 +1069,7 @@ func TestProxyProfileHandler_Delete_InternalServerError(t *testing.T) {
 
 	profileHandler, profileSrvcMock := testPrepareProfileHandler(t)
 
-	profileSrvcMock.EXPECT().Delete(gomock.Any(), 1).Return(errs.ServiceUnknownError)
+	profileSrvcMock.EXPECT().Delete(gomock.Any(), 1, 0).Return(errs.ServiceUnknownError)
 
 	req, err := http.NewRequest(http.MethodDelete, "/rules/1", nil)
 	if err != nil {
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"bytes"
+	"github.com/nnemirovsky/pacgen/pkg/provision"
+	"github.com/nnemirovsky/pacgen/pkg/rest"
+	"github.com/rs/zerolog"
+	"net/http"
+)
+
+type ProvisioningHandler struct {
+	logger zerolog.Logger
+	// signer is nil if configuration profiles are served unsigned.
+	signer *provision.Signer
+}
+
+func NewProvisioningHandler(signer *provision.Signer, logger zerolog.Logger) *ProvisioningHandler {
+	return &ProvisioningHandler{
+		logger: logger,
+		signer: signer,
+	}
+}
+
+func (h *ProvisioningHandler) AppleMobileConfig(w http.ResponseWriter, r *http.Request) {
+	buf := bytes.Buffer{}
+	if err := provision.MobileConfig(&buf, rest.GetExternalURL(r, "/proxy.pac")); err != nil {
+		h.logger.Error().Err(err).Msg("Error occurred while generating configuration profile")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return
+	}
+
+	profile := buf.Bytes()
+	if h.signer != nil {
+		signed, err := h.signer.Sign(profile)
+		if err != nil {
+			h.logger.Error().Err(err).Msg("Error occurred while signing configuration profile")
+			Render(w, r, rest.InternalErrorResponse(), h.logger)
+			return
+		}
+		profile = signed
+	}
+
+	w.Header().Set("Content-Type", "application/x-apple-aspen-config")
+	w.Header().Set("Content-Disposition", `attachment; filename="pacgen.mobileconfig"`)
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(profile); err != nil {
+		h.logger.Error().Err(err).Msg("Error occurred while writing configuration profile")
+	}
+}
+
+func (h *ProvisioningHandler) WindowsRegistry(w http.ResponseWriter, r *http.Request) {
+	buf := bytes.Buffer{}
+	if err := provision.WindowsRegistry(&buf, rest.GetExternalURL(r, "/proxy.pac")); err != nil {
+		h.logger.Error().Err(err).Msg("Error occurred while generating registry file")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/x-ms-regedit")
+	w.Header().Set("Content-Disposition", `attachment; filename="pacgen.reg"`)
+	w.WriteHeader(http.StatusOK)
+	if _, err := buf.WriteTo(w); err != nil {
+		h.logger.Error().Err(err).Msg("Error occurred while writing registry file")
+	}
+}
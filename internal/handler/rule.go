@@ -1,30 +1,177 @@
 package handler
 
 import (
+	"fmt"
 	"github.com/go-chi/render"
 	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/nnemirovsky/pacgen/pkg/domain"
 	"github.com/nnemirovsky/pacgen/pkg/rest"
 	"github.com/rs/zerolog"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type RuleHandler struct {
-	logger  zerolog.Logger
-	service RuleService
+	logger   zerolog.Logger
+	service  RuleService
+	profiles ProxyProfileService
+	linter   RuleLinter
+	matcher  HostMatcher
+	// requireIfMatch makes changes of a rule without If-Match header fail with 428 Precondition Required.
+	requireIfMatch bool
 }
 
-func NewRuleHandler(service RuleService, logger zerolog.Logger) *RuleHandler {
+func NewRuleHandler(
+	service RuleService,
+	profiles ProxyProfileService,
+	linter RuleLinter,
+	matcher HostMatcher,
+	requireIfMatch bool,
+	logger zerolog.Logger,
+) *RuleHandler {
 	return &RuleHandler{
-		logger:  logger,
-		service: service,
+		logger:         logger,
+		service:        service,
+		profiles:       profiles,
+		linter:         linter,
+		matcher:        matcher,
+		requireIfMatch: requireIfMatch,
 	}
 }
 
+// ruleExpansions are the related entities rules can be expanded with.
+var ruleExpansions = []string{"proxy_profile"}
+
+// GetAll responds with a page of the rules matching the filter given in the query.
 func (h *RuleHandler) GetAll(w http.ResponseWriter, r *http.Request) {
-	rules, err := h.service.GetAll(r.Context())
+	filter := model.RuleFilter{}
+	if value := r.URL.Query().Get("proxy_profile_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil || id < 1 {
+			h.logger.Debug().Err(err).Str("proxy_profile_id", value).Msg("Invalid proxy_profile_id query parameter")
+			Render(w, r, rest.BadRequestResponse("Query parameter 'proxy_profile_id' must be a positive integer"), h.logger)
+			return
+		}
+		filter.ProxyProfileID = id
+	}
+
+	h.list(w, r, filter)
+}
+
+// GetAllByProfile responds with a page of the rules routed through the profile given in the URL, which match
+// the filter given in the query.
+func (h *RuleHandler) GetAllByProfile(w http.ResponseWriter, r *http.Request) {
+	id, ok := getIDFromURL(w, r, h.logger)
+	if !ok {
+		return
+	}
+
+	if _, err := h.profiles.GetByID(r.Context(), id); err != nil {
+		if _, ok := err.(*errs.EntityNotFoundError); ok {
+			h.logger.Debug().Err(err).Send()
+			Render(w, r, errorResponse(err), h.logger)
+			return
+		}
+		h.logger.Error().Err(err).Msg("Error occurred while getting proxy profile by id")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return
+	}
+
+	h.list(w, r, model.RuleFilter{ProxyProfileID: id})
+}
+
+// list responds with a page of the rules matching the filter completed with the rest of the query.
+func (h *RuleHandler) list(w http.ResponseWriter, r *http.Request, filter model.RuleFilter) {
+	query := r.URL.Query()
+	filter.Tag, filter.Mode, filter.Domain = query.Get("tag"), query.Get("mode"), query.Get("domain")
+	if filter.Mode != "" && !contains(model.RuleFilterModes, filter.Mode) {
+		h.logger.Debug().Str("mode", filter.Mode).Msg("Unknown mode in query parameter")
+		Render(w, r, rest.BadRequestResponse(
+			fmt.Sprintf("Query parameter 'mode' must be one of: %s", strings.Join(model.RuleFilterModes, ", ")),
+		), h.logger)
+		return
+	}
+
+	var ok bool
+	if filter.MetadataFilter, filter.Sort, ok = getListQuery(w, r, h.logger, model.RuleSortFields); !ok {
+		return
+	}
+	if filter.Page, ok = getPage(w, r, h.logger, 0); !ok {
+		return
+	}
+	var fields []string
+	if fields, filter.Fields, ok = getFields(w, r, h.logger, ruleFields); !ok {
+		return
+	}
+	expand, ok := getRelated(w, r, h.logger, "expand", ruleExpansions)
+	if !ok {
+		return
+	}
+	filter.ExpandProfile = contains(expand, "proxy_profile")
+	// Expanded profiles are kept by the projection.
+	if len(fields) != 0 {
+		fields = append(fields, expand...)
+	}
+
+	rules, total, err := h.service.GetAll(r.Context(), filter)
 	if err != nil {
 		h.logger.Error().Err(err).Msg("Error occurred while getting all rules")
-		w.WriteHeader(http.StatusInternalServerError)
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return
+	}
+
+	ruleEntities := make([]RuleR, 0)
+	for _, rule := range rules {
+		ruleEntities = append(ruleEntities, ruleEntity(rule, filter.ExpandProfile))
+	}
+	projected, err := project(ruleEntities, fields)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Error occurred while projecting rules")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return
+	}
+
+	setPageHeaders(w, r, filter.Page, total)
+	render.JSON(w, r, projected)
+	w.WriteHeader(http.StatusOK)
+}
+
+// ruleEntity converts the rule into its entity, along with its proxy profile if it is expanded.
+func ruleEntity(rule model.Rule, expandProfile bool) RuleR {
+	ruleR := RuleR{}
+	ruleR.FromModel(rule)
+	if expandProfile && rule.ProxyProfile != nil {
+		profileR := ProxyProfileR{}
+		profileR.FromModel(*rule.ProxyProfile)
+		ruleR.ProxyProfile = &profileR
+	}
+	return ruleR
+}
+
+// defaultExpiringWithin is the period looked ahead for expiring rules if the query does not give one.
+const defaultExpiringWithin = 7 * 24 * time.Hour
+
+// Expiring responds with enabled rules expiring within the period given by within query parameter,
+// 7 days by default.
+func (h *RuleHandler) Expiring(w http.ResponseWriter, r *http.Request) {
+	within := defaultExpiringWithin
+	if value := r.URL.Query().Get("within"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			h.logger.Debug().Err(err).Str("within", value).Msg("Invalid within query parameter")
+			Render(w, r, rest.BadRequestResponse("Query parameter 'within' must be a positive duration, e.g. 72h"), h.logger)
+			return
+		}
+		within = d
+	}
+
+	rules, err := h.service.GetExpiring(r.Context(), time.Now().Add(within))
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Error occurred while getting expiring rules")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
 		return
 	}
 
@@ -45,21 +192,26 @@ func (h *RuleHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	expand, ok := getRelated(w, r, h.logger, "expand", ruleExpansions)
+	if !ok {
+		return
+	}
+
 	rule, err := h.service.GetByID(r.Context(), id)
 	if err != nil {
 		if _, ok := err.(*errs.EntityNotFoundError); ok {
 			h.logger.Debug().Err(err).Send()
-			Render(w, r, rest.NotFoundResponse(err.Error()), h.logger)
+			Render(w, r, errorResponse(err), h.logger)
 			return
 		}
 		h.logger.Error().Err(err).Msg("Error occurred while getting rule by id")
-		w.WriteHeader(http.StatusInternalServerError)
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
 		return
 	}
 
-	ruleR := RuleR{}
-	ruleR.FromModel(rule)
+	ruleR := ruleEntity(rule, contains(expand, "proxy_profile"))
 
+	setETag(w, rule.Version)
 	render.JSON(w, r, ruleR)
 	w.WriteHeader(http.StatusOK)
 }
@@ -72,20 +224,27 @@ func (h *RuleHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 	ruleModel, err := rule.ToModel()
 	if err != nil {
-		h.logger.Debug().Err(err).Msg("Error occurred while converting rule entity to corresponding model")
-		w.WriteHeader(http.StatusUnprocessableEntity)
+		renderConversionError(w, r, h.logger, err)
+		return
+	}
+
+	if ruleModel.Owner == "" {
+		ruleModel.Owner = requestUser(r)
+	}
+
+	if ok := h.check(w, r, ruleModel); !ok {
 		return
 	}
 
 	err = h.service.Create(r.Context(), &ruleModel)
 	if err == errs.InvalidReferenceError {
 		h.logger.Debug().Err(err).Send()
-		Render(w, r, rest.ConflictResponse(err.Error()), h.logger)
+		Render(w, r, errorResponse(err), h.logger)
 		return
 	}
 	if err != nil {
 		h.logger.Error().Err(err).Msg("Error occurred while creating rule")
-		w.WriteHeader(http.StatusInternalServerError)
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
 		return
 	}
 
@@ -98,6 +257,11 @@ func (h *RuleHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	version, ok := getIfMatch(w, r, h.logger, h.requireIfMatch)
+	if !ok {
+		return
+	}
+
 	rule := RuleCU{}
 	if ok := getFromBodyAndValidate(w, r, h.logger, &rule); !ok {
 		return
@@ -105,48 +269,262 @@ func (h *RuleHandler) Update(w http.ResponseWriter, r *http.Request) {
 
 	ruleModel, err := rule.ToModel()
 	if err != nil {
-		h.logger.Debug().Err(err).Msg("Error occurred while converting rule entity to corresponding model")
-		w.WriteHeader(http.StatusUnprocessableEntity)
+		renderConversionError(w, r, h.logger, err)
 		return
 	}
 	ruleModel.ID = id
+	ruleModel.Version = version
+
+	if ok := h.check(w, r, ruleModel); !ok {
+		return
+	}
 
 	err = h.service.Update(r.Context(), ruleModel)
 	if err == errs.InvalidReferenceError {
 		h.logger.Debug().Err(err).Send()
-		Render(w, r, rest.ConflictResponse(err.Error()), h.logger)
+		Render(w, r, errorResponse(err), h.logger)
 		return
 	}
 	if _, ok := err.(*errs.EntityNotFoundError); ok {
 		h.logger.Debug().Err(err).Send()
-		Render(w, r, rest.NotFoundResponse(err.Error()), h.logger)
+		Render(w, r, errorResponse(err), h.logger)
+		return
+	}
+	if _, ok := err.(*errs.EntityVersionConflictError); ok {
+		h.logger.Debug().Err(err).Send()
+		Render(w, r, errorResponse(err), h.logger)
 		return
 	}
 	if err != nil {
 		h.logger.Error().Err(err).Msg("Error occurred while updating rule")
-		w.WriteHeader(http.StatusInternalServerError)
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
 		return
 	}
 
+	if version != 0 {
+		setETag(w, version+1)
+	}
 	render.NoContent(w, r)
 }
 
-func (h *RuleHandler) Delete(w http.ResponseWriter, r *http.Request) {
+// Patch applies JSON merge patch (RFC 7396) to the rule. Only the supplied fields are validated and updated,
+// a rule made of a custom regex keeps it unless domain, mode or domain_list_id is supplied.
+func (h *RuleHandler) Patch(w http.ResponseWriter, r *http.Request) {
 	id, ok := getIDFromURL(w, r, h.logger)
 	if !ok {
 		return
 	}
 
-	if err := h.service.Delete(r.Context(), id); err != nil {
+	version, ok := getIfMatch(w, r, h.logger, h.requireIfMatch)
+	if !ok {
+		return
+	}
+
+	patch, ok := getPatch(w, r, h.logger, rulePatchFields)
+	if !ok {
+		return
+	}
+
+	current, err := h.service.GetByID(r.Context(), id)
+	if err != nil {
 		if _, ok := err.(*errs.EntityNotFoundError); ok {
 			h.logger.Debug().Err(err).Send()
-			Render(w, r, rest.NotFoundResponse(err.Error()), h.logger)
+			Render(w, r, errorResponse(err), h.logger)
+			return
+		}
+		h.logger.Error().Err(err).Msg("Error occurred while getting rule to patch")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return
+	}
+
+	if version != 0 && version != current.Version {
+		err = &errs.EntityVersionConflictError{Name: "rule", Key: "id", Value: id, Version: current.Version}
+		h.logger.Debug().Err(err).Send()
+		Render(w, r, errorResponse(err), h.logger)
+		return
+	}
+
+	currentR := RuleCU{}
+	currentR.FromModel(current)
+	rule, ok := applyPatch(w, r, h.logger, currentR, patch)
+	if !ok {
+		return
+	}
+	fields := patchedFields(patch, rulePatchFields)
+	if !contains(fields, "regex") {
+		rule.regex = currentR.regex
+	}
+
+	ruleModel, err := rule.ToModel()
+	if err != nil {
+		renderConversionError(w, r, h.logger, err)
+		return
+	}
+	ruleModel.ID = id
+	// The patch is merged into the current rule, so it is applied to the current version only.
+	ruleModel.Version = current.Version
+
+	if ok := h.check(w, r, ruleModel); !ok {
+		return
+	}
+
+	err = h.service.Patch(r.Context(), ruleModel, fields)
+	if err == errs.InvalidReferenceError {
+		h.logger.Debug().Err(err).Send()
+		Render(w, r, errorResponse(err), h.logger)
+		return
+	}
+	if _, ok := err.(*errs.EntityNotFoundError); ok {
+		h.logger.Debug().Err(err).Send()
+		Render(w, r, errorResponse(err), h.logger)
+		return
+	}
+	if _, ok := err.(*errs.EntityVersionConflictError); ok {
+		h.logger.Debug().Err(err).Send()
+		Render(w, r, errorResponse(err), h.logger)
+		return
+	}
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Error occurred while patching rule")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return
+	}
+
+	setETag(w, current.Version+1)
+	render.NoContent(w, r)
+}
+
+// Lint responds with the problems of the enabled rules.
+func (h *RuleHandler) Lint(w http.ResponseWriter, r *http.Request) {
+	findings, err := h.linter.Lint(r.Context())
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Error occurred while linting rules")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return
+	}
+
+	render.JSON(w, r, lintFindingEntities(findings))
+	w.WriteHeader(http.StatusOK)
+}
+
+// Match responds with the rules matching the host given in the query, in the order pac file evaluates them.
+func (h *RuleHandler) Match(w http.ResponseWriter, r *http.Request) {
+	value := r.URL.Query().Get("host")
+	host, err := domain.Normalize(strings.Trim(value, "[]"))
+	if err != nil {
+		h.logger.Debug().Err(err).Str("host", value).Msg("Invalid host query parameter")
+		Render(w, r, rest.BadRequestResponse("Query parameter 'host' must be a host name or an IP address"), h.logger)
+		return
+	}
+
+	match, err := h.matcher.Match(r.Context(), host)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Error occurred while matching host")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return
+	}
+
+	matchR := HostMatchR{}
+	matchR.FromModel(match)
+	render.JSON(w, r, matchR)
+	w.WriteHeader(http.StatusOK)
+}
+
+// check lints the rule before it is saved. Problems are reported in Warning headers, in strict mode
+// (strict=true query parameter) the rule is rejected with 409 instead.
+func (h *RuleHandler) check(w http.ResponseWriter, r *http.Request, rule model.Rule) (ok bool) {
+	findings, err := h.linter.Check(r.Context(), rule)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Error occurred while checking rule")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return false
+	}
+	if len(findings) == 0 {
+		return true
+	}
+
+	if r.URL.Query().Get("strict") == "true" {
+		h.logger.Debug().Int("findings", len(findings)).Msg("Rule rejected in strict mode")
+		Render(w, r, &LintConflictR{
+			ErrorResponse: *rest.ConflictResponse("Rule has problems").WithType(ProblemLintConflict),
+			Findings:      lintFindingEntities(findings),
+		}, h.logger)
+		return false
+	}
+	for _, finding := range findings {
+		w.Header().Add("Warning", fmt.Sprintf("299 - %q", finding.Kind.String()+": "+finding.Message))
+	}
+	return true
+}
+
+func (h *RuleHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := getIDFromURL(w, r, h.logger)
+	if !ok {
+		return
+	}
+
+	version, ok := getIfMatch(w, r, h.logger, h.requireIfMatch)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(r.Context(), id, version); err != nil {
+		switch err.(type) {
+		case *errs.EntityNotFoundError, *errs.EntityVersionConflictError:
+			h.logger.Debug().Err(err).Send()
+			Render(w, r, errorResponse(err), h.logger)
 			return
 		}
 		h.logger.Error().Err(err).Msg("Error occurred while deleting rule")
-		w.WriteHeader(http.StatusInternalServerError)
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
 		return
 	}
 
 	render.NoContent(w, r)
 }
+
+// BulkUpdate applies changes to all the rules with the tag given in the query.
+func (h *RuleHandler) BulkUpdate(w http.ResponseWriter, r *http.Request) {
+	tag, ok := getTagFromQuery(w, r, h.logger)
+	if !ok {
+		return
+	}
+
+	changes := RuleBulkU{}
+	if ok := getFromBodyAndValidate(w, r, h.logger, &changes); !ok {
+		return
+	}
+
+	count, err := h.service.UpdateByTag(r.Context(), tag, changes.ToModel())
+	if err == errs.InvalidReferenceError {
+		h.logger.Debug().Err(err).Send()
+		Render(w, r, errorResponse(err), h.logger)
+		return
+	}
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Error occurred while updating rules by tag")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return
+	}
+
+	render.JSON(w, r, BulkResultR{Affected: count})
+	w.WriteHeader(http.StatusOK)
+}
+
+// BulkDelete deletes all the rules with the tag given in the query.
+func (h *RuleHandler) BulkDelete(w http.ResponseWriter, r *http.Request) {
+	tag, ok := getTagFromQuery(w, r, h.logger)
+	if !ok {
+		return
+	}
+
+	count, err := h.service.DeleteByTag(r.Context(), tag)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Error occurred while deleting rules by tag")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return
+	}
+
+	render.JSON(w, r, BulkResultR{Affected: count})
+	w.WriteHeader(http.StatusOK)
+}
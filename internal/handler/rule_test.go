@@ -2,6 +2,7 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/assert/v2"
 	"github.com/golang/mock/gomock"
@@ -9,18 +10,55 @@ import (
 	"github.com/nnemirovsky/pacgen/internal/handler/mock"
 	"github.com/nnemirovsky/pacgen/internal/model"
 	"github.com/nnemirovsky/pacgen/pkg/logutil"
+	"github.com/nnemirovsky/pacgen/pkg/rest"
+	"github.com/rs/zerolog/hlog"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 )
 
+// testPrepareRuleHandler returns the handler with a linter that finds no problems.
 func testPrepareRuleHandler(t *testing.T) (*RuleHandler, *mock.RuleService) {
+	ruleHandler, ruleSrvcMock, linterMock := testPrepareRuleHandlerWithLinter(t)
+	linterMock.EXPECT().Check(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+
+	return ruleHandler, ruleSrvcMock
+}
+
+func testPrepareRuleHandlerWithLinter(t *testing.T) (*RuleHandler, *mock.RuleService, *mock.RuleLinter) {
+	ctrl := gomock.NewController(t)
+	ruleSrvcMock := mock.NewRuleService(ctrl)
+	linterMock := mock.NewRuleLinter(ctrl)
+
+	return NewRuleHandler(ruleSrvcMock, nil, linterMock, nil, false, logutil.DiscardLogger), ruleSrvcMock, linterMock
+}
+
+func testPrepareRuleHandlerWithProfiles(t *testing.T) (*RuleHandler, *mock.RuleService, *mock.ProxyProfileService) {
 	ctrl := gomock.NewController(t)
 	ruleSrvcMock := mock.NewRuleService(ctrl)
+	profileSrvcMock := mock.NewProxyProfileService(ctrl)
+	ruleHandler := NewRuleHandler(ruleSrvcMock, profileSrvcMock, nil, nil, false, logutil.DiscardLogger)
 
-	return NewRuleHandler(ruleSrvcMock, logutil.DiscardLogger), ruleSrvcMock
+	return ruleHandler, ruleSrvcMock, profileSrvcMock
+}
+
+func testPrepareRuleHandlerWithMatcher(t *testing.T) (*RuleHandler, *mock.HostMatcher) {
+	ctrl := gomock.NewController(t)
+	matcherMock := mock.NewHostMatcher(ctrl)
+
+	ruleHandler := NewRuleHandler(
+		mock.NewRuleService(ctrl),
+		nil,
+		mock.NewRuleLinter(ctrl),
+		matcherMock,
+		false,
+		logutil.DiscardLogger,
+	)
+
+	return ruleHandler, matcherMock
 }
 
 func TestRuleHandler_GetAll_OK(t *testing.T) {
@@ -32,19 +70,32 @@ func TestRuleHandler_GetAll_OK(t *testing.T) {
 		{
 			ID:           1,
 			Regex:        `^www\.google\.com$`,
+			Enabled:      true,
+			Tags:         model.Tags{"search"},
 			ProxyProfile: &model.ProxyProfile{ID: 1},
 		},
 		{
 			ID:           2,
-			Regex:        `(?:^|\.)facebook\.com$`,
+			Regex:        `(?:^|\.)xn--e1afmkfd\.xn--p1ai$`,
+			Tags:         model.Tags{},
+			ProxyProfile: &model.ProxyProfile{ID: 2},
+		},
+		{
+			ID:           3,
+			DomainListID: 5,
+			Enabled:      true,
+			Tags:         model.Tags{},
 			ProxyProfile: &model.ProxyProfile{ID: 2},
 		},
 	}
 
-	want := `[{"id":1,"regexp":"^www\\.google\\.com$","proxy_profile_id":1},` +
-		`{"id":2,"regexp":"(?:^|\\.)facebook\\.com$","proxy_profile_id":2}]`
+	want := `[{"id":1,"regexp":"^www\\.google\\.com$","domain":"www.google.com","domain_unicode":"www.google.com",` +
+		`"mode":"domain","proxy_profile_id":1,"enabled":true,"tags":["search"]},` +
+		`{"id":2,"regexp":"(?:^|\\.)xn--e1afmkfd\\.xn--p1ai$","domain":"xn--e1afmkfd.xn--p1ai",` +
+		`"domain_unicode":"пример.рф","mode":"domain_and_subdomains","proxy_profile_id":2,"enabled":false,"tags":[]},` +
+		`{"id":3,"domain_list_id":5,"proxy_profile_id":2,"enabled":true,"tags":[]}]`
 
-	ruleSrvcMock.EXPECT().GetAll(gomock.Any()).Return(rules, nil)
+	ruleSrvcMock.EXPECT().GetAll(gomock.Any(), model.RuleFilter{}).Return(rules, 3, nil)
 
 	req, err := http.NewRequest(http.MethodGet, "/rules", nil)
 	if err != nil {
@@ -61,6 +112,302 @@ func TestRuleHandler_GetAll_OK(t *testing.T) {
 	assert.Equal(t, rr.Code, http.StatusOK)
 
 	assert.Equal(t, got, want)
+
+	assert.Equal(t, rr.Header().Get("X-Total-Count"), "3")
+	// Rules are not paged unless the query asks for a page.
+	assert.Equal(t, rr.Header().Get("Link"), "")
+}
+
+func TestRuleHandler_GetAll_Filtered(t *testing.T) {
+	t.Parallel()
+
+	ruleHandler, ruleSrvcMock := testPrepareRuleHandler(t)
+
+	created := time.Date(2022, 9, 1, 10, 0, 0, 0, time.UTC)
+	rules := []model.Rule{
+		{
+			ID:           1,
+			Regex:        `^www\.google\.com$`,
+			Enabled:      true,
+			Tags:         model.Tags{},
+			ProxyProfile: &model.ProxyProfile{ID: 1},
+			Metadata:     model.Metadata{Description: "Ticket 42", Owner: "alice", CreatedAt: &created, UpdatedAt: &created},
+		},
+	}
+
+	want := `[{"id":1,"regexp":"^www\\.google\\.com$","domain":"www.google.com","domain_unicode":"www.google.com",` +
+		`"mode":"domain","proxy_profile_id":1,"enabled":true,"tags":[],"description":"Ticket 42","owner":"alice",` +
+		`"created_at":"2022-09-01T10:00:00Z","updated_at":"2022-09-01T10:00:00Z"}]`
+
+	filter := model.RuleFilter{
+		MetadataFilter: model.MetadataFilter{
+			Owner:        "alice",
+			Description:  "ticket",
+			CreatedAfter: time.Date(2022, 9, 1, 0, 0, 0, 0, time.FixedZone("", 2*60*60)),
+		},
+		Sort: model.Sort{Field: "created_at", Desc: true},
+	}
+	ruleSrvcMock.EXPECT().GetAll(gomock.Any(), filter).Return(rules, 1, nil)
+
+	target := "/rules?owner=alice&description=ticket&created_after=2022-09-01T00:00:00%2B02:00&sort=-created_at"
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(ruleHandler.GetAll)
+
+	handler.ServeHTTP(rr, req)
+
+	got := strings.TrimSuffix(rr.Body.String(), "\n")
+
+	assert.Equal(t, rr.Code, http.StatusOK)
+
+	assert.Equal(t, got, want)
+}
+
+func TestRuleHandler_GetAll_Paged(t *testing.T) {
+	t.Parallel()
+
+	ruleHandler, ruleSrvcMock := testPrepareRuleHandler(t)
+
+	rules := []model.Rule{
+		{ID: 21, Regex: `(?:^|\.)google\.com$`, Tags: model.Tags{"search"}},
+		{ID: 22, DomainListID: 5, Tags: model.Tags{}},
+	}
+
+	want := `[{"domain":"google.com","id":21,"tags":["search"]},{"id":22,"tags":[]}]`
+
+	filter := model.RuleFilter{
+		Tag:            "search",
+		ProxyProfileID: 2,
+		Mode:           "domain_and_subdomains",
+		Domain:         "google",
+		Sort:           model.Sort{Field: "domain"},
+		Page:           model.Page{Limit: 10, Offset: 20},
+		Fields:         []string{"id", "regex", "tags"},
+	}
+	ruleSrvcMock.EXPECT().GetAll(gomock.Any(), filter).Return(rules, 45, nil)
+
+	target := "/rules?tag=search&proxy_profile_id=2&mode=domain_and_subdomains&domain=google&sort=domain" +
+		"&limit=10&offset=20&fields=id,domain,tags"
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(ruleHandler.GetAll)
+
+	handler.ServeHTTP(rr, req)
+
+	got := strings.TrimSuffix(rr.Body.String(), "\n")
+
+	assert.Equal(t, rr.Code, http.StatusOK)
+
+	assert.Equal(t, got, want)
+
+	assert.Equal(t, rr.Header().Get("X-Total-Count"), "45")
+
+	link := func(offset int, rel string) string {
+		return `</rules?domain=google&fields=id%2Cdomain%2Ctags&limit=10&mode=domain_and_subdomains` +
+			`&offset=` + strconv.Itoa(offset) + `&proxy_profile_id=2&sort=domain&tag=search>; rel="` + rel + `"`
+	}
+	wantLink := link(0, "first") + ", " + link(10, "prev") + ", " + link(30, "next") + ", " + link(40, "last")
+	assert.Equal(t, rr.Header().Get("Link"), wantLink)
+}
+
+func TestRuleHandler_GetAll_Expanded(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		target string
+		fields []string
+		want   string
+	}{
+		"all fields": {
+			target: "/rules?expand=proxy_profile",
+			want: `[{"id":1,"regexp":"^google\\.com$","domain":"google.com","domain_unicode":"google.com",` +
+				`"mode":"domain","proxy_profile_id":3,"enabled":true,"tags":[],` +
+				`"proxy_profile":{"id":3,"name":"squid","type":"HTTP","address":"proxy.local:3128"}}]`,
+		},
+		"projected": {
+			target: "/rules?fields=id&expand=proxy_profile",
+			fields: []string{"id"},
+			want:   `[{"id":1,"proxy_profile":{"id":3,"name":"squid","type":"HTTP","address":"proxy.local:3128"}}]`,
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ruleHandler, ruleSrvcMock := testPrepareRuleHandler(t)
+
+			rules := []model.Rule{
+				{
+					ID:           1,
+					Regex:        `^google\.com$`,
+					Enabled:      true,
+					Tags:         model.Tags{},
+					ProxyProfile: &model.ProxyProfile{ID: 3, Name: "squid", Type: model.Http, Host: "proxy.local", Port: 3128},
+				},
+			}
+
+			filter := model.RuleFilter{Fields: c.fields, ExpandProfile: true}
+			ruleSrvcMock.EXPECT().GetAll(gomock.Any(), filter).Return(rules, 1, nil)
+
+			req, err := http.NewRequest(http.MethodGet, c.target, nil)
+			if err != nil {
+				t.Errorf("Unexpected error: %#v", err)
+			}
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(ruleHandler.GetAll)
+
+			handler.ServeHTTP(rr, req)
+
+			got := strings.TrimSuffix(rr.Body.String(), "\n")
+
+			assert.Equal(t, rr.Code, http.StatusOK)
+
+			assert.Equal(t, got, c.want)
+		})
+	}
+}
+
+func TestRuleHandler_GetAllByProfile_OK(t *testing.T) {
+	t.Parallel()
+
+	ruleHandler, ruleSrvcMock, profileSrvcMock := testPrepareRuleHandlerWithProfiles(t)
+
+	rules := []model.Rule{
+		{ID: 4, Regex: `^google\.com$`, Tags: model.Tags{"search"}, ProxyProfile: &model.ProxyProfile{ID: 3}},
+	}
+
+	want := `[{"id":4,"regexp":"^google\\.com$","domain":"google.com","domain_unicode":"google.com",` +
+		`"mode":"domain","proxy_profile_id":3,"enabled":false,"tags":["search"]}]`
+
+	profileSrvcMock.EXPECT().GetByID(gomock.Any(), 3).Return(model.ProxyProfile{ID: 3}, nil)
+	filter := model.RuleFilter{ProxyProfileID: 3, Tag: "search"}
+	ruleSrvcMock.EXPECT().GetAll(gomock.Any(), filter).Return(rules, 1, nil)
+
+	req, err := http.NewRequest(http.MethodGet, "/profiles/3/rules?tag=search", nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "3")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(ruleHandler.GetAllByProfile)
+
+	handler.ServeHTTP(rr, req)
+
+	got := strings.TrimSuffix(rr.Body.String(), "\n")
+
+	assert.Equal(t, rr.Code, http.StatusOK)
+
+	assert.Equal(t, got, want)
+
+	assert.Equal(t, rr.Header().Get("X-Total-Count"), "1")
+}
+
+func TestRuleHandler_GetAllByProfile_NotFound(t *testing.T) {
+	t.Parallel()
+
+	ruleHandler, _, profileSrvcMock := testPrepareRuleHandlerWithProfiles(t)
+
+	profileSrvcMock.EXPECT().
+		GetByID(gomock.Any(), 3).
+		Return(model.ProxyProfile{}, &errs.EntityNotFoundError{Name: "proxy profile", Key: "id", Value: 3})
+
+	req, err := http.NewRequest(http.MethodGet, "/profiles/3/rules", nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "3")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(ruleHandler.GetAllByProfile)
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, rr.Code, http.StatusNotFound)
+}
+
+func TestRuleHandler_GetAll_BadRequest(t *testing.T) {
+	t.Parallel()
+
+	ruleHandler, _ := testPrepareRuleHandler(t)
+
+	cases := map[string]struct {
+		query  string
+		detail string
+	}{
+		"unknown sort field": {
+			query: "sort=name",
+			detail: `Query parameter 'sort' must be one of: id, priority, domain, created_at, updated_at, owner, ` +
+				`description`,
+		},
+		"unknown mode": {
+			query:  "mode=regex",
+			detail: `Query parameter 'mode' must be one of: domain, domain_and_subdomains, domain_list`,
+		},
+		"invalid proxy profile id": {
+			query:  "proxy_profile_id=first",
+			detail: `Query parameter 'proxy_profile_id' must be a positive integer`,
+		},
+		"limit too large": {
+			query:  "limit=5000",
+			detail: `Query parameter 'limit' must be an integer from 1 to 1000`,
+		},
+		"negative offset": {
+			query:  "offset=-1",
+			detail: `Query parameter 'offset' must be a non-negative integer`,
+		},
+		"unknown field": {
+			query: "fields=id,name",
+			detail: `Query parameter 'fields' must be a comma separated list of: active_from, created_at, ` +
+				`description, domain, domain_list_id, domain_unicode, enabled, expires_at, id, mode, owner, ` +
+				`proxy_profile_id, regexp, tags, updated_at`,
+		},
+		"invalid time": {
+			query:  "updated_before=yesterday",
+			detail: `Query parameter 'updated_before' must be in RFC 3339 format`,
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(http.MethodGet, "/rules?"+c.query, nil)
+			if err != nil {
+				t.Errorf("Unexpected error: %#v", err)
+			}
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(ruleHandler.GetAll)
+
+			handler.ServeHTTP(rr, req)
+
+			got := strings.TrimSuffix(rr.Body.String(), "\n")
+
+			assert.Equal(t, rr.Code, http.StatusBadRequest)
+
+			assert.Equal(t, got, `{"type":"about:blank","title":"Bad Request","status":400,"detail":"`+c.detail+
+				`","instance":"/rules"}`)
+		})
+	}
 }
 
 func TestRuleHandler_GetAll_InternalServerError(t *testing.T) {
@@ -68,7 +415,8 @@ func TestRuleHandler_GetAll_InternalServerError(t *testing.T) {
 
 	ruleHandler, ruleSrvcMock := testPrepareRuleHandler(t)
 
-	ruleSrvcMock.EXPECT().GetAll(gomock.Any()).Return(nil, errs.ServiceUnknownError)
+	ruleSrvcMock.EXPECT().GetAll(gomock.Any(), model.RuleFilter{}).
+		Return(nil, 0, errs.ServiceUnknownError)
 
 	req, err := http.NewRequest(http.MethodGet, "/rules", nil)
 	if err != nil {
@@ -93,8 +441,10 @@ func TestRuleHandler_GetById_OK(t *testing.T) {
 		Regex:        `^www\.google\.com$`,
 		ProxyProfile: &model.ProxyProfile{ID: 14},
 	}
+	rule.Version = 3
 
-	want := `{"id":1,"regexp":"^www\\.google\\.com$","proxy_profile_id":14}`
+	want := `{"id":1,"regexp":"^www\\.google\\.com$","domain":"www.google.com","domain_unicode":"www.google.com",` +
+		`"mode":"domain","proxy_profile_id":14,"enabled":false,"tags":[]}`
 	ruleSrvcMock.EXPECT().GetByID(gomock.Any(), 1).Return(rule, nil)
 
 	req, err := http.NewRequest(http.MethodGet, "/rules/1", nil)
@@ -116,6 +466,8 @@ func TestRuleHandler_GetById_OK(t *testing.T) {
 	assert.Equal(t, rr.Code, http.StatusOK)
 
 	assert.Equal(t, got, want)
+
+	assert.Equal(t, rr.Header().Get("ETag"), `"3"`)
 }
 
 func TestRuleHandler_GetById_NotFound(t *testing.T) {
@@ -173,6 +525,8 @@ func TestRuleHandler_Create_OK(t *testing.T) {
 
 	rule := model.Rule{
 		Regex:        `^google\.com$`,
+		Enabled:      true,
+		Tags:         model.Tags{},
 		ProxyProfile: &model.ProxyProfile{ID: 1},
 	}
 
@@ -204,81 +558,110 @@ func TestRuleHandler_Create_OK(t *testing.T) {
 	assert.Equal(t, rr.Header().Get("Location"), "http://localhost/rules/"+strconv.Itoa(insertedID))
 }
 
-func TestRuleHandler_Create_UnprocessableEntity(t *testing.T) {
+func TestRuleHandler_Create_DefaultOwner(t *testing.T) {
 	t.Parallel()
 
-	ruleHandler, _ := testPrepareRuleHandler(t)
+	ruleHandler, ruleSrvcMock := testPrepareRuleHandler(t)
 
-	cases := map[string]string{
-		"missing proxy_profile_id": `{"domain":"google.com","mode":"domain"}`,
-		"invalid mode":             `{"domain":"google.com","proxy_profile_id":1,"mode":"just_domain"}`,
+	cases := map[string]struct {
+		body string
+		want model.Metadata
+	}{
+		"authenticated user": {
+			body: `{"domain":"google.com","mode":"domain","proxy_profile_id":1,"description":"Ticket 42"}`,
+			want: model.Metadata{Description: "Ticket 42", Owner: "admin"},
+		},
+		"given owner": {
+			body: `{"domain":"google.com","mode":"domain","proxy_profile_id":1,"owner":"network team"}`,
+			want: model.Metadata{Owner: "network team"},
+		},
 	}
 
-	for name, body := range cases {
+	for name, c := range cases {
+		c := c
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
 
-			req, err := http.NewRequest(http.MethodGet, "/rules/1", strings.NewReader(body))
+			rule := model.Rule{
+				Regex:        `^google\.com$`,
+				Enabled:      true,
+				Tags:         model.Tags{},
+				ProxyProfile: &model.ProxyProfile{ID: 1},
+				Metadata:     c.want,
+			}
+			ruleSrvcMock.EXPECT().Create(gomock.Any(), &rule).Return(nil)
+
+			req, err := http.NewRequest(http.MethodPost, "http://localhost/rules", strings.NewReader(c.body))
 			if err != nil {
 				t.Errorf("Unexpected error: %#v", err)
 			}
 
 			req.Header.Set("Content-Type", "application/json")
+			req = req.WithContext(model.WithActor(req.Context(), model.Actor{User: "admin", Role: model.Editor}))
 
 			rr := httptest.NewRecorder()
 			handler := http.HandlerFunc(ruleHandler.Create)
 
 			handler.ServeHTTP(rr, req)
 
-			assert.Equal(t, rr.Code, http.StatusUnprocessableEntity)
+			assert.Equal(t, rr.Code, http.StatusCreated)
 		})
 	}
 }
 
-func TestRuleHandler_Create_Conflict(t *testing.T) {
+func TestRuleHandler_Create_Scheduled(t *testing.T) {
 	t.Parallel()
 
 	ruleHandler, ruleSrvcMock := testPrepareRuleHandler(t)
 
+	activeFrom := time.Date(2022, 9, 1, 8, 0, 0, 0, time.UTC)
+	expiresAt := time.Date(2022, 9, 8, 8, 0, 0, 0, time.UTC)
 	rule := model.Rule{
 		Regex:        `^google\.com$`,
+		Enabled:      true,
+		Tags:         model.Tags{},
 		ProxyProfile: &model.ProxyProfile{ID: 1},
+		ActiveFrom:   &activeFrom,
+		ExpiresAt:    &expiresAt,
+		Metadata:     model.Metadata{Owner: "admin"},
 	}
+	ruleSrvcMock.EXPECT().Create(gomock.Any(), &rule).Return(nil)
 
-	ruleSrvcMock.EXPECT().Create(gomock.Any(), &rule).Return(errs.InvalidReferenceError)
-
-	body := `{"domain":"google.com","mode":"domain","proxy_profile_id":1}`
-
-	req, err := http.NewRequest(http.MethodPost, "/rules", strings.NewReader(body))
+	body := `{"domain":"google.com","mode":"domain","proxy_profile_id":1,` +
+		`"active_from":"2022-09-01T10:00:00.5+02:00","expires_at":"2022-09-08T08:00:00Z"}`
+	req, err := http.NewRequest(http.MethodPost, "http://localhost/rules", strings.NewReader(body))
 	if err != nil {
 		t.Errorf("Unexpected error: %#v", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(model.WithActor(req.Context(), model.Actor{User: "admin", Role: model.Editor}))
 
 	rr := httptest.NewRecorder()
 	handler := http.HandlerFunc(ruleHandler.Create)
 
 	handler.ServeHTTP(rr, req)
 
-	assert.Equal(t, rr.Code, http.StatusConflict)
+	assert.Equal(t, rr.Code, http.StatusCreated)
 }
 
-func TestRuleHandler_Create_InternalServerError(t *testing.T) {
+func TestRuleHandler_Create_InternationalDomain(t *testing.T) {
 	t.Parallel()
 
 	ruleHandler, ruleSrvcMock := testPrepareRuleHandler(t)
 
 	rule := model.Rule{
-		Regex:        `^google\.com$`,
+		Regex:        `(?:^|\.)xn--e1afmkfd\.xn--p1ai$`,
+		Enabled:      true,
+		Tags:         model.Tags{},
 		ProxyProfile: &model.ProxyProfile{ID: 1},
 	}
 
-	ruleSrvcMock.EXPECT().Create(gomock.Any(), &rule).Return(errs.ServiceUnknownError)
+	ruleSrvcMock.EXPECT().Create(gomock.Any(), &rule).Return(nil)
 
-	body := `{"domain":"google.com","mode":"domain","proxy_profile_id":1}`
+	body := `{"domain":"Пример.РФ.","mode":"domain_and_subdomains","proxy_profile_id":1}`
 
-	req, err := http.NewRequest(http.MethodPost, "/rules", strings.NewReader(body))
+	req, err := http.NewRequest(http.MethodPost, "http://localhost/rules", strings.NewReader(body))
 	if err != nil {
 		t.Errorf("Unexpected error: %#v", err)
 	}
@@ -290,44 +673,74 @@ func TestRuleHandler_Create_InternalServerError(t *testing.T) {
 
 	handler.ServeHTTP(rr, req)
 
-	assert.Equal(t, rr.Code, http.StatusInternalServerError)
+	assert.Equal(t, rr.Code, http.StatusCreated)
 }
 
-func TestRuleHandler_Update_OK(t *testing.T) {
+func TestRuleHandler_Create_InvalidDomain(t *testing.T) {
+	t.Parallel()
+
+	ruleHandler, _ := testPrepareRuleHandler(t)
+
+	body := `{"domain":"google.com:443","mode":"domain","proxy_profile_id":1}`
+
+	req, err := http.NewRequest(http.MethodPost, "http://localhost/rules", strings.NewReader(body))
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(ruleHandler.Create)
+
+	handler.ServeHTTP(rr, req)
+
+	want := `{"type":"urn:pacgen:problem:validation","title":"Unprocessable Entity","status":422,` +
+		`"detail":"Request body is invalid","instance":"/rules","errors":[{"field":"domain",` +
+		`"message":"domain must consist of letters, digits, hyphens and underscores separated by dots"}]}`
+	got := strings.TrimSuffix(rr.Body.String(), "\n")
+
+	assert.Equal(t, rr.Code, http.StatusUnprocessableEntity)
+	assert.Equal(t, got, want)
+}
+
+func TestRuleHandler_Create_DomainList_OK(t *testing.T) {
 	t.Parallel()
 
 	ruleHandler, ruleSrvcMock := testPrepareRuleHandler(t)
 
 	rule := model.Rule{
-		ID:           12,
-		Regex:        `^google\.com$`,
+		DomainListID: 2,
+		Enabled:      true,
+		Tags:         model.Tags{},
 		ProxyProfile: &model.ProxyProfile{ID: 1},
 	}
 
-	ruleSrvcMock.EXPECT().Update(gomock.Any(), rule).Return(nil)
+	ruleSrvcMock.EXPECT().Create(gomock.Any(), &rule).DoAndReturn(
+		func(ctx context.Context, rule *model.Rule) error {
+			rule.ID = 16
+			return nil
+		},
+	)
 
-	body := `{"domain":"google.com","mode":"domain","proxy_profile_id":1}`
+	body := `{"domain_list_id":2,"proxy_profile_id":1}`
 
-	req, err := http.NewRequest(http.MethodPut, "/rules/12", strings.NewReader(body))
+	req, err := http.NewRequest(http.MethodPost, "http://localhost/rules", strings.NewReader(body))
 	if err != nil {
 		t.Errorf("Unexpected error: %#v", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 
-	rctx := chi.NewRouteContext()
-	rctx.URLParams.Add("id", "12")
-	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
-
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(ruleHandler.Update)
+	handler := http.HandlerFunc(ruleHandler.Create)
 
 	handler.ServeHTTP(rr, req)
 
-	assert.Equal(t, rr.Code, http.StatusNoContent)
+	assert.Equal(t, rr.Code, http.StatusCreated)
 }
 
-func TestRuleHandler_Update_UnprocessableEntity(t *testing.T) {
+func TestRuleHandler_Create_UnprocessableEntity(t *testing.T) {
 	t.Parallel()
 
 	ruleHandler, _ := testPrepareRuleHandler(t)
@@ -335,9 +748,18 @@ func TestRuleHandler_Update_UnprocessableEntity(t *testing.T) {
 	cases := map[string]string{
 		"missing proxy_profile_id": `{"domain":"google.com","mode":"domain"}`,
 		"invalid mode":             `{"domain":"google.com","proxy_profile_id":1,"mode":"just_domain"}`,
+		"domain and list":          `{"domain":"google.com","mode":"domain","domain_list_id":2,"proxy_profile_id":1}`,
+		"neither domain nor list":  `{"proxy_profile_id":1}`,
+		"list with mode":           `{"mode":"domain","domain_list_id":2,"proxy_profile_id":1}`,
+		"invalid label":            `{"domain":"-google.com","mode":"domain","proxy_profile_id":1}`,
+		"empty label":              `{"domain":"google..com","mode":"domain","proxy_profile_id":1}`,
+		"invalid expires_at":       `{"domain":"google.com","mode":"domain","proxy_profile_id":1,"expires_at":"tomorrow"}`,
+		"expires before active": `{"domain":"google.com","mode":"domain","proxy_profile_id":1,` +
+			`"active_from":"2022-09-08T10:00:00Z","expires_at":"2022-09-01T10:00:00Z"}`,
 	}
 
 	for name, body := range cases {
+		body := body
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
 
@@ -348,12 +770,8 @@ func TestRuleHandler_Update_UnprocessableEntity(t *testing.T) {
 
 			req.Header.Set("Content-Type", "application/json")
 
-			rctx := chi.NewRouteContext()
-			rctx.URLParams.Add("id", "1")
-			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
-
 			rr := httptest.NewRecorder()
-			handler := http.HandlerFunc(ruleHandler.Update)
+			handler := http.HandlerFunc(ruleHandler.Create)
 
 			handler.ServeHTTP(rr, req)
 
@@ -362,66 +780,103 @@ func TestRuleHandler_Update_UnprocessableEntity(t *testing.T) {
 	}
 }
 
-func TestRuleHandler_Update_BadRequest(t *testing.T) {
+func TestRuleHandler_Create_ValidationErrors(t *testing.T) {
 	t.Parallel()
 
 	ruleHandler, _ := testPrepareRuleHandler(t)
 
-	body := `{"domain":"google.com","mode":"domain","proxy_profile_id":1}`
+	body := `{"domain":"google.com","mode":"just_domain"}`
 
-	req, err := http.NewRequest(http.MethodPut, "/rules/abcd", strings.NewReader(body))
+	req, err := http.NewRequest(http.MethodPost, "/rules", strings.NewReader(body))
 	if err != nil {
 		t.Errorf("Unexpected error: %#v", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 
-	rctx := chi.NewRouteContext()
-	rctx.URLParams.Add("id", "abcd")
-	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
-
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(ruleHandler.Update)
+	handler := hlog.RequestIDHandler("req_id", "")(http.HandlerFunc(ruleHandler.Create))
 
 	handler.ServeHTTP(rr, req)
 
-	assert.Equal(t, rr.Code, http.StatusBadRequest)
+	var problem rest.ErrorResponse
+	if err = json.Unmarshal(rr.Body.Bytes(), &problem); err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	assert.Equal(t, rr.Code, http.StatusUnprocessableEntity)
+	assert.Equal(t, rr.Header().Get("Content-Type"), "application/problem+json")
+	assert.Equal(t, problem.Type, ProblemValidation)
+	assert.NotEqual(t, problem.RequestID, "")
+	assert.Equal(t, problem.Errors, []rest.FieldError{
+		{Field: "mode", Code: "oneof", Message: "must be one of: domain, domain_and_subdomains"},
+		{Field: "proxy_profile_id", Code: "required", Message: "is required"},
+	})
 }
 
-func TestRuleHandler_Update_NotFound(t *testing.T) {
+func TestRuleHandler_Create_Conflict(t *testing.T) {
 	t.Parallel()
 
 	ruleHandler, ruleSrvcMock := testPrepareRuleHandler(t)
 
 	rule := model.Rule{
-		ID:           12,
 		Regex:        `^google\.com$`,
+		Enabled:      true,
+		Tags:         model.Tags{},
 		ProxyProfile: &model.ProxyProfile{ID: 1},
 	}
 
-	ruleSrvcMock.EXPECT().Update(gomock.Any(), rule).Return(&errs.EntityNotFoundError{})
+	ruleSrvcMock.EXPECT().Create(gomock.Any(), &rule).Return(errs.InvalidReferenceError)
+
 	body := `{"domain":"google.com","mode":"domain","proxy_profile_id":1}`
 
-	req, err := http.NewRequest(http.MethodPut, "/rules/12", strings.NewReader(body))
+	req, err := http.NewRequest(http.MethodPost, "/rules", strings.NewReader(body))
 	if err != nil {
 		t.Errorf("Unexpected error: %#v", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 
-	rctx := chi.NewRouteContext()
-	rctx.URLParams.Add("id", "12")
-	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(ruleHandler.Create)
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, rr.Code, http.StatusConflict)
+}
+
+func TestRuleHandler_Create_InternalServerError(t *testing.T) {
+	t.Parallel()
+
+	ruleHandler, ruleSrvcMock := testPrepareRuleHandler(t)
+
+	rule := model.Rule{
+		Regex:        `^google\.com$`,
+		Enabled:      true,
+		Tags:         model.Tags{},
+		ProxyProfile: &model.ProxyProfile{ID: 1},
+	}
+
+	ruleSrvcMock.EXPECT().Create(gomock.Any(), &rule).Return(errs.ServiceUnknownError)
+
+	body := `{"domain":"google.com","mode":"domain","proxy_profile_id":1}`
+
+	req, err := http.NewRequest(http.MethodPost, "/rules", strings.NewReader(body))
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(ruleHandler.Update)
+	handler := http.HandlerFunc(ruleHandler.Create)
 
 	handler.ServeHTTP(rr, req)
 
-	assert.Equal(t, rr.Code, http.StatusNotFound)
+	assert.Equal(t, rr.Code, http.StatusInternalServerError)
 }
 
-func TestRuleHandler_Update_Conflict(t *testing.T) {
+func TestRuleHandler_Update_OK(t *testing.T) {
 	t.Parallel()
 
 	ruleHandler, ruleSrvcMock := testPrepareRuleHandler(t)
@@ -429,10 +884,13 @@ func TestRuleHandler_Update_Conflict(t *testing.T) {
 	rule := model.Rule{
 		ID:           12,
 		Regex:        `^google\.com$`,
+		Enabled:      true,
+		Tags:         model.Tags{},
 		ProxyProfile: &model.ProxyProfile{ID: 1},
 	}
 
-	ruleSrvcMock.EXPECT().Update(gomock.Any(), rule).Return(errs.InvalidReferenceError)
+	ruleSrvcMock.EXPECT().Update(gomock.Any(), rule).Return(nil)
+
 	body := `{"domain":"google.com","mode":"domain","proxy_profile_id":1}`
 
 	req, err := http.NewRequest(http.MethodPut, "/rules/12", strings.NewReader(body))
@@ -451,10 +909,201 @@ func TestRuleHandler_Update_Conflict(t *testing.T) {
 
 	handler.ServeHTTP(rr, req)
 
-	assert.Equal(t, rr.Code, http.StatusConflict)
+	assert.Equal(t, rr.Code, http.StatusNoContent)
 }
 
-func TestRuleHandler_Update_InternalServerError(t *testing.T) {
+func TestRuleHandler_Patch_OK(t *testing.T) {
+	t.Parallel()
+
+	activeFrom := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	cases := map[string]struct {
+		current model.Rule
+		body    string
+		want    model.Rule
+		fields  []string
+	}{
+		"profile and tags": {
+			current: model.Rule{ID: 12, Regex: `^google\.com$`, Enabled: true, Tags: model.Tags{"a"},
+				ProxyProfile: &model.ProxyProfile{ID: 1}, ActiveFrom: &activeFrom},
+			body: `{"proxy_profile_id":2,"tags":null}`,
+			want: model.Rule{ID: 12, Regex: `^google\.com$`, Enabled: true, Tags: model.Tags{},
+				ProxyProfile: &model.ProxyProfile{ID: 2}, ActiveFrom: &activeFrom},
+			fields: []string{"proxy_profile_id", "tags"},
+		},
+		"mode": {
+			current: model.Rule{ID: 12, Regex: `^google\.com$`, Enabled: true, ProxyProfile: &model.ProxyProfile{ID: 1}},
+			body:    `{"mode":"domain_and_subdomains"}`,
+			want: model.Rule{ID: 12, Regex: `(?:^|\.)google\.com$`, Enabled: true, Tags: model.Tags{},
+				ProxyProfile: &model.ProxyProfile{ID: 1}},
+			fields: []string{"domain_list_id", "regex"},
+		},
+		"custom regex kept": {
+			current: model.Rule{ID: 12, Regex: `^foo[0-9]+\.com$`, Enabled: true, ProxyProfile: &model.ProxyProfile{ID: 1},
+				Metadata: model.Metadata{Owner: "alice"}},
+			body: `{"enabled":false,"active_from":null}`,
+			want: model.Rule{ID: 12, Regex: `^foo[0-9]+\.com$`, Tags: model.Tags{}, ProxyProfile: &model.ProxyProfile{ID: 1},
+				Metadata: model.Metadata{Owner: "alice"}},
+			fields: []string{"active_from", "enabled"},
+		},
+		"list to domain": {
+			current: model.Rule{ID: 12, DomainListID: 3, Enabled: true, ProxyProfile: &model.ProxyProfile{ID: 1}},
+			body:    `{"domain_list_id":null,"domain":"Example.COM","mode":"domain"}`,
+			want: model.Rule{ID: 12, Regex: `^example\.com$`, Enabled: true, Tags: model.Tags{},
+				ProxyProfile: &model.ProxyProfile{ID: 1}},
+			fields: []string{"domain_list_id", "regex"},
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ruleHandler, ruleSrvcMock := testPrepareRuleHandler(t)
+
+			ruleSrvcMock.EXPECT().GetByID(gomock.Any(), 12).Return(c.current, nil)
+			ruleSrvcMock.EXPECT().Patch(gomock.Any(), c.want, c.fields).Return(nil)
+
+			req, err := http.NewRequest(http.MethodPatch, "/rules/12", strings.NewReader(c.body))
+			if err != nil {
+				t.Errorf("Unexpected error: %#v", err)
+			}
+
+			req.Header.Set("Content-Type", "application/merge-patch+json")
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "12")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(ruleHandler.Patch)
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, rr.Code, http.StatusNoContent)
+		})
+	}
+}
+
+func TestRuleHandler_Patch_UnprocessableEntity(t *testing.T) {
+	t.Parallel()
+
+	const invalid = `{"type":"urn:pacgen:problem:validation","title":"Unprocessable Entity","status":422,` +
+		`"detail":"Request body is invalid","instance":"/rules/12","errors":`
+
+	cases := map[string]struct {
+		body string
+		want string
+	}{
+		"not an object": {
+			body: `[]`,
+			want: `{"type":"urn:pacgen:problem:validation","title":"Unprocessable Entity","status":422,` +
+				`"detail":"Merge patch must be a JSON object","instance":"/rules/12"}`,
+		},
+		"unknown field": {
+			body: `{"regexp":"^a$"}`,
+			want: invalid + `[{"field":"regexp","code":"unknown","message":"is not a known field"}]}`,
+		},
+		"wrong type": {
+			body: `{"enabled":"yes"}`,
+			want: invalid + `[{"field":"enabled","code":"type","message":"must be a boolean"}]}`,
+		},
+		"invalid mode": {
+			body: `{"mode":"just_domain"}`,
+			want: invalid + `[{"field":"mode","code":"oneof","message":"must be one of: domain, domain_and_subdomains"}]}`,
+		},
+		"removed profile": {
+			body: `{"proxy_profile_id":null}`,
+			want: invalid + `[{"field":"proxy_profile_id","code":"required","message":"is required"}]}`,
+		},
+		"domain and list": {
+			body: `{"domain_list_id":2,"domain":"a.com"}`,
+			want: invalid + `[{"field":"domain","code":"excluded_with",` +
+				`"message":"must not be given along with domain_list_id"}]}`,
+		},
+		"invalid domain": {
+			body: `{"domain":"a b.com"}`,
+			want: invalid + `[{"field":"domain",` +
+				`"message":"domain must consist of letters, digits, hyphens and underscores separated by dots"}]}`,
+		},
+		"expires before start": {
+			body: `{"expires_at":"2022-08-01T00:00:00Z"}`,
+			want: invalid + `[{"field":"expires_at","message":"must be after active_from"}]}`,
+		},
+	}
+
+	activeFrom := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	current := model.Rule{ID: 12, Regex: `^google\.com$`, Enabled: true, ProxyProfile: &model.ProxyProfile{ID: 1},
+		ActiveFrom: &activeFrom}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ruleHandler, ruleSrvcMock := testPrepareRuleHandler(t)
+			ruleSrvcMock.EXPECT().GetByID(gomock.Any(), 12).Return(current, nil).AnyTimes()
+
+			req, err := http.NewRequest(http.MethodPatch, "/rules/12", strings.NewReader(c.body))
+			if err != nil {
+				t.Errorf("Unexpected error: %#v", err)
+			}
+
+			req.Header.Set("Content-Type", "application/merge-patch+json")
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "12")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(ruleHandler.Patch)
+
+			handler.ServeHTTP(rr, req)
+
+			got := strings.TrimSuffix(rr.Body.String(), "\n")
+
+			assert.Equal(t, rr.Code, http.StatusUnprocessableEntity)
+
+			assert.Equal(t, got, c.want)
+		})
+	}
+}
+
+func TestRuleHandler_Patch_NotFound(t *testing.T) {
+	t.Parallel()
+
+	ruleHandler, ruleSrvcMock := testPrepareRuleHandler(t)
+
+	ruleSrvcMock.EXPECT().GetByID(gomock.Any(), 12).
+		Return(model.Rule{}, &errs.EntityNotFoundError{Name: "rule", Key: "id", Value: 12})
+
+	req, err := http.NewRequest(http.MethodPatch, "/rules/12", strings.NewReader(`{"enabled":false}`))
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "12")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(ruleHandler.Patch)
+
+	handler.ServeHTTP(rr, req)
+
+	got := strings.TrimSuffix(rr.Body.String(), "\n")
+
+	assert.Equal(t, rr.Code, http.StatusNotFound)
+
+	assert.Equal(t, rr.Header().Get("Content-Type"), "application/problem+json")
+
+	assert.Equal(t, got, `{"type":"urn:pacgen:problem:entity-not-found","title":"Not Found","status":404,`+
+		`"detail":"rule with id 12 not found","instance":"/rules/12"}`)
+}
+
+func TestRuleHandler_Update_VersionConflict(t *testing.T) {
 	t.Parallel()
 
 	ruleHandler, ruleSrvcMock := testPrepareRuleHandler(t)
@@ -462,10 +1111,16 @@ func TestRuleHandler_Update_InternalServerError(t *testing.T) {
 	rule := model.Rule{
 		ID:           12,
 		Regex:        `^google\.com$`,
+		Enabled:      true,
+		Tags:         model.Tags{},
 		ProxyProfile: &model.ProxyProfile{ID: 1},
 	}
+	rule.Version = 2
+
+	ruleSrvcMock.EXPECT().
+		Update(gomock.Any(), rule).
+		Return(&errs.EntityVersionConflictError{Name: "rule", Key: "id", Value: 12, Version: 3})
 
-	ruleSrvcMock.EXPECT().Update(gomock.Any(), rule).Return(errs.ServiceUnknownError)
 	body := `{"domain":"google.com","mode":"domain","proxy_profile_id":1}`
 
 	req, err := http.NewRequest(http.MethodPut, "/rules/12", strings.NewReader(body))
@@ -474,6 +1129,7 @@ func TestRuleHandler_Update_InternalServerError(t *testing.T) {
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `"2"`)
 
 	rctx := chi.NewRouteContext()
 	rctx.URLParams.Add("id", "12")
@@ -484,77 +1140,732 @@ func TestRuleHandler_Update_InternalServerError(t *testing.T) {
 
 	handler.ServeHTTP(rr, req)
 
-	assert.Equal(t, rr.Code, http.StatusInternalServerError)
+	got := strings.TrimSuffix(rr.Body.String(), "\n")
+
+	want := `{"type":"urn:pacgen:problem:version-conflict","title":"Precondition Failed","status":412,` +
+		`"detail":"rule with id 12 has been changed, current version is 3","instance":"/rules/12"}`
+
+	assert.Equal(t, rr.Code, http.StatusPreconditionFailed)
+
+	assert.Equal(t, got, want)
 }
 
-func TestRuleHandler_Delete_OK(t *testing.T) {
+func TestRuleHandler_Update_UnprocessableEntity(t *testing.T) {
 	t.Parallel()
 
-	ruleHandler, ruleSrvcMock := testPrepareRuleHandler(t)
+	ruleHandler, _ := testPrepareRuleHandler(t)
 
-	ruleSrvcMock.EXPECT().Delete(gomock.Any(), 12).Return(nil)
+	cases := map[string]string{
+		"missing proxy_profile_id": `{"domain":"google.com","mode":"domain"}`,
+		"invalid mode":             `{"domain":"google.com","proxy_profile_id":1,"mode":"just_domain"}`,
+		"domain and list":          `{"domain":"google.com","mode":"domain","domain_list_id":2,"proxy_profile_id":1}`,
+		"neither domain nor list":  `{"proxy_profile_id":1}`,
+		"list with mode":           `{"mode":"domain","domain_list_id":2,"proxy_profile_id":1}`,
+		"invalid label":            `{"domain":"-google.com","mode":"domain","proxy_profile_id":1}`,
+		"empty label":              `{"domain":"google..com","mode":"domain","proxy_profile_id":1}`,
+	}
 
-	req, err := http.NewRequest(http.MethodDelete, "/rules/12", nil)
+	for name, body := range cases {
+		body := body
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(http.MethodGet, "/rules/1", strings.NewReader(body))
+			if err != nil {
+				t.Errorf("Unexpected error: %#v", err)
+			}
+
+			req.Header.Set("Content-Type", "application/json")
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "1")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(ruleHandler.Update)
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, rr.Code, http.StatusUnprocessableEntity)
+		})
+	}
+}
+
+func TestRuleHandler_Update_BadRequest(t *testing.T) {
+	t.Parallel()
+
+	ruleHandler, _ := testPrepareRuleHandler(t)
+
+	body := `{"domain":"google.com","mode":"domain","proxy_profile_id":1}`
+
+	req, err := http.NewRequest(http.MethodPut, "/rules/abcd", strings.NewReader(body))
 	if err != nil {
 		t.Errorf("Unexpected error: %#v", err)
 	}
 
+	req.Header.Set("Content-Type", "application/json")
+
 	rctx := chi.NewRouteContext()
-	rctx.URLParams.Add("id", "12")
+	rctx.URLParams.Add("id", "abcd")
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(ruleHandler.Delete)
+	handler := http.HandlerFunc(ruleHandler.Update)
 
 	handler.ServeHTTP(rr, req)
 
-	assert.Equal(t, rr.Code, http.StatusNoContent)
+	assert.Equal(t, rr.Code, http.StatusBadRequest)
 }
 
-func TestRuleHandler_Delete_NotFound(t *testing.T) {
+func TestRuleHandler_Update_NotFound(t *testing.T) {
 	t.Parallel()
 
 	ruleHandler, ruleSrvcMock := testPrepareRuleHandler(t)
 
-	ruleSrvcMock.EXPECT().Delete(gomock.Any(), 12).Return(&errs.EntityNotFoundError{})
+	rule := model.Rule{
+		ID:           12,
+		Regex:        `^google\.com$`,
+		Enabled:      true,
+		Tags:         model.Tags{},
+		ProxyProfile: &model.ProxyProfile{ID: 1},
+	}
+
+	ruleSrvcMock.EXPECT().Update(gomock.Any(), rule).Return(&errs.EntityNotFoundError{})
+	body := `{"domain":"google.com","mode":"domain","proxy_profile_id":1}`
 
-	req, err := http.NewRequest(http.MethodDelete, "/rules/12", nil)
+	req, err := http.NewRequest(http.MethodPut, "/rules/12", strings.NewReader(body))
 	if err != nil {
 		t.Errorf("Unexpected error: %#v", err)
 	}
 
+	req.Header.Set("Content-Type", "application/json")
+
 	rctx := chi.NewRouteContext()
 	rctx.URLParams.Add("id", "12")
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(ruleHandler.Delete)
+	handler := http.HandlerFunc(ruleHandler.Update)
 
 	handler.ServeHTTP(rr, req)
 
 	assert.Equal(t, rr.Code, http.StatusNotFound)
 }
 
-func TestRuleHandler_Delete_InternalServerError(t *testing.T) {
+func TestRuleHandler_Update_Conflict(t *testing.T) {
 	t.Parallel()
 
 	ruleHandler, ruleSrvcMock := testPrepareRuleHandler(t)
 
-	ruleSrvcMock.EXPECT().Delete(gomock.Any(), 12).Return(errs.ServiceUnknownError)
+	rule := model.Rule{
+		ID:           12,
+		Regex:        `^google\.com$`,
+		Enabled:      true,
+		Tags:         model.Tags{},
+		ProxyProfile: &model.ProxyProfile{ID: 1},
+	}
+
+	ruleSrvcMock.EXPECT().Update(gomock.Any(), rule).Return(errs.InvalidReferenceError)
+	body := `{"domain":"google.com","mode":"domain","proxy_profile_id":1}`
 
-	req, err := http.NewRequest(http.MethodDelete, "/rules/12", nil)
+	req, err := http.NewRequest(http.MethodPut, "/rules/12", strings.NewReader(body))
 	if err != nil {
 		t.Errorf("Unexpected error: %#v", err)
 	}
 
+	req.Header.Set("Content-Type", "application/json")
+
 	rctx := chi.NewRouteContext()
 	rctx.URLParams.Add("id", "12")
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(ruleHandler.Delete)
+	handler := http.HandlerFunc(ruleHandler.Update)
 
 	handler.ServeHTTP(rr, req)
 
-	assert.Equal(t, rr.Code, http.StatusInternalServerError)
+	assert.Equal(t, rr.Code, http.StatusConflict)
+}
+
+func TestRuleHandler_Update_InternalServerError(t *testing.T) {
+	t.Parallel()
+
+	ruleHandler, ruleSrvcMock := testPrepareRuleHandler(t)
+
+	rule := model.Rule{
+		ID:           12,
+		Regex:        `^google\.com$`,
+		Enabled:      true,
+		Tags:         model.Tags{},
+		ProxyProfile: &model.ProxyProfile{ID: 1},
+	}
+
+	ruleSrvcMock.EXPECT().Update(gomock.Any(), rule).Return(errs.ServiceUnknownError)
+	body := `{"domain":"google.com","mode":"domain","proxy_profile_id":1}`
+
+	req, err := http.NewRequest(http.MethodPut, "/rules/12", strings.NewReader(body))
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "12")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(ruleHandler.Update)
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, rr.Code, http.StatusInternalServerError)
+}
+
+func TestRuleHandler_Delete_OK(t *testing.T) {
+	t.Parallel()
+
+	ruleHandler, ruleSrvcMock := testPrepareRuleHandler(t)
+
+	ruleSrvcMock.EXPECT().Delete(gomock.Any(), 12, 0).Return(nil)
+
+	req, err := http.NewRequest(http.MethodDelete, "/rules/12", nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "12")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(ruleHandler.Delete)
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, rr.Code, http.StatusNoContent)
+}
+
+func TestRuleHandler_Delete_NotFound(t *testing.T) {
+	t.Parallel()
+
+	ruleHandler, ruleSrvcMock := testPrepareRuleHandler(t)
+
+	ruleSrvcMock.EXPECT().Delete(gomock.Any(), 12, 0).Return(&errs.EntityNotFoundError{})
+
+	req, err := http.NewRequest(http.MethodDelete, "/rules/12", nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "12")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(ruleHandler.Delete)
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, rr.Code, http.StatusNotFound)
+}
+
+func TestRuleHandler_Delete_InternalServerError(t *testing.T) {
+	t.Parallel()
+
+	ruleHandler, ruleSrvcMock := testPrepareRuleHandler(t)
+
+	ruleSrvcMock.EXPECT().Delete(gomock.Any(), 12, 0).Return(errs.ServiceUnknownError)
+
+	req, err := http.NewRequest(http.MethodDelete, "/rules/12", nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "12")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(ruleHandler.Delete)
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, rr.Code, http.StatusInternalServerError)
+}
+
+func TestRuleHandler_Delete_IfMatch(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		ifMatch  string
+		wantCode int
+	}{
+		"missing":   {ifMatch: "", wantCode: http.StatusPreconditionRequired},
+		"weak":      {ifMatch: `W/"2"`, wantCode: http.StatusPreconditionFailed},
+		"unquoted":  {ifMatch: "2", wantCode: http.StatusPreconditionFailed},
+		"malformed": {ifMatch: `"two"`, wantCode: http.StatusPreconditionFailed},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			ruleHandler := NewRuleHandler(mock.NewRuleService(ctrl), nil, nil, nil, true, logutil.DiscardLogger)
+
+			req, err := http.NewRequest(http.MethodDelete, "/rules/12", nil)
+			if err != nil {
+				t.Errorf("Unexpected error: %#v", err)
+			}
+
+			if c.ifMatch != "" {
+				req.Header.Set("If-Match", c.ifMatch)
+			}
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "12")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(ruleHandler.Delete)
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, rr.Code, c.wantCode)
+		})
+	}
+}
+
+func TestRuleHandler_Delete_Version(t *testing.T) {
+	t.Parallel()
+
+	ruleHandler, ruleSrvcMock := testPrepareRuleHandler(t)
+
+	ruleSrvcMock.EXPECT().Delete(gomock.Any(), 12, 2).Return(nil)
+
+	req, err := http.NewRequest(http.MethodDelete, "/rules/12", nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	req.Header.Set("If-Match", `"2"`)
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "12")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(ruleHandler.Delete)
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, rr.Code, http.StatusNoContent)
+}
+
+func TestRuleHandler_BulkUpdate_OK(t *testing.T) {
+	t.Parallel()
+
+	ruleHandler, ruleSrvcMock := testPrepareRuleHandler(t)
+
+	enabled := false
+	want := `{"affected":3}`
+
+	ruleSrvcMock.EXPECT().
+		UpdateByTag(gomock.Any(), "streaming", model.RuleBulkUpdate{Enabled: &enabled}).
+		Return(3, nil)
+
+	req, err := http.NewRequest(http.MethodPatch, "/rules?tag=streaming", strings.NewReader(`{"enabled":false}`))
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(ruleHandler.BulkUpdate)
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, rr.Code, http.StatusOK)
+	assert.Equal(t, strings.TrimSpace(rr.Body.String()), want)
+}
+
+func TestRuleHandler_BulkUpdate_BadRequest(t *testing.T) {
+	t.Parallel()
+
+	ruleHandler, _ := testPrepareRuleHandler(t)
+
+	req, err := http.NewRequest(http.MethodPatch, "/rules", strings.NewReader(`{"enabled":false}`))
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(ruleHandler.BulkUpdate)
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, rr.Code, http.StatusBadRequest)
+}
+
+func TestRuleHandler_BulkUpdate_Conflict(t *testing.T) {
+	t.Parallel()
+
+	ruleHandler, ruleSrvcMock := testPrepareRuleHandler(t)
+
+	profileID := 9
+
+	ruleSrvcMock.EXPECT().
+		UpdateByTag(gomock.Any(), "streaming", model.RuleBulkUpdate{ProxyProfileID: &profileID}).
+		Return(0, errs.InvalidReferenceError)
+
+	req, err := http.NewRequest(http.MethodPatch, "/rules?tag=streaming", strings.NewReader(`{"proxy_profile_id":9}`))
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(ruleHandler.BulkUpdate)
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, rr.Code, http.StatusConflict)
+}
+
+func TestRuleHandler_BulkDelete_OK(t *testing.T) {
+	t.Parallel()
+
+	ruleHandler, ruleSrvcMock := testPrepareRuleHandler(t)
+
+	want := `{"affected":2}`
+
+	ruleSrvcMock.EXPECT().DeleteByTag(gomock.Any(), "streaming").Return(2, nil)
+
+	req, err := http.NewRequest(http.MethodDelete, "/rules?tag=streaming", nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(ruleHandler.BulkDelete)
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, rr.Code, http.StatusOK)
+	assert.Equal(t, strings.TrimSpace(rr.Body.String()), want)
+}
+
+func TestRuleHandler_BulkDelete_BadRequest(t *testing.T) {
+	t.Parallel()
+
+	ruleHandler, _ := testPrepareRuleHandler(t)
+
+	req, err := http.NewRequest(http.MethodDelete, "/rules", nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(ruleHandler.BulkDelete)
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, rr.Code, http.StatusBadRequest)
+}
+
+func TestRuleHandler_Lint_OK(t *testing.T) {
+	t.Parallel()
+
+	ruleHandler, _, linterMock := testPrepareRuleHandlerWithLinter(t)
+
+	findings := []model.LintFinding{
+		{
+			Kind:       model.LintShadowed,
+			RuleID:     7,
+			RelatedIDs: []int{3},
+			Message:    "all the hosts it matches are matched by earlier rule 3",
+			Fix:        "delete the rule",
+		},
+		{Kind: model.LintNoop, RuleID: 9, Message: "domain list 2 is empty", Fix: "add domains to the list"},
+	}
+
+	linterMock.EXPECT().Lint(gomock.Any()).Return(findings, nil)
+
+	req, err := http.NewRequest(http.MethodGet, "/rules/lint", nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(ruleHandler.Lint)
+
+	handler.ServeHTTP(rr, req)
+
+	want := `[{"kind":"shadowed","rule_id":7,"related_rule_ids":[3],` +
+		`"message":"all the hosts it matches are matched by earlier rule 3","fix":"delete the rule"},` +
+		`{"kind":"noop","rule_id":9,"related_rule_ids":[],"message":"domain list 2 is empty",` +
+		`"fix":"add domains to the list"}]`
+	got := strings.TrimSuffix(rr.Body.String(), "\n")
+
+	assert.Equal(t, rr.Code, http.StatusOK)
+	assert.Equal(t, got, want)
+}
+
+func TestRuleHandler_Create_LintWarning(t *testing.T) {
+	t.Parallel()
+
+	ruleHandler, ruleSrvcMock, linterMock := testPrepareRuleHandlerWithLinter(t)
+
+	findings := []model.LintFinding{
+		{Kind: model.LintDuplicate, RelatedIDs: []int{3}, Message: "matches the same hosts as rule 3"},
+	}
+
+	linterMock.EXPECT().Check(gomock.Any(), gomock.Any()).Return(findings, nil)
+	ruleSrvcMock.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+
+	body := `{"domain":"google.com","mode":"domain","proxy_profile_id":1}`
+
+	req, err := http.NewRequest(http.MethodPost, "http://localhost/rules", strings.NewReader(body))
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(ruleHandler.Create)
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, rr.Code, http.StatusCreated)
+	assert.Equal(t, rr.Header().Get("Warning"), `299 - "duplicate: matches the same hosts as rule 3"`)
+}
+
+func TestRuleHandler_Create_LintStrict(t *testing.T) {
+	t.Parallel()
+
+	ruleHandler, _, linterMock := testPrepareRuleHandlerWithLinter(t)
+
+	findings := []model.LintFinding{
+		{Kind: model.LintConflict, RelatedIDs: []int{3}, Message: "matches the same hosts as rule 3", Fix: "delete"},
+	}
+
+	linterMock.EXPECT().Check(gomock.Any(), gomock.Any()).Return(findings, nil)
+
+	body := `{"domain":"google.com","mode":"domain","proxy_profile_id":1}`
+
+	req, err := http.NewRequest(http.MethodPost, "http://localhost/rules?strict=true", strings.NewReader(body))
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(ruleHandler.Create)
+
+	handler.ServeHTTP(rr, req)
+
+	want := `{"type":"urn:pacgen:problem:lint-conflict","title":"Conflict","status":409,` +
+		`"detail":"Rule has problems","instance":"/rules","findings":[{"kind":"conflict","related_rule_ids":[3],` +
+		`"message":"matches the same hosts as rule 3","fix":"delete"}]}`
+	got := strings.TrimSuffix(rr.Body.String(), "\n")
+
+	assert.Equal(t, rr.Code, http.StatusConflict)
+	assert.Equal(t, got, want)
+}
+
+func TestRuleHandler_Expiring_OK(t *testing.T) {
+	t.Parallel()
+
+	ruleHandler, ruleSrvcMock := testPrepareRuleHandler(t)
+
+	expiresAt := time.Date(2022, 9, 8, 10, 0, 0, 0, time.UTC)
+	ruleSrvcMock.EXPECT().GetExpiring(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, until time.Time) ([]model.Rule, error) {
+			if d := time.Until(until); d < 71*time.Hour || d > 72*time.Hour {
+				t.Errorf("Unexpected until: %s", until)
+			}
+			return []model.Rule{
+				{
+					ID:           10,
+					Regex:        `^google\.com$`,
+					Enabled:      true,
+					Tags:         model.Tags{"vendor"},
+					ProxyProfile: &model.ProxyProfile{ID: 1},
+					ExpiresAt:    &expiresAt,
+				},
+			}, nil
+		},
+	)
+
+	req, err := http.NewRequest(http.MethodGet, "/rules/expiring?within=72h", nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(ruleHandler.Expiring)
+
+	handler.ServeHTTP(rr, req)
+
+	got := strings.TrimSuffix(rr.Body.String(), "\n")
+	want := `[{"id":10,"regexp":"^google\\.com$","domain":"google.com","domain_unicode":"google.com","mode":"domain",` +
+		`"proxy_profile_id":1,"enabled":true,"tags":["vendor"],"expires_at":"2022-09-08T10:00:00Z"}]`
+
+	assert.Equal(t, rr.Code, http.StatusOK)
+
+	assert.Equal(t, got, want)
+}
+
+func TestRuleHandler_Expiring_BadRequest(t *testing.T) {
+	t.Parallel()
+
+	ruleHandler, _ := testPrepareRuleHandler(t)
+
+	cases := map[string]string{
+		"not a duration": "within=week",
+		"negative":       "within=-1h",
+	}
+
+	for name, query := range cases {
+		query := query
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(http.MethodGet, "/rules/expiring?"+query, nil)
+			if err != nil {
+				t.Errorf("Unexpected error: %#v", err)
+			}
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(ruleHandler.Expiring)
+
+			handler.ServeHTTP(rr, req)
+
+			got := strings.TrimSuffix(rr.Body.String(), "\n")
+
+			assert.Equal(t, rr.Code, http.StatusBadRequest)
+
+			assert.Equal(t, got, `{"type":"about:blank","title":"Bad Request","status":400,`+
+				`"detail":"Query parameter 'within' must be a positive duration, e.g. 72h","instance":"/rules/expiring"}`)
+		})
+	}
+}
+
+func TestRuleHandler_Match_OK(t *testing.T) {
+	t.Parallel()
+
+	ruleHandler, matcherMock := testPrepareRuleHandlerWithMatcher(t)
+
+	matcherMock.EXPECT().Match(gomock.Any(), "a.b.xn--e1afmkfd.xn--p1ai").Return(model.HostMatch{
+		Host: "a.b.xn--e1afmkfd.xn--p1ai",
+		Matches: []model.RuleMatch{
+			{
+				Rule:  model.Rule{ID: 3, DomainListID: 2, ProxyProfile: &model.ProxyProfile{ID: 1}},
+				Proxy: "HTTPS proxy.example:443",
+			},
+			{
+				Rule:  model.Rule{ID: 5, Regex: `(?:^|\.)xn--e1afmkfd\.xn--p1ai$`, ProxyProfile: &model.ProxyProfile{ID: 2}},
+				Proxy: "DIRECT",
+			},
+		},
+		Proxy: "HTTPS proxy.example:443",
+	}, nil)
+
+	req, err := http.NewRequest(http.MethodGet, "/rules/match?host=a.b.%D0%BF%D1%80%D0%B8%D0%BC%D0%B5%D1%80.%D1%80%D1%84", nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(ruleHandler.Match)
+
+	handler.ServeHTTP(rr, req)
+
+	got := strings.TrimSuffix(rr.Body.String(), "\n")
+	want := `{"host":"a.b.xn--e1afmkfd.xn--p1ai","bypassed":false,"winner_rule_id":3,"proxy":"HTTPS proxy.example:443",` +
+		`"matches":[{"rule_id":3,"domain_list_id":2,"proxy_profile_id":1,"proxy":"HTTPS proxy.example:443"},` +
+		`{"rule_id":5,"regexp":"(?:^|\\.)xn--e1afmkfd\\.xn--p1ai$","domain":"xn--e1afmkfd.xn--p1ai",` +
+		`"domain_unicode":"пример.рф","mode":"domain_and_subdomains","proxy_profile_id":2,"proxy":"DIRECT"}]}`
+
+	assert.Equal(t, rr.Code, http.StatusOK)
+
+	assert.Equal(t, got, want)
+}
+
+func TestRuleHandler_Match_Bypassed(t *testing.T) {
+	t.Parallel()
+
+	ruleHandler, matcherMock := testPrepareRuleHandlerWithMatcher(t)
+
+	matcherMock.EXPECT().Match(gomock.Any(), "::1").Return(model.HostMatch{
+		Host:     "::1",
+		Bypassed: true,
+		Matches: []model.RuleMatch{
+			{Rule: model.Rule{ID: 1, Regex: `^::1$`, ProxyProfile: &model.ProxyProfile{ID: 1}}, Proxy: "PROXY a:8080"},
+		},
+		Proxy: "DIRECT",
+	}, nil)
+
+	req, err := http.NewRequest(http.MethodGet, "/rules/match?host=[::1]", nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(ruleHandler.Match)
+
+	handler.ServeHTTP(rr, req)
+
+	got := strings.TrimSuffix(rr.Body.String(), "\n")
+	want := `{"host":"::1","bypassed":true,"proxy":"DIRECT",` +
+		`"matches":[{"rule_id":1,"regexp":"^::1$","domain":"::1","domain_unicode":"::1","mode":"domain",` +
+		`"proxy_profile_id":1,"proxy":"PROXY a:8080"}]}`
+
+	assert.Equal(t, rr.Code, http.StatusOK)
+
+	assert.Equal(t, got, want)
+}
+
+func TestRuleHandler_Match_BadRequest(t *testing.T) {
+	t.Parallel()
+
+	ruleHandler, _ := testPrepareRuleHandlerWithMatcher(t)
+
+	cases := map[string]string{
+		"missing":   "",
+		"with port": "host=example.com:8080",
+		"invalid":   "host=exa%20mple.com",
+	}
+
+	for name, query := range cases {
+		query := query
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(http.MethodGet, "/rules/match?"+query, nil)
+			if err != nil {
+				t.Errorf("Unexpected error: %#v", err)
+			}
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(ruleHandler.Match)
+
+			handler.ServeHTTP(rr, req)
+
+			got := strings.TrimSuffix(rr.Body.String(), "\n")
+
+			assert.Equal(t, rr.Code, http.StatusBadRequest)
+
+			assert.Equal(t, got, `{"type":"about:blank","title":"Bad Request","status":400,`+
+				`"detail":"Query parameter 'host' must be a host name or an IP address","instance":"/rules/match"}`)
+		})
+	}
 }
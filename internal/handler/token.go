@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"errors"
+	"github.com/go-chi/render"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/pkg/rest"
+	"github.com/rs/zerolog"
+	"net/http"
+)
+
+type TokenHandler struct {
+	logger  zerolog.Logger
+	service TokenService
+}
+
+func NewTokenHandler(service TokenService, logger zerolog.Logger) *TokenHandler {
+	return &TokenHandler{
+		logger:  logger,
+		service: service,
+	}
+}
+
+// GetAll responds with all the tokens to admins and with their personal tokens to the other users.
+func (h *TokenHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	tokens, err := h.service.GetAll(r.Context())
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Error occurred while getting all API tokens")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return
+	}
+
+	tokenEntities := make([]TokenR, 0)
+	for _, token := range tokens {
+		tokenR := TokenR{}
+		tokenR.FromModel(token)
+		tokenEntities = append(tokenEntities, tokenR)
+	}
+
+	render.JSON(w, r, tokenEntities)
+	w.WriteHeader(http.StatusOK)
+}
+
+// Create creates the token and responds with it, the token itself is never shown again.
+func (h *TokenHandler) Create(w http.ResponseWriter, r *http.Request) {
+	tokenC := TokenC{}
+	if ok := getFromBodyAndValidate(w, r, h.logger, &tokenC); !ok {
+		return
+	}
+
+	tokenModel, err := tokenC.ToModel()
+	if err != nil {
+		renderConversionError(w, r, h.logger, err)
+		return
+	}
+
+	plain, err := h.service.Create(r.Context(), &tokenModel, tokenC.Service)
+	if err != nil {
+		if errors.Is(err, errs.TokenNotAllowedError) {
+			h.logger.Debug().Err(err).Send()
+			Render(w, r, errorResponse(err), h.logger)
+			return
+		}
+		h.logger.Error().Err(err).Msg("Error occurred while creating API token")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return
+	}
+
+	createdR := TokenCreatedR{Token: plain}
+	createdR.FromModel(tokenModel)
+
+	// The token is shown once, so the response is neither cached nor stored for the repeats of the request.
+	w.Header().Set("Cache-Control", "no-store")
+
+	rest.CreatedJSON(w, r, tokenModel.ID, createdR)
+}
+
+// Delete revokes the token.
+func (h *TokenHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := getIDFromURL(w, r, h.logger)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(r.Context(), id); err != nil {
+		if _, ok := err.(*errs.EntityNotFoundError); ok {
+			h.logger.Debug().Err(err).Send()
+			Render(w, r, errorResponse(err), h.logger)
+			return
+		}
+		h.logger.Error().Err(err).Msg("Error occurred while revoking API token")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return
+	}
+
+	render.NoContent(w, r)
+}
@@ -0,0 +1,230 @@
+package handler
+
+import (
+	"context"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/handler/mock"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/nnemirovsky/pacgen/pkg/logutil"
+	"github.com/nnemirovsky/pacgen/pkg/rest"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testPrepareTokenHandler(t *testing.T) (*TokenHandler, *mock.TokenService) {
+	ctrl := gomock.NewController(t)
+	tokenSrvcMock := mock.NewTokenService(ctrl)
+
+	return NewTokenHandler(tokenSrvcMock, logutil.DiscardLogger), tokenSrvcMock
+}
+
+func TestTokenHandler_GetAll_OK(t *testing.T) {
+	t.Parallel()
+
+	tokenHandler, tokenSrvcMock := testPrepareTokenHandler(t)
+
+	createdAt := time.Date(2023, 6, 6, 10, 0, 0, 0, time.UTC)
+	tokens := []model.APIToken{
+		{
+			ID:        1,
+			Name:      "laptop",
+			UserID:    2,
+			UserName:  "bob",
+			TokenHash: "hash",
+			Scopes:    model.Scopes{"rules:read"},
+			CreatedAt: createdAt,
+		},
+		{
+			ID:         2,
+			Name:       "ci",
+			TokenHash:  "hash",
+			Scopes:     model.Scopes{"pac:read"},
+			LastUsedAt: &createdAt,
+			CreatedAt:  createdAt,
+		},
+	}
+
+	tokenSrvcMock.EXPECT().GetAll(gomock.Any()).Return(tokens, nil)
+
+	req, err := http.NewRequest(http.MethodGet, "/tokens", nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(tokenHandler.GetAll)
+
+	handler.ServeHTTP(rr, req)
+
+	got := strings.TrimSuffix(rr.Body.String(), "\n")
+	want := `[{"id":1,"name":"laptop","kind":"personal","user":"bob","scopes":["rules:read"],` +
+		`"created_at":"2023-06-06T10:00:00Z"},{"id":2,"name":"ci","kind":"service","scopes":["pac:read"],` +
+		`"last_used_at":"2023-06-06T10:00:00Z","created_at":"2023-06-06T10:00:00Z"}]`
+
+	assert.Equal(t, rr.Code, http.StatusOK)
+
+	assert.Equal(t, got, want)
+}
+
+func TestTokenHandler_Create_OK(t *testing.T) {
+	t.Parallel()
+
+	tokenHandler, tokenSrvcMock := testPrepareTokenHandler(t)
+
+	createdAt := time.Date(2023, 6, 6, 10, 0, 0, 0, time.UTC)
+	token := model.APIToken{Name: "ci", Scopes: model.Scopes{"pac:read", "rules:write"}}
+
+	tokenSrvcMock.EXPECT().Create(gomock.Any(), &token, true).DoAndReturn(
+		func(ctx context.Context, t *model.APIToken, service bool) (string, error) {
+			*t = model.APIToken{ID: 3, Name: "ci", Scopes: model.Scopes{"pac:read", "rules:write"}, CreatedAt: createdAt}
+			return "pacgen_secret", nil
+		},
+	)
+
+	body := `{"name":"ci","scopes":["rules:write","pac:read","rules:write"],"service":true}`
+	req, err := http.NewRequest(http.MethodPost, "http://localhost/tokens", strings.NewReader(body))
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(tokenHandler.Create)
+
+	handler.ServeHTTP(rr, req)
+
+	got := strings.TrimSuffix(rr.Body.String(), "\n")
+	want := `{"id":3,"name":"ci","kind":"service","scopes":["pac:read","rules:write"],` +
+		`"created_at":"2023-06-06T10:00:00Z","token":"pacgen_secret"}`
+
+	assert.Equal(t, rr.Code, http.StatusCreated)
+
+	assert.Equal(t, rr.Header().Get("Location"), "http://localhost/tokens/3")
+
+	assert.Equal(t, got, want)
+}
+
+// testIdempotencyStore keeps the idempotent responses in memory.
+type testIdempotencyStore map[string]rest.IdempotentResponse
+
+func (s testIdempotencyStore) Get(_ context.Context, key string) (rest.IdempotentResponse, bool, error) {
+	resp, ok := s[key]
+	return resp, ok, nil
+}
+
+func (s testIdempotencyStore) Save(_ context.Context, key string, resp rest.IdempotentResponse, _ time.Time) error {
+	s[key] = resp
+	return nil
+}
+
+func TestTokenHandler_Create_NotStoredForIdempotency(t *testing.T) {
+	t.Parallel()
+
+	tokenHandler, tokenSrvcMock := testPrepareTokenHandler(t)
+
+	tokenSrvcMock.EXPECT().Create(gomock.Any(), gomock.Any(), false).DoAndReturn(
+		func(ctx context.Context, t *model.APIToken, service bool) (string, error) {
+			t.ID = 3
+			return "pacgen_secret", nil
+		},
+	).Times(2)
+
+	store := testIdempotencyStore{}
+	handler := rest.Idempotency(store, time.Hour, func(r *http.Request) string { return "bob" })(
+		http.HandlerFunc(tokenHandler.Create),
+	)
+
+	// The repeat creates another token instead of showing the first one again.
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest(http.MethodPost, "/tokens", strings.NewReader(`{"name":"ci","scopes":["rules:read"]}`))
+		if err != nil {
+			t.Errorf("Unexpected error: %#v", err)
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(rest.IdempotencyKeyHeader, "key-1")
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, rr.Code, http.StatusCreated)
+		assert.Equal(t, rr.Header().Get("Cache-Control"), "no-store")
+		assert.Equal(t, rr.Header().Get(rest.IdempotentReplayedHeader), "")
+	}
+
+	for _, resp := range store {
+		if strings.Contains(string(resp.Body), "pacgen_secret") {
+			t.Errorf("Token is stored for idempotency: %s", resp.Body)
+		}
+	}
+	assert.Equal(t, len(store), 0)
+}
+
+func TestTokenHandler_Create_UnprocessableEntity(t *testing.T) {
+	t.Parallel()
+
+	tokenHandler, _ := testPrepareTokenHandler(t)
+
+	cases := map[string]string{
+		"missing scopes":   `{"name":"ci"}`,
+		"unknown resource": `{"name":"ci","scopes":["proxy.pac:read"]}`,
+		"unknown access":   `{"name":"ci","scopes":["rules:delete"]}`,
+		"past expiry":      `{"name":"ci","scopes":["rules:read"],"expires_at":"2020-01-01T00:00:00Z"}`,
+	}
+
+	for name, body := range cases {
+		body := body
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(http.MethodPost, "/tokens", strings.NewReader(body))
+			if err != nil {
+				t.Errorf("Unexpected error: %#v", err)
+			}
+
+			req.Header.Set("Content-Type", "application/json")
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(tokenHandler.Create)
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, rr.Code, http.StatusUnprocessableEntity)
+		})
+	}
+}
+
+func TestTokenHandler_Create_Forbidden(t *testing.T) {
+	t.Parallel()
+
+	tokenHandler, tokenSrvcMock := testPrepareTokenHandler(t)
+
+	tokenSrvcMock.EXPECT().Create(gomock.Any(), gomock.Any(), true).Return("", errs.TokenNotAllowedError)
+
+	body := `{"name":"ci","scopes":["rules:read"],"service":true}`
+	req, err := http.NewRequest(http.MethodPost, "/tokens", strings.NewReader(body))
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(tokenHandler.Create)
+
+	handler.ServeHTTP(rr, req)
+
+	got := strings.TrimSuffix(rr.Body.String(), "\n")
+	want := `{"type":"about:blank","title":"Forbidden","status":403,` +
+		`"detail":"API token cannot be allowed more than its creator is","instance":"/tokens"}`
+
+	assert.Equal(t, rr.Code, http.StatusForbidden)
+
+	assert.Equal(t, got, want)
+}
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"errors"
+	"github.com/go-chi/render"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/pkg/rest"
+	"github.com/rs/zerolog"
+	"net/http"
+)
+
+type UserHandler struct {
+	logger  zerolog.Logger
+	service UserService
+}
+
+func NewUserHandler(service UserService, logger zerolog.Logger) *UserHandler {
+	return &UserHandler{
+		logger:  logger,
+		service: service,
+	}
+}
+
+func (h *UserHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	users, err := h.service.GetAll(r.Context())
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Error occurred while getting all users")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return
+	}
+
+	userEntities := make([]UserR, 0)
+	for _, user := range users {
+		userR := UserR{}
+		userR.FromModel(user)
+		userEntities = append(userEntities, userR)
+	}
+
+	render.JSON(w, r, userEntities)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *UserHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := getIDFromURL(w, r, h.logger)
+	if !ok {
+		return
+	}
+
+	user, err := h.service.GetByID(r.Context(), id)
+	if err != nil {
+		if _, ok := err.(*errs.EntityNotFoundError); ok {
+			h.logger.Debug().Err(err).Send()
+			Render(w, r, errorResponse(err), h.logger)
+			return
+		}
+		h.logger.Error().Err(err).Msg("Error occurred while getting user by id")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return
+	}
+
+	userR := UserR{}
+	userR.FromModel(user)
+
+	render.JSON(w, r, userR)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *UserHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userC := UserC{}
+	if ok := getFromBodyAndValidate(w, r, h.logger, &userC); !ok {
+		return
+	}
+
+	userModel, err := userC.ToModel()
+	if err != nil {
+		renderConversionError(w, r, h.logger, err)
+		return
+	}
+
+	if err := h.service.Create(r.Context(), &userModel, userC.Password); err != nil {
+		if _, ok := err.(*errs.EntityAlreadyExistsError); ok {
+			h.logger.Debug().Err(err).Send()
+			Render(w, r, errorResponse(err), h.logger)
+			return
+		}
+		h.logger.Error().Err(err).Msg("Error occurred while creating user")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return
+	}
+
+	rest.Created(w, r, userModel.ID)
+}
+
+// Update updates the user, its password is kept unless a new one is given.
+func (h *UserHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, ok := getIDFromURL(w, r, h.logger)
+	if !ok {
+		return
+	}
+
+	userU := UserU{}
+	if ok := getFromBodyAndValidate(w, r, h.logger, &userU); !ok {
+		return
+	}
+
+	userModel, err := userU.ToModel()
+	if err != nil {
+		renderConversionError(w, r, h.logger, err)
+		return
+	}
+	userModel.ID = id
+
+	if err := h.service.Update(r.Context(), userModel, userU.Password); err != nil {
+		var (
+			notFound      *errs.EntityNotFoundError
+			alreadyExists *errs.EntityAlreadyExistsError
+		)
+		if errors.As(err, &notFound) || errors.As(err, &alreadyExists) || errors.Is(err, errs.LastAdminError) {
+			h.logger.Debug().Err(err).Send()
+			Render(w, r, errorResponse(err), h.logger)
+			return
+		}
+		h.logger.Error().Err(err).Msg("Error occurred while updating user")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return
+	}
+
+	render.NoContent(w, r)
+}
+
+func (h *UserHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := getIDFromURL(w, r, h.logger)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(r.Context(), id); err != nil {
+		var notFound *errs.EntityNotFoundError
+		if errors.As(err, &notFound) || errors.Is(err, errs.LastAdminError) {
+			h.logger.Debug().Err(err).Send()
+			Render(w, r, errorResponse(err), h.logger)
+			return
+		}
+		h.logger.Error().Err(err).Msg("Error occurred while deleting user")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return
+	}
+
+	render.NoContent(w, r)
+}
@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"context"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/handler/mock"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/nnemirovsky/pacgen/pkg/logutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testPrepareUserHandler(t *testing.T) (*UserHandler, *mock.UserService) {
+	ctrl := gomock.NewController(t)
+	userSrvcMock := mock.NewUserService(ctrl)
+
+	return NewUserHandler(userSrvcMock, logutil.DiscardLogger), userSrvcMock
+}
+
+func TestUserHandler_GetAll_OK(t *testing.T) {
+	t.Parallel()
+
+	userHandler, userSrvcMock := testPrepareUserHandler(t)
+
+	createdAt := time.Date(2023, 6, 6, 10, 0, 0, 0, time.UTC)
+	users := []model.User{
+		{ID: 1, Name: "alice", PasswordHash: "$2a$10$hash", Role: model.Admin, CreatedAt: createdAt, UpdatedAt: createdAt},
+	}
+
+	userSrvcMock.EXPECT().GetAll(gomock.Any()).Return(users, nil)
+
+	req, err := http.NewRequest(http.MethodGet, "/users", nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(userHandler.GetAll)
+
+	handler.ServeHTTP(rr, req)
+
+	got := strings.TrimSuffix(rr.Body.String(), "\n")
+	want := `[{"id":1,"name":"alice","role":"admin","created_at":"2023-06-06T10:00:00Z",` +
+		`"updated_at":"2023-06-06T10:00:00Z"}]`
+
+	assert.Equal(t, rr.Code, http.StatusOK)
+
+	assert.Equal(t, got, want)
+}
+
+func TestUserHandler_Create_OK(t *testing.T) {
+	t.Parallel()
+
+	userHandler, userSrvcMock := testPrepareUserHandler(t)
+
+	user := model.User{Name: "bob", Role: model.Editor}
+
+	userSrvcMock.EXPECT().Create(gomock.Any(), &user, "correct horse").DoAndReturn(
+		func(ctx context.Context, u *model.User, password string) error {
+			u.ID = 2
+			return nil
+		},
+	)
+
+	body := `{"name":"bob","password":"correct horse","role":"editor"}`
+	req, err := http.NewRequest(http.MethodPost, "http://localhost/users", strings.NewReader(body))
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(userHandler.Create)
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, rr.Code, http.StatusCreated)
+
+	assert.Equal(t, rr.Header().Get("Location"), "http://localhost/users/2")
+}
+
+func TestUserHandler_Create_UnprocessableEntity(t *testing.T) {
+	t.Parallel()
+
+	userHandler, _ := testPrepareUserHandler(t)
+
+	cases := map[string]string{
+		"missing password": `{"name":"bob","role":"editor"}`,
+		"short password":   `{"name":"bob","password":"short","role":"editor"}`,
+		"colon in name":    `{"name":"bob:smith","password":"correct horse","role":"editor"}`,
+		"unknown role":     `{"name":"bob","password":"correct horse","role":"owner"}`,
+	}
+
+	for name, body := range cases {
+		body := body
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+			if err != nil {
+				t.Errorf("Unexpected error: %#v", err)
+			}
+
+			req.Header.Set("Content-Type", "application/json")
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(userHandler.Create)
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, rr.Code, http.StatusUnprocessableEntity)
+		})
+	}
+}
+
+func TestUserHandler_Update_LastAdmin(t *testing.T) {
+	t.Parallel()
+
+	userHandler, userSrvcMock := testPrepareUserHandler(t)
+
+	// The password is kept when it is not given.
+	userSrvcMock.EXPECT().
+		Update(gomock.Any(), model.User{ID: 1, Name: "alice", Role: model.Viewer}, "").
+		Return(errs.LastAdminError)
+
+	req, err := http.NewRequest(http.MethodPut, "/users/1", strings.NewReader(`{"name":"alice","role":"viewer"}`))
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "1")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(userHandler.Update)
+
+	handler.ServeHTTP(rr, req)
+
+	got := strings.TrimSuffix(rr.Body.String(), "\n")
+	want := `{"type":"urn:pacgen:problem:last-admin","title":"Conflict","status":409,` +
+		`"detail":"the last admin can be neither deleted nor demoted","instance":"/users/1"}`
+
+	assert.Equal(t, rr.Code, http.StatusConflict)
+
+	assert.Equal(t, got, want)
+}
+
+func TestUserHandler_Delete_NotFound(t *testing.T) {
+	t.Parallel()
+
+	userHandler, userSrvcMock := testPrepareUserHandler(t)
+
+	userSrvcMock.EXPECT().Delete(gomock.Any(), 7).Return(&errs.EntityNotFoundError{Name: "user", Key: "id", Value: 7})
+
+	req, err := http.NewRequest(http.MethodDelete, "/users/7", nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "7")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(userHandler.Delete)
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, rr.Code, http.StatusNotFound)
+}
@@ -1,23 +1,47 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 	"github.com/go-playground/validator/v10"
+	"github.com/nnemirovsky/pacgen/internal/model"
 	"github.com/nnemirovsky/pacgen/pkg/rest"
 	"github.com/rs/zerolog"
+	"math"
 	"net/http"
+	"reflect"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 )
 
 var validate *validator.Validate
 
 func init() {
 	validate = validator.New()
+	// Validation errors name the fields as the request body does.
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
 }
 
+// Render renders the response, problems are rendered as problem details (RFC 7807).
 func Render(w http.ResponseWriter, r *http.Request, v render.Renderer, logger zerolog.Logger) {
-	if err := render.Render(w, r, v); err != nil {
+	var err error
+	if problem, ok := v.(rest.Problem); ok {
+		err = rest.RenderProblem(w, r, problem)
+	} else {
+		err = render.Render(w, r, v)
+	}
+	if err != nil {
 		logger.Error().Err(err).Msg("Error occurred while trying to render response")
 		w.WriteHeader(http.StatusInternalServerError)
 	}
@@ -33,18 +57,485 @@ func getIDFromURL(w http.ResponseWriter, r *http.Request, logger zerolog.Logger)
 	return id, true
 }
 
+// getIfMatch returns the version of the entity given in If-Match header as ETag of the entity. Zero version
+// matches any, it is returned for * and for the missing header unless the header is required.
+func getIfMatch(w http.ResponseWriter, r *http.Request, logger zerolog.Logger, required bool) (version int, ok bool) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	switch value {
+	case "":
+		if required {
+			logger.Debug().Msg("Missing If-Match header")
+			problem := rest.PreconditionRequiredResponse("If-Match header with the ETag of the entity is required")
+			Render(w, r, problem.WithType(ProblemPreconditionRequired), logger)
+			return 0, false
+		}
+		return 0, true
+	case "*":
+		return 0, true
+	}
+
+	// Weak and malformed tags never match, as If-Match compares tags strongly.
+	version, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(value, `"`), `"`))
+	if err != nil || version < 1 || !strings.HasPrefix(value, `"`) || !strings.HasSuffix(value, `"`) {
+		logger.Debug().Str("if-match", value).Msg("Invalid If-Match header")
+		problem := rest.PreconditionFailedResponse("If-Match header does not match the ETag of the entity")
+		Render(w, r, problem.WithType(ProblemVersionConflict), logger)
+		return 0, false
+	}
+	return version, true
+}
+
+// setETag sets ETag header to the version of the entity, unknown zero version is omitted.
+func setETag(w http.ResponseWriter, version int) {
+	if version > 0 {
+		w.Header().Set("ETag", strconv.Quote(strconv.Itoa(version)))
+	}
+}
+
+// getTagFromQuery returns the required tag query parameter. Bulk operations refuse to run without it,
+// so a forgotten parameter does not affect all the rules.
+func getTagFromQuery(w http.ResponseWriter, r *http.Request, logger zerolog.Logger) (tag string, ok bool) {
+	tag = r.URL.Query().Get("tag")
+	if tag == "" {
+		logger.Debug().Msg("Missing tag query parameter")
+		Render(w, r, rest.BadRequestResponse("Query parameter 'tag' is required"), logger)
+		return "", false
+	}
+	return tag, true
+}
+
+// getListQuery returns the metadata filter and the sort given in query parameters: owner, description,
+// created_after, created_before, updated_after and updated_before in RFC 3339, and sort with one of the sort fields,
+// prefixed with a minus for descending order.
+func getListQuery(
+	w http.ResponseWriter,
+	r *http.Request,
+	logger zerolog.Logger,
+	sortFields []string,
+) (filter model.MetadataFilter, sorting model.Sort, ok bool) {
+	query := r.URL.Query()
+	filter.Owner = query.Get("owner")
+	filter.Description = query.Get("description")
+
+	bounds := map[string]*time.Time{
+		"created_after":  &filter.CreatedAfter,
+		"created_before": &filter.CreatedBefore,
+		"updated_after":  &filter.UpdatedAfter,
+		"updated_before": &filter.UpdatedBefore,
+	}
+	for name, bound := range bounds {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			logger.Debug().Err(err).Str("param", name).Msg("Invalid time in query parameter")
+			Render(w, r, rest.BadRequestResponse(fmt.Sprintf("Query parameter '%s' must be in RFC 3339 format", name)), logger)
+			return model.MetadataFilter{}, model.Sort{}, false
+		}
+		*bound = t
+	}
+
+	if field := query.Get("sort"); field != "" {
+		sorting.Field, sorting.Desc = strings.TrimPrefix(field, "-"), strings.HasPrefix(field, "-")
+		known := false
+		for _, f := range sortFields {
+			known = known || f == sorting.Field
+		}
+		if !known {
+			logger.Debug().Str("sort", field).Msg("Unknown sort field")
+			Render(w, r, rest.BadRequestResponse(
+				fmt.Sprintf("Query parameter 'sort' must be one of: %s", strings.Join(sortFields, ", ")),
+			), logger)
+			return model.MetadataFilter{}, model.Sort{}, false
+		}
+	}
+	return filter, sorting, true
+}
+
+const (
+	// defaultPageLimit is the number of entities in a page of the audit log and the versions if the query does not
+	// give one.
+	defaultPageLimit = 100
+	// maxPageLimit is the largest number of entities in a page of a list.
+	maxPageLimit = 1000
+)
+
+// getPage returns the page given in limit and offset query parameters, the first defaultLimit entities by default.
+// Zero defaultLimit leaves the list unpaged unless the query asks for a page, so clients listing everything keep
+// getting all the entities.
+func getPage(
+	w http.ResponseWriter, r *http.Request, logger zerolog.Logger, defaultLimit int,
+) (page model.Page, ok bool) {
+	page.Limit = defaultLimit
+	params := []struct {
+		name    string
+		value   *int
+		min     int
+		max     int
+		message string
+	}{
+		{"limit", &page.Limit, 1, maxPageLimit, fmt.Sprintf("an integer from 1 to %d", maxPageLimit)},
+		{"offset", &page.Offset, 0, math.MaxInt32, "a non-negative integer"},
+	}
+	for _, p := range params {
+		value := r.URL.Query().Get(p.name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < p.min || n > p.max {
+			logger.Debug().Err(err).Str("param", p.name).Msg("Invalid page in query parameter")
+			Render(w, r, rest.BadRequestResponse(
+				fmt.Sprintf("Query parameter '%s' must be %s", p.name, p.message),
+			), logger)
+			return model.Page{}, false
+		}
+		*p.value = n
+	}
+	return page, true
+}
+
+// setPageHeaders reports the number of all the entities of the list in X-Total-Count header and links
+// to the first, previous, next and last pages of the list in Link header. Unlimited pages have no links.
+func setPageHeaders(w http.ResponseWriter, r *http.Request, page model.Page, total int) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	if page.Limit == 0 {
+		return
+	}
+
+	link := func(offset int, rel string) string {
+		u := *r.URL
+		query := u.Query()
+		query.Set("limit", strconv.Itoa(page.Limit))
+		query.Set("offset", strconv.Itoa(offset))
+		u.RawQuery = query.Encode()
+		return fmt.Sprintf(`<%s>; rel="%s"`, u.RequestURI(), rel)
+	}
+	last := 0
+	if total > 0 {
+		last = (total - 1) / page.Limit * page.Limit
+	}
+
+	links := []string{link(0, "first")}
+	if page.Offset > 0 {
+		prev := page.Offset - page.Limit
+		if prev < 0 {
+			prev = 0
+		}
+		links = append(links, link(prev, "prev"))
+	}
+	if page.Offset+page.Limit < total {
+		links = append(links, link(page.Offset+page.Limit, "next"))
+	}
+	links = append(links, link(last, "last"))
+	w.Header().Set("Link", strings.Join(links, ", "))
+}
+
+// getFields returns the fields given in comma separated fields query parameter along with the fields of the model
+// they are made of according to modelFields, which has all the known fields. Both are nil if the parameter
+// is missing.
+func getFields(
+	w http.ResponseWriter,
+	r *http.Request,
+	logger zerolog.Logger,
+	modelFields map[string][]string,
+) (fields []string, loaded []string, ok bool) {
+	value := r.URL.Query().Get("fields")
+	if value == "" {
+		return nil, nil, true
+	}
+
+	for _, field := range strings.Split(value, ",") {
+		fieldModelFields, known := modelFields[field]
+		if !known {
+			names := make([]string, 0, len(modelFields))
+			for name := range modelFields {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			logger.Debug().Str("field", field).Msg("Unknown field in query parameter")
+			Render(w, r, rest.BadRequestResponse(
+				fmt.Sprintf("Query parameter 'fields' must be a comma separated list of: %s", strings.Join(names, ", ")),
+			), logger)
+			return nil, nil, false
+		}
+		fields = append(fields, field)
+		for _, f := range fieldModelFields {
+			if !contains(loaded, f) {
+				loaded = append(loaded, f)
+			}
+		}
+	}
+	return fields, loaded, true
+}
+
+// getRelated returns the related entities given in the comma separated query parameter, e.g. expand or include,
+// each of them must be one of known. Nil is returned if the parameter is missing.
+func getRelated(
+	w http.ResponseWriter,
+	r *http.Request,
+	logger zerolog.Logger,
+	param string,
+	known []string,
+) (related []string, ok bool) {
+	value := r.URL.Query().Get(param)
+	if value == "" {
+		return nil, true
+	}
+
+	for _, name := range strings.Split(value, ",") {
+		if !contains(known, name) {
+			logger.Debug().Str(param, name).Msg("Unknown related entity in query parameter")
+			Render(w, r, rest.BadRequestResponse(
+				fmt.Sprintf("Query parameter '%s' must be a comma separated list of: %s", param, strings.Join(known, ", ")),
+			), logger)
+			return nil, false
+		}
+		if !contains(related, name) {
+			related = append(related, name)
+		}
+	}
+	return related, true
+}
+
+// project returns JSON objects of the entities with the given fields only, or the entities as they are
+// if no fields are given.
+func project[T any](entities []T, fields []string) (any, error) {
+	if len(fields) == 0 {
+		return entities, nil
+	}
+
+	objects := make([]map[string]json.RawMessage, 0, len(entities))
+	for _, entity := range entities {
+		data, err := json.Marshal(entity)
+		if err != nil {
+			return nil, err
+		}
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(data, &object); err != nil {
+			return nil, err
+		}
+		for name := range object {
+			if !contains(fields, name) {
+				delete(object, name)
+			}
+		}
+		objects = append(objects, object)
+	}
+	return objects, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// requestUser returns the name of the authenticated user, it is the default owner of new entities.
+func requestUser(r *http.Request) string {
+	return model.ActorFromContext(r.Context()).User
+}
+
 func getFromBodyAndValidate(w http.ResponseWriter, r *http.Request, logger zerolog.Logger, entity any) (ok bool) {
 	if err := render.DecodeJSON(r.Body, entity); err != nil {
 		logger.Debug().Err(err).Msg("Error occurred while decoding request body")
-		w.WriteHeader(http.StatusUnprocessableEntity)
+		Render(w, r, decodeErrorResponse(err), logger)
 		return false
 	}
 
 	if err := validate.Struct(entity); err != nil {
 		logger.Debug().Err(err).Msg("Error occurred while validating request body")
-		w.WriteHeader(http.StatusUnprocessableEntity)
+		var validationErrs validator.ValidationErrors
+		if !errors.As(err, &validationErrs) {
+			Render(w, r, rest.InternalErrorResponse(), logger)
+			return false
+		}
+		Render(w, r, validationResponse(fieldErrors(validationErrs)...), logger)
 		return false
 	}
 
 	return true
 }
+
+// getPatch decodes the request body as JSON merge patch (RFC 7396) of an entity with the given fields.
+// Unknown fields are rejected, so a misspelled field is not silently ignored.
+func getPatch(
+	w http.ResponseWriter,
+	r *http.Request,
+	logger zerolog.Logger,
+	fields map[string][]string,
+) (patch map[string]any, ok bool) {
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil || patch == nil {
+		logger.Debug().Err(err).Msg("Error occurred while decoding merge patch")
+		problem := rest.UnprocessableEntityResponse("Merge patch must be a JSON object").WithType(ProblemValidation)
+		Render(w, r, problem, logger)
+		return nil, false
+	}
+	for name := range patch {
+		if _, known := fields[name]; !known {
+			logger.Debug().Str("field", name).Msg("Unknown field in merge patch")
+			fieldErr := rest.FieldError{Field: name, Code: "unknown", Message: "is not a known field"}
+			Render(w, r, validationResponse(fieldErr), logger)
+			return nil, false
+		}
+	}
+	return patch, true
+}
+
+// patchedFields returns the fields of the model changed by the patch according to modelFields.
+func patchedFields(patch map[string]any, modelFields map[string][]string) []string {
+	fields := make([]string, 0, len(patch))
+	for name := range patch {
+		for _, f := range modelFields[name] {
+			if !contains(fields, f) {
+				fields = append(fields, f)
+			}
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// applyPatch merges the patch into the entity as RFC 7396 describes and validates the supplied fields
+// of the result only. Invalid fields are reported along with the field name.
+func applyPatch[T any](
+	w http.ResponseWriter,
+	r *http.Request,
+	logger zerolog.Logger,
+	entity T,
+	patch map[string]any,
+) (patched T, ok bool) {
+	var target any
+	data, err := json.Marshal(entity)
+	if err == nil {
+		err = json.Unmarshal(data, &target)
+	}
+	if err == nil {
+		data, err = json.Marshal(mergePatch(target, patch))
+	}
+	if err != nil {
+		logger.Error().Err(err).Msg("Error occurred while applying merge patch")
+		Render(w, r, rest.InternalErrorResponse(), logger)
+		return patched, false
+	}
+
+	if err := json.Unmarshal(data, &patched); err != nil {
+		logger.Debug().Err(err).Msg("Error occurred while decoding patched entity")
+		Render(w, r, decodeErrorResponse(err), logger)
+		return patched, false
+	}
+
+	paths := jsonFieldPaths(reflect.TypeOf(patched), "")
+	supplied := func(namespace string) (name string, ok bool) {
+		field := namespace[strings.IndexByte(namespace, '.')+1:]
+		for name = range patch {
+			path := paths[name]
+			if field == path || strings.HasPrefix(field, path+".") || strings.HasPrefix(field, path+"[") {
+				return name, true
+			}
+		}
+		return "", false
+	}
+	err = validate.StructFiltered(patched, func(ns []byte) bool {
+		namespace := string(ns)
+		if _, ok := supplied(namespace); ok {
+			return false
+		}
+		// Embedded structs are traversed to reach the supplied fields they have.
+		field := namespace[strings.IndexByte(namespace, '.')+1:]
+		for name := range patch {
+			if strings.HasPrefix(paths[name], field+".") {
+				return false
+			}
+		}
+		return true
+	})
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) && len(validationErrs) > 0 {
+		logger.Debug().Err(err).Msg("Error occurred while validating patched entity")
+		Render(w, r, validationResponse(fieldErrors(validationErrs)...), logger)
+		return patched, false
+	}
+	if err != nil {
+		logger.Error().Err(err).Msg("Error occurred while validating patched entity")
+		Render(w, r, rest.InternalErrorResponse(), logger)
+		return patched, false
+	}
+	return patched, true
+}
+
+// mergePatch returns the target with the patch applied: null removes the member, objects are merged recursively
+// and other values replace the member.
+func mergePatch(target, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = make(map[string]any, len(patchObject))
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+			continue
+		}
+		targetObject[name] = mergePatch(targetObject[name], value)
+	}
+	return targetObject
+}
+
+// jsonFieldPaths maps JSON names of the fields of the struct to their paths as validator expects them,
+// fields of embedded structs are promoted.
+func jsonFieldPaths(t reflect.Type, prefix string) map[string]string {
+	paths := make(map[string]string)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			for name, path := range jsonFieldPaths(field.Type, prefix+field.Name+".") {
+				paths[name] = path
+			}
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" {
+			name = field.Name
+		}
+		paths[name] = prefix + field.Name
+	}
+	return paths
+}
+
+// renderConversionError responds to a request body that could not be converted into the model. Errors tied
+// to a field are reported along with the field name.
+func renderConversionError(w http.ResponseWriter, r *http.Request, logger zerolog.Logger, err error) {
+	logger.Debug().Err(err).Msg("Error occurred while converting entity to corresponding model")
+	var fieldErr *FieldError
+	if errors.As(err, &fieldErr) {
+		Render(w, r, validationResponse(rest.FieldError{Field: fieldErr.Field, Message: fieldErr.Err.Error()}), logger)
+		return
+	}
+	Render(w, r, rest.UnprocessableEntityResponse(err.Error()).WithType(ProblemValidation), logger)
+}
+
+// decodeErrorResponse is the problem with the request body that is not valid JSON of the entity. Values
+// of a wrong type are reported along with the field.
+func decodeErrorResponse(err error) *rest.ErrorResponse {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return validationResponse(rest.FieldError{
+			Field:   typeErr.Field,
+			Code:    "type",
+			Message: "must be " + jsonType(typeErr.Type),
+		})
+	}
+	return rest.UnprocessableEntityResponse("Request body must be a JSON object of the entity").WithType(ProblemValidation)
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"github.com/go-chi/render"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/pkg/rest"
+	"github.com/rs/zerolog"
+	"net/http"
+	"strconv"
+)
+
+type VersionHandler struct {
+	logger  zerolog.Logger
+	service VersionService
+}
+
+func NewVersionHandler(service VersionService, logger zerolog.Logger) *VersionHandler {
+	return &VersionHandler{
+		logger:  logger,
+		service: service,
+	}
+}
+
+// GetAll responds with a page of the configuration versions, newest first.
+func (h *VersionHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	page, ok := getPage(w, r, h.logger, defaultPageLimit)
+	if !ok {
+		return
+	}
+
+	versions, total, err := h.service.GetAll(r.Context(), page)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Error occurred while getting configuration versions")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return
+	}
+
+	versionEntities := make([]VersionR, 0, len(versions))
+	for _, version := range versions {
+		versionR := VersionR{}
+		versionR.FromModel(version)
+		versionEntities = append(versionEntities, versionR)
+	}
+
+	setPageHeaders(w, r, page, total)
+	render.JSON(w, r, versionEntities)
+	w.WriteHeader(http.StatusOK)
+}
+
+// Diff responds with the changes made between the version and the one given in to query parameter, the latest
+// one by default.
+func (h *VersionHandler) Diff(w http.ResponseWriter, r *http.Request) {
+	id, ok := getIDFromURL(w, r, h.logger)
+	if !ok {
+		return
+	}
+
+	to := 0
+	if value := r.URL.Query().Get("to"); value != "" {
+		var err error
+		if to, err = strconv.Atoi(value); err != nil || to < 1 {
+			h.logger.Debug().Err(err).Str("to", value).Msg("Invalid to query parameter")
+			Render(w, r, rest.BadRequestResponse("Query parameter 'to' must be a positive integer"), h.logger)
+			return
+		}
+	}
+
+	diff, err := h.service.Diff(r.Context(), id, to)
+	if err != nil {
+		if _, ok := err.(*errs.EntityNotFoundError); ok {
+			h.logger.Debug().Err(err).Send()
+			Render(w, r, errorResponse(err), h.logger)
+			return
+		}
+		h.logger.Error().Err(err).Msg("Error occurred while comparing configuration versions")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return
+	}
+
+	diffR := VersionDiffR{}
+	diffR.FromModel(diff)
+
+	render.JSON(w, r, diffR)
+	w.WriteHeader(http.StatusOK)
+}
+
+// Rollback restores the configuration of the version and responds with the changes it has made.
+func (h *VersionHandler) Rollback(w http.ResponseWriter, r *http.Request) {
+	id, ok := getIDFromURL(w, r, h.logger)
+	if !ok {
+		return
+	}
+
+	changes, err := h.service.Rollback(r.Context(), id)
+	if err != nil {
+		if _, ok := err.(*errs.EntityNotFoundError); ok {
+			h.logger.Debug().Err(err).Send()
+			Render(w, r, errorResponse(err), h.logger)
+			return
+		}
+		h.logger.Error().Err(err).Msg("Error occurred while restoring configuration version")
+		Render(w, r, rest.InternalErrorResponse(), h.logger)
+		return
+	}
+
+	render.JSON(w, r, RollbackR{Version: id, Changes: versionChanges(changes)})
+	w.WriteHeader(http.StatusOK)
+}
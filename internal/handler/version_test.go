@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"context"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/handler/mock"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/nnemirovsky/pacgen/pkg/logutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testPrepareVersionHandler(t *testing.T) (*VersionHandler, *mock.VersionService) {
+	ctrl := gomock.NewController(t)
+	versionSrvcMock := mock.NewVersionService(ctrl)
+
+	return NewVersionHandler(versionSrvcMock, logutil.DiscardLogger), versionSrvcMock
+}
+
+func testVersionRequest(t *testing.T, method, target, id string) *http.Request {
+	req, err := http.NewRequest(method, target, nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id)
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestVersionHandler_GetAll_OK(t *testing.T) {
+	t.Parallel()
+
+	versionHandler, versionSrvcMock := testPrepareVersionHandler(t)
+
+	versions := []model.ConfigVersion{
+		{ID: 7, RuleCount: 12, ProfileCount: 3, CreatedAt: time.Date(2023, 6, 6, 10, 0, 0, 0, time.UTC)},
+	}
+	versionSrvcMock.EXPECT().GetAll(gomock.Any(), model.Page{Limit: 1, Offset: 1}).Return(versions, 7, nil)
+
+	req, err := http.NewRequest(http.MethodGet, "/versions?limit=1&offset=1", nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(versionHandler.GetAll)
+
+	handler.ServeHTTP(rr, req)
+
+	got := strings.TrimSuffix(rr.Body.String(), "\n")
+	want := `[{"id":7,"rule_count":12,"profile_count":3,"created_at":"2023-06-06T10:00:00Z"}]`
+
+	assert.Equal(t, rr.Code, http.StatusOK)
+	assert.Equal(t, rr.Header().Get("X-Total-Count"), "7")
+	assert.Equal(t, got, want)
+}
+
+func TestVersionHandler_Diff_OK(t *testing.T) {
+	t.Parallel()
+
+	versionHandler, versionSrvcMock := testPrepareVersionHandler(t)
+
+	diff := model.VersionDiff{
+		From:       3,
+		To:         5,
+		PACChanged: true,
+		Changes: []model.VersionChange{
+			{Entity: model.AuditRule, EntityID: 2, Action: model.AuditDelete, Before: `{"id":2}`},
+			{Entity: model.AuditBypass, Action: model.AuditUpdate, Before: `{"entries":[]}`, After: `{"entries":[]}`},
+		},
+	}
+	versionSrvcMock.EXPECT().Diff(gomock.Any(), 3, 5).Return(diff, nil)
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(versionHandler.Diff)
+
+	handler.ServeHTTP(rr, testVersionRequest(t, http.MethodGet, "/versions/3/diff?to=5", "3"))
+
+	got := strings.TrimSuffix(rr.Body.String(), "\n")
+	want := `{"from":3,"to":5,"pac_changed":true,"changes":[` +
+		`{"entity":"rule","entity_id":2,"action":"delete","before":{"id":2}},` +
+		`{"entity":"bypass","action":"update","before":{"entries":[]},"after":{"entries":[]}}]}`
+
+	assert.Equal(t, rr.Code, http.StatusOK)
+	assert.Equal(t, got, want)
+}
+
+func TestVersionHandler_Diff_BadRequest(t *testing.T) {
+	t.Parallel()
+
+	versionHandler, _ := testPrepareVersionHandler(t)
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(versionHandler.Diff)
+
+	handler.ServeHTTP(rr, testVersionRequest(t, http.MethodGet, "/versions/3/diff?to=0", "3"))
+
+	got := strings.TrimSuffix(rr.Body.String(), "\n")
+	want := `{"type":"about:blank","title":"Bad Request","status":400,` +
+		`"detail":"Query parameter 'to' must be a positive integer","instance":"/versions/3/diff"}`
+
+	assert.Equal(t, rr.Code, http.StatusBadRequest)
+	assert.Equal(t, got, want)
+}
+
+func TestVersionHandler_Rollback_OK(t *testing.T) {
+	t.Parallel()
+
+	versionHandler, versionSrvcMock := testPrepareVersionHandler(t)
+
+	changes := []model.VersionChange{
+		{Entity: model.AuditProxyProfile, EntityID: 1, Action: model.AuditCreate, After: `{"id":1,"version":2}`},
+	}
+	versionSrvcMock.EXPECT().Rollback(gomock.Any(), 4).Return(changes, nil)
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(versionHandler.Rollback)
+
+	handler.ServeHTTP(rr, testVersionRequest(t, http.MethodPost, "/versions/4/rollback", "4"))
+
+	got := strings.TrimSuffix(rr.Body.String(), "\n")
+	want := `{"version":4,"changes":[{"entity":"proxy_profile","entity_id":1,"action":"create",` +
+		`"after":{"id":1,"version":2}}]}`
+
+	assert.Equal(t, rr.Code, http.StatusOK)
+	assert.Equal(t, got, want)
+}
+
+func TestVersionHandler_Rollback_NotFound(t *testing.T) {
+	t.Parallel()
+
+	versionHandler, versionSrvcMock := testPrepareVersionHandler(t)
+
+	err := &errs.EntityNotFoundError{Name: "configuration version", Key: "id", Value: 9}
+	versionSrvcMock.EXPECT().Rollback(gomock.Any(), 9).Return(nil, err)
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(versionHandler.Rollback)
+
+	handler.ServeHTTP(rr, testVersionRequest(t, http.MethodPost, "/versions/9/rollback", "9"))
+
+	got := strings.TrimSuffix(rr.Body.String(), "\n")
+	want := `{"type":"urn:pacgen:problem:entity-not-found","title":"Not Found","status":404,` +
+		`"detail":"configuration version with id 9 not found","instance":"/versions/9/rollback"}`
+
+	assert.Equal(t, rr.Code, http.StatusNotFound)
+	assert.Equal(t, got, want)
+}
+
+func TestPACFileHandler_Serve_Version(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	versionSrvcMock := mock.NewVersionService(ctrl)
+	pacFileHandler := NewPACFileHandler(versionSrvcMock, "", logutil.DiscardLogger)
+
+	pac := "function FindProxyForURL(url, host) {\n  return \"DIRECT\";\n}\n"
+	versionSrvcMock.EXPECT().GetByID(gomock.Any(), 3).Return(model.ConfigVersion{ID: 3, PAC: pac}, nil)
+
+	req, err := http.NewRequest(http.MethodGet, "/proxy.pac?version=3", nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(pacFileHandler.Serve)
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, rr.Code, http.StatusOK)
+	assert.Equal(t, rr.Header().Get("Content-Type"), "application/x-ns-proxy-autoconfig")
+	assert.Equal(t, rr.Body.String(), pac)
+}
@@ -0,0 +1,65 @@
+package model
+
+import (
+	"errors"
+	"github.com/nnemirovsky/pacgen/pkg/domain"
+	"net"
+	"strconv"
+	"strings"
+)
+
+var (
+	errAddressScheme    = errors.New("address must not contain a scheme")
+	errAddressPath      = errors.New("address must consist of host and port only")
+	errAddressIPv6      = errors.New("IPv6 address must be enclosed in brackets")
+	errAddressNoPort    = errors.New("address must contain a port")
+	errAddressPort      = errors.New("port must be a number between 1 and 65535")
+	errAddressHost      = errors.New("invalid host name")
+	errAddressEmptyHost = errors.New("address must contain a host")
+)
+
+// ParseAddress splits the proxy address into host and port. The host is returned in canonical form: names are
+// normalized by domain.Normalize, IP addresses are formatted by net.IP.String. IPv6 addresses must be enclosed
+// in brackets, since otherwise the port cannot be told apart from the last group of the address.
+func ParseAddress(s string) (host string, port int, err error) {
+	if strings.Contains(s, "://") {
+		return "", 0, errAddressScheme
+	}
+	if strings.ContainsAny(s, "/?#@ ") {
+		return "", 0, errAddressPath
+	}
+
+	h, p, err := net.SplitHostPort(s)
+	if err != nil {
+		if strings.Count(s, ":") > 1 && !strings.HasPrefix(s, "[") {
+			return "", 0, errAddressIPv6
+		}
+		return "", 0, errAddressNoPort
+	}
+
+	if p == "" || strings.Trim(p, "0123456789") != "" {
+		return "", 0, errAddressPort
+	}
+	if port, err = strconv.Atoi(p); err != nil || port < 1 || port > 65535 {
+		return "", 0, errAddressPort
+	}
+
+	if h == "" {
+		return "", 0, errAddressEmptyHost
+	}
+	if ip := net.ParseIP(h); ip != nil {
+		return ip.String(), port, nil
+	}
+	if h, err = domain.Normalize(h); err != nil {
+		return "", 0, errAddressHost
+	}
+	return h, port, nil
+}
+
+// FormatAddress joins host and port, IPv6 hosts are enclosed in brackets. Empty host gives empty address.
+func FormatAddress(host string, port int) string {
+	if host == "" {
+		return ""
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"github.com/go-playground/assert/v2"
+	"testing"
+)
+
+func TestParseAddress(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		address string
+		host    string
+		port    int
+		err     error
+	}{
+		"host name":            {"localhost:1080", "localhost", 1080, nil},
+		"uppercase fqdn":       {"Proxy.EXAMPLE.com.:3128", "proxy.example.com", 3128, nil},
+		"ipv4":                 {"10.0.0.1:80", "10.0.0.1", 80, nil},
+		"ipv6":                 {"[::1]:8080", "::1", 8080, nil},
+		"ipv6 canonical":       {"[2001:DB8:0:0::1]:443", "2001:db8::1", 443, nil},
+		"max port":             {"localhost:65535", "localhost", 65535, nil},
+		"no port":              {"localhost", "", 0, errAddressNoPort},
+		"unbracketed ipv6":     {"::1:8080", "", 0, errAddressIPv6},
+		"scheme":               {"http://localhost:3128", "", 0, errAddressScheme},
+		"path":                 {"localhost:3128/proxy", "", 0, errAddressPath},
+		"credentials":          {"user@localhost:3128", "", 0, errAddressPath},
+		"zero port":            {"localhost:0", "", 0, errAddressPort},
+		"port out of range":    {"localhost:65536", "", 0, errAddressPort},
+		"signed port":          {"localhost:+80", "", 0, errAddressPort},
+		"empty port":           {"localhost:", "", 0, errAddressPort},
+		"empty host":           {":80", "", 0, errAddressEmptyHost},
+		"invalid host":         {"local$host:80", "", 0, errAddressHost},
+		"international name":   {"пример.рф:3128", "xn--e1afmkfd.xn--p1ai", 3128, nil},
+		"label ends in hyphen": {"proxy-.example.com:80", "", 0, errAddressHost},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			host, port, err := ParseAddress(c.address)
+
+			assert.Equal(t, err, c.err)
+			assert.Equal(t, host, c.host)
+			assert.Equal(t, port, c.port)
+		})
+	}
+}
+
+func TestFormatAddress(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, FormatAddress("localhost", 1080), "localhost:1080")
+	assert.Equal(t, FormatAddress("::1", 8080), "[::1]:8080")
+	assert.Equal(t, FormatAddress("", 0), "")
+}
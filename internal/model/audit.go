@@ -0,0 +1,106 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Entities whose changes are recorded in the audit log.
+const (
+	AuditRule         = "rule"
+	AuditProxyProfile = "proxy_profile"
+	AuditDomainList   = "domain_list"
+	AuditBypass       = "bypass"
+	AuditUser         = "user"
+	AuditAPIToken     = "api_token"
+)
+
+// AuditEntities are the entities whose changes are recorded in the audit log.
+var AuditEntities = []string{AuditRule, AuditProxyProfile, AuditDomainList, AuditBypass, AuditUser, AuditAPIToken}
+
+// AuditAction is the kind of change recorded in the audit log.
+type AuditAction string
+
+const (
+	AuditCreate AuditAction = "create"
+	AuditUpdate AuditAction = "update"
+	AuditDelete AuditAction = "delete"
+)
+
+// AuditActions are the names of all the kinds of changes recorded in the audit log.
+var AuditActions = []string{string(AuditCreate), string(AuditUpdate), string(AuditDelete)}
+
+// SchedulerActor is the actor of the changes made by the server itself when the rules expire.
+const SchedulerActor = "scheduler"
+
+// BootstrapActor is the actor of the admin created by the server on startup when there are no users.
+const BootstrapActor = "bootstrap"
+
+// CLIActor is the actor of the API tokens minted with the command line tool against the database.
+const CLIActor = "cli"
+
+// Actor is whoever makes the changes, they are recorded in the audit log along with the changes. UserID and Role
+// are the id and the role of the authenticated user, zero for the server itself. TokenID and Scopes are the id
+// and the scopes of the API token the request is authenticated with, they narrow down the role, and they are zero
+// and nil for the others.
+type Actor struct {
+	User       string
+	UserID     int
+	Role       Role
+	TokenID    int
+	Scopes     Scopes
+	RequestID  string
+	RemoteAddr string
+}
+
+// Principal identifies who is authenticated: the API token, or the user if there is no token. Ids of deleted
+// users and tokens may be taken again, so the names are part of it too.
+func (a Actor) Principal() string {
+	if a.TokenID != 0 {
+		return fmt.Sprintf("token %d %s", a.TokenID, a.User)
+	}
+	return fmt.Sprintf("user %d %s", a.UserID, a.User)
+}
+
+type actorKey struct{}
+
+// WithActor returns the context of the changes made by the actor.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor of the changes made in the context, zero actor if there is none.
+func ActorFromContext(ctx context.Context) Actor {
+	actor, _ := ctx.Value(actorKey{}).(Actor)
+	return actor
+}
+
+// AuditEntry records a change of the entity. Before and After are the states of the entity as JSON objects,
+// Before is empty for created entities and After is empty for deleted ones. EntityID is zero for the bypass list,
+// there is only one.
+type AuditEntry struct {
+	ID         int         `db:"id"`
+	Actor      string      `db:"actor"`
+	RequestID  string      `db:"request_id"`
+	RemoteAddr string      `db:"remote_addr"`
+	Entity     string      `db:"entity"`
+	EntityID   int         `db:"entity_id"`
+	Action     AuditAction `db:"action"`
+	Before     string      `db:"before"`
+	After      string      `db:"after"`
+	CreatedAt  time.Time   `db:"created_at"`
+}
+
+// AuditFilter narrows down and pages the audit log, newest entries go first. Zero value matches all the entries.
+// Since and Until bound the time of the change inclusively.
+type AuditFilter struct {
+	Actor     string
+	Entity    string
+	EntityID  int
+	Action    AuditAction
+	RequestID string
+	Since     time.Time
+	Until     time.Time
+	Page      Page
+}
@@ -0,0 +1,162 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"github.com/nnemirovsky/pacgen/pkg/domain"
+	"net"
+	"regexp"
+	"strings"
+)
+
+type BypassKind int
+
+const (
+	// BypassPreset is one of the predefined sets of hosts, its value is the name of the preset.
+	BypassPreset BypassKind = iota + 1
+	BypassDomain
+	BypassDomainAndSubdomains
+	// BypassNetwork is an IPv4 range in CIDR notation, it matches hosts given as IP addresses only.
+	BypassNetwork
+)
+
+func (k BypassKind) String() string {
+	switch k {
+	case BypassPreset:
+		return "preset"
+	case BypassDomain:
+		return "domain"
+	case BypassDomainAndSubdomains:
+		return "domain_and_subdomains"
+	case BypassNetwork:
+		return "network"
+	default:
+		return "unknown"
+	}
+}
+
+func ParseBypassKind(s string) (BypassKind, error) {
+	switch s {
+	case "preset":
+		return BypassPreset, nil
+	case "domain":
+		return BypassDomain, nil
+	case "domain_and_subdomains":
+		return BypassDomainAndSubdomains, nil
+	case "network":
+		return BypassNetwork, nil
+	default:
+		return 0, errors.New("unknown kind, possible values: preset, domain, domain_and_subdomains, network")
+	}
+}
+
+const (
+	// PresetPlainHostNames matches host names without dots, like intranet or localhost.
+	PresetPlainHostNames = "plain_hostnames"
+	// PresetPrivateNetworks matches loopback, private (RFC 1918, RFC 4193) and link-local addresses.
+	PresetPrivateNetworks = "private_networks"
+	// PresetLocalDomains matches the .local domain of multicast DNS.
+	PresetLocalDomains = "local_domains"
+)
+
+// BypassEntry is a set of hosts always reached directly, before any rule is checked.
+type BypassEntry struct {
+	Kind  BypassKind `db:"kind"`
+	Value string     `db:"value"`
+}
+
+// NewBypassEntry validates the value of the kind and returns the entry with the value in canonical form.
+func NewBypassEntry(kind BypassKind, value string) (BypassEntry, error) {
+	switch kind {
+	case BypassPreset:
+		switch value {
+		case PresetPlainHostNames, PresetPrivateNetworks, PresetLocalDomains:
+			return BypassEntry{Kind: kind, Value: value}, nil
+		}
+		return BypassEntry{}, fmt.Errorf(
+			"unknown preset, possible values: %s, %s, %s",
+			PresetPlainHostNames, PresetPrivateNetworks, PresetLocalDomains,
+		)
+	case BypassDomain, BypassDomainAndSubdomains:
+		ascii, err := domain.Normalize(value)
+		if err != nil {
+			return BypassEntry{}, err
+		}
+		return BypassEntry{Kind: kind, Value: ascii}, nil
+	case BypassNetwork:
+		_, ipNet, err := net.ParseCIDR(value)
+		if err != nil || ipNet.IP.To4() == nil {
+			return BypassEntry{}, errors.New("network must be an IPv4 range in CIDR notation")
+		}
+		return BypassEntry{Kind: kind, Value: ipNet.String()}, nil
+	default:
+		return BypassEntry{}, errors.New("unknown kind")
+	}
+}
+
+// ipv4WildcardRe matches IPv4 ranges written with a trailing wildcard, e.g. 192.168.*.
+var ipv4WildcardRe = regexp.MustCompile(`^(\d{1,3}\.){1,3}\*$`)
+
+// ParseBypassList parses the bypass list in the syntax of browsers and operating systems: entries are separated
+// by commas, semicolons or spaces. Supported entries are <local>, host names, IP addresses, IPv4 ranges in CIDR
+// notation or with a trailing wildcard, and domains with a leading dot or "*." which match the domain and all
+// its subdomains.
+func ParseBypassList(s string) ([]BypassEntry, error) {
+	items := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ';' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+
+	entries := make([]BypassEntry, 0, len(items))
+	seen := make(map[BypassEntry]struct{}, len(items))
+	for _, item := range items {
+		entry, err := parseBypassItem(item)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", item, err)
+		}
+		if _, ok := seen[entry]; !ok {
+			seen[entry] = struct{}{}
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func parseBypassItem(item string) (BypassEntry, error) {
+	switch {
+	case strings.EqualFold(item, "<local>"):
+		return BypassEntry{Kind: BypassPreset, Value: PresetPlainHostNames}, nil
+	case strings.HasPrefix(item, "<"):
+		return BypassEntry{}, errors.New("only <local> special entry is supported")
+	case strings.Contains(item, "://"):
+		return BypassEntry{}, errors.New("schemes are not supported")
+	case strings.Contains(item, "/"):
+		return NewBypassEntry(BypassNetwork, item)
+	case ipv4WildcardRe.MatchString(item):
+		octets := strings.Split(strings.TrimSuffix(item, ".*"), ".")
+		for len(octets) < 4 {
+			octets = append(octets, "0")
+		}
+		return NewBypassEntry(BypassNetwork, fmt.Sprintf("%s/%d", strings.Join(octets, "."), 8*strings.Count(item, ".")))
+	}
+
+	if ip := net.ParseIP(strings.Trim(item, "[]")); ip != nil {
+		if ip.To4() != nil {
+			return NewBypassEntry(BypassNetwork, ip.String()+"/32")
+		}
+		return NewBypassEntry(BypassDomain, ip.String())
+	}
+	if strings.Contains(item, ":") {
+		return BypassEntry{}, errors.New("ports are not supported")
+	}
+
+	kind := BypassDomain
+	switch {
+	case strings.HasPrefix(item, "*."):
+		item, kind = item[2:], BypassDomainAndSubdomains
+	case strings.HasPrefix(item, "."):
+		item, kind = item[1:], BypassDomainAndSubdomains
+	case strings.Contains(item, "*"):
+		return BypassEntry{}, errors.New("only leading *. wildcard is supported")
+	}
+	return NewBypassEntry(kind, item)
+}
@@ -0,0 +1,68 @@
+package model
+
+import (
+	"github.com/go-playground/assert/v2"
+	"testing"
+)
+
+func TestParseBypassList(t *testing.T) {
+	t.Parallel()
+
+	got, err := ParseBypassList("<local>, localhost; *.Corp.example.com .lan 10.0.0.0/8,192.168.*, 172.16.5.4 [::1] пример.рф")
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	want := []BypassEntry{
+		{Kind: BypassPreset, Value: PresetPlainHostNames},
+		{Kind: BypassDomain, Value: "localhost"},
+		{Kind: BypassDomainAndSubdomains, Value: "corp.example.com"},
+		{Kind: BypassDomainAndSubdomains, Value: "lan"},
+		{Kind: BypassNetwork, Value: "10.0.0.0/8"},
+		{Kind: BypassNetwork, Value: "192.168.0.0/16"},
+		{Kind: BypassNetwork, Value: "172.16.5.4/32"},
+		{Kind: BypassDomain, Value: "::1"},
+		{Kind: BypassDomain, Value: "xn--e1afmkfd.xn--p1ai"},
+	}
+	assert.Equal(t, got, want)
+}
+
+func TestParseBypassList_Invalid(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"loopback override": "<-loopback>",
+		"scheme":            "http://example.com",
+		"port":              "example.com:8080",
+		"inner wildcard":    "*example.com",
+		"everything":        "*",
+		"ipv6 range":        "fc00::/7",
+		"invalid range":     "10.0.0.0/33",
+		"invalid domain":    "exa$mple.com",
+	}
+
+	for name, list := range cases {
+		list := list
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got, err := ParseBypassList(list); err == nil {
+				t.Errorf("expected error, but got %v", got)
+			}
+		})
+	}
+}
+
+func TestNewBypassEntry(t *testing.T) {
+	t.Parallel()
+
+	got, err := NewBypassEntry(BypassNetwork, "10.1.2.3/8")
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	assert.Equal(t, got, BypassEntry{Kind: BypassNetwork, Value: "10.0.0.0/8"})
+
+	if _, err := NewBypassEntry(BypassPreset, "intranet"); err == nil {
+		t.Errorf("expected error for unknown preset")
+	}
+}
@@ -1,6 +1,12 @@
 package model
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
 
 type ProxyType int
 
@@ -9,6 +15,10 @@ const (
 	Https
 	Socks4
 	Socks5
+	// Direct routes traffic without a proxy, profiles of this type have no address.
+	Direct
+	// Pool spreads traffic across its member profiles by host, profiles of this type have no address.
+	Pool
 )
 
 func (t ProxyType) String() string {
@@ -21,6 +31,10 @@ func (t ProxyType) String() string {
 		return "SOCKS4"
 	case Socks5:
 		return "SOCKS5"
+	case Direct:
+		return "DIRECT"
+	case Pool:
+		return "POOL"
 	default:
 		return "UNKNOWN"
 	}
@@ -36,20 +50,335 @@ func ParseType(s string) (ProxyType, error) {
 		return Socks4, nil
 	case "SOCKS5", "socks5":
 		return Socks5, nil
+	case "DIRECT", "direct":
+		return Direct, nil
+	case "POOL", "pool":
+		return Pool, nil
 	default:
-		return 0, errors.New("unknown type, possible values: HTTP, HTTPS, SOCKS4, SOCKS5")
+		return 0, errors.New("unknown type, possible values: HTTP, HTTPS, SOCKS4, SOCKS5, DIRECT, POOL")
 	}
 }
 
 type ProxyProfile struct {
-	ID      int       `db:"id"`
-	Name    string    `db:"name"`
-	Type    ProxyType `db:"type"`
-	Address string    `db:"address"`
+	ID   int       `db:"id"`
+	Name string    `db:"name"`
+	Type ProxyType `db:"type"`
+	// Host and Port of the proxy, empty for direct profiles and pools. Host is also empty for profiles
+	// whose legacy address could not be normalized.
+	Host string `db:"host"`
+	Port int    `db:"port"`
+	// StandbyID refers to the profile used when this one is down, zero if there is no standby.
+	StandbyID int `db:"standby_id"`
+	// Members of the pool, nil for profiles of other types.
+	Members []PoolMember `db:"-"`
+	// RuleCount is the number of the rules routed through the profile, it is loaded on request only.
+	RuleCount int `db:"rule_count"`
+	Metadata
+}
+
+// Address returns host and port of the proxy in canonical form.
+func (p ProxyProfile) Address() string {
+	return FormatAddress(p.Host, p.Port)
+}
+
+// Routable reports whether traffic can be routed through the profile. Profiles of proxy types must have an address.
+func (p ProxyProfile) Routable() bool {
+	return p.Type == Direct || p.Type == Pool || p.Host != ""
+}
+
+// PoolMember is a profile of a pool. Hosts are spread across the members in proportion to their weights.
+type PoolMember struct {
+	ProfileID int `db:"member_id"`
+	Weight    int `db:"weight"`
+}
+
+type HealthStatus int
+
+const (
+	// HealthUnknown is the status of profiles that have not been probed yet, of direct profiles and of pools.
+	HealthUnknown HealthStatus = iota
+	HealthUp
+	HealthDown
+)
+
+func (s HealthStatus) String() string {
+	switch s {
+	case HealthUp:
+		return "up"
+	case HealthDown:
+		return "down"
+	default:
+		return "unknown"
+	}
+}
+
+// ProfileHealth is the result of the last probe of a proxy profile.
+type ProfileHealth struct {
+	ProfileID int
+	Status    HealthStatus
+	Latency   time.Duration
+	CheckedAt time.Time
+	// Error describes why the profile is down.
+	Error string
 }
 
 type Rule struct {
-	ID           int           `db:"id"`
-	Regex        string        `db:"regex"`
+	ID int `db:"id"`
+	// Regex is empty if the rule matches a domain list.
+	Regex string `db:"regex"`
+	// DomainListID is zero if the rule matches a regex.
+	DomainListID int `db:"domain_list_id"`
+	// DomainList is the list referenced by DomainListID, it is loaded only along with proxy profiles.
+	DomainList *DomainList `db:"-"`
+	Enabled    bool        `db:"enabled"`
+	// Tags is nil if tags were not queried.
+	Tags         Tags          `db:"tags"`
 	ProxyProfile *ProxyProfile `db:"proxy_profile"`
+	// ActiveFrom and ExpiresAt bound the time the enabled rule is in effect, nil means unbounded.
+	ActiveFrom *time.Time `db:"active_from"`
+	ExpiresAt  *time.Time `db:"expires_at"`
+	Metadata
+}
+
+// ExpiryPolicy tells what happens to rules once they expire.
+type ExpiryPolicy int
+
+const (
+	// ExpiryKeep leaves expired rules as they are, they just stop being in effect.
+	ExpiryKeep ExpiryPolicy = iota
+	// ExpiryArchive disables expired rules and tags them with ArchiveTag.
+	ExpiryArchive
+	// ExpiryDelete deletes expired rules.
+	ExpiryDelete
+)
+
+// ArchiveTag is the tag of the rules archived by ExpiryArchive policy.
+const ArchiveTag = "expired"
+
+func (p ExpiryPolicy) String() string {
+	switch p {
+	case ExpiryKeep:
+		return "keep"
+	case ExpiryArchive:
+		return "archive"
+	case ExpiryDelete:
+		return "delete"
+	default:
+		return "unknown"
+	}
+}
+
+func ParseExpiryPolicy(s string) (ExpiryPolicy, error) {
+	switch s {
+	case "keep":
+		return ExpiryKeep, nil
+	case "archive":
+		return ExpiryArchive, nil
+	case "delete":
+		return ExpiryDelete, nil
+	default:
+		return 0, errors.New("unknown expiry policy, possible values: keep, archive, delete")
+	}
+}
+
+// Metadata tells why an entity exists and who and when created it. Timestamps are maintained by repositories,
+// they are nil for entities created before they were tracked.
+type Metadata struct {
+	Description string     `db:"description"`
+	Owner       string     `db:"owner"`
+	CreatedAt   *time.Time `db:"created_at"`
+	UpdatedAt   *time.Time `db:"updated_at"`
+	// Version is incremented by every change of the entity. Changes are applied only to the version they are
+	// based on unless it is zero.
+	Version int `db:"version"`
+}
+
+// MetadataFilter narrows down entities by their metadata. Description matches case-insensitive substrings,
+// time bounds are inclusive and ignored if zero. Entities without timestamps do not match time bounds.
+type MetadataFilter struct {
+	Owner         string
+	Description   string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	UpdatedAfter  time.Time
+	UpdatedBefore time.Time
+}
+
+// Sort orders entities by the field, zero value orders them by id.
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// Tags is a sorted list of tag names. It is scanned from a comma separated list aggregated by the database.
+type Tags []string
+
+func (t *Tags) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*t = Tags{}
+	case string:
+		*t = strings.Split(v, ",")
+	case []byte:
+		*t = strings.Split(string(v), ",")
+	default:
+		return fmt.Errorf("unsupported type %T for tags", src)
+	}
+	sort.Strings(*t)
+	return nil
+}
+
+// Page selects a part of a list: Limit entities after the first Offset ones. Zero Limit selects all of them.
+type Page struct {
+	Limit  int
+	Offset int
+}
+
+// RuleFilter narrows down, orders and pages the list of rules. Zero value matches all the rules. Domain matches
+// substrings of the domain of the rule or of the entries of its domain list. Fields are the fields of the rules
+// to load, all of them if empty. ExpandProfile loads the whole proxy profiles of the rules instead of their ids.
+type RuleFilter struct {
+	Tag            string
+	ProxyProfileID int
+	Mode           string
+	Domain         string
+	MetadataFilter
+	Sort          Sort
+	Page          Page
+	Fields        []string
+	ExpandProfile bool
+}
+
+// RuleFilterModes are the modes rules can be filtered by.
+var RuleFilterModes = []string{"domain", "domain_and_subdomains", "domain_list"}
+
+// RuleSortFields are the fields rules can be sorted by. Priority is the order rules are evaluated in, that is
+// the order of ids.
+var RuleSortFields = []string{"id", "priority", "domain", "created_at", "updated_at", "owner", "description"}
+
+// RuleFields are the fields of rules which can be loaded selectively.
+var RuleFields = []string{
+	"id", "regex", "domain_list_id", "proxy_profile_id", "enabled", "tags", "active_from", "expires_at",
+	"description", "owner", "created_at", "updated_at",
+}
+
+// ProxyProfileFilter narrows down, orders and pages the list of proxy profiles. Zero value matches all the profiles.
+// Fields are the fields of the profiles to load, all of them if empty. WithRuleCount loads the number of the rules
+// of the profiles as well.
+type ProxyProfileFilter struct {
+	MetadataFilter
+	Sort          Sort
+	Page          Page
+	Fields        []string
+	WithRuleCount bool
+}
+
+// ProxyProfileSortFields are the fields proxy profiles can be sorted by.
+var ProxyProfileSortFields = []string{"id", "name", "created_at", "updated_at", "owner"}
+
+// ProxyProfileFields are the fields of proxy profiles which can be loaded selectively. Address consists of host
+// and port.
+var ProxyProfileFields = []string{
+	"id", "name", "type", "address", "standby_id", "members", "description", "owner", "created_at", "updated_at",
+}
+
+// RuleBulkUpdate describes changes applied to multiple rules at once. Nil fields are left intact.
+type RuleBulkUpdate struct {
+	ProxyProfileID *int
+	Enabled        *bool
+}
+
+type LintKind int
+
+const (
+	// LintDuplicate is a rule matching the same hosts as an earlier rule with the same proxy profile.
+	LintDuplicate LintKind = iota + 1
+	// LintConflict is a rule matching the same hosts as an earlier rule with another proxy profile.
+	LintConflict
+	// LintShadowed is a rule whose hosts are all matched by earlier broader rules, so it never applies.
+	LintShadowed
+	// LintNoop is a rule that does not change routing of any host.
+	LintNoop
+)
+
+func (k LintKind) String() string {
+	switch k {
+	case LintDuplicate:
+		return "duplicate"
+	case LintConflict:
+		return "conflict"
+	case LintShadowed:
+		return "shadowed"
+	case LintNoop:
+		return "noop"
+	default:
+		return "unknown"
+	}
+}
+
+// LintFinding is a problem of a rule found by the rule set analysis.
+type LintFinding struct {
+	Kind   LintKind
+	RuleID int
+	// RelatedIDs are the rules causing the problem, e.g. earlier rules shadowing this one.
+	RelatedIDs []int
+	Message    string
+	Fix        string
+}
+
+// HostMatch is the result of evaluating the host the way pac file does.
+type HostMatch struct {
+	Host     string
+	Bypassed bool
+	// Matches are the rules matching the host in evaluation order, the first one wins unless the host is bypassed.
+	Matches []RuleMatch
+	// Proxy is what pac file returns for the host.
+	Proxy string
+}
+
+// RuleMatch is a rule matching the host, Proxy is what pac file returns if the rule wins.
+type RuleMatch struct {
+	Rule  Rule
+	Proxy string
+}
+
+type DomainMode int
+
+const (
+	ExactDomain DomainMode = iota + 1
+	DomainAndSubdomains
+)
+
+func (m DomainMode) String() string {
+	switch m {
+	case ExactDomain:
+		return "domain"
+	case DomainAndSubdomains:
+		return "domain_and_subdomains"
+	default:
+		return "unknown"
+	}
+}
+
+func ParseDomainMode(s string) (DomainMode, error) {
+	switch s {
+	case "domain":
+		return ExactDomain, nil
+	case "domain_and_subdomains":
+		return DomainAndSubdomains, nil
+	default:
+		return 0, errors.New("unknown mode, possible values: domain, domain_and_subdomains")
+	}
+}
+
+type DomainListEntry struct {
+	Domain string     `db:"domain"`
+	Mode   DomainMode `db:"mode"`
+}
+
+// DomainList is a named set of domains shared by rules.
+type DomainList struct {
+	ID      int               `db:"id"`
+	Name    string            `db:"name"`
+	Entries []DomainListEntry `db:"-"`
 }
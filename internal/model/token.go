@@ -0,0 +1,105 @@
+package model
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Scopes granted to API tokens are resource:read or resource:write, the write scope of a resource implies the read
+// one. The scopes narrow down the role of the user the token belongs to, they do not extend it.
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+)
+
+// ScopeResources are the resources the scopes are granted for: rules and domain lists, proxy profiles and the bypass
+// list, versions and the draft, the audit log, users, tokens, and provisioning of devices with PAC file.
+var ScopeResources = []string{"rules", "profiles", "versions", "audit", "users", "tokens", "pac"}
+
+// Scopes is a sorted list of scopes. It is stored as a space separated list.
+type Scopes []string
+
+func (s *Scopes) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*s = Scopes{}
+	case string:
+		*s = strings.Fields(v)
+	case []byte:
+		*s = strings.Fields(string(v))
+	default:
+		return fmt.Errorf("unsupported type %T for scopes", src)
+	}
+	sort.Strings(*s)
+	return nil
+}
+
+func (s Scopes) Value() (driver.Value, error) {
+	return strings.Join(s, " "), nil
+}
+
+// Allows reports whether the scopes allow reading the resource, or changing it if write is true.
+func (s Scopes) Allows(resource string, write bool) bool {
+	for _, scope := range s {
+		switch scope {
+		case resource + ":" + ScopeWrite:
+			return true
+		case resource + ":" + ScopeRead:
+			if !write {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ParseScope checks that the scope grants reading or writing one of ScopeResources.
+func ParseScope(s string) (string, error) {
+	resource, access, _ := strings.Cut(s, ":")
+	if access != ScopeRead && access != ScopeWrite {
+		return "", fmt.Errorf("unknown access of scope %s, possible values: read, write", s)
+	}
+	for _, r := range ScopeResources {
+		if r == resource {
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("unknown resource of scope %s, possible values: %s", s, strings.Join(ScopeResources, ", "))
+}
+
+// APIToken authenticates scripts with Authorization: Bearer header. Personal tokens belong to the user they act
+// as, service tokens have no user and act with the role of admin narrowed down by their scopes. Only the SHA-256
+// hash of the token is stored, the token itself is shown once when it is created.
+type APIToken struct {
+	ID   int    `db:"id"`
+	Name string `db:"name"`
+	// UserID is zero for service tokens. UserName and UserRole are the current ones of the user.
+	UserID     int        `db:"user_id"`
+	UserName   string     `db:"user_name"`
+	UserRole   Role       `db:"user_role"`
+	TokenHash  string     `db:"token_hash"`
+	Scopes     Scopes     `db:"scopes"`
+	ExpiresAt  *time.Time `db:"expires_at"`
+	LastUsedAt *time.Time `db:"last_used_at"`
+	CreatedAt  time.Time  `db:"created_at"`
+}
+
+// ServiceTokenActorPrefix prefixes the names of service tokens to make up the actors of the changes made with them.
+const ServiceTokenActorPrefix = "token:"
+
+// Actor returns the actor of the changes made with the token: the user of a personal token, or the token itself
+// for a service one.
+func (t APIToken) Actor() Actor {
+	// Nil scopes are not narrowed down at all, so the token without scopes gets the empty ones.
+	scopes := t.Scopes
+	if scopes == nil {
+		scopes = Scopes{}
+	}
+	if t.UserID == 0 {
+		return Actor{User: ServiceTokenActorPrefix + t.Name, Role: Admin, TokenID: t.ID, Scopes: scopes}
+	}
+	return Actor{User: t.UserName, UserID: t.UserID, Role: t.UserRole, TokenID: t.ID, Scopes: scopes}
+}
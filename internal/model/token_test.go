@@ -0,0 +1,78 @@
+package model
+
+import (
+	"github.com/go-playground/assert/v2"
+	"testing"
+)
+
+func TestScopes_Allows(t *testing.T) {
+	t.Parallel()
+
+	scopes := Scopes{"pac:read", "rules:write"}
+
+	assert.Equal(t, scopes.Allows("rules", false), true)
+	assert.Equal(t, scopes.Allows("rules", true), true)
+	assert.Equal(t, scopes.Allows("pac", false), true)
+	assert.Equal(t, scopes.Allows("pac", true), false)
+	assert.Equal(t, scopes.Allows("profiles", false), false)
+	assert.Equal(t, Scopes{}.Allows("rules", false), false)
+}
+
+func TestScopes_Scan(t *testing.T) {
+	t.Parallel()
+
+	var scopes Scopes
+	if err := scopes.Scan("rules:write pac:read"); err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	assert.Equal(t, scopes, Scopes{"pac:read", "rules:write"})
+
+	if err := scopes.Scan(""); err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	assert.Equal(t, scopes, Scopes{})
+}
+
+func TestParseScope_Invalid(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"no access":        "rules",
+		"unknown access":   "rules:delete",
+		"unknown resource": "proxy.pac:read",
+		"empty":            "",
+	}
+
+	for name, s := range cases {
+		s := s
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := ParseScope(s); err == nil {
+				t.Errorf("Expected error for %q", s)
+			}
+		})
+	}
+}
+
+func TestAPIToken_Actor(t *testing.T) {
+	t.Parallel()
+
+	personal := APIToken{Name: "laptop", UserID: 2, UserName: "bob", UserRole: Editor, Scopes: Scopes{"rules:read"}}
+	assert.Equal(t, personal.Actor(), Actor{User: "bob", UserID: 2, Role: Editor, Scopes: Scopes{"rules:read"}})
+
+	service := APIToken{Name: "ci"}
+	assert.Equal(t, service.Actor(), Actor{User: "token:ci", Role: Admin, Scopes: Scopes{}})
+}
+
+func TestActor_Principal(t *testing.T) {
+	t.Parallel()
+
+	user := Actor{User: "bob", UserID: 2, Role: Editor}
+	personal := APIToken{ID: 5, Name: "laptop", UserID: 2, UserName: "bob", UserRole: Editor}.Actor()
+	service := APIToken{ID: 6, Name: "ci"}.Actor()
+
+	assert.Equal(t, user.Principal(), "user 2 bob")
+	assert.Equal(t, personal.Principal(), "token 5 bob")
+	assert.Equal(t, service.Principal(), "token 6 token:ci")
+}
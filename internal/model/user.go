@@ -0,0 +1,54 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+// Role tells what the user is allowed to do. Every role is allowed whatever the preceding ones are.
+type Role int
+
+const (
+	// Viewer only reads.
+	Viewer Role = iota + 1
+	// Editor changes rules and domain lists.
+	Editor
+	// Admin changes proxy profiles, the bypass list, versions, drafts and users.
+	Admin
+)
+
+func (r Role) String() string {
+	switch r {
+	case Viewer:
+		return "viewer"
+	case Editor:
+		return "editor"
+	case Admin:
+		return "admin"
+	default:
+		return "unknown"
+	}
+}
+
+func ParseRole(s string) (Role, error) {
+	switch s {
+	case "viewer":
+		return Viewer, nil
+	case "editor":
+		return Editor, nil
+	case "admin":
+		return Admin, nil
+	default:
+		return 0, errors.New("unknown role, possible values: viewer, editor, admin")
+	}
+}
+
+// User is authenticated with basic auth. PasswordHash is bcrypt hash of the password, it is never returned by API.
+type User struct {
+	ID           int       `db:"id"`
+	Name         string    `db:"name"`
+	PasswordHash string    `db:"password_hash"`
+	Role         Role      `db:"role"`
+	CreatedAt    time.Time `db:"created_at"`
+	UpdatedAt    time.Time `db:"updated_at"`
+}
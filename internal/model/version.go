@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+// ConfigVersion is the configuration PAC file has been generated from, along with the generated PAC file.
+// The configuration is recorded whenever PAC file is regenerated and differs from the previous version. State is
+// the JSON object of the proxy profiles, domain lists, rules and the bypass list in the form they are recorded
+// in the audit log.
+type ConfigVersion struct {
+	ID           int       `db:"id"`
+	RuleCount    int       `db:"rule_count"`
+	ProfileCount int       `db:"profile_count"`
+	State        string    `db:"state"`
+	PAC          string    `db:"pac"`
+	CreatedAt    time.Time `db:"created_at"`
+}
+
+// VersionChange is the change of the entity between two versions of the configuration. Before is empty
+// for created entities and After is empty for deleted ones, EntityID is zero for the bypass list.
+type VersionChange struct {
+	Entity   string
+	EntityID int
+	Action   AuditAction
+	Before   string
+	After    string
+}
+
+// VersionDiff is the difference between two versions of the configuration.
+type VersionDiff struct {
+	From       int
+	To         int
+	PACChanged bool
+	Changes    []VersionChange
+}
+
+// Draft is the difference between the published configuration and the one being edited, which is published
+// only when explicitly asked to. PACDiff is the unified diff of the PAC files generated from both of them.
+// Approvers are the users who have approved the draft as it is now.
+type Draft struct {
+	Published int
+	Changes   []VersionChange
+	PACDiff   string
+	Approvers []string
+}
@@ -0,0 +1,243 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/rs/zerolog"
+	"time"
+)
+
+// Objects are the states of the entities recorded in the audit log as JSON objects, they are selected from the rows
+// of the tables aliased as r, p, l, u and t. Password hashes of the users and hashes of API tokens are left out.
+const (
+	ruleObject = `json_object(
+					  'id', r.id,
+					  'regex', r.regex,
+					  'domain_list_id', r.domain_list_id,
+					  'proxy_profile_id', r.proxy_profile_id,
+					  'enabled', json(CASE WHEN r.enabled THEN 'true' ELSE 'false' END),
+					  'tags', json((SELECT json_group_array(t.name)
+									FROM rule_tags rt
+									JOIN tags t ON rt.tag_id = t.id
+									WHERE rt.rule_id = r.id)),
+					  'active_from', r.active_from,
+					  'expires_at', r.expires_at,
+					  'description', r.description,
+					  'owner', r.owner,
+					  'created_at', r.created_at,
+					  'updated_at', r.updated_at,
+					  'version', r.version)`
+	profileObject = `json_object(
+						 'id', p.id,
+						 'name', p.name,
+						 'type', p.type,
+						 'host', p.host,
+						 'port', p.port,
+						 'standby_profile_id', p.standby_profile_id,
+						 'members', json((SELECT json_group_array(json_object('profile_id', m.member_id,
+																			  'weight', m.weight))
+										  FROM profile_pool_members m
+										  WHERE m.pool_id = p.id)),
+						 'description', p.description,
+						 'owner', p.owner,
+						 'created_at', p.created_at,
+						 'updated_at', p.updated_at,
+						 'version', p.version)`
+	domainListObject = `json_object(
+							'id', l.id,
+							'name', l.name,
+							'entries', json((SELECT json_group_array(json_object('domain', e.domain, 'mode', e.mode))
+											 FROM domain_list_entries e
+											 WHERE e.list_id = l.id)))`
+	userObject = `json_object(
+					  'id', u.id,
+					  'name', u.name,
+					  'role', CASE u.role WHEN 1 THEN 'viewer' WHEN 2 THEN 'editor' ELSE 'admin' END,
+					  'created_at', u.created_at,
+					  'updated_at', u.updated_at)`
+	tokenObject = `json_object(
+					   'id', t.id,
+					   'name', t.name,
+					   'user_id', t.user_id,
+					   'scopes', t.scopes,
+					   'expires_at', t.expires_at,
+					   'created_at', t.created_at)`
+)
+
+// Snapshots select the state of an entity recorded in the audit log as JSON object, the queries take the id
+// of the entity. The bypass list is a single entity, so its query takes no id.
+const (
+	ruleSnapshot       = `SELECT ` + ruleObject + ` FROM rules r WHERE r.id = ?`
+	profileSnapshot    = `SELECT ` + profileObject + ` FROM proxy_profiles p WHERE p.id = ?`
+	domainListSnapshot = `SELECT ` + domainListObject + ` FROM domain_lists l WHERE l.id = ?`
+	userSnapshot       = `SELECT ` + userObject + ` FROM users u WHERE u.id = ?`
+	tokenSnapshot      = `SELECT ` + tokenObject + ` FROM api_tokens t WHERE t.id = ?`
+	bypassSnapshot     = `SELECT json_object(
+							  'entries', json_group_array(json_object('kind', b.kind, 'value', b.value)))
+						  FROM (SELECT kind, value FROM bypass_entries ORDER BY kind, value) b`
+)
+
+// snapshot returns the state of the entity selected by the snapshot query, empty if there is no such entity.
+func snapshot(ctx context.Context, tx *sqlx.Tx, query string, args ...any) (string, error) {
+	var state string
+	if err := tx.GetContext(ctx, &state, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return "", err
+	}
+	return state, nil
+}
+
+// snapshots returns the states of the entities with the ids selected by the snapshot query.
+func snapshots(ctx context.Context, tx *sqlx.Tx, query string, ids []int) ([]string, error) {
+	states := make([]string, 0, len(ids))
+	for _, id := range ids {
+		state, err := snapshot(ctx, tx, query, id)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// audit records the change of the entity made in the transaction, so the change and its record are committed
+// together. The actor is taken from the context.
+func audit(ctx context.Context, tx *sqlx.Tx, entry model.AuditEntry) error {
+	actor := model.ActorFromContext(ctx)
+	cmd := `INSERT INTO audit_log (actor, request_id, remote_addr, entity, entity_id, action, before, after,
+								   created_at)
+			VALUES (?, ?, ?, ?, nullif(?, 0), ?, nullif(?, ''), nullif(?, ''), CURRENT_TIMESTAMP)`
+	_, err := tx.ExecContext(
+		ctx,
+		cmd,
+		actor.User,
+		actor.RequestID,
+		actor.RemoteAddr,
+		entry.Entity,
+		entry.EntityID,
+		entry.Action,
+		entry.Before,
+		entry.After,
+	)
+	return err
+}
+
+// auditChange records the change of the entity with the id made in the transaction. The state after the change
+// is selected by the snapshot query, unless the entity has been deleted.
+func auditChange(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	query string,
+	entity string,
+	id int,
+	action model.AuditAction,
+	before string,
+) error {
+	entry := model.AuditEntry{Entity: entity, EntityID: id, Action: action, Before: before}
+	if action != model.AuditDelete {
+		var err error
+		if entry.After, err = snapshot(ctx, tx, query, id); err != nil {
+			return err
+		}
+	}
+	return audit(ctx, tx, entry)
+}
+
+// AuditRepository reads and prunes the audit log, the entries are written by the other repositories along with
+// the changes.
+type AuditRepository struct {
+	logger zerolog.Logger
+	db     *sqlx.DB
+}
+
+func NewAuditRepository(db *sqlx.DB, logger zerolog.Logger) *AuditRepository {
+	return &AuditRepository{
+		logger: logger,
+		db:     db,
+	}
+}
+
+// auditConditions returns conditions on the audit log matching the filter, along with their arguments.
+func auditConditions(filter model.AuditFilter) (conds []string, args []any) {
+	if filter.Actor != "" {
+		conds = append(conds, `actor = ?`)
+		args = append(args, filter.Actor)
+	}
+	if filter.Entity != "" {
+		conds = append(conds, `entity = ?`)
+		args = append(args, filter.Entity)
+	}
+	if filter.EntityID != 0 {
+		conds = append(conds, `entity_id = ?`)
+		args = append(args, filter.EntityID)
+	}
+	if filter.Action != "" {
+		conds = append(conds, `action = ?`)
+		args = append(args, filter.Action)
+	}
+	if filter.RequestID != "" {
+		conds = append(conds, `request_id = ?`)
+		args = append(args, filter.RequestID)
+	}
+	if !filter.Since.IsZero() {
+		conds = append(conds, `created_at >= ?`)
+		args = append(args, filter.Since.UTC().Format(timestampLayout))
+	}
+	if !filter.Until.IsZero() {
+		conds = append(conds, `created_at <= ?`)
+		args = append(args, filter.Until.UTC().Format(timestampLayout))
+	}
+	return conds, args
+}
+
+// GetAll returns the page of the audit log entries matching the filter, newest first.
+func (r *AuditRepository) GetAll(ctx context.Context, filter model.AuditFilter) ([]model.AuditEntry, error) {
+	conds, args := auditConditions(filter)
+	limitClause, limitArgs := limit(filter.Page)
+	query := `SELECT id, actor, request_id, remote_addr, entity, coalesce(entity_id, 0) AS entity_id, action,
+					 coalesce(before, '') AS before, coalesce(after, '') AS after, created_at
+			  FROM audit_log` + where(conds) + ` ORDER BY id DESC` + limitClause
+	args = append(args, limitArgs...)
+
+	entries := make([]model.AuditEntry, 0)
+	if err := r.db.SelectContext(ctx, &entries, query, args...); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while getting audit log entries")
+		return nil, errs.RepositoryUnknownError
+	}
+	return entries, nil
+}
+
+// Count returns the number of the audit log entries matching the filter, regardless of its page.
+func (r *AuditRepository) Count(ctx context.Context, filter model.AuditFilter) (int, error) {
+	conds, args := auditConditions(filter)
+	query := `SELECT count(*) FROM audit_log` + where(conds)
+
+	var count int
+	if err := r.db.GetContext(ctx, &count, query, args...); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while counting audit log entries")
+		return 0, errs.RepositoryUnknownError
+	}
+	return count, nil
+}
+
+// DeleteBefore deletes the audit log entries recorded before the time and returns the number of deleted entries.
+func (r *AuditRepository) DeleteBefore(ctx context.Context, before time.Time) (int, error) {
+	cmd := `DELETE FROM audit_log WHERE created_at < ?`
+	result, err := r.db.ExecContext(ctx, cmd, before.UTC().Format(timestampLayout))
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while deleting audit log entries")
+		return 0, errs.RepositoryUnknownError
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while retrieving affected rows count after deleting audit log entries")
+		return 0, errs.RepositoryUnknownError
+	}
+	return int(count), nil
+}
@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-playground/assert/v2"
+	"github.com/jmoiron/sqlx"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/nnemirovsky/pacgen/pkg/logutil"
+	"testing"
+	"time"
+)
+
+// expectSnapshot expects the state of the entity to be selected from the table for the audit log, the entity
+// is missing if the state is empty.
+func expectSnapshot(mock sqlmock.Sqlmock, from string, state string, args ...driver.Value) {
+	rows := sqlmock.NewRows([]string{"state"})
+	if state != "" {
+		rows.AddRow(state)
+	}
+	mock.
+		ExpectQuery(`SELECT json_object\(.* FROM ` + from).
+		WithArgs(args...).
+		WillReturnRows(rows)
+}
+
+// expectAudit expects the change of the entity to be recorded in the audit log without an actor.
+func expectAudit(mock sqlmock.Sqlmock, entity string, id int, action model.AuditAction) {
+	mock.
+		ExpectExec(`INSERT INTO audit_log \(actor, request_id, remote_addr, entity, entity_id, action, before, after, `+
+			`created_at\) VALUES \(\?, \?, \?, \?, nullif\(\?, 0\), \?, nullif\(\?, ''\), nullif\(\?, ''\), `+
+			`CURRENT_TIMESTAMP\)`).
+		WithArgs("", "", "", entity, id, string(action), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
+func testPrepareAuditRepository(t *testing.T) (*AuditRepository, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	dbx := sqlx.NewDb(db, "sqlmock")
+	repo := NewAuditRepository(dbx, logutil.DiscardLogger)
+
+	return repo, mock
+}
+
+func TestAudit_Actor(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	dbx := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectBegin()
+	expectSnapshot(mock, `rules r WHERE r.id = \?`, `{"id":10,"enabled":true}`, 10)
+	mock.
+		ExpectExec(`INSERT INTO audit_log`).
+		WithArgs("alice", "req-1", "10.0.0.1:5000", "rule", 10, "update", `{"id":10,"enabled":false}`,
+			`{"id":10,"enabled":true}`).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx = model.WithActor(ctx, model.Actor{User: "alice", RequestID: "req-1", RemoteAddr: "10.0.0.1:5000"})
+
+	tx, err := dbx.BeginTxx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = auditChange(ctx, tx, ruleSnapshot, model.AuditRule, 10, model.AuditUpdate, `{"id":10,"enabled":false}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAuditRepository_GetAll_Filtered(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareAuditRepository(t)
+
+	createdAt := time.Date(2023, 6, 6, 10, 0, 0, 0, time.UTC)
+
+	mock.
+		ExpectQuery(`SELECT id, actor, request_id, remote_addr, entity, coalesce\(entity_id, 0\) AS entity_id, `+
+			`action, coalesce\(before, ''\) AS before, coalesce\(after, ''\) AS after, created_at FROM audit_log `+
+			`WHERE actor = \? AND entity = \? AND entity_id = \? AND created_at >= \? ORDER BY id DESC `+
+			`LIMIT \? OFFSET \?$`).
+		WithArgs("alice", "rule", 10, "2023-06-06 00:00:00", 20, 40).
+		WillReturnRows(
+			sqlmock.
+				NewRows([]string{
+					"id", "actor", "request_id", "remote_addr", "entity", "entity_id", "action", "before", "after",
+					"created_at",
+				}).
+				AddRow(7, "alice", "req-1", "10.0.0.1:5000", "rule", 10, "delete", `{"id":10}`, "", createdAt),
+		)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	got, err := repo.GetAll(ctx, model.AuditFilter{
+		Actor:    "alice",
+		Entity:   model.AuditRule,
+		EntityID: 10,
+		Since:    time.Date(2023, 6, 6, 0, 0, 0, 0, time.UTC),
+		Page:     model.Page{Limit: 20, Offset: 40},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []model.AuditEntry{
+		{
+			ID:         7,
+			Actor:      "alice",
+			RequestID:  "req-1",
+			RemoteAddr: "10.0.0.1:5000",
+			Entity:     model.AuditRule,
+			EntityID:   10,
+			Action:     model.AuditDelete,
+			Before:     `{"id":10}`,
+			CreatedAt:  createdAt,
+		},
+	}
+
+	assert.Equal(t, got, want)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAuditRepository_Count_OK(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareAuditRepository(t)
+
+	mock.
+		ExpectQuery(`SELECT count\(\*\) FROM audit_log WHERE action = \? AND request_id = \?$`).
+		WithArgs("create", "req-1").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	got, err := repo.Count(ctx, model.AuditFilter{Action: model.AuditCreate, RequestID: "req-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, got, 3)
+}
+
+func TestAuditRepository_DeleteBefore_OK(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareAuditRepository(t)
+
+	mock.
+		ExpectExec(`DELETE FROM audit_log WHERE created_at < \?$`).
+		WithArgs("2023-03-08 12:00:00").
+		WillReturnResult(sqlmock.NewResult(0, 5))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	got, err := repo.DeleteBefore(ctx, time.Date(2023, 3, 8, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, got, 5)
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/rs/zerolog"
+)
+
+type BypassRepository struct {
+	logger zerolog.Logger
+	db     *sqlx.DB
+}
+
+func NewBypassRepository(db *sqlx.DB, logger zerolog.Logger) *BypassRepository {
+	return &BypassRepository{
+		logger: logger,
+		db:     db,
+	}
+}
+
+func (r *BypassRepository) GetAll(ctx context.Context) ([]model.BypassEntry, error) {
+	query := `SELECT kind, value FROM bypass_entries ORDER BY kind, value`
+	entries := make([]model.BypassEntry, 0)
+	if err := r.db.SelectContext(ctx, &entries, query); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while getting bypass entries")
+		return nil, errs.RepositoryUnknownError
+	}
+	return entries, nil
+}
+
+// Replace replaces all the bypass entries with the given ones.
+func (r *BypassRepository) Replace(ctx context.Context, entries []model.BypassEntry) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while starting transaction")
+		return errs.RepositoryUnknownError
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	before, err := snapshot(ctx, tx, bypassSnapshot)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while getting bypass list before replace")
+		return errs.RepositoryUnknownError
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM bypass_entries`); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while deleting bypass entries")
+		return errs.RepositoryUnknownError
+	}
+	cmd := `INSERT OR IGNORE INTO bypass_entries (kind, value) VALUES (?, ?)`
+	for _, entry := range entries {
+		if _, err := tx.ExecContext(ctx, cmd, entry.Kind, entry.Value); err != nil {
+			r.logger.Error().Err(err).Msg("Error occurred while inserting bypass entry")
+			return errs.RepositoryUnknownError
+		}
+	}
+
+	after, err := snapshot(ctx, tx, bypassSnapshot)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while getting bypass list after replace")
+		return errs.RepositoryUnknownError
+	}
+	entry := model.AuditEntry{Entity: model.AuditBypass, Action: model.AuditUpdate, Before: before, After: after}
+	if err := audit(ctx, tx, entry); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while auditing replaced bypass list")
+		return errs.RepositoryUnknownError
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while committing transaction")
+		return errs.RepositoryUnknownError
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-playground/assert/v2"
+	"github.com/jmoiron/sqlx"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/nnemirovsky/pacgen/pkg/logutil"
+	"testing"
+)
+
+func testPrepareBypassRepository(t *testing.T) (*BypassRepository, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	dbx := sqlx.NewDb(db, "sqlmock")
+	repo := NewBypassRepository(dbx, logutil.DiscardLogger)
+
+	return repo, mock
+}
+
+func TestBypassRepository_GetAll_OK(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareBypassRepository(t)
+
+	mock.
+		ExpectQuery(`SELECT kind, value FROM bypass_entries ORDER BY kind, value`).
+		WillReturnRows(
+			sqlmock.
+				NewRows([]string{"kind", "value"}).
+				AddRow(model.BypassPreset, model.PresetPlainHostNames).
+				AddRow(model.BypassNetwork, "10.0.0.0/8"),
+		)
+
+	got, err := repo.GetAll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []model.BypassEntry{
+		{Kind: model.BypassPreset, Value: model.PresetPlainHostNames},
+		{Kind: model.BypassNetwork, Value: "10.0.0.0/8"},
+	}
+	assert.Equal(t, got, want)
+}
+
+func TestBypassRepository_Replace_OK(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareBypassRepository(t)
+
+	const bypassEntries = `\(SELECT kind, value FROM bypass_entries ORDER BY kind, value\) b$`
+
+	mock.ExpectBegin()
+	expectSnapshot(mock, bypassEntries, `{"entries":[]}`)
+	mock.ExpectExec(`DELETE FROM bypass_entries`).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.
+		ExpectExec(`INSERT OR IGNORE INTO bypass_entries \(kind, value\) VALUES \(\?, \?\)`).
+		WithArgs(model.BypassDomainAndSubdomains, "corp").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	expectSnapshot(mock, bypassEntries, `{"entries":[{"kind":2,"value":"corp"}]}`)
+	expectAudit(mock, model.AuditBypass, 0, model.AuditUpdate)
+	mock.ExpectCommit()
+
+	entries := []model.BypassEntry{{Kind: model.BypassDomainAndSubdomains, Value: "corp"}}
+	if err := repo.Replace(context.Background(), entries); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBypassRepository_Replace_Error(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareBypassRepository(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM bypass_entries`).WillReturnError(errors.New("disk I/O error"))
+	mock.ExpectRollback()
+
+	err := repo.Replace(context.Background(), nil)
+	if err != errs.RepositoryUnknownError {
+		t.Errorf("expected error is errs.RepositoryUnknownError, but got %#v", err)
+	}
+}
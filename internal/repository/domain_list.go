@@ -0,0 +1,247 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/rs/zerolog"
+)
+
+// domainListEntryRow is a domain list entry along with the id of its list.
+type domainListEntryRow struct {
+	ListID int `db:"list_id"`
+	model.DomainListEntry
+}
+
+type DomainListRepository struct {
+	logger zerolog.Logger
+	db     *sqlx.DB
+}
+
+func NewDomainListRepository(db *sqlx.DB, logger zerolog.Logger) *DomainListRepository {
+	return &DomainListRepository{
+		logger: logger,
+		db:     db,
+	}
+}
+
+func (r *DomainListRepository) GetAll(ctx context.Context) ([]model.DomainList, error) {
+	query := `SELECT id, name FROM domain_lists`
+	lists := make([]model.DomainList, 0)
+	if err := r.db.SelectContext(ctx, &lists, query); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while getting domain lists")
+		return nil, errs.RepositoryUnknownError
+	}
+
+	query = `SELECT list_id, domain, mode FROM domain_list_entries ORDER BY list_id, domain`
+	entries := make([]domainListEntryRow, 0)
+	if err := r.db.SelectContext(ctx, &entries, query); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while getting domain list entries")
+		return nil, errs.RepositoryUnknownError
+	}
+
+	byID := make(map[int]*model.DomainList, len(lists))
+	for i := range lists {
+		lists[i].Entries = make([]model.DomainListEntry, 0)
+		byID[lists[i].ID] = &lists[i]
+	}
+	for _, entry := range entries {
+		if list, ok := byID[entry.ListID]; ok {
+			list.Entries = append(list.Entries, entry.DomainListEntry)
+		}
+	}
+	return lists, nil
+}
+
+func (r *DomainListRepository) GetByID(ctx context.Context, id int) (model.DomainList, error) {
+	query := `SELECT id, name FROM domain_lists WHERE id = ?`
+	var list model.DomainList
+	if err := r.db.GetContext(ctx, &list, query, id); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			err = &errs.EntityNotFoundError{Name: "domain list", Key: "id", Value: id}
+			r.logger.Debug().Err(err).Send()
+		default:
+			err = errs.RepositoryUnknownError
+			r.logger.Error().Err(err).Msg("Error occurred while getting domain list by id")
+		}
+		return model.DomainList{}, err
+	}
+
+	query = `SELECT domain, mode FROM domain_list_entries WHERE list_id = ? ORDER BY domain`
+	list.Entries = make([]model.DomainListEntry, 0)
+	if err := r.db.SelectContext(ctx, &list.Entries, query, id); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while getting domain list entries")
+		return model.DomainList{}, errs.RepositoryUnknownError
+	}
+	return list, nil
+}
+
+func (r *DomainListRepository) Create(ctx context.Context, list *model.DomainList) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while starting transaction")
+		return errs.RepositoryUnknownError
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	cmd := `INSERT INTO domain_lists (name) VALUES (:name)`
+	result, err := tx.NamedExecContext(ctx, cmd, list)
+	if err != nil {
+		if s, ok := err.(sqlite3.Error); ok && s.Code == sqlite3.ErrConstraint {
+			err := &errs.EntityAlreadyExistsError{Name: "domain list", Key: "name", Value: list.Name}
+			r.logger.Debug().Err(err).Send()
+			return err
+		}
+		r.logger.Error().Err(err).Msg("Error occurred while creating domain list")
+		return errs.RepositoryUnknownError
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while retrieving created domain list id")
+		return errs.RepositoryUnknownError
+	}
+
+	if err := r.setEntries(ctx, tx, int(id), list.Entries); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while setting entries of created domain list")
+		return errs.RepositoryUnknownError
+	}
+
+	if err := auditChange(ctx, tx, domainListSnapshot, model.AuditDomainList, int(id), model.AuditCreate, ""); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while auditing created domain list")
+		return errs.RepositoryUnknownError
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while committing transaction")
+		return errs.RepositoryUnknownError
+	}
+
+	list.ID = int(id)
+	return nil
+}
+
+func (r *DomainListRepository) Update(ctx context.Context, list model.DomainList) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while starting transaction")
+		return errs.RepositoryUnknownError
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	before, err := snapshot(ctx, tx, domainListSnapshot, list.ID)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while getting domain list before update")
+		return errs.RepositoryUnknownError
+	}
+
+	cmd := `UPDATE domain_lists SET name = :name WHERE id = :id`
+	result, err := tx.NamedExecContext(ctx, cmd, list)
+	if err != nil {
+		if s, ok := err.(sqlite3.Error); ok && s.Code == sqlite3.ErrConstraint {
+			err := &errs.EntityAlreadyExistsError{Name: "domain list", Key: "name", Value: list.Name}
+			r.logger.Debug().Err(err).Send()
+			return err
+		}
+		r.logger.Error().Err(err).Msg("Error occurred while updating domain list")
+		return errs.RepositoryUnknownError
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while retrieving affected rows count after updating domain list")
+		return errs.RepositoryUnknownError
+	}
+	if count == 0 {
+		err = &errs.EntityNotFoundError{Name: "domain list", Key: "id", Value: list.ID}
+		r.logger.Debug().Err(err).Send()
+		return err
+	}
+
+	if err := r.setEntries(ctx, tx, list.ID, list.Entries); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while setting entries of updated domain list")
+		return errs.RepositoryUnknownError
+	}
+
+	err = auditChange(ctx, tx, domainListSnapshot, model.AuditDomainList, list.ID, model.AuditUpdate, before)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while auditing updated domain list")
+		return errs.RepositoryUnknownError
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while committing transaction")
+		return errs.RepositoryUnknownError
+	}
+	return nil
+}
+
+func (r *DomainListRepository) Delete(ctx context.Context, id int) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while starting transaction")
+		return errs.RepositoryUnknownError
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	before, err := snapshot(ctx, tx, domainListSnapshot, id)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while getting domain list before delete")
+		return errs.RepositoryUnknownError
+	}
+
+	cmd := `DELETE FROM domain_lists WHERE id = ?`
+	result, err := tx.ExecContext(ctx, cmd, id)
+	if err != nil {
+		if s, ok := err.(sqlite3.Error); ok && s.Code == sqlite3.ErrConstraint {
+			err := &errs.EntityStillReferencedError{Name: "domain list", Key: "id", Value: id}
+			r.logger.Debug().Err(err).Send()
+			return err
+		}
+		r.logger.Error().Err(err).Msg("Error occurred while deleting domain list")
+		return errs.RepositoryUnknownError
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while retrieving affected rows count after deleting domain list")
+		return errs.RepositoryUnknownError
+	}
+	if count == 0 {
+		err = &errs.EntityNotFoundError{Name: "domain list", Key: "id", Value: id}
+		r.logger.Debug().Err(err).Send()
+		return err
+	}
+
+	if err := auditChange(ctx, tx, domainListSnapshot, model.AuditDomainList, id, model.AuditDelete, before); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while auditing deleted domain list")
+		return errs.RepositoryUnknownError
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while committing transaction")
+		return errs.RepositoryUnknownError
+	}
+	return nil
+}
+
+// setEntries replaces entries of the domain list.
+func (r *DomainListRepository) setEntries(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	listID int,
+	entries []model.DomainListEntry,
+) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM domain_list_entries WHERE list_id = ?`, listID); err != nil {
+		return err
+	}
+	cmd := `INSERT INTO domain_list_entries (list_id, domain, mode) VALUES (?, ?, ?)`
+	for _, entry := range entries {
+		if _, err := tx.ExecContext(ctx, cmd, listID, entry.Domain, entry.Mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
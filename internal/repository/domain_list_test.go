@@ -0,0 +1,244 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-playground/assert/v2"
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/nnemirovsky/pacgen/pkg/logutil"
+	"testing"
+)
+
+func testPrepareDomainListRepository(t *testing.T) (*DomainListRepository, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	dbx := sqlx.NewDb(db, "sqlmock")
+	repo := NewDomainListRepository(dbx, logutil.DiscardLogger)
+
+	return repo, mock
+}
+
+func TestDomainListRepository_GetAll_OK(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareDomainListRepository(t)
+
+	mock.
+		ExpectQuery(`SELECT id, name FROM domain_lists`).
+		WillReturnRows(
+			sqlmock.
+				NewRows([]string{"id", "name"}).
+				AddRow(1, "video cdn").
+				AddRow(2, "empty"),
+		)
+	mock.
+		ExpectQuery(`SELECT list_id, domain, mode FROM domain_list_entries ORDER BY list_id, domain`).
+		WillReturnRows(
+			sqlmock.
+				NewRows([]string{"list_id", "domain", "mode"}).
+				AddRow(1, "googlevideo.com", model.DomainAndSubdomains).
+				AddRow(1, "vimeo.com", model.ExactDomain),
+		)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	got, err := repo.GetAll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []model.DomainList{
+		{
+			ID:   1,
+			Name: "video cdn",
+			Entries: []model.DomainListEntry{
+				{Domain: "googlevideo.com", Mode: model.DomainAndSubdomains},
+				{Domain: "vimeo.com", Mode: model.ExactDomain},
+			},
+		},
+		{ID: 2, Name: "empty", Entries: []model.DomainListEntry{}},
+	}
+
+	assert.Equal(t, got, want)
+}
+
+func TestDomainListRepository_GetByID_OK(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareDomainListRepository(t)
+
+	mock.
+		ExpectQuery(`SELECT id, name FROM domain_lists WHERE id = \?`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "video cdn"))
+	mock.
+		ExpectQuery(`SELECT domain, mode FROM domain_list_entries WHERE list_id = \? ORDER BY domain`).
+		WithArgs(1).
+		WillReturnRows(
+			sqlmock.
+				NewRows([]string{"domain", "mode"}).
+				AddRow("googlevideo.com", model.DomainAndSubdomains),
+		)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	got, err := repo.GetByID(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := model.DomainList{
+		ID:      1,
+		Name:    "video cdn",
+		Entries: []model.DomainListEntry{{Domain: "googlevideo.com", Mode: model.DomainAndSubdomains}},
+	}
+
+	assert.Equal(t, got, want)
+}
+
+func TestDomainListRepository_GetByID_NotFound(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareDomainListRepository(t)
+
+	mock.
+		ExpectQuery(`SELECT id, name FROM domain_lists WHERE id = \?`).
+		WithArgs(1).
+		WillReturnError(sql.ErrNoRows)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := repo.GetByID(ctx, 1)
+
+	if _, ok := err.(*errs.EntityNotFoundError); !ok {
+		t.Fatal("expected errs.EntityNotFoundError")
+	}
+}
+
+func TestDomainListRepository_Create_OK(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareDomainListRepository(t)
+
+	const insertedID = 15
+
+	mock.ExpectBegin()
+	mock.
+		ExpectExec(`INSERT INTO domain_lists \(name\) VALUES \(\?\)`).
+		WithArgs("video cdn").
+		WillReturnResult(sqlmock.NewResult(insertedID, 1))
+	mock.
+		ExpectExec(`DELETE FROM domain_list_entries WHERE list_id = \?`).
+		WithArgs(insertedID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.
+		ExpectExec(`INSERT INTO domain_list_entries \(list_id, domain, mode\) VALUES \(\?, \?, \?\)`).
+		WithArgs(insertedID, "googlevideo.com", model.DomainAndSubdomains).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.
+		ExpectExec(`INSERT INTO domain_list_entries \(list_id, domain, mode\) VALUES \(\?, \?, \?\)`).
+		WithArgs(insertedID, "vimeo.com", model.ExactDomain).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	expectSnapshot(mock, `domain_lists l WHERE l.id = \?`, `{"id":15}`, insertedID)
+	expectAudit(mock, model.AuditDomainList, insertedID, model.AuditCreate)
+	mock.ExpectCommit()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	list := model.DomainList{
+		Name: "video cdn",
+		Entries: []model.DomainListEntry{
+			{Domain: "googlevideo.com", Mode: model.DomainAndSubdomains},
+			{Domain: "vimeo.com", Mode: model.ExactDomain},
+		},
+	}
+	err := repo.Create(ctx, &list)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, list.ID, insertedID)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDomainListRepository_Create_AlreadyExists(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareDomainListRepository(t)
+
+	mock.ExpectBegin()
+	mock.
+		ExpectExec(`INSERT INTO domain_lists \(name\) VALUES \(\?\)`).
+		WithArgs("video cdn").
+		WillReturnError(sqlite3.Error{Code: sqlite3.ErrConstraint})
+	mock.ExpectRollback()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	list := model.DomainList{Name: "video cdn"}
+	err := repo.Create(ctx, &list)
+
+	if _, ok := err.(*errs.EntityAlreadyExistsError); !ok {
+		t.Fatal("expected error errs.EntityAlreadyExistsError")
+	}
+}
+
+func TestDomainListRepository_Update_NotFound(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareDomainListRepository(t)
+
+	mock.ExpectBegin()
+	expectSnapshot(mock, `domain_lists l WHERE l.id = \?`, "", 10)
+	mock.
+		ExpectExec(`UPDATE domain_lists SET name = \? WHERE id = \?`).
+		WithArgs("video cdn", 10).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err := repo.Update(ctx, model.DomainList{ID: 10, Name: "video cdn"})
+
+	if _, ok := err.(*errs.EntityNotFoundError); !ok {
+		t.Fatal("expected errs.EntityNotFoundError")
+	}
+}
+
+func TestDomainListRepository_Delete_StillReferenced(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareDomainListRepository(t)
+
+	mock.ExpectBegin()
+	expectSnapshot(mock, `domain_lists l WHERE l.id = \?`, `{"id":10}`, 10)
+	mock.
+		ExpectExec(`DELETE FROM domain_lists WHERE id = \?`).
+		WithArgs(10).
+		WillReturnError(sqlite3.Error{Code: sqlite3.ErrConstraint})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err := repo.Delete(ctx, 10)
+
+	if _, ok := err.(*errs.EntityStillReferencedError); !ok {
+		t.Fatal("expected errs.EntityStillReferencedError")
+	}
+}
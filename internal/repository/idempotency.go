@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/pkg/rest"
+	"github.com/rs/zerolog"
+	"net/http"
+	"time"
+)
+
+// IdempotencyRepository stores the responses of the requests with idempotency keys, it is rest.IdempotencyStore.
+type IdempotencyRepository struct {
+	logger zerolog.Logger
+	db     *sqlx.DB
+}
+
+func NewIdempotencyRepository(db *sqlx.DB, logger zerolog.Logger) *IdempotencyRepository {
+	return &IdempotencyRepository{
+		logger: logger,
+		db:     db,
+	}
+}
+
+// Get returns the response stored for the key unless it has expired.
+func (r *IdempotencyRepository) Get(ctx context.Context, key string) (rest.IdempotentResponse, bool, error) {
+	query := `SELECT fingerprint, status_code, header, body
+			  FROM idempotency_keys
+			  WHERE key = ? AND expires_at > CURRENT_TIMESTAMP`
+	var row struct {
+		Fingerprint string `db:"fingerprint"`
+		StatusCode  int    `db:"status_code"`
+		Header      string `db:"header"`
+		Body        []byte `db:"body"`
+	}
+	if err := r.db.GetContext(ctx, &row, query, key); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return rest.IdempotentResponse{}, false, nil
+		}
+		r.logger.Error().Err(err).Msg("Error occurred while getting idempotent response")
+		return rest.IdempotentResponse{}, false, errs.RepositoryUnknownError
+	}
+
+	header := make(http.Header)
+	if err := json.Unmarshal([]byte(row.Header), &header); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while decoding header of idempotent response")
+		return rest.IdempotentResponse{}, false, errs.RepositoryUnknownError
+	}
+	resp := rest.IdempotentResponse{
+		Fingerprint: row.Fingerprint,
+		StatusCode:  row.StatusCode,
+		Header:      header,
+		Body:        row.Body,
+	}
+	return resp, true, nil
+}
+
+// Save stores the response for the key until the expiration time. The responses which have already expired
+// are deleted along the way, so the table does not grow.
+func (r *IdempotencyRepository) Save(
+	ctx context.Context,
+	key string,
+	resp rest.IdempotentResponse,
+	expiresAt time.Time,
+) error {
+	header, err := json.Marshal(resp.Header)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while encoding header of idempotent response")
+		return errs.RepositoryUnknownError
+	}
+	body := resp.Body
+	if body == nil {
+		body = []byte{}
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while starting transaction")
+		return errs.RepositoryUnknownError
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= CURRENT_TIMESTAMP`); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while deleting expired idempotent responses")
+		return errs.RepositoryUnknownError
+	}
+	cmd := `INSERT OR REPLACE INTO idempotency_keys (key, fingerprint, status_code, header, body, created_at, expires_at)
+			VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP, ?)`
+	_, err = tx.ExecContext(
+		ctx,
+		cmd,
+		key,
+		resp.Fingerprint,
+		resp.StatusCode,
+		string(header),
+		body,
+		expiresAt.UTC().Format(timestampLayout),
+	)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while saving idempotent response")
+		return errs.RepositoryUnknownError
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while committing transaction")
+		return errs.RepositoryUnknownError
+	}
+	return nil
+}
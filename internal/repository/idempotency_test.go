@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-playground/assert/v2"
+	"github.com/jmoiron/sqlx"
+	"github.com/nnemirovsky/pacgen/pkg/logutil"
+	"github.com/nnemirovsky/pacgen/pkg/rest"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func testPrepareIdempotencyRepository(t *testing.T) (*IdempotencyRepository, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	dbx := sqlx.NewDb(db, "sqlmock")
+	repo := NewIdempotencyRepository(dbx, logutil.DiscardLogger)
+
+	return repo, mock
+}
+
+func TestIdempotencyRepository_Get_OK(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareIdempotencyRepository(t)
+
+	mock.
+		ExpectQuery(`SELECT fingerprint, status_code, header, body FROM idempotency_keys ` +
+			`WHERE key = \? AND expires_at > CURRENT_TIMESTAMP$`).
+		WithArgs("key-1").
+		WillReturnRows(
+			sqlmock.
+				NewRows([]string{"fingerprint", "status_code", "header", "body"}).
+				AddRow("f1", http.StatusCreated, `{"Location":["/api/v1/rules/3"]}`, []byte{}),
+		)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	got, ok, err := repo.Get(ctx, "key-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := rest.IdempotentResponse{
+		Fingerprint: "f1",
+		StatusCode:  http.StatusCreated,
+		Header:      http.Header{"Location": {"/api/v1/rules/3"}},
+		Body:        []byte{},
+	}
+
+	assert.Equal(t, ok, true)
+	assert.Equal(t, got, want)
+}
+
+func TestIdempotencyRepository_Get_Missing(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareIdempotencyRepository(t)
+
+	mock.
+		ExpectQuery(`SELECT fingerprint, status_code, header, body FROM idempotency_keys`).
+		WithArgs("key-1").
+		WillReturnRows(sqlmock.NewRows([]string{"fingerprint", "status_code", "header", "body"}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, ok, err := repo.Get(ctx, "key-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, ok, false)
+}
+
+func TestIdempotencyRepository_Save_OK(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareIdempotencyRepository(t)
+
+	expiresAt := time.Date(2023, 6, 2, 15, 4, 5, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.
+		ExpectExec(`DELETE FROM idempotency_keys WHERE expires_at <= CURRENT_TIMESTAMP`).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.
+		ExpectExec(`INSERT OR REPLACE INTO idempotency_keys \(key, fingerprint, status_code, header, body, `+
+			`created_at, expires_at\) VALUES \(\?, \?, \?, \?, \?, CURRENT_TIMESTAMP, \?\)`).
+		WithArgs("key-1", "f1", http.StatusNoContent, `{"Etag":["\"2\""]}`, []byte{}, "2023-06-02 15:04:05").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	resp := rest.IdempotentResponse{
+		Fingerprint: "f1",
+		StatusCode:  http.StatusNoContent,
+		Header:      http.Header{"Etag": {`"2"`}},
+	}
+	if err := repo.Save(ctx, "key-1", resp, expiresAt); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"strings"
+)
+
+// domainColumn extracts the domain from the regex of the rule created by regexp.Domain
+// or regexp.DomainAndSubdomains, %[1]s stands for the alias of the rules table.
+const domainColumn = `replace(rtrim(CASE WHEN substr(%[1]s.regex, 1, 8) = '(?:^|\.)' THEN substr(%[1]s.regex, 9)
+									  ELSE ltrim(%[1]s.regex, '^') END, '$'), '\.', '.')`
+
+// fieldColumns are the columns selected for the field of an entity.
+type fieldColumns struct {
+	field   string
+	columns string
+}
+
+// selectColumns returns the select list of the given fields, or of all the fields if none are given.
+func selectColumns(columns []fieldColumns, fields []string) string {
+	selected := make([]string, 0, len(columns))
+	for _, c := range columns {
+		if len(fields) == 0 || contains(fields, c.field) {
+			selected = append(selected, c.columns)
+		}
+	}
+	return strings.Join(selected, ", ")
+}
+
+// limit returns LIMIT clause of the page along with its arguments, or nothing if the page is not limited.
+func limit(page model.Page) (string, []any) {
+	switch {
+	case page.Limit > 0:
+		return ` LIMIT ? OFFSET ?`, []any{page.Limit, page.Offset}
+	case page.Offset > 0:
+		return ` LIMIT -1 OFFSET ?`, []any{page.Offset}
+	default:
+		return "", nil
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/rs/zerolog"
+	"strings"
+	"time"
+)
+
+// timestampLayout is the layout of CURRENT_TIMESTAMP, bounds are formatted the same way to be compared as text.
+const timestampLayout = "2006-01-02 15:04:05"
+
+// sortColumns are the expressions entities can be ordered by, %[1]s stands for the alias of the table. Other fields
+// of model.Sort fall back to id.
+var sortColumns = map[string]string{
+	"id":          "%[1]s.id",
+	"priority":    "%[1]s.id",
+	"name":        "%[1]s.name",
+	"domain":      domainColumn,
+	"created_at":  "%[1]s.created_at",
+	"updated_at":  "%[1]s.updated_at",
+	"owner":       "%[1]s.owner",
+	"description": "%[1]s.description",
+}
+
+// metadataConditions returns conditions on the metadata columns of the table with the alias matching the filter,
+// along with their arguments.
+func metadataConditions(alias string, filter model.MetadataFilter) (conds []string, args []any) {
+	if filter.Owner != "" {
+		conds = append(conds, alias+`.owner = ?`)
+		args = append(args, filter.Owner)
+	}
+	if filter.Description != "" {
+		conds = append(conds, `instr(lower(`+alias+`.description), lower(?)) > 0`)
+		args = append(args, filter.Description)
+	}
+	bounds := []struct {
+		cond string
+		t    time.Time
+	}{
+		{alias + `.created_at >= ?`, filter.CreatedAfter},
+		{alias + `.created_at <= ?`, filter.CreatedBefore},
+		{alias + `.updated_at >= ?`, filter.UpdatedAfter},
+		{alias + `.updated_at <= ?`, filter.UpdatedBefore},
+	}
+	for _, b := range bounds {
+		if !b.t.IsZero() {
+			conds = append(conds, b.cond)
+			args = append(args, b.t.UTC().Format(timestampLayout))
+		}
+	}
+	return conds, args
+}
+
+// orderBy returns ORDER BY clause of the sort on columns of the table with the alias. Ties are broken by id,
+// so pages of the list do not overlap.
+func orderBy(alias string, sort model.Sort) string {
+	column, ok := sortColumns[sort.Field]
+	if !ok {
+		column = sortColumns["id"]
+	}
+	direction := " ASC"
+	if sort.Desc {
+		direction = " DESC"
+	}
+	clause := ` ORDER BY ` + fmt.Sprintf(column, alias) + direction
+	if column != sortColumns["id"] {
+		clause += `, ` + alias + `.id` + direction
+	}
+	return clause
+}
+
+// where returns WHERE clause joining the conditions, or nothing if there are none.
+func where(conds []string) string {
+	if len(conds) == 0 {
+		return ""
+	}
+	return ` WHERE ` + strings.Join(conds, ` AND `)
+}
+
+// versionGuard matches the entity of the version given in :version, zero matches any version. Statements
+// guarded by it increment the version along with the change.
+const versionGuard = `:version IN (0, version)`
+
+// versionConflict tells why a statement guarded by the version of the entity with the id changed nothing: either
+// there is no such entity or it has another version.
+func versionConflict(
+	ctx context.Context,
+	q sqlx.QueryerContext,
+	logger zerolog.Logger,
+	table string,
+	name string,
+	id int,
+) error {
+	var version int
+	if err := sqlx.GetContext(ctx, q, &version, `SELECT version FROM `+table+` WHERE id = ?`, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = &errs.EntityNotFoundError{Name: name, Key: "id", Value: id}
+			logger.Debug().Err(err).Send()
+			return err
+		}
+		logger.Error().Err(err).Msgf("Error occurred while getting version of %s", name)
+		return errs.RepositoryUnknownError
+	}
+	err := &errs.EntityVersionConflictError{Name: name, Key: "id", Value: id, Version: version}
+	logger.Debug().Err(err).Send()
+	return err
+}
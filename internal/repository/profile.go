@@ -4,13 +4,24 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"github.com/jmoiron/sqlx"
 	"github.com/mattn/go-sqlite3"
 	"github.com/nnemirovsky/pacgen/internal/errs"
 	"github.com/nnemirovsky/pacgen/internal/model"
 	"github.com/rs/zerolog"
+	"strings"
 )
 
+// poolMemberRow is a pool member along with the id of its pool.
+type poolMemberRow struct {
+	PoolID int `db:"pool_id"`
+	model.PoolMember
+}
+
+// ruleCountColumn counts the rules routed through the profile, %[1]s stands for the alias of the profiles table.
+const ruleCountColumn = `(SELECT count(*) FROM rules r WHERE r.proxy_profile_id = %[1]s.id) AS rule_count`
+
 type ProxyProfileRepository struct {
 	logger zerolog.Logger
 	db     *sqlx.DB
@@ -23,18 +34,95 @@ func NewProxyProfileRepository(db *sqlx.DB, logger zerolog.Logger) *ProxyProfile
 	}
 }
 
-func (r *ProxyProfileRepository) GetAll(ctx context.Context) ([]model.ProxyProfile, error) {
-	query := `SELECT id, name, type, address FROM proxy_profiles`
+// profileColumns are the columns selected for the fields of model.ProxyProfileFields. Members are loaded
+// by a separate query.
+var profileColumns = []fieldColumns{
+	{"id", `p.id`},
+	{"name", `p.name`},
+	{"type", `p.type`},
+	{"address", `coalesce(p.host, '') AS host, coalesce(p.port, 0) AS port`},
+	{"standby_id", `coalesce(p.standby_profile_id, 0) AS standby_id`},
+	{"description", `p.description`},
+	{"owner", `p.owner`},
+	{"created_at", `p.created_at`},
+	{"updated_at", `p.updated_at`},
+}
+
+// GetAll returns the page of the proxy profiles matching the filter. Only the fields of the filter are loaded
+// if it has any, members of pools need id and type to be loaded as well. Rule counts are loaded on request.
+func (r *ProxyProfileRepository) GetAll(
+	ctx context.Context,
+	filter model.ProxyProfileFilter,
+) ([]model.ProxyProfile, error) {
+	conds, args := metadataConditions("p", filter.MetadataFilter)
+	limitClause, limitArgs := limit(filter.Page)
+	columns := selectColumns(profileColumns, filter.Fields)
+	if filter.WithRuleCount {
+		columns += `, ` + fmt.Sprintf(ruleCountColumn, "p")
+	}
+	query := `SELECT ` + columns + ` FROM proxy_profiles p` + where(conds) + orderBy("p", filter.Sort) + limitClause
+	args = append(args, limitArgs...)
+
 	profiles := make([]model.ProxyProfile, 0)
-	if err := r.db.SelectContext(ctx, &profiles, query); err != nil {
+	if err := r.db.SelectContext(ctx, &profiles, query, args...); err != nil {
 		r.logger.Error().Err(err).Msg("Error occurred while getting proxy profiles")
 		return nil, errs.RepositoryUnknownError
 	}
+	if len(filter.Fields) != 0 && !contains(filter.Fields, "members") {
+		return profiles, nil
+	}
+
+	query = `SELECT pool_id, member_id, weight FROM profile_pool_members ORDER BY pool_id, member_id`
+	members := make([]poolMemberRow, 0)
+	if err := r.db.SelectContext(ctx, &members, query); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while getting pool members")
+		return nil, errs.RepositoryUnknownError
+	}
+
+	byID := make(map[int]*model.ProxyProfile, len(profiles))
+	for i := range profiles {
+		if profiles[i].Type == model.Pool {
+			profiles[i].Members = make([]model.PoolMember, 0)
+		}
+		byID[profiles[i].ID] = &profiles[i]
+	}
+	for _, member := range members {
+		if pool, ok := byID[member.PoolID]; ok {
+			pool.Members = append(pool.Members, member.PoolMember)
+		}
+	}
 	return profiles, nil
 }
 
+// Count returns the number of the proxy profiles matching the filter regardless of its page.
+func (r *ProxyProfileRepository) Count(ctx context.Context, filter model.ProxyProfileFilter) (int, error) {
+	conds, args := metadataConditions("p", filter.MetadataFilter)
+	query := `SELECT count(*) FROM proxy_profiles p` + where(conds)
+
+	var count int
+	if err := r.db.GetContext(ctx, &count, query, args...); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while counting proxy profiles")
+		return 0, errs.RepositoryUnknownError
+	}
+	return count, nil
+}
+
+// GetByID returns the profile along with the number of its rules.
 func (r *ProxyProfileRepository) GetByID(ctx context.Context, id int) (model.ProxyProfile, error) {
-	query := `SELECT id, name, type, address FROM proxy_profiles WHERE id = ?`
+	query := `SELECT id,
+					 name,
+					 type,
+					 coalesce(host, '') AS host,
+					 coalesce(port, 0) AS port,
+					 coalesce(standby_profile_id, 0) AS standby_id,
+					 description,
+					 owner,
+					 created_at,
+					 updated_at,
+					 version,
+					 ` + fmt.Sprintf(ruleCountColumn, "proxy_profiles") + `
+			  FROM proxy_profiles
+			  WHERE id = ?`
 	var profile model.ProxyProfile
 	if err := r.db.GetContext(ctx, &profile, query, id); err != nil {
 		switch {
@@ -47,13 +135,36 @@ func (r *ProxyProfileRepository) GetByID(ctx context.Context, id int) (model.Pro
 		}
 		return model.ProxyProfile{}, err
 	}
+
+	if profile.Type != model.Pool {
+		return profile, nil
+	}
+	query = `SELECT member_id, weight FROM profile_pool_members WHERE pool_id = ? ORDER BY member_id`
+	profile.Members = make([]model.PoolMember, 0)
+	if err := r.db.SelectContext(ctx, &profile.Members, query, id); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while getting pool members")
+		return model.ProxyProfile{}, errs.RepositoryUnknownError
+	}
 	return profile, nil
 }
 
 func (r *ProxyProfileRepository) Create(ctx context.Context, profile *model.ProxyProfile) error {
-	cmd := `INSERT INTO proxy_profiles (name, type, address) VALUES (:name, :type, :address)`
-	result, err := r.db.NamedExecContext(ctx, cmd, profile)
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while starting transaction")
+		return errs.RepositoryUnknownError
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	cmd := `INSERT INTO proxy_profiles (name, type, host, port, standby_profile_id, description, owner, created_at, updated_at)
+			VALUES (:name, :type, nullif(:host, ''), nullif(:port, 0), nullif(:standby_id, 0), :description, :owner,
+					CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`
+	result, err := tx.NamedExecContext(ctx, cmd, profile)
 	if err != nil {
+		if s, ok := err.(sqlite3.Error); ok && isInvalidReference(s) {
+			r.logger.Debug().Err(err).Msg("Unknown standby profile or profile is standby for itself")
+			return errs.InvalidReferenceError
+		}
 		if s, ok := err.(sqlite3.Error); ok && s.Code == sqlite3.ErrConstraint {
 			err := &errs.EntityAlreadyExistsError{Name: "proxy profile", Key: "name", Value: profile.Name}
 			r.logger.Debug().Err(err).Send()
@@ -67,14 +178,59 @@ func (r *ProxyProfileRepository) Create(ctx context.Context, profile *model.Prox
 		r.logger.Error().Err(err).Msg("Error occurred while retrieving created profile id")
 		return errs.RepositoryUnknownError
 	}
+
+	if err := r.setMembers(ctx, tx, int(id), profile.Members); err != nil {
+		return err
+	}
+
+	if err := auditChange(ctx, tx, profileSnapshot, model.AuditProxyProfile, int(id), model.AuditCreate, ""); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while auditing created proxy profile")
+		return errs.RepositoryUnknownError
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while committing transaction")
+		return errs.RepositoryUnknownError
+	}
+
 	profile.ID = int(id)
 	return nil
 }
 
+// Update replaces the profile of the version given in the profile. Owner of the profile is kept if the given one
+// is empty.
 func (r *ProxyProfileRepository) Update(ctx context.Context, profile model.ProxyProfile) error {
-	cmd := `UPDATE proxy_profiles SET name = :name, type = :type, address = :address WHERE id = :id`
-	result, err := r.db.NamedExecContext(ctx, cmd, profile)
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while starting transaction")
+		return errs.RepositoryUnknownError
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	before, err := snapshot(ctx, tx, profileSnapshot, profile.ID)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while getting proxy profile before update")
+		return errs.RepositoryUnknownError
+	}
+
+	cmd := `UPDATE proxy_profiles
+			SET name               = :name,
+				type               = :type,
+				host               = nullif(:host, ''),
+				port               = nullif(:port, 0),
+				address            = NULL,
+				standby_profile_id = nullif(:standby_id, 0),
+				description        = :description,
+				owner              = coalesce(nullif(:owner, ''), owner),
+				updated_at         = CURRENT_TIMESTAMP,
+				version            = version + 1
+			WHERE id = :id AND ` + versionGuard
+	result, err := tx.NamedExecContext(ctx, cmd, profile)
 	if err != nil {
+		if s, ok := err.(sqlite3.Error); ok && isInvalidReference(s) {
+			r.logger.Debug().Err(err).Msg("Unknown standby profile or profile is standby for itself")
+			return errs.InvalidReferenceError
+		}
 		if s, ok := err.(sqlite3.Error); ok && s.Code == sqlite3.ErrConstraint {
 			err := &errs.EntityAlreadyExistsError{Name: "proxy profile", Key: "name", Value: profile.Name}
 			r.logger.Debug().Err(err).Send()
@@ -89,16 +245,126 @@ func (r *ProxyProfileRepository) Update(ctx context.Context, profile model.Proxy
 		return errs.RepositoryUnknownError
 	}
 	if count == 0 {
-		err = &errs.EntityNotFoundError{Name: "proxy profile", Key: "id", Value: profile.ID}
-		r.logger.Print(err)
+		return versionConflict(ctx, tx, r.logger, "proxy_profiles", "proxy profile", profile.ID)
+	}
+
+	if err := r.setMembers(ctx, tx, profile.ID, profile.Members); err != nil {
 		return err
 	}
+
+	err = auditChange(ctx, tx, profileSnapshot, model.AuditProxyProfile, profile.ID, model.AuditUpdate, before)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while auditing updated proxy profile")
+		return errs.RepositoryUnknownError
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while committing transaction")
+		return errs.RepositoryUnknownError
+	}
+	return nil
+}
+
+// profilePatchColumns maps the fields of model.ProxyProfileFields that can be patched to the assignments
+// updating them.
+var profilePatchColumns = map[string]string{
+	"name":        "name = :name",
+	"type":        "type = :type",
+	"address":     "host = nullif(:host, ''), port = nullif(:port, 0), address = NULL",
+	"standby_id":  "standby_profile_id = nullif(:standby_id, 0)",
+	"description": "description = :description",
+	"owner":       "owner = coalesce(nullif(:owner, ''), owner)",
+}
+
+// Patch updates the given fields of the profile of the version given in the profile only, the fields are named
+// as in model.ProxyProfileFields.
+func (r *ProxyProfileRepository) Patch(ctx context.Context, profile model.ProxyProfile, fields []string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while starting transaction")
+		return errs.RepositoryUnknownError
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	before, err := snapshot(ctx, tx, profileSnapshot, profile.ID)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while getting proxy profile before patch")
+		return errs.RepositoryUnknownError
+	}
+
+	assignments := make([]string, 0, len(fields)+1)
+	for _, field := range model.ProxyProfileFields {
+		if column, ok := profilePatchColumns[field]; ok && contains(fields, field) {
+			assignments = append(assignments, column)
+		}
+	}
+	assignments = append(assignments, "updated_at = CURRENT_TIMESTAMP", "version = version + 1")
+	cmd := `UPDATE proxy_profiles SET ` + strings.Join(assignments, ", ") + ` WHERE id = :id AND ` + versionGuard
+
+	result, err := tx.NamedExecContext(ctx, cmd, profile)
+	if err != nil {
+		if s, ok := err.(sqlite3.Error); ok && isInvalidReference(s) {
+			r.logger.Debug().Err(err).Msg("Unknown standby profile or profile is standby for itself")
+			return errs.InvalidReferenceError
+		}
+		if s, ok := err.(sqlite3.Error); ok && s.Code == sqlite3.ErrConstraint {
+			err := &errs.EntityAlreadyExistsError{Name: "proxy profile", Key: "name", Value: profile.Name}
+			r.logger.Debug().Err(err).Send()
+			return err
+		}
+		r.logger.Error().Err(err).Msg("Error occurred while patching proxy profile")
+		return errs.RepositoryUnknownError
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while retrieving affected rows count after patching proxy profile")
+		return errs.RepositoryUnknownError
+	}
+	if count == 0 {
+		return versionConflict(ctx, tx, r.logger, "proxy_profiles", "proxy profile", profile.ID)
+	}
+
+	switch {
+	case contains(fields, "members"):
+		if err := r.setMembers(ctx, tx, profile.ID, profile.Members); err != nil {
+			return err
+		}
+	case contains(fields, "type") || contains(fields, "standby_id"):
+		if err := r.checkNestedPools(ctx, tx, profile.ID); err != nil {
+			return err
+		}
+	}
+
+	err = auditChange(ctx, tx, profileSnapshot, model.AuditProxyProfile, profile.ID, model.AuditUpdate, before)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while auditing patched proxy profile")
+		return errs.RepositoryUnknownError
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while committing transaction")
+		return errs.RepositoryUnknownError
+	}
 	return nil
 }
 
-func (r *ProxyProfileRepository) Delete(ctx context.Context, id int) error {
-	cmd := `DELETE FROM proxy_profiles WHERE id = ?`
-	result, err := r.db.ExecContext(ctx, cmd, id)
+// Delete deletes the profile of the version, zero version matches any.
+func (r *ProxyProfileRepository) Delete(ctx context.Context, id int, version int) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while starting transaction")
+		return errs.RepositoryUnknownError
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	before, err := snapshot(ctx, tx, profileSnapshot, id)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while getting proxy profile before delete")
+		return errs.RepositoryUnknownError
+	}
+
+	cmd := `DELETE FROM proxy_profiles WHERE id = :id AND ` + versionGuard
+	result, err := tx.NamedExecContext(ctx, cmd, map[string]any{"id": id, "version": version})
 	if err != nil {
 		if s, ok := err.(sqlite3.Error); ok && s.Code == sqlite3.ErrConstraint {
 			err := &errs.EntityStillReferencedError{Name: "proxy profile", Key: "id", Value: id}
@@ -114,9 +380,105 @@ func (r *ProxyProfileRepository) Delete(ctx context.Context, id int) error {
 		return errs.RepositoryUnknownError
 	}
 	if count == 0 {
-		err = &errs.EntityNotFoundError{Name: "proxy profile", Key: "id", Value: id}
-		r.logger.Print(err)
+		return versionConflict(ctx, tx, r.logger, "proxy_profiles", "proxy profile", id)
+	}
+
+	if err := auditChange(ctx, tx, profileSnapshot, model.AuditProxyProfile, id, model.AuditDelete, before); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while auditing deleted proxy profile")
+		return errs.RepositoryUnknownError
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while committing transaction")
+		return errs.RepositoryUnknownError
+	}
+	return nil
+}
+
+// setMembers replaces members of the pool and makes sure pools are neither members of other pools
+// nor standby profiles, as a host is routed through a single level of pools only.
+func (r *ProxyProfileRepository) setMembers(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	poolID int,
+	members []model.PoolMember,
+) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM profile_pool_members WHERE pool_id = ?`, poolID); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while deleting pool members")
+		return errs.RepositoryUnknownError
+	}
+	cmd := `INSERT INTO profile_pool_members (pool_id, member_id, weight) VALUES (?, ?, ?)`
+	for _, member := range members {
+		if _, err := tx.ExecContext(ctx, cmd, poolID, member.ProfileID, member.Weight); err != nil {
+			if s, ok := err.(sqlite3.Error); ok && isInvalidReference(s) {
+				r.logger.Debug().Err(err).Int("member-id", member.ProfileID).Msg("Unknown pool member")
+				return errs.InvalidReferenceError
+			}
+			r.logger.Error().Err(err).Msg("Error occurred while adding pool member")
+			return errs.RepositoryUnknownError
+		}
+	}
+	return r.checkNestedPools(ctx, tx, poolID)
+}
+
+// checkNestedPools makes sure pools are neither members of other pools nor standby profiles after the profile
+// was changed.
+func (r *ProxyProfileRepository) checkNestedPools(ctx context.Context, tx *sqlx.Tx, id int) error {
+	query := `SELECT count(*)
+			  FROM proxy_profiles
+			  WHERE type = ?
+				AND (id IN (SELECT member_id FROM profile_pool_members)
+				  OR id IN (SELECT standby_profile_id FROM proxy_profiles))`
+	var nested int
+	if err := tx.GetContext(ctx, &nested, query, model.Pool); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while checking nested pools")
+		return errs.RepositoryUnknownError
+	}
+	if nested > 0 {
+		r.logger.Debug().Int("profile-id", id).Msg("Pool is a member of another pool or a standby profile")
+		return errs.InvalidReferenceError
+	}
+	return nil
+}
+
+// isInvalidReference reports whether the error is caused by a reference to a missing or inappropriate entity.
+func isInvalidReference(err sqlite3.Error) bool {
+	return err.ExtendedCode == sqlite3.ErrConstraintForeignKey || err.ExtendedCode == sqlite3.ErrConstraintCheck
+}
+
+// loadPoolMembers loads the members of the pools among the profiles, the same pool may occur several times.
+func loadPoolMembers(ctx context.Context, db *sqlx.DB, profiles []*model.ProxyProfile) error {
+	pools := make(map[int][]*model.ProxyProfile)
+	ids := make([]int, 0)
+	for _, profile := range profiles {
+		if profile == nil || profile.Type != model.Pool {
+			continue
+		}
+		if _, ok := pools[profile.ID]; !ok {
+			ids = append(ids, profile.ID)
+		}
+		profile.Members = make([]model.PoolMember, 0)
+		pools[profile.ID] = append(pools[profile.ID], profile)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query, args, err := sqlx.In(
+		`SELECT pool_id, member_id, weight FROM profile_pool_members WHERE pool_id IN (?) ORDER BY pool_id, member_id`,
+		ids,
+	)
+	if err != nil {
 		return err
 	}
+	members := make([]poolMemberRow, 0)
+	if err := db.SelectContext(ctx, &members, db.Rebind(query), args...); err != nil {
+		return err
+	}
+	for _, member := range members {
+		for _, pool := range pools[member.PoolID] {
+			pool.Members = append(pool.Members, member.PoolMember)
+		}
+	}
 	return nil
 }
@@ -33,50 +33,120 @@ func TestProxyProfileRepository_GetAll_OK(t *testing.T) {
 	repo, mock := testPrepareProxyProfileRepository(t)
 
 	mock.
-		ExpectQuery(`SELECT id, name, type, address FROM proxy_profiles`).
+		ExpectQuery(`SELECT p.id, p.name, p.type, coalesce\(p.host, ''\) AS host, coalesce\(p.port, 0\) AS port, coalesce\(p.standby_profile_id, 0\) AS standby_id, p.description, p.owner, p.created_at, p.updated_at FROM proxy_profiles p ORDER BY p.id ASC$`).
 		WillReturnRows(
 			sqlmock.
-				NewRows([]string{"id", "name", "type", "address"}).
-				AddRow(10, "shadowsocks", model.Https, "127.0.0.1:1080").
-				AddRow(20, "simple socks", model.Socks5, "127.0.0.1:9999").
-				AddRow(123456789, "tor", model.Http, "localhost:9050").
-				AddRow(1111, "some name", model.Socks4, "::1:1080"),
+				NewRows([]string{"id", "name", "type", "host", "port"}).
+				AddRow(10, "shadowsocks", model.Https, "127.0.0.1", 1080).
+				AddRow(20, "simple socks", model.Socks5, "127.0.0.1", 9999).
+				AddRow(123456789, "tor", model.Http, "localhost", 9050).
+				AddRow(1111, "some name", model.Socks4, "::1", 1080).
+				AddRow(30, "egress", model.Pool, "", 0),
+		)
+	mock.
+		ExpectQuery(`SELECT pool_id, member_id, weight FROM profile_pool_members ORDER BY pool_id, member_id`).
+		WillReturnRows(
+			sqlmock.
+				NewRows([]string{"pool_id", "member_id", "weight"}).
+				AddRow(30, 10, 2).
+				AddRow(30, 20, 1),
 		)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	got, err := repo.GetAll(ctx)
+	got, err := repo.GetAll(ctx, model.ProxyProfileFilter{})
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	want := []model.ProxyProfile{
-		{ID: 10, Name: "shadowsocks", Type: model.Https, Address: "127.0.0.1:1080"},
-		{ID: 20, Name: "simple socks", Type: model.Socks5, Address: "127.0.0.1:9999"},
-		{ID: 123456789, Name: "tor", Type: model.Http, Address: "localhost:9050"},
-		{ID: 1111, Name: "some name", Type: model.Socks4, Address: "::1:1080"},
+		{ID: 10, Name: "shadowsocks", Type: model.Https, Host: "127.0.0.1", Port: 1080},
+		{ID: 20, Name: "simple socks", Type: model.Socks5, Host: "127.0.0.1", Port: 9999},
+		{ID: 123456789, Name: "tor", Type: model.Http, Host: "localhost", Port: 9050},
+		{ID: 1111, Name: "some name", Type: model.Socks4, Host: "::1", Port: 1080},
+		{
+			ID:      30,
+			Name:    "egress",
+			Type:    model.Pool,
+			Members: []model.PoolMember{{ProfileID: 10, Weight: 2}, {ProfileID: 20, Weight: 1}},
+		},
 	}
 
 	assert.Equal(t, got, want)
 }
 
+func TestProxyProfileRepository_GetAll_Projected(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareProxyProfileRepository(t)
+
+	mock.
+		ExpectQuery(`SELECT p.name FROM proxy_profiles p ORDER BY p.name ASC, p.id ASC LIMIT -1 OFFSET \?$`).
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("tor"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	got, err := repo.GetAll(ctx, model.ProxyProfileFilter{
+		Sort:   model.Sort{Field: "name"},
+		Page:   model.Page{Offset: 5},
+		Fields: []string{"name"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, got, []model.ProxyProfile{{Name: "tor"}})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProxyProfileRepository_GetAll_RuleCount(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareProxyProfileRepository(t)
+
+	mock.
+		ExpectQuery(`SELECT p.id, p.name, \(SELECT count\(\*\) FROM rules r WHERE r.proxy_profile_id = p.id\) ` +
+			`AS rule_count FROM proxy_profiles p`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "rule_count"}).AddRow(1, "tor", 4))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	got, err := repo.GetAll(ctx, model.ProxyProfileFilter{Fields: []string{"id", "name"}, WithRuleCount: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, got, []model.ProxyProfile{{ID: 1, Name: "tor", RuleCount: 4}})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestProxyProfileRepository_GetByID_OK(t *testing.T) {
 	t.Parallel()
 
 	repo, mock := testPrepareProxyProfileRepository(t)
 
 	mock.
-		ExpectQuery(`SELECT id, name, type, address FROM proxy_profiles WHERE id = \?`).
+		ExpectQuery(`SELECT id, name, type, coalesce\(host, ''\) AS host, coalesce\(port, 0\) AS port, coalesce\(standby_profile_id, 0\) AS standby_id, description, owner, created_at, updated_at, version, \(SELECT count\(\*\) FROM rules r WHERE r.proxy_profile_id = proxy_profiles.id\) AS rule_count FROM proxy_profiles WHERE id = \?`).
 		WithArgs(10).
 		WillReturnRows(
 			sqlmock.
-				NewRows([]string{"id", "name", "type", "address"}).
+				NewRows([]string{"id", "name", "type", "host", "port"}).
 				AddRow(
 					driver.Value(10),
 					driver.Value("shadowsocks"),
 					driver.Value(model.Https),
-					driver.Value("127.0.0.1:1081"),
+					driver.Value("127.0.0.1"),
+					driver.Value(1081),
 				),
 		)
 
@@ -88,7 +158,44 @@ func TestProxyProfileRepository_GetByID_OK(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	want := model.ProxyProfile{ID: 10, Name: "shadowsocks", Type: model.Https, Address: "127.0.0.1:1081"}
+	want := model.ProxyProfile{ID: 10, Name: "shadowsocks", Type: model.Https, Host: "127.0.0.1", Port: 1081}
+
+	assert.Equal(t, got, want)
+}
+
+func TestProxyProfileRepository_GetByID_Pool_OK(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareProxyProfileRepository(t)
+
+	mock.
+		ExpectQuery(`SELECT id, name, type, coalesce\(host, ''\) AS host, coalesce\(port, 0\) AS port, coalesce\(standby_profile_id, 0\) AS standby_id, description, owner, created_at, updated_at, version, \(SELECT count\(\*\) FROM rules r WHERE r.proxy_profile_id = proxy_profiles.id\) AS rule_count FROM proxy_profiles WHERE id = \?`).
+		WithArgs(30).
+		WillReturnRows(
+			sqlmock.
+				NewRows([]string{"id", "name", "type", "host", "port", "standby_id"}).
+				AddRow(30, "egress", model.Pool, "", 0, 40),
+		)
+	mock.
+		ExpectQuery(`SELECT member_id, weight FROM profile_pool_members WHERE pool_id = \? ORDER BY member_id`).
+		WithArgs(30).
+		WillReturnRows(sqlmock.NewRows([]string{"member_id", "weight"}).AddRow(10, 3))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	got, err := repo.GetByID(ctx, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := model.ProxyProfile{
+		ID:        30,
+		Name:      "egress",
+		Type:      model.Pool,
+		StandbyID: 40,
+		Members:   []model.PoolMember{{ProfileID: 10, Weight: 3}},
+	}
 
 	assert.Equal(t, got, want)
 }
@@ -99,7 +206,7 @@ func TestProxyProfileRepository_GetByID_NotFound(t *testing.T) {
 	repo, mock := testPrepareProxyProfileRepository(t)
 
 	mock.
-		ExpectQuery(`SELECT id, name, type, address FROM proxy_profiles WHERE id = \?`).
+		ExpectQuery(`SELECT id, name, type, coalesce\(host, ''\) AS host, coalesce\(port, 0\) AS port, coalesce\(standby_profile_id, 0\) AS standby_id, description, owner, created_at, updated_at, version, \(SELECT count\(\*\) FROM rules r WHERE r.proxy_profile_id = proxy_profiles.id\) AS rule_count FROM proxy_profiles WHERE id = \?`).
 		WithArgs(0).
 		WillReturnError(sql.ErrNoRows)
 
@@ -120,15 +227,27 @@ func TestProxyProfileRepository_Create_OK(t *testing.T) {
 
 	const insertedID = 15
 
+	mock.ExpectBegin()
 	mock.
-		ExpectExec(`INSERT INTO proxy_profiles \(name, type, address\) VALUES \(\?, \?, \?\)`).
-		WithArgs("some name", model.Http, "::1:1080").
+		ExpectExec(`INSERT INTO proxy_profiles \(name, type, host, port, standby_profile_id, description, owner, created_at, updated_at\) VALUES \(\?, \?, nullif\(\?, ''\), nullif\(\?, 0\), nullif\(\?, 0\), \?, \?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP\)`).
+		WithArgs("some name", model.Http, "::1", 1080, 0, "", "").
 		WillReturnResult(sqlmock.NewResult(insertedID, 1))
+	mock.
+		ExpectExec(`DELETE FROM profile_pool_members WHERE pool_id = \?`).
+		WithArgs(insertedID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.
+		ExpectQuery(`SELECT count\(\*\) FROM proxy_profiles WHERE type = \?`).
+		WithArgs(model.Pool).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	expectSnapshot(mock, `proxy_profiles p WHERE p.id = \?`, `{"id":1}`, insertedID)
+	expectAudit(mock, model.AuditProxyProfile, insertedID, model.AuditCreate)
+	mock.ExpectCommit()
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	profile := model.ProxyProfile{Name: "some name", Type: model.Http, Address: "::1:1080"}
+	profile := model.ProxyProfile{Name: "some name", Type: model.Http, Host: "::1", Port: 1080}
 	err := repo.Create(ctx, &profile)
 
 	if err != nil {
@@ -143,15 +262,17 @@ func TestProxyProfileRepository_Create_AlreadyExists(t *testing.T) {
 
 	repo, mock := testPrepareProxyProfileRepository(t)
 
+	mock.ExpectBegin()
 	mock.
-		ExpectExec(`INSERT INTO proxy_profiles \(name, type, address\) VALUES \(\?, \?, \?\)`).
-		WithArgs("some socks", model.Socks5, "1.1.1.1:1080").
+		ExpectExec(`INSERT INTO proxy_profiles \(name, type, host, port, standby_profile_id, description, owner, created_at, updated_at\) VALUES \(\?, \?, nullif\(\?, ''\), nullif\(\?, 0\), nullif\(\?, 0\), \?, \?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP\)`).
+		WithArgs("some socks", model.Socks5, "1.1.1.1", 1080, 0, "", "").
 		WillReturnError(sqlite3.Error{Code: sqlite3.ErrConstraint})
+	mock.ExpectRollback()
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	profile := model.ProxyProfile{Name: "some socks", Type: model.Socks5, Address: "1.1.1.1:1080"}
+	profile := model.ProxyProfile{Name: "some socks", Type: model.Socks5, Host: "1.1.1.1", Port: 1080}
 	err := repo.Create(ctx, &profile)
 
 	if _, ok := err.(*errs.EntityAlreadyExistsError); !ok {
@@ -159,20 +280,175 @@ func TestProxyProfileRepository_Create_AlreadyExists(t *testing.T) {
 	}
 }
 
+func TestProxyProfileRepository_Create_InvalidReference(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareProxyProfileRepository(t)
+
+	mock.ExpectBegin()
+	mock.
+		ExpectExec(`INSERT INTO proxy_profiles \(name, type, host, port, standby_profile_id, description, owner, created_at, updated_at\) VALUES \(\?, \?, nullif\(\?, ''\), nullif\(\?, 0\), nullif\(\?, 0\), \?, \?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP\)`).
+		WithArgs("some socks", model.Socks5, "1.1.1.1", 1080, 42, "", "").
+		WillReturnError(sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintForeignKey})
+	mock.ExpectRollback()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	profile := model.ProxyProfile{Name: "some socks", Type: model.Socks5, Host: "1.1.1.1", Port: 1080, StandbyID: 42}
+	err := repo.Create(ctx, &profile)
+
+	if err != errs.InvalidReferenceError {
+		t.Fatal("expected error errs.InvalidReferenceError")
+	}
+}
+
+func TestProxyProfileRepository_Create_Pool_OK(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareProxyProfileRepository(t)
+
+	const insertedID = 30
+
+	mock.ExpectBegin()
+	mock.
+		ExpectExec(`INSERT INTO proxy_profiles \(name, type, host, port, standby_profile_id, description, owner, created_at, updated_at\) VALUES \(\?, \?, nullif\(\?, ''\), nullif\(\?, 0\), nullif\(\?, 0\), \?, \?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP\)`).
+		WithArgs("egress", model.Pool, "", 0, 0, "", "").
+		WillReturnResult(sqlmock.NewResult(insertedID, 1))
+	mock.
+		ExpectExec(`DELETE FROM profile_pool_members WHERE pool_id = \?`).
+		WithArgs(insertedID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.
+		ExpectExec(`INSERT INTO profile_pool_members \(pool_id, member_id, weight\) VALUES \(\?, \?, \?\)`).
+		WithArgs(insertedID, 10, 2).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.
+		ExpectExec(`INSERT INTO profile_pool_members \(pool_id, member_id, weight\) VALUES \(\?, \?, \?\)`).
+		WithArgs(insertedID, 20, 1).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.
+		ExpectQuery(`SELECT count\(\*\) FROM proxy_profiles WHERE type = \?`).
+		WithArgs(model.Pool).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	expectSnapshot(mock, `proxy_profiles p WHERE p.id = \?`, `{"id":3}`, insertedID)
+	expectAudit(mock, model.AuditProxyProfile, insertedID, model.AuditCreate)
+	mock.ExpectCommit()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	profile := model.ProxyProfile{
+		Name:    "egress",
+		Type:    model.Pool,
+		Members: []model.PoolMember{{ProfileID: 10, Weight: 2}, {ProfileID: 20, Weight: 1}},
+	}
+	err := repo.Create(ctx, &profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, profile.ID, insertedID)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProxyProfileRepository_Create_Pool_UnknownMember(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareProxyProfileRepository(t)
+
+	mock.ExpectBegin()
+	mock.
+		ExpectExec(`INSERT INTO proxy_profiles \(name, type, host, port, standby_profile_id, description, owner, created_at, updated_at\) VALUES \(\?, \?, nullif\(\?, ''\), nullif\(\?, 0\), nullif\(\?, 0\), \?, \?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP\)`).
+		WithArgs("egress", model.Pool, "", 0, 0, "", "").
+		WillReturnResult(sqlmock.NewResult(30, 1))
+	mock.
+		ExpectExec(`DELETE FROM profile_pool_members WHERE pool_id = \?`).
+		WithArgs(30).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.
+		ExpectExec(`INSERT INTO profile_pool_members \(pool_id, member_id, weight\) VALUES \(\?, \?, \?\)`).
+		WithArgs(30, 99, 1).
+		WillReturnError(sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintForeignKey})
+	mock.ExpectRollback()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	profile := model.ProxyProfile{Name: "egress", Type: model.Pool, Members: []model.PoolMember{{ProfileID: 99, Weight: 1}}}
+	err := repo.Create(ctx, &profile)
+
+	if err != errs.InvalidReferenceError {
+		t.Fatal("expected error errs.InvalidReferenceError")
+	}
+}
+
+func TestProxyProfileRepository_Update_NestedPool(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareProxyProfileRepository(t)
+
+	mock.ExpectBegin()
+	expectSnapshot(mock, `proxy_profiles p WHERE p.id = \?`, `{"id":31}`, 31)
+	mock.
+		ExpectExec(`UPDATE proxy_profiles SET name = \?, type = \?, host = nullif\(\?, ''\), port = nullif\(\?, 0\), address = NULL, standby_profile_id = nullif\(\?, 0\), description = \?, owner = coalesce\(nullif\(\?, ''\), owner\), updated_at = CURRENT_TIMESTAMP, version = version \+ 1 WHERE id = \? AND \? IN \(0, version\)`).
+		WithArgs("inner", model.Pool, "", 0, 0, "", "", 31, 0).
+		WillReturnResult(sqlmock.NewResult(31, 1))
+	mock.
+		ExpectExec(`DELETE FROM profile_pool_members WHERE pool_id = \?`).
+		WithArgs(31).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.
+		ExpectExec(`INSERT INTO profile_pool_members \(pool_id, member_id, weight\) VALUES \(\?, \?, \?\)`).
+		WithArgs(31, 10, 1).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.
+		ExpectQuery(`SELECT count\(\*\) FROM proxy_profiles WHERE type = \?`).
+		WithArgs(model.Pool).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectRollback()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	profile := model.ProxyProfile{ID: 31, Name: "inner", Type: model.Pool, Members: []model.PoolMember{{ProfileID: 10, Weight: 1}}}
+	err := repo.Update(ctx, profile)
+
+	if err != errs.InvalidReferenceError {
+		t.Fatal("expected error errs.InvalidReferenceError")
+	}
+}
+
 func TestProxyProfileRepository_Update_OK(t *testing.T) {
 	t.Parallel()
 
 	repo, mock := testPrepareProxyProfileRepository(t)
 
+	mock.ExpectBegin()
+	expectSnapshot(mock, `proxy_profiles p WHERE p.id = \?`, `{"id":10}`, 10)
 	mock.
-		ExpectExec(`UPDATE proxy_profiles SET name = \?, type = \?, address = \? WHERE id = \?`).
-		WithArgs("some name", model.Https, "127.0.0.1:1080", 10).
+		ExpectExec(`UPDATE proxy_profiles SET name = \?, type = \?, host = nullif\(\?, ''\), port = nullif\(\?, 0\), address = NULL, standby_profile_id = nullif\(\?, 0\), description = \?, owner = coalesce\(nullif\(\?, ''\), owner\), updated_at = CURRENT_TIMESTAMP, version = version \+ 1 WHERE id = \? AND \? IN \(0, version\)`).
+		WithArgs("some name", model.Https, "127.0.0.1", 1080, 0, "", "", 10, 0).
 		WillReturnResult(sqlmock.NewResult(10, 1))
+	mock.
+		ExpectExec(`DELETE FROM profile_pool_members WHERE pool_id = \?`).
+		WithArgs(10).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.
+		ExpectQuery(`SELECT count\(\*\) FROM proxy_profiles WHERE type = \?`).
+		WithArgs(model.Pool).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	expectSnapshot(mock, `proxy_profiles p WHERE p.id = \?`, `{"id":10}`, 10)
+	expectAudit(mock, model.AuditProxyProfile, 10, model.AuditUpdate)
+	mock.ExpectCommit()
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	profile := model.ProxyProfile{ID: 10, Name: "some name", Type: model.Https, Address: "127.0.0.1:1080"}
+	profile := model.ProxyProfile{ID: 10, Name: "some name", Type: model.Https, Host: "127.0.0.1", Port: 1080}
 	err := repo.Update(ctx, profile)
 
 	if err != nil {
@@ -180,20 +456,92 @@ func TestProxyProfileRepository_Update_OK(t *testing.T) {
 	}
 }
 
+func TestProxyProfileRepository_Patch_OK(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareProxyProfileRepository(t)
+
+	mock.ExpectBegin()
+	expectSnapshot(mock, `proxy_profiles p WHERE p.id = \?`, `{"id":10}`, 10)
+	mock.
+		ExpectExec(`UPDATE proxy_profiles SET name = \?, description = \?, updated_at = CURRENT_TIMESTAMP, version = version \+ 1 WHERE id = \? AND \? IN \(0, version\)$`).
+		WithArgs("renamed", "edge", 10, 0).
+		WillReturnResult(sqlmock.NewResult(10, 1))
+	expectSnapshot(mock, `proxy_profiles p WHERE p.id = \?`, `{"id":10}`, 10)
+	expectAudit(mock, model.AuditProxyProfile, 10, model.AuditUpdate)
+	mock.ExpectCommit()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	profile := model.ProxyProfile{
+		ID:       10,
+		Name:     "renamed",
+		Type:     model.Https,
+		Host:     "127.0.0.1",
+		Port:     1080,
+		Metadata: model.Metadata{Description: "edge"},
+	}
+	err := repo.Patch(ctx, profile, []string{"description", "name"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProxyProfileRepository_Patch_NestedPool(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareProxyProfileRepository(t)
+
+	mock.ExpectBegin()
+	expectSnapshot(mock, `proxy_profiles p WHERE p.id = \?`, `{"id":10}`, 10)
+	mock.
+		ExpectExec(`UPDATE proxy_profiles SET standby_profile_id = nullif\(\?, 0\), updated_at = CURRENT_TIMESTAMP, version = version \+ 1 WHERE id = \? AND \? IN \(0, version\)$`).
+		WithArgs(31, 10, 0).
+		WillReturnResult(sqlmock.NewResult(10, 1))
+	mock.
+		ExpectQuery(`SELECT count\(\*\) FROM proxy_profiles WHERE type = \?`).
+		WithArgs(model.Pool).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectRollback()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	profile := model.ProxyProfile{ID: 10, Name: "some name", Type: model.Https, Host: "127.0.0.1", Port: 1080, StandbyID: 31}
+	err := repo.Patch(ctx, profile, []string{"standby_id"})
+
+	if err != errs.InvalidReferenceError {
+		t.Fatal("expected error errs.InvalidReferenceError")
+	}
+}
+
 func TestProxyProfileRepository_Update_NotFound(t *testing.T) {
 	t.Parallel()
 
 	repo, mock := testPrepareProxyProfileRepository(t)
 
+	mock.ExpectBegin()
+	expectSnapshot(mock, `proxy_profiles p WHERE p.id = \?`, "", 10)
 	mock.
-		ExpectExec(`UPDATE proxy_profiles SET name = \?, type = \?, address = \? WHERE id = \?`).
-		WithArgs("some name", model.Https, "127.0.0.1:1080", 10).
+		ExpectExec(`UPDATE proxy_profiles SET name = \?, type = \?, host = nullif\(\?, ''\), port = nullif\(\?, 0\), address = NULL, standby_profile_id = nullif\(\?, 0\), description = \?, owner = coalesce\(nullif\(\?, ''\), owner\), updated_at = CURRENT_TIMESTAMP, version = version \+ 1 WHERE id = \? AND \? IN \(0, version\)`).
+		WithArgs("some name", model.Https, "127.0.0.1", 1080, 0, "", "", 10, 0).
 		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.
+		ExpectQuery(`SELECT version FROM proxy_profiles WHERE id = \?`).
+		WithArgs(10).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	profile := model.ProxyProfile{ID: 10, Name: "some name", Type: model.Https, Address: "127.0.0.1:1080"}
+	profile := model.ProxyProfile{ID: 10, Name: "some name", Type: model.Https, Host: "127.0.0.1", Port: 1080}
 	err := repo.Update(ctx, profile)
 
 	if _, ok := err.(*errs.EntityNotFoundError); !ok {
@@ -206,15 +554,18 @@ func TestProxyProfileRepository_Update_AlreadyExists(t *testing.T) {
 
 	repo, mock := testPrepareProxyProfileRepository(t)
 
+	mock.ExpectBegin()
+	expectSnapshot(mock, `proxy_profiles p WHERE p.id = \?`, `{"id":10}`, 10)
 	mock.
-		ExpectExec(`UPDATE proxy_profiles SET name = \?, type = \?, address = \? WHERE id = \?`).
-		WithArgs("some socks", model.Socks5, "localhost:1080", 10).
+		ExpectExec(`UPDATE proxy_profiles SET name = \?, type = \?, host = nullif\(\?, ''\), port = nullif\(\?, 0\), address = NULL, standby_profile_id = nullif\(\?, 0\), description = \?, owner = coalesce\(nullif\(\?, ''\), owner\), updated_at = CURRENT_TIMESTAMP, version = version \+ 1 WHERE id = \? AND \? IN \(0, version\)`).
+		WithArgs("some socks", model.Socks5, "localhost", 1080, 0, "", "", 10, 0).
 		WillReturnError(sqlite3.Error{Code: sqlite3.ErrConstraint})
+	mock.ExpectRollback()
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	profile := model.ProxyProfile{ID: 10, Name: "some socks", Type: model.Socks5, Address: "localhost:1080"}
+	profile := model.ProxyProfile{ID: 10, Name: "some socks", Type: model.Socks5, Host: "localhost", Port: 1080}
 	err := repo.Update(ctx, profile)
 
 	if _, ok := err.(*errs.EntityAlreadyExistsError); !ok {
@@ -227,15 +578,19 @@ func TestProxyProfileRepository_Delete_OK(t *testing.T) {
 
 	repo, mock := testPrepareProxyProfileRepository(t)
 
+	mock.ExpectBegin()
+	expectSnapshot(mock, `proxy_profiles p WHERE p.id = \?`, `{"id":10}`, 10)
 	mock.
-		ExpectExec(`DELETE FROM proxy_profiles WHERE id = \?`).
-		WithArgs(10).
+		ExpectExec(`DELETE FROM proxy_profiles WHERE id = \? AND \? IN \(0, version\)$`).
+		WithArgs(10, 0).
 		WillReturnResult(sqlmock.NewResult(10, 1))
+	expectAudit(mock, model.AuditProxyProfile, 10, model.AuditDelete)
+	mock.ExpectCommit()
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	err := repo.Delete(ctx, 10)
+	err := repo.Delete(ctx, 10, 0)
 
 	if err != nil {
 		t.Fatal(err)
@@ -247,15 +602,21 @@ func TestProxyProfileRepository_Delete_NotFound(t *testing.T) {
 
 	repo, mock := testPrepareProxyProfileRepository(t)
 
+	mock.ExpectBegin()
+	expectSnapshot(mock, `proxy_profiles p WHERE p.id = \?`, "", 10)
 	mock.
-		ExpectExec(`DELETE FROM proxy_profiles WHERE id = \?`).
-		WithArgs(10).
+		ExpectExec(`DELETE FROM proxy_profiles WHERE id = \? AND \? IN \(0, version\)$`).
+		WithArgs(10, 0).
 		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.
+		ExpectQuery(`SELECT version FROM proxy_profiles WHERE id = \?`).
+		WithArgs(10).
+		WillReturnError(sql.ErrNoRows)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	err := repo.Delete(ctx, 10)
+	err := repo.Delete(ctx, 10, 0)
 
 	if _, ok := err.(*errs.EntityNotFoundError); !ok {
 		t.Fatal("expected error errs.EntityNotFoundError")
@@ -267,15 +628,17 @@ func TestProxyProfileRepository_Delete_StillReferenced(t *testing.T) {
 
 	repo, mock := testPrepareProxyProfileRepository(t)
 
+	mock.ExpectBegin()
+	expectSnapshot(mock, `proxy_profiles p WHERE p.id = \?`, `{"id":10}`, 10)
 	mock.
-		ExpectExec(`DELETE FROM proxy_profiles WHERE id = \?`).
-		WithArgs(10).
+		ExpectExec(`DELETE FROM proxy_profiles WHERE id = \? AND \? IN \(0, version\)$`).
+		WithArgs(10, 0).
 		WillReturnError(sqlite3.Error{Code: sqlite3.ErrConstraint})
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	err := repo.Delete(ctx, 10)
+	err := repo.Delete(ctx, 10, 0)
 
 	if _, ok := err.(*errs.EntityStillReferencedError); !ok {
 		t.Fatal("expected error errs.EntityStillReferencedError")
@@ -4,13 +4,48 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"github.com/jmoiron/sqlx"
 	"github.com/mattn/go-sqlite3"
 	"github.com/nnemirovsky/pacgen/internal/errs"
 	"github.com/nnemirovsky/pacgen/internal/model"
 	"github.com/rs/zerolog"
+	"strings"
+	"time"
 )
 
+// tagsColumn aggregates names of the rule tags into a comma separated list.
+const tagsColumn = `(SELECT group_concat(t.name)
+					 FROM rule_tags rt
+					 JOIN tags t ON rt.tag_id = t.id
+					 WHERE rt.rule_id = r.id) AS tags`
+
+// ruleMetadataColumns selects metadata of the rule.
+const ruleMetadataColumns = `r.description, r.owner, r.created_at, r.updated_at, r.version`
+
+// ruleProfileColumns selects the proxy profile of the rule joined as p, except for its id and the members of pools.
+const ruleProfileColumns = `p.name AS "proxy_profile.name",
+					 p.type AS "proxy_profile.type",
+					 coalesce(p.host, '') AS "proxy_profile.host",
+					 coalesce(p.port, 0) AS "proxy_profile.port",
+					 coalesce(p.standby_profile_id, 0) AS "proxy_profile.standby_id",
+					 p.description AS "proxy_profile.description",
+					 p.owner AS "proxy_profile.owner",
+					 p.created_at AS "proxy_profile.created_at",
+					 p.updated_at AS "proxy_profile.updated_at",
+					 p.version AS "proxy_profile.version"`
+
+// ruleScheduleColumns selects the time bounds of the rule.
+const ruleScheduleColumns = `r.active_from, r.expires_at`
+
+// activeRules matches enabled rules in effect now.
+const activeRules = `r.enabled
+			   AND (r.active_from IS NULL OR r.active_from <= CURRENT_TIMESTAMP)
+			   AND (r.expires_at IS NULL OR r.expires_at > CURRENT_TIMESTAMP)`
+
+// taggedRules selects ids of the rules with the tag.
+const taggedRules = `SELECT rt.rule_id FROM rule_tags rt JOIN tags t ON rt.tag_id = t.id WHERE t.name = ?`
+
 type RuleRepository struct {
 	logger zerolog.Logger
 	db     *sqlx.DB
@@ -23,41 +58,163 @@ func NewRuleRepository(db *sqlx.DB, logger zerolog.Logger) *RuleRepository {
 	}
 }
 
-func (r *RuleRepository) GetAll(ctx context.Context) ([]model.Rule, error) {
-	query := `SELECT id, regex, proxy_profile_id AS "proxy_profile.id" FROM rules`
+// ruleColumns are the columns selected for the fields of model.RuleFields.
+var ruleColumns = []fieldColumns{
+	{"id", `r.id`},
+	{"regex", `coalesce(r.regex, '') AS regex`},
+	{"domain_list_id", `coalesce(r.domain_list_id, 0) AS domain_list_id`},
+	{"enabled", `r.enabled`},
+	{"proxy_profile_id", `r.proxy_profile_id AS "proxy_profile.id"`},
+	{"tags", tagsColumn},
+	{"active_from", `r.active_from`},
+	{"expires_at", `r.expires_at`},
+	{"description", `r.description`},
+	{"owner", `r.owner`},
+	{"created_at", `r.created_at`},
+	{"updated_at", `r.updated_at`},
+}
+
+// GetAll returns the page of the rules matching the filter. Only the fields of the filter are loaded if it has any,
+// the proxy profiles are joined if the filter expands them.
+func (r *RuleRepository) GetAll(ctx context.Context, filter model.RuleFilter) ([]model.Rule, error) {
+	conds, args := ruleConditions(filter)
+	limitClause, limitArgs := limit(filter.Page)
+	fields, from := filter.Fields, ` FROM rules r`
+	if filter.ExpandProfile {
+		if len(fields) != 0 && !contains(fields, "proxy_profile_id") {
+			fields = append(fields[:len(fields):len(fields)], "proxy_profile_id")
+		}
+		from = `, ` + ruleProfileColumns + ` FROM rules r JOIN proxy_profiles p ON r.proxy_profile_id = p.id`
+	}
+	query := `SELECT ` + selectColumns(ruleColumns, fields) + from +
+		where(conds) + orderBy("r", filter.Sort) + limitClause
+	args = append(args, limitArgs...)
+
 	rules := make([]model.Rule, 0)
-	if err := r.db.SelectContext(ctx, &rules, query); err != nil {
+	if err := r.db.SelectContext(ctx, &rules, query, args...); err != nil {
 		r.logger.Error().Err(err).Msg("Error occurred while getting rules")
 		return nil, errs.RepositoryUnknownError
 	}
+	if !filter.ExpandProfile {
+		return rules, nil
+	}
+
+	profiles := make([]*model.ProxyProfile, 0, len(rules))
+	for i := range rules {
+		profiles = append(profiles, rules[i].ProxyProfile)
+	}
+	if err := loadPoolMembers(ctx, r.db, profiles); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while getting pool members of rule profiles")
+		return nil, errs.RepositoryUnknownError
+	}
 	return rules, nil
 }
 
+// Count returns the number of the rules matching the filter regardless of its page.
+func (r *RuleRepository) Count(ctx context.Context, filter model.RuleFilter) (int, error) {
+	conds, args := ruleConditions(filter)
+	query := `SELECT count(*) FROM rules r` + where(conds)
+
+	var count int
+	if err := r.db.GetContext(ctx, &count, query, args...); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while counting rules")
+		return 0, errs.RepositoryUnknownError
+	}
+	return count, nil
+}
+
+// ruleConditions returns conditions on the rules matching the filter along with their arguments.
+func ruleConditions(filter model.RuleFilter) (conds []string, args []any) {
+	if filter.Tag != "" {
+		conds = append(conds, `r.id IN (`+taggedRules+`)`)
+		args = append(args, filter.Tag)
+	}
+	if filter.ProxyProfileID != 0 {
+		conds = append(conds, `r.proxy_profile_id = ?`)
+		args = append(args, filter.ProxyProfileID)
+	}
+	switch filter.Mode {
+	case "domain":
+		conds = append(conds, `substr(r.regex, 1, 1) = '^'`)
+	case "domain_and_subdomains":
+		conds = append(conds, `substr(r.regex, 1, 8) = '(?:^|\.)'`)
+	case "domain_list":
+		conds = append(conds, `r.domain_list_id IS NOT NULL`)
+	}
+	if filter.Domain != "" {
+		conds = append(conds, `(instr(`+fmt.Sprintf(domainColumn, "r")+`, ?) > 0 OR r.domain_list_id IN
+									(SELECT list_id FROM domain_list_entries WHERE instr(domain, ?) > 0))`)
+		args = append(args, strings.ToLower(filter.Domain), strings.ToLower(filter.Domain))
+	}
+	metaConds, metaArgs := metadataConditions("r", filter.MetadataFilter)
+	return append(conds, metaConds...), append(args, metaArgs...)
+}
+
+// GetAllWithProfiles returns enabled rules in effect now along with their proxy profiles and domain lists. Rules are
+// ordered by their ids, the order they are evaluated in, whichever index the query is answered with.
 func (r *RuleRepository) GetAllWithProfiles(ctx context.Context) ([]model.Rule, error) {
 	query := `SELECT r.id,
-    				 r.regex,
+    				 coalesce(r.regex, '') AS regex,
+    				 coalesce(r.domain_list_id, 0) AS domain_list_id,
+    				 r.enabled,
     				 p.id AS "proxy_profile.id",
     				 p.name AS "proxy_profile.name",
     				 p.type AS "proxy_profile.type",
-    				 p.address AS "proxy_profile.address"
+    				 coalesce(p.host, '') AS "proxy_profile.host",
+    				 coalesce(p.port, 0) AS "proxy_profile.port"
 			  FROM rules r
-			  JOIN proxy_profiles p ON r.proxy_profile_id = p.id`
+			  JOIN proxy_profiles p ON r.proxy_profile_id = p.id
+			  WHERE ` + activeRules + `
+			  ORDER BY r.id`
 
 	rules := make([]model.Rule, 0)
 	if err := r.db.SelectContext(ctx, &rules, query); err != nil {
 		r.logger.Error().Err(err).Msg("Error occurred while getting rules")
 		return nil, errs.RepositoryUnknownError
 	}
+
+	lists := make(map[int]*model.DomainList)
+	for i := range rules {
+		if id := rules[i].DomainListID; id != 0 {
+			if lists[id] == nil {
+				lists[id] = &model.DomainList{ID: id, Entries: make([]model.DomainListEntry, 0)}
+			}
+			rules[i].DomainList = lists[id]
+		}
+	}
+	if len(lists) == 0 {
+		return rules, nil
+	}
+
+	query = `SELECT list_id, domain, mode
+			 FROM domain_list_entries
+			 WHERE list_id IN (SELECT domain_list_id FROM rules WHERE enabled)
+			 ORDER BY list_id, domain`
+
+	entries := make([]domainListEntryRow, 0)
+	if err := r.db.SelectContext(ctx, &entries, query); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while getting domain list entries of rules")
+		return nil, errs.RepositoryUnknownError
+	}
+	for _, entry := range entries {
+		// Rules could have been changed in between the queries.
+		if list, ok := lists[entry.ListID]; ok {
+			list.Entries = append(list.Entries, entry.DomainListEntry)
+		}
+	}
 	return rules, nil
 }
 
 func (r *RuleRepository) GetByID(ctx context.Context, id int) (model.Rule, error) {
 	query := `SELECT r.id,
-					 r.regex,
+					 coalesce(r.regex, '') AS regex,
+					 coalesce(r.domain_list_id, 0) AS domain_list_id,
+					 r.enabled,
 					 p.id AS "proxy_profile.id",
-					 p.name AS "proxy_profile.name",
-					 p.type AS "proxy_profile.type",
-					 p.address AS "proxy_profile.address"
+					 ` + ruleProfileColumns + `,
+					 ` + tagsColumn + `,
+					 ` + ruleScheduleColumns + `,
+					 ` + ruleMetadataColumns + `
 			  FROM rules r
 			  JOIN proxy_profiles p ON r.proxy_profile_id = p.id
 			  WHERE r.id = ?`
@@ -74,16 +231,80 @@ func (r *RuleRepository) GetByID(ctx context.Context, id int) (model.Rule, error
 		}
 		return model.Rule{}, err
 	}
+
+	if err := loadPoolMembers(ctx, r.db, []*model.ProxyProfile{rule.ProxyProfile}); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while getting pool members of rule profile")
+		return model.Rule{}, errs.RepositoryUnknownError
+	}
 	return rule, nil
 }
 
+// GetExpiring returns enabled rules which are going to expire by the time, ordered by expiration time.
+func (r *RuleRepository) GetExpiring(ctx context.Context, until time.Time) ([]model.Rule, error) {
+	query := `SELECT r.id,
+					 coalesce(r.regex, '') AS regex,
+					 coalesce(r.domain_list_id, 0) AS domain_list_id,
+					 r.enabled,
+					 r.proxy_profile_id AS "proxy_profile.id",
+					 ` + tagsColumn + `,
+					 ` + ruleScheduleColumns + `,
+					 ` + ruleMetadataColumns + `
+			  FROM rules r
+			  WHERE r.enabled AND r.expires_at > CURRENT_TIMESTAMP AND r.expires_at <= ?
+			  ORDER BY r.expires_at, r.id`
+
+	rules := make([]model.Rule, 0)
+	if err := r.db.SelectContext(ctx, &rules, query, until.UTC().Format(timestampLayout)); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while getting expiring rules")
+		return nil, errs.RepositoryUnknownError
+	}
+	return rules, nil
+}
+
+// NextBoundary returns the earliest time after the given one at which some enabled rule becomes active
+// or expires. Zero time is returned if there is none.
+func (r *RuleRepository) NextBoundary(ctx context.Context, after time.Time) (time.Time, error) {
+	query := `SELECT min(boundary)
+			  FROM (SELECT active_from AS boundary FROM rules WHERE enabled AND active_from > ?
+					UNION ALL
+					SELECT expires_at FROM rules WHERE enabled AND expires_at > ?)`
+
+	bound := after.UTC().Format(timestampLayout)
+	var boundary sql.NullString
+	if err := r.db.GetContext(ctx, &boundary, query, bound, bound); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while getting next rule boundary")
+		return time.Time{}, errs.RepositoryUnknownError
+	}
+	if !boundary.Valid {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(timestampLayout, boundary.String)
+	if err != nil {
+		r.logger.Error().Err(err).Str("boundary", boundary.String).Msg("Error occurred while parsing rule boundary")
+		return time.Time{}, errs.RepositoryUnknownError
+	}
+	return t, nil
+}
+
 func (r *RuleRepository) Create(ctx context.Context, rule *model.Rule) error {
-	cmd := `INSERT INTO rules (regex, proxy_profile_id) VALUES (:regex, :proxy_profile.id) RETURNING id`
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while starting transaction")
+		return errs.RepositoryUnknownError
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	cmd := `INSERT INTO rules (regex, domain_list_id, proxy_profile_id, enabled, active_from, expires_at, description, owner,
+							   created_at, updated_at)
+			VALUES (nullif(:regex, ''), nullif(:domain_list_id, 0), :proxy_profile.id, :enabled, datetime(:active_from),
+					datetime(:expires_at), :description, :owner, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+			RETURNING id`
 
-	result, err := r.db.NamedExecContext(ctx, cmd, rule)
+	result, err := tx.NamedExecContext(ctx, cmd, rule)
 	if e, ok := err.(sqlite3.Error); ok && e.Code == sqlite3.ErrConstraint {
 		err = errs.InvalidReferenceError
-		r.logger.Debug().Err(err).Msg("Unknown proxy profile")
+		r.logger.Debug().Err(err).Msg("Unknown proxy profile or domain list")
 		return err
 	}
 	if err != nil {
@@ -96,17 +317,58 @@ func (r *RuleRepository) Create(ctx context.Context, rule *model.Rule) error {
 		r.logger.Error().Err(err).Msg("Error occurred while retrieving created rule id")
 		return errs.RepositoryUnknownError
 	}
+
+	if err := r.setTags(ctx, tx, int(id), rule.Tags); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while setting tags of created rule")
+		return errs.RepositoryUnknownError
+	}
+
+	if err := auditChange(ctx, tx, ruleSnapshot, model.AuditRule, int(id), model.AuditCreate, ""); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while auditing created rule")
+		return errs.RepositoryUnknownError
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while committing transaction")
+		return errs.RepositoryUnknownError
+	}
+
 	rule.ID = int(id)
 	return nil
 }
 
+// Update replaces the rule of the version given in the rule. Owner of the rule is kept if the given one is empty.
 func (r *RuleRepository) Update(ctx context.Context, rule model.Rule) error {
-	cmd := `UPDATE rules SET regex = :regex, proxy_profile_id = :proxy_profile.id WHERE id = :id`
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while starting transaction")
+		return errs.RepositoryUnknownError
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	before, err := snapshot(ctx, tx, ruleSnapshot, rule.ID)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while getting rule before update")
+		return errs.RepositoryUnknownError
+	}
+
+	cmd := `UPDATE rules
+			SET regex = nullif(:regex, ''),
+				domain_list_id = nullif(:domain_list_id, 0),
+				proxy_profile_id = :proxy_profile.id,
+				enabled = :enabled,
+				active_from = datetime(:active_from),
+				expires_at = datetime(:expires_at),
+				description = :description,
+				owner = coalesce(nullif(:owner, ''), owner),
+				updated_at = CURRENT_TIMESTAMP,
+				version = version + 1
+			WHERE id = :id AND ` + versionGuard
 
-	result, err := r.db.NamedExecContext(ctx, cmd, rule)
+	result, err := tx.NamedExecContext(ctx, cmd, rule)
 	if e, ok := err.(sqlite3.Error); ok && e.Code == sqlite3.ErrConstraint {
 		err = errs.InvalidReferenceError
-		r.logger.Debug().Err(err).Msg("Unknown proxy profile")
+		r.logger.Debug().Err(err).Msg("Unknown proxy profile or domain list")
 		return err
 	}
 	if err != nil {
@@ -119,16 +381,118 @@ func (r *RuleRepository) Update(ctx context.Context, rule model.Rule) error {
 		return errs.RepositoryUnknownError
 	}
 	if count == 0 {
-		err = &errs.EntityNotFoundError{Name: "rule", Key: "id", Value: rule.ID}
-		r.logger.Debug().Err(err).Send()
+		return versionConflict(ctx, tx, r.logger, "rules", "rule", rule.ID)
+	}
+
+	if err := r.setTags(ctx, tx, rule.ID, rule.Tags); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while setting tags of updated rule")
+		return errs.RepositoryUnknownError
+	}
+
+	if err := auditChange(ctx, tx, ruleSnapshot, model.AuditRule, rule.ID, model.AuditUpdate, before); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while auditing updated rule")
+		return errs.RepositoryUnknownError
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while committing transaction")
+		return errs.RepositoryUnknownError
+	}
+	return nil
+}
+
+// rulePatchColumns maps the fields of model.RuleFields that can be patched to the assignments updating them.
+var rulePatchColumns = map[string]string{
+	"regex":            "regex = nullif(:regex, '')",
+	"domain_list_id":   "domain_list_id = nullif(:domain_list_id, 0)",
+	"proxy_profile_id": "proxy_profile_id = :proxy_profile.id",
+	"enabled":          "enabled = :enabled",
+	"active_from":      "active_from = datetime(:active_from)",
+	"expires_at":       "expires_at = datetime(:expires_at)",
+	"description":      "description = :description",
+	"owner":            "owner = coalesce(nullif(:owner, ''), owner)",
+}
+
+// Patch updates the given fields of the rule of the version given in the rule only, the fields are named
+// as in model.RuleFields.
+func (r *RuleRepository) Patch(ctx context.Context, rule model.Rule, fields []string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while starting transaction")
+		return errs.RepositoryUnknownError
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	before, err := snapshot(ctx, tx, ruleSnapshot, rule.ID)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while getting rule before patch")
+		return errs.RepositoryUnknownError
+	}
+
+	assignments := make([]string, 0, len(fields)+1)
+	for _, field := range model.RuleFields {
+		if column, ok := rulePatchColumns[field]; ok && contains(fields, field) {
+			assignments = append(assignments, column)
+		}
+	}
+	assignments = append(assignments, "updated_at = CURRENT_TIMESTAMP", "version = version + 1")
+	cmd := `UPDATE rules SET ` + strings.Join(assignments, ", ") + ` WHERE id = :id AND ` + versionGuard
+
+	result, err := tx.NamedExecContext(ctx, cmd, rule)
+	if e, ok := err.(sqlite3.Error); ok && e.Code == sqlite3.ErrConstraint {
+		err = errs.InvalidReferenceError
+		r.logger.Debug().Err(err).Msg("Unknown proxy profile or domain list")
 		return err
 	}
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while patching rule")
+		return errs.RepositoryUnknownError
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while retrieving affected rows count after patching rule")
+		return errs.RepositoryUnknownError
+	}
+	if count == 0 {
+		return versionConflict(ctx, tx, r.logger, "rules", "rule", rule.ID)
+	}
+
+	if contains(fields, "tags") {
+		if err := r.setTags(ctx, tx, rule.ID, rule.Tags); err != nil {
+			r.logger.Error().Err(err).Msg("Error occurred while setting tags of patched rule")
+			return errs.RepositoryUnknownError
+		}
+	}
+
+	if err := auditChange(ctx, tx, ruleSnapshot, model.AuditRule, rule.ID, model.AuditUpdate, before); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while auditing patched rule")
+		return errs.RepositoryUnknownError
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while committing transaction")
+		return errs.RepositoryUnknownError
+	}
 	return nil
 }
 
-func (r *RuleRepository) Delete(ctx context.Context, id int) error {
-	cmd := `DELETE FROM rules WHERE id = ?`
-	result, err := r.db.ExecContext(ctx, cmd, id)
+// Delete deletes the rule of the version, zero version matches any.
+func (r *RuleRepository) Delete(ctx context.Context, id int, version int) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while starting transaction")
+		return errs.RepositoryUnknownError
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	before, err := snapshot(ctx, tx, ruleSnapshot, id)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while getting rule before delete")
+		return errs.RepositoryUnknownError
+	}
+
+	cmd := `DELETE FROM rules WHERE id = :id AND ` + versionGuard
+	result, err := tx.NamedExecContext(ctx, cmd, map[string]any{"id": id, "version": version})
 	if err != nil {
 		r.logger.Error().Err(err).Msg("Error occurred while deleting rule")
 		return errs.RepositoryUnknownError
@@ -139,9 +503,246 @@ func (r *RuleRepository) Delete(ctx context.Context, id int) error {
 		return errs.RepositoryUnknownError
 	}
 	if count == 0 {
-		err = &errs.EntityNotFoundError{Name: "rule", Key: "id", Value: id}
-		r.logger.Debug().Err(err).Send()
+		return versionConflict(ctx, tx, r.logger, "rules", "rule", id)
+	}
+
+	if err := auditChange(ctx, tx, ruleSnapshot, model.AuditRule, id, model.AuditDelete, before); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while auditing deleted rule")
+		return errs.RepositoryUnknownError
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while committing transaction")
+		return errs.RepositoryUnknownError
+	}
+	return nil
+}
+
+// UpdateByTag applies the changes to all the rules with the tag and returns the number of updated rules.
+func (r *RuleRepository) UpdateByTag(ctx context.Context, tag string, changes model.RuleBulkUpdate) (int, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while starting transaction")
+		return 0, errs.RepositoryUnknownError
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	ids, before, err := r.snapshotRules(ctx, tx, taggedRules, tag)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while getting rules before update by tag")
+		return 0, errs.RepositoryUnknownError
+	}
+
+	cmd := `UPDATE rules
+			SET proxy_profile_id = coalesce(?, proxy_profile_id),
+				enabled = coalesce(?, enabled),
+				updated_at = CURRENT_TIMESTAMP,
+				version = version + 1
+			WHERE id IN (` + taggedRules + `)`
+
+	result, err := tx.ExecContext(ctx, cmd, changes.ProxyProfileID, changes.Enabled, tag)
+	if e, ok := err.(sqlite3.Error); ok && e.Code == sqlite3.ErrConstraint {
+		err = errs.InvalidReferenceError
+		r.logger.Debug().Err(err).Msg("Unknown proxy profile")
+		return 0, err
+	}
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while updating rules by tag")
+		return 0, errs.RepositoryUnknownError
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while retrieving affected rows count after updating rules")
+		return 0, errs.RepositoryUnknownError
+	}
+
+	if err := auditRules(ctx, tx, ids, model.AuditUpdate, before); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while auditing rules updated by tag")
+		return 0, errs.RepositoryUnknownError
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while committing transaction")
+		return 0, errs.RepositoryUnknownError
+	}
+	return int(count), nil
+}
+
+// DeleteByTag deletes all the rules with the tag and returns the number of deleted rules.
+func (r *RuleRepository) DeleteByTag(ctx context.Context, tag string) (int, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while starting transaction")
+		return 0, errs.RepositoryUnknownError
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	ids, before, err := r.snapshotRules(ctx, tx, taggedRules, tag)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while getting rules before delete by tag")
+		return 0, errs.RepositoryUnknownError
+	}
+
+	cmd := `DELETE FROM rules WHERE id IN (` + taggedRules + `)`
+	result, err := tx.ExecContext(ctx, cmd, tag)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while deleting rules by tag")
+		return 0, errs.RepositoryUnknownError
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while retrieving affected rows count after deleting rules")
+		return 0, errs.RepositoryUnknownError
+	}
+
+	if err := auditRules(ctx, tx, ids, model.AuditDelete, before); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while auditing rules deleted by tag")
+		return 0, errs.RepositoryUnknownError
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while committing transaction")
+		return 0, errs.RepositoryUnknownError
+	}
+	return int(count), nil
+}
+
+// expiredRules selects ids of the rules which have expired.
+const expiredRules = `SELECT id FROM rules WHERE expires_at <= CURRENT_TIMESTAMP`
+
+// ArchiveExpired disables enabled rules which have expired and tags them with the tag. It returns the number
+// of archived rules.
+func (r *RuleRepository) ArchiveExpired(ctx context.Context, tag string) (int, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while starting transaction")
+		return 0, errs.RepositoryUnknownError
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	ids, before, err := r.snapshotRules(ctx, tx, expiredRules+` AND enabled`)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while getting expired rules before archiving")
+		return 0, errs.RepositoryUnknownError
+	}
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO tags (name) VALUES (?) ON CONFLICT (name) DO NOTHING`, tag); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while creating archive tag")
+		return 0, errs.RepositoryUnknownError
+	}
+	cmd := `INSERT INTO rule_tags (rule_id, tag_id)
+			SELECT r.id, t.id FROM rules r, tags t WHERE r.enabled AND r.expires_at <= CURRENT_TIMESTAMP AND t.name = ?
+			ON CONFLICT DO NOTHING`
+	if _, err := tx.ExecContext(ctx, cmd, tag); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while tagging expired rules")
+		return 0, errs.RepositoryUnknownError
+	}
+
+	cmd = `UPDATE rules
+		   SET enabled = 0, updated_at = CURRENT_TIMESTAMP, version = version + 1
+		   WHERE enabled AND expires_at <= CURRENT_TIMESTAMP`
+	result, err := tx.ExecContext(ctx, cmd)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while disabling expired rules")
+		return 0, errs.RepositoryUnknownError
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while retrieving affected rows count after disabling rules")
+		return 0, errs.RepositoryUnknownError
+	}
+
+	if err := auditRules(ctx, tx, ids, model.AuditUpdate, before); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while auditing archived rules")
+		return 0, errs.RepositoryUnknownError
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while committing transaction")
+		return 0, errs.RepositoryUnknownError
+	}
+	return int(count), nil
+}
+
+// DeleteExpired deletes the rules which have expired and returns the number of deleted rules.
+func (r *RuleRepository) DeleteExpired(ctx context.Context) (int, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while starting transaction")
+		return 0, errs.RepositoryUnknownError
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	ids, before, err := r.snapshotRules(ctx, tx, expiredRules)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while getting expired rules before deleting")
+		return 0, errs.RepositoryUnknownError
+	}
+
+	cmd := `DELETE FROM rules WHERE expires_at <= CURRENT_TIMESTAMP`
+	result, err := tx.ExecContext(ctx, cmd)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while deleting expired rules")
+		return 0, errs.RepositoryUnknownError
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while retrieving affected rows count after deleting rules")
+		return 0, errs.RepositoryUnknownError
+	}
+
+	if err := auditRules(ctx, tx, ids, model.AuditDelete, before); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while auditing deleted expired rules")
+		return 0, errs.RepositoryUnknownError
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while committing transaction")
+		return 0, errs.RepositoryUnknownError
+	}
+	return int(count), nil
+}
+
+// snapshotRules returns the ids of the rules selected by the query along with their states for the audit log.
+func (r *RuleRepository) snapshotRules(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	query string,
+	args ...any,
+) (ids []int, states []string, err error) {
+	if err := tx.SelectContext(ctx, &ids, query, args...); err != nil {
+		return nil, nil, err
+	}
+	if states, err = snapshots(ctx, tx, ruleSnapshot, ids); err != nil {
+		return nil, nil, err
+	}
+	return ids, states, nil
+}
+
+// auditRules records the change of the rules with the ids made in the transaction, before are their states
+// before the change.
+func auditRules(ctx context.Context, tx *sqlx.Tx, ids []int, action model.AuditAction, before []string) error {
+	for i, id := range ids {
+		if err := auditChange(ctx, tx, ruleSnapshot, model.AuditRule, id, action, before[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setTags replaces tags of the rule, creating the missing ones.
+func (r *RuleRepository) setTags(ctx context.Context, tx *sqlx.Tx, ruleID int, tags []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM rule_tags WHERE rule_id = ?`, ruleID); err != nil {
 		return err
 	}
+	for _, tag := range tags {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO tags (name) VALUES (?) ON CONFLICT (name) DO NOTHING`, tag); err != nil {
+			return err
+		}
+		cmd := `INSERT INTO rule_tags (rule_id, tag_id) SELECT ?, id FROM tags WHERE name = ?`
+		if _, err := tx.ExecContext(ctx, cmd, ruleID, tag); err != nil {
+			return err
+		}
+	}
 	return nil
 }
@@ -10,7 +10,11 @@ import (
 	"github.com/nnemirovsky/pacgen/internal/errs"
 	"github.com/nnemirovsky/pacgen/internal/model"
 	"github.com/nnemirovsky/pacgen/pkg/logutil"
+	"os"
+	"path/filepath"
+	"sort"
 	"testing"
+	"time"
 )
 
 func testPrepareRuleRepository(t *testing.T) (*RuleRepository, sqlmock.Sqlmock) {
@@ -32,27 +36,179 @@ func TestRuleRepository_GetAll_OK(t *testing.T) {
 	repo, mock := testPrepareRuleRepository(t)
 
 	mock.
-		ExpectQuery(`SELECT id, regex, proxy_profile_id AS "proxy_profile.id" FROM rules`).
+		ExpectQuery(
+			`SELECT r.id,
+					coalesce\(r.regex, ''\) AS regex,
+					coalesce\(r.domain_list_id, 0\) AS domain_list_id,
+					r.enabled,
+					r.proxy_profile_id AS "proxy_profile.id",
+					\(SELECT group_concat\(t.name\)
+					 FROM rule_tags rt
+					 JOIN tags t ON rt.tag_id = t.id
+					 WHERE rt.rule_id = r.id\) AS tags,
+					r.active_from, r.expires_at,
+					r.description, r.owner, r.created_at, r.updated_at
+			 FROM rules r ORDER BY r.id ASC$`,
+		).
+		WillReturnRows(
+			sqlmock.
+				NewRows([]string{"id", "regex", "enabled", "proxy_profile.id", "tags"}).
+				AddRow(10, `^google\.com$`, true, 1, "search").
+				AddRow(20, `(?:^|\.)aws\.com$`, false, 2, nil).
+				AddRow(123456789, `^facebook\.com$`, true, 3, "social,ads"),
+		)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	got, err := repo.GetAll(ctx, model.RuleFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []model.Rule{
+		{ID: 10, Regex: `^google\.com$`, Enabled: true, Tags: model.Tags{"search"}, ProxyProfile: &model.ProxyProfile{ID: 1}},
+		{ID: 20, Regex: `(?:^|\.)aws\.com$`, Tags: model.Tags{}, ProxyProfile: &model.ProxyProfile{ID: 2}},
+		{
+			ID:           123456789,
+			Regex:        `^facebook\.com$`,
+			Enabled:      true,
+			Tags:         model.Tags{"ads", "social"},
+			ProxyProfile: &model.ProxyProfile{ID: 3},
+		},
+	}
+
+	assert.Equal(t, want, got)
+}
+
+func TestRuleRepository_GetAll_ByTag(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareRuleRepository(t)
+
+	mock.
+		ExpectQuery(
+			`FROM rules r WHERE r.id IN \(SELECT rt.rule_id FROM rule_tags rt JOIN tags t ON rt.tag_id = t.id ` +
+				`WHERE t.name = \?\)`,
+		).
+		WithArgs("search").
 		WillReturnRows(
 			sqlmock.
-				NewRows([]string{"id", "regex", "proxy_profile.id"}).
-				AddRow(10, `^google\.com$`, 1).
-				AddRow(20, `(?:^|\.)aws\.com$`, 2).
-				AddRow(123456789, `^facebook\.com$`, 3),
+				NewRows([]string{"id", "regex", "enabled", "proxy_profile.id", "tags"}).
+				AddRow(10, `^google\.com$`, true, 1, "search"),
 		)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	got, err := repo.GetAll(ctx)
+	got, err := repo.GetAll(ctx, model.RuleFilter{Tag: "search"})
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	want := []model.Rule{
-		{ID: 10, Regex: `^google\.com$`, ProxyProfile: &model.ProxyProfile{ID: 1}},
-		{ID: 20, Regex: `(?:^|\.)aws\.com$`, ProxyProfile: &model.ProxyProfile{ID: 2}},
-		{ID: 123456789, Regex: `^facebook\.com$`, ProxyProfile: &model.ProxyProfile{ID: 3}},
+		{ID: 10, Regex: `^google\.com$`, Enabled: true, Tags: model.Tags{"search"}, ProxyProfile: &model.ProxyProfile{ID: 1}},
+	}
+
+	assert.Equal(t, want, got)
+}
+
+func TestRuleRepository_GetAll_Expanded(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareRuleRepository(t)
+
+	mock.
+		ExpectQuery(
+			`SELECT r.id, r.proxy_profile_id AS "proxy_profile.id", p.name AS "proxy_profile.name", .* ` +
+				`FROM rules r JOIN proxy_profiles p ON r.proxy_profile_id = p.id`,
+		).
+		WillReturnRows(
+			sqlmock.
+				NewRows([]string{"id", "proxy_profile.id", "proxy_profile.name", "proxy_profile.type"}).
+				AddRow(10, 1, "squid", model.Http).
+				AddRow(11, 3, "balanced", model.Pool).
+				AddRow(12, 3, "balanced", model.Pool),
+		)
+	mock.
+		ExpectQuery(`SELECT pool_id, member_id, weight FROM profile_pool_members WHERE pool_id IN \(\?\)`).
+		WithArgs(3).
+		WillReturnRows(
+			sqlmock.
+				NewRows([]string{"pool_id", "member_id", "weight"}).
+				AddRow(3, 1, 2),
+		)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	got, err := repo.GetAll(ctx, model.RuleFilter{Fields: []string{"id"}, ExpandProfile: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	members := []model.PoolMember{{ProfileID: 1, Weight: 2}}
+	want := []model.Rule{
+		{ID: 10, ProxyProfile: &model.ProxyProfile{ID: 1, Name: "squid", Type: model.Http}},
+		{ID: 11, ProxyProfile: &model.ProxyProfile{ID: 3, Name: "balanced", Type: model.Pool, Members: members}},
+		{ID: 12, ProxyProfile: &model.ProxyProfile{ID: 3, Name: "balanced", Type: model.Pool, Members: members}},
+	}
+
+	assert.Equal(t, got, want)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRuleRepository_GetAll_ByMetadata(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareRuleRepository(t)
+
+	created := time.Date(2022, 9, 1, 10, 0, 0, 0, time.UTC)
+
+	mock.
+		ExpectQuery(
+			`FROM rules r WHERE r.id IN \(SELECT rt.rule_id FROM rule_tags rt JOIN tags t ON rt.tag_id = t.id `+
+				`WHERE t.name = \?\) AND r.owner = \? AND instr\(lower\(r.description\), lower\(\?\)\) > 0 `+
+				`AND r.created_at >= \? ORDER BY r.created_at DESC, r.id DESC$`,
+		).
+		WithArgs("search", "alice", "ticket", "2022-09-01 08:00:00").
+		WillReturnRows(
+			sqlmock.
+				NewRows([]string{
+					"id", "regex", "enabled", "proxy_profile.id", "tags", "description", "owner", "created_at", "updated_at",
+				}).
+				AddRow(10, `^google\.com$`, true, 1, "search", "Ticket 42", "alice", created, created),
+		)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	filter := model.RuleFilter{
+		Tag: "search",
+		MetadataFilter: model.MetadataFilter{
+			Owner:        "alice",
+			Description:  "ticket",
+			CreatedAfter: time.Date(2022, 9, 1, 10, 0, 0, 0, time.FixedZone("UTC+2", 2*60*60)),
+		},
+		Sort: model.Sort{Field: "created_at", Desc: true},
+	}
+	got, err := repo.GetAll(ctx, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []model.Rule{
+		{
+			ID:           10,
+			Regex:        `^google\.com$`,
+			Enabled:      true,
+			Tags:         model.Tags{"search"},
+			ProxyProfile: &model.ProxyProfile{ID: 1},
+			Metadata:     model.Metadata{Description: "Ticket 42", Owner: "alice", CreatedAt: &created, UpdatedAt: &created},
+		},
 	}
 
 	assert.Equal(t, want, got)
@@ -66,13 +222,20 @@ func TestRuleRepository_GetAllWithProfiles_OK(t *testing.T) {
 	mock.
 		ExpectQuery(
 			`SELECT r.id,
-					r.regex,
+					coalesce\(r.regex, ''\) AS regex,
+					coalesce\(r.domain_list_id, 0\) AS domain_list_id,
+					r.enabled,
 					p.id AS "proxy_profile.id",
 					p.name AS "proxy_profile.name",
 					p.type AS "proxy_profile.type",
-					p.address AS "proxy_profile.address"
+					coalesce\(p.host, ''\) AS "proxy_profile.host",
+					coalesce\(p.port, 0\) AS "proxy_profile.port"
 			 FROM rules r
-			 JOIN proxy_profiles p ON r.proxy_profile_id = p.id`,
+			 JOIN proxy_profiles p ON r.proxy_profile_id = p.id
+			 WHERE r.enabled
+			   AND \(r.active_from IS NULL OR r.active_from <= CURRENT_TIMESTAMP\)
+			   AND \(r.expires_at IS NULL OR r.expires_at > CURRENT_TIMESTAMP\)
+			 ORDER BY r.id`,
 		).
 		WillReturnRows(
 			sqlmock.
@@ -83,12 +246,13 @@ func TestRuleRepository_GetAllWithProfiles_OK(t *testing.T) {
 						"proxy_profile.id",
 						"proxy_profile.name",
 						"proxy_profile.type",
-						"proxy_profile.address",
+						"proxy_profile.host",
+						"proxy_profile.port",
 					},
 				).
-				AddRow(10, `^google\.com$`, 1, "shadowsocks", model.Socks5, "localhost:1080").
-				AddRow(20, `(?:^|\.)aws\.com$`, 1, "shadowsocks", model.Socks5, "localhost:1080").
-				AddRow(123456789, `^facebook\.com$`, 2, "tor", model.Socks5, "10.100.100.50:9050"),
+				AddRow(10, `^google\.com$`, 1, "shadowsocks", model.Socks5, "localhost", 1080).
+				AddRow(20, `(?:^|\.)aws\.com$`, 1, "shadowsocks", model.Socks5, "localhost", 1080).
+				AddRow(123456789, `^facebook\.com$`, 2, "tor", model.Socks5, "10.100.100.50", 9050),
 		)
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -104,30 +268,33 @@ func TestRuleRepository_GetAllWithProfiles_OK(t *testing.T) {
 			ID:    10,
 			Regex: `^google\.com$`,
 			ProxyProfile: &model.ProxyProfile{
-				ID:      1,
-				Name:    "shadowsocks",
-				Type:    model.Socks5,
-				Address: "localhost:1080",
+				ID:   1,
+				Name: "shadowsocks",
+				Type: model.Socks5,
+				Host: "localhost",
+				Port: 1080,
 			},
 		},
 		{
 			ID:    20,
 			Regex: `(?:^|\.)aws\.com$`,
 			ProxyProfile: &model.ProxyProfile{
-				ID:      1,
-				Name:    "shadowsocks",
-				Type:    model.Socks5,
-				Address: "localhost:1080",
+				ID:   1,
+				Name: "shadowsocks",
+				Type: model.Socks5,
+				Host: "localhost",
+				Port: 1080,
 			},
 		},
 		{
 			ID:    123456789,
 			Regex: `^facebook\.com$`,
 			ProxyProfile: &model.ProxyProfile{
-				ID:      2,
-				Name:    "tor",
-				Type:    model.Socks5,
-				Address: "10.100.100.50:9050",
+				ID:   2,
+				Name: "tor",
+				Type: model.Socks5,
+				Host: "10.100.100.50",
+				Port: 9050,
 			},
 		},
 	}
@@ -143,11 +310,26 @@ func TestRuleRepository_GetByID_OK(t *testing.T) {
 	mock.
 		ExpectQuery(
 			`SELECT r.id,
-					r.regex,
+					coalesce\(r.regex, ''\) AS regex,
+					coalesce\(r.domain_list_id, 0\) AS domain_list_id,
+					r.enabled,
 					p.id AS "proxy_profile.id",
 					p.name AS "proxy_profile.name",
 					p.type AS "proxy_profile.type",
-					p.address AS "proxy_profile.address"
+					coalesce\(p.host, ''\) AS "proxy_profile.host",
+					coalesce\(p.port, 0\) AS "proxy_profile.port",
+					coalesce\(p.standby_profile_id, 0\) AS "proxy_profile.standby_id",
+					p.description AS "proxy_profile.description",
+					p.owner AS "proxy_profile.owner",
+					p.created_at AS "proxy_profile.created_at",
+					p.updated_at AS "proxy_profile.updated_at",
+					p.version AS "proxy_profile.version",
+					\(SELECT group_concat\(t.name\)
+					 FROM rule_tags rt
+					 JOIN tags t ON rt.tag_id = t.id
+					 WHERE rt.rule_id = r.id\) AS tags,
+					r.active_from, r.expires_at,
+					r.description, r.owner, r.created_at, r.updated_at, r.version
 			 FROM rules r
 			 JOIN proxy_profiles p ON r.proxy_profile_id = p.id
 			 WHERE r.id = \?`,
@@ -162,10 +344,11 @@ func TestRuleRepository_GetByID_OK(t *testing.T) {
 						"proxy_profile.id",
 						"proxy_profile.name",
 						"proxy_profile.type",
-						"proxy_profile.address",
+						"proxy_profile.host",
+						"proxy_profile.port",
 					},
 				).
-				AddRow(10, `^google\.com$`, 1, "shadowsocks", model.Socks5, "localhost:1080"),
+				AddRow(10, `^google\.com$`, 1, "shadowsocks", model.Socks5, "localhost", 1080),
 		)
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -180,10 +363,11 @@ func TestRuleRepository_GetByID_OK(t *testing.T) {
 		ID:    10,
 		Regex: `^google\.com$`,
 		ProxyProfile: &model.ProxyProfile{
-			ID:      1,
-			Name:    "shadowsocks",
-			Type:    model.Socks5,
-			Address: "localhost:1080",
+			ID:   1,
+			Name: "shadowsocks",
+			Type: model.Socks5,
+			Host: "localhost",
+			Port: 1080,
 		},
 	}
 
@@ -198,11 +382,26 @@ func TestRuleRepository_GetByID_NotFound(t *testing.T) {
 	mock.
 		ExpectQuery(
 			`SELECT r.id,
-					r.regex,
+					coalesce\(r.regex, ''\) AS regex,
+					coalesce\(r.domain_list_id, 0\) AS domain_list_id,
+					r.enabled,
 					p.id AS "proxy_profile.id",
 					p.name AS "proxy_profile.name",
 					p.type AS "proxy_profile.type",
-					p.address AS "proxy_profile.address"
+					coalesce\(p.host, ''\) AS "proxy_profile.host",
+					coalesce\(p.port, 0\) AS "proxy_profile.port",
+					coalesce\(p.standby_profile_id, 0\) AS "proxy_profile.standby_id",
+					p.description AS "proxy_profile.description",
+					p.owner AS "proxy_profile.owner",
+					p.created_at AS "proxy_profile.created_at",
+					p.updated_at AS "proxy_profile.updated_at",
+					p.version AS "proxy_profile.version",
+					\(SELECT group_concat\(t.name\)
+					 FROM rule_tags rt
+					 JOIN tags t ON rt.tag_id = t.id
+					 WHERE rt.rule_id = r.id\) AS tags,
+					r.active_from, r.expires_at,
+					r.description, r.owner, r.created_at, r.updated_at, r.version
 			 FROM rules r
 			 JOIN proxy_profiles p ON r.proxy_profile_id = p.id
 			 WHERE r.id = \?`,
@@ -227,15 +426,38 @@ func TestRuleRepository_Create_OK(t *testing.T) {
 
 	const insertedID = 15
 
+	mock.ExpectBegin()
 	mock.
-		ExpectExec(`INSERT INTO rules \(regex, proxy_profile_id\) VALUES \(\?, \?\) RETURNING id`).
-		WithArgs(`^google\.com$`, 1).
+		ExpectExec(`INSERT INTO rules \(regex, domain_list_id, proxy_profile_id, enabled, active_from, expires_at, `+
+			`description, owner, created_at, updated_at\) VALUES \(nullif\(\?, ''\), nullif\(\?, 0\), \?, \?, `+
+			`datetime\(\?\), datetime\(\?\), \?, \?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP\) RETURNING id`).
+		WithArgs(`^google\.com$`, 0, 1, true, nil, nil, "", "").
 		WillReturnResult(sqlmock.NewResult(insertedID, 1))
+	mock.
+		ExpectExec(`DELETE FROM rule_tags WHERE rule_id = \?`).
+		WithArgs(insertedID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.
+		ExpectExec(`INSERT INTO tags \(name\) VALUES \(\?\) ON CONFLICT \(name\) DO NOTHING`).
+		WithArgs("search").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.
+		ExpectExec(`INSERT INTO rule_tags \(rule_id, tag_id\) SELECT \?, id FROM tags WHERE name = \?`).
+		WithArgs(insertedID, "search").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	expectSnapshot(mock, `rules r WHERE r.id = \?`, `{"id":15}`, insertedID)
+	expectAudit(mock, model.AuditRule, insertedID, model.AuditCreate)
+	mock.ExpectCommit()
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	rule := model.Rule{Regex: `^google\.com$`, ProxyProfile: &model.ProxyProfile{ID: 1}}
+	rule := model.Rule{
+		Regex:        `^google\.com$`,
+		Enabled:      true,
+		Tags:         model.Tags{"search"},
+		ProxyProfile: &model.ProxyProfile{ID: 1},
+	}
 	err := repo.Create(ctx, &rule)
 
 	if err != nil {
@@ -243,6 +465,10 @@ func TestRuleRepository_Create_OK(t *testing.T) {
 	}
 
 	assert.Equal(t, insertedID, rule.ID)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
 }
 
 func TestRuleRepository_Create_InvalidReference(t *testing.T) {
@@ -250,15 +476,19 @@ func TestRuleRepository_Create_InvalidReference(t *testing.T) {
 
 	repo, mock := testPrepareRuleRepository(t)
 
+	mock.ExpectBegin()
 	mock.
-		ExpectExec(`INSERT INTO rules \(regex, proxy_profile_id\) VALUES \(\?, \?\) RETURNING id`).
-		WithArgs(`^google\.com$`, 1).
+		ExpectExec(`INSERT INTO rules \(regex, domain_list_id, proxy_profile_id, enabled, active_from, expires_at, `+
+			`description, owner, created_at, updated_at\) VALUES \(nullif\(\?, ''\), nullif\(\?, 0\), \?, \?, `+
+			`datetime\(\?\), datetime\(\?\), \?, \?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP\) RETURNING id`).
+		WithArgs(`^google\.com$`, 0, 1, true, nil, nil, "", "").
 		WillReturnError(sqlite3.Error{Code: sqlite3.ErrConstraint})
+	mock.ExpectRollback()
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	rule := model.Rule{Regex: `^google\.com$`, ProxyProfile: &model.ProxyProfile{ID: 1}}
+	rule := model.Rule{Regex: `^google\.com$`, Enabled: true, ProxyProfile: &model.ProxyProfile{ID: 1}}
 	err := repo.Create(ctx, &rule)
 
 	if err != errs.InvalidReferenceError {
@@ -271,20 +501,35 @@ func TestRuleRepository_Update_OK(t *testing.T) {
 
 	repo, mock := testPrepareRuleRepository(t)
 
+	mock.ExpectBegin()
+	expectSnapshot(mock, `rules r WHERE r.id = \?`, `{"id":10}`, 10)
 	mock.
-		ExpectExec(`UPDATE rules SET regex = \?, proxy_profile_id = \? WHERE id = \?`).
-		WithArgs(`^google\.com$`, 1, 10).
+		ExpectExec(`UPDATE rules SET regex = nullif\(\?, ''\), domain_list_id = nullif\(\?, 0\), proxy_profile_id = \?, `+
+			`enabled = \?, active_from = datetime\(\?\), expires_at = datetime\(\?\), description = \?, owner = coalesce\(nullif\(\?, ''\), owner\), updated_at = CURRENT_TIMESTAMP, `+
+			`version = version \+ 1 WHERE id = \? AND \? IN \(0, version\)`).
+		WithArgs(`^google\.com$`, 0, 1, false, nil, nil, "", "", 10, 0).
 		WillReturnResult(sqlmock.NewResult(10, 1))
+	mock.
+		ExpectExec(`DELETE FROM rule_tags WHERE rule_id = \?`).
+		WithArgs(10).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	expectSnapshot(mock, `rules r WHERE r.id = \?`, `{"id":10}`, 10)
+	expectAudit(mock, model.AuditRule, 10, model.AuditUpdate)
+	mock.ExpectCommit()
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	rule := model.Rule{ID: 10, Regex: `^google\.com$`, ProxyProfile: &model.ProxyProfile{ID: 1}}
+	rule := model.Rule{ID: 10, Regex: `^google\.com$`, Tags: model.Tags{}, ProxyProfile: &model.ProxyProfile{ID: 1}}
 	err := repo.Update(ctx, rule)
 
 	if err != nil {
 		t.Fatal(err)
 	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
 }
 
 func TestRuleRepository_Update_NotFound(t *testing.T) {
@@ -292,15 +537,24 @@ func TestRuleRepository_Update_NotFound(t *testing.T) {
 
 	repo, mock := testPrepareRuleRepository(t)
 
+	mock.ExpectBegin()
+	expectSnapshot(mock, `rules r WHERE r.id = \?`, "", 10)
 	mock.
-		ExpectExec(`UPDATE rules SET regex = \?, proxy_profile_id = \? WHERE id = \?`).
-		WithArgs(`^google\.com$`, 1, 10).
+		ExpectExec(`UPDATE rules SET regex = nullif\(\?, ''\), domain_list_id = nullif\(\?, 0\), proxy_profile_id = \?, `+
+			`enabled = \?, active_from = datetime\(\?\), expires_at = datetime\(\?\), description = \?, owner = coalesce\(nullif\(\?, ''\), owner\), updated_at = CURRENT_TIMESTAMP, `+
+			`version = version \+ 1 WHERE id = \? AND \? IN \(0, version\)`).
+		WithArgs(`^google\.com$`, 0, 1, true, nil, nil, "", "", 10, 0).
 		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.
+		ExpectQuery(`SELECT version FROM rules WHERE id = \?`).
+		WithArgs(10).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	rule := model.Rule{ID: 10, Regex: `^google\.com$`, ProxyProfile: &model.ProxyProfile{ID: 1}}
+	rule := model.Rule{ID: 10, Regex: `^google\.com$`, Enabled: true, ProxyProfile: &model.ProxyProfile{ID: 1}}
 	err := repo.Update(ctx, rule)
 
 	if _, ok := err.(*errs.EntityNotFoundError); !ok {
@@ -308,20 +562,59 @@ func TestRuleRepository_Update_NotFound(t *testing.T) {
 	}
 }
 
+func TestRuleRepository_Update_VersionConflict(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareRuleRepository(t)
+
+	mock.ExpectBegin()
+	expectSnapshot(mock, `rules r WHERE r.id = \?`, `{"id":10}`, 10)
+	mock.
+		ExpectExec(`UPDATE rules SET regex = nullif\(\?, ''\), domain_list_id = nullif\(\?, 0\), proxy_profile_id = \?, `+
+			`enabled = \?, active_from = datetime\(\?\), expires_at = datetime\(\?\), description = \?, owner = coalesce\(nullif\(\?, ''\), owner\), updated_at = CURRENT_TIMESTAMP, `+
+			`version = version \+ 1 WHERE id = \? AND \? IN \(0, version\)`).
+		WithArgs(`^google\.com$`, 0, 1, true, nil, nil, "", "", 10, 2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.
+		ExpectQuery(`SELECT version FROM rules WHERE id = \?`).
+		WithArgs(10).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(3))
+	mock.ExpectRollback()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rule := model.Rule{ID: 10, Regex: `^google\.com$`, Enabled: true, ProxyProfile: &model.ProxyProfile{ID: 1}}
+	rule.Version = 2
+	err := repo.Update(ctx, rule)
+
+	conflict, ok := err.(*errs.EntityVersionConflictError)
+	if !ok {
+		t.Fatal("expected errs.EntityVersionConflictError")
+	}
+
+	assert.Equal(t, 3, conflict.Version)
+}
+
 func TestRuleRepository_Update_InvalidReference(t *testing.T) {
 	t.Parallel()
 
 	repo, mock := testPrepareRuleRepository(t)
 
+	mock.ExpectBegin()
+	expectSnapshot(mock, `rules r WHERE r.id = \?`, `{"id":10}`, 10)
 	mock.
-		ExpectExec(`UPDATE rules SET regex = \?, proxy_profile_id = \? WHERE id = \?`).
-		WithArgs(`^google\.com$`, 1, 10).
+		ExpectExec(`UPDATE rules SET regex = nullif\(\?, ''\), domain_list_id = nullif\(\?, 0\), proxy_profile_id = \?, `+
+			`enabled = \?, active_from = datetime\(\?\), expires_at = datetime\(\?\), description = \?, owner = coalesce\(nullif\(\?, ''\), owner\), updated_at = CURRENT_TIMESTAMP, `+
+			`version = version \+ 1 WHERE id = \? AND \? IN \(0, version\)`).
+		WithArgs(`^google\.com$`, 0, 1, true, nil, nil, "", "", 10, 0).
 		WillReturnError(sqlite3.Error{Code: sqlite3.ErrConstraint})
+	mock.ExpectRollback()
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	rule := model.Rule{ID: 10, Regex: `^google\.com$`, ProxyProfile: &model.ProxyProfile{ID: 1}}
+	rule := model.Rule{ID: 10, Regex: `^google\.com$`, Enabled: true, ProxyProfile: &model.ProxyProfile{ID: 1}}
 	err := repo.Update(ctx, rule)
 
 	if err != errs.InvalidReferenceError {
@@ -334,19 +627,27 @@ func TestRuleRepository_Delete_OK(t *testing.T) {
 
 	repo, mock := testPrepareRuleRepository(t)
 
+	mock.ExpectBegin()
+	expectSnapshot(mock, `rules r WHERE r.id = \?`, `{"id":10}`, 10)
 	mock.
-		ExpectExec(`DELETE FROM rules WHERE id = \?`).
-		WithArgs(10).
+		ExpectExec(`DELETE FROM rules WHERE id = \? AND \? IN \(0, version\)$`).
+		WithArgs(10, 0).
 		WillReturnResult(sqlmock.NewResult(10, 1))
+	expectAudit(mock, model.AuditRule, 10, model.AuditDelete)
+	mock.ExpectCommit()
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	err := repo.Delete(ctx, 10)
+	err := repo.Delete(ctx, 10, 0)
 
 	if err != nil {
 		t.Fatal(err)
 	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
 }
 
 func TestRuleRepository_Delete_NotFound(t *testing.T) {
@@ -354,17 +655,556 @@ func TestRuleRepository_Delete_NotFound(t *testing.T) {
 
 	repo, mock := testPrepareRuleRepository(t)
 
+	mock.ExpectBegin()
+	expectSnapshot(mock, `rules r WHERE r.id = \?`, "", 10)
 	mock.
-		ExpectExec(`DELETE FROM rules WHERE id = \?`).
-		WithArgs(10).
+		ExpectExec(`DELETE FROM rules WHERE id = \? AND \? IN \(0, version\)$`).
+		WithArgs(10, 0).
 		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.
+		ExpectQuery(`SELECT version FROM rules WHERE id = \?`).
+		WithArgs(10).
+		WillReturnError(sql.ErrNoRows)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	err := repo.Delete(ctx, 10)
+	err := repo.Delete(ctx, 10, 0)
 
 	if _, ok := err.(*errs.EntityNotFoundError); !ok {
 		t.Fatal("expected errs.EntityNotFoundError")
 	}
 }
+
+func TestRuleRepository_Patch_OK(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareRuleRepository(t)
+
+	mock.ExpectBegin()
+	expectSnapshot(mock, `rules r WHERE r.id = \?`, `{"id":10}`, 10)
+	mock.
+		ExpectExec(`UPDATE rules SET proxy_profile_id = \?, enabled = \?, updated_at = CURRENT_TIMESTAMP, `+
+			`version = version \+ 1 WHERE id = \? AND \? IN \(0, version\)$`).
+		WithArgs(2, false, 10, 0).
+		WillReturnResult(sqlmock.NewResult(10, 1))
+	mock.
+		ExpectExec(`DELETE FROM rule_tags WHERE rule_id = \?`).
+		WithArgs(10).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectSnapshot(mock, `rules r WHERE r.id = \?`, `{"id":10}`, 10)
+	expectAudit(mock, model.AuditRule, 10, model.AuditUpdate)
+	mock.ExpectCommit()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rule := model.Rule{ID: 10, Regex: `^google\.com$`, Tags: model.Tags{}, ProxyProfile: &model.ProxyProfile{ID: 2}}
+	err := repo.Patch(ctx, rule, []string{"enabled", "proxy_profile_id", "tags"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRuleRepository_Patch_NotFound(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareRuleRepository(t)
+
+	mock.ExpectBegin()
+	expectSnapshot(mock, `rules r WHERE r.id = \?`, `{"id":10}`, 10)
+	mock.
+		ExpectExec(`UPDATE rules SET regex = nullif\(\?, ''\), domain_list_id = nullif\(\?, 0\), `+
+			`updated_at = CURRENT_TIMESTAMP, version = version \+ 1 WHERE id = \? AND \? IN \(0, version\)$`).
+		WithArgs("", 3, 10, 0).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.
+		ExpectQuery(`SELECT version FROM rules WHERE id = \?`).
+		WithArgs(10).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rule := model.Rule{ID: 10, DomainListID: 3, ProxyProfile: &model.ProxyProfile{ID: 1}}
+	err := repo.Patch(ctx, rule, []string{"domain_list_id", "regex"})
+
+	if _, ok := err.(*errs.EntityNotFoundError); !ok {
+		t.Fatal("expected error errs.EntityNotFoundError")
+	}
+}
+
+func TestRuleRepository_UpdateByTag_OK(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareRuleRepository(t)
+
+	enabled := false
+
+	mock.ExpectBegin()
+	mock.
+		ExpectQuery(`SELECT rt.rule_id FROM rule_tags rt JOIN tags t ON rt.tag_id = t.id WHERE t.name = \?$`).
+		WithArgs("streaming").
+		WillReturnRows(sqlmock.NewRows([]string{"rule_id"}).AddRow(10).AddRow(11))
+	expectSnapshot(mock, `rules r WHERE r.id = \?`, `{"id":10}`, 10)
+	expectSnapshot(mock, `rules r WHERE r.id = \?`, `{"id":11}`, 11)
+	mock.
+		ExpectExec(`UPDATE rules SET proxy_profile_id = coalesce\(\?, proxy_profile_id\), enabled = coalesce\(\?, enabled\), `+
+			`updated_at = CURRENT_TIMESTAMP, version = version \+ 1 `+
+			`WHERE id IN \(SELECT rt.rule_id FROM rule_tags rt JOIN tags t ON rt.tag_id = t.id WHERE t.name = \?\)`).
+		WithArgs(nil, false, "streaming").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	expectSnapshot(mock, `rules r WHERE r.id = \?`, `{"id":10,"enabled":false}`, 10)
+	expectAudit(mock, model.AuditRule, 10, model.AuditUpdate)
+	expectSnapshot(mock, `rules r WHERE r.id = \?`, `{"id":11,"enabled":false}`, 11)
+	expectAudit(mock, model.AuditRule, 11, model.AuditUpdate)
+	mock.ExpectCommit()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	got, err := repo.UpdateByTag(ctx, "streaming", model.RuleBulkUpdate{Enabled: &enabled})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, got, 2)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRuleRepository_UpdateByTag_InvalidReference(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareRuleRepository(t)
+
+	profileID := 7
+
+	mock.ExpectBegin()
+	mock.
+		ExpectQuery(`SELECT rt.rule_id FROM rule_tags rt JOIN tags t ON rt.tag_id = t.id WHERE t.name = \?$`).
+		WithArgs("streaming").
+		WillReturnRows(sqlmock.NewRows([]string{"rule_id"}).AddRow(10).AddRow(11))
+	expectSnapshot(mock, `rules r WHERE r.id = \?`, `{"id":10}`, 10)
+	expectSnapshot(mock, `rules r WHERE r.id = \?`, `{"id":11}`, 11)
+	mock.
+		ExpectExec(`UPDATE rules SET proxy_profile_id = coalesce\(\?, proxy_profile_id\)`).
+		WithArgs(7, nil, "streaming").
+		WillReturnError(sqlite3.Error{Code: sqlite3.ErrConstraint})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := repo.UpdateByTag(ctx, "streaming", model.RuleBulkUpdate{ProxyProfileID: &profileID})
+
+	if err != errs.InvalidReferenceError {
+		t.Fatal("expected errs.InvalidReferenceError")
+	}
+}
+
+func TestRuleRepository_DeleteByTag_OK(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareRuleRepository(t)
+
+	mock.ExpectBegin()
+	mock.
+		ExpectQuery(`SELECT rt.rule_id FROM rule_tags rt JOIN tags t ON rt.tag_id = t.id WHERE t.name = \?$`).
+		WithArgs("streaming").
+		WillReturnRows(sqlmock.NewRows([]string{"rule_id"}).AddRow(10).AddRow(11))
+	expectSnapshot(mock, `rules r WHERE r.id = \?`, `{"id":10}`, 10)
+	expectSnapshot(mock, `rules r WHERE r.id = \?`, `{"id":11}`, 11)
+	mock.
+		ExpectExec(`DELETE FROM rules WHERE id IN \(SELECT rt.rule_id FROM rule_tags rt JOIN tags t ON rt.tag_id = t.id ` +
+			`WHERE t.name = \?\)`).
+		WithArgs("streaming").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	expectAudit(mock, model.AuditRule, 10, model.AuditDelete)
+	expectAudit(mock, model.AuditRule, 11, model.AuditDelete)
+	mock.ExpectCommit()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	got, err := repo.DeleteByTag(ctx, "streaming")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, got, 2)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRuleRepository_GetAllWithProfiles_DomainLists(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareRuleRepository(t)
+
+	mock.
+		ExpectQuery(`FROM rules r JOIN proxy_profiles p ON r.proxy_profile_id = p.id WHERE r.enabled`).
+		WillReturnRows(
+			sqlmock.
+				NewRows(
+					[]string{
+						"id",
+						"regex",
+						"domain_list_id",
+						"proxy_profile.id",
+						"proxy_profile.name",
+						"proxy_profile.type",
+						"proxy_profile.host",
+						"proxy_profile.port",
+					},
+				).
+				AddRow(10, "", 1, 1, "tor", model.Socks5, "localhost", 9050).
+				AddRow(20, `^google\.com$`, 0, 1, "tor", model.Socks5, "localhost", 9050).
+				AddRow(30, "", 1, 2, "direct", model.Direct, "", 0),
+		)
+	mock.
+		ExpectQuery(
+			`SELECT list_id, domain, mode FROM domain_list_entries ` +
+				`WHERE list_id IN \(SELECT domain_list_id FROM rules WHERE enabled\) ORDER BY list_id, domain`,
+		).
+		WillReturnRows(
+			sqlmock.
+				NewRows([]string{"list_id", "domain", "mode"}).
+				AddRow(1, "googlevideo.com", model.DomainAndSubdomains).
+				AddRow(1, "vimeo.com", model.ExactDomain),
+		)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	got, err := repo.GetAllWithProfiles(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantList := &model.DomainList{
+		ID: 1,
+		Entries: []model.DomainListEntry{
+			{Domain: "googlevideo.com", Mode: model.DomainAndSubdomains},
+			{Domain: "vimeo.com", Mode: model.ExactDomain},
+		},
+	}
+
+	assert.Equal(t, len(got), 3)
+	assert.Equal(t, got[0].DomainList, wantList)
+	assert.Equal(t, got[1].DomainList == nil, true)
+	// Rules referring to the same list share it.
+	assert.Equal(t, got[0].DomainList == got[2].DomainList, true)
+}
+
+func TestRuleRepository_Create_Scheduled(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareRuleRepository(t)
+
+	activeFrom := time.Date(2022, 9, 1, 10, 0, 0, 0, time.UTC)
+	expiresAt := time.Date(2022, 9, 8, 10, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.
+		ExpectExec(`INSERT INTO rules`).
+		WithArgs(`^google\.com$`, 0, 1, true, activeFrom, expiresAt, "", "").
+		WillReturnResult(sqlmock.NewResult(15, 1))
+	mock.
+		ExpectExec(`DELETE FROM rule_tags WHERE rule_id = \?`).
+		WithArgs(15).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	expectSnapshot(mock, `rules r WHERE r.id = \?`, `{"id":15}`, 15)
+	expectAudit(mock, model.AuditRule, 15, model.AuditCreate)
+	mock.ExpectCommit()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rule := model.Rule{
+		Regex:        `^google\.com$`,
+		Enabled:      true,
+		ProxyProfile: &model.ProxyProfile{ID: 1},
+		ActiveFrom:   &activeFrom,
+		ExpiresAt:    &expiresAt,
+	}
+	if err := repo.Create(ctx, &rule); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRuleRepository_GetExpiring_OK(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareRuleRepository(t)
+
+	expiresAt := time.Date(2022, 9, 8, 10, 0, 0, 0, time.UTC)
+
+	mock.
+		ExpectQuery(
+			`FROM rules r WHERE r.enabled AND r.expires_at > CURRENT_TIMESTAMP AND r.expires_at <= \? ` +
+				`ORDER BY r.expires_at, r.id$`,
+		).
+		WithArgs("2022-09-10 10:00:00").
+		WillReturnRows(
+			sqlmock.
+				NewRows([]string{"id", "regex", "enabled", "proxy_profile.id", "tags", "expires_at"}).
+				AddRow(10, `^google\.com$`, true, 1, "vendor", expiresAt),
+		)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	got, err := repo.GetExpiring(ctx, time.Date(2022, 9, 10, 12, 0, 0, 0, time.FixedZone("", 2*60*60)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []model.Rule{
+		{
+			ID:           10,
+			Regex:        `^google\.com$`,
+			Enabled:      true,
+			Tags:         model.Tags{"vendor"},
+			ProxyProfile: &model.ProxyProfile{ID: 1},
+			ExpiresAt:    &expiresAt,
+		},
+	}
+
+	assert.Equal(t, want, got)
+}
+
+func TestRuleRepository_NextBoundary(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		boundary any
+		want     time.Time
+	}{
+		"some": {boundary: "2022-09-08 10:00:00", want: time.Date(2022, 9, 8, 10, 0, 0, 0, time.UTC)},
+		"none": {boundary: nil, want: time.Time{}},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			repo, mock := testPrepareRuleRepository(t)
+
+			mock.
+				ExpectQuery(
+					`SELECT min\(boundary\) FROM \(SELECT active_from AS boundary FROM rules WHERE enabled AND `+
+						`active_from > \? UNION ALL SELECT expires_at FROM rules WHERE enabled AND expires_at > \?\)$`,
+				).
+				WithArgs("2022-09-01 10:00:00", "2022-09-01 10:00:00").
+				WillReturnRows(sqlmock.NewRows([]string{"min(boundary)"}).AddRow(c.boundary))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			got, err := repo.NextBoundary(ctx, time.Date(2022, 9, 1, 10, 0, 0, 500, time.UTC))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, got, c.want)
+		})
+	}
+}
+
+func TestRuleRepository_ArchiveExpired_OK(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareRuleRepository(t)
+
+	mock.ExpectBegin()
+	mock.
+		ExpectQuery(`SELECT id FROM rules WHERE expires_at <= CURRENT_TIMESTAMP AND enabled$`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4).AddRow(5))
+	expectSnapshot(mock, `rules r WHERE r.id = \?`, `{"id":4,"enabled":true}`, 4)
+	expectSnapshot(mock, `rules r WHERE r.id = \?`, `{"id":5,"enabled":true}`, 5)
+	mock.
+		ExpectExec(`INSERT INTO tags \(name\) VALUES \(\?\) ON CONFLICT \(name\) DO NOTHING`).
+		WithArgs("expired").
+		WillReturnResult(sqlmock.NewResult(3, 1))
+	mock.
+		ExpectExec(`INSERT INTO rule_tags \(rule_id, tag_id\) SELECT r.id, t.id FROM rules r, tags t ` +
+			`WHERE r.enabled AND r.expires_at <= CURRENT_TIMESTAMP AND t.name = \? ON CONFLICT DO NOTHING`).
+		WithArgs("expired").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.
+		ExpectExec(`UPDATE rules SET enabled = 0, updated_at = CURRENT_TIMESTAMP, version = version \+ 1 ` +
+			`WHERE enabled AND expires_at <= CURRENT_TIMESTAMP`).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	expectSnapshot(mock, `rules r WHERE r.id = \?`, `{"id":4,"enabled":false}`, 4)
+	expectAudit(mock, model.AuditRule, 4, model.AuditUpdate)
+	expectSnapshot(mock, `rules r WHERE r.id = \?`, `{"id":5,"enabled":false}`, 5)
+	expectAudit(mock, model.AuditRule, 5, model.AuditUpdate)
+	mock.ExpectCommit()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	got, err := repo.ArchiveExpired(ctx, model.ArchiveTag)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, got, 2)
+}
+
+func TestRuleRepository_DeleteExpired_OK(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareRuleRepository(t)
+
+	mock.ExpectBegin()
+	mock.
+		ExpectQuery(`SELECT id FROM rules WHERE expires_at <= CURRENT_TIMESTAMP$`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	expectSnapshot(mock, `rules r WHERE r.id = \?`, `{"id":4}`, 4)
+	mock.
+		ExpectExec(`DELETE FROM rules WHERE expires_at <= CURRENT_TIMESTAMP`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectAudit(mock, model.AuditRule, 4, model.AuditDelete)
+	mock.ExpectCommit()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	got, err := repo.DeleteExpired(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, got, 1)
+}
+
+func TestRuleRepository_GetAll_Paged(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareRuleRepository(t)
+
+	mock.
+		ExpectQuery(
+			`SELECT r.id, coalesce\(r.regex, ''\) AS regex FROM rules r WHERE r.proxy_profile_id = \? `+
+				`AND substr\(r.regex, 1, 8\) = '\(\?:\^\|\\\.\)' `+
+				`AND \(instr\(replace\(rtrim\(CASE WHEN substr\(r.regex, 1, 8\) = '\(\?:\^\|\\\.\)' `+
+				`THEN substr\(r.regex, 9\) ELSE ltrim\(r.regex, '\^'\) END, '\$'\), '\\\.', '\.'\), \?\) > 0 `+
+				`OR r.domain_list_id IN \(SELECT list_id FROM domain_list_entries WHERE instr\(domain, \?\) > 0\)\) `+
+				`ORDER BY replace\(.+\) DESC, r.id DESC LIMIT \? OFFSET \?$`,
+		).
+		WithArgs(2, "google", "google", 10, 20).
+		WillReturnRows(
+			sqlmock.
+				NewRows([]string{"id", "regex"}).
+				AddRow(21, `(?:^|\.)google\.com$`),
+		)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	got, err := repo.GetAll(ctx, model.RuleFilter{
+		ProxyProfileID: 2,
+		Mode:           "domain_and_subdomains",
+		Domain:         "Google",
+		Sort:           model.Sort{Field: "domain", Desc: true},
+		Page:           model.Page{Limit: 10, Offset: 20},
+		Fields:         []string{"id", "regex"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []model.Rule{{ID: 21, Regex: `(?:^|\.)google\.com$`}}
+
+	assert.Equal(t, want, got)
+}
+
+func TestRuleRepository_Count_OK(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareRuleRepository(t)
+
+	mock.
+		ExpectQuery(`SELECT count\(\*\) FROM rules r WHERE r.domain_list_id IS NOT NULL$`).
+		WillReturnRows(sqlmock.NewRows([]string{"count(*)"}).AddRow(45))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	got, err := repo.Count(ctx, model.RuleFilter{Mode: "domain_list", Page: model.Page{Limit: 10}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, got, 45)
+}
+
+// testMigratedDB opens an in-memory SQLite database with all the migrations applied, for the tests that depend on
+// how SQLite plans the queries against the real schema and its indexes.
+func testMigratedDB(t *testing.T) *sqlx.DB {
+	db, err := sqlx.Connect("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening an in-memory database", err)
+	}
+	// Every connection to :memory: opens a database of its own.
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+
+	files, err := filepath.Glob(filepath.Join("..", "..", "migrations", "*.up.sql"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no migrations found: %v", err)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		query, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when reading %s", err, file)
+		}
+		if _, err = db.Exec(string(query)); err != nil {
+			t.Fatalf("an error '%s' was not expected when applying %s", err, file)
+		}
+	}
+	return db
+}
+
+func TestRuleRepository_GetAllWithProfiles_OrderWithSchedule(t *testing.T) {
+	t.Parallel()
+
+	db := testMigratedDB(t)
+	repo := NewRuleRepository(db, logutil.DiscardLogger)
+
+	_, err := db.Exec(`INSERT INTO proxy_profiles (id, name, type, host, port) VALUES (1, 'tor', 4, 'localhost', 9050);
+		INSERT INTO rules (id, regex, proxy_profile_id, active_from) VALUES
+			(1, 'first', 1, '2020-01-01 00:00:00'),
+			(2, 'second', 1, NULL),
+			(3, 'third', 1, '2019-01-01 00:00:00'),
+			(4, 'fourth', 1, NULL)`)
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when inserting the rules", err)
+	}
+
+	rules, err := repo.GetAllWithProfiles(context.Background())
+
+	assert.Equal(t, err, nil)
+	ids := make([]int, 0, len(rules))
+	for _, rule := range rules {
+		ids = append(ids, rule.ID)
+	}
+	assert.Equal(t, ids, []int{1, 2, 3, 4})
+}
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/rs/zerolog"
+)
+
+// tokenQuery selects the tokens along with the current names and roles of their users.
+const tokenQuery = `SELECT t.id, t.name, coalesce(t.user_id, 0) AS user_id, coalesce(u.name, '') AS user_name,
+						   coalesce(u.role, 0) AS user_role, t.token_hash, t.scopes, t.expires_at, t.last_used_at,
+						   t.created_at
+					FROM api_tokens t
+					LEFT JOIN users u ON u.id = t.user_id`
+
+type TokenRepository struct {
+	logger zerolog.Logger
+	db     *sqlx.DB
+}
+
+func NewTokenRepository(db *sqlx.DB, logger zerolog.Logger) *TokenRepository {
+	return &TokenRepository{
+		logger: logger,
+		db:     db,
+	}
+}
+
+// GetAll returns the personal tokens of the user, all the tokens if userID is zero.
+func (r *TokenRepository) GetAll(ctx context.Context, userID int) ([]model.APIToken, error) {
+	query := tokenQuery + ` WHERE ? = 0 OR t.user_id = ? ORDER BY t.id`
+
+	tokens := make([]model.APIToken, 0)
+	if err := r.db.SelectContext(ctx, &tokens, query, userID, userID); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while getting API tokens")
+		return nil, errs.RepositoryUnknownError
+	}
+	return tokens, nil
+}
+
+func (r *TokenRepository) GetByID(ctx context.Context, id int) (model.APIToken, error) {
+	return r.get(ctx, `id`, id)
+}
+
+func (r *TokenRepository) GetByHash(ctx context.Context, hash string) (model.APIToken, error) {
+	return r.get(ctx, `token_hash`, hash)
+}
+
+func (r *TokenRepository) get(ctx context.Context, key string, value any) (model.APIToken, error) {
+	query := tokenQuery + ` WHERE t.` + key + ` = ?`
+
+	var token model.APIToken
+	if err := r.db.GetContext(ctx, &token, query, value); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			err = &errs.EntityNotFoundError{Name: "API token", Key: key, Value: value}
+			// Hashes are not logged, they would let the tokens be tried out offline.
+			if key == `token_hash` {
+				err = &errs.EntityNotFoundError{Name: "API token"}
+			}
+			r.logger.Debug().Err(err).Send()
+		default:
+			r.logger.Error().Err(err).Msgf("Error occurred while getting API token by %s", key)
+			err = errs.RepositoryUnknownError
+		}
+		return model.APIToken{}, err
+	}
+	return token, nil
+}
+
+func (r *TokenRepository) Create(ctx context.Context, token *model.APIToken) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while starting transaction")
+		return errs.RepositoryUnknownError
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	cmd := `INSERT INTO api_tokens (name, user_id, token_hash, scopes, expires_at, created_at)
+			VALUES (:name, nullif(:user_id, 0), :token_hash, :scopes, datetime(:expires_at), CURRENT_TIMESTAMP)`
+	result, err := tx.NamedExecContext(ctx, cmd, token)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while creating API token")
+		return errs.RepositoryUnknownError
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while retrieving created API token id")
+		return errs.RepositoryUnknownError
+	}
+
+	if err := auditChange(ctx, tx, tokenSnapshot, model.AuditAPIToken, int(id), model.AuditCreate, ""); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while auditing created API token")
+		return errs.RepositoryUnknownError
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while committing transaction")
+		return errs.RepositoryUnknownError
+	}
+
+	token.ID = int(id)
+	return nil
+}
+
+// Delete revokes the token.
+func (r *TokenRepository) Delete(ctx context.Context, id int) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while starting transaction")
+		return errs.RepositoryUnknownError
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	before, err := snapshot(ctx, tx, tokenSnapshot, id)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while getting API token before delete")
+		return errs.RepositoryUnknownError
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM api_tokens WHERE id = ?`, id)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while deleting API token")
+		return errs.RepositoryUnknownError
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while retrieving affected rows count after deleting API token")
+		return errs.RepositoryUnknownError
+	}
+	if count == 0 {
+		err = &errs.EntityNotFoundError{Name: "API token", Key: "id", Value: id}
+		r.logger.Debug().Err(err).Send()
+		return err
+	}
+
+	if err := auditChange(ctx, tx, tokenSnapshot, model.AuditAPIToken, id, model.AuditDelete, before); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while auditing deleted API token")
+		return errs.RepositoryUnknownError
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while committing transaction")
+		return errs.RepositoryUnknownError
+	}
+	return nil
+}
+
+// Touch records the use of the token. The time of the last use is updated at most once a minute, so requests
+// in a row do not write to the database each.
+func (r *TokenRepository) Touch(ctx context.Context, id int) error {
+	cmd := `UPDATE api_tokens
+			SET last_used_at = CURRENT_TIMESTAMP
+			WHERE id = ? AND (last_used_at IS NULL OR last_used_at < datetime('now', '-1 minute'))`
+	if _, err := r.db.ExecContext(ctx, cmd, id); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while recording use of API token")
+		return errs.RepositoryUnknownError
+	}
+	return nil
+}
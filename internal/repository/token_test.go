@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-playground/assert/v2"
+	"github.com/jmoiron/sqlx"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/nnemirovsky/pacgen/pkg/logutil"
+	"testing"
+)
+
+func testPrepareTokenRepository(t *testing.T) (*TokenRepository, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	dbx := sqlx.NewDb(db, "sqlmock")
+	repo := NewTokenRepository(dbx, logutil.DiscardLogger)
+
+	return repo, mock
+}
+
+func TestTokenRepository_GetByHash_NotFound(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareTokenRepository(t)
+
+	mock.
+		ExpectQuery(`SELECT t.id, .* FROM api_tokens t LEFT JOIN users u ON u.id = t.user_id WHERE t.token_hash = \?$`).
+		WithArgs("hash").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := repo.GetByHash(ctx, "hash")
+
+	assert.Equal(t, err, &errs.EntityNotFoundError{Name: "API token"})
+}
+
+func TestTokenRepository_Create_OK(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareTokenRepository(t)
+
+	mock.ExpectBegin()
+	mock.
+		ExpectExec(`INSERT INTO api_tokens \(name, user_id, token_hash, scopes, expires_at, created_at\) `+
+			`VALUES \(\?, nullif\(\?, 0\), \?, \?, datetime\(\?\), CURRENT_TIMESTAMP\)$`).
+		WithArgs("ci", 0, "hash", "pac:read rules:write", nil).
+		WillReturnResult(sqlmock.NewResult(3, 1))
+	expectSnapshot(mock, `api_tokens t WHERE t.id = \?`, `{"id":3,"name":"ci","scopes":"pac:read rules:write"}`, 3)
+	expectAudit(mock, model.AuditAPIToken, 3, model.AuditCreate)
+	mock.ExpectCommit()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	token := model.APIToken{Name: "ci", TokenHash: "hash", Scopes: model.Scopes{"pac:read", "rules:write"}}
+	if err := repo.Create(ctx, &token); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, token.ID, 3)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTokenRepository_Delete_NotFound(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareTokenRepository(t)
+
+	mock.ExpectBegin()
+	expectSnapshot(mock, `api_tokens t WHERE t.id = \?`, "", 5)
+	mock.
+		ExpectExec(`DELETE FROM api_tokens WHERE id = \?$`).
+		WithArgs(5).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err := repo.Delete(ctx, 5)
+
+	assert.Equal(t, err, &errs.EntityNotFoundError{Name: "API token", Key: "id", Value: 5})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,268 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/rs/zerolog"
+)
+
+const userColumns = `id, name, password_hash, role, created_at, updated_at`
+
+type UserRepository struct {
+	logger zerolog.Logger
+	db     *sqlx.DB
+}
+
+func NewUserRepository(db *sqlx.DB, logger zerolog.Logger) *UserRepository {
+	return &UserRepository{
+		logger: logger,
+		db:     db,
+	}
+}
+
+func (r *UserRepository) GetAll(ctx context.Context) ([]model.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users ORDER BY id`
+
+	users := make([]model.User, 0)
+	if err := r.db.SelectContext(ctx, &users, query); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while getting users")
+		return nil, errs.RepositoryUnknownError
+	}
+	return users, nil
+}
+
+func (r *UserRepository) GetByID(ctx context.Context, id int) (model.User, error) {
+	return r.get(ctx, `id`, id)
+}
+
+func (r *UserRepository) GetByName(ctx context.Context, name string) (model.User, error) {
+	return r.get(ctx, `name`, name)
+}
+
+func (r *UserRepository) get(ctx context.Context, key string, value any) (model.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE ` + key + ` = ?`
+
+	var user model.User
+	if err := r.db.GetContext(ctx, &user, query, value); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			err = &errs.EntityNotFoundError{Name: "user", Key: key, Value: value}
+			r.logger.Debug().Err(err).Send()
+		default:
+			r.logger.Error().Err(err).Msgf("Error occurred while getting user by %s", key)
+			err = errs.RepositoryUnknownError
+		}
+		return model.User{}, err
+	}
+	return user, nil
+}
+
+// Bootstrap creates the user unless there are users already, and reports whether the user has been created.
+func (r *UserRepository) Bootstrap(ctx context.Context, user *model.User) (bool, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while starting transaction")
+		return false, errs.RepositoryUnknownError
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	cmd := `INSERT INTO users (name, password_hash, role, created_at, updated_at)
+			SELECT ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+			WHERE NOT EXISTS (SELECT 1 FROM users)`
+	result, err := tx.ExecContext(ctx, cmd, user.Name, user.PasswordHash, user.Role)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while bootstrapping user")
+		return false, errs.RepositoryUnknownError
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while retrieving affected rows count after bootstrapping user")
+		return false, errs.RepositoryUnknownError
+	}
+	if count == 0 {
+		return false, nil
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while retrieving bootstrapped user id")
+		return false, errs.RepositoryUnknownError
+	}
+
+	if err := auditChange(ctx, tx, userSnapshot, model.AuditUser, int(id), model.AuditCreate, ""); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while auditing bootstrapped user")
+		return false, errs.RepositoryUnknownError
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while committing transaction")
+		return false, errs.RepositoryUnknownError
+	}
+
+	user.ID = int(id)
+	return true, nil
+}
+
+func (r *UserRepository) Create(ctx context.Context, user *model.User) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while starting transaction")
+		return errs.RepositoryUnknownError
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	cmd := `INSERT INTO users (name, password_hash, role, created_at, updated_at)
+			VALUES (:name, :password_hash, :role, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`
+	result, err := tx.NamedExecContext(ctx, cmd, user)
+	if err != nil {
+		if s, ok := err.(sqlite3.Error); ok && s.Code == sqlite3.ErrConstraint {
+			err := &errs.EntityAlreadyExistsError{Name: "user", Key: "name", Value: user.Name}
+			r.logger.Debug().Err(err).Send()
+			return err
+		}
+		r.logger.Error().Err(err).Msg("Error occurred while creating user")
+		return errs.RepositoryUnknownError
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while retrieving created user id")
+		return errs.RepositoryUnknownError
+	}
+
+	if err := auditChange(ctx, tx, userSnapshot, model.AuditUser, int(id), model.AuditCreate, ""); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while auditing created user")
+		return errs.RepositoryUnknownError
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while committing transaction")
+		return errs.RepositoryUnknownError
+	}
+
+	user.ID = int(id)
+	return nil
+}
+
+// Update updates the name and the role of the user, and the password hash unless it is empty. errs.LastAdminError
+// is returned if no admin would remain.
+func (r *UserRepository) Update(ctx context.Context, user model.User) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while starting transaction")
+		return errs.RepositoryUnknownError
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	before, err := snapshot(ctx, tx, userSnapshot, user.ID)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while getting user before update")
+		return errs.RepositoryUnknownError
+	}
+
+	cmd := `UPDATE users
+			SET name          = :name,
+				password_hash = coalesce(nullif(:password_hash, ''), password_hash),
+				role          = :role,
+				updated_at    = CURRENT_TIMESTAMP
+			WHERE id = :id`
+	result, err := tx.NamedExecContext(ctx, cmd, user)
+	if err != nil {
+		if s, ok := err.(sqlite3.Error); ok && s.Code == sqlite3.ErrConstraint {
+			err := &errs.EntityAlreadyExistsError{Name: "user", Key: "name", Value: user.Name}
+			r.logger.Debug().Err(err).Send()
+			return err
+		}
+		r.logger.Error().Err(err).Msg("Error occurred while updating user")
+		return errs.RepositoryUnknownError
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while retrieving affected rows count after updating user")
+		return errs.RepositoryUnknownError
+	}
+	if count == 0 {
+		err = &errs.EntityNotFoundError{Name: "user", Key: "id", Value: user.ID}
+		r.logger.Debug().Err(err).Send()
+		return err
+	}
+
+	if err := r.checkAdmins(ctx, tx); err != nil {
+		return err
+	}
+
+	if err := auditChange(ctx, tx, userSnapshot, model.AuditUser, user.ID, model.AuditUpdate, before); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while auditing updated user")
+		return errs.RepositoryUnknownError
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while committing transaction")
+		return errs.RepositoryUnknownError
+	}
+	return nil
+}
+
+// Delete deletes the user, errs.LastAdminError is returned if no admin would remain.
+func (r *UserRepository) Delete(ctx context.Context, id int) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while starting transaction")
+		return errs.RepositoryUnknownError
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	before, err := snapshot(ctx, tx, userSnapshot, id)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while getting user before delete")
+		return errs.RepositoryUnknownError
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = ?`, id)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while deleting user")
+		return errs.RepositoryUnknownError
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while retrieving affected rows count after deleting user")
+		return errs.RepositoryUnknownError
+	}
+	if count == 0 {
+		err = &errs.EntityNotFoundError{Name: "user", Key: "id", Value: id}
+		r.logger.Debug().Err(err).Send()
+		return err
+	}
+
+	if err := r.checkAdmins(ctx, tx); err != nil {
+		return err
+	}
+
+	if err := auditChange(ctx, tx, userSnapshot, model.AuditUser, id, model.AuditDelete, before); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while auditing deleted user")
+		return errs.RepositoryUnknownError
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while committing transaction")
+		return errs.RepositoryUnknownError
+	}
+	return nil
+}
+
+// checkAdmins returns errs.LastAdminError if the change made in the transaction has left no admins.
+func (r *UserRepository) checkAdmins(ctx context.Context, tx *sqlx.Tx) error {
+	var count int
+	if err := tx.GetContext(ctx, &count, `SELECT count(*) FROM users WHERE role = ?`, model.Admin); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while counting admins")
+		return errs.RepositoryUnknownError
+	}
+	if count == 0 {
+		r.logger.Debug().Err(errs.LastAdminError).Send()
+		return errs.LastAdminError
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-playground/assert/v2"
+	"github.com/jmoiron/sqlx"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/nnemirovsky/pacgen/pkg/logutil"
+	"testing"
+)
+
+func testPrepareUserRepository(t *testing.T) (*UserRepository, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	dbx := sqlx.NewDb(db, "sqlmock")
+	repo := NewUserRepository(dbx, logutil.DiscardLogger)
+
+	return repo, mock
+}
+
+func TestUserRepository_GetByName_NotFound(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareUserRepository(t)
+
+	mock.
+		ExpectQuery(`SELECT id, name, password_hash, role, created_at, updated_at FROM users WHERE name = \?$`).
+		WithArgs("bob").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "password_hash", "role", "created_at", "updated_at"}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := repo.GetByName(ctx, "bob")
+
+	assert.Equal(t, err, &errs.EntityNotFoundError{Name: "user", Key: "name", Value: "bob"})
+}
+
+func TestUserRepository_Bootstrap_UsersExist(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareUserRepository(t)
+
+	mock.ExpectBegin()
+	mock.
+		ExpectExec(`INSERT INTO users \(name, password_hash, role, created_at, updated_at\) `+
+			`SELECT \?, \?, \?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP WHERE NOT EXISTS \(SELECT 1 FROM users\)$`).
+		WithArgs("admin", "hash", model.Admin).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	user := model.User{Name: "admin", PasswordHash: "hash", Role: model.Admin}
+	created, err := repo.Bootstrap(ctx, &user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, created, false)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUserRepository_Update_LastAdmin(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareUserRepository(t)
+
+	mock.ExpectBegin()
+	expectSnapshot(mock, `users u WHERE u.id = \?`, `{"id":1,"name":"alice","role":"admin"}`, 1)
+	mock.
+		ExpectExec(`UPDATE users SET name = \?, password_hash = coalesce\(nullif\(\?, ''\), password_hash\), `+
+			`role = \?, updated_at = CURRENT_TIMESTAMP WHERE id = \?$`).
+		WithArgs("alice", "", model.Viewer, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.
+		ExpectQuery(`SELECT count\(\*\) FROM users WHERE role = \?$`).
+		WithArgs(model.Admin).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectRollback()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err := repo.Update(ctx, model.User{ID: 1, Name: "alice", Role: model.Viewer})
+
+	assert.Equal(t, err, errs.LastAdminError)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUserRepository_Delete_OK(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareUserRepository(t)
+
+	mock.ExpectBegin()
+	expectSnapshot(mock, `users u WHERE u.id = \?`, `{"id":2,"name":"bob","role":"editor"}`, 2)
+	mock.
+		ExpectExec(`DELETE FROM users WHERE id = \?$`).
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.
+		ExpectQuery(`SELECT count\(\*\) FROM users WHERE role = \?$`).
+		WithArgs(model.Admin).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	expectAudit(mock, model.AuditUser, 2, model.AuditDelete)
+	mock.ExpectCommit()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := repo.Delete(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
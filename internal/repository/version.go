@@ -0,0 +1,415 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/rs/zerolog"
+	"sort"
+)
+
+// configSnapshot selects the whole configuration as JSON object, the entities are in the form they are recorded
+// in the audit log.
+const configSnapshot = `SELECT json_object(
+							'proxy_profiles', json((SELECT json_group_array(json(` + profileObject + `))
+													FROM (SELECT * FROM proxy_profiles ORDER BY id) p)),
+							'domain_lists', json((SELECT json_group_array(json(` + domainListObject + `))
+												  FROM (SELECT * FROM domain_lists ORDER BY id) l)),
+							'rules', json((SELECT json_group_array(json(` + ruleObject + `))
+										   FROM (SELECT * FROM rules ORDER BY id) r)),
+							'bypass', json((` + bypassSnapshot + `)))`
+
+// versionedEntity is an entity of the configuration restored from its versions.
+type versionedEntity struct {
+	name string
+	// ids selects the ids of the entities, it is empty for the bypass list, which is a single entity.
+	ids      string
+	snapshot string
+	// bump raises the version of the restored entity above both the restored and the replaced one, so stale ETags
+	// do not match it. It takes the replaced state and the id, and is empty for the entities without versions.
+	bump string
+}
+
+var versionedEntities = []versionedEntity{
+	{
+		name:     model.AuditProxyProfile,
+		ids:      `SELECT id FROM proxy_profiles ORDER BY id`,
+		snapshot: profileSnapshot,
+		bump: `UPDATE proxy_profiles
+			   SET version    = max(version, coalesce(json_extract(nullif(?, ''), '$.version'), 0)) + 1,
+				   updated_at = CURRENT_TIMESTAMP
+			   WHERE id = ?`,
+	},
+	{
+		name:     model.AuditDomainList,
+		ids:      `SELECT id FROM domain_lists ORDER BY id`,
+		snapshot: domainListSnapshot,
+	},
+	{
+		name:     model.AuditRule,
+		ids:      `SELECT id FROM rules ORDER BY id`,
+		snapshot: ruleSnapshot,
+		bump: `UPDATE rules
+			   SET version    = max(version, coalesce(json_extract(nullif(?, ''), '$.version'), 0)) + 1,
+				   updated_at = CURRENT_TIMESTAMP
+			   WHERE id = ?`,
+	},
+	{
+		name:     model.AuditBypass,
+		snapshot: bypassSnapshot,
+	},
+}
+
+// clearCmds delete the whole configuration, referencing entities go first.
+var clearCmds = []string{
+	`DELETE FROM rules`,
+	`DELETE FROM profile_pool_members`,
+	`DELETE FROM proxy_profiles`,
+	`DELETE FROM domain_lists`,
+	`DELETE FROM bypass_entries`,
+}
+
+// restoreCmds insert the configuration from its state, each of them takes the state.
+var restoreCmds = []string{
+	`INSERT INTO proxy_profiles (id, name, type, host, port, standby_profile_id, description, owner, created_at,
+								 updated_at, version)
+	 SELECT json_extract(value, '$.id'), json_extract(value, '$.name'), json_extract(value, '$.type'),
+			json_extract(value, '$.host'), json_extract(value, '$.port'), json_extract(value, '$.standby_profile_id'),
+			json_extract(value, '$.description'), json_extract(value, '$.owner'), json_extract(value, '$.created_at'),
+			json_extract(value, '$.updated_at'), json_extract(value, '$.version')
+	 FROM json_each(?, '$.proxy_profiles')`,
+	`INSERT INTO profile_pool_members (pool_id, member_id, weight)
+	 SELECT json_extract(p.value, '$.id'), json_extract(m.value, '$.profile_id'), json_extract(m.value, '$.weight')
+	 FROM json_each(?, '$.proxy_profiles') p, json_each(p.value, '$.members') m`,
+	`INSERT INTO domain_lists (id, name)
+	 SELECT json_extract(value, '$.id'), json_extract(value, '$.name')
+	 FROM json_each(?, '$.domain_lists')`,
+	`INSERT INTO domain_list_entries (list_id, domain, mode)
+	 SELECT json_extract(l.value, '$.id'), json_extract(e.value, '$.domain'), json_extract(e.value, '$.mode')
+	 FROM json_each(?, '$.domain_lists') l, json_each(l.value, '$.entries') e`,
+	`INSERT INTO rules (id, regex, domain_list_id, proxy_profile_id, enabled, active_from, expires_at, description,
+						owner, created_at, updated_at, version)
+	 SELECT json_extract(value, '$.id'), json_extract(value, '$.regex'), json_extract(value, '$.domain_list_id'),
+			json_extract(value, '$.proxy_profile_id'), json_extract(value, '$.enabled'),
+			json_extract(value, '$.active_from'), json_extract(value, '$.expires_at'),
+			json_extract(value, '$.description'), json_extract(value, '$.owner'), json_extract(value, '$.created_at'),
+			json_extract(value, '$.updated_at'), json_extract(value, '$.version')
+	 FROM json_each(?, '$.rules')`,
+	`INSERT INTO tags (name)
+	 SELECT DISTINCT t.value FROM json_each(?, '$.rules') r, json_each(r.value, '$.tags') t WHERE true
+	 ON CONFLICT (name) DO NOTHING`,
+	`INSERT INTO rule_tags (rule_id, tag_id)
+	 SELECT json_extract(r.value, '$.id'), tags.id
+	 FROM json_each(?, '$.rules') r, json_each(r.value, '$.tags') t
+	 JOIN tags ON tags.name = t.value`,
+	`INSERT INTO bypass_entries (kind, value)
+	 SELECT json_extract(value, '$.kind'), json_extract(value, '$.value')
+	 FROM json_each(?, '$.bypass.entries')`,
+}
+
+type VersionRepository struct {
+	logger zerolog.Logger
+	db     *sqlx.DB
+}
+
+func NewVersionRepository(db *sqlx.DB, logger zerolog.Logger) *VersionRepository {
+	return &VersionRepository{
+		logger: logger,
+		db:     db,
+	}
+}
+
+// GetAll returns the page of the configuration versions without their states and PAC files, newest first.
+func (r *VersionRepository) GetAll(ctx context.Context, page model.Page) ([]model.ConfigVersion, error) {
+	limitClause, args := limit(page)
+	query := `SELECT id, json_array_length(state, '$.rules') AS rule_count,
+					 json_array_length(state, '$.proxy_profiles') AS profile_count, created_at
+			  FROM config_versions
+			  ORDER BY id DESC` + limitClause
+
+	versions := make([]model.ConfigVersion, 0)
+	if err := r.db.SelectContext(ctx, &versions, query, args...); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while getting configuration versions")
+		return nil, errs.RepositoryUnknownError
+	}
+	return versions, nil
+}
+
+// Count returns the number of the configuration versions.
+func (r *VersionRepository) Count(ctx context.Context) (int, error) {
+	var count int
+	if err := r.db.GetContext(ctx, &count, `SELECT count(*) FROM config_versions`); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while counting configuration versions")
+		return 0, errs.RepositoryUnknownError
+	}
+	return count, nil
+}
+
+func (r *VersionRepository) GetByID(ctx context.Context, id int) (model.ConfigVersion, error) {
+	query := `SELECT id, json_array_length(state, '$.rules') AS rule_count,
+					 json_array_length(state, '$.proxy_profiles') AS profile_count, state, pac, created_at
+			  FROM config_versions
+			  WHERE id = ?`
+
+	var version model.ConfigVersion
+	if err := r.db.GetContext(ctx, &version, query, id); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			err = &errs.EntityNotFoundError{Name: "configuration version", Key: "id", Value: id}
+			r.logger.Debug().Err(err).Send()
+		default:
+			r.logger.Error().Err(err).Msg("Error occurred while getting configuration version by id")
+			err = errs.RepositoryUnknownError
+		}
+		return model.ConfigVersion{}, err
+	}
+	return version, nil
+}
+
+// Snapshot returns the state of the current configuration, see model.ConfigVersion.
+func (r *VersionRepository) Snapshot(ctx context.Context) (string, error) {
+	var state string
+	if err := r.db.GetContext(ctx, &state, configSnapshot); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while selecting configuration")
+		return "", errs.RepositoryUnknownError
+	}
+	return state, nil
+}
+
+// GetLatest returns the latest version, which is the published one when the changes are drafts.
+func (r *VersionRepository) GetLatest(ctx context.Context) (model.ConfigVersion, error) {
+	query := `SELECT id, json_array_length(state, '$.rules') AS rule_count,
+					 json_array_length(state, '$.proxy_profiles') AS profile_count, state, pac, created_at
+			  FROM config_versions
+			  ORDER BY id DESC
+			  LIMIT 1`
+
+	var version model.ConfigVersion
+	if err := r.db.GetContext(ctx, &version, query); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			err = &errs.EntityNotFoundError{Name: "configuration version"}
+			r.logger.Debug().Err(err).Send()
+		default:
+			r.logger.Error().Err(err).Msg("Error occurred while getting latest configuration version")
+			err = errs.RepositoryUnknownError
+		}
+		return model.ConfigVersion{}, err
+	}
+	return version, nil
+}
+
+// Save records the state of the configuration along with PAC file generated from it, unless the state is the same
+// as in the latest version: PAC file changing with the health of the profiles alone makes up no new version.
+// Versions other than the latest keep ones are deleted.
+func (r *VersionRepository) Save(ctx context.Context, state, pac string, keep int) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while starting transaction")
+		return errs.RepositoryUnknownError
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	cmd := `INSERT INTO config_versions (state, pac, created_at)
+			SELECT ?, ?, CURRENT_TIMESTAMP
+			WHERE NOT EXISTS (SELECT 1
+							  FROM (SELECT state FROM config_versions ORDER BY id DESC LIMIT 1)
+							  WHERE state = ?)`
+	if _, err := tx.ExecContext(ctx, cmd, state, pac, state); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while saving configuration version")
+		return errs.RepositoryUnknownError
+	}
+
+	cmd = `DELETE FROM config_versions
+		   WHERE id <= (SELECT id FROM config_versions ORDER BY id DESC LIMIT 1 OFFSET ?)`
+	if _, err := tx.ExecContext(ctx, cmd, keep); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while deleting old configuration versions")
+		return errs.RepositoryUnknownError
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while committing transaction")
+		return errs.RepositoryUnknownError
+	}
+	return nil
+}
+
+// Restore replaces the whole configuration with the version and returns the changes it has made. The changes
+// are recorded in the audit log, and the restored entities get versions newer than the replaced ones.
+func (r *VersionRepository) Restore(ctx context.Context, id int) ([]model.VersionChange, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while starting transaction")
+		return nil, errs.RepositoryUnknownError
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	var state string
+	if err := tx.GetContext(ctx, &state, `SELECT state FROM config_versions WHERE id = ?`, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = &errs.EntityNotFoundError{Name: "configuration version", Key: "id", Value: id}
+			r.logger.Debug().Err(err).Send()
+			return nil, err
+		}
+		r.logger.Error().Err(err).Msg("Error occurred while getting configuration version state")
+		return nil, errs.RepositoryUnknownError
+	}
+
+	before, err := entityStates(ctx, tx)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while selecting replaced configuration")
+		return nil, errs.RepositoryUnknownError
+	}
+
+	for _, cmd := range clearCmds {
+		if _, err := tx.ExecContext(ctx, cmd); err != nil {
+			r.logger.Error().Err(err).Msg("Error occurred while clearing configuration")
+			return nil, errs.RepositoryUnknownError
+		}
+	}
+	for _, cmd := range restoreCmds {
+		if _, err := tx.ExecContext(ctx, cmd, state); err != nil {
+			r.logger.Error().Err(err).Msg("Error occurred while restoring configuration")
+			return nil, errs.RepositoryUnknownError
+		}
+	}
+
+	after, err := entityStates(ctx, tx)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while selecting restored configuration")
+		return nil, errs.RepositoryUnknownError
+	}
+
+	changes := make([]model.VersionChange, 0)
+	for i, entity := range versionedEntities {
+		for _, entityID := range changedIDs(before[i], after[i]) {
+			change := model.VersionChange{
+				Entity:   entity.name,
+				EntityID: entityID,
+				Action:   model.AuditUpdate,
+				Before:   before[i][entityID],
+				After:    after[i][entityID],
+			}
+			switch {
+			case change.Before == "":
+				change.Action = model.AuditCreate
+			case change.After == "":
+				change.Action = model.AuditDelete
+			}
+
+			if change.After != "" && entity.bump != "" {
+				if _, err := tx.ExecContext(ctx, entity.bump, change.Before, entityID); err != nil {
+					r.logger.Error().Err(err).Msg("Error occurred while bumping version of restored entity")
+					return nil, errs.RepositoryUnknownError
+				}
+				if change.After, err = snapshot(ctx, tx, entity.snapshot, entityID); err != nil {
+					r.logger.Error().Err(err).Msg("Error occurred while selecting restored entity")
+					return nil, errs.RepositoryUnknownError
+				}
+			}
+
+			err = audit(ctx, tx, model.AuditEntry{
+				Entity:   change.Entity,
+				EntityID: change.EntityID,
+				Action:   change.Action,
+				Before:   change.Before,
+				After:    change.After,
+			})
+			if err != nil {
+				r.logger.Error().Err(err).Msg("Error occurred while auditing restored entity")
+				return nil, errs.RepositoryUnknownError
+			}
+			changes = append(changes, change)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while committing transaction")
+		return nil, errs.RepositoryUnknownError
+	}
+	return changes, nil
+}
+
+// entityStates returns the states of all the versioned entities by their ids, in the order of versionedEntities.
+func entityStates(ctx context.Context, tx *sqlx.Tx) ([]map[int]string, error) {
+	states := make([]map[int]string, 0, len(versionedEntities))
+	for _, entity := range versionedEntities {
+		byID := make(map[int]string)
+		if entity.ids == "" {
+			state, err := snapshot(ctx, tx, entity.snapshot)
+			if err != nil {
+				return nil, err
+			}
+			byID[0] = state
+			states = append(states, byID)
+			continue
+		}
+
+		ids := make([]int, 0)
+		if err := tx.SelectContext(ctx, &ids, entity.ids); err != nil {
+			return nil, err
+		}
+		entityStates, err := snapshots(ctx, tx, entity.snapshot, ids)
+		if err != nil {
+			return nil, err
+		}
+		for i, id := range ids {
+			byID[id] = entityStates[i]
+		}
+		states = append(states, byID)
+	}
+	return states, nil
+}
+
+// changedIDs returns the ordered ids of the entities whose states differ.
+func changedIDs(before, after map[int]string) []int {
+	ids := make([]int, 0)
+	for id, state := range before {
+		if after[id] != state {
+			ids = append(ids, id)
+		}
+	}
+	for id := range after {
+		if _, ok := before[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// Approve records the approval of the draft in the state by the user of the actor in the context.
+func (r *VersionRepository) Approve(ctx context.Context, state string) error {
+	cmd := `INSERT INTO draft_approvals (user, state, approved_at)
+			VALUES (?, ?, CURRENT_TIMESTAMP)
+			ON CONFLICT (user, state) DO NOTHING`
+	if _, err := r.db.ExecContext(ctx, cmd, model.ActorFromContext(ctx).User, state); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while approving draft")
+		return errs.RepositoryUnknownError
+	}
+	return nil
+}
+
+// GetApprovers returns the users who have approved the draft in the state, in the order of their approvals.
+func (r *VersionRepository) GetApprovers(ctx context.Context, state string) ([]string, error) {
+	query := `SELECT user FROM draft_approvals WHERE state = ? ORDER BY approved_at, user`
+
+	approvers := make([]string, 0)
+	if err := r.db.SelectContext(ctx, &approvers, query, state); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while getting draft approvers")
+		return nil, errs.RepositoryUnknownError
+	}
+	return approvers, nil
+}
+
+// DeleteApprovals deletes the approvals of all the drafts, once the draft is either published or discarded.
+func (r *VersionRepository) DeleteApprovals(ctx context.Context) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM draft_approvals`); err != nil {
+		r.logger.Error().Err(err).Msg("Error occurred while deleting draft approvals")
+		return errs.RepositoryUnknownError
+	}
+	return nil
+}
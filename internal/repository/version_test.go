@@ -0,0 +1,301 @@
+package repository
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-playground/assert/v2"
+	"github.com/jmoiron/sqlx"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/nnemirovsky/pacgen/pkg/logutil"
+	"testing"
+	"time"
+)
+
+func testPrepareVersionRepository(t *testing.T) (*VersionRepository, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	dbx := sqlx.NewDb(db, "sqlmock")
+	repo := NewVersionRepository(dbx, logutil.DiscardLogger)
+
+	return repo, mock
+}
+
+// expectIDs expects the ids of the entities to be selected from the table.
+func expectIDs(mock sqlmock.Sqlmock, table string, ids ...int) {
+	rows := sqlmock.NewRows([]string{"id"})
+	for _, id := range ids {
+		rows.AddRow(id)
+	}
+	mock.ExpectQuery(`SELECT id FROM ` + table + ` ORDER BY id$`).WillReturnRows(rows)
+}
+
+func TestVersionRepository_GetAll_OK(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareVersionRepository(t)
+
+	createdAt := time.Date(2023, 6, 6, 10, 0, 0, 0, time.UTC)
+
+	mock.
+		ExpectQuery(`SELECT id, json_array_length\(state, '\$\.rules'\) AS rule_count, `+
+			`json_array_length\(state, '\$\.proxy_profiles'\) AS profile_count, created_at FROM config_versions `+
+			`ORDER BY id DESC LIMIT \? OFFSET \?$`).
+		WithArgs(10, 0).
+		WillReturnRows(
+			sqlmock.
+				NewRows([]string{"id", "rule_count", "profile_count", "created_at"}).
+				AddRow(4, 12, 3, createdAt),
+		)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	got, err := repo.GetAll(ctx, model.Page{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, got, []model.ConfigVersion{{ID: 4, RuleCount: 12, ProfileCount: 3, CreatedAt: createdAt}})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVersionRepository_GetByID_NotFound(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareVersionRepository(t)
+
+	mock.
+		ExpectQuery(`SELECT id, .*, state, pac, created_at FROM config_versions WHERE id = \?$`).
+		WithArgs(9).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "rule_count", "profile_count", "state", "pac", "created_at"}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := repo.GetByID(ctx, 9)
+
+	assert.Equal(t, err, &errs.EntityNotFoundError{Name: "configuration version", Key: "id", Value: 9})
+}
+
+func TestVersionRepository_GetLatest_NotFound(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareVersionRepository(t)
+
+	mock.
+		ExpectQuery(`SELECT id, .*, state, pac, created_at FROM config_versions ORDER BY id DESC LIMIT 1$`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "rule_count", "profile_count", "state", "pac", "created_at"}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := repo.GetLatest(ctx)
+
+	assert.Equal(t, err, &errs.EntityNotFoundError{Name: "configuration version"})
+}
+
+func TestVersionRepository_Snapshot_OK(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareVersionRepository(t)
+
+	state := `{"proxy_profiles":[],"domain_lists":[],"rules":[],"bypass":{"entries":[]}}`
+	mock.
+		ExpectQuery(`SELECT json_object\( 'proxy_profiles', .* 'bypass', .*\)$`).
+		WillReturnRows(sqlmock.NewRows([]string{"state"}).AddRow(state))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	got, err := repo.Snapshot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, got, state)
+}
+
+func TestVersionRepository_Save_OK(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareVersionRepository(t)
+
+	state := `{"proxy_profiles":[],"domain_lists":[],"rules":[],"bypass":{"entries":[]}}`
+	pac := "function FindProxyForURL(url, host) {}"
+
+	mock.ExpectBegin()
+	mock.
+		ExpectExec(`INSERT INTO config_versions \(state, pac, created_at\) SELECT \?, \?, CURRENT_TIMESTAMP `+
+			`WHERE NOT EXISTS \(SELECT 1 FROM \(SELECT state FROM config_versions ORDER BY id DESC LIMIT 1\) `+
+			`WHERE state = \?\)$`).
+		WithArgs(state, pac, state).
+		WillReturnResult(sqlmock.NewResult(5, 1))
+	mock.
+		ExpectExec(`DELETE FROM config_versions WHERE id <= ` +
+			`\(SELECT id FROM config_versions ORDER BY id DESC LIMIT 1 OFFSET \?\)$`).
+		WithArgs(50).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := repo.Save(ctx, state, pac, 50); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVersionRepository_Restore_OK(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareVersionRepository(t)
+
+	state := `{"proxy_profiles":[{"id":1,"version":2}],"domain_lists":[],"rules":[{"id":2,"version":1}],` +
+		`"bypass":{"entries":[]}}`
+
+	mock.ExpectBegin()
+	mock.
+		ExpectQuery(`SELECT state FROM config_versions WHERE id = \?$`).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"state"}).AddRow(state))
+
+	expectIDs(mock, "proxy_profiles", 1)
+	expectSnapshot(mock, `proxy_profiles p WHERE p.id = \?`, `{"id":1,"version":3}`, 1)
+	expectIDs(mock, "domain_lists")
+	expectIDs(mock, "rules")
+	expectSnapshot(mock, `\(SELECT kind, value FROM bypass_entries ORDER BY kind, value\) b$`, `{"entries":[]}`)
+
+	for _, table := range []string{"rules", "profile_pool_members", "proxy_profiles", "domain_lists", "bypass_entries"} {
+		mock.ExpectExec(`DELETE FROM ` + table + `$`).WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	restored := []string{
+		"proxy_profiles", "profile_pool_members", "domain_lists", "domain_list_entries", "rules", "tags", "rule_tags",
+		"bypass_entries",
+	}
+	for _, table := range restored {
+		mock.ExpectExec(`INSERT INTO ` + table + ` \(`).WithArgs(state).WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+
+	expectIDs(mock, "proxy_profiles", 1)
+	expectSnapshot(mock, `proxy_profiles p WHERE p.id = \?`, `{"id":1,"version":2}`, 1)
+	expectIDs(mock, "domain_lists")
+	expectIDs(mock, "rules", 2)
+	expectSnapshot(mock, `rules r WHERE r.id = \?`, `{"id":2,"version":1}`, 2)
+	expectSnapshot(mock, `\(SELECT kind, value FROM bypass_entries ORDER BY kind, value\) b$`, `{"entries":[]}`)
+
+	mock.
+		ExpectExec(`UPDATE proxy_profiles SET version = max\(version, .*\) \+ 1, updated_at = CURRENT_TIMESTAMP `+
+			`WHERE id = \?$`).
+		WithArgs(`{"id":1,"version":3}`, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectSnapshot(mock, `proxy_profiles p WHERE p.id = \?`, `{"id":1,"version":4}`, 1)
+	expectAudit(mock, model.AuditProxyProfile, 1, model.AuditUpdate)
+	mock.
+		ExpectExec(`UPDATE rules SET version = max\(version, .*\) \+ 1, updated_at = CURRENT_TIMESTAMP WHERE id = \?$`).
+		WithArgs("", 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectSnapshot(mock, `rules r WHERE r.id = \?`, `{"id":2,"version":2}`, 2)
+	expectAudit(mock, model.AuditRule, 2, model.AuditCreate)
+	mock.ExpectCommit()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	got, err := repo.Restore(ctx, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []model.VersionChange{
+		{
+			Entity:   model.AuditProxyProfile,
+			EntityID: 1,
+			Action:   model.AuditUpdate,
+			Before:   `{"id":1,"version":3}`,
+			After:    `{"id":1,"version":4}`,
+		},
+		{Entity: model.AuditRule, EntityID: 2, Action: model.AuditCreate, After: `{"id":2,"version":2}`},
+	}
+
+	assert.Equal(t, got, want)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVersionRepository_Restore_NotFound(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareVersionRepository(t)
+
+	mock.ExpectBegin()
+	mock.
+		ExpectQuery(`SELECT state FROM config_versions WHERE id = \?$`).
+		WithArgs(9).
+		WillReturnRows(sqlmock.NewRows([]string{"state"}))
+	mock.ExpectRollback()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := repo.Restore(ctx, 9)
+
+	assert.Equal(t, err, &errs.EntityNotFoundError{Name: "configuration version", Key: "id", Value: 9})
+}
+
+func TestVersionRepository_Approve_OK(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareVersionRepository(t)
+
+	state := `{"proxy_profiles":[],"domain_lists":[],"rules":[],"bypass":{"entries":[]}}`
+	mock.
+		ExpectExec(`INSERT INTO draft_approvals \(user, state, approved_at\) VALUES \(\?, \?, CURRENT_TIMESTAMP\) `+
+			`ON CONFLICT \(user, state\) DO NOTHING$`).
+		WithArgs("alice", state).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := repo.Approve(model.WithActor(ctx, model.Actor{User: "alice"}), state); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVersionRepository_GetApprovers_OK(t *testing.T) {
+	t.Parallel()
+
+	repo, mock := testPrepareVersionRepository(t)
+
+	mock.
+		ExpectQuery(`SELECT user FROM draft_approvals WHERE state = \? ORDER BY approved_at, user$`).
+		WithArgs("{}").
+		WillReturnRows(sqlmock.NewRows([]string{"user"}).AddRow("alice").AddRow("bob"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	got, err := repo.GetApprovers(ctx, "{}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, got, []string{"alice", "bob"})
+}
@@ -0,0 +1,119 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/nnemirovsky/pacgen/pkg/rest"
+	"github.com/rs/zerolog/hlog"
+	"net/http"
+	"strings"
+)
+
+// Authenticator returns the user with the name and the password of basic auth, errs.InvalidCredentialsError
+// if there is none.
+type Authenticator interface {
+	Authenticate(ctx context.Context, name, password string) (model.User, error)
+}
+
+// TokenAuthenticator returns the API token of Authorization: Bearer header, errs.InvalidCredentialsError if there
+// is no such token or it has expired.
+type TokenAuthenticator interface {
+	AuthenticateToken(ctx context.Context, token string) (model.APIToken, error)
+}
+
+// authenticate rejects the requests without valid basic auth credentials or API token. The authenticated user,
+// the request id and the remote address are the actor of the changes made by the request, so they are recorded
+// in the audit log, and the role of the user along with the scopes of the token are authorized by the route groups.
+func authenticate(
+	authenticator Authenticator, tokenAuthenticator TokenAuthenticator,
+) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var (
+				actor model.Actor
+				err   error
+			)
+			scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+			if strings.EqualFold(scheme, "Bearer") {
+				var apiToken model.APIToken
+				if apiToken, err = tokenAuthenticator.AuthenticateToken(r.Context(), token); err == nil {
+					actor = apiToken.Actor()
+				}
+			} else if name, password, ok := r.BasicAuth(); ok {
+				var user model.User
+				if user, err = authenticator.Authenticate(r.Context(), name, password); err == nil {
+					actor = model.Actor{User: user.Name, UserID: user.ID, Role: user.Role}
+				}
+			} else {
+				unauthorized(w, r, "Basic auth credentials or API token are required")
+				return
+			}
+			if err != nil {
+				if errors.Is(err, errs.InvalidCredentialsError) {
+					unauthorized(w, r, err.Error())
+					return
+				}
+				hlog.FromRequest(r).Error().Err(err).Msg("Error occurred while authenticating request")
+				writeProblem(w, r, rest.InternalErrorResponse())
+				return
+			}
+
+			actor.RemoteAddr = r.RemoteAddr
+			if id, ok := hlog.IDFromRequest(r); ok {
+				actor.RequestID = id.String()
+			}
+			next.ServeHTTP(w, r.WithContext(model.WithActor(r.Context(), actor)))
+		})
+	}
+}
+
+// authorize lets GET and HEAD requests through for the users with the read role or a higher one, and the other
+// requests for the users with the write role or a higher one. Requests authenticated with API tokens also need
+// the read or the write scope of the resource.
+func authorize(resource string, read, write model.Role) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			required, access := write, model.ScopeWrite
+			if r.Method == http.MethodGet || r.Method == http.MethodHead {
+				required, access = read, model.ScopeRead
+			}
+
+			actor := model.ActorFromContext(r.Context())
+			if actor.Role < required {
+				hlog.FromRequest(r).Debug().
+					Str("user", actor.User).
+					Stringer("role", actor.Role).
+					Stringer("required-role", required).
+					Msg("Request is forbidden")
+				writeProblem(w, r, rest.ForbiddenResponse("The request requires the role of "+required.String()))
+				return
+			}
+			if actor.Scopes != nil && !actor.Scopes.Allows(resource, access == model.ScopeWrite) {
+				scope := resource + ":" + access
+				hlog.FromRequest(r).Debug().
+					Str("user", actor.User).
+					Strs("scopes", actor.Scopes).
+					Str("required-scope", scope).
+					Msg("Request is forbidden")
+				writeProblem(w, r, rest.ForbiddenResponse("The request requires the scope "+scope))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request, detail string) {
+	w.Header().Add("WWW-Authenticate", `Basic realm="/"`)
+	w.Header().Add("WWW-Authenticate", `Bearer realm="/"`)
+	writeProblem(w, r, rest.UnauthorizedResponse(detail))
+}
+
+// writeProblem renders the problem, falling back to the bare status code if it cannot be rendered.
+func writeProblem(w http.ResponseWriter, r *http.Request, v rest.Problem) {
+	if err := rest.RenderProblem(w, r, v); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,111 @@
+package router
+
+import (
+	"context"
+	"github.com/go-playground/assert/v2"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type testAuthenticator map[string]model.User
+
+func (a testAuthenticator) Authenticate(_ context.Context, name, password string) (model.User, error) {
+	user, ok := a[name]
+	if !ok || password != "secret" {
+		return model.User{}, errs.InvalidCredentialsError
+	}
+	return user, nil
+}
+
+type testTokenAuthenticator map[string]model.APIToken
+
+func (a testTokenAuthenticator) AuthenticateToken(_ context.Context, token string) (model.APIToken, error) {
+	apiToken, ok := a[token]
+	if !ok {
+		return model.APIToken{}, errs.InvalidCredentialsError
+	}
+	return apiToken, nil
+}
+
+func TestAuth(t *testing.T) {
+	t.Parallel()
+
+	authenticator := testAuthenticator{
+		"viewer": {Name: "viewer", Role: model.Viewer},
+		"editor": {Name: "editor", Role: model.Editor},
+	}
+	editor := model.APIToken{UserID: 1, UserName: "editor", UserRole: model.Editor}
+	reader, writer := editor, editor
+	reader.Scopes, writer.Scopes = model.Scopes{"rules:read"}, model.Scopes{"rules:write"}
+	tokenAuthenticator := testTokenAuthenticator{
+		"reader": reader,
+		"writer": writer,
+		"other":  {Name: "other", Scopes: model.Scopes{"profiles:write"}},
+		"ci":     {Name: "ci", Scopes: model.Scopes{"pac:read", "rules:write"}},
+	}
+	var actor model.Actor
+	handler := authenticate(authenticator, tokenAuthenticator)(authorize("rules", model.Viewer, model.Editor)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			actor = model.ActorFromContext(r.Context())
+		}),
+	))
+
+	data := map[string]struct {
+		method   string
+		user     string
+		password string
+		token    string
+		actor    string
+		want     int
+	}{
+		"no credentials":        {method: http.MethodGet, want: http.StatusUnauthorized},
+		"wrong password":        {method: http.MethodGet, user: "viewer", password: "guess", want: http.StatusUnauthorized},
+		"viewer reads":          {method: http.MethodGet, user: "viewer", password: "secret", want: http.StatusOK},
+		"viewer writes":         {method: http.MethodPost, user: "viewer", password: "secret", want: http.StatusForbidden},
+		"editor writes":         {method: http.MethodDelete, user: "editor", password: "secret", want: http.StatusOK},
+		"unknown user, no read": {method: http.MethodGet, user: "admin", password: "secret", want: http.StatusUnauthorized},
+		"unknown token":         {method: http.MethodGet, token: "guess", want: http.StatusUnauthorized},
+		"read scope reads":      {method: http.MethodGet, token: "reader", actor: "editor", want: http.StatusOK},
+		"read scope writes":     {method: http.MethodPut, token: "reader", want: http.StatusForbidden},
+		"write scope writes":    {method: http.MethodPut, token: "writer", actor: "editor", want: http.StatusOK},
+		"other scope reads":     {method: http.MethodGet, token: "other", want: http.StatusForbidden},
+		"service token writes":  {method: http.MethodPost, token: "ci", actor: "token:ci", want: http.StatusOK},
+	}
+
+	for name, d := range data {
+		d := d
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(d.method, "/rules", nil)
+			if err != nil {
+				t.Errorf("Unexpected error: %#v", err)
+			}
+			if d.user != "" {
+				req.SetBasicAuth(d.user, d.password)
+			}
+			if d.token != "" {
+				req.Header.Set("Authorization", "Bearer "+d.token)
+			}
+
+			actor = model.Actor{}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, rr.Code, d.want)
+			switch d.want {
+			case http.StatusUnauthorized:
+				assert.Equal(t, rr.Header().Values("WWW-Authenticate"), []string{`Basic realm="/"`, `Bearer realm="/"`})
+			case http.StatusOK:
+				if d.token != "" {
+					assert.Equal(t, actor.User, d.actor)
+					assert.Equal(t, actor.Scopes, tokenAuthenticator[d.token].Scopes)
+					break
+				}
+				assert.Equal(t, actor.User, d.user)
+				assert.Equal(t, actor.Role, authenticator[d.user].Role)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
+	"github.com/nnemirovsky/pacgen/internal/model"
 	"github.com/nnemirovsky/pacgen/pkg/rest"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/hlog"
@@ -13,10 +14,17 @@ import (
 
 type RuleHandler interface {
 	GetAll(w http.ResponseWriter, r *http.Request)
+	GetAllByProfile(w http.ResponseWriter, r *http.Request)
 	GetByID(w http.ResponseWriter, r *http.Request)
 	Create(w http.ResponseWriter, r *http.Request)
 	Update(w http.ResponseWriter, r *http.Request)
+	Patch(w http.ResponseWriter, r *http.Request)
 	Delete(w http.ResponseWriter, r *http.Request)
+	BulkUpdate(w http.ResponseWriter, r *http.Request)
+	BulkDelete(w http.ResponseWriter, r *http.Request)
+	Lint(w http.ResponseWriter, r *http.Request)
+	Expiring(w http.ResponseWriter, r *http.Request)
+	Match(w http.ResponseWriter, r *http.Request)
 }
 
 type ProxyProfileHandler interface {
@@ -24,6 +32,56 @@ type ProxyProfileHandler interface {
 	GetByID(w http.ResponseWriter, r *http.Request)
 	Create(w http.ResponseWriter, r *http.Request)
 	Update(w http.ResponseWriter, r *http.Request)
+	Patch(w http.ResponseWriter, r *http.Request)
+	Delete(w http.ResponseWriter, r *http.Request)
+}
+
+type ProfileHealthHandler interface {
+	Serve(w http.ResponseWriter, r *http.Request)
+}
+
+type DomainListHandler interface {
+	GetAll(w http.ResponseWriter, r *http.Request)
+	GetByID(w http.ResponseWriter, r *http.Request)
+	Create(w http.ResponseWriter, r *http.Request)
+	Update(w http.ResponseWriter, r *http.Request)
+	Delete(w http.ResponseWriter, r *http.Request)
+}
+
+type BypassHandler interface {
+	Get(w http.ResponseWriter, r *http.Request)
+	Update(w http.ResponseWriter, r *http.Request)
+	Import(w http.ResponseWriter, r *http.Request)
+}
+
+type AuditHandler interface {
+	GetAll(w http.ResponseWriter, r *http.Request)
+}
+
+type VersionHandler interface {
+	GetAll(w http.ResponseWriter, r *http.Request)
+	Diff(w http.ResponseWriter, r *http.Request)
+	Rollback(w http.ResponseWriter, r *http.Request)
+}
+
+type DraftHandler interface {
+	Get(w http.ResponseWriter, r *http.Request)
+	Approve(w http.ResponseWriter, r *http.Request)
+	Publish(w http.ResponseWriter, r *http.Request)
+	Discard(w http.ResponseWriter, r *http.Request)
+}
+
+type UserHandler interface {
+	GetAll(w http.ResponseWriter, r *http.Request)
+	GetByID(w http.ResponseWriter, r *http.Request)
+	Create(w http.ResponseWriter, r *http.Request)
+	Update(w http.ResponseWriter, r *http.Request)
+	Delete(w http.ResponseWriter, r *http.Request)
+}
+
+type TokenHandler interface {
+	GetAll(w http.ResponseWriter, r *http.Request)
+	Create(w http.ResponseWriter, r *http.Request)
 	Delete(w http.ResponseWriter, r *http.Request)
 }
 
@@ -31,12 +89,33 @@ type PACFileHandler interface {
 	Serve(w http.ResponseWriter, r *http.Request)
 }
 
+type ExportHandler interface {
+	Serve(w http.ResponseWriter, r *http.Request)
+}
+
+type ProvisioningHandler interface {
+	AppleMobileConfig(w http.ResponseWriter, r *http.Request)
+	WindowsRegistry(w http.ResponseWriter, r *http.Request)
+}
+
 func New(
 	ruleHandler RuleHandler,
 	profileHandler ProxyProfileHandler,
+	healthHandler ProfileHealthHandler,
+	listHandler DomainListHandler,
+	bypassHandler BypassHandler,
+	auditHandler AuditHandler,
+	versionHandler VersionHandler,
+	draftHandler DraftHandler,
+	userHandler UserHandler,
+	tokenHandler TokenHandler,
 	pacFileHandler PACFileHandler,
+	exportHandler ExportHandler,
+	provisioningHandler ProvisioningHandler,
+	idempotency func(next http.Handler) http.Handler,
 	logger zerolog.Logger,
-	basicAuthCreds map[string]string,
+	authenticator Authenticator,
+	tokenAuthenticator TokenAuthenticator,
 ) http.Handler {
 	router := chi.NewRouter()
 
@@ -55,29 +134,98 @@ func New(
 	router.Use(rest.Recoverer)
 	router.Use(middleware.RedirectSlashes)
 	router.Use(middleware.Heartbeat("/ping"))
-	router.Use(middleware.AllowContentType("application/json"))
+	router.Use(middleware.AllowContentType("application/json", "application/merge-patch+json"))
 	router.Use(render.SetContentType(render.ContentTypeJSON))
 	router.Use(rest.ValidateJSONBody)
 
 	router.Route("/api/v1", func(r chi.Router) {
-		r.Use(middleware.BasicAuth("/", basicAuthCreds))
+		r.Use(authenticate(authenticator, tokenAuthenticator))
+		// group authorizes the roles of the route group, ahead of idempotency so responses are not replayed
+		// to the users who are not allowed to make the request.
+		group := func(r chi.Router, resource string, read, write model.Role) {
+			r.Use(authorize(resource, read, write))
+			if idempotency != nil {
+				r.Use(idempotency)
+			}
+		}
 		r.Route("/rules", func(r chi.Router) {
+			group(r, "rules", model.Viewer, model.Editor)
 			r.Get("/", ruleHandler.GetAll)
+			r.Get("/lint", ruleHandler.Lint)
+			r.Get("/expiring", ruleHandler.Expiring)
+			r.Get("/match", ruleHandler.Match)
 			r.Get("/{id}", ruleHandler.GetByID)
 			r.Post("/", ruleHandler.Create)
 			r.Put("/{id}", ruleHandler.Update)
+			r.Patch("/{id}", ruleHandler.Patch)
 			r.Delete("/{id}", ruleHandler.Delete)
+			r.Patch("/", ruleHandler.BulkUpdate)
+			r.Delete("/", ruleHandler.BulkDelete)
+		})
+		r.Route("/domain-lists", func(r chi.Router) {
+			group(r, "rules", model.Viewer, model.Editor)
+			r.Get("/", listHandler.GetAll)
+			r.Get("/{id}", listHandler.GetByID)
+			r.Post("/", listHandler.Create)
+			r.Put("/{id}", listHandler.Update)
+			r.Delete("/{id}", listHandler.Delete)
 		})
 		r.Route("/profiles", func(r chi.Router) {
+			group(r, "profiles", model.Viewer, model.Admin)
 			r.Get("/", profileHandler.GetAll)
 			r.Get("/{id}", profileHandler.GetByID)
 			r.Post("/", profileHandler.Create)
 			r.Put("/{id}", profileHandler.Update)
+			r.Patch("/{id}", profileHandler.Patch)
 			r.Delete("/{id}", profileHandler.Delete)
+			r.Get("/{id}/health", healthHandler.Serve)
+			r.Get("/{id}/rules", ruleHandler.GetAllByProfile)
+		})
+		r.Route("/bypass", func(r chi.Router) {
+			group(r, "profiles", model.Viewer, model.Admin)
+			r.Get("/", bypassHandler.Get)
+			r.Put("/", bypassHandler.Update)
+			r.Post("/import", bypassHandler.Import)
+		})
+		r.With(authorize("audit", model.Viewer, model.Viewer)).Get("/audit", auditHandler.GetAll)
+		r.Route("/versions", func(r chi.Router) {
+			group(r, "versions", model.Viewer, model.Admin)
+			r.Get("/", versionHandler.GetAll)
+			r.Get("/{id}/diff", versionHandler.Diff)
+			r.Post("/{id}/rollback", versionHandler.Rollback)
+		})
+		if draftHandler != nil {
+			r.Route("/draft", func(r chi.Router) {
+				group(r, "versions", model.Viewer, model.Admin)
+				r.Get("/", draftHandler.Get)
+				r.Post("/approve", draftHandler.Approve)
+				r.Post("/publish", draftHandler.Publish)
+				r.Post("/discard", draftHandler.Discard)
+			})
+		}
+		r.Route("/users", func(r chi.Router) {
+			group(r, "users", model.Admin, model.Admin)
+			r.Get("/", userHandler.GetAll)
+			r.Get("/{id}", userHandler.GetByID)
+			r.Post("/", userHandler.Create)
+			r.Put("/{id}", userHandler.Update)
+			r.Delete("/{id}", userHandler.Delete)
+		})
+		r.Route("/tokens", func(r chi.Router) {
+			group(r, "tokens", model.Viewer, model.Viewer)
+			r.Get("/", tokenHandler.GetAll)
+			r.Post("/", tokenHandler.Create)
+			r.Delete("/{id}", tokenHandler.Delete)
+		})
+		r.Route("/provisioning", func(r chi.Router) {
+			group(r, "pac", model.Viewer, model.Viewer)
+			r.Get("/apple.mobileconfig", provisioningHandler.AppleMobileConfig)
+			r.Get("/windows.reg", provisioningHandler.WindowsRegistry)
 		})
 	})
 
 	router.Get("/proxy.pac", pacFileHandler.Serve)
+	router.Get("/export/{name}", exportHandler.Serve)
 
 	return router
 }
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/rs/zerolog"
+	"time"
+)
+
+// AuditService reads the audit log, the changes are recorded by the repositories along with the changes.
+type AuditService struct {
+	logger zerolog.Logger
+	repo   AuditRepository
+}
+
+func NewAuditService(repo AuditRepository, logger zerolog.Logger) *AuditService {
+	return &AuditService{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+// GetAll returns the page of the audit log entries matching the filter along with the number of all the matching
+// ones.
+func (s *AuditService) GetAll(ctx context.Context, filter model.AuditFilter) ([]model.AuditEntry, int, error) {
+	entries, err := s.repo.GetAll(ctx, filter)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while getting audit log entries")
+		return nil, 0, errs.ServiceUnknownError
+	}
+	if filter.Page == (model.Page{}) {
+		return entries, len(entries), nil
+	}
+
+	total, err := s.repo.Count(ctx, filter)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while counting audit log entries")
+		return nil, 0, errs.ServiceUnknownError
+	}
+	return entries, total, nil
+}
+
+// AuditPruneOptions configures the audit log pruner.
+type AuditPruneOptions struct {
+	// Retention is how long the entries are kept for.
+	Retention time.Duration
+	// Interval is the time between the prunes.
+	Interval time.Duration
+}
+
+// AuditPruner deletes the audit log entries which are past the retention.
+type AuditPruner struct {
+	logger zerolog.Logger
+	repo   AuditRepository
+	opts   AuditPruneOptions
+}
+
+func NewAuditPruner(repo AuditRepository, opts AuditPruneOptions, logger zerolog.Logger) *AuditPruner {
+	return &AuditPruner{
+		logger: logger,
+		repo:   repo,
+		opts:   opts,
+	}
+}
+
+// Run prunes the audit log at once and then every interval until the context is done.
+func (p *AuditPruner) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		p.Prune(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Prune deletes the audit log entries recorded before the retention.
+func (p *AuditPruner) Prune(ctx context.Context) {
+	count, err := p.repo.DeleteBefore(ctx, time.Now().Add(-p.opts.Retention))
+	if err != nil {
+		p.logger.Error().Err(err).Msg("Error occurred while pruning audit log")
+		return
+	}
+	if count > 0 {
+		p.logger.Info().Int("count", count).Msg("Audit log entries past retention deleted")
+	}
+}
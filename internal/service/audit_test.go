@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/nnemirovsky/pacgen/internal/service/mock"
+	"github.com/nnemirovsky/pacgen/pkg/logutil"
+	"testing"
+	"time"
+)
+
+func TestAuditService_GetAll_Paged(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	repoMock := mock.NewAuditRepository(ctrl)
+	auditSrvc := NewAuditService(repoMock, logutil.DiscardLogger)
+
+	want := []model.AuditEntry{{ID: 3, Actor: "admin", Entity: model.AuditRule, EntityID: 1, Action: model.AuditCreate}}
+	filter := model.AuditFilter{Entity: model.AuditRule, Page: model.Page{Limit: 1}}
+
+	repoMock.EXPECT().GetAll(gomock.Any(), filter).Return(want, nil)
+	repoMock.EXPECT().Count(gomock.Any(), filter).Return(3, nil)
+
+	got, total, err := auditSrvc.GetAll(context.Background(), filter)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	assert.Equal(t, got, want)
+	assert.Equal(t, total, 3)
+}
+
+func TestAuditPruner_Prune(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	repoMock := mock.NewAuditRepository(ctrl)
+	pruner := NewAuditPruner(
+		repoMock,
+		AuditPruneOptions{Retention: 24 * time.Hour, Interval: time.Hour},
+		logutil.DiscardLogger,
+	)
+
+	start := time.Now()
+	repoMock.EXPECT().
+		DeleteBefore(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, before time.Time) (int, error) {
+			if before.After(start.Add(-24*time.Hour+time.Minute)) || before.Before(start.Add(-25*time.Hour)) {
+				t.Errorf("Unexpected prune time: %v", before)
+			}
+			return 2, nil
+		})
+
+	pruner.Prune(context.Background())
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/rs/zerolog"
+	"time"
+)
+
+type BypassService struct {
+	logger  zerolog.Logger
+	repo    BypassRepository
+	pacSrvc pacService
+}
+
+func NewBypassService(repo BypassRepository, pacSrvc pacService, logger zerolog.Logger) *BypassService {
+	return &BypassService{
+		logger:  logger,
+		repo:    repo,
+		pacSrvc: pacSrvc,
+	}
+}
+
+func (s *BypassService) GetAll(ctx context.Context) ([]model.BypassEntry, error) {
+	entries, err := s.repo.GetAll(ctx)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while getting bypass entries")
+		return nil, errs.ServiceUnknownError
+	}
+	return entries, nil
+}
+
+// Replace replaces the bypass list with the given entries.
+func (s *BypassService) Replace(ctx context.Context, entries []model.BypassEntry) error {
+	if err := s.repo.Replace(ctx, entries); err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while replacing bypass entries")
+		return errs.ServiceUnknownError
+	}
+
+	s.logger.Debug().Int("entries", len(entries)).Msg("Bypass list replaced")
+
+	s.generatePACFile()
+
+	return nil
+}
+
+// Import adds the entries to the bypass list and returns the resulting list. Entries already in the list are skipped.
+func (s *BypassService) Import(ctx context.Context, entries []model.BypassEntry) ([]model.BypassEntry, error) {
+	current, err := s.repo.GetAll(ctx)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while getting bypass entries to import into")
+		return nil, errs.ServiceUnknownError
+	}
+
+	seen := make(map[model.BypassEntry]struct{}, len(current))
+	for _, entry := range current {
+		seen[entry] = struct{}{}
+	}
+	merged := current
+	for _, entry := range entries {
+		if _, ok := seen[entry]; !ok {
+			seen[entry] = struct{}{}
+			merged = append(merged, entry)
+		}
+	}
+
+	if len(merged) == len(current) {
+		return current, nil
+	}
+	if err := s.Replace(ctx, merged); err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+func (s *BypassService) generatePACFile() {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.pacSrvc.GeneratePACFile(ctx); err != nil {
+			s.logger.Error().Err(err).Msg("Error occurred while generating pac file after changing bypass list")
+			return
+		}
+		s.logger.Debug().Msg("Pac file generated after changing bypass list")
+	}()
+}
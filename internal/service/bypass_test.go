@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/nnemirovsky/pacgen/internal/service/mock"
+	"github.com/nnemirovsky/pacgen/pkg/logutil"
+	"testing"
+)
+
+func testPrepareBypassService(t *testing.T) (*BypassService, *mock.BypassRepository, *mock.PacService) {
+	ctrl := gomock.NewController(t)
+	repoMock := mock.NewBypassRepository(ctrl)
+	pacSrvcMock := mock.NewPacService(ctrl)
+
+	return NewBypassService(repoMock, pacSrvcMock, logutil.DiscardLogger), repoMock, pacSrvcMock
+}
+
+func TestBypassService_Replace_OK(t *testing.T) {
+	t.Parallel()
+
+	bypassSrvc, repoMock, pacSrvcMock := testPrepareBypassService(t)
+
+	entries := []model.BypassEntry{{Kind: model.BypassPreset, Value: model.PresetPrivateNetworks}}
+
+	generated := make(chan struct{})
+
+	repoMock.EXPECT().Replace(gomock.Any(), entries).Return(nil)
+	pacSrvcMock.EXPECT().GeneratePACFile(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		close(generated)
+		return nil
+	})
+
+	err := bypassSrvc.Replace(context.Background(), entries)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	<-generated
+}
+
+func TestBypassService_Import_OK(t *testing.T) {
+	t.Parallel()
+
+	bypassSrvc, repoMock, pacSrvcMock := testPrepareBypassService(t)
+
+	current := []model.BypassEntry{
+		{Kind: model.BypassPreset, Value: model.PresetPlainHostNames},
+		{Kind: model.BypassDomain, Value: "a.com"},
+	}
+	imported := []model.BypassEntry{
+		{Kind: model.BypassDomain, Value: "a.com"},
+		{Kind: model.BypassNetwork, Value: "10.0.0.0/8"},
+	}
+	want := []model.BypassEntry{
+		{Kind: model.BypassPreset, Value: model.PresetPlainHostNames},
+		{Kind: model.BypassDomain, Value: "a.com"},
+		{Kind: model.BypassNetwork, Value: "10.0.0.0/8"},
+	}
+
+	generated := make(chan struct{})
+
+	repoMock.EXPECT().GetAll(gomock.Any()).Return(current, nil)
+	repoMock.EXPECT().Replace(gomock.Any(), want).Return(nil)
+	pacSrvcMock.EXPECT().GeneratePACFile(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		close(generated)
+		return nil
+	})
+
+	got, err := bypassSrvc.Import(context.Background(), imported)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	assert.Equal(t, got, want)
+
+	<-generated
+}
+
+func TestBypassService_Import_NothingNew(t *testing.T) {
+	t.Parallel()
+
+	bypassSrvc, repoMock, _ := testPrepareBypassService(t)
+
+	current := []model.BypassEntry{{Kind: model.BypassDomain, Value: "a.com"}}
+
+	repoMock.EXPECT().GetAll(gomock.Any()).Return(current, nil)
+
+	got, err := bypassSrvc.Import(context.Background(), current)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	assert.Equal(t, got, current)
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/rs/zerolog"
+	"time"
+)
+
+type DomainListService struct {
+	logger  zerolog.Logger
+	repo    DomainListRepository
+	pacSrvc pacService
+}
+
+func NewDomainListService(
+	repo DomainListRepository,
+	pacSrvc pacService,
+	logger zerolog.Logger,
+) *DomainListService {
+	return &DomainListService{
+		logger:  logger,
+		repo:    repo,
+		pacSrvc: pacSrvc,
+	}
+}
+
+func (s *DomainListService) GetAll(ctx context.Context) ([]model.DomainList, error) {
+	lists, err := s.repo.GetAll(ctx)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while getting domain lists")
+		return nil, errs.ServiceUnknownError
+	}
+	return lists, nil
+}
+
+func (s *DomainListService) GetByID(ctx context.Context, id int) (model.DomainList, error) {
+	list, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if _, ok := err.(*errs.EntityNotFoundError); ok {
+			s.logger.Debug().Err(err).Send()
+			return list, err
+		}
+		s.logger.Error().Err(err).Msg("Error occurred while getting domain list by id")
+		return list, errs.ServiceUnknownError
+	}
+	return list, nil
+}
+
+// Create creates the domain list. PAC file is not regenerated, since no rule refers to the new list yet.
+func (s *DomainListService) Create(ctx context.Context, list *model.DomainList) error {
+	err := s.repo.Create(ctx, list)
+	if err != nil {
+		if _, ok := err.(*errs.EntityAlreadyExistsError); ok {
+			s.logger.Debug().Err(err).Send()
+			return err
+		}
+		s.logger.Error().Err(err).Msg("Error occurred while creating domain list")
+		return errs.ServiceUnknownError
+	}
+
+	s.logger.Debug().Int("list-id", list.ID).Msg("Domain list created")
+
+	return nil
+}
+
+func (s *DomainListService) Update(ctx context.Context, list model.DomainList) error {
+	err := s.repo.Update(ctx, list)
+	if err != nil {
+		switch err.(type) {
+		case *errs.EntityNotFoundError:
+			s.logger.Debug().Err(err).Send()
+			return err
+		case *errs.EntityAlreadyExistsError:
+			s.logger.Debug().Err(err).Send()
+			return err
+		default:
+			s.logger.Error().Err(err).Msg("Error occurred while updating domain list")
+			return errs.ServiceUnknownError
+		}
+	}
+
+	s.logger.Debug().Int("list-id", list.ID).Msg("Domain list updated")
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.pacSrvc.GeneratePACFile(ctx); err != nil {
+			s.logger.Error().Err(err).Msg("Error occurred while generating pac file after updating domain list")
+			return
+		}
+		s.logger.Debug().Msg("Pac file generated after updating domain list")
+	}()
+
+	return nil
+}
+
+// Delete deletes the domain list. Lists referenced by rules cannot be deleted, so PAC file stays the same.
+func (s *DomainListService) Delete(ctx context.Context, id int) error {
+	err := s.repo.Delete(ctx, id)
+	if err != nil {
+		switch err.(type) {
+		case *errs.EntityNotFoundError:
+			s.logger.Debug().Err(err).Send()
+			return err
+		case *errs.EntityStillReferencedError:
+			s.logger.Debug().Err(err).Send()
+			return err
+		default:
+			s.logger.Error().Err(err).Msg("Error occurred while deleting domain list")
+			return errs.ServiceUnknownError
+		}
+	}
+
+	s.logger.Debug().Int("list-id", id).Msg("Domain list deleted")
+
+	return nil
+}
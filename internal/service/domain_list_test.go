@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/nnemirovsky/pacgen/internal/service/mock"
+	"github.com/nnemirovsky/pacgen/pkg/logutil"
+	"testing"
+)
+
+func testPrepareDomainListService(t *testing.T) (*DomainListService, *mock.DomainListRepository, *mock.PacService) {
+	ctrl := gomock.NewController(t)
+	repoMock := mock.NewDomainListRepository(ctrl)
+	pacSrvcMock := mock.NewPacService(ctrl)
+
+	return NewDomainListService(repoMock, pacSrvcMock, logutil.DiscardLogger), repoMock, pacSrvcMock
+}
+
+func TestDomainListService_GetAll_OK(t *testing.T) {
+	t.Parallel()
+
+	listSrvc, repoMock, _ := testPrepareDomainListService(t)
+
+	want := []model.DomainList{
+		{
+			ID:   1,
+			Name: "video cdn",
+			Entries: []model.DomainListEntry{
+				{Domain: "googlevideo.com", Mode: model.DomainAndSubdomains},
+				{Domain: "vimeo.com", Mode: model.ExactDomain},
+			},
+		},
+	}
+
+	repoMock.EXPECT().GetAll(gomock.Any()).Return(want, nil)
+
+	got, err := listSrvc.GetAll(context.Background())
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	assert.Equal(t, got, want)
+}
+
+func TestDomainListService_GetByID_NotFound(t *testing.T) {
+	t.Parallel()
+
+	listSrvc, repoMock, _ := testPrepareDomainListService(t)
+
+	repoMock.EXPECT().GetByID(gomock.Any(), 1).Return(model.DomainList{}, &errs.EntityNotFoundError{})
+
+	_, err := listSrvc.GetByID(context.Background(), 1)
+	if _, ok := err.(*errs.EntityNotFoundError); !ok {
+		t.Errorf("expected error is errs.EntityNotFoundError, but got %#v", err)
+	}
+}
+
+func TestDomainListService_Create_AlreadyExists(t *testing.T) {
+	t.Parallel()
+
+	listSrvc, repoMock, _ := testPrepareDomainListService(t)
+
+	list := model.DomainList{Name: "video cdn"}
+
+	repoMock.EXPECT().Create(gomock.Any(), &list).Return(&errs.EntityAlreadyExistsError{})
+
+	err := listSrvc.Create(context.Background(), &list)
+	if _, ok := err.(*errs.EntityAlreadyExistsError); !ok {
+		t.Errorf("expected error is errs.EntityAlreadyExistsError, but got %#v", err)
+	}
+}
+
+func TestDomainListService_Update_OK(t *testing.T) {
+	t.Parallel()
+
+	listSrvc, repoMock, pacSrvcMock := testPrepareDomainListService(t)
+
+	list := model.DomainList{
+		ID:      1,
+		Name:    "video cdn",
+		Entries: []model.DomainListEntry{{Domain: "googlevideo.com", Mode: model.DomainAndSubdomains}},
+	}
+
+	generated := make(chan struct{})
+
+	repoMock.EXPECT().Update(gomock.Any(), list).Return(nil)
+	pacSrvcMock.EXPECT().GeneratePACFile(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		close(generated)
+		return nil
+	})
+
+	err := listSrvc.Update(context.Background(), list)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	<-generated
+}
+
+func TestDomainListService_Delete_StillReferenced(t *testing.T) {
+	t.Parallel()
+
+	listSrvc, repoMock, _ := testPrepareDomainListService(t)
+
+	repoMock.EXPECT().Delete(gomock.Any(), 1).Return(&errs.EntityStillReferencedError{})
+
+	err := listSrvc.Delete(context.Background(), 1)
+	if _, ok := err.(*errs.EntityStillReferencedError); !ok {
+		t.Errorf("expected error is errs.EntityStillReferencedError, but got %#v", err)
+	}
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/nnemirovsky/pacgen/pkg/textdiff"
+	"github.com/rs/zerolog"
+	"reflect"
+)
+
+// DraftService reviews, publishes and discards the draft. When the changes are drafts, the current configuration
+// is the draft, and the published one is the latest version PAC file is generated from.
+type DraftService struct {
+	logger   zerolog.Logger
+	repo     VersionRepository
+	pacSrvc  pacPublisher
+	approval bool
+}
+
+// NewDraftService creates the service. With approval, the draft must be approved by a user other than the one
+// publishing it.
+func NewDraftService(repo VersionRepository, pacSrvc pacPublisher, approval bool, logger zerolog.Logger) *DraftService {
+	return &DraftService{
+		logger:   logger,
+		repo:     repo,
+		pacSrvc:  pacSrvc,
+		approval: approval,
+	}
+}
+
+// Get returns the changes of the draft since the published version along with the diff of PAC files generated
+// from them. Both PAC files are generated with the current health of the profiles, so the diff is made
+// by the changes alone.
+func (s *DraftService) Get(ctx context.Context) (model.Draft, error) {
+	published, err := s.published(ctx)
+	if err != nil {
+		return model.Draft{}, err
+	}
+	state, err := s.repo.Snapshot(ctx)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while selecting draft")
+		return model.Draft{}, errs.ServiceUnknownError
+	}
+
+	changes, err := draftChanges(published.State, state)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while comparing draft with published configuration")
+		return model.Draft{}, errs.ServiceUnknownError
+	}
+	publishedPAC, err := s.pacSrvc.Render(published.State)
+	if err != nil {
+		return model.Draft{}, errs.ServiceUnknownError
+	}
+	draftPAC, err := s.pacSrvc.Render(state)
+	if err != nil {
+		return model.Draft{}, errs.ServiceUnknownError
+	}
+
+	approvers, err := s.repo.GetApprovers(ctx, state)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while getting draft approvers")
+		return model.Draft{}, errs.ServiceUnknownError
+	}
+
+	return model.Draft{
+		Published: published.ID,
+		Changes:   changes,
+		PACDiff:   textdiff.Unified("published", "draft", publishedPAC, draftPAC),
+		Approvers: approvers,
+	}, nil
+}
+
+// Approve approves the draft as it is now on behalf of the user of the actor in the context. Any further change
+// of the draft needs to be approved again.
+func (s *DraftService) Approve(ctx context.Context) error {
+	state, err := s.repo.Snapshot(ctx)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while selecting draft")
+		return errs.ServiceUnknownError
+	}
+	if err = s.repo.Approve(ctx, state); err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while approving draft")
+		return errs.ServiceUnknownError
+	}
+
+	s.logger.Info().Str("user", model.ActorFromContext(ctx).User).Msg("Draft approved")
+	return nil
+}
+
+// Publish makes the draft the published configuration and returns its version. PAC file is regenerated from it
+// right away. With approval, errs.DraftNotApprovedError is returned unless the draft as it is now has been approved
+// by a user other than the publishing one.
+func (s *DraftService) Publish(ctx context.Context) (model.ConfigVersion, error) {
+	state, err := s.repo.Snapshot(ctx)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while selecting draft")
+		return model.ConfigVersion{}, errs.ServiceUnknownError
+	}
+
+	user := model.ActorFromContext(ctx).User
+	if s.approval {
+		approvers, err := s.repo.GetApprovers(ctx, state)
+		if err != nil {
+			s.logger.Error().Err(err).Msg("Error occurred while getting draft approvers")
+			return model.ConfigVersion{}, errs.ServiceUnknownError
+		}
+
+		approved := false
+		for _, approver := range approvers {
+			approved = approved || approver != user
+		}
+		if !approved {
+			s.logger.Debug().Str("user", user).Strs("approvers", approvers).Msg("Draft is not approved")
+			return model.ConfigVersion{}, errs.DraftNotApprovedError
+		}
+	}
+
+	if err = s.pacSrvc.Publish(ctx, state); err != nil {
+		return model.ConfigVersion{}, errs.ServiceUnknownError
+	}
+	if err = s.repo.DeleteApprovals(ctx); err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while deleting draft approvals")
+		return model.ConfigVersion{}, errs.ServiceUnknownError
+	}
+
+	published, err := s.published(ctx)
+	if err != nil {
+		return model.ConfigVersion{}, err
+	}
+	s.logger.Info().Int("version", published.ID).Str("user", user).Msg("Draft published")
+	return published, nil
+}
+
+// Discard replaces the draft with the published configuration and returns the published version along with
+// the changes it has made, which are recorded in the audit log.
+func (s *DraftService) Discard(ctx context.Context) (int, []model.VersionChange, error) {
+	published, err := s.published(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	changes, err := s.repo.Restore(ctx, published.ID)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while discarding draft")
+		return 0, nil, errs.ServiceUnknownError
+	}
+	if err = s.repo.DeleteApprovals(ctx); err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while deleting draft approvals")
+		return 0, nil, errs.ServiceUnknownError
+	}
+
+	s.logger.Info().Int("version", published.ID).Int("changes", len(changes)).Msg("Draft discarded")
+	return published.ID, changes, nil
+}
+
+// published returns the published version, errs.EntityNotFoundError if nothing has been published yet.
+func (s *DraftService) published(ctx context.Context) (model.ConfigVersion, error) {
+	version, err := s.repo.GetLatest(ctx)
+	if err != nil {
+		if _, ok := err.(*errs.EntityNotFoundError); ok {
+			s.logger.Debug().Err(err).Send()
+			return version, err
+		}
+		s.logger.Error().Err(err).Msg("Error occurred while getting published configuration version")
+		return version, errs.ServiceUnknownError
+	}
+	return version, nil
+}
+
+// draftChanges returns the changes of the draft since the published state. Discarded changes leave the entities
+// with newer versions than the published ones, so the updates of nothing but the version are left out.
+func draftChanges(published, draft string) ([]model.VersionChange, error) {
+	changes, err := diffStates(published, draft)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make([]model.VersionChange, 0, len(changes))
+	for _, change := range changes {
+		if change.Action == model.AuditUpdate {
+			before, err := withoutRevision(change.Before)
+			if err != nil {
+				return nil, err
+			}
+			after, err := withoutRevision(change.After)
+			if err != nil {
+				return nil, err
+			}
+			if reflect.DeepEqual(before, after) {
+				continue
+			}
+		}
+		filtered = append(filtered, change)
+	}
+	return filtered, nil
+}
+
+// withoutRevision returns the fields of the entity state other than its version and the time of its update.
+func withoutRevision(state string) (map[string]json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(state), &fields); err != nil {
+		return nil, err
+	}
+	delete(fields, "version")
+	delete(fields, "updated_at")
+	return fields, nil
+}
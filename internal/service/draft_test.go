@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/nnemirovsky/pacgen/internal/service/mock"
+	"github.com/nnemirovsky/pacgen/pkg/logutil"
+	"testing"
+)
+
+func TestDraftChanges(t *testing.T) {
+	t.Parallel()
+
+	published := `{"proxy_profiles":[{"id":1,"name":"tor","updated_at":"2023-06-06 10:00:00","version":2}],` +
+		`"domain_lists":[],"rules":[{"id":1,"regex":"^a$","version":1}],"bypass":{"entries":[]}}`
+	draft := `{"proxy_profiles":[{"id":1,"name":"tor","updated_at":"2023-06-07 10:00:00","version":4}],` +
+		`"domain_lists":[],"rules":[{"id":1,"regex":"^b$","version":2}],"bypass":{"entries":[]}}`
+
+	got, err := draftChanges(published, draft)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	want := []model.VersionChange{
+		{
+			Entity:   model.AuditRule,
+			EntityID: 1,
+			Action:   model.AuditUpdate,
+			Before:   `{"id":1,"regex":"^a$","version":1}`,
+			After:    `{"id":1,"regex":"^b$","version":2}`,
+		},
+	}
+
+	assert.Equal(t, got, want)
+}
+
+func TestDraftService_Get(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	repoMock := mock.NewVersionRepository(ctrl)
+	pacSrvcMock := mock.NewPacPublisher(ctrl)
+	draftSrvc := NewDraftService(repoMock, pacSrvcMock, false, logutil.DiscardLogger)
+
+	published := `{"proxy_profiles":[],"domain_lists":[],"rules":[],"bypass":{"entries":[]}}`
+	draft := `{"proxy_profiles":[],"domain_lists":[],"rules":[{"id":1}],"bypass":{"entries":[]}}`
+	repoMock.EXPECT().GetLatest(gomock.Any()).Return(model.ConfigVersion{ID: 3, State: published}, nil)
+	repoMock.EXPECT().Snapshot(gomock.Any()).Return(draft, nil)
+	repoMock.EXPECT().GetApprovers(gomock.Any(), draft).Return([]string{"alice"}, nil)
+	pacSrvcMock.EXPECT().Render(published).Return("a\nb\n", nil)
+	pacSrvcMock.EXPECT().Render(draft).Return("a\nc\n", nil)
+
+	got, err := draftSrvc.Get(context.Background())
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	want := model.Draft{
+		Published: 3,
+		Changes: []model.VersionChange{
+			{Entity: model.AuditRule, EntityID: 1, Action: model.AuditCreate, After: `{"id":1}`},
+		},
+		PACDiff:   "--- published\n+++ draft\n@@ -1,2 +1,2 @@\n a\n-b\n+c\n",
+		Approvers: []string{"alice"},
+	}
+
+	assert.Equal(t, got, want)
+}
+
+func TestDraftService_Publish_NotApproved(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	repoMock := mock.NewVersionRepository(ctrl)
+	draftSrvc := NewDraftService(repoMock, mock.NewPacPublisher(ctrl), true, logutil.DiscardLogger)
+
+	// Approval of the publisher does not count.
+	repoMock.EXPECT().Snapshot(gomock.Any()).Return("{}", nil)
+	repoMock.EXPECT().GetApprovers(gomock.Any(), "{}").Return([]string{"alice"}, nil)
+
+	ctx := model.WithActor(context.Background(), model.Actor{User: "alice"})
+	_, err := draftSrvc.Publish(ctx)
+
+	assert.Equal(t, err, errs.DraftNotApprovedError)
+}
+
+func TestDraftService_Publish_Approved(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	repoMock := mock.NewVersionRepository(ctrl)
+	pacSrvcMock := mock.NewPacPublisher(ctrl)
+	draftSrvc := NewDraftService(repoMock, pacSrvcMock, true, logutil.DiscardLogger)
+
+	repoMock.EXPECT().Snapshot(gomock.Any()).Return("{}", nil)
+	repoMock.EXPECT().GetApprovers(gomock.Any(), "{}").Return([]string{"alice", "bob"}, nil)
+	pacSrvcMock.EXPECT().Publish(gomock.Any(), "{}").Return(nil)
+	repoMock.EXPECT().DeleteApprovals(gomock.Any()).Return(nil)
+	repoMock.EXPECT().GetLatest(gomock.Any()).Return(model.ConfigVersion{ID: 4, State: "{}"}, nil)
+
+	ctx := model.WithActor(context.Background(), model.Actor{User: "alice"})
+	got, err := draftSrvc.Publish(ctx)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	assert.Equal(t, got, model.ConfigVersion{ID: 4, State: "{}"})
+}
+
+func TestDraftService_Discard(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	repoMock := mock.NewVersionRepository(ctrl)
+	draftSrvc := NewDraftService(repoMock, mock.NewPacPublisher(ctrl), false, logutil.DiscardLogger)
+
+	changes := []model.VersionChange{
+		{Entity: model.AuditRule, EntityID: 1, Action: model.AuditDelete, Before: `{"id":1}`},
+	}
+	repoMock.EXPECT().GetLatest(gomock.Any()).Return(model.ConfigVersion{ID: 3}, nil)
+	repoMock.EXPECT().Restore(gomock.Any(), 3).Return(changes, nil)
+	repoMock.EXPECT().DeleteApprovals(gomock.Any()).Return(nil)
+
+	published, got, err := draftSrvc.Discard(context.Background())
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	assert.Equal(t, published, 3)
+	assert.Equal(t, got, changes)
+}
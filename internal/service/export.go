@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/nnemirovsky/pacgen/pkg/export"
+	"github.com/nnemirovsky/pacgen/pkg/regexp"
+	"github.com/rs/zerolog"
+	"io"
+	"net"
+	"strings"
+)
+
+type ExportService struct {
+	logger zerolog.Logger
+	config pacConfiguration
+}
+
+// NewExportService creates the service exporting the same configuration pac file is generated from, so drafts
+// are not exported until they are published.
+func NewExportService(config pacConfiguration, logger zerolog.Logger) *ExportService {
+	return &ExportService{
+		logger: logger,
+		config: config,
+	}
+}
+
+// Export writes rules and proxy profiles to wr in the format of the exporter registered by the given name.
+// It returns the media type of the written document and the rules and profiles that were left out.
+// If the default proxy in opts is not an exportable profile, errs.InvalidReferenceError is returned.
+func (s *ExportService) Export(
+	ctx context.Context,
+	name string,
+	opts export.Options,
+	wr io.Writer,
+) (string, []export.Skipped, error) {
+	exporter, ok := export.Get(name)
+	if !ok {
+		err := &errs.EntityNotFoundError{Name: "export format", Key: "name", Value: name}
+		s.logger.Debug().Err(err).Send()
+		return "", nil, err
+	}
+
+	_, rules, profiles, err := s.config.Configuration(ctx)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while getting configuration to export")
+		return "", nil, errs.ServiceUnknownError
+	}
+
+	cfg, skipped := exportConfig(profiles, rules)
+
+	if cfg.Options, err = exportOptions(opts, profiles); err != nil {
+		s.logger.Debug().Err(err).Str("default-proxy", opts.DefaultProxy).Msg("Unknown default proxy")
+		return "", nil, err
+	}
+
+	exportSkipped, err := exporter.Export(wr, cfg)
+	if errors.Is(err, export.ErrDefaultProxySkipped) {
+		s.logger.Debug().Err(err).Str("format", name).Send()
+		return "", nil, errs.InvalidReferenceError
+	}
+	if err != nil {
+		s.logger.Error().Err(err).Str("format", name).Msg("Error occurred while exporting rules")
+		return "", nil, errs.ServiceUnknownError
+	}
+	skipped = append(skipped, exportSkipped...)
+
+	for _, sk := range skipped {
+		s.logger.Debug().Int("rule-id", sk.RuleID).Str("profile", sk.Proxy).Str("reason", sk.Reason).
+			Str("format", name).Msg("Skipped while exporting")
+	}
+
+	return exporter.ContentType(), skipped, nil
+}
+
+// exportOptions checks that the default proxy refers to an existing profile. Direct profile as a default
+// proxy is the same as no default proxy at all.
+func exportOptions(opts export.Options, profiles []model.ProxyProfile) (export.Options, error) {
+	if opts.DefaultProxy == "" {
+		return opts, nil
+	}
+	for _, profile := range profiles {
+		if profile.Name != opts.DefaultProxy {
+			continue
+		}
+		if profile.Type == model.Direct {
+			opts.DefaultProxy = ""
+		}
+		return opts, nil
+	}
+	return opts, errs.InvalidReferenceError
+}
+
+// exportConfig converts profiles and rules into exporter configuration. Pools and profiles without a valid address
+// are reported as skipped. Rules routed through direct profiles are exported as direct ones. Rules matching
+// a domain list are exported as one rule per list entry.
+func exportConfig(profiles []model.ProxyProfile, rules []model.Rule) (export.Config, []export.Skipped) {
+	var skipped []export.Skipped
+	cfg := export.Config{
+		Proxies: make([]export.Proxy, 0, len(profiles)),
+		Rules:   make([]export.Rule, 0, len(rules)),
+	}
+
+	for _, profile := range profiles {
+		if profile.Type == model.Direct {
+			continue
+		}
+		if profile.Type == model.Pool {
+			skipped = append(skipped, export.Skipped{Proxy: profile.Name, Reason: "pools are not supported"})
+			continue
+		}
+		if !profile.Routable() {
+			skipped = append(skipped, export.Skipped{Proxy: profile.Name, Reason: "malformed address"})
+			continue
+		}
+		cfg.Proxies = append(cfg.Proxies, export.Proxy{
+			Name:     profile.Name,
+			Protocol: strings.ToLower(profile.Type.String()),
+			Host:     profile.Host,
+			Port:     profile.Port,
+		})
+	}
+
+	for _, rule := range rules {
+		var proxy string
+		if rule.ProxyProfile != nil && rule.ProxyProfile.Type != model.Direct {
+			proxy = rule.ProxyProfile.Name
+		}
+
+		if rule.DomainList != nil {
+			for _, entry := range rule.DomainList.Entries {
+				withSubdomains := entry.Mode == model.DomainAndSubdomains
+				cfg.Rules = append(cfg.Rules, domainRule(rule.ID, proxy, entry.Domain, withSubdomains))
+			}
+			continue
+		}
+
+		if domain, withSubdomains, ok := regexp.ParseDomain(rule.Regex); ok {
+			cfg.Rules = append(cfg.Rules, domainRule(rule.ID, proxy, domain, withSubdomains))
+			continue
+		}
+		cfg.Rules = append(cfg.Rules, export.Rule{ID: rule.ID, Kind: export.Regex, Value: rule.Regex, Proxy: proxy})
+	}
+
+	return cfg, skipped
+}
+
+// domainRule converts a domain matched by the rule into exporter rule. IP literals become single address ranges.
+func domainRule(id int, proxy string, domain string, withSubdomains bool) export.Rule {
+	r := export.Rule{ID: id, Proxy: proxy}
+	switch ip := net.ParseIP(domain); {
+	case ip != nil && ip.To4() != nil:
+		r.Kind, r.Value = export.IPCIDR, domain+"/32"
+	case ip != nil:
+		r.Kind, r.Value = export.IPCIDR, domain+"/128"
+	case withSubdomains:
+		r.Kind, r.Value = export.DomainSuffix, domain
+	default:
+		r.Kind, r.Value = export.Domain, domain
+	}
+	return r
+}
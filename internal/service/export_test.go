@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/nnemirovsky/pacgen/internal/service/mock"
+	"github.com/nnemirovsky/pacgen/pkg/export"
+	"github.com/nnemirovsky/pacgen/pkg/logutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportConfig_OK(t *testing.T) {
+	t.Parallel()
+
+	tor := model.ProxyProfile{ID: 1, Name: "tor", Type: model.Socks5, Host: "localhost", Port: 9050}
+	// Legacy address of the profile could not be normalized.
+	broken := model.ProxyProfile{ID: 2, Name: "broken", Type: model.Http}
+
+	profiles := []model.ProxyProfile{tor, broken}
+	rules := []model.Rule{
+		{ID: 1, Regex: `^www\.google\.com$`, ProxyProfile: &tor},
+		{ID: 2, Regex: `(?:^|\.)facebook\.com$`, ProxyProfile: &tor},
+		{ID: 3, Regex: `^1\.2\.3\.4$`, ProxyProfile: &tor},
+		{ID: 4, Regex: `^::1$`, ProxyProfile: &tor},
+		{ID: 5, Regex: `^api\d+\.example\.com$`, ProxyProfile: &broken},
+	}
+
+	direct := model.ProxyProfile{ID: 3, Name: "direct", Type: model.Direct}
+
+	profiles = append(profiles, direct)
+	rules = append(rules, model.Rule{ID: 6, Regex: `(?:^|\.)local$`, ProxyProfile: &direct})
+
+	cdn := model.DomainList{
+		ID: 1,
+		Entries: []model.DomainListEntry{
+			{Domain: "googlevideo.com", Mode: model.DomainAndSubdomains},
+			{Domain: "vimeo.com", Mode: model.ExactDomain},
+		},
+	}
+	rules = append(rules, model.Rule{ID: 7, DomainListID: 1, DomainList: &cdn, ProxyProfile: &tor})
+
+	egress := model.ProxyProfile{ID: 4, Name: "egress", Type: model.Pool, Members: []model.PoolMember{{ProfileID: 1, Weight: 1}}}
+
+	profiles = append(profiles, egress)
+	rules = append(rules, model.Rule{ID: 8, Regex: `^example\.com$`, ProxyProfile: &egress})
+
+	got, skipped := exportConfig(profiles, rules)
+
+	want := export.Config{
+		Proxies: []export.Proxy{{Name: "tor", Protocol: export.SOCKS5, Host: "localhost", Port: 9050}},
+		Rules: []export.Rule{
+			{ID: 1, Kind: export.Domain, Value: "www.google.com", Proxy: "tor"},
+			{ID: 2, Kind: export.DomainSuffix, Value: "facebook.com", Proxy: "tor"},
+			{ID: 3, Kind: export.IPCIDR, Value: "1.2.3.4/32", Proxy: "tor"},
+			{ID: 4, Kind: export.IPCIDR, Value: "::1/128", Proxy: "tor"},
+			{ID: 5, Kind: export.Regex, Value: `^api\d+\.example\.com$`, Proxy: "broken"},
+			{ID: 6, Kind: export.DomainSuffix, Value: "local"},
+			{ID: 7, Kind: export.DomainSuffix, Value: "googlevideo.com", Proxy: "tor"},
+			{ID: 7, Kind: export.Domain, Value: "vimeo.com", Proxy: "tor"},
+			{ID: 8, Kind: export.Domain, Value: "example.com", Proxy: "egress"},
+		},
+	}
+
+	assert.Equal(t, got, want)
+	assert.Equal(t, skipped, []export.Skipped{
+		{Proxy: "broken", Reason: "malformed address"},
+		{Proxy: "egress", Reason: "pools are not supported"},
+	})
+}
+
+func TestExportService_Export_Drafts(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	versionRepoMock := mock.NewVersionRepository(ctrl)
+	pacSrvc := NewPACService(
+		mock.NewRuleRepository(ctrl),
+		mock.NewProxyProfileRepository(ctrl),
+		mock.NewBypassRepository(ctrl),
+		versionRepoMock,
+		nil,
+		filepath.Join(t.TempDir(), "proxy.pac"),
+		VersionOptions{Keep: 10, Drafts: true},
+		logutil.DiscardLogger,
+	)
+	exportSrvc := NewExportService(pacSrvc, logutil.DiscardLogger)
+
+	published, draft := testPublishedState("example.org"), testPublishedState("example.com")
+	gomock.InOrder(
+		versionRepoMock.EXPECT().GetLatest(gomock.Any()).Return(model.ConfigVersion{ID: 1, State: published}, nil),
+		versionRepoMock.EXPECT().Save(gomock.Any(), draft, gomock.Any(), 10).Return(nil),
+		versionRepoMock.EXPECT().GetLatest(gomock.Any()).Return(model.ConfigVersion{ID: 2, State: draft}, nil),
+	)
+
+	exported := &strings.Builder{}
+	if _, _, err := exportSrvc.Export(context.Background(), "surge.conf", export.Options{}, exported); err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	assert.Equal(t, strings.Contains(exported.String(), "example.org"), true)
+	assert.Equal(t, strings.Contains(exported.String(), "example.com"), false)
+
+	if err := pacSrvc.Publish(context.Background(), draft); err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	exported.Reset()
+	if _, _, err := exportSrvc.Export(context.Background(), "surge.conf", export.Options{}, exported); err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	assert.Equal(t, strings.Contains(exported.String(), "example.com"), true)
+}
+
+func TestExportOptions(t *testing.T) {
+	t.Parallel()
+
+	profiles := []model.ProxyProfile{
+		{ID: 1, Name: "tor", Type: model.Socks5, Host: "localhost", Port: 9050},
+		{ID: 2, Name: "direct", Type: model.Direct},
+	}
+
+	data := []struct {
+		name, defaultProxy, want string
+		err                      error
+	}{
+		{name: "none", defaultProxy: "", want: ""},
+		{name: "proxy", defaultProxy: "tor", want: "tor"},
+		{name: "direct", defaultProxy: "direct", want: ""},
+		{name: "unknown", defaultProxy: "qwerty", want: "qwerty", err: errs.InvalidReferenceError},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.name, func(t *testing.T) {
+			got, err := exportOptions(export.Options{DefaultProxy: d.defaultProxy}, profiles)
+			assert.Equal(t, err, d.err)
+			assert.Equal(t, got.DefaultProxy, d.want)
+		})
+	}
+}
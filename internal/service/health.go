@@ -0,0 +1,203 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/rs/zerolog"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ProbeOptions configures the prober.
+type ProbeOptions struct {
+	// Interval between probes of all the profiles.
+	Interval time.Duration
+	// Timeout of a single probe including the handshake.
+	Timeout time.Duration
+	// Handshake enables HTTP CONNECT and SOCKS5 greeting after the TCP connect. Profiles of other types are
+	// checked with TCP connect only.
+	Handshake bool
+	// ConnectTarget is the authority requested by HTTP CONNECT.
+	ConnectTarget string
+}
+
+// Prober periodically checks that proxy profiles accept connections and keeps the latest results in memory.
+type Prober struct {
+	logger zerolog.Logger
+	repo   ProxyProfileRepository
+	opts   ProbeOptions
+
+	mu     sync.RWMutex
+	health map[int]model.ProfileHealth
+}
+
+func NewProber(repo ProxyProfileRepository, opts ProbeOptions, logger zerolog.Logger) *Prober {
+	return &Prober{
+		logger: logger,
+		repo:   repo,
+		opts:   opts,
+		health: make(map[int]model.ProfileHealth),
+	}
+}
+
+// Run probes the profiles every interval until the context is done. PAC file is regenerated whenever
+// some profile goes down or comes back up.
+func (p *Prober) Run(ctx context.Context, pacSrvc pacService) {
+	ticker := time.NewTicker(p.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		if p.ProbeAll(ctx) {
+			genCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			if err := pacSrvc.GeneratePACFile(genCtx); err != nil {
+				p.logger.Error().Err(err).Msg("Error occurred while generating pac file after health change")
+			} else {
+				p.logger.Debug().Msg("Pac file generated after health change")
+			}
+			cancel()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProbeAll probes all the profiles concurrently and reports whether any of them has gone down or come back up.
+func (p *Prober) ProbeAll(ctx context.Context) (changed bool) {
+	profiles, err := p.repo.GetAll(ctx, model.ProxyProfileFilter{})
+	if err != nil {
+		p.logger.Error().Err(err).Msg("Error occurred while getting proxy profiles to probe")
+		return false
+	}
+
+	results := make([]model.ProfileHealth, len(profiles))
+	var wg sync.WaitGroup
+	for i, profile := range profiles {
+		wg.Add(1)
+		go func(i int, profile model.ProxyProfile) {
+			defer wg.Done()
+			results[i] = p.probe(ctx, profile)
+		}(i, profile)
+	}
+	wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	health := make(map[int]model.ProfileHealth, len(results))
+	for _, result := range results {
+		prev := p.health[result.ProfileID]
+		if (prev.Status == model.HealthDown) != (result.Status == model.HealthDown) {
+			changed = true
+			p.logger.Info().Int("profile-id", result.ProfileID).Stringer("status", result.Status).
+				Str("error", result.Error).Msg("Proxy profile health changed")
+		}
+		health[result.ProfileID] = result
+	}
+	p.health = health
+
+	return changed
+}
+
+// Health returns the result of the last probe of the profile.
+func (p *Prober) Health(ctx context.Context, id int) (model.ProfileHealth, error) {
+	if _, err := p.repo.GetByID(ctx, id); err != nil {
+		if _, ok := err.(*errs.EntityNotFoundError); ok {
+			p.logger.Debug().Err(err).Send()
+			return model.ProfileHealth{}, err
+		}
+		p.logger.Error().Err(err).Msg("Error occurred while getting proxy profile by id")
+		return model.ProfileHealth{}, errs.ServiceUnknownError
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	health, ok := p.health[id]
+	if !ok {
+		return model.ProfileHealth{ProfileID: id, Status: model.HealthUnknown}, nil
+	}
+	return health, nil
+}
+
+// IsDown reports whether the last probe of the profile has failed.
+func (p *Prober) IsDown(id int) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.health[id].Status == model.HealthDown
+}
+
+func (p *Prober) probe(ctx context.Context, profile model.ProxyProfile) model.ProfileHealth {
+	health := model.ProfileHealth{ProfileID: profile.ID, CheckedAt: time.Now()}
+	// Direct profiles, pools and profiles without a valid address have nothing to probe.
+	if profile.Host == "" {
+		return health
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.opts.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", profile.Address())
+	if err != nil {
+		health.Status, health.Error = model.HealthDown, err.Error()
+		return health
+	}
+	defer conn.Close()
+
+	if p.opts.Handshake {
+		if deadline, ok := ctx.Deadline(); ok {
+			_ = conn.SetDeadline(deadline)
+		}
+		if err := p.handshake(conn, profile.Type); err != nil {
+			health.Status, health.Error = model.HealthDown, err.Error()
+			return health
+		}
+	}
+
+	health.Status, health.Latency = model.HealthUp, time.Since(start)
+	return health
+}
+
+// handshake checks that the proxy speaks the protocol of the profile type. Any response of the proxy is fine,
+// including authentication requests and upstream errors.
+func (p *Prober) handshake(conn net.Conn, t model.ProxyType) error {
+	switch t {
+	case model.Http:
+		target := p.opts.ConnectTarget
+		if _, err := fmt.Fprintf(conn, "CONNECT %s HTTP/1.1\r\nHost: %s\r\n\r\n", target, target); err != nil {
+			return fmt.Errorf("http connect: %w", err)
+		}
+		// Body is not read, the connection is closed right after the status line and headers are received.
+		if _, err := http.ReadResponse(bufio.NewReader(conn), &http.Request{Method: http.MethodConnect}); err != nil {
+			return fmt.Errorf("http connect: %w", err)
+		}
+		return nil
+	case model.Socks5:
+		// Version 5, one method offered: no authentication.
+		if _, err := conn.Write([]byte{5, 1, 0}); err != nil {
+			return fmt.Errorf("socks5 greeting: %w", err)
+		}
+		reply := make([]byte, 2)
+		if _, err := io.ReadFull(conn, reply); err != nil {
+			return fmt.Errorf("socks5 greeting: %w", err)
+		}
+		if reply[0] != 5 {
+			return errors.New("socks5 greeting: unexpected version in reply")
+		}
+		return nil
+	default:
+		return nil
+	}
+}
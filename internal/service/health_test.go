@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/nnemirovsky/pacgen/internal/service/mock"
+	"github.com/nnemirovsky/pacgen/pkg/logutil"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func testPrepareProber(t *testing.T, handshake bool) (*Prober, *mock.ProxyProfileRepository) {
+	ctrl := gomock.NewController(t)
+	repoMock := mock.NewProxyProfileRepository(ctrl)
+
+	opts := ProbeOptions{Timeout: time.Second, Handshake: handshake, ConnectTarget: "example.com:443"}
+	return NewProber(repoMock, opts, logutil.DiscardLogger), repoMock
+}
+
+// testProfile returns profile of the type with the address.
+func testProfile(t *testing.T, id int, proxyType model.ProxyType, address string) model.ProxyProfile {
+	host, port, err := model.ParseAddress(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return model.ProxyProfile{ID: id, Type: proxyType, Host: host, Port: port}
+}
+
+// testListen starts a listener that serves every connection with the handler.
+func testListen(t *testing.T, handler func(conn net.Conn)) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				handler(conn)
+			}()
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+// testClosedAddress returns an address nobody listens on.
+func testClosedAddress(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+	return addr
+}
+
+func TestProber_Probe(t *testing.T) {
+	t.Parallel()
+
+	idle := testListen(t, func(conn net.Conn) {})
+	socks5 := testListen(t, func(conn net.Conn) {
+		greeting := make([]byte, 3)
+		if _, err := io.ReadFull(conn, greeting); err == nil {
+			_, _ = conn.Write([]byte{5, 0})
+		}
+	})
+	httpProxy := testListen(t, func(conn net.Conn) {
+		_, _ = conn.Read(make([]byte, 1024))
+		_, _ = io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+	})
+	garbage := testListen(t, func(conn net.Conn) {
+		_, _ = io.WriteString(conn, "garbage\r\n\r\n")
+	})
+	closed := testClosedAddress(t)
+
+	cases := map[string]struct {
+		handshake bool
+		profile   model.ProxyProfile
+		want      model.HealthStatus
+	}{
+		"tcp connect":        {false, testProfile(t, 0, model.Http, idle), model.HealthUp},
+		"connection refused": {false, testProfile(t, 0, model.Http, closed), model.HealthDown},
+		"direct":             {false, model.ProxyProfile{Type: model.Direct}, model.HealthUnknown},
+		"socks5 handshake":   {true, testProfile(t, 0, model.Socks5, socks5), model.HealthUp},
+		"http handshake":     {true, testProfile(t, 0, model.Http, httpProxy), model.HealthUp},
+		"socks5 garbage":     {true, testProfile(t, 0, model.Socks5, garbage), model.HealthDown},
+		"http garbage":       {true, testProfile(t, 0, model.Http, garbage), model.HealthDown},
+		"https tcp only":     {true, testProfile(t, 0, model.Https, idle), model.HealthUp},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			prober, _ := testPrepareProber(t, c.handshake)
+
+			got := prober.probe(context.Background(), c.profile)
+
+			assert.Equal(t, got.Status, c.want)
+		})
+	}
+}
+
+func TestProber_ProbeAll_Changed(t *testing.T) {
+	t.Parallel()
+
+	prober, repoMock := testPrepareProber(t, false)
+
+	up := testListen(t, func(conn net.Conn) {})
+	profiles := []model.ProxyProfile{
+		testProfile(t, 1, model.Socks5, up),
+		testProfile(t, 2, model.Socks5, testClosedAddress(t)),
+	}
+
+	repoMock.EXPECT().GetAll(gomock.Any(), model.ProxyProfileFilter{}).Return(profiles, nil).Times(2)
+
+	assert.Equal(t, prober.ProbeAll(context.Background()), true)
+	assert.Equal(t, prober.IsDown(1), false)
+	assert.Equal(t, prober.IsDown(2), true)
+
+	assert.Equal(t, prober.ProbeAll(context.Background()), false)
+}
+
+func TestProber_Health_Unknown(t *testing.T) {
+	t.Parallel()
+
+	prober, repoMock := testPrepareProber(t, false)
+
+	repoMock.EXPECT().GetByID(gomock.Any(), 1).Return(model.ProxyProfile{ID: 1}, nil)
+
+	got, err := prober.Health(context.Background(), 1)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	assert.Equal(t, got, model.ProfileHealth{ProfileID: 1, Status: model.HealthUnknown})
+}
+
+func TestProber_Health_NotFound(t *testing.T) {
+	t.Parallel()
+
+	prober, repoMock := testPrepareProber(t, false)
+
+	repoMock.EXPECT().GetByID(gomock.Any(), 1).Return(model.ProxyProfile{}, &errs.EntityNotFoundError{})
+
+	_, err := prober.Health(context.Background(), 1)
+	if _, ok := err.(*errs.EntityNotFoundError); !ok {
+		t.Errorf("expected error is errs.EntityNotFoundError, but got %#v", err)
+	}
+}
@@ -3,25 +3,100 @@ package service
 import (
 	"context"
 	"github.com/nnemirovsky/pacgen/internal/model"
+	"time"
 )
 
 type RuleRepository interface {
-	GetAll(ctx context.Context) ([]model.Rule, error)
+	GetAll(ctx context.Context, filter model.RuleFilter) ([]model.Rule, error)
+	Count(ctx context.Context, filter model.RuleFilter) (int, error)
 	GetAllWithProfiles(ctx context.Context) ([]model.Rule, error)
 	GetByID(ctx context.Context, id int) (model.Rule, error)
 	Create(ctx context.Context, rule *model.Rule) error
 	Update(ctx context.Context, rule model.Rule) error
-	Delete(ctx context.Context, id int) error
+	Patch(ctx context.Context, rule model.Rule, fields []string) error
+	Delete(ctx context.Context, id int, version int) error
+	UpdateByTag(ctx context.Context, tag string, changes model.RuleBulkUpdate) (int, error)
+	DeleteByTag(ctx context.Context, tag string) (int, error)
+	GetExpiring(ctx context.Context, until time.Time) ([]model.Rule, error)
+	NextBoundary(ctx context.Context, after time.Time) (time.Time, error)
+	ArchiveExpired(ctx context.Context, tag string) (int, error)
+	DeleteExpired(ctx context.Context) (int, error)
 }
 
 type ProxyProfileRepository interface {
-	GetAll(ctx context.Context) ([]model.ProxyProfile, error)
+	GetAll(ctx context.Context, filter model.ProxyProfileFilter) ([]model.ProxyProfile, error)
+	Count(ctx context.Context, filter model.ProxyProfileFilter) (int, error)
 	GetByID(ctx context.Context, id int) (model.ProxyProfile, error)
 	Create(ctx context.Context, profile *model.ProxyProfile) error
 	Update(ctx context.Context, profile model.ProxyProfile) error
+	Patch(ctx context.Context, profile model.ProxyProfile, fields []string) error
+	Delete(ctx context.Context, id int, version int) error
+}
+
+type DomainListRepository interface {
+	GetAll(ctx context.Context) ([]model.DomainList, error)
+	GetByID(ctx context.Context, id int) (model.DomainList, error)
+	Create(ctx context.Context, list *model.DomainList) error
+	Update(ctx context.Context, list model.DomainList) error
 	Delete(ctx context.Context, id int) error
 }
 
+type BypassRepository interface {
+	GetAll(ctx context.Context) ([]model.BypassEntry, error)
+	Replace(ctx context.Context, entries []model.BypassEntry) error
+}
+
+type AuditRepository interface {
+	GetAll(ctx context.Context, filter model.AuditFilter) ([]model.AuditEntry, error)
+	Count(ctx context.Context, filter model.AuditFilter) (int, error)
+	DeleteBefore(ctx context.Context, before time.Time) (int, error)
+}
+
+type VersionRepository interface {
+	GetAll(ctx context.Context, page model.Page) ([]model.ConfigVersion, error)
+	Count(ctx context.Context) (int, error)
+	GetByID(ctx context.Context, id int) (model.ConfigVersion, error)
+	GetLatest(ctx context.Context) (model.ConfigVersion, error)
+	Snapshot(ctx context.Context) (string, error)
+	Save(ctx context.Context, state, pac string, keep int) error
+	Restore(ctx context.Context, id int) ([]model.VersionChange, error)
+	Approve(ctx context.Context, state string) error
+	GetApprovers(ctx context.Context, state string) ([]string, error)
+	DeleteApprovals(ctx context.Context) error
+}
+
+type UserRepository interface {
+	GetAll(ctx context.Context) ([]model.User, error)
+	GetByID(ctx context.Context, id int) (model.User, error)
+	GetByName(ctx context.Context, name string) (model.User, error)
+	Bootstrap(ctx context.Context, user *model.User) (bool, error)
+	Create(ctx context.Context, user *model.User) error
+	Update(ctx context.Context, user model.User) error
+	Delete(ctx context.Context, id int) error
+}
+
+type TokenRepository interface {
+	GetAll(ctx context.Context, userID int) ([]model.APIToken, error)
+	GetByID(ctx context.Context, id int) (model.APIToken, error)
+	GetByHash(ctx context.Context, hash string) (model.APIToken, error)
+	Create(ctx context.Context, token *model.APIToken) error
+	Delete(ctx context.Context, id int) error
+	Touch(ctx context.Context, id int) error
+}
+
 type pacService interface {
 	GeneratePACFile(ctx context.Context) error
 }
+
+type pacPublisher interface {
+	Publish(ctx context.Context, state string) error
+	Render(state string) (string, error)
+}
+
+type pacConfiguration interface {
+	Configuration(ctx context.Context) ([]model.BypassEntry, []model.Rule, []model.ProxyProfile, error)
+}
+
+type healthChecker interface {
+	IsDown(id int) bool
+}
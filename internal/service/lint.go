@@ -0,0 +1,343 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/nnemirovsky/pacgen/pkg/regexp"
+	"github.com/rs/zerolog"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LintService analyzes enabled rules in the order they are evaluated in PAC file, that is in order of their ids.
+type LintService struct {
+	logger      zerolog.Logger
+	ruleRepo    RuleRepository
+	profileRepo ProxyProfileRepository
+	listRepo    DomainListRepository
+}
+
+func NewLintService(
+	ruleRepo RuleRepository,
+	profileRepo ProxyProfileRepository,
+	listRepo DomainListRepository,
+	logger zerolog.Logger,
+) *LintService {
+	return &LintService{
+		logger:      logger,
+		ruleRepo:    ruleRepo,
+		profileRepo: profileRepo,
+		listRepo:    listRepo,
+	}
+}
+
+// Lint returns problems of the enabled rules.
+func (s *LintService) Lint(ctx context.Context) ([]model.LintFinding, error) {
+	rules, err := s.ruleRepo.GetAllWithProfiles(ctx)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while getting rules to lint")
+		return nil, errs.ServiceUnknownError
+	}
+	return lintRules(rules), nil
+}
+
+// Check returns problems the rule would have or cause if it was saved. Rule with zero id is checked as a new one,
+// which is evaluated after all the existing rules. Unknown proxy profile or domain list gives no problems,
+// since references are checked when the rule is saved.
+func (s *LintService) Check(ctx context.Context, rule model.Rule) ([]model.LintFinding, error) {
+	if !rule.Enabled {
+		return nil, nil
+	}
+
+	rules, err := s.ruleRepo.GetAllWithProfiles(ctx)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while getting rules to check rule")
+		return nil, errs.ServiceUnknownError
+	}
+
+	profile, err := s.profileRepo.GetByID(ctx, rule.ProxyProfile.ID)
+	if _, ok := err.(*errs.EntityNotFoundError); ok {
+		return nil, nil
+	}
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while getting proxy profile to check rule")
+		return nil, errs.ServiceUnknownError
+	}
+	rule.ProxyProfile = &profile
+
+	if rule.DomainListID != 0 {
+		list, err := s.listRepo.GetByID(ctx, rule.DomainListID)
+		if _, ok := err.(*errs.EntityNotFoundError); ok {
+			return nil, nil
+		}
+		if err != nil {
+			s.logger.Error().Err(err).Msg("Error occurred while getting domain list to check rule")
+			return nil, errs.ServiceUnknownError
+		}
+		rule.DomainList = &list
+	}
+
+	replaced := false
+	for i := range rules {
+		if rule.ID != 0 && rules[i].ID == rule.ID {
+			rules[i], replaced = rule, true
+		}
+	}
+	if !replaced {
+		rules = append(rules, rule)
+	}
+
+	var findings []model.LintFinding
+	for _, finding := range lintRules(rules) {
+		if finding.RuleID == rule.ID || containsID(finding.RelatedIDs, rule.ID) {
+			findings = append(findings, finding)
+		}
+	}
+	return findings, nil
+}
+
+// lintPattern is a domain matched by a rule, with or without its subdomains.
+type lintPattern struct {
+	domain         string
+	withSubdomains bool
+}
+
+// lintIndex maps domains to the position of the first rule matching them.
+type lintIndex struct {
+	exact      map[string]int
+	subdomains map[string]int
+}
+
+func newLintIndex() lintIndex {
+	return lintIndex{exact: make(map[string]int), subdomains: make(map[string]int)}
+}
+
+func (x lintIndex) add(p lintPattern, pos int) {
+	m := x.exact
+	if p.withSubdomains {
+		m = x.subdomains
+	}
+	if _, ok := m[p.domain]; !ok {
+		m[p.domain] = pos
+	}
+}
+
+// covering returns the position of a rule matching all the hosts of the pattern.
+func (x lintIndex) covering(p lintPattern) (int, bool) {
+	if !p.withSubdomains {
+		if pos, ok := x.exact[p.domain]; ok {
+			return pos, true
+		}
+	}
+	for d := p.domain; ; {
+		if pos, ok := x.subdomains[d]; ok {
+			return pos, true
+		}
+		dot := strings.IndexByte(d, '.')
+		if dot == -1 {
+			return 0, false
+		}
+		d = d[dot+1:]
+	}
+}
+
+// lintRules finds problems of the rules. Rules matching arbitrary regexes are compared by the regex only,
+// since it is not known which hosts they match.
+func lintRules(rules []model.Rule) []model.LintFinding {
+	rules = append([]model.Rule(nil), rules...)
+	// New rules have zero id and are evaluated last.
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[j].ID == 0 && rules[i].ID != 0 || rules[i].ID != 0 && rules[i].ID < rules[j].ID
+	})
+
+	byRule := make([][]model.LintFinding, len(rules))
+
+	earlier := newLintIndex()
+	keys := make(map[string]int, len(rules))
+	for pos, rule := range rules {
+		key := ruleKey(rule)
+		if first, ok := keys[key]; ok {
+			byRule[pos] = append(byRule[pos], sameHostsFinding(rule, rules[first]))
+			continue
+		}
+		keys[key] = pos
+
+		patterns, known := rulePatterns(rule)
+		if !known {
+			continue
+		}
+		if len(patterns) == 0 {
+			byRule[pos] = append(byRule[pos], model.LintFinding{
+				Kind:    model.LintNoop,
+				RuleID:  rule.ID,
+				Message: fmt.Sprintf("domain list %d is empty", rule.DomainListID),
+				Fix:     "add domains to the list or delete the rule",
+			})
+			continue
+		}
+
+		covering := make(map[int]struct{})
+		for _, p := range patterns {
+			first, ok := earlier.covering(p)
+			if !ok {
+				covering = nil
+				break
+			}
+			covering[first] = struct{}{}
+		}
+		if covering != nil {
+			byRule[pos] = append(byRule[pos], shadowedFinding(rule, rules, covering))
+		}
+
+		for _, p := range patterns {
+			earlier.add(p, pos)
+		}
+	}
+
+	// Direct rules make a difference only if later rules route some of their hosts through a proxy.
+	later := newLintIndex()
+	ancestors := make(map[string]struct{})
+	opaque := false
+	for pos := len(rules) - 1; pos >= 0; pos-- {
+		rule := rules[pos]
+		patterns, known := rulePatterns(rule)
+
+		if !isDirect(rule) {
+			opaque = opaque || !known
+			for _, p := range patterns {
+				later.add(p, pos)
+				for d := p.domain; ; d = d[strings.IndexByte(d, '.')+1:] {
+					ancestors[d] = struct{}{}
+					if !strings.Contains(d, ".") {
+						break
+					}
+				}
+			}
+			continue
+		}
+
+		if opaque || !known || len(patterns) == 0 || len(byRule[pos]) > 0 {
+			continue
+		}
+		overlaps := false
+		for _, p := range patterns {
+			_, covered := later.covering(p)
+			_, covers := ancestors[p.domain]
+			if covered || p.withSubdomains && covers {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			byRule[pos] = append(byRule[pos], model.LintFinding{
+				Kind:    model.LintNoop,
+				RuleID:  rule.ID,
+				Message: "routes hosts directly, which is the default, and no later rule routes them through a proxy",
+				Fix:     "delete the rule",
+			})
+		}
+	}
+
+	findings := make([]model.LintFinding, 0)
+	for _, f := range byRule {
+		findings = append(findings, f...)
+	}
+	return findings
+}
+
+// ruleKey identifies the hosts matched by the rule. Regexes created from domains are canonical, so equal keys
+// mean equal sets of hosts.
+func ruleKey(rule model.Rule) string {
+	if rule.DomainListID != 0 {
+		return "list:" + strconv.Itoa(rule.DomainListID)
+	}
+	return "regex:" + rule.Regex
+}
+
+// rulePatterns returns the domains matched by the rule and known set to false if the rule matches
+// an arbitrary regex.
+func rulePatterns(rule model.Rule) (patterns []lintPattern, known bool) {
+	if rule.DomainListID != 0 {
+		if rule.DomainList == nil {
+			return nil, false
+		}
+		for _, entry := range rule.DomainList.Entries {
+			patterns = append(patterns, lintPattern{entry.Domain, entry.Mode == model.DomainAndSubdomains})
+		}
+		return patterns, true
+	}
+	if domain, withSubdomains, ok := regexp.ParseDomain(rule.Regex); ok {
+		return []lintPattern{{domain, withSubdomains}}, true
+	}
+	return nil, false
+}
+
+func isDirect(rule model.Rule) bool {
+	return rule.ProxyProfile != nil && rule.ProxyProfile.Type == model.Direct
+}
+
+// sameRoute reports whether traffic of the rules goes the same way.
+func sameRoute(a, b model.Rule) bool {
+	if a.ProxyProfile == nil || b.ProxyProfile == nil {
+		return false
+	}
+	return a.ProxyProfile.ID == b.ProxyProfile.ID || isDirect(a) && isDirect(b)
+}
+
+func sameHostsFinding(rule, first model.Rule) model.LintFinding {
+	if sameRoute(rule, first) {
+		return model.LintFinding{
+			Kind:       model.LintDuplicate,
+			RuleID:     rule.ID,
+			RelatedIDs: []int{first.ID},
+			Message:    fmt.Sprintf("matches the same hosts as rule %d with the same proxy profile", first.ID),
+			Fix:        "delete the rule",
+		}
+	}
+	return model.LintFinding{
+		Kind:       model.LintConflict,
+		RuleID:     rule.ID,
+		RelatedIDs: []int{first.ID},
+		Message: fmt.Sprintf(
+			"matches the same hosts as rule %d with another proxy profile, only rule %d applies", first.ID, first.ID,
+		),
+		Fix: "delete one of the rules or point them at the same proxy profile",
+	}
+}
+
+func shadowedFinding(rule model.Rule, rules []model.Rule, covering map[int]struct{}) model.LintFinding {
+	finding := model.LintFinding{
+		Kind:   model.LintShadowed,
+		RuleID: rule.ID,
+		Fix:    "delete the rule, earlier rules already route its hosts the same way",
+	}
+	ids := make([]string, 0, len(covering))
+	for pos := range covering {
+		finding.RelatedIDs = append(finding.RelatedIDs, rules[pos].ID)
+		if !sameRoute(rule, rules[pos]) {
+			finding.Fix = "delete the rule or narrow down the earlier rules, rules are evaluated in order of their ids"
+		}
+	}
+	sort.Ints(finding.RelatedIDs)
+	for _, id := range finding.RelatedIDs {
+		ids = append(ids, strconv.Itoa(id))
+	}
+	if len(ids) == 1 {
+		finding.Message = fmt.Sprintf("all the hosts it matches are matched by earlier rule %s", ids[0])
+	} else {
+		finding.Message = fmt.Sprintf("all the hosts it matches are matched by earlier rules %s", strings.Join(ids, ", "))
+	}
+	return finding
+}
+
+func containsID(ids []int, id int) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
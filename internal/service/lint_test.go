@@ -0,0 +1,311 @@
+package service
+
+import (
+	"context"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/nnemirovsky/pacgen/internal/service/mock"
+	"github.com/nnemirovsky/pacgen/pkg/logutil"
+	"testing"
+)
+
+func TestLintRules(t *testing.T) {
+	t.Parallel()
+
+	tor := &model.ProxyProfile{ID: 1, Type: model.Socks5}
+	ss := &model.ProxyProfile{ID: 2, Type: model.Socks5}
+	direct := &model.ProxyProfile{ID: 3, Type: model.Direct}
+	lan := &model.ProxyProfile{ID: 4, Type: model.Direct}
+
+	cdn := &model.DomainList{ID: 5, Entries: []model.DomainListEntry{
+		{Domain: "a.example.com", Mode: model.ExactDomain},
+		{Domain: "video.com", Mode: model.DomainAndSubdomains},
+	}}
+
+	cases := map[string]struct {
+		rules []model.Rule
+		want  []model.LintFinding
+	}{
+		"clean": {
+			rules: []model.Rule{
+				{ID: 1, Regex: `(?:^|\.)example\.com$`, ProxyProfile: tor},
+				{ID: 2, Regex: `^example\.org$`, ProxyProfile: ss},
+				{ID: 3, Regex: `^mail\.`, ProxyProfile: ss},
+			},
+			want: []model.LintFinding{},
+		},
+		"duplicate": {
+			rules: []model.Rule{
+				{ID: 1, Regex: `^example\.com$`, ProxyProfile: tor},
+				{ID: 2, Regex: `^example\.com$`, ProxyProfile: tor},
+			},
+			want: []model.LintFinding{{
+				Kind:       model.LintDuplicate,
+				RuleID:     2,
+				RelatedIDs: []int{1},
+				Message:    "matches the same hosts as rule 1 with the same proxy profile",
+				Fix:        "delete the rule",
+			}},
+		},
+		"duplicate regex": {
+			rules: []model.Rule{
+				{ID: 1, Regex: `^mail\.`, ProxyProfile: tor},
+				{ID: 2, Regex: `^mail\.`, ProxyProfile: tor},
+			},
+			want: []model.LintFinding{{
+				Kind:       model.LintDuplicate,
+				RuleID:     2,
+				RelatedIDs: []int{1},
+				Message:    "matches the same hosts as rule 1 with the same proxy profile",
+				Fix:        "delete the rule",
+			}},
+		},
+		"conflict": {
+			rules: []model.Rule{
+				{ID: 1, Regex: `^example\.com$`, ProxyProfile: tor},
+				{ID: 2, Regex: `^example\.com$`, ProxyProfile: ss},
+			},
+			want: []model.LintFinding{{
+				Kind:       model.LintConflict,
+				RuleID:     2,
+				RelatedIDs: []int{1},
+				Message:    "matches the same hosts as rule 1 with another proxy profile, only rule 1 applies",
+				Fix:        "delete one of the rules or point them at the same proxy profile",
+			}},
+		},
+		"shadowed": {
+			rules: []model.Rule{
+				{ID: 1, Regex: `(?:^|\.)example\.com$`, ProxyProfile: tor},
+				{ID: 2, Regex: `^api\.example\.com$`, ProxyProfile: ss},
+				{ID: 3, Regex: `(?:^|\.)cdn\.example\.com$`, ProxyProfile: tor},
+			},
+			want: []model.LintFinding{
+				{
+					Kind:       model.LintShadowed,
+					RuleID:     2,
+					RelatedIDs: []int{1},
+					Message:    "all the hosts it matches are matched by earlier rule 1",
+					Fix:        "delete the rule or narrow down the earlier rules, rules are evaluated in order of their ids",
+				},
+				{
+					Kind:       model.LintShadowed,
+					RuleID:     3,
+					RelatedIDs: []int{1},
+					Message:    "all the hosts it matches are matched by earlier rule 1",
+					Fix:        "delete the rule, earlier rules already route its hosts the same way",
+				},
+			},
+		},
+		"list shadowed by several rules": {
+			rules: []model.Rule{
+				{ID: 1, Regex: `^a\.example\.com$`, ProxyProfile: tor},
+				{ID: 2, Regex: `(?:^|\.)video\.com$`, ProxyProfile: tor},
+				{ID: 3, DomainListID: 5, DomainList: cdn, ProxyProfile: tor},
+			},
+			want: []model.LintFinding{{
+				Kind:       model.LintShadowed,
+				RuleID:     3,
+				RelatedIDs: []int{1, 2},
+				Message:    "all the hosts it matches are matched by earlier rules 1, 2",
+				Fix:        "delete the rule, earlier rules already route its hosts the same way",
+			}},
+		},
+		"exact domain does not shadow subdomains": {
+			rules: []model.Rule{
+				{ID: 1, Regex: `^example\.com$`, ProxyProfile: tor},
+				{ID: 2, Regex: `(?:^|\.)example\.com$`, ProxyProfile: ss},
+			},
+			want: []model.LintFinding{},
+		},
+		"empty list": {
+			rules: []model.Rule{
+				{ID: 1, DomainListID: 6, DomainList: &model.DomainList{ID: 6}, ProxyProfile: tor},
+			},
+			want: []model.LintFinding{{
+				Kind:    model.LintNoop,
+				RuleID:  1,
+				Message: "domain list 6 is empty",
+				Fix:     "add domains to the list or delete the rule",
+			}},
+		},
+		"direct exception": {
+			rules: []model.Rule{
+				{ID: 1, Regex: `^intranet\.example\.com$`, ProxyProfile: direct},
+				{ID: 2, Regex: `(?:^|\.)example\.com$`, ProxyProfile: tor},
+			},
+			want: []model.LintFinding{},
+		},
+		"direct covering later proxy rule": {
+			rules: []model.Rule{
+				{ID: 1, Regex: `(?:^|\.)corp$`, ProxyProfile: direct},
+				{ID: 2, Regex: `^git\.corp$`, ProxyProfile: tor},
+			},
+			want: []model.LintFinding{{
+				Kind:       model.LintShadowed,
+				RuleID:     2,
+				RelatedIDs: []int{1},
+				Message:    "all the hosts it matches are matched by earlier rule 1",
+				Fix:        "delete the rule or narrow down the earlier rules, rules are evaluated in order of their ids",
+			}},
+		},
+		"direct without later rules": {
+			rules: []model.Rule{
+				{ID: 1, Regex: `(?:^|\.)example\.com$`, ProxyProfile: tor},
+				{ID: 2, Regex: `^intranet\.corp$`, ProxyProfile: direct},
+			},
+			want: []model.LintFinding{{
+				Kind:    model.LintNoop,
+				RuleID:  2,
+				Message: "routes hosts directly, which is the default, and no later rule routes them through a proxy",
+				Fix:     "delete the rule",
+			}},
+		},
+		"direct before arbitrary regex": {
+			rules: []model.Rule{
+				{ID: 1, Regex: `^intranet\.corp$`, ProxyProfile: direct},
+				{ID: 2, Regex: `corp$`, ProxyProfile: tor},
+			},
+			want: []model.LintFinding{},
+		},
+		"direct profiles are the same route": {
+			rules: []model.Rule{
+				{ID: 1, Regex: `^intranet\.corp$`, ProxyProfile: direct},
+				{ID: 2, Regex: `^intranet\.corp$`, ProxyProfile: lan},
+				{ID: 3, Regex: `(?:^|\.)corp$`, ProxyProfile: tor},
+			},
+			want: []model.LintFinding{{
+				Kind:       model.LintDuplicate,
+				RuleID:     2,
+				RelatedIDs: []int{1},
+				Message:    "matches the same hosts as rule 1 with the same proxy profile",
+				Fix:        "delete the rule",
+			}},
+		},
+		"evaluation order": {
+			rules: []model.Rule{
+				{ID: 2, Regex: `^api\.example\.com$`, ProxyProfile: ss},
+				{ID: 1, Regex: `(?:^|\.)example\.com$`, ProxyProfile: tor},
+			},
+			want: []model.LintFinding{{
+				Kind:       model.LintShadowed,
+				RuleID:     2,
+				RelatedIDs: []int{1},
+				Message:    "all the hosts it matches are matched by earlier rule 1",
+				Fix:        "delete the rule or narrow down the earlier rules, rules are evaluated in order of their ids",
+			}},
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got := lintRules(c.rules)
+
+			assert.Equal(t, got, c.want)
+		})
+	}
+}
+
+func testPrepareLintService(t *testing.T) (
+	*LintService,
+	*mock.RuleRepository,
+	*mock.ProxyProfileRepository,
+	*mock.DomainListRepository,
+) {
+	ctrl := gomock.NewController(t)
+	ruleRepoMock := mock.NewRuleRepository(ctrl)
+	profileRepoMock := mock.NewProxyProfileRepository(ctrl)
+	listRepoMock := mock.NewDomainListRepository(ctrl)
+
+	srvc := NewLintService(ruleRepoMock, profileRepoMock, listRepoMock, logutil.DiscardLogger)
+	return srvc, ruleRepoMock, profileRepoMock, listRepoMock
+}
+
+func TestLintService_Check_New(t *testing.T) {
+	t.Parallel()
+
+	srvc, ruleRepoMock, profileRepoMock, _ := testPrepareLintService(t)
+
+	tor := model.ProxyProfile{ID: 1, Type: model.Socks5}
+	rules := []model.Rule{
+		{ID: 1, Regex: `(?:^|\.)example\.com$`, Enabled: true, ProxyProfile: &tor},
+		{ID: 2, Regex: `^example\.org$`, Enabled: true, ProxyProfile: &tor},
+		{ID: 3, Regex: `^example\.org$`, Enabled: true, ProxyProfile: &tor},
+	}
+
+	ruleRepoMock.EXPECT().GetAllWithProfiles(gomock.Any()).Return(rules, nil)
+	profileRepoMock.EXPECT().GetByID(gomock.Any(), 2).Return(model.ProxyProfile{ID: 2, Type: model.Http}, nil)
+
+	rule := model.Rule{Regex: `^api\.example\.com$`, Enabled: true, ProxyProfile: &model.ProxyProfile{ID: 2}}
+
+	got, err := srvc.Check(context.Background(), rule)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	// The duplicate of rule 2 is not caused by the new rule.
+	want := []model.LintFinding{{
+		Kind:       model.LintShadowed,
+		RelatedIDs: []int{1},
+		Message:    "all the hosts it matches are matched by earlier rule 1",
+		Fix:        "delete the rule or narrow down the earlier rules, rules are evaluated in order of their ids",
+	}}
+	assert.Equal(t, got, want)
+}
+
+func TestLintService_Check_Update(t *testing.T) {
+	t.Parallel()
+
+	srvc, ruleRepoMock, profileRepoMock, listRepoMock := testPrepareLintService(t)
+
+	tor := model.ProxyProfile{ID: 1, Type: model.Socks5}
+	rules := []model.Rule{
+		{ID: 1, Regex: `^example\.com$`, Enabled: true, ProxyProfile: &tor},
+		{ID: 2, Regex: `^api\.example\.com$`, Enabled: true, ProxyProfile: &tor},
+	}
+	list := model.DomainList{ID: 4, Entries: []model.DomainListEntry{
+		{Domain: "example.com", Mode: model.DomainAndSubdomains},
+	}}
+
+	ruleRepoMock.EXPECT().GetAllWithProfiles(gomock.Any()).Return(rules, nil)
+	profileRepoMock.EXPECT().GetByID(gomock.Any(), 1).Return(tor, nil)
+	listRepoMock.EXPECT().GetByID(gomock.Any(), 4).Return(list, nil)
+
+	rule := model.Rule{ID: 1, DomainListID: 4, Enabled: true, ProxyProfile: &model.ProxyProfile{ID: 1}}
+
+	got, err := srvc.Check(context.Background(), rule)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	want := []model.LintFinding{{
+		Kind:       model.LintShadowed,
+		RuleID:     2,
+		RelatedIDs: []int{1},
+		Message:    "all the hosts it matches are matched by earlier rule 1",
+		Fix:        "delete the rule, earlier rules already route its hosts the same way",
+	}}
+	assert.Equal(t, got, want)
+}
+
+func TestLintService_Check_UnknownProfile(t *testing.T) {
+	t.Parallel()
+
+	srvc, ruleRepoMock, profileRepoMock, _ := testPrepareLintService(t)
+
+	ruleRepoMock.EXPECT().GetAllWithProfiles(gomock.Any()).Return([]model.Rule{}, nil)
+	profileRepoMock.EXPECT().GetByID(gomock.Any(), 9).Return(model.ProxyProfile{}, &errs.EntityNotFoundError{})
+
+	rule := model.Rule{Regex: `^example\.com$`, Enabled: true, ProxyProfile: &model.ProxyProfile{ID: 9}}
+
+	got, err := srvc.Check(context.Background(), rule)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	assert.Equal(t, len(got), 0)
+}
@@ -7,6 +7,7 @@ package mock
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	model "github.com/nnemirovsky/pacgen/internal/model"
@@ -35,6 +36,36 @@ func (m *RuleRepository) EXPECT() *RuleRepositoryMockRecorder {
 	return m.recorder
 }
 
+// ArchiveExpired mocks base method.
+func (m *RuleRepository) ArchiveExpired(ctx context.Context, tag string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ArchiveExpired", ctx, tag)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ArchiveExpired indicates an expected call of ArchiveExpired.
+func (mr *RuleRepositoryMockRecorder) ArchiveExpired(ctx, tag interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ArchiveExpired", reflect.TypeOf((*RuleRepository)(nil).ArchiveExpired), ctx, tag)
+}
+
+// Count mocks base method.
+func (m *RuleRepository) Count(ctx context.Context, filter model.RuleFilter) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Count", ctx, filter)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Count indicates an expected call of Count.
+func (mr *RuleRepositoryMockRecorder) Count(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*RuleRepository)(nil).Count), ctx, filter)
+}
+
 // Create mocks base method.
 func (m *RuleRepository) Create(ctx context.Context, rule *model.Rule) error {
 	m.ctrl.T.Helper()
@@ -50,32 +81,62 @@ func (mr *RuleRepositoryMockRecorder) Create(ctx, rule interface{}) *gomock.Call
 }
 
 // Delete mocks base method.
-func (m *RuleRepository) Delete(ctx context.Context, id int) error {
+func (m *RuleRepository) Delete(ctx context.Context, id, version int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", ctx, id)
+	ret := m.ctrl.Call(m, "Delete", ctx, id, version)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *RuleRepositoryMockRecorder) Delete(ctx, id interface{}) *gomock.Call {
+func (mr *RuleRepositoryMockRecorder) Delete(ctx, id, version interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*RuleRepository)(nil).Delete), ctx, id, version)
+}
+
+// DeleteByTag mocks base method.
+func (m *RuleRepository) DeleteByTag(ctx context.Context, tag string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteByTag", ctx, tag)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteByTag indicates an expected call of DeleteByTag.
+func (mr *RuleRepositoryMockRecorder) DeleteByTag(ctx, tag interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteByTag", reflect.TypeOf((*RuleRepository)(nil).DeleteByTag), ctx, tag)
+}
+
+// DeleteExpired mocks base method.
+func (m *RuleRepository) DeleteExpired(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpired", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpired indicates an expected call of DeleteExpired.
+func (mr *RuleRepositoryMockRecorder) DeleteExpired(ctx interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*RuleRepository)(nil).Delete), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpired", reflect.TypeOf((*RuleRepository)(nil).DeleteExpired), ctx)
 }
 
 // GetAll mocks base method.
-func (m *RuleRepository) GetAll(ctx context.Context) ([]model.Rule, error) {
+func (m *RuleRepository) GetAll(ctx context.Context, filter model.RuleFilter) ([]model.Rule, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAll", ctx)
+	ret := m.ctrl.Call(m, "GetAll", ctx, filter)
 	ret0, _ := ret[0].([]model.Rule)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetAll indicates an expected call of GetAll.
-func (mr *RuleRepositoryMockRecorder) GetAll(ctx interface{}) *gomock.Call {
+func (mr *RuleRepositoryMockRecorder) GetAll(ctx, filter interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*RuleRepository)(nil).GetAll), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*RuleRepository)(nil).GetAll), ctx, filter)
 }
 
 // GetAllWithProfiles mocks base method.
@@ -108,6 +169,50 @@ func (mr *RuleRepositoryMockRecorder) GetByID(ctx, id interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*RuleRepository)(nil).GetByID), ctx, id)
 }
 
+// GetExpiring mocks base method.
+func (m *RuleRepository) GetExpiring(ctx context.Context, until time.Time) ([]model.Rule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExpiring", ctx, until)
+	ret0, _ := ret[0].([]model.Rule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetExpiring indicates an expected call of GetExpiring.
+func (mr *RuleRepositoryMockRecorder) GetExpiring(ctx, until interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExpiring", reflect.TypeOf((*RuleRepository)(nil).GetExpiring), ctx, until)
+}
+
+// NextBoundary mocks base method.
+func (m *RuleRepository) NextBoundary(ctx context.Context, after time.Time) (time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NextBoundary", ctx, after)
+	ret0, _ := ret[0].(time.Time)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// NextBoundary indicates an expected call of NextBoundary.
+func (mr *RuleRepositoryMockRecorder) NextBoundary(ctx, after interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NextBoundary", reflect.TypeOf((*RuleRepository)(nil).NextBoundary), ctx, after)
+}
+
+// Patch mocks base method.
+func (m *RuleRepository) Patch(ctx context.Context, rule model.Rule, fields []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Patch", ctx, rule, fields)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Patch indicates an expected call of Patch.
+func (mr *RuleRepositoryMockRecorder) Patch(ctx, rule, fields interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Patch", reflect.TypeOf((*RuleRepository)(nil).Patch), ctx, rule, fields)
+}
+
 // Update mocks base method.
 func (m *RuleRepository) Update(ctx context.Context, rule model.Rule) error {
 	m.ctrl.T.Helper()
@@ -122,6 +227,21 @@ func (mr *RuleRepositoryMockRecorder) Update(ctx, rule interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*RuleRepository)(nil).Update), ctx, rule)
 }
 
+// UpdateByTag mocks base method.
+func (m *RuleRepository) UpdateByTag(ctx context.Context, tag string, changes model.RuleBulkUpdate) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateByTag", ctx, tag, changes)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateByTag indicates an expected call of UpdateByTag.
+func (mr *RuleRepositoryMockRecorder) UpdateByTag(ctx, tag, changes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateByTag", reflect.TypeOf((*RuleRepository)(nil).UpdateByTag), ctx, tag, changes)
+}
+
 // ProxyProfileRepository is a mock of ProxyProfileRepository interface.
 type ProxyProfileRepository struct {
 	ctrl     *gomock.Controller
@@ -145,6 +265,21 @@ func (m *ProxyProfileRepository) EXPECT() *ProxyProfileRepositoryMockRecorder {
 	return m.recorder
 }
 
+// Count mocks base method.
+func (m *ProxyProfileRepository) Count(ctx context.Context, filter model.ProxyProfileFilter) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Count", ctx, filter)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Count indicates an expected call of Count.
+func (mr *ProxyProfileRepositoryMockRecorder) Count(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*ProxyProfileRepository)(nil).Count), ctx, filter)
+}
+
 // Create mocks base method.
 func (m *ProxyProfileRepository) Create(ctx context.Context, profile *model.ProxyProfile) error {
 	m.ctrl.T.Helper()
@@ -160,32 +295,32 @@ func (mr *ProxyProfileRepositoryMockRecorder) Create(ctx, profile interface{}) *
 }
 
 // Delete mocks base method.
-func (m *ProxyProfileRepository) Delete(ctx context.Context, id int) error {
+func (m *ProxyProfileRepository) Delete(ctx context.Context, id, version int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", ctx, id)
+	ret := m.ctrl.Call(m, "Delete", ctx, id, version)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *ProxyProfileRepositoryMockRecorder) Delete(ctx, id interface{}) *gomock.Call {
+func (mr *ProxyProfileRepositoryMockRecorder) Delete(ctx, id, version interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*ProxyProfileRepository)(nil).Delete), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*ProxyProfileRepository)(nil).Delete), ctx, id, version)
 }
 
 // GetAll mocks base method.
-func (m *ProxyProfileRepository) GetAll(ctx context.Context) ([]model.ProxyProfile, error) {
+func (m *ProxyProfileRepository) GetAll(ctx context.Context, filter model.ProxyProfileFilter) ([]model.ProxyProfile, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAll", ctx)
+	ret := m.ctrl.Call(m, "GetAll", ctx, filter)
 	ret0, _ := ret[0].([]model.ProxyProfile)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetAll indicates an expected call of GetAll.
-func (mr *ProxyProfileRepositoryMockRecorder) GetAll(ctx interface{}) *gomock.Call {
+func (mr *ProxyProfileRepositoryMockRecorder) GetAll(ctx, filter interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*ProxyProfileRepository)(nil).GetAll), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*ProxyProfileRepository)(nil).GetAll), ctx, filter)
 }
 
 // GetByID mocks base method.
@@ -203,6 +338,20 @@ func (mr *ProxyProfileRepositoryMockRecorder) GetByID(ctx, id interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*ProxyProfileRepository)(nil).GetByID), ctx, id)
 }
 
+// Patch mocks base method.
+func (m *ProxyProfileRepository) Patch(ctx context.Context, profile model.ProxyProfile, fields []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Patch", ctx, profile, fields)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Patch indicates an expected call of Patch.
+func (mr *ProxyProfileRepositoryMockRecorder) Patch(ctx, profile, fields interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Patch", reflect.TypeOf((*ProxyProfileRepository)(nil).Patch), ctx, profile, fields)
+}
+
 // Update mocks base method.
 func (m *ProxyProfileRepository) Update(ctx context.Context, profile model.ProxyProfile) error {
 	m.ctrl.T.Helper()
@@ -217,39 +366,788 @@ func (mr *ProxyProfileRepositoryMockRecorder) Update(ctx, profile interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*ProxyProfileRepository)(nil).Update), ctx, profile)
 }
 
-// PacService is a mock of pacService interface.
-type PacService struct {
+// DomainListRepository is a mock of DomainListRepository interface.
+type DomainListRepository struct {
 	ctrl     *gomock.Controller
-	recorder *PacServiceMockRecorder
+	recorder *DomainListRepositoryMockRecorder
 }
 
-// PacServiceMockRecorder is the mock recorder for PacService.
-type PacServiceMockRecorder struct {
-	mock *PacService
+// DomainListRepositoryMockRecorder is the mock recorder for DomainListRepository.
+type DomainListRepositoryMockRecorder struct {
+	mock *DomainListRepository
 }
 
-// NewPacService creates a new mock instance.
-func NewPacService(ctrl *gomock.Controller) *PacService {
-	mock := &PacService{ctrl: ctrl}
-	mock.recorder = &PacServiceMockRecorder{mock}
+// NewDomainListRepository creates a new mock instance.
+func NewDomainListRepository(ctrl *gomock.Controller) *DomainListRepository {
+	mock := &DomainListRepository{ctrl: ctrl}
+	mock.recorder = &DomainListRepositoryMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *PacService) EXPECT() *PacServiceMockRecorder {
+func (m *DomainListRepository) EXPECT() *DomainListRepositoryMockRecorder {
 	return m.recorder
 }
 
-// GeneratePACFile mocks base method.
-func (m *PacService) GeneratePACFile(ctx context.Context) error {
+// Create mocks base method.
+func (m *DomainListRepository) Create(ctx context.Context, list *model.DomainList) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GeneratePACFile", ctx)
+	ret := m.ctrl.Call(m, "Create", ctx, list)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// GeneratePACFile indicates an expected call of GeneratePACFile.
-func (mr *PacServiceMockRecorder) GeneratePACFile(ctx interface{}) *gomock.Call {
+// Create indicates an expected call of Create.
+func (mr *DomainListRepositoryMockRecorder) Create(ctx, list interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GeneratePACFile", reflect.TypeOf((*PacService)(nil).GeneratePACFile), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*DomainListRepository)(nil).Create), ctx, list)
+}
+
+// Delete mocks base method.
+func (m *DomainListRepository) Delete(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *DomainListRepositoryMockRecorder) Delete(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*DomainListRepository)(nil).Delete), ctx, id)
+}
+
+// GetAll mocks base method.
+func (m *DomainListRepository) GetAll(ctx context.Context) ([]model.DomainList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll", ctx)
+	ret0, _ := ret[0].([]model.DomainList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAll indicates an expected call of GetAll.
+func (mr *DomainListRepositoryMockRecorder) GetAll(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*DomainListRepository)(nil).GetAll), ctx)
+}
+
+// GetByID mocks base method.
+func (m *DomainListRepository) GetByID(ctx context.Context, id int) (model.DomainList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByID", ctx, id)
+	ret0, _ := ret[0].(model.DomainList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByID indicates an expected call of GetByID.
+func (mr *DomainListRepositoryMockRecorder) GetByID(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*DomainListRepository)(nil).GetByID), ctx, id)
+}
+
+// Update mocks base method.
+func (m *DomainListRepository) Update(ctx context.Context, list model.DomainList) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, list)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *DomainListRepositoryMockRecorder) Update(ctx, list interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*DomainListRepository)(nil).Update), ctx, list)
+}
+
+// BypassRepository is a mock of BypassRepository interface.
+type BypassRepository struct {
+	ctrl     *gomock.Controller
+	recorder *BypassRepositoryMockRecorder
+}
+
+// BypassRepositoryMockRecorder is the mock recorder for BypassRepository.
+type BypassRepositoryMockRecorder struct {
+	mock *BypassRepository
+}
+
+// NewBypassRepository creates a new mock instance.
+func NewBypassRepository(ctrl *gomock.Controller) *BypassRepository {
+	mock := &BypassRepository{ctrl: ctrl}
+	mock.recorder = &BypassRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *BypassRepository) EXPECT() *BypassRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetAll mocks base method.
+func (m *BypassRepository) GetAll(ctx context.Context) ([]model.BypassEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll", ctx)
+	ret0, _ := ret[0].([]model.BypassEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAll indicates an expected call of GetAll.
+func (mr *BypassRepositoryMockRecorder) GetAll(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*BypassRepository)(nil).GetAll), ctx)
+}
+
+// Replace mocks base method.
+func (m *BypassRepository) Replace(ctx context.Context, entries []model.BypassEntry) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Replace", ctx, entries)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Replace indicates an expected call of Replace.
+func (mr *BypassRepositoryMockRecorder) Replace(ctx, entries interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Replace", reflect.TypeOf((*BypassRepository)(nil).Replace), ctx, entries)
+}
+
+// AuditRepository is a mock of AuditRepository interface.
+type AuditRepository struct {
+	ctrl     *gomock.Controller
+	recorder *AuditRepositoryMockRecorder
+}
+
+// AuditRepositoryMockRecorder is the mock recorder for AuditRepository.
+type AuditRepositoryMockRecorder struct {
+	mock *AuditRepository
+}
+
+// NewAuditRepository creates a new mock instance.
+func NewAuditRepository(ctrl *gomock.Controller) *AuditRepository {
+	mock := &AuditRepository{ctrl: ctrl}
+	mock.recorder = &AuditRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *AuditRepository) EXPECT() *AuditRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Count mocks base method.
+func (m *AuditRepository) Count(ctx context.Context, filter model.AuditFilter) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Count", ctx, filter)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Count indicates an expected call of Count.
+func (mr *AuditRepositoryMockRecorder) Count(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*AuditRepository)(nil).Count), ctx, filter)
+}
+
+// DeleteBefore mocks base method.
+func (m *AuditRepository) DeleteBefore(ctx context.Context, before time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteBefore", ctx, before)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteBefore indicates an expected call of DeleteBefore.
+func (mr *AuditRepositoryMockRecorder) DeleteBefore(ctx, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBefore", reflect.TypeOf((*AuditRepository)(nil).DeleteBefore), ctx, before)
+}
+
+// GetAll mocks base method.
+func (m *AuditRepository) GetAll(ctx context.Context, filter model.AuditFilter) ([]model.AuditEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll", ctx, filter)
+	ret0, _ := ret[0].([]model.AuditEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAll indicates an expected call of GetAll.
+func (mr *AuditRepositoryMockRecorder) GetAll(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*AuditRepository)(nil).GetAll), ctx, filter)
+}
+
+// VersionRepository is a mock of VersionRepository interface.
+type VersionRepository struct {
+	ctrl     *gomock.Controller
+	recorder *VersionRepositoryMockRecorder
+}
+
+// VersionRepositoryMockRecorder is the mock recorder for VersionRepository.
+type VersionRepositoryMockRecorder struct {
+	mock *VersionRepository
+}
+
+// NewVersionRepository creates a new mock instance.
+func NewVersionRepository(ctrl *gomock.Controller) *VersionRepository {
+	mock := &VersionRepository{ctrl: ctrl}
+	mock.recorder = &VersionRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *VersionRepository) EXPECT() *VersionRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Approve mocks base method.
+func (m *VersionRepository) Approve(ctx context.Context, state string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Approve", ctx, state)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Approve indicates an expected call of Approve.
+func (mr *VersionRepositoryMockRecorder) Approve(ctx, state interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Approve", reflect.TypeOf((*VersionRepository)(nil).Approve), ctx, state)
+}
+
+// Count mocks base method.
+func (m *VersionRepository) Count(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Count", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Count indicates an expected call of Count.
+func (mr *VersionRepositoryMockRecorder) Count(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*VersionRepository)(nil).Count), ctx)
+}
+
+// DeleteApprovals mocks base method.
+func (m *VersionRepository) DeleteApprovals(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteApprovals", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteApprovals indicates an expected call of DeleteApprovals.
+func (mr *VersionRepositoryMockRecorder) DeleteApprovals(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteApprovals", reflect.TypeOf((*VersionRepository)(nil).DeleteApprovals), ctx)
+}
+
+// GetAll mocks base method.
+func (m *VersionRepository) GetAll(ctx context.Context, page model.Page) ([]model.ConfigVersion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll", ctx, page)
+	ret0, _ := ret[0].([]model.ConfigVersion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAll indicates an expected call of GetAll.
+func (mr *VersionRepositoryMockRecorder) GetAll(ctx, page interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*VersionRepository)(nil).GetAll), ctx, page)
+}
+
+// GetApprovers mocks base method.
+func (m *VersionRepository) GetApprovers(ctx context.Context, state string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetApprovers", ctx, state)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetApprovers indicates an expected call of GetApprovers.
+func (mr *VersionRepositoryMockRecorder) GetApprovers(ctx, state interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetApprovers", reflect.TypeOf((*VersionRepository)(nil).GetApprovers), ctx, state)
+}
+
+// GetByID mocks base method.
+func (m *VersionRepository) GetByID(ctx context.Context, id int) (model.ConfigVersion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByID", ctx, id)
+	ret0, _ := ret[0].(model.ConfigVersion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByID indicates an expected call of GetByID.
+func (mr *VersionRepositoryMockRecorder) GetByID(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*VersionRepository)(nil).GetByID), ctx, id)
+}
+
+// GetLatest mocks base method.
+func (m *VersionRepository) GetLatest(ctx context.Context) (model.ConfigVersion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLatest", ctx)
+	ret0, _ := ret[0].(model.ConfigVersion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLatest indicates an expected call of GetLatest.
+func (mr *VersionRepositoryMockRecorder) GetLatest(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLatest", reflect.TypeOf((*VersionRepository)(nil).GetLatest), ctx)
+}
+
+// Restore mocks base method.
+func (m *VersionRepository) Restore(ctx context.Context, id int) ([]model.VersionChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", ctx, id)
+	ret0, _ := ret[0].([]model.VersionChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *VersionRepositoryMockRecorder) Restore(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*VersionRepository)(nil).Restore), ctx, id)
+}
+
+// Save mocks base method.
+func (m *VersionRepository) Save(ctx context.Context, state, pac string, keep int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Save", ctx, state, pac, keep)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Save indicates an expected call of Save.
+func (mr *VersionRepositoryMockRecorder) Save(ctx, state, pac, keep interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*VersionRepository)(nil).Save), ctx, state, pac, keep)
+}
+
+// Snapshot mocks base method.
+func (m *VersionRepository) Snapshot(ctx context.Context) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Snapshot", ctx)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Snapshot indicates an expected call of Snapshot.
+func (mr *VersionRepositoryMockRecorder) Snapshot(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Snapshot", reflect.TypeOf((*VersionRepository)(nil).Snapshot), ctx)
+}
+
+// UserRepository is a mock of UserRepository interface.
+type UserRepository struct {
+	ctrl     *gomock.Controller
+	recorder *UserRepositoryMockRecorder
+}
+
+// UserRepositoryMockRecorder is the mock recorder for UserRepository.
+type UserRepositoryMockRecorder struct {
+	mock *UserRepository
+}
+
+// NewUserRepository creates a new mock instance.
+func NewUserRepository(ctrl *gomock.Controller) *UserRepository {
+	mock := &UserRepository{ctrl: ctrl}
+	mock.recorder = &UserRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *UserRepository) EXPECT() *UserRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Bootstrap mocks base method.
+func (m *UserRepository) Bootstrap(ctx context.Context, user *model.User) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Bootstrap", ctx, user)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Bootstrap indicates an expected call of Bootstrap.
+func (mr *UserRepositoryMockRecorder) Bootstrap(ctx, user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Bootstrap", reflect.TypeOf((*UserRepository)(nil).Bootstrap), ctx, user)
+}
+
+// Create mocks base method.
+func (m *UserRepository) Create(ctx context.Context, user *model.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, user)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *UserRepositoryMockRecorder) Create(ctx, user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*UserRepository)(nil).Create), ctx, user)
+}
+
+// Delete mocks base method.
+func (m *UserRepository) Delete(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *UserRepositoryMockRecorder) Delete(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*UserRepository)(nil).Delete), ctx, id)
+}
+
+// GetAll mocks base method.
+func (m *UserRepository) GetAll(ctx context.Context) ([]model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll", ctx)
+	ret0, _ := ret[0].([]model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAll indicates an expected call of GetAll.
+func (mr *UserRepositoryMockRecorder) GetAll(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*UserRepository)(nil).GetAll), ctx)
+}
+
+// GetByID mocks base method.
+func (m *UserRepository) GetByID(ctx context.Context, id int) (model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByID", ctx, id)
+	ret0, _ := ret[0].(model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByID indicates an expected call of GetByID.
+func (mr *UserRepositoryMockRecorder) GetByID(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*UserRepository)(nil).GetByID), ctx, id)
+}
+
+// GetByName mocks base method.
+func (m *UserRepository) GetByName(ctx context.Context, name string) (model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByName", ctx, name)
+	ret0, _ := ret[0].(model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByName indicates an expected call of GetByName.
+func (mr *UserRepositoryMockRecorder) GetByName(ctx, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByName", reflect.TypeOf((*UserRepository)(nil).GetByName), ctx, name)
+}
+
+// Update mocks base method.
+func (m *UserRepository) Update(ctx context.Context, user model.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, user)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *UserRepositoryMockRecorder) Update(ctx, user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*UserRepository)(nil).Update), ctx, user)
+}
+
+// TokenRepository is a mock of TokenRepository interface.
+type TokenRepository struct {
+	ctrl     *gomock.Controller
+	recorder *TokenRepositoryMockRecorder
+}
+
+// TokenRepositoryMockRecorder is the mock recorder for TokenRepository.
+type TokenRepositoryMockRecorder struct {
+	mock *TokenRepository
+}
+
+// NewTokenRepository creates a new mock instance.
+func NewTokenRepository(ctrl *gomock.Controller) *TokenRepository {
+	mock := &TokenRepository{ctrl: ctrl}
+	mock.recorder = &TokenRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *TokenRepository) EXPECT() *TokenRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *TokenRepository) Create(ctx context.Context, token *model.APIToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *TokenRepositoryMockRecorder) Create(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*TokenRepository)(nil).Create), ctx, token)
+}
+
+// Delete mocks base method.
+func (m *TokenRepository) Delete(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *TokenRepositoryMockRecorder) Delete(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*TokenRepository)(nil).Delete), ctx, id)
+}
+
+// GetAll mocks base method.
+func (m *TokenRepository) GetAll(ctx context.Context, userID int) ([]model.APIToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll", ctx, userID)
+	ret0, _ := ret[0].([]model.APIToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAll indicates an expected call of GetAll.
+func (mr *TokenRepositoryMockRecorder) GetAll(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*TokenRepository)(nil).GetAll), ctx, userID)
+}
+
+// GetByHash mocks base method.
+func (m *TokenRepository) GetByHash(ctx context.Context, hash string) (model.APIToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByHash", ctx, hash)
+	ret0, _ := ret[0].(model.APIToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByHash indicates an expected call of GetByHash.
+func (mr *TokenRepositoryMockRecorder) GetByHash(ctx, hash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByHash", reflect.TypeOf((*TokenRepository)(nil).GetByHash), ctx, hash)
+}
+
+// GetByID mocks base method.
+func (m *TokenRepository) GetByID(ctx context.Context, id int) (model.APIToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByID", ctx, id)
+	ret0, _ := ret[0].(model.APIToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByID indicates an expected call of GetByID.
+func (mr *TokenRepositoryMockRecorder) GetByID(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*TokenRepository)(nil).GetByID), ctx, id)
+}
+
+// Touch mocks base method.
+func (m *TokenRepository) Touch(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Touch", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Touch indicates an expected call of Touch.
+func (mr *TokenRepositoryMockRecorder) Touch(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Touch", reflect.TypeOf((*TokenRepository)(nil).Touch), ctx, id)
+}
+
+// PacService is a mock of pacService interface.
+type PacService struct {
+	ctrl     *gomock.Controller
+	recorder *PacServiceMockRecorder
+}
+
+// PacServiceMockRecorder is the mock recorder for PacService.
+type PacServiceMockRecorder struct {
+	mock *PacService
+}
+
+// NewPacService creates a new mock instance.
+func NewPacService(ctrl *gomock.Controller) *PacService {
+	mock := &PacService{ctrl: ctrl}
+	mock.recorder = &PacServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *PacService) EXPECT() *PacServiceMockRecorder {
+	return m.recorder
+}
+
+// GeneratePACFile mocks base method.
+func (m *PacService) GeneratePACFile(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GeneratePACFile", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// GeneratePACFile indicates an expected call of GeneratePACFile.
+func (mr *PacServiceMockRecorder) GeneratePACFile(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GeneratePACFile", reflect.TypeOf((*PacService)(nil).GeneratePACFile), ctx)
+}
+
+// PacPublisher is a mock of pacPublisher interface.
+type PacPublisher struct {
+	ctrl     *gomock.Controller
+	recorder *PacPublisherMockRecorder
+}
+
+// PacPublisherMockRecorder is the mock recorder for PacPublisher.
+type PacPublisherMockRecorder struct {
+	mock *PacPublisher
+}
+
+// NewPacPublisher creates a new mock instance.
+func NewPacPublisher(ctrl *gomock.Controller) *PacPublisher {
+	mock := &PacPublisher{ctrl: ctrl}
+	mock.recorder = &PacPublisherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *PacPublisher) EXPECT() *PacPublisherMockRecorder {
+	return m.recorder
+}
+
+// Publish mocks base method.
+func (m *PacPublisher) Publish(ctx context.Context, state string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Publish", ctx, state)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Publish indicates an expected call of Publish.
+func (mr *PacPublisherMockRecorder) Publish(ctx, state interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Publish", reflect.TypeOf((*PacPublisher)(nil).Publish), ctx, state)
+}
+
+// Render mocks base method.
+func (m *PacPublisher) Render(state string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Render", state)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Render indicates an expected call of Render.
+func (mr *PacPublisherMockRecorder) Render(state interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Render", reflect.TypeOf((*PacPublisher)(nil).Render), state)
+}
+
+// PacConfiguration is a mock of pacConfiguration interface.
+type PacConfiguration struct {
+	ctrl     *gomock.Controller
+	recorder *PacConfigurationMockRecorder
+}
+
+// PacConfigurationMockRecorder is the mock recorder for PacConfiguration.
+type PacConfigurationMockRecorder struct {
+	mock *PacConfiguration
+}
+
+// NewPacConfiguration creates a new mock instance.
+func NewPacConfiguration(ctrl *gomock.Controller) *PacConfiguration {
+	mock := &PacConfiguration{ctrl: ctrl}
+	mock.recorder = &PacConfigurationMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *PacConfiguration) EXPECT() *PacConfigurationMockRecorder {
+	return m.recorder
+}
+
+// Configuration mocks base method.
+func (m *PacConfiguration) Configuration(ctx context.Context) ([]model.BypassEntry, []model.Rule, []model.ProxyProfile, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Configuration", ctx)
+	ret0, _ := ret[0].([]model.BypassEntry)
+	ret1, _ := ret[1].([]model.Rule)
+	ret2, _ := ret[2].([]model.ProxyProfile)
+	ret3, _ := ret[3].(error)
+	return ret0, ret1, ret2, ret3
+}
+
+// Configuration indicates an expected call of Configuration.
+func (mr *PacConfigurationMockRecorder) Configuration(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Configuration", reflect.TypeOf((*PacConfiguration)(nil).Configuration), ctx)
+}
+
+// HealthChecker is a mock of healthChecker interface.
+type HealthChecker struct {
+	ctrl     *gomock.Controller
+	recorder *HealthCheckerMockRecorder
+}
+
+// HealthCheckerMockRecorder is the mock recorder for HealthChecker.
+type HealthCheckerMockRecorder struct {
+	mock *HealthChecker
+}
+
+// NewHealthChecker creates a new mock instance.
+func NewHealthChecker(ctrl *gomock.Controller) *HealthChecker {
+	mock := &HealthChecker{ctrl: ctrl}
+	mock.recorder = &HealthCheckerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *HealthChecker) EXPECT() *HealthCheckerMockRecorder {
+	return m.recorder
+}
+
+// IsDown mocks base method.
+func (m *HealthChecker) IsDown(id int) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsDown", id)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsDown indicates an expected call of IsDown.
+func (mr *HealthCheckerMockRecorder) IsDown(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsDown", reflect.TypeOf((*HealthChecker)(nil).IsDown), id)
 }
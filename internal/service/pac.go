@@ -1,62 +1,612 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
+	"encoding/json"
+	"github.com/nnemirovsky/pacgen/internal/errs"
 	"github.com/nnemirovsky/pacgen/internal/model"
 	"github.com/nnemirovsky/pacgen/pkg/gen"
 	"github.com/rs/zerolog"
 	"io"
+	"net"
 	"os"
+	"sort"
+	"strings"
+	"time"
 )
 
+// VersionOptions configures the versions of the configuration recorded by PACService.
+type VersionOptions struct {
+	// Keep is the number of the latest versions kept.
+	Keep int
+	// Drafts makes the changes drafts: pac file is generated from the latest version, which is the published one,
+	// until the draft is explicitly published as the new version.
+	Drafts bool
+}
+
 type PACService struct {
-	logger   zerolog.Logger
-	repo     RuleRepository
-	filePath string
+	logger      zerolog.Logger
+	repo        RuleRepository
+	profileRepo ProxyProfileRepository
+	bypassRepo  BypassRepository
+	versionRepo VersionRepository
+	health      healthChecker
+	filePath    string
+	opts        VersionOptions
 }
 
-func NewPACService(repo RuleRepository, filePath string, logger zerolog.Logger) *PACService {
+// NewPACService creates the service. Health checker is optional, without it all the profiles are considered up.
+// Version repository is optional as well, without it versions of the configuration are not recorded and the changes
+// are never drafts.
+func NewPACService(
+	repo RuleRepository,
+	profileRepo ProxyProfileRepository,
+	bypassRepo BypassRepository,
+	versionRepo VersionRepository,
+	health healthChecker,
+	filePath string,
+	opts VersionOptions,
+	logger zerolog.Logger,
+) *PACService {
 	return &PACService{
-		logger:   logger,
-		repo:     repo,
-		filePath: filePath,
+		logger:      logger,
+		repo:        repo,
+		profileRepo: profileRepo,
+		bypassRepo:  bypassRepo,
+		versionRepo: versionRepo,
+		health:      health,
+		filePath:    filePath,
+		opts:        opts,
 	}
 }
 
+// GeneratePACFile generates pac file and records the configuration it is generated from as a new version. When
+// the changes are drafts, pac file is generated from the published version instead, so only the health of the profiles
+// is taken into account. The current configuration is published if nothing has been published yet.
 func (s *PACService) GeneratePACFile(ctx context.Context) error {
-	rules, err := s.repo.GetAllWithProfiles(ctx)
+	if s.versionRepo == nil {
+		return s.generateCurrent(ctx)
+	}
+	if s.opts.Drafts {
+		return s.generatePublished(ctx)
+	}
+
+	// Pac file is rendered from the same snapshot that is recorded, so they can't get out of sync with each other.
+	state, err := s.versionRepo.Snapshot(ctx)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while selecting configuration of pac file")
+		return err
+	}
+	return s.Publish(ctx, state)
+}
+
+// generateCurrent generates pac file from the current configuration without recording it.
+func (s *PACService) generateCurrent(ctx context.Context) error {
+	src, err := s.source(ctx)
 	if err != nil {
-		s.logger.Error().Err(err).Msg("Error occurred while getting rules to generate pac file")
 		return err
 	}
 
-	if err = generatePACFile(rules, s.filePath); err != nil {
+	pac := &bytes.Buffer{}
+	if err = generatePAC(pac, src.bypass, src.rules, src.chains, src.pools); err != nil {
 		s.logger.Error().Err(err).Msg("Error occurred while generating pac file")
 		return err
 	}
+	if err = writePACFile(pac.Bytes(), s.filePath); err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while writing pac file")
+		return err
+	}
+	return nil
+}
+
+func (s *PACService) generatePublished(ctx context.Context) error {
+	version, err := s.versionRepo.GetLatest(ctx)
+	if err != nil {
+		if _, ok := err.(*errs.EntityNotFoundError); !ok {
+			s.logger.Error().Err(err).Msg("Error occurred while getting published configuration version")
+			return err
+		}
+
+		state, err := s.versionRepo.Snapshot(ctx)
+		if err != nil {
+			s.logger.Error().Err(err).Msg("Error occurred while selecting configuration of pac file")
+			return err
+		}
+		s.logger.Info().Msg("No configuration has been published yet, publishing the current one")
+		return s.Publish(ctx, state)
+	}
+
+	pac, err := s.Render(version.State)
+	if err != nil {
+		return err
+	}
+	if err = writePACFile([]byte(pac), s.filePath); err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while writing pac file")
+		return err
+	}
+	return nil
+}
 
+// Publish generates pac file from the state of the configuration, see model.ConfigVersion, and records both
+// of them as the new version, which becomes the published one.
+func (s *PACService) Publish(ctx context.Context, state string) error {
+	pac, err := s.Render(state)
+	if err != nil {
+		return err
+	}
+	if err = s.versionRepo.Save(ctx, state, pac, s.opts.Keep); err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while saving configuration version")
+		return err
+	}
+	if err = writePACFile([]byte(pac), s.filePath); err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while writing pac file")
+		return err
+	}
 	return nil
 }
 
-func generatePAC(wr io.Writer, rules []model.Rule) error {
-	conditions := make([]gen.Condition, 0)
+// Render returns pac file generated from the state of the configuration, see model.ConfigVersion, with the current
+// health of the profiles.
+func (s *PACService) Render(state string) (string, error) {
+	bypass, rules, profiles, err := parseState(state, time.Now())
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while parsing configuration state")
+		return "", err
+	}
+
+	src := s.pacSource(bypass, rules, profiles)
+	pac := &strings.Builder{}
+	if err = generatePAC(pac, src.bypass, src.rules, src.chains, src.pools); err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while generating pac file")
+		return "", err
+	}
+	return pac.String(), nil
+}
+
+// Match evaluates the host against the same rule set the pac file is generated from, i.e. the published one when
+// the changes are drafts. The host is expected in the form browsers pass it to pac file, i.e. lowercased,
+// in punycode and without port.
+func (s *PACService) Match(ctx context.Context, host string) (model.HostMatch, error) {
+	src, err := s.source(ctx)
+	if err != nil {
+		return model.HostMatch{}, err
+	}
+
+	set := pacRules(src.rules, src.chains, src.pools)
+	matcher, err := gen.NewMatcher(bypassPAC(src.bypass), set.lists, set.pools, set.conditions)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while building matcher")
+		return model.HostMatch{}, err
+	}
+
+	result := matcher.Match(host)
+	match := model.HostMatch{
+		Host:     host,
+		Bypassed: result.Bypassed,
+		Matches:  make([]model.RuleMatch, 0, len(result.Matches)),
+		Proxy:    result.Proxy,
+	}
+	for _, m := range result.Matches {
+		match.Matches = append(match.Matches, model.RuleMatch{Rule: set.rules[m.Index], Proxy: m.Action})
+	}
+	return match, nil
+}
+
+// pacSource is everything pac file is generated from.
+type pacSource struct {
+	bypass []model.BypassEntry
+	rules  []model.Rule
+	chains map[int]string
+	pools  map[int]gen.Pool
+}
+
+func (s *PACService) source(ctx context.Context) (pacSource, error) {
+	bypass, rules, profiles, err := s.Configuration(ctx)
+	if err != nil {
+		return pacSource{}, err
+	}
+	return s.pacSource(bypass, rules, profiles), nil
+}
+
+// Configuration returns the bypass entries, the rules in effect and the proxy profiles pac file is generated from.
+// When the changes are drafts, they are the ones of the published version, or the current ones if nothing has been
+// published yet.
+func (s *PACService) Configuration(
+	ctx context.Context,
+) ([]model.BypassEntry, []model.Rule, []model.ProxyProfile, error) {
+	if s.versionRepo != nil && s.opts.Drafts {
+		version, err := s.versionRepo.GetLatest(ctx)
+		if err == nil {
+			bypass, rules, profiles, err := parseState(version.State, time.Now())
+			if err != nil {
+				s.logger.Error().Err(err).Msg("Error occurred while parsing configuration state")
+			}
+			return bypass, rules, profiles, err
+		}
+		if _, ok := err.(*errs.EntityNotFoundError); !ok {
+			s.logger.Error().Err(err).Msg("Error occurred while getting published configuration version")
+			return nil, nil, nil, err
+		}
+	}
+
+	rules, err := s.repo.GetAllWithProfiles(ctx)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while getting rules of pac file")
+		return nil, nil, nil, err
+	}
+
+	profiles, err := s.profileRepo.GetAll(ctx, model.ProxyProfileFilter{})
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while getting proxy profiles of pac file")
+		return nil, nil, nil, err
+	}
+
+	bypass, err := s.bypassRepo.GetAll(ctx)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while getting bypass entries of pac file")
+		return nil, nil, nil, err
+	}
+
+	return bypass, rules, profiles, nil
+}
+
+// pacSource routes the rules through the routable profiles, taking their health into account.
+func (s *PACService) pacSource(
+	bypass []model.BypassEntry,
+	rules []model.Rule,
+	profiles []model.ProxyProfile,
+) pacSource {
+	isDown := func(int) bool { return false }
+	if s.health != nil {
+		isDown = s.health.IsDown
+	}
+
+	routable := make([]model.ProxyProfile, 0, len(profiles))
+	for _, profile := range profiles {
+		if !profile.Routable() {
+			s.logger.Warn().Int("profile-id", profile.ID).Str("name", profile.Name).
+				Msg("Proxy profile has no valid address, it is left out of pac file until updated")
+			continue
+		}
+		routable = append(routable, profile)
+	}
+
+	return pacSource{
+		bypass: bypass,
+		rules:  rules,
+		chains: proxyChains(routable, isDown),
+		pools:  proxyPools(routable, isDown),
+	}
+}
+
+// stateTimeLayout is the layout of the time bounds of the rules in the state of the configuration.
+const stateTimeLayout = "2006-01-02 15:04:05"
+
+// parseState returns the bypass entries, the rules in effect at the time and the proxy profiles of the state
+// of the configuration, see model.ConfigVersion. Rules come with their profiles and domain lists, the same way
+// as the repositories return them.
+func parseState(state string, now time.Time) ([]model.BypassEntry, []model.Rule, []model.ProxyProfile, error) {
+	var config struct {
+		ProxyProfiles []struct {
+			ID        int             `json:"id"`
+			Name      string          `json:"name"`
+			Type      model.ProxyType `json:"type"`
+			Host      string          `json:"host"`
+			Port      int             `json:"port"`
+			StandbyID int             `json:"standby_profile_id"`
+			Members   []struct {
+				ProfileID int `json:"profile_id"`
+				Weight    int `json:"weight"`
+			} `json:"members"`
+		} `json:"proxy_profiles"`
+		DomainLists []struct {
+			ID      int    `json:"id"`
+			Name    string `json:"name"`
+			Entries []struct {
+				Domain string           `json:"domain"`
+				Mode   model.DomainMode `json:"mode"`
+			} `json:"entries"`
+		} `json:"domain_lists"`
+		Rules []struct {
+			ID             int    `json:"id"`
+			Regex          string `json:"regex"`
+			DomainListID   int    `json:"domain_list_id"`
+			ProxyProfileID int    `json:"proxy_profile_id"`
+			Enabled        bool   `json:"enabled"`
+			ActiveFrom     string `json:"active_from"`
+			ExpiresAt      string `json:"expires_at"`
+		} `json:"rules"`
+		Bypass struct {
+			Entries []struct {
+				Kind  model.BypassKind `json:"kind"`
+				Value string           `json:"value"`
+			} `json:"entries"`
+		} `json:"bypass"`
+	}
+	if err := json.Unmarshal([]byte(state), &config); err != nil {
+		return nil, nil, nil, err
+	}
+
+	profiles := make([]model.ProxyProfile, 0, len(config.ProxyProfiles))
+	profilesByID := make(map[int]model.ProxyProfile, len(config.ProxyProfiles))
+	for _, p := range config.ProxyProfiles {
+		profile := model.ProxyProfile{
+			ID:        p.ID,
+			Name:      p.Name,
+			Type:      p.Type,
+			Host:      p.Host,
+			Port:      p.Port,
+			StandbyID: p.StandbyID,
+		}
+		if p.Type == model.Pool {
+			profile.Members = make([]model.PoolMember, 0, len(p.Members))
+			for _, m := range p.Members {
+				profile.Members = append(profile.Members, model.PoolMember{ProfileID: m.ProfileID, Weight: m.Weight})
+			}
+		}
+		profiles = append(profiles, profile)
+		profilesByID[profile.ID] = profile
+	}
+
+	lists := make(map[int]*model.DomainList, len(config.DomainLists))
+	for _, l := range config.DomainLists {
+		list := &model.DomainList{ID: l.ID, Name: l.Name, Entries: make([]model.DomainListEntry, 0, len(l.Entries))}
+		for _, e := range l.Entries {
+			list.Entries = append(list.Entries, model.DomainListEntry{Domain: e.Domain, Mode: e.Mode})
+		}
+		sort.Slice(list.Entries, func(i, j int) bool { return list.Entries[i].Domain < list.Entries[j].Domain })
+		lists[list.ID] = list
+	}
+
+	rules := make([]model.Rule, 0, len(config.Rules))
+	for _, r := range config.Rules {
+		if !r.Enabled || r.ActiveFrom != "" && now.Before(parseStateTime(r.ActiveFrom)) ||
+			r.ExpiresAt != "" && !now.Before(parseStateTime(r.ExpiresAt)) {
+			continue
+		}
+		profile, ok := profilesByID[r.ProxyProfileID]
+		if !ok {
+			continue
+		}
+		rule := model.Rule{ID: r.ID, Regex: r.Regex, DomainListID: r.DomainListID, Enabled: true, ProxyProfile: &profile}
+		if r.DomainListID != 0 {
+			rule.DomainList = lists[r.DomainListID]
+		}
+		rules = append(rules, rule)
+	}
+
+	bypass := make([]model.BypassEntry, 0, len(config.Bypass.Entries))
+	for _, e := range config.Bypass.Entries {
+		bypass = append(bypass, model.BypassEntry{Kind: e.Kind, Value: e.Value})
+	}
+	return bypass, rules, profiles, nil
+}
+
+// parseStateTime parses the time bound of the rule, unparsable bounds are taken as the zero time.
+func parseStateTime(value string) time.Time {
+	t, _ := time.Parse(stateTimeLayout, value)
+	return t
+}
+
+// proxyChains returns PAC proxy list of every profile by its id. Profile with a standby is followed by it.
+// Profiles that are down are left out of the list, unless nothing else remains.
+func proxyChains(profiles []model.ProxyProfile, isDown func(id int) bool) map[int]string {
+	byID := make(map[int]model.ProxyProfile, len(profiles))
+	for _, profile := range profiles {
+		byID[profile.ID] = profile
+	}
+
+	chains := make(map[int]string, len(profiles))
+	for _, profile := range profiles {
+		if profile.Type == model.Pool {
+			continue
+		}
+		chain := []model.ProxyProfile{profile}
+		if standby, ok := byID[profile.StandbyID]; ok {
+			chain = append(chain, standby)
+		}
+
+		alive := make([]string, 0, len(chain))
+		all := make([]string, 0, len(chain))
+		for _, p := range chain {
+			all = append(all, proxyString(p))
+			if !isDown(p.ID) {
+				alive = append(alive, proxyString(p))
+			}
+		}
+		if len(alive) == 0 {
+			alive = all
+		}
+		chains[profile.ID] = strings.Join(alive, "; ")
+	}
+	return chains
+}
+
+// proxyPools returns pools by their ids. Members that are down are left out, unless nothing else remains.
+// Standby profile of the pool is the last resort: it follows the members and is used alone if all of them are down.
+func proxyPools(profiles []model.ProxyProfile, isDown func(id int) bool) map[int]gen.Pool {
+	byID := make(map[int]model.ProxyProfile, len(profiles))
+	for _, profile := range profiles {
+		byID[profile.ID] = profile
+	}
+
+	pools := make(map[int]gen.Pool)
+	for _, profile := range profiles {
+		if profile.Type != model.Pool {
+			continue
+		}
+
+		alive := make([]gen.PoolMember, 0, len(profile.Members))
+		all := make([]gen.PoolMember, 0, len(profile.Members))
+		for _, member := range profile.Members {
+			p, ok := byID[member.ProfileID]
+			if !ok {
+				continue
+			}
+			all = append(all, gen.PoolMember{Weight: member.Weight, Action: proxyString(p)})
+			if !isDown(p.ID) {
+				alive = append(alive, gen.PoolMember{Weight: member.Weight, Action: proxyString(p)})
+			}
+		}
+
+		standby, hasStandby := byID[profile.StandbyID]
+		switch {
+		case len(alive) == 0 && hasStandby && !isDown(standby.ID):
+			alive = append(alive, gen.PoolMember{Weight: 1, Action: proxyString(standby)})
+		case len(alive) == 0 && hasStandby:
+			alive = append(all, gen.PoolMember{Action: proxyString(standby)})
+		case len(alive) == 0:
+			alive = all
+		case hasStandby && !isDown(standby.ID):
+			alive = append(alive, gen.PoolMember{Action: proxyString(standby)})
+		}
+		if len(alive) == 0 {
+			continue
+		}
+		pools[profile.ID] = gen.Pool{ID: profile.ID, Members: alive}
+	}
+	return pools
+}
+
+func proxyString(profile model.ProxyProfile) string {
+	if profile.Type == model.Direct {
+		return "DIRECT"
+	}
+	return profile.Type.String() + " " + profile.Address()
+}
+
+// privateNetworks are loopback, private (RFC 1918) and link-local IPv4 ranges.
+var privateNetworks = []gen.Network{
+	{Addr: 0x0A000000, Mask: 0xFF000000}, // 10.0.0.0/8
+	{Addr: 0x7F000000, Mask: 0xFF000000}, // 127.0.0.0/8
+	{Addr: 0xA9FE0000, Mask: 0xFFFF0000}, // 169.254.0.0/16
+	{Addr: 0xAC100000, Mask: 0xFFF00000}, // 172.16.0.0/12
+	{Addr: 0xC0A80000, Mask: 0xFFFF0000}, // 192.168.0.0/16
+}
+
+// privateIPv6Regex matches loopback, unique local (RFC 4193) and link-local IPv6 addresses, with or without
+// brackets, since browsers differ in how they pass them.
+const privateIPv6Regex = `^\[?(?:::1\]?$|f[cd][0-9a-f]{2}:|fe[89ab][0-9a-f]:)`
+
+// bypassPAC converts the bypass entries, presets are expanded into the hosts they match.
+func bypassPAC(entries []model.BypassEntry) gen.Bypass {
+	bypass := gen.Bypass{}
+	for _, entry := range entries {
+		switch entry.Kind {
+		case model.BypassPreset:
+			switch entry.Value {
+			case model.PresetPlainHostNames:
+				bypass.PlainHostNames = true
+			case model.PresetLocalDomains:
+				bypass.Domains = append(bypass.Domains, gen.DomainListEntry{Domain: "local", WithSubdomains: true})
+			case model.PresetPrivateNetworks:
+				bypass.Networks = append(bypass.Networks, privateNetworks...)
+				bypass.Regexes = append(bypass.Regexes, privateIPv6Regex)
+			}
+		case model.BypassDomain, model.BypassDomainAndSubdomains:
+			bypass.Domains = append(bypass.Domains, gen.DomainListEntry{
+				Domain:         entry.Value,
+				WithSubdomains: entry.Kind == model.BypassDomainAndSubdomains,
+			})
+		case model.BypassNetwork:
+			_, ipNet, err := net.ParseCIDR(entry.Value)
+			if err != nil || ipNet.IP.To4() == nil {
+				continue
+			}
+			bypass.Networks = append(bypass.Networks, gen.Network{
+				Addr: binary.BigEndian.Uint32(ipNet.IP.To4()),
+				Mask: binary.BigEndian.Uint32(ipNet.Mask[len(ipNet.Mask)-4:]),
+			})
+		}
+	}
+	return bypass
+}
+
+// pacRuleSet is the rule set of pac file, rules[i] is the rule conditions[i] is made of.
+type pacRuleSet struct {
+	lists      []gen.DomainList
+	pools      []gen.Pool
+	conditions []gen.Condition
+	rules      []model.Rule
+}
+
+// pacRules converts the rules into the conditions of pac file. Rules are routed through the pools or the chains
+// of their profiles, the profile itself is used if it has neither. Rules that cannot be routed, i.e. through a profile
+// without address or a pool without members, are left out.
+func pacRules(rules []model.Rule, chains map[int]string, pools map[int]gen.Pool) pacRuleSet {
+	set := pacRuleSet{
+		lists:      make([]gen.DomainList, 0),
+		pools:      make([]gen.Pool, 0),
+		conditions: make([]gen.Condition, 0),
+		rules:      make([]model.Rule, 0),
+	}
+	seen := make(map[int]bool)
+	seenPools := make(map[int]bool)
 	for _, rule := range rules {
-		action := "DIRECT"
-		if rule.ProxyProfile != nil {
-			action = rule.ProxyProfile.Type.String() + " " + rule.ProxyProfile.Address
+		condition := gen.Condition{Action: "DIRECT"}
+		if profile := rule.ProxyProfile; profile != nil {
+			pool, isPool := pools[profile.ID]
+			if !profile.Routable() || profile.Type == model.Pool && !isPool {
+				continue
+			}
+			condition.Action = proxyString(*profile)
+			if chain, ok := chains[profile.ID]; ok {
+				condition.Action = chain
+			}
+			if isPool {
+				condition.Pool = pool.ID
+				if !seenPools[pool.ID] {
+					seenPools[pool.ID] = true
+					set.pools = append(set.pools, pool)
+				}
+			}
+		}
+		set.rules = append(set.rules, rule)
+		if rule.DomainList == nil {
+			condition.Regex = rule.Regex
+			set.conditions = append(set.conditions, condition)
+			continue
 		}
-		conditions = append(conditions, gen.Condition{Regex: rule.Regex, Action: action})
+
+		condition.List = rule.DomainList.ID
+		set.conditions = append(set.conditions, condition)
+		if seen[rule.DomainList.ID] {
+			continue
+		}
+		seen[rule.DomainList.ID] = true
+		list := gen.DomainList{ID: rule.DomainList.ID}
+		for _, entry := range rule.DomainList.Entries {
+			list.Entries = append(list.Entries, gen.DomainListEntry{
+				Domain:         entry.Domain,
+				WithSubdomains: entry.Mode == model.DomainAndSubdomains,
+			})
+		}
+		set.lists = append(set.lists, list)
 	}
+	return set
+}
 
-	return gen.Generate(wr, conditions)
+// generatePAC writes PAC file with the bypass followed by the rules.
+func generatePAC(
+	wr io.Writer,
+	bypass []model.BypassEntry,
+	rules []model.Rule,
+	chains map[int]string,
+	pools map[int]gen.Pool,
+) error {
+	set := pacRules(rules, chains, pools)
+	return gen.Generate(wr, bypassPAC(bypass), set.lists, set.pools, set.conditions)
 }
 
-func generatePACFile(rules []model.Rule, filePath string) error {
+func writePACFile(pac []byte, filePath string) error {
 	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC|os.O_SYNC, 0644)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 
-	return generatePAC(file, rules)
+	_, err = file.Write(pac)
+	return err
 }
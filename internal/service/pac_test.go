@@ -2,9 +2,18 @@ package service
 
 import (
 	"bytes"
+	"context"
 	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
 	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/nnemirovsky/pacgen/internal/service/mock"
+	"github.com/nnemirovsky/pacgen/pkg/gen"
+	"github.com/nnemirovsky/pacgen/pkg/logutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestGeneratePAC_OK(t *testing.T) {
@@ -17,25 +26,36 @@ func TestGeneratePAC_OK(t *testing.T) {
 			ID:    1,
 			Regex: `^www\.google\.com$`,
 			ProxyProfile: &model.ProxyProfile{
-				ID:      1,
-				Name:    "tor",
-				Type:    model.Socks5,
-				Address: "localhost:9050",
+				ID:   1,
+				Name: "tor",
+				Type: model.Socks5,
+				Host: "localhost",
+				Port: 9050,
 			},
 		},
 		{
 			ID:    2,
 			Regex: `(?:^|\.)facebook\.com$`,
 			ProxyProfile: &model.ProxyProfile{
-				ID:      2,
-				Name:    "shadowsocks",
-				Type:    model.Socks5,
-				Address: "localhost:1080",
+				ID:   2,
+				Name: "shadowsocks",
+				Type: model.Socks5,
+				Host: "localhost",
+				Port: 1080,
+			},
+		},
+		{
+			ID:    3,
+			Regex: `(?:^|\.)corp$`,
+			ProxyProfile: &model.ProxyProfile{
+				ID:   3,
+				Name: "intranet",
+				Type: model.Direct,
 			},
 		},
 	}
 
-	err := generatePAC(buff, rules)
+	err := generatePAC(buff, nil, rules, nil, nil)
 	if err != nil {
 		t.Errorf("Unexpected error: %#v", err)
 	}
@@ -43,9 +63,515 @@ func TestGeneratePAC_OK(t *testing.T) {
 	want := `function FindProxyForURL(url, host) {
 	if (/^www\.google\.com$/.test(host)) return 'SOCKS5 localhost:9050';
 	if (/(?:^|\.)facebook\.com$/.test(host)) return 'SOCKS5 localhost:1080';
+	if (/(?:^|\.)corp$/.test(host)) return 'DIRECT';
+	return 'DIRECT';
+}`
+	got := buff.String()
+
+	assert.Equal(t, got, want)
+}
+
+func TestGeneratePAC_DomainLists(t *testing.T) {
+	t.Parallel()
+
+	buff := bytes.NewBuffer([]byte{})
+
+	cdn := &model.DomainList{
+		ID: 7,
+		Entries: []model.DomainListEntry{
+			{Domain: "googlevideo.com", Mode: model.DomainAndSubdomains},
+			{Domain: "vimeo.com", Mode: model.ExactDomain},
+		},
+	}
+	tor := &model.ProxyProfile{ID: 1, Name: "tor", Type: model.Socks5, Host: "localhost", Port: 9050}
+
+	rules := []model.Rule{
+		{ID: 1, DomainListID: 7, DomainList: cdn, ProxyProfile: tor},
+		{ID: 2, Regex: `(?:^|\.)corp$`, ProxyProfile: &model.ProxyProfile{ID: 3, Name: "intranet", Type: model.Direct}},
+		{ID: 3, DomainListID: 8, DomainList: &model.DomainList{ID: 8}, ProxyProfile: tor},
+		{ID: 4, DomainListID: 7, DomainList: cdn, ProxyProfile: tor},
+	}
+
+	err := generatePAC(buff, nil, rules, nil, nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	want := `var lists = {
+	7: {'googlevideo.com': 2, 'vimeo.com': 1},
+	8: {}
+};
+
+function inList(list, host) {
+	if (Object.prototype.hasOwnProperty.call(list, host)) return true;
+	for (var i = host.indexOf('.'); i !== -1; i = host.indexOf('.', i + 1)) {
+		if (list[host.substring(i + 1)] === 2) return true;
+	}
+	return false;
+}
+
+function FindProxyForURL(url, host) {
+	if (inList(lists[7], host)) return 'SOCKS5 localhost:9050';
+	if (/(?:^|\.)corp$/.test(host)) return 'DIRECT';
+	if (inList(lists[8], host)) return 'SOCKS5 localhost:9050';
+	if (inList(lists[7], host)) return 'SOCKS5 localhost:9050';
+	return 'DIRECT';
+}`
+	got := buff.String()
+
+	assert.Equal(t, got, want)
+}
+
+func TestGeneratePAC_CanonicalAddress(t *testing.T) {
+	t.Parallel()
+
+	buff := bytes.NewBuffer([]byte{})
+
+	rules := []model.Rule{
+		{ID: 1, Regex: `^a$`, ProxyProfile: &model.ProxyProfile{ID: 1, Type: model.Http, Host: "::1", Port: 3128}},
+		// Legacy address that could not be normalized, the rule is left out.
+		{ID: 2, Regex: `^b$`, ProxyProfile: &model.ProxyProfile{ID: 2, Type: model.Http}},
+	}
+
+	err := generatePAC(buff, nil, rules, nil, nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	want := `function FindProxyForURL(url, host) {
+	if (/^a$/.test(host)) return 'HTTP [::1]:3128';
+	return 'DIRECT';
+}`
+	got := buff.String()
+
+	assert.Equal(t, got, want)
+}
+
+func TestGeneratePAC_Bypass(t *testing.T) {
+	t.Parallel()
+
+	buff := bytes.NewBuffer([]byte{})
+
+	bypass := []model.BypassEntry{
+		{Kind: model.BypassPreset, Value: model.PresetPlainHostNames},
+		{Kind: model.BypassPreset, Value: model.PresetPrivateNetworks},
+		{Kind: model.BypassPreset, Value: model.PresetLocalDomains},
+		{Kind: model.BypassDomain, Value: "example.com"},
+		{Kind: model.BypassDomainAndSubdomains, Value: "corp"},
+		{Kind: model.BypassNetwork, Value: "100.64.0.0/10"},
+	}
+	rules := []model.Rule{
+		{ID: 1, Regex: `(?:^|\.)com$`, ProxyProfile: &model.ProxyProfile{ID: 1, Type: model.Http, Host: "proxy", Port: 3128}},
+	}
+
+	err := generatePAC(buff, bypass, rules, nil, nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	want := `var bypass = {'local': 2, 'example.com': 1, 'corp': 2};
+
+function inList(list, host) {
+	if (Object.prototype.hasOwnProperty.call(list, host)) return true;
+	for (var i = host.indexOf('.'); i !== -1; i = host.indexOf('.', i + 1)) {
+		if (list[host.substring(i + 1)] === 2) return true;
+	}
+	return false;
+}
+
+var bypassNets = [[167772160, 4278190080], [2130706432, 4278190080], [2851995648, 4294901760], ` +
+		`[2886729728, 4293918720], [3232235520, 4294901760], [1681915904, 4290772992]];
+
+// inNets reports whether the host is an IPv4 address from one of the networks. Host names are not resolved.
+function inNets(nets, host) {
+	var m = /^(\d{1,3})\.(\d{1,3})\.(\d{1,3})\.(\d{1,3})$/.exec(host);
+	if (!m) return false;
+	var ip = ((m[1] << 24) | (m[2] << 16) | (m[3] << 8) | m[4]) >>> 0;
+	for (var i = 0; i < nets.length; i++) {
+		if (((ip & nets[i][1]) >>> 0) === nets[i][0]) return true;
+	}
+	return false;
+}
+
+function FindProxyForURL(url, host) {
+	if (isPlainHostName(host) && host.indexOf(':') === -1) return 'DIRECT';
+	if (inList(bypass, host)) return 'DIRECT';
+	if (inNets(bypassNets, host)) return 'DIRECT';
+	if (/^\[?(?:::1\]?$|f[cd][0-9a-f]{2}:|fe[89ab][0-9a-f]:)/.test(host)) return 'DIRECT';
+	if (/(?:^|\.)com$/.test(host)) return 'HTTP proxy:3128';
+	return 'DIRECT';
+}`
+	got := buff.String()
+
+	assert.Equal(t, got, want)
+}
+
+func TestProxyChains(t *testing.T) {
+	t.Parallel()
+
+	profiles := []model.ProxyProfile{
+		{ID: 1, Type: model.Socks5, Host: "a", Port: 1080, StandbyID: 2},
+		{ID: 2, Type: model.Socks5, Host: "b", Port: 1080},
+		{ID: 3, Type: model.Direct},
+	}
+
+	cases := map[string]struct {
+		down map[int]bool
+		want map[int]string
+	}{
+		"all up": {
+			down: map[int]bool{},
+			want: map[int]string{1: "SOCKS5 a:1080; SOCKS5 b:1080", 2: "SOCKS5 b:1080", 3: "DIRECT"},
+		},
+		"primary down": {
+			down: map[int]bool{1: true},
+			want: map[int]string{1: "SOCKS5 b:1080", 2: "SOCKS5 b:1080", 3: "DIRECT"},
+		},
+		"both down": {
+			down: map[int]bool{1: true, 2: true},
+			want: map[int]string{1: "SOCKS5 a:1080; SOCKS5 b:1080", 2: "SOCKS5 b:1080", 3: "DIRECT"},
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got := proxyChains(profiles, func(id int) bool { return c.down[id] })
+
+			assert.Equal(t, got, c.want)
+		})
+	}
+}
+
+func TestGeneratePAC_Pools(t *testing.T) {
+	t.Parallel()
+
+	buff := bytes.NewBuffer([]byte{})
+
+	pool := &model.ProxyProfile{ID: 6, Name: "egress", Type: model.Pool}
+	rules := []model.Rule{
+		{ID: 1, Regex: `^a\.com$`, ProxyProfile: pool},
+		{ID: 2, Regex: `^b\.com$`, ProxyProfile: &model.ProxyProfile{ID: 1, Type: model.Direct}},
+		{ID: 3, Regex: `^c\.com$`, ProxyProfile: pool},
+	}
+	pools := map[int]gen.Pool{
+		6: {ID: 6, Members: []gen.PoolMember{{Weight: 3, Action: "SOCKS5 a:1080"}, {Weight: 1, Action: "SOCKS5 b:1080"}}},
+		7: {ID: 7, Members: []gen.PoolMember{{Weight: 1, Action: "SOCKS5 c:1080"}}},
+	}
+
+	err := generatePAC(buff, nil, rules, nil, pools)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	want := `var pools = {
+	6: [[3, 'SOCKS5 a:1080'], [1, 'SOCKS5 b:1080']]
+};
+
+// hash is 32-bit FNV-1a, multiplication is done with shifts to stay exact without Math.imul.
+function hash(s) {
+	var h = 2166136261;
+	for (var i = 0; i < s.length; i++) {
+		h ^= s.charCodeAt(i);
+		h = (h + (h << 1) + (h << 4) + (h << 7) + (h << 8) + (h << 24)) >>> 0;
+	}
+	return h;
+}
+
+// pick orders members of the pool by weighted rendezvous hashing of the host, so a host sticks to its member
+// and only hosts of a member that is gone are moved to the others.
+function pick(pool, host) {
+	var scored = [], chain = [], i;
+	for (i = 0; i < pool.length; i++) {
+		var h = hash(pool[i][1] + ' ' + host);
+		scored.push([pool[i][0] / -Math.log((h + 1) / 4294967297), pool[i][1]]);
+	}
+	scored.sort(function (a, b) { return b[0] - a[0]; });
+	for (i = 0; i < scored.length; i++) chain.push(scored[i][1]);
+	return chain.join('; ');
+}
+
+function FindProxyForURL(url, host) {
+	if (/^a\.com$/.test(host)) return pick(pools[6], host);
+	if (/^b\.com$/.test(host)) return 'DIRECT';
+	if (/^c\.com$/.test(host)) return pick(pools[6], host);
 	return 'DIRECT';
 }`
 	got := buff.String()
 
 	assert.Equal(t, got, want)
 }
+
+func TestProxyPools(t *testing.T) {
+	t.Parallel()
+
+	profiles := []model.ProxyProfile{
+		{ID: 1, Type: model.Socks5, Host: "a", Port: 1080},
+		{ID: 2, Type: model.Socks5, Host: "b", Port: 1080},
+		{ID: 3, Type: model.Http, Host: "s", Port: 3128},
+		{ID: 6, Type: model.Pool, StandbyID: 3, Members: []model.PoolMember{{ProfileID: 1, Weight: 3}, {ProfileID: 2, Weight: 1}}},
+	}
+
+	cases := map[string]struct {
+		down map[int]bool
+		want []gen.PoolMember
+	}{
+		"all up": {
+			down: map[int]bool{},
+			want: []gen.PoolMember{{Weight: 3, Action: "SOCKS5 a:1080"}, {Weight: 1, Action: "SOCKS5 b:1080"}, {Action: "HTTP s:3128"}},
+		},
+		"member down": {
+			down: map[int]bool{1: true},
+			want: []gen.PoolMember{{Weight: 1, Action: "SOCKS5 b:1080"}, {Action: "HTTP s:3128"}},
+		},
+		"members down": {
+			down: map[int]bool{1: true, 2: true},
+			want: []gen.PoolMember{{Weight: 1, Action: "HTTP s:3128"}},
+		},
+		"all down": {
+			down: map[int]bool{1: true, 2: true, 3: true},
+			want: []gen.PoolMember{{Weight: 3, Action: "SOCKS5 a:1080"}, {Weight: 1, Action: "SOCKS5 b:1080"}, {Action: "HTTP s:3128"}},
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got := proxyPools(profiles, func(id int) bool { return c.down[id] })
+
+			assert.Equal(t, got, map[int]gen.Pool{6: {ID: 6, Members: c.want}})
+		})
+	}
+}
+
+func TestPACService_Match(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	ruleRepoMock := mock.NewRuleRepository(ctrl)
+	profileRepoMock := mock.NewProxyProfileRepository(ctrl)
+	bypassRepoMock := mock.NewBypassRepository(ctrl)
+	srvc := NewPACService(
+		ruleRepoMock,
+		profileRepoMock,
+		bypassRepoMock,
+		nil,
+		nil,
+		"",
+		VersionOptions{},
+		logutil.DiscardLogger,
+	)
+
+	tor := model.ProxyProfile{ID: 1, Name: "tor", Type: model.Socks5, Host: "localhost", Port: 9050}
+	broken := model.ProxyProfile{ID: 2, Name: "broken", Type: model.Https}
+	list := &model.DomainList{ID: 4, Entries: []model.DomainListEntry{{Domain: "example.com", Mode: model.DomainAndSubdomains}}}
+	rules := []model.Rule{
+		{ID: 1, Regex: `^www\.example\.com$`, ProxyProfile: &broken},
+		{ID: 2, DomainListID: 4, DomainList: list, ProxyProfile: &tor},
+		{ID: 3, Regex: `(?:^|\.)example\.com$`, ProxyProfile: &model.ProxyProfile{ID: 3, Type: model.Direct}},
+		{ID: 4, Regex: `^example\.org$`, ProxyProfile: &tor},
+	}
+
+	ruleRepoMock.EXPECT().GetAllWithProfiles(gomock.Any()).Return(rules, nil).Times(2)
+	profileRepoMock.EXPECT().GetAll(gomock.Any(), model.ProxyProfileFilter{}).
+		Return([]model.ProxyProfile{tor, broken}, nil).Times(2)
+	bypassRepoMock.EXPECT().GetAll(gomock.Any()).
+		Return([]model.BypassEntry{{Kind: model.BypassDomain, Value: "intra.example.com"}}, nil).Times(2)
+
+	got, err := srvc.Match(context.Background(), "www.example.com")
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	want := model.HostMatch{
+		Host: "www.example.com",
+		Matches: []model.RuleMatch{
+			{Rule: rules[1], Proxy: "SOCKS5 localhost:9050"},
+			{Rule: rules[2], Proxy: "DIRECT"},
+		},
+		Proxy: "SOCKS5 localhost:9050",
+	}
+	assert.Equal(t, got, want)
+
+	got, err = srvc.Match(context.Background(), "intra.example.com")
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	assert.Equal(t, got.Bypassed, true)
+	assert.Equal(t, len(got.Matches), 2)
+	assert.Equal(t, got.Proxy, "DIRECT")
+}
+
+func TestPACService_GeneratePACFile_Version(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	versionRepoMock := mock.NewVersionRepository(ctrl)
+	filePath := filepath.Join(t.TempDir(), "proxy.pac")
+	// Pac file is rendered from the recorded snapshot, the repositories of the rules, the profiles and the bypass list
+	// expect nothing.
+	srvc := NewPACService(
+		mock.NewRuleRepository(ctrl),
+		mock.NewProxyProfileRepository(ctrl),
+		mock.NewBypassRepository(ctrl),
+		versionRepoMock,
+		nil,
+		filePath,
+		VersionOptions{Keep: 10},
+		logutil.DiscardLogger,
+	)
+
+	state := testPublishedState("example.org")
+
+	var saved string
+	versionRepoMock.EXPECT().Snapshot(gomock.Any()).Return(state, nil)
+	versionRepoMock.EXPECT().
+		Save(gomock.Any(), state, gomock.Any(), 10).
+		DoAndReturn(func(ctx context.Context, state, pac string, keep int) error {
+			saved = pac
+			return nil
+		})
+
+	if err := srvc.GeneratePACFile(context.Background()); err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	written, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, saved, string(written))
+	assert.Equal(t, strings.Contains(saved, "SOCKS5 localhost:9050"), true)
+	assert.Equal(t, strings.Contains(saved, `/^example\.org$/`), true)
+}
+
+func TestPACService_GeneratePACFile_Drafts(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	versionRepoMock := mock.NewVersionRepository(ctrl)
+	filePath := filepath.Join(t.TempDir(), "proxy.pac")
+	srvc := NewPACService(
+		mock.NewRuleRepository(ctrl),
+		mock.NewProxyProfileRepository(ctrl),
+		mock.NewBypassRepository(ctrl),
+		versionRepoMock,
+		nil,
+		filePath,
+		VersionOptions{Keep: 10, Drafts: true},
+		logutil.DiscardLogger,
+	)
+
+	// The draft is not even looked at, pac file is generated from the published version.
+	published := `{"proxy_profiles":[{"id":1,"name":"tor","type":4,"host":"localhost","port":9050,"members":[]}],` +
+		`"domain_lists":[],"rules":[{"id":1,"regex":"^example\\.org$","proxy_profile_id":1,"enabled":true}],` +
+		`"bypass":{"entries":[]}}`
+	versionRepoMock.EXPECT().GetLatest(gomock.Any()).Return(model.ConfigVersion{ID: 3, State: published}, nil)
+
+	if err := srvc.GeneratePACFile(context.Background()); err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	written, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pac, err := srvc.Render(published)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	assert.Equal(t, string(written), pac)
+	assert.Equal(t, strings.Contains(pac, "SOCKS5 localhost:9050"), true)
+}
+
+// testPublishedState is the state of the configuration routing the domain through tor.
+func testPublishedState(domain string) string {
+	return `{"proxy_profiles":[{"id":1,"name":"tor","type":4,"host":"localhost","port":9050,"members":[]}],` +
+		`"domain_lists":[],"rules":[{"id":1,"regex":"^` + strings.ReplaceAll(domain, ".", `\\.`) +
+		`$","proxy_profile_id":1,"enabled":true}],"bypass":{"entries":[]}}`
+}
+
+func TestPACService_Match_Drafts(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	versionRepoMock := mock.NewVersionRepository(ctrl)
+	// The draft is never looked at, the repositories of the rules, the profiles and the bypass list expect nothing.
+	srvc := NewPACService(
+		mock.NewRuleRepository(ctrl),
+		mock.NewProxyProfileRepository(ctrl),
+		mock.NewBypassRepository(ctrl),
+		versionRepoMock,
+		nil,
+		filepath.Join(t.TempDir(), "proxy.pac"),
+		VersionOptions{Keep: 10, Drafts: true},
+		logutil.DiscardLogger,
+	)
+
+	published, draft := testPublishedState("example.org"), testPublishedState("example.com")
+	gomock.InOrder(
+		versionRepoMock.EXPECT().GetLatest(gomock.Any()).Return(model.ConfigVersion{ID: 1, State: published}, nil),
+		versionRepoMock.EXPECT().Save(gomock.Any(), draft, gomock.Any(), 10).Return(nil),
+		versionRepoMock.EXPECT().GetLatest(gomock.Any()).Return(model.ConfigVersion{ID: 2, State: draft}, nil),
+	)
+
+	got, err := srvc.Match(context.Background(), "example.com")
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	assert.Equal(t, got.Proxy, "DIRECT")
+
+	if err := srvc.Publish(context.Background(), draft); err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	got, err = srvc.Match(context.Background(), "example.com")
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	assert.Equal(t, got.Proxy, "SOCKS5 localhost:9050")
+}
+
+func TestParseState(t *testing.T) {
+	t.Parallel()
+
+	state := `{"proxy_profiles":[{"id":1,"name":"tor","type":4,"host":"localhost","port":9050,"members":[]},` +
+		`{"id":2,"name":"pool","type":6,"host":null,"port":null,"standby_profile_id":1,` +
+		`"members":[{"profile_id":1,"weight":2}]}],` +
+		`"domain_lists":[{"id":4,"name":"corp","entries":[{"domain":"b.com","mode":1},{"domain":"a.com","mode":2}]}],` +
+		`"rules":[{"id":1,"regex":null,"domain_list_id":4,"proxy_profile_id":2,"enabled":true},` +
+		`{"id":2,"regex":"^a$","proxy_profile_id":1,"enabled":false},` +
+		`{"id":3,"regex":"^b$","proxy_profile_id":1,"enabled":true,"active_from":"2023-06-07 00:00:00"},` +
+		`{"id":4,"regex":"^c$","proxy_profile_id":1,"enabled":true,"expires_at":"2023-06-06 10:00:00"},` +
+		`{"id":5,"regex":"^d$","proxy_profile_id":1,"enabled":true,"active_from":"2023-06-06 10:00:00",` +
+		`"expires_at":"2023-06-07 00:00:00"}],` +
+		`"bypass":{"entries":[{"kind":1,"value":"plain_hostnames"}]}}`
+
+	bypass, rules, profiles, err := parseState(state, time.Date(2023, 6, 6, 10, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	tor := model.ProxyProfile{ID: 1, Name: "tor", Type: model.Socks5, Host: "localhost", Port: 9050}
+	pool := model.ProxyProfile{
+		ID:        2,
+		Name:      "pool",
+		Type:      model.Pool,
+		StandbyID: 1,
+		Members:   []model.PoolMember{{ProfileID: 1, Weight: 2}},
+	}
+	list := &model.DomainList{ID: 4, Name: "corp", Entries: []model.DomainListEntry{
+		{Domain: "a.com", Mode: model.DomainAndSubdomains},
+		{Domain: "b.com", Mode: model.ExactDomain},
+	}}
+
+	assert.Equal(t, bypass, []model.BypassEntry{{Kind: model.BypassPreset, Value: model.PresetPlainHostNames}})
+	assert.Equal(t, profiles, []model.ProxyProfile{tor, pool})
+	assert.Equal(t, rules, []model.Rule{
+		{ID: 1, DomainListID: 4, DomainList: list, Enabled: true, ProxyProfile: &pool},
+		{ID: 5, Regex: "^d$", Enabled: true, ProxyProfile: &tor},
+	})
+}
@@ -26,13 +26,26 @@ func NewProxyProfileService(
 	}
 }
 
-func (s *ProxyProfileService) GetAll(ctx context.Context) ([]model.ProxyProfile, error) {
-	profiles, err := s.repo.GetAll(ctx)
+// GetAll returns the page of the proxy profiles matching the filter along with the number of all the matching ones.
+func (s *ProxyProfileService) GetAll(
+	ctx context.Context,
+	filter model.ProxyProfileFilter,
+) ([]model.ProxyProfile, int, error) {
+	profiles, err := s.repo.GetAll(ctx, filter)
 	if err != nil {
 		s.logger.Error().Err(err).Msg("Error occurred while getting proxy profiles")
-		return nil, errs.ServiceUnknownError
+		return nil, 0, errs.ServiceUnknownError
 	}
-	return profiles, nil
+	if filter.Page == (model.Page{}) {
+		return profiles, len(profiles), nil
+	}
+
+	total, err := s.repo.Count(ctx, filter)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while counting proxy profiles")
+		return nil, 0, errs.ServiceUnknownError
+	}
+	return profiles, total, nil
 }
 
 func (s *ProxyProfileService) GetByID(ctx context.Context, id int) (model.ProxyProfile, error) {
@@ -50,6 +63,10 @@ func (s *ProxyProfileService) GetByID(ctx context.Context, id int) (model.ProxyP
 
 func (s *ProxyProfileService) Create(ctx context.Context, profile *model.ProxyProfile) error {
 	err := s.repo.Create(ctx, profile)
+	if err == errs.InvalidReferenceError {
+		s.logger.Debug().Err(err).Send()
+		return err
+	}
 	if err != nil {
 		if _, ok := err.(*errs.EntityAlreadyExistsError); ok {
 			s.logger.Debug().Err(err).Send()
@@ -76,9 +93,13 @@ func (s *ProxyProfileService) Create(ctx context.Context, profile *model.ProxyPr
 
 func (s *ProxyProfileService) Update(ctx context.Context, profile model.ProxyProfile) error {
 	err := s.repo.Update(ctx, profile)
+	if err == errs.InvalidReferenceError {
+		s.logger.Debug().Err(err).Send()
+		return err
+	}
 	if err != nil {
 		switch err.(type) {
-		case *errs.EntityNotFoundError:
+		case *errs.EntityNotFoundError, *errs.EntityVersionConflictError:
 			s.logger.Debug().Err(err).Send()
 			return err
 		case *errs.EntityAlreadyExistsError:
@@ -105,11 +126,48 @@ func (s *ProxyProfileService) Update(ctx context.Context, profile model.ProxyPro
 	return nil
 }
 
-func (s *ProxyProfileService) Delete(ctx context.Context, id int) error {
-	err := s.repo.Delete(ctx, id)
+// Patch updates the given fields of the profile only, the fields are named as in model.ProxyProfileFields.
+func (s *ProxyProfileService) Patch(ctx context.Context, profile model.ProxyProfile, fields []string) error {
+	err := s.repo.Patch(ctx, profile, fields)
+	if err == errs.InvalidReferenceError {
+		s.logger.Debug().Err(err).Send()
+		return err
+	}
+	if err != nil {
+		switch err.(type) {
+		case *errs.EntityNotFoundError, *errs.EntityVersionConflictError:
+			s.logger.Debug().Err(err).Send()
+			return err
+		case *errs.EntityAlreadyExistsError:
+			s.logger.Debug().Err(err).Send()
+			return err
+		default:
+			s.logger.Error().Err(err).Msg("Error occurred while patching proxy profile")
+			return errs.ServiceUnknownError
+		}
+	}
+
+	s.logger.Debug().Int("profile-id", profile.ID).Strs("fields", fields).Msg("Proxy profile patched")
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.pacSrvc.GeneratePACFile(ctx); err != nil {
+			s.logger.Error().Err(err).Msg("Error occurred while generating pac file after patching profile")
+			return
+		}
+		s.logger.Debug().Msg("Pac file generated after patching profile")
+	}()
+
+	return nil
+}
+
+// Delete deletes the profile of the version, zero version matches any.
+func (s *ProxyProfileService) Delete(ctx context.Context, id int, version int) error {
+	err := s.repo.Delete(ctx, id, version)
 	if err != nil {
 		switch err.(type) {
-		case *errs.EntityNotFoundError:
+		case *errs.EntityNotFoundError, *errs.EntityVersionConflictError:
 			s.logger.Debug().Err(err).Send()
 			return err
 		case *errs.EntityStillReferencedError:
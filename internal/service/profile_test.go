@@ -32,27 +32,30 @@ func TestProxyProfileService_GetAll_OK(t *testing.T) {
 
 	want := []model.ProxyProfile{
 		{
-			ID:      1,
-			Name:    "shadowsocks",
-			Type:    model.Socks5,
-			Address: "192.168.1.1:1080",
+			ID:   1,
+			Name: "shadowsocks",
+			Type: model.Socks5,
+			Host: "192.168.1.1",
+			Port: 1080,
 		},
 		{
-			ID:      2,
-			Name:    "some http proxy",
-			Type:    model.Http,
-			Address: "::1:8080",
+			ID:   2,
+			Name: "some http proxy",
+			Type: model.Http,
+			Host: "::1",
+			Port: 8080,
 		},
 	}
 
-	repoMock.EXPECT().GetAll(gomock.Any()).Return(want, nil)
+	repoMock.EXPECT().GetAll(gomock.Any(), model.ProxyProfileFilter{}).Return(want, nil)
 
-	got, err := proxyProfileSrvc.GetAll(context.Background())
+	got, total, err := proxyProfileSrvc.GetAll(context.Background(), model.ProxyProfileFilter{})
 	if err != nil {
 		t.Errorf("Unexpected error: %#v", err)
 	}
 
 	assert.Equal(t, got, want)
+	assert.Equal(t, total, 2)
 }
 
 func TestProxyProfileService_GetByID_OK(t *testing.T) {
@@ -61,10 +64,11 @@ func TestProxyProfileService_GetByID_OK(t *testing.T) {
 	proxyProfileSrvc, repoMock, _ := testPrepareProxyProfileService(t)
 
 	want := model.ProxyProfile{
-		ID:      1,
-		Name:    "shadowsocks",
-		Type:    model.Socks5,
-		Address: "1.1.1.1:1080",
+		ID:   1,
+		Name: "shadowsocks",
+		Type: model.Socks5,
+		Host: "1.1.1.1",
+		Port: 1080,
 	}
 
 	repoMock.EXPECT().GetByID(gomock.Any(), want.ID).Return(want, nil)
@@ -97,9 +101,10 @@ func TestProxyProfileService_Create_OK(t *testing.T) {
 	proxyProfileSrvc, repoMock, _ := testPrepareProxyProfileService(t)
 
 	profile := model.ProxyProfile{
-		Name:    "shadowsocks",
-		Type:    model.Socks5,
-		Address: "10.1.1.1:9999",
+		Name: "shadowsocks",
+		Type: model.Socks5,
+		Host: "10.1.1.1",
+		Port: 9999,
 	}
 
 	const insertedID = 15
@@ -125,9 +130,10 @@ func TestProxyProfileService_Create_AlreadyExists(t *testing.T) {
 	proxyProfileSrvc, repoMock, _ := testPrepareProxyProfileService(t)
 
 	profile := model.ProxyProfile{
-		Name:    "shadowsocks",
-		Type:    model.Socks5,
-		Address: "localhost:1080",
+		Name: "shadowsocks",
+		Type: model.Socks5,
+		Host: "localhost",
+		Port: 1080,
 	}
 
 	repoMock.EXPECT().Create(gomock.Any(), &profile).Return(&errs.EntityAlreadyExistsError{})
@@ -145,10 +151,11 @@ func TestProxyProfileService_Update_OK(t *testing.T) {
 	proxyProfileSrvc, repoMock, _ := testPrepareProxyProfileService(t)
 
 	profile := model.ProxyProfile{
-		ID:      1,
-		Name:    "shadowsocks",
-		Type:    model.Socks5,
-		Address: "127.0.0.1:1080",
+		ID:   1,
+		Name: "shadowsocks",
+		Type: model.Socks5,
+		Host: "127.0.0.1",
+		Port: 1080,
 	}
 
 	repoMock.EXPECT().Update(gomock.Any(), profile).Return(nil)
@@ -160,16 +167,35 @@ func TestProxyProfileService_Update_OK(t *testing.T) {
 	}
 }
 
+func TestProxyProfileService_Patch_AlreadyExists(t *testing.T) {
+	t.Parallel()
+
+	proxyProfileSrvc, repoMock, _ := testPrepareProxyProfileService(t)
+
+	profile := model.ProxyProfile{ID: 1, Name: "shadowsocks", Type: model.Socks5, Host: "127.0.0.1", Port: 1080}
+	fields := []string{"name"}
+
+	repoMock.EXPECT().Patch(gomock.Any(), profile, fields).
+		Return(&errs.EntityAlreadyExistsError{Name: "proxy profile", Key: "name", Value: "shadowsocks"})
+
+	err := proxyProfileSrvc.Patch(context.Background(), profile, fields)
+
+	if _, ok := err.(*errs.EntityAlreadyExistsError); !ok {
+		t.Errorf("expected error is errs.EntityAlreadyExistsError, but got %#v", err)
+	}
+}
+
 func TestProxyProfileService_Update_NotFound(t *testing.T) {
 	t.Parallel()
 
 	proxyProfileSrvc, repoMock, _ := testPrepareProxyProfileService(t)
 
 	profile := model.ProxyProfile{
-		ID:      1,
-		Name:    "shadowsocks",
-		Type:    model.Socks5,
-		Address: "127.0.0.1:1080",
+		ID:   1,
+		Name: "shadowsocks",
+		Type: model.Socks5,
+		Host: "127.0.0.1",
+		Port: 1080,
 	}
 
 	repoMock.EXPECT().Update(gomock.Any(), profile).Return(&errs.EntityNotFoundError{})
@@ -187,10 +213,11 @@ func TestProxyProfileService_Update_AlreadyExists(t *testing.T) {
 	proxyProfileSrvc, repoMock, _ := testPrepareProxyProfileService(t)
 
 	profile := model.ProxyProfile{
-		ID:      1,
-		Name:    "shadowsocks",
-		Type:    model.Socks5,
-		Address: "localhost:1080",
+		ID:   1,
+		Name: "shadowsocks",
+		Type: model.Socks5,
+		Host: "localhost",
+		Port: 1080,
 	}
 
 	repoMock.EXPECT().Update(gomock.Any(), profile).Return(&errs.EntityAlreadyExistsError{})
@@ -207,9 +234,9 @@ func TestProxyProfileService_Delete_OK(t *testing.T) {
 
 	proxyProfileSrvc, repoMock, _ := testPrepareProxyProfileService(t)
 
-	repoMock.EXPECT().Delete(gomock.Any(), 1).Return(nil)
+	repoMock.EXPECT().Delete(gomock.Any(), 1, 0).Return(nil)
 
-	err := proxyProfileSrvc.Delete(context.Background(), 1)
+	err := proxyProfileSrvc.Delete(context.Background(), 1, 0)
 
 	if err != nil {
 		t.Errorf("Unexpected error: %#v", err)
@@ -221,9 +248,9 @@ func TestProxyProfileService_Delete_NotFound(t *testing.T) {
 
 	proxyProfileSrvc, repoMock, _ := testPrepareProxyProfileService(t)
 
-	repoMock.EXPECT().Delete(gomock.Any(), 1).Return(&errs.EntityNotFoundError{})
+	repoMock.EXPECT().Delete(gomock.Any(), 1, 0).Return(&errs.EntityNotFoundError{})
 
-	err := proxyProfileSrvc.Delete(context.Background(), 1)
+	err := proxyProfileSrvc.Delete(context.Background(), 1, 0)
 
 	if _, ok := err.(*errs.EntityNotFoundError); !ok {
 		t.Errorf("expected error is errs.EntityNotFoundError, but got %#v", err)
@@ -235,9 +262,9 @@ func TestProxyProfileService_Delete_StillReferenced(t *testing.T) {
 
 	proxyProfileSrvc, repoMock, _ := testPrepareProxyProfileService(t)
 
-	repoMock.EXPECT().Delete(gomock.Any(), 1).Return(&errs.EntityStillReferencedError{})
+	repoMock.EXPECT().Delete(gomock.Any(), 1, 0).Return(&errs.EntityStillReferencedError{})
 
-	err := proxyProfileSrvc.Delete(context.Background(), 1)
+	err := proxyProfileSrvc.Delete(context.Background(), 1, 0)
 
 	if _, ok := err.(*errs.EntityStillReferencedError); !ok {
 		t.Errorf("expected error is errs.EntityStillReferencedError, but got %#v", err)
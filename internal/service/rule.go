@@ -22,13 +22,23 @@ func NewRuleService(repo RuleRepository, pacSrvc pacService, logger zerolog.Logg
 	}
 }
 
-func (s *RuleService) GetAll(ctx context.Context) ([]model.Rule, error) {
-	rules, err := s.repo.GetAll(ctx)
+// GetAll returns the page of the rules matching the filter along with the number of all the matching rules.
+func (s *RuleService) GetAll(ctx context.Context, filter model.RuleFilter) ([]model.Rule, int, error) {
+	rules, err := s.repo.GetAll(ctx, filter)
 	if err != nil {
 		s.logger.Error().Err(err).Msg("Error occurred while getting rules")
-		return nil, errs.ServiceUnknownError
+		return nil, 0, errs.ServiceUnknownError
 	}
-	return rules, nil
+	if filter.Page == (model.Page{}) {
+		return rules, len(rules), nil
+	}
+
+	total, err := s.repo.Count(ctx, filter)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while counting rules")
+		return nil, 0, errs.ServiceUnknownError
+	}
+	return rules, total, nil
 }
 
 func (s *RuleService) GetAllWithProfiles(ctx context.Context) ([]model.Rule, error) {
@@ -40,6 +50,16 @@ func (s *RuleService) GetAllWithProfiles(ctx context.Context) ([]model.Rule, err
 	return rules, nil
 }
 
+// GetExpiring returns enabled rules which are going to expire by the time, ordered by expiration time.
+func (s *RuleService) GetExpiring(ctx context.Context, until time.Time) ([]model.Rule, error) {
+	rules, err := s.repo.GetExpiring(ctx, until)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while getting expiring rules")
+		return nil, errs.ServiceUnknownError
+	}
+	return rules, nil
+}
+
 func (s *RuleService) GetByID(ctx context.Context, id int) (model.Rule, error) {
 	rule, err := s.repo.GetByID(ctx, id)
 	if err != nil {
@@ -89,6 +109,10 @@ func (s *RuleService) Update(ctx context.Context, rule model.Rule) error {
 		s.logger.Debug().Err(err).Send()
 		return err
 	}
+	if _, ok := err.(*errs.EntityVersionConflictError); ok {
+		s.logger.Debug().Err(err).Send()
+		return err
+	}
 	if err != nil {
 		s.logger.Error().Err(err).Msg("Error occurred while updating rule")
 		return errs.ServiceUnknownError
@@ -109,13 +133,53 @@ func (s *RuleService) Update(ctx context.Context, rule model.Rule) error {
 	return nil
 }
 
-func (s *RuleService) Delete(ctx context.Context, id int) error {
-	err := s.repo.Delete(ctx, id)
+// Patch updates the given fields of the rule only, the fields are named as in model.RuleFields.
+func (s *RuleService) Patch(ctx context.Context, rule model.Rule, fields []string) error {
+	err := s.repo.Patch(ctx, rule, fields)
+	if err == errs.InvalidReferenceError {
+		s.logger.Debug().Err(err).Send()
+		return err
+	}
+	if _, ok := err.(*errs.EntityNotFoundError); ok {
+		s.logger.Debug().Err(err).Send()
+		return err
+	}
+	if _, ok := err.(*errs.EntityVersionConflictError); ok {
+		s.logger.Debug().Err(err).Send()
+		return err
+	}
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while patching rule")
+		return errs.ServiceUnknownError
+	}
+
+	s.logger.Debug().Int("rule-id", rule.ID).Strs("fields", fields).Msg("Rule patched")
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.pacSrvc.GeneratePACFile(ctx); err != nil {
+			s.logger.Error().Err(err).Msg("Error occurred while generating pac file after patching rule")
+			return
+		}
+		s.logger.Debug().Msg("Pac file generated after patching rule")
+	}()
+
+	return nil
+}
+
+// Delete deletes the rule of the version, zero version matches any.
+func (s *RuleService) Delete(ctx context.Context, id int, version int) error {
+	err := s.repo.Delete(ctx, id, version)
 	if err != nil {
 		if _, ok := err.(*errs.EntityNotFoundError); ok {
 			s.logger.Debug().Err(err).Send()
 			return err
 		}
+		if _, ok := err.(*errs.EntityVersionConflictError); ok {
+			s.logger.Debug().Err(err).Send()
+			return err
+		}
 		s.logger.Error().Err(err).Msg("Error occurred while deleting rule")
 		return errs.ServiceUnknownError
 	}
@@ -134,3 +198,59 @@ func (s *RuleService) Delete(ctx context.Context, id int) error {
 
 	return nil
 }
+
+func (s *RuleService) UpdateByTag(ctx context.Context, tag string, changes model.RuleBulkUpdate) (int, error) {
+	count, err := s.repo.UpdateByTag(ctx, tag, changes)
+	if err == errs.InvalidReferenceError {
+		s.logger.Debug().Err(err).Send()
+		return 0, err
+	}
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while updating rules by tag")
+		return 0, errs.ServiceUnknownError
+	}
+
+	s.logger.Debug().Str("tag", tag).Int("count", count).Msg("Rules updated")
+
+	if count == 0 {
+		return 0, nil
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.pacSrvc.GeneratePACFile(ctx); err != nil {
+			s.logger.Error().Err(err).Msg("Error occurred while generating pac file after updating rules")
+			return
+		}
+		s.logger.Debug().Msg("Pac file generated after updating rules")
+	}()
+
+	return count, nil
+}
+
+func (s *RuleService) DeleteByTag(ctx context.Context, tag string) (int, error) {
+	count, err := s.repo.DeleteByTag(ctx, tag)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while deleting rules by tag")
+		return 0, errs.ServiceUnknownError
+	}
+
+	s.logger.Debug().Str("tag", tag).Int("count", count).Msg("Rules deleted")
+
+	if count == 0 {
+		return 0, nil
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.pacSrvc.GeneratePACFile(ctx); err != nil {
+			s.logger.Error().Err(err).Msg("Error occurred while generating pac file after deleting rules")
+			return
+		}
+		s.logger.Debug().Msg("Pac file generated after deleting rules")
+	}()
+
+	return count, nil
+}
@@ -39,14 +39,35 @@ func TestRuleService_GetAll_OK(t *testing.T) {
 		},
 	}
 
-	repoMock.EXPECT().GetAll(gomock.Any()).Return(want, nil)
+	repoMock.EXPECT().GetAll(gomock.Any(), model.RuleFilter{Tag: "search"}).Return(want, nil)
 
-	got, err := ruleSrvc.GetAll(context.Background())
+	got, total, err := ruleSrvc.GetAll(context.Background(), model.RuleFilter{Tag: "search"})
 	if err != nil {
 		t.Errorf("Unexpected error: %#v", err)
 	}
 
 	assert.Equal(t, got, want)
+	assert.Equal(t, total, 2)
+}
+
+func TestRuleService_GetAll_Paged(t *testing.T) {
+	t.Parallel()
+
+	ruleSrvc, repoMock, _ := testPrepareRuleService(t)
+
+	want := []model.Rule{{ID: 11, Regex: `^www\.google\.com$`, ProxyProfile: &model.ProxyProfile{ID: 1}}}
+	filter := model.RuleFilter{Tag: "search", Page: model.Page{Limit: 10, Offset: 10}}
+
+	repoMock.EXPECT().GetAll(gomock.Any(), filter).Return(want, nil)
+	repoMock.EXPECT().Count(gomock.Any(), filter).Return(11, nil)
+
+	got, total, err := ruleSrvc.GetAll(context.Background(), filter)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	assert.Equal(t, got, want)
+	assert.Equal(t, total, 11)
 }
 
 func TestRuleService_GetAllWithProfiles_OK(t *testing.T) {
@@ -59,20 +80,22 @@ func TestRuleService_GetAllWithProfiles_OK(t *testing.T) {
 			ID:    1,
 			Regex: `^www\.google\.com$`,
 			ProxyProfile: &model.ProxyProfile{
-				ID:      1,
-				Name:    "shadowsocks",
-				Type:    model.Socks5,
-				Address: "localhost:1080",
+				ID:   1,
+				Name: "shadowsocks",
+				Type: model.Socks5,
+				Host: "localhost",
+				Port: 1080,
 			},
 		},
 		{
 			ID:    2,
 			Regex: `(?:^|\.)facebook\.com$`,
 			ProxyProfile: &model.ProxyProfile{
-				ID:      2,
-				Name:    "some http proxy",
-				Type:    model.Http,
-				Address: "localhost:8080",
+				ID:   2,
+				Name: "some http proxy",
+				Type: model.Http,
+				Host: "localhost",
+				Port: 8080,
 			},
 		},
 	}
@@ -185,6 +208,42 @@ func TestRuleService_Update_OK(t *testing.T) {
 	}
 }
 
+func TestRuleService_Patch_OK(t *testing.T) {
+	t.Parallel()
+
+	ruleSrvc, repoMock, _ := testPrepareRuleService(t)
+
+	rule := model.Rule{
+		ID:           1,
+		Regex:        `^www\.google\.com$`,
+		ProxyProfile: &model.ProxyProfile{ID: 2},
+	}
+	fields := []string{"proxy_profile_id"}
+
+	repoMock.EXPECT().Patch(gomock.Any(), rule, fields).Return(nil)
+
+	err := ruleSrvc.Patch(context.Background(), rule, fields)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+}
+
+func TestRuleService_Patch_InvalidReference(t *testing.T) {
+	t.Parallel()
+
+	ruleSrvc, repoMock, _ := testPrepareRuleService(t)
+
+	rule := model.Rule{ID: 1, DomainListID: 15, ProxyProfile: &model.ProxyProfile{ID: 1}}
+	fields := []string{"domain_list_id", "regex"}
+
+	repoMock.EXPECT().Patch(gomock.Any(), rule, fields).Return(errs.InvalidReferenceError)
+
+	err := ruleSrvc.Patch(context.Background(), rule, fields)
+	if err != errs.InvalidReferenceError {
+		t.Errorf("expected error is errs.InvalidReferenceError, but got %#v", err)
+	}
+}
+
 func TestRuleService_Update_InvalidReference(t *testing.T) {
 	t.Parallel()
 
@@ -228,9 +287,9 @@ func TestRuleService_Delete_OK(t *testing.T) {
 
 	ruleSrvc, repoMock, _ := testPrepareRuleService(t)
 
-	repoMock.EXPECT().Delete(gomock.Any(), 1).Return(nil)
+	repoMock.EXPECT().Delete(gomock.Any(), 1, 0).Return(nil)
 
-	err := ruleSrvc.Delete(context.Background(), 1)
+	err := ruleSrvc.Delete(context.Background(), 1, 0)
 	if err != nil {
 		t.Errorf("Unexpected error: %#v", err)
 	}
@@ -241,10 +300,59 @@ func TestRuleService_Delete_NotFound(t *testing.T) {
 
 	ruleSrvc, repoMock, _ := testPrepareRuleService(t)
 
-	repoMock.EXPECT().Delete(gomock.Any(), 1).Return(&errs.EntityNotFoundError{})
+	repoMock.EXPECT().Delete(gomock.Any(), 1, 0).Return(&errs.EntityNotFoundError{})
 
-	err := ruleSrvc.Delete(context.Background(), 1)
+	err := ruleSrvc.Delete(context.Background(), 1, 0)
 	if _, ok := err.(*errs.EntityNotFoundError); !ok {
 		t.Errorf("expected error is errs.EntityNotFoundError, but got %#v", err)
 	}
 }
+
+func TestRuleService_UpdateByTag_OK(t *testing.T) {
+	t.Parallel()
+
+	ruleSrvc, repoMock, _ := testPrepareRuleService(t)
+
+	enabled := false
+	changes := model.RuleBulkUpdate{Enabled: &enabled}
+
+	repoMock.EXPECT().UpdateByTag(gomock.Any(), "streaming", changes).Return(3, nil)
+
+	got, err := ruleSrvc.UpdateByTag(context.Background(), "streaming", changes)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	assert.Equal(t, got, 3)
+}
+
+func TestRuleService_UpdateByTag_InvalidReference(t *testing.T) {
+	t.Parallel()
+
+	ruleSrvc, repoMock, _ := testPrepareRuleService(t)
+
+	profileID := 9
+	changes := model.RuleBulkUpdate{ProxyProfileID: &profileID}
+
+	repoMock.EXPECT().UpdateByTag(gomock.Any(), "streaming", changes).Return(0, errs.InvalidReferenceError)
+
+	_, err := ruleSrvc.UpdateByTag(context.Background(), "streaming", changes)
+	if err != errs.InvalidReferenceError {
+		t.Errorf("expected error is errs.InvalidReferenceError, but got %#v", err)
+	}
+}
+
+func TestRuleService_DeleteByTag_OK(t *testing.T) {
+	t.Parallel()
+
+	ruleSrvc, repoMock, _ := testPrepareRuleService(t)
+
+	repoMock.EXPECT().DeleteByTag(gomock.Any(), "streaming").Return(2, nil)
+
+	got, err := ruleSrvc.DeleteByTag(context.Background(), "streaming")
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	assert.Equal(t, got, 2)
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/rs/zerolog"
+	"time"
+)
+
+// ScheduleOptions configures the rule scheduler.
+type ScheduleOptions struct {
+	// Interval is the longest time between checks for new boundaries, rules created or changed in between
+	// are picked up by the next check.
+	Interval time.Duration
+	// Policy is applied to the rules once they expire.
+	Policy model.ExpiryPolicy
+}
+
+// RuleScheduler puts the time bounds of the rules into effect: PAC file is regenerated whenever some rule becomes
+// active or expires, and the expired rules are kept, archived or deleted according to the policy.
+type RuleScheduler struct {
+	logger zerolog.Logger
+	repo   RuleRepository
+	opts   ScheduleOptions
+}
+
+func NewRuleScheduler(repo RuleRepository, opts ScheduleOptions, logger zerolog.Logger) *RuleScheduler {
+	return &RuleScheduler{
+		logger: logger,
+		repo:   repo,
+		opts:   opts,
+	}
+}
+
+// Run waits for the boundaries of the rules until the context is done. The boundaries passed while the server
+// was down are put into effect at once.
+func (s *RuleScheduler) Run(ctx context.Context, pacSrvc pacService) {
+	s.Apply(ctx, pacSrvc)
+	last := time.Now()
+
+	for {
+		wait := s.opts.Interval
+		next, err := s.repo.NextBoundary(ctx, last)
+		if err != nil {
+			s.logger.Error().Err(err).Msg("Error occurred while getting next rule boundary")
+		} else if !next.IsZero() {
+			now := time.Now()
+			if !now.Before(next) {
+				last = now
+				s.Apply(ctx, pacSrvc)
+				continue
+			}
+			if d := next.Sub(now); d < wait {
+				wait = d
+			}
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// Apply applies the expiry policy to the expired rules and regenerates PAC file. The changes are recorded
+// in the audit log as made by the scheduler.
+func (s *RuleScheduler) Apply(ctx context.Context, pacSrvc pacService) {
+	ctx = model.WithActor(ctx, model.Actor{User: model.SchedulerActor})
+	var count int
+	var err error
+	switch s.opts.Policy {
+	case model.ExpiryArchive:
+		count, err = s.repo.ArchiveExpired(ctx, model.ArchiveTag)
+	case model.ExpiryDelete:
+		count, err = s.repo.DeleteExpired(ctx)
+	}
+	if err != nil {
+		s.logger.Error().Err(err).Str("policy", s.opts.Policy.String()).Msg("Error occurred while applying expiry policy")
+	} else if count > 0 {
+		s.logger.Info().Int("count", count).Str("policy", s.opts.Policy.String()).Msg("Expiry policy applied to rules")
+	}
+
+	genCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := pacSrvc.GeneratePACFile(genCtx); err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while generating pac file after rule boundary")
+		return
+	}
+	s.logger.Debug().Msg("Pac file generated after rule boundary")
+}
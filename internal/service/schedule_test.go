@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/nnemirovsky/pacgen/internal/service/mock"
+	"github.com/nnemirovsky/pacgen/pkg/logutil"
+	"testing"
+	"time"
+)
+
+func TestRuleScheduler_Apply(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		policy model.ExpiryPolicy
+		expect func(repoMock *mock.RuleRepository)
+	}{
+		"keep": {
+			policy: model.ExpiryKeep,
+			expect: func(repoMock *mock.RuleRepository) {},
+		},
+		"archive": {
+			policy: model.ExpiryArchive,
+			expect: func(repoMock *mock.RuleRepository) {
+				repoMock.EXPECT().ArchiveExpired(gomock.Any(), model.ArchiveTag).Return(2, nil)
+			},
+		},
+		"delete": {
+			policy: model.ExpiryDelete,
+			expect: func(repoMock *mock.RuleRepository) {
+				repoMock.EXPECT().DeleteExpired(gomock.Any()).Return(2, nil)
+			},
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			repoMock := mock.NewRuleRepository(ctrl)
+			pacSrvcMock := mock.NewPacService(ctrl)
+
+			c.expect(repoMock)
+			pacSrvcMock.EXPECT().GeneratePACFile(gomock.Any()).Return(nil)
+
+			scheduler := NewRuleScheduler(repoMock, ScheduleOptions{Interval: time.Minute, Policy: c.policy}, logutil.DiscardLogger)
+			scheduler.Apply(context.Background(), pacSrvcMock)
+		})
+	}
+}
+
+func TestRuleScheduler_Apply_Actor(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	repoMock := mock.NewRuleRepository(ctrl)
+	pacSrvcMock := mock.NewPacService(ctrl)
+
+	// The changes are recorded in the audit log as made by the scheduler.
+	repoMock.EXPECT().
+		DeleteExpired(gomock.Any()).
+		DoAndReturn(func(ctx context.Context) (int, error) {
+			assert.Equal(t, model.ActorFromContext(ctx), model.Actor{User: model.SchedulerActor})
+			return 1, nil
+		})
+	pacSrvcMock.EXPECT().GeneratePACFile(gomock.Any()).Return(nil)
+
+	scheduler := NewRuleScheduler(repoMock, ScheduleOptions{Interval: time.Minute, Policy: model.ExpiryDelete}, logutil.DiscardLogger)
+	scheduler.Apply(context.Background(), pacSrvcMock)
+}
+
+func TestRuleScheduler_Run_BoundaryPassed(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	repoMock := mock.NewRuleRepository(ctrl)
+	pacSrvcMock := mock.NewPacService(ctrl)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Rules are expired once on start and once more when the boundary passes.
+	repoMock.EXPECT().DeleteExpired(gomock.Any()).Return(0, nil).Times(2)
+	pacSrvcMock.EXPECT().GeneratePACFile(gomock.Any()).Return(nil).Times(2)
+	gomock.InOrder(
+		repoMock.EXPECT().NextBoundary(gomock.Any(), gomock.Any()).Return(time.Now().Add(-time.Second), nil),
+		repoMock.EXPECT().NextBoundary(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(context.Context, time.Time) (time.Time, error) {
+				cancel()
+				return time.Time{}, nil
+			},
+		),
+	)
+
+	scheduler := NewRuleScheduler(repoMock, ScheduleOptions{Interval: time.Minute, Policy: model.ExpiryDelete}, logutil.DiscardLogger)
+
+	done := make(chan struct{})
+	go func() {
+		scheduler.Run(ctx, pacSrvcMock)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("scheduler did not stop")
+	}
+}
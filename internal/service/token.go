@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/rs/zerolog"
+	"strings"
+	"time"
+)
+
+// TokenPrefix starts every API token, so leaked tokens are easy to find and tell apart from passwords.
+const TokenPrefix = "pacgen_"
+
+type TokenService struct {
+	logger zerolog.Logger
+	repo   TokenRepository
+	now    func() time.Time
+}
+
+func NewTokenService(repo TokenRepository, logger zerolog.Logger) *TokenService {
+	return &TokenService{
+		logger: logger,
+		repo:   repo,
+		now:    time.Now,
+	}
+}
+
+// GetAll returns all the tokens to admins and the personal tokens of the user of the actor in the context
+// to the others.
+func (s *TokenService) GetAll(ctx context.Context) ([]model.APIToken, error) {
+	tokens, err := s.repo.GetAll(ctx, s.ownerFilter(ctx))
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while getting API tokens")
+		return nil, errs.ServiceUnknownError
+	}
+	return tokens, nil
+}
+
+// Create creates the personal token of the user of the actor in the context, or the service token if service
+// is true, and returns the token itself, which is not stored. Only admins create service tokens, and the tokens
+// created with other tokens are allowed no more than their scopes, errs.TokenNotAllowedError is returned otherwise.
+func (s *TokenService) Create(ctx context.Context, token *model.APIToken, service bool) (string, error) {
+	actor := model.ActorFromContext(ctx)
+	if service && actor.Role < model.Admin {
+		s.logger.Debug().Str("user", actor.User).Msg("Service token is not allowed to the user")
+		return "", errs.TokenNotAllowedError
+	}
+	if actor.Scopes != nil {
+		for _, scope := range token.Scopes {
+			resource, access, _ := strings.Cut(scope, ":")
+			if !actor.Scopes.Allows(resource, access == model.ScopeWrite) {
+				s.logger.Debug().Str("user", actor.User).Str("scope", scope).Msg("Scope is not allowed to the token")
+				return "", errs.TokenNotAllowedError
+			}
+		}
+	}
+
+	// Service tokens have no user, so the tokens they create are service ones too.
+	token.UserID = 0
+	if !service {
+		token.UserID = actor.UserID
+	}
+	return s.Mint(ctx, token)
+}
+
+// Mint creates the token without checking whether the actor in the context is allowed to create it, and returns
+// the token itself. Tokens without UserID are service ones. The token is updated with its stored state.
+func (s *TokenService) Mint(ctx context.Context, token *model.APIToken) (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while generating API token")
+		return "", errs.ServiceUnknownError
+	}
+	plain := TokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	token.TokenHash = hashToken(plain)
+
+	if err := s.repo.Create(ctx, token); err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while creating API token")
+		return "", errs.ServiceUnknownError
+	}
+	created, err := s.repo.GetByID(ctx, token.ID)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while getting created API token")
+		return "", errs.ServiceUnknownError
+	}
+	*token = created
+
+	s.logger.Info().Int("token-id", token.ID).Int("user-id", token.UserID).Strs("scopes", token.Scopes).
+		Msg("API token created")
+	return plain, nil
+}
+
+// Delete revokes the token. Users other than admins revoke their personal tokens only, the others are not found
+// for them.
+func (s *TokenService) Delete(ctx context.Context, id int) error {
+	token, err := s.repo.GetByID(ctx, id)
+	if err == nil {
+		if userID := s.ownerFilter(ctx); userID != 0 && token.UserID != userID {
+			err = &errs.EntityNotFoundError{Name: "API token", Key: "id", Value: id}
+		}
+	}
+	if err == nil {
+		err = s.repo.Delete(ctx, id)
+	}
+	if err != nil {
+		if _, ok := err.(*errs.EntityNotFoundError); ok {
+			s.logger.Debug().Err(err).Send()
+			return err
+		}
+		s.logger.Error().Err(err).Msg("Error occurred while revoking API token")
+		return errs.ServiceUnknownError
+	}
+
+	s.logger.Info().Int("token-id", id).Msg("API token revoked")
+	return nil
+}
+
+// AuthenticateToken returns the token, errs.InvalidCredentialsError if there is no such token or it has expired.
+// The use of the token is recorded.
+func (s *TokenService) AuthenticateToken(ctx context.Context, plain string) (model.APIToken, error) {
+	token, err := s.repo.GetByHash(ctx, hashToken(plain))
+	if err != nil {
+		if _, ok := err.(*errs.EntityNotFoundError); ok {
+			s.logger.Debug().Msg("Unknown API token")
+			return model.APIToken{}, errs.InvalidCredentialsError
+		}
+		s.logger.Error().Err(err).Msg("Error occurred while getting API token by hash")
+		return model.APIToken{}, errs.ServiceUnknownError
+	}
+	if token.ExpiresAt != nil && !s.now().Before(*token.ExpiresAt) {
+		s.logger.Debug().Int("token-id", token.ID).Msg("API token has expired")
+		return model.APIToken{}, errs.InvalidCredentialsError
+	}
+
+	// The request goes on even if its use is not recorded.
+	if err := s.repo.Touch(ctx, token.ID); err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while recording use of API token")
+	}
+	return token, nil
+}
+
+// ownerFilter returns the id of the user whose tokens the actor in the context manages, zero for admins managing
+// all of them.
+func (s *TokenService) ownerFilter(ctx context.Context) int {
+	actor := model.ActorFromContext(ctx)
+	if actor.Role >= model.Admin {
+		return 0
+	}
+	return actor.UserID
+}
+
+// hashToken returns the hex encoded SHA-256 hash of the token. Tokens are random, so they need no salt and no slow
+// hash, unlike passwords.
+func hashToken(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/nnemirovsky/pacgen/internal/service/mock"
+	"github.com/nnemirovsky/pacgen/pkg/logutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenService_AuthenticateToken(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Minute), now.Add(time.Hour)
+	valid := model.APIToken{ID: 1, Name: "ci", Scopes: model.Scopes{"rules:write"}, ExpiresAt: &future}
+
+	data := map[string]struct {
+		token   model.APIToken
+		err     error
+		want    model.APIToken
+		wantErr error
+	}{
+		"valid": {
+			token: valid,
+			want:  valid,
+		},
+		"expired": {
+			token:   model.APIToken{ID: 2, Name: "old", ExpiresAt: &past},
+			wantErr: errs.InvalidCredentialsError,
+		},
+		"unknown": {
+			err:     &errs.EntityNotFoundError{Name: "API token"},
+			wantErr: errs.InvalidCredentialsError,
+		},
+		"repository error": {
+			err:     errs.RepositoryUnknownError,
+			wantErr: errs.ServiceUnknownError,
+		},
+	}
+
+	for name, d := range data {
+		d := d
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			repoMock := mock.NewTokenRepository(ctrl)
+			tokenSrvc := NewTokenService(repoMock, logutil.DiscardLogger)
+			tokenSrvc.now = func() time.Time { return now }
+
+			repoMock.EXPECT().GetByHash(gomock.Any(), hashToken("pacgen_secret")).Return(d.token, d.err)
+			if d.wantErr == nil {
+				repoMock.EXPECT().Touch(gomock.Any(), d.token.ID).Return(nil)
+			}
+
+			got, err := tokenSrvc.AuthenticateToken(context.Background(), "pacgen_secret")
+
+			assert.Equal(t, err, d.wantErr)
+			assert.Equal(t, got, d.want)
+		})
+	}
+}
+
+func TestTokenService_Create(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	repoMock := mock.NewTokenRepository(ctrl)
+	tokenSrvc := NewTokenService(repoMock, logutil.DiscardLogger)
+
+	var hash string
+	repoMock.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, token *model.APIToken) error {
+		assert.Equal(t, token.UserID, 2)
+		hash = token.TokenHash
+		token.ID = 7
+		return nil
+	})
+	repoMock.EXPECT().GetByID(gomock.Any(), 7).Return(model.APIToken{ID: 7, Name: "laptop", UserID: 2}, nil)
+
+	ctx := model.WithActor(context.Background(), model.Actor{User: "bob", UserID: 2, Role: model.Editor})
+	token := model.APIToken{Name: "laptop", Scopes: model.Scopes{"rules:write"}}
+	plain, err := tokenSrvc.Create(ctx, &token, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, strings.HasPrefix(plain, TokenPrefix), true)
+	assert.Equal(t, hash, hashToken(plain))
+	assert.Equal(t, token, model.APIToken{ID: 7, Name: "laptop", UserID: 2})
+}
+
+func TestTokenService_Create_NotAllowed(t *testing.T) {
+	t.Parallel()
+
+	data := map[string]struct {
+		actor   model.Actor
+		scopes  model.Scopes
+		service bool
+	}{
+		"service token by editor": {
+			actor:   model.Actor{User: "bob", UserID: 2, Role: model.Editor},
+			scopes:  model.Scopes{"rules:read"},
+			service: true,
+		},
+		"scope beyond token": {
+			actor:  model.Actor{User: "bob", UserID: 2, Role: model.Editor, Scopes: model.Scopes{"rules:read", "tokens:write"}},
+			scopes: model.Scopes{"rules:write"},
+		},
+	}
+
+	for name, d := range data {
+		d := d
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			repoMock := mock.NewTokenRepository(ctrl)
+			tokenSrvc := NewTokenService(repoMock, logutil.DiscardLogger)
+
+			ctx := model.WithActor(context.Background(), d.actor)
+			_, err := tokenSrvc.Create(ctx, &model.APIToken{Name: "ci", Scopes: d.scopes}, d.service)
+
+			assert.Equal(t, err, errs.TokenNotAllowedError)
+		})
+	}
+}
+
+func TestTokenService_Delete_NotOwn(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	repoMock := mock.NewTokenRepository(ctrl)
+	tokenSrvc := NewTokenService(repoMock, logutil.DiscardLogger)
+
+	repoMock.EXPECT().GetByID(gomock.Any(), 4).Return(model.APIToken{ID: 4, Name: "ci"}, nil)
+
+	ctx := model.WithActor(context.Background(), model.Actor{User: "bob", UserID: 2, Role: model.Editor})
+	err := tokenSrvc.Delete(ctx, 4)
+
+	assert.Equal(t, err, &errs.EntityNotFoundError{Name: "API token", Key: "id", Value: 4})
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/rs/zerolog"
+	"golang.org/x/crypto/bcrypt"
+	"sync"
+)
+
+// dummyHash is compared with the passwords of unknown users, so they take as long to reject as the known ones.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
+// verification is the password of the user verified against the hash.
+type verification struct {
+	passwordHash string
+	digest       [sha256.Size]byte
+}
+
+type UserService struct {
+	logger zerolog.Logger
+	repo   UserRepository
+	// verified caches the latest verified password of every user, since bcrypt is slow by design and every request
+	// is authenticated. Entries are bound to the password hash, so they are outdated by the change of the password.
+	verified map[string]verification
+	mu       sync.Mutex
+}
+
+func NewUserService(repo UserRepository, logger zerolog.Logger) *UserService {
+	return &UserService{
+		logger:   logger,
+		repo:     repo,
+		verified: make(map[string]verification),
+	}
+}
+
+func (s *UserService) GetAll(ctx context.Context) ([]model.User, error) {
+	users, err := s.repo.GetAll(ctx)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while getting users")
+		return nil, errs.ServiceUnknownError
+	}
+	return users, nil
+}
+
+func (s *UserService) GetByID(ctx context.Context, id int) (model.User, error) {
+	user, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if _, ok := err.(*errs.EntityNotFoundError); ok {
+			s.logger.Debug().Err(err).Send()
+			return user, err
+		}
+		s.logger.Error().Err(err).Msg("Error occurred while getting user by id")
+		return user, errs.ServiceUnknownError
+	}
+	return user, nil
+}
+
+// Authenticate returns the user with the name and the password, errs.InvalidCredentialsError if there is none.
+func (s *UserService) Authenticate(ctx context.Context, name, password string) (model.User, error) {
+	user, err := s.repo.GetByName(ctx, name)
+	if err != nil {
+		if _, ok := err.(*errs.EntityNotFoundError); ok {
+			_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+			s.logger.Debug().Str("user", name).Msg("Unknown user")
+			return model.User{}, errs.InvalidCredentialsError
+		}
+		s.logger.Error().Err(err).Msg("Error occurred while getting user by name")
+		return model.User{}, errs.ServiceUnknownError
+	}
+
+	digest := sha256.Sum256([]byte(password))
+	s.mu.Lock()
+	cached, ok := s.verified[name]
+	s.mu.Unlock()
+	if ok && cached.passwordHash == user.PasswordHash && subtle.ConstantTimeCompare(cached.digest[:], digest[:]) == 1 {
+		return user, nil
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		s.logger.Debug().Str("user", name).Msg("Invalid password")
+		return model.User{}, errs.InvalidCredentialsError
+	}
+
+	s.mu.Lock()
+	s.verified[name] = verification{passwordHash: user.PasswordHash, digest: digest}
+	s.mu.Unlock()
+	return user, nil
+}
+
+// Bootstrap creates the admin with the name and the password unless there are users already.
+func (s *UserService) Bootstrap(ctx context.Context, name, password string) error {
+	user := model.User{Name: name, Role: model.Admin}
+	if err := hashPassword(&user, password); err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while hashing password")
+		return errs.ServiceUnknownError
+	}
+
+	ctx = model.WithActor(ctx, model.Actor{User: model.BootstrapActor})
+	created, err := s.repo.Bootstrap(ctx, &user)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while bootstrapping admin")
+		return errs.ServiceUnknownError
+	}
+	if created {
+		s.logger.Info().Str("user", name).Msg("Admin bootstrapped, since there were no users")
+	}
+	return nil
+}
+
+// Create creates the user with the password.
+func (s *UserService) Create(ctx context.Context, user *model.User, password string) error {
+	if err := hashPassword(user, password); err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while hashing password")
+		return errs.ServiceUnknownError
+	}
+
+	if err := s.repo.Create(ctx, user); err != nil {
+		if _, ok := err.(*errs.EntityAlreadyExistsError); ok {
+			s.logger.Debug().Err(err).Send()
+			return err
+		}
+		s.logger.Error().Err(err).Msg("Error occurred while creating user")
+		return errs.ServiceUnknownError
+	}
+
+	s.logger.Info().Int("user-id", user.ID).Str("role", user.Role.String()).Msg("User created")
+	return nil
+}
+
+// Update updates the user, the password is kept if the new one is empty. errs.LastAdminError is returned if no admin
+// would remain.
+func (s *UserService) Update(ctx context.Context, user model.User, password string) error {
+	if password != "" {
+		if err := hashPassword(&user, password); err != nil {
+			s.logger.Error().Err(err).Msg("Error occurred while hashing password")
+			return errs.ServiceUnknownError
+		}
+	}
+
+	if err := s.repo.Update(ctx, user); err != nil {
+		switch err.(type) {
+		case *errs.EntityNotFoundError, *errs.EntityAlreadyExistsError:
+			s.logger.Debug().Err(err).Send()
+			return err
+		}
+		if err == errs.LastAdminError {
+			s.logger.Debug().Err(err).Send()
+			return err
+		}
+		s.logger.Error().Err(err).Msg("Error occurred while updating user")
+		return errs.ServiceUnknownError
+	}
+
+	s.logger.Info().Int("user-id", user.ID).Str("role", user.Role.String()).Msg("User updated")
+	return nil
+}
+
+// Delete deletes the user, errs.LastAdminError is returned if no admin would remain.
+func (s *UserService) Delete(ctx context.Context, id int) error {
+	if err := s.repo.Delete(ctx, id); err != nil {
+		if _, ok := err.(*errs.EntityNotFoundError); ok || err == errs.LastAdminError {
+			s.logger.Debug().Err(err).Send()
+			return err
+		}
+		s.logger.Error().Err(err).Msg("Error occurred while deleting user")
+		return errs.ServiceUnknownError
+	}
+
+	s.logger.Info().Int("user-id", id).Msg("User deleted")
+	return nil
+}
+
+func hashPassword(user *model.User, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	user.PasswordHash = string(hash)
+	return nil
+}
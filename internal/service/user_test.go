@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/nnemirovsky/pacgen/internal/service/mock"
+	"github.com/nnemirovsky/pacgen/pkg/logutil"
+	"golang.org/x/crypto/bcrypt"
+	"testing"
+)
+
+func TestUserService_Authenticate(t *testing.T) {
+	t.Parallel()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice := model.User{ID: 1, Name: "alice", PasswordHash: string(hash), Role: model.Editor}
+
+	data := map[string]struct {
+		name     string
+		password string
+		user     model.User
+		err      error
+		want     model.User
+		wantErr  error
+	}{
+		"valid": {
+			name:     "alice",
+			password: "correct horse",
+			user:     alice,
+			want:     alice,
+		},
+		"wrong password": {
+			name:     "alice",
+			password: "battery staple",
+			user:     alice,
+			wantErr:  errs.InvalidCredentialsError,
+		},
+		"unknown user": {
+			name:     "bob",
+			password: "correct horse",
+			err:      &errs.EntityNotFoundError{Name: "user", Key: "name", Value: "bob"},
+			wantErr:  errs.InvalidCredentialsError,
+		},
+	}
+
+	for name, d := range data {
+		d := d
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			repoMock := mock.NewUserRepository(ctrl)
+			userSrvc := NewUserService(repoMock, logutil.DiscardLogger)
+
+			repoMock.EXPECT().GetByName(gomock.Any(), d.name).Return(d.user, d.err)
+
+			got, err := userSrvc.Authenticate(context.Background(), d.name, d.password)
+
+			assert.Equal(t, err, d.wantErr)
+			assert.Equal(t, got, d.want)
+		})
+	}
+}
+
+func TestUserService_Authenticate_PasswordChanged(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	repoMock := mock.NewUserRepository(ctrl)
+	userSrvc := NewUserService(repoMock, logutil.DiscardLogger)
+
+	oldHash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newHash, err := bcrypt.GenerateFromPassword([]byte("battery staple"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gomock.InOrder(
+		repoMock.EXPECT().GetByName(gomock.Any(), "alice").Return(model.User{PasswordHash: string(oldHash)}, nil),
+		repoMock.EXPECT().GetByName(gomock.Any(), "alice").Return(model.User{PasswordHash: string(newHash)}, nil),
+	)
+
+	if _, err := userSrvc.Authenticate(context.Background(), "alice", "correct horse"); err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	// The verified old password is not accepted once the hash has changed.
+	_, err = userSrvc.Authenticate(context.Background(), "alice", "correct horse")
+
+	assert.Equal(t, err, errs.InvalidCredentialsError)
+}
+
+func TestUserService_Create(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	repoMock := mock.NewUserRepository(ctrl)
+	userSrvc := NewUserService(repoMock, logutil.DiscardLogger)
+
+	var hash string
+	repoMock.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, user *model.User) error {
+		hash = user.PasswordHash
+		user.ID = 2
+		return nil
+	})
+
+	user := model.User{Name: "bob", Role: model.Viewer}
+	if err := userSrvc.Create(context.Background(), &user, "correct horse"); err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	assert.Equal(t, user.ID, 2)
+	assert.Equal(t, bcrypt.CompareHashAndPassword([]byte(hash), []byte("correct horse")), nil)
+}
+
+func TestUserService_Update_KeepsPassword(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	repoMock := mock.NewUserRepository(ctrl)
+	userSrvc := NewUserService(repoMock, logutil.DiscardLogger)
+
+	user := model.User{ID: 1, Name: "alice", Role: model.Viewer}
+	repoMock.EXPECT().Update(gomock.Any(), user).Return(errs.LastAdminError)
+
+	err := userSrvc.Update(context.Background(), user, "")
+
+	assert.Equal(t, err, errs.LastAdminError)
+}
+
+func TestUserService_Bootstrap(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	repoMock := mock.NewUserRepository(ctrl)
+	userSrvc := NewUserService(repoMock, logutil.DiscardLogger)
+
+	repoMock.EXPECT().Bootstrap(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, user *model.User) (bool, error) {
+			assert.Equal(t, model.ActorFromContext(ctx).User, model.BootstrapActor)
+			assert.Equal(t, user.Name, "admin")
+			assert.Equal(t, user.Role, model.Admin)
+			return false, nil
+		},
+	)
+
+	if err := userSrvc.Bootstrap(context.Background(), "admin", "admin"); err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+}
@@ -0,0 +1,219 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/rs/zerolog"
+	"sort"
+	"time"
+)
+
+// VersionService gives access to the versions of the configuration, which are recorded whenever PAC file
+// is regenerated.
+type VersionService struct {
+	logger  zerolog.Logger
+	repo    VersionRepository
+	pacSrvc pacService
+}
+
+func NewVersionService(repo VersionRepository, pacSrvc pacService, logger zerolog.Logger) *VersionService {
+	return &VersionService{
+		logger:  logger,
+		repo:    repo,
+		pacSrvc: pacSrvc,
+	}
+}
+
+// GetAll returns the page of the versions, newest first, along with the number of all the versions.
+func (s *VersionService) GetAll(ctx context.Context, page model.Page) ([]model.ConfigVersion, int, error) {
+	versions, err := s.repo.GetAll(ctx, page)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while getting configuration versions")
+		return nil, 0, errs.ServiceUnknownError
+	}
+	if page == (model.Page{}) {
+		return versions, len(versions), nil
+	}
+
+	total, err := s.repo.Count(ctx)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while counting configuration versions")
+		return nil, 0, errs.ServiceUnknownError
+	}
+	return versions, total, nil
+}
+
+func (s *VersionService) GetByID(ctx context.Context, id int) (model.ConfigVersion, error) {
+	version, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if _, ok := err.(*errs.EntityNotFoundError); ok {
+			s.logger.Debug().Err(err).Send()
+			return version, err
+		}
+		s.logger.Error().Err(err).Msg("Error occurred while getting configuration version by id")
+		return version, errs.ServiceUnknownError
+	}
+	return version, nil
+}
+
+// Diff returns the changes made to the configuration between the versions. Zero to stands for the latest version.
+func (s *VersionService) Diff(ctx context.Context, from, to int) (model.VersionDiff, error) {
+	if to == 0 {
+		latest, _, err := s.GetAll(ctx, model.Page{Limit: 1})
+		if err != nil {
+			return model.VersionDiff{}, err
+		}
+		if len(latest) == 0 {
+			return model.VersionDiff{}, &errs.EntityNotFoundError{Name: "configuration version", Key: "id", Value: from}
+		}
+		to = latest[0].ID
+	}
+
+	fromVersion, err := s.GetByID(ctx, from)
+	if err != nil {
+		return model.VersionDiff{}, err
+	}
+	toVersion, err := s.GetByID(ctx, to)
+	if err != nil {
+		return model.VersionDiff{}, err
+	}
+
+	changes, err := diffStates(fromVersion.State, toVersion.State)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Error occurred while comparing configuration versions")
+		return model.VersionDiff{}, errs.ServiceUnknownError
+	}
+	return model.VersionDiff{
+		From:       from,
+		To:         to,
+		PACChanged: fromVersion.PAC != toVersion.PAC,
+		Changes:    changes,
+	}, nil
+}
+
+// Rollback restores the configuration of the version and returns the changes it has made. PAC file is regenerated,
+// so the restored configuration is recorded as the new version, unless the changes are drafts: then the restored
+// configuration is the draft to be published.
+func (s *VersionService) Rollback(ctx context.Context, id int) ([]model.VersionChange, error) {
+	changes, err := s.repo.Restore(ctx, id)
+	if err != nil {
+		if _, ok := err.(*errs.EntityNotFoundError); ok {
+			s.logger.Debug().Err(err).Send()
+			return nil, err
+		}
+		s.logger.Error().Err(err).Msg("Error occurred while restoring configuration version")
+		return nil, errs.ServiceUnknownError
+	}
+
+	s.logger.Info().Int("version", id).Int("changes", len(changes)).Msg("Configuration version restored")
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.pacSrvc.GeneratePACFile(ctx); err != nil {
+			s.logger.Error().Err(err).Msg("Error occurred while generating pac file after restoring configuration")
+			return
+		}
+		s.logger.Debug().Msg("Pac file generated after restoring configuration")
+	}()
+
+	return changes, nil
+}
+
+// configState is the configuration recorded in the version, see model.ConfigVersion.
+type configState struct {
+	ProxyProfiles []json.RawMessage `json:"proxy_profiles"`
+	DomainLists   []json.RawMessage `json:"domain_lists"`
+	Rules         []json.RawMessage `json:"rules"`
+	Bypass        json.RawMessage   `json:"bypass"`
+}
+
+// diffStates returns the changes between the states of the configuration, ordered by the entity and its id
+// the same way as the changes made by the rollback.
+func diffStates(from, to string) ([]model.VersionChange, error) {
+	var fromState, toState configState
+	if err := json.Unmarshal([]byte(from), &fromState); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(to), &toState); err != nil {
+		return nil, err
+	}
+
+	changes := make([]model.VersionChange, 0)
+	entities := []struct {
+		name     string
+		from, to []json.RawMessage
+	}{
+		{model.AuditProxyProfile, fromState.ProxyProfiles, toState.ProxyProfiles},
+		{model.AuditDomainList, fromState.DomainLists, toState.DomainLists},
+		{model.AuditRule, fromState.Rules, toState.Rules},
+	}
+	for _, entity := range entities {
+		before, err := entitiesByID(entity.from)
+		if err != nil {
+			return nil, err
+		}
+		after, err := entitiesByID(entity.to)
+		if err != nil {
+			return nil, err
+		}
+
+		ids := make([]int, 0)
+		for id, state := range before {
+			if !bytes.Equal(after[id], state) {
+				ids = append(ids, id)
+			}
+		}
+		for id := range after {
+			if _, ok := before[id]; !ok {
+				ids = append(ids, id)
+			}
+		}
+		sort.Ints(ids)
+
+		for _, id := range ids {
+			change := model.VersionChange{
+				Entity:   entity.name,
+				EntityID: id,
+				Action:   model.AuditUpdate,
+				Before:   string(before[id]),
+				After:    string(after[id]),
+			}
+			switch {
+			case change.Before == "":
+				change.Action = model.AuditCreate
+			case change.After == "":
+				change.Action = model.AuditDelete
+			}
+			changes = append(changes, change)
+		}
+	}
+
+	if !bytes.Equal(fromState.Bypass, toState.Bypass) {
+		changes = append(changes, model.VersionChange{
+			Entity: model.AuditBypass,
+			Action: model.AuditUpdate,
+			Before: string(fromState.Bypass),
+			After:  string(toState.Bypass),
+		})
+	}
+	return changes, nil
+}
+
+// entitiesByID returns the states of the entities by their ids.
+func entitiesByID(states []json.RawMessage) (map[int]json.RawMessage, error) {
+	byID := make(map[int]json.RawMessage, len(states))
+	for _, state := range states {
+		var entity struct {
+			ID int `json:"id"`
+		}
+		if err := json.Unmarshal(state, &entity); err != nil {
+			return nil, err
+		}
+		byID[entity.ID] = state
+	}
+	return byID, nil
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"github.com/go-playground/assert/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/nnemirovsky/pacgen/internal/errs"
+	"github.com/nnemirovsky/pacgen/internal/model"
+	"github.com/nnemirovsky/pacgen/internal/service/mock"
+	"github.com/nnemirovsky/pacgen/pkg/logutil"
+	"testing"
+)
+
+func TestDiffStates(t *testing.T) {
+	t.Parallel()
+
+	from := `{"proxy_profiles":[{"id":1,"name":"tor"},{"id":2,"name":"squid"}],"domain_lists":[],` +
+		`"rules":[{"id":1,"regex":"^a\\.com$"},{"id":2,"regex":"^b\\.com$"}],"bypass":{"entries":[]}}`
+	to := `{"proxy_profiles":[{"id":1,"name":"tor"},{"id":2,"name":"proxy"}],"domain_lists":[{"id":1,"entries":[]}],` +
+		`"rules":[{"id":2,"regex":"^b\\.com$"}],"bypass":{"entries":[{"kind":1,"value":"plain_hostnames"}]}}`
+
+	got, err := diffStates(from, to)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	want := []model.VersionChange{
+		{
+			Entity:   model.AuditProxyProfile,
+			EntityID: 2,
+			Action:   model.AuditUpdate,
+			Before:   `{"id":2,"name":"squid"}`,
+			After:    `{"id":2,"name":"proxy"}`,
+		},
+		{Entity: model.AuditDomainList, EntityID: 1, Action: model.AuditCreate, After: `{"id":1,"entries":[]}`},
+		{Entity: model.AuditRule, EntityID: 1, Action: model.AuditDelete, Before: `{"id":1,"regex":"^a\\.com$"}`},
+		{
+			Entity: model.AuditBypass,
+			Action: model.AuditUpdate,
+			Before: `{"entries":[]}`,
+			After:  `{"entries":[{"kind":1,"value":"plain_hostnames"}]}`,
+		},
+	}
+
+	assert.Equal(t, got, want)
+}
+
+func TestVersionService_Diff_Latest(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	repoMock := mock.NewVersionRepository(ctrl)
+	versionSrvc := NewVersionService(repoMock, nil, logutil.DiscardLogger)
+
+	state := `{"proxy_profiles":[],"domain_lists":[],"rules":[],"bypass":{"entries":[]}}`
+	repoMock.EXPECT().GetAll(gomock.Any(), model.Page{Limit: 1}).Return([]model.ConfigVersion{{ID: 5}}, nil)
+	repoMock.EXPECT().Count(gomock.Any()).Return(5, nil)
+	repoMock.EXPECT().GetByID(gomock.Any(), 3).Return(model.ConfigVersion{ID: 3, State: state, PAC: "a"}, nil)
+	repoMock.EXPECT().GetByID(gomock.Any(), 5).Return(model.ConfigVersion{ID: 5, State: state, PAC: "b"}, nil)
+
+	got, err := versionSrvc.Diff(context.Background(), 3, 0)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	assert.Equal(t, got, model.VersionDiff{From: 3, To: 5, PACChanged: true, Changes: []model.VersionChange{}})
+}
+
+func TestVersionService_Rollback(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	repoMock := mock.NewVersionRepository(ctrl)
+	pacSrvcMock := mock.NewPacService(ctrl)
+	versionSrvc := NewVersionService(repoMock, pacSrvcMock, logutil.DiscardLogger)
+
+	changes := []model.VersionChange{{Entity: model.AuditRule, EntityID: 1, Action: model.AuditCreate, After: `{"id":1}`}}
+	generated := make(chan struct{})
+	repoMock.EXPECT().Restore(gomock.Any(), 4).Return(changes, nil)
+	pacSrvcMock.EXPECT().GeneratePACFile(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		close(generated)
+		return nil
+	})
+
+	got, err := versionSrvc.Rollback(context.Background(), 4)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	<-generated
+
+	assert.Equal(t, got, changes)
+}
+
+func TestVersionService_Rollback_NotFound(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	repoMock := mock.NewVersionRepository(ctrl)
+	versionSrvc := NewVersionService(repoMock, nil, logutil.DiscardLogger)
+
+	notFound := &errs.EntityNotFoundError{Name: "configuration version", Key: "id", Value: 9}
+	repoMock.EXPECT().Restore(gomock.Any(), 9).Return(nil, notFound)
+
+	_, err := versionSrvc.Rollback(context.Background(), 9)
+
+	assert.Equal(t, err, notFound)
+}
@@ -0,0 +1,23 @@
+DELETE
+FROM rules
+WHERE proxy_profile_id IN (SELECT id FROM proxy_profiles WHERE type = 5);
+
+DELETE
+FROM proxy_profiles
+WHERE type = 5;
+
+CREATE TABLE proxy_profiles_new
+(
+    id      INTEGER PRIMARY KEY,
+    name    TEXT    NOT NULL UNIQUE,
+    type    INTEGER NOT NULL CHECK (type >= 1 AND type <= 4),
+    address TEXT
+);
+
+INSERT INTO proxy_profiles_new (id, name, type, address)
+SELECT id, name, type, address
+FROM proxy_profiles;
+
+DROP TABLE proxy_profiles;
+
+ALTER TABLE proxy_profiles_new RENAME TO proxy_profiles;
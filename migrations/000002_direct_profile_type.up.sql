@@ -0,0 +1,15 @@
+CREATE TABLE proxy_profiles_new
+(
+    id      INTEGER PRIMARY KEY,
+    name    TEXT    NOT NULL UNIQUE,
+    type    INTEGER NOT NULL CHECK (type >= 1 AND type <= 5),
+    address TEXT
+);
+
+INSERT INTO proxy_profiles_new (id, name, type, address)
+SELECT id, name, type, address
+FROM proxy_profiles;
+
+DROP TABLE proxy_profiles;
+
+ALTER TABLE proxy_profiles_new RENAME TO proxy_profiles;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS rule_tags;
+DROP TABLE IF EXISTS tags;
+
+ALTER TABLE rules
+    DROP COLUMN enabled;
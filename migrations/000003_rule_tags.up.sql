@@ -0,0 +1,17 @@
+ALTER TABLE rules
+    ADD COLUMN enabled INTEGER NOT NULL DEFAULT 1 CHECK (enabled IN (0, 1));
+
+CREATE TABLE tags
+(
+    id   INTEGER PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE rule_tags
+(
+    rule_id INTEGER NOT NULL REFERENCES rules (id) ON DELETE CASCADE,
+    tag_id  INTEGER NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+    PRIMARY KEY (rule_id, tag_id)
+);
+
+CREATE INDEX rule_tags_tag_id_idx ON rule_tags (tag_id);
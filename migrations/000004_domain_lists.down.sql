@@ -0,0 +1,26 @@
+DELETE
+FROM rule_tags
+WHERE rule_id IN (SELECT id FROM rules WHERE domain_list_id IS NOT NULL);
+
+DELETE
+FROM rules
+WHERE domain_list_id IS NOT NULL;
+
+CREATE TABLE rules_new
+(
+    id               INTEGER PRIMARY KEY,
+    regex            TEXT                                   NOT NULL,
+    proxy_profile_id INTEGER REFERENCES proxy_profiles (id) NOT NULL,
+    enabled          INTEGER                                NOT NULL DEFAULT 1 CHECK (enabled IN (0, 1))
+);
+
+INSERT INTO rules_new (id, regex, proxy_profile_id, enabled)
+SELECT id, regex, proxy_profile_id, enabled
+FROM rules;
+
+DROP TABLE rules;
+
+ALTER TABLE rules_new RENAME TO rules;
+
+DROP TABLE IF EXISTS domain_list_entries;
+DROP TABLE IF EXISTS domain_lists;
@@ -0,0 +1,31 @@
+CREATE TABLE domain_lists
+(
+    id   INTEGER PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE domain_list_entries
+(
+    list_id INTEGER NOT NULL REFERENCES domain_lists (id) ON DELETE CASCADE,
+    domain  TEXT    NOT NULL,
+    mode    INTEGER NOT NULL CHECK (mode IN (1, 2)),
+    PRIMARY KEY (list_id, domain)
+);
+
+CREATE TABLE rules_new
+(
+    id               INTEGER PRIMARY KEY,
+    regex            TEXT,
+    domain_list_id   INTEGER REFERENCES domain_lists (id),
+    proxy_profile_id INTEGER REFERENCES proxy_profiles (id) NOT NULL,
+    enabled          INTEGER NOT NULL DEFAULT 1 CHECK (enabled IN (0, 1)),
+    CHECK ((regex IS NULL) <> (domain_list_id IS NULL))
+);
+
+INSERT INTO rules_new (id, regex, proxy_profile_id, enabled)
+SELECT id, regex, proxy_profile_id, enabled
+FROM rules;
+
+DROP TABLE rules;
+
+ALTER TABLE rules_new RENAME TO rules;
@@ -0,0 +1,2 @@
+ALTER TABLE proxy_profiles
+    ADD COLUMN standby_profile_id INTEGER REFERENCES proxy_profiles (id) CHECK (standby_profile_id <> id);
@@ -0,0 +1,34 @@
+DROP TABLE profile_pool_members;
+
+DELETE
+FROM rule_tags
+WHERE rule_id IN (SELECT id FROM rules WHERE proxy_profile_id IN (SELECT id FROM proxy_profiles WHERE type = 6));
+
+DELETE
+FROM rules
+WHERE proxy_profile_id IN (SELECT id FROM proxy_profiles WHERE type = 6);
+
+UPDATE proxy_profiles
+SET standby_profile_id = NULL
+WHERE standby_profile_id IN (SELECT id FROM proxy_profiles WHERE type = 6);
+
+DELETE
+FROM proxy_profiles
+WHERE type = 6;
+
+CREATE TABLE proxy_profiles_new
+(
+    id                 INTEGER PRIMARY KEY,
+    name               TEXT    NOT NULL UNIQUE,
+    type               INTEGER NOT NULL CHECK (type >= 1 AND type <= 5),
+    address            TEXT,
+    standby_profile_id INTEGER REFERENCES proxy_profiles (id) CHECK (standby_profile_id <> id)
+);
+
+INSERT INTO proxy_profiles_new (id, name, type, address, standby_profile_id)
+SELECT id, name, type, address, standby_profile_id
+FROM proxy_profiles;
+
+DROP TABLE proxy_profiles;
+
+ALTER TABLE proxy_profiles_new RENAME TO proxy_profiles;
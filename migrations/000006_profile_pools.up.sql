@@ -0,0 +1,27 @@
+CREATE TABLE proxy_profiles_new
+(
+    id                 INTEGER PRIMARY KEY,
+    name               TEXT    NOT NULL UNIQUE,
+    type               INTEGER NOT NULL CHECK (type >= 1 AND type <= 6),
+    address            TEXT,
+    standby_profile_id INTEGER REFERENCES proxy_profiles (id) CHECK (standby_profile_id <> id)
+);
+
+INSERT INTO proxy_profiles_new (id, name, type, address, standby_profile_id)
+SELECT id, name, type, address, standby_profile_id
+FROM proxy_profiles;
+
+DROP TABLE proxy_profiles;
+
+ALTER TABLE proxy_profiles_new RENAME TO proxy_profiles;
+
+CREATE TABLE profile_pool_members
+(
+    pool_id   INTEGER NOT NULL REFERENCES proxy_profiles (id) ON DELETE CASCADE,
+    member_id INTEGER NOT NULL REFERENCES proxy_profiles (id),
+    weight    INTEGER NOT NULL DEFAULT 1 CHECK (weight >= 1 AND weight <= 100),
+    PRIMARY KEY (pool_id, member_id),
+    CHECK (member_id <> pool_id)
+);
+
+CREATE INDEX profile_pool_members_member_id_idx ON profile_pool_members (member_id);
@@ -0,0 +1,20 @@
+UPDATE proxy_profiles
+SET address = CASE WHEN instr(host, ':') > 0 THEN '[' || host || ']' ELSE host END || ':' || port
+WHERE host IS NOT NULL;
+
+CREATE TABLE proxy_profiles_new
+(
+    id                 INTEGER PRIMARY KEY,
+    name               TEXT    NOT NULL UNIQUE,
+    type               INTEGER NOT NULL CHECK (type >= 1 AND type <= 6),
+    address            TEXT,
+    standby_profile_id INTEGER REFERENCES proxy_profiles (id) CHECK (standby_profile_id <> id)
+);
+
+INSERT INTO proxy_profiles_new (id, name, type, address, standby_profile_id)
+SELECT id, name, type, address, standby_profile_id
+FROM proxy_profiles;
+
+DROP TABLE proxy_profiles;
+
+ALTER TABLE proxy_profiles_new RENAME TO proxy_profiles;
@@ -0,0 +1,5 @@
+ALTER TABLE proxy_profiles
+    ADD COLUMN host TEXT;
+
+ALTER TABLE proxy_profiles
+    ADD COLUMN port INTEGER CHECK (port >= 1 AND port <= 65535);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS bypass_entries;
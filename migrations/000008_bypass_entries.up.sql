@@ -0,0 +1,6 @@
+CREATE TABLE bypass_entries
+(
+    kind  INTEGER NOT NULL CHECK (kind >= 1 AND kind <= 4),
+    value TEXT    NOT NULL,
+    PRIMARY KEY (kind, value)
+);
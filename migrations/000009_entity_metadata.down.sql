@@ -0,0 +1,17 @@
+ALTER TABLE proxy_profiles
+    DROP COLUMN updated_at;
+ALTER TABLE proxy_profiles
+    DROP COLUMN created_at;
+ALTER TABLE proxy_profiles
+    DROP COLUMN owner;
+ALTER TABLE proxy_profiles
+    DROP COLUMN description;
+
+ALTER TABLE rules
+    DROP COLUMN updated_at;
+ALTER TABLE rules
+    DROP COLUMN created_at;
+ALTER TABLE rules
+    DROP COLUMN owner;
+ALTER TABLE rules
+    DROP COLUMN description;
@@ -0,0 +1,17 @@
+ALTER TABLE rules
+    ADD COLUMN description TEXT NOT NULL DEFAULT '';
+ALTER TABLE rules
+    ADD COLUMN owner TEXT NOT NULL DEFAULT '';
+ALTER TABLE rules
+    ADD COLUMN created_at DATETIME;
+ALTER TABLE rules
+    ADD COLUMN updated_at DATETIME;
+
+ALTER TABLE proxy_profiles
+    ADD COLUMN description TEXT NOT NULL DEFAULT '';
+ALTER TABLE proxy_profiles
+    ADD COLUMN owner TEXT NOT NULL DEFAULT '';
+ALTER TABLE proxy_profiles
+    ADD COLUMN created_at DATETIME;
+ALTER TABLE proxy_profiles
+    ADD COLUMN updated_at DATETIME;
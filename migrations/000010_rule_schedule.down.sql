@@ -0,0 +1,7 @@
+DROP INDEX rules_expires_at_idx;
+DROP INDEX rules_active_from_idx;
+
+ALTER TABLE rules
+    DROP COLUMN expires_at;
+ALTER TABLE rules
+    DROP COLUMN active_from;
@@ -0,0 +1,7 @@
+ALTER TABLE rules
+    ADD COLUMN active_from DATETIME;
+ALTER TABLE rules
+    ADD COLUMN expires_at DATETIME;
+
+CREATE INDEX rules_active_from_idx ON rules (active_from);
+CREATE INDEX rules_expires_at_idx ON rules (expires_at);
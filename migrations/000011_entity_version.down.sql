@@ -0,0 +1,5 @@
+ALTER TABLE rules
+    DROP COLUMN version;
+
+ALTER TABLE proxy_profiles
+    DROP COLUMN version;
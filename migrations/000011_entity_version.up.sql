@@ -0,0 +1,5 @@
+ALTER TABLE rules
+    ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+ALTER TABLE proxy_profiles
+    ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
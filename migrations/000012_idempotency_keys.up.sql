@@ -0,0 +1,12 @@
+CREATE TABLE idempotency_keys
+(
+    key         TEXT PRIMARY KEY,
+    fingerprint TEXT     NOT NULL,
+    status_code INTEGER  NOT NULL,
+    header      TEXT     NOT NULL,
+    body        BLOB     NOT NULL,
+    created_at  DATETIME NOT NULL,
+    expires_at  DATETIME NOT NULL
+);
+
+CREATE INDEX idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
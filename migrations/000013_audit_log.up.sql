@@ -0,0 +1,24 @@
+CREATE TABLE audit_log
+(
+    id          INTEGER PRIMARY KEY,
+    actor       TEXT     NOT NULL,
+    request_id  TEXT     NOT NULL,
+    remote_addr TEXT     NOT NULL,
+    entity      TEXT     NOT NULL,
+    entity_id   INTEGER,
+    action      TEXT     NOT NULL CHECK (action IN ('create', 'update', 'delete')),
+    before      TEXT,
+    after       TEXT,
+    created_at  DATETIME NOT NULL
+);
+
+CREATE INDEX audit_log_created_at_idx ON audit_log (created_at);
+CREATE INDEX audit_log_entity_idx ON audit_log (entity, entity_id);
+
+-- The log is append-only, entries are only deleted once they are past the retention.
+CREATE TRIGGER audit_log_append_only
+    BEFORE UPDATE
+    ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit log is append-only');
+END;
@@ -0,0 +1,7 @@
+CREATE TABLE config_versions
+(
+    id         INTEGER PRIMARY KEY,
+    state      TEXT     NOT NULL,
+    pac        TEXT     NOT NULL,
+    created_at DATETIME NOT NULL
+);
@@ -0,0 +1,8 @@
+-- Approvals are bound to the state of the draft, so any change made afterwards needs to be approved again.
+CREATE TABLE draft_approvals
+(
+    user        TEXT     NOT NULL,
+    state       TEXT     NOT NULL,
+    approved_at DATETIME NOT NULL,
+    PRIMARY KEY (user, state)
+);
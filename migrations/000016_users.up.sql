@@ -0,0 +1,10 @@
+-- Roles are 1 for viewers, 2 for editors and 3 for admins, see model.Role.
+CREATE TABLE users
+(
+    id            INTEGER PRIMARY KEY,
+    name          TEXT     NOT NULL UNIQUE,
+    password_hash TEXT     NOT NULL,
+    role          INTEGER  NOT NULL CHECK (role BETWEEN 1 AND 3),
+    created_at    DATETIME NOT NULL,
+    updated_at    DATETIME NOT NULL
+);
@@ -0,0 +1,15 @@
+-- Tokens without a user are service tokens. Only SHA-256 hashes of the tokens are stored, scopes are separated
+-- by spaces.
+CREATE TABLE api_tokens
+(
+    id           INTEGER PRIMARY KEY,
+    name         TEXT     NOT NULL,
+    user_id      INTEGER REFERENCES users (id) ON DELETE CASCADE,
+    token_hash   TEXT     NOT NULL UNIQUE,
+    scopes       TEXT     NOT NULL,
+    expires_at   DATETIME,
+    last_used_at DATETIME,
+    created_at   DATETIME NOT NULL
+);
+
+CREATE INDEX api_tokens_user_id_idx ON api_tokens (user_id);
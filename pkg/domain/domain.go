@@ -0,0 +1,61 @@
+// Package domain normalizes domain names the way browsers pass them to FindProxyForURL.
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"golang.org/x/net/idna"
+	"net"
+	"regexp"
+	"strings"
+)
+
+// labelsRe matches lowercase ASCII domain names. Underscores are allowed, since they are common in internal names.
+var labelsRe = regexp.MustCompile(`^[a-z0-9_]([a-z0-9_-]{0,61}[a-z0-9_])?(\.[a-z0-9_]([a-z0-9_-]{0,61}[a-z0-9_])?)*$`)
+
+// profile maps names for lookup according to UTS #46 without the transitional processing, like browsers do.
+// STD3 rules are relaxed to let underscores through, the rest of ASCII is checked by labelsRe.
+var profile = idna.New(
+	idna.MapForLookup(),
+	idna.Transitional(false),
+	idna.BidiRule(),
+	idna.StrictDomainName(false),
+	idna.VerifyDNSLength(true),
+)
+
+var (
+	ErrEmpty  = errors.New("domain must not be empty")
+	ErrLabels = errors.New("domain must consist of letters, digits, hyphens and underscores separated by dots")
+)
+
+// Normalize converts the domain into the ASCII form compared with the host in PAC file: it is lowercased,
+// stripped of the trailing dot and converted to punycode. IP addresses are returned in canonical form.
+func Normalize(domain string) (string, error) {
+	if ip := net.ParseIP(domain); ip != nil {
+		return ip.String(), nil
+	}
+
+	domain = strings.TrimSuffix(domain, ".")
+	if domain == "" {
+		return "", ErrEmpty
+	}
+
+	ascii, err := profile.ToASCII(domain)
+	if err != nil {
+		return "", fmt.Errorf("invalid international domain: %w", err)
+	}
+	if !labelsRe.MatchString(ascii) {
+		return "", ErrLabels
+	}
+	return ascii, nil
+}
+
+// ToUnicode converts the normalized domain back into Unicode form for display. Labels that are not valid
+// punycode are left as they are.
+func ToUnicode(domain string) string {
+	unicode, err := profile.ToUnicode(domain)
+	if err != nil {
+		return domain
+	}
+	return unicode
+}
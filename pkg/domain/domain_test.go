@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"github.com/go-playground/assert/v2"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	t.Parallel()
+
+	data := []struct{ name, input, want string }{
+		{name: "ascii", input: "www.google.com", want: "www.google.com"},
+		{name: "uppercase", input: "WWW.Google.COM", want: "www.google.com"},
+		{name: "trailing dot", input: "google.com.", want: "google.com"},
+		{name: "cyrillic", input: "Пример.РФ", want: "xn--e1afmkfd.xn--p1ai"},
+		{name: "punycode", input: "xn--e1afmkfd.xn--p1ai", want: "xn--e1afmkfd.xn--p1ai"},
+		{name: "nontransitional", input: "faß.de", want: "xn--fa-hia.de"},
+		{name: "fullwidth", input: "ｇｏｏｇｌｅ.com", want: "google.com"},
+		{name: "underscore", input: "_sip.corp", want: "_sip.corp"},
+		{name: "ipv4", input: "10.0.0.1", want: "10.0.0.1"},
+		{name: "ipv6", input: "2001:DB8::1", want: "2001:db8::1"},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Normalize(d.input)
+			if err != nil {
+				t.Errorf("Unexpected error: %#v", err)
+			}
+			assert.Equal(t, got, d.want)
+		})
+	}
+}
+
+func TestNormalize_Invalid(t *testing.T) {
+	t.Parallel()
+
+	data := []struct{ name, input string }{
+		{name: "empty", input: ""},
+		{name: "dot", input: "."},
+		{name: "empty label", input: "google..com"},
+		{name: "leading hyphen", input: "-google.com"},
+		{name: "port", input: "localhost:80"},
+		{name: "wildcard", input: "*.google.com"},
+		{name: "space", input: "google com"},
+		{name: "bad punycode", input: "xn--zz.com"},
+		{name: "long label", input: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa.com"},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got, err := Normalize(d.input); err == nil {
+				t.Errorf("expected error, but got %q", got)
+			}
+		})
+	}
+}
+
+func TestToUnicode(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, ToUnicode("xn--e1afmkfd.xn--p1ai"), "пример.рф")
+	assert.Equal(t, ToUnicode("google.com"), "google.com")
+	assert.Equal(t, ToUnicode("xn--zz.com"), "xn--zz.com")
+}
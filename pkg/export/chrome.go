@@ -0,0 +1,142 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	Register("chrome-policy.json", chromePolicy{})
+	Register("chrome-bypass-list.txt", chromeBypassList{})
+}
+
+// chromePolicy exports ProxySettings policy of Chrome and Edge.
+// See https://chromeenterprise.google/policies/#ProxySettings.
+//
+// If all the traffic goes through the default proxy except for the direct rules, the policy
+// configures the proxy server explicitly and turns the rules into a bypass list.
+// Otherwise, the policy points browsers at the PAC file.
+type chromePolicy struct{}
+
+type chromeProxySettings struct {
+	ProxyMode       string `json:"ProxyMode"`
+	ProxyPacURL     string `json:"ProxyPacUrl,omitempty"`
+	ProxyServer     string `json:"ProxyServer,omitempty"`
+	ProxyBypassList string `json:"ProxyBypassList,omitempty"`
+}
+
+type chromePolicyConfig struct {
+	ProxySettings chromeProxySettings `json:"ProxySettings"`
+}
+
+func (chromePolicy) ContentType() string {
+	return "application/json"
+}
+
+func (chromePolicy) Export(wr io.Writer, cfg Config) ([]Skipped, error) {
+	var (
+		config  chromePolicyConfig
+		skipped []Skipped
+	)
+
+	if server, ok := chromeProxyServer(cfg); ok && chromeOnlyDirectExceptions(cfg) {
+		var entries []string
+		entries, skipped = chromeBypassEntries(cfg)
+		config.ProxySettings = chromeProxySettings{
+			ProxyMode:       "fixed_servers",
+			ProxyServer:     server,
+			ProxyBypassList: strings.Join(entries, ","),
+		}
+	} else {
+		if cfg.PACURL == "" {
+			return nil, fmt.Errorf("export: pac url is required")
+		}
+		config.ProxySettings = chromeProxySettings{ProxyMode: "pac_script", ProxyPacURL: cfg.PACURL}
+	}
+
+	encoder := json.NewEncoder(wr)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(config); err != nil {
+		return nil, err
+	}
+	return skipped, nil
+}
+
+// chromeBypassList exports direct rules as a value of ProxyBypassList policy or
+// --proxy-bypass-list command line switch.
+// See https://chromium.googlesource.com/chromium/src/+/HEAD/net/docs/proxy.md#proxy-bypass-rules.
+type chromeBypassList struct{}
+
+func (chromeBypassList) ContentType() string {
+	return "text/plain; charset=utf-8"
+}
+
+func (chromeBypassList) Export(wr io.Writer, cfg Config) ([]Skipped, error) {
+	entries, skipped := chromeBypassEntries(cfg)
+	if _, err := fmt.Fprintln(wr, strings.Join(entries, ";")); err != nil {
+		return nil, err
+	}
+	return skipped, nil
+}
+
+// chromeProxyServer returns the default proxy in the format of ProxyServer policy.
+func chromeProxyServer(cfg Config) (string, bool) {
+	if cfg.DefaultProxy == "" {
+		return "", false
+	}
+	for _, proxy := range cfg.Proxies {
+		if proxy.Name == cfg.DefaultProxy {
+			return proxy.Protocol + "://" + net.JoinHostPort(proxy.Host, strconv.Itoa(proxy.Port)), true
+		}
+	}
+	return "", false
+}
+
+// chromeOnlyDirectExceptions reports whether rules route traffic either directly or through the default proxy.
+// Rules routed through the default proxy are only allowed after all the direct ones, since bypass list
+// cannot express an exception from an exception.
+func chromeOnlyDirectExceptions(cfg Config) bool {
+	lastDirect, firstDefault := -1, len(cfg.Rules)
+	for i, rule := range cfg.Rules {
+		switch rule.Proxy {
+		case "":
+			lastDirect = i
+		case cfg.DefaultProxy:
+			if i < firstDefault {
+				firstDefault = i
+			}
+		default:
+			return false
+		}
+	}
+	return lastDirect < firstDefault
+}
+
+// chromeBypassEntries converts direct rules into bypass list entries. Rules routed through proxies
+// are skipped as well as regular expressions.
+func chromeBypassEntries(cfg Config) ([]string, []Skipped) {
+	var skipped []Skipped
+	entries := make([]string, 0, len(cfg.Rules))
+
+	for _, rule := range cfg.Rules {
+		if rule.Proxy != "" {
+			if rule.Proxy != cfg.DefaultProxy {
+				skipped = append(skipped, Skipped{RuleID: rule.ID, Reason: "routed through proxy " + rule.Proxy})
+			}
+			continue
+		}
+		switch rule.Kind {
+		case Domain, IPCIDR:
+			entries = append(entries, rule.Value)
+		case DomainSuffix:
+			entries = append(entries, rule.Value, "*."+rule.Value)
+		default:
+			skipped = append(skipped, Skipped{RuleID: rule.ID, Reason: "regular expressions are not supported"})
+		}
+	}
+	return entries, skipped
+}
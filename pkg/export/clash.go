@@ -0,0 +1,93 @@
+package export
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"strings"
+)
+
+func init() {
+	Register("clash.yaml", clash{})
+}
+
+// clash exports rules in Clash configuration format.
+// See https://github.com/Dreamacro/clash/wiki/configuration.
+type clash struct{}
+
+type clashProxy struct {
+	Name   string `yaml:"name"`
+	Type   string `yaml:"type"`
+	Server string `yaml:"server"`
+	Port   int    `yaml:"port"`
+	TLS    bool   `yaml:"tls,omitempty"`
+}
+
+type clashConfig struct {
+	Proxies []clashProxy `yaml:"proxies"`
+	Rules   []string     `yaml:"rules"`
+}
+
+func (clash) ContentType() string {
+	return "application/yaml"
+}
+
+func (clash) Export(wr io.Writer, cfg Config) ([]Skipped, error) {
+	var skipped []Skipped
+	skippedProxies := make(map[string]struct{})
+
+	config := clashConfig{Proxies: make([]clashProxy, 0), Rules: make([]string, 0)}
+	for _, proxy := range cfg.Proxies {
+		p := clashProxy{Name: proxy.Name, Server: proxy.Host, Port: proxy.Port}
+		switch proxy.Protocol {
+		case HTTP:
+			p.Type = "http"
+		case HTTPS:
+			p.Type, p.TLS = "http", true
+		case SOCKS5:
+			p.Type = "socks5"
+		default:
+			skipped = append(skipped, Skipped{Proxy: proxy.Name, Reason: "unsupported protocol " + proxy.Protocol})
+			skippedProxies[proxy.Name] = struct{}{}
+			continue
+		}
+		if strings.Contains(proxy.Name, ",") {
+			skipped = append(skipped, Skipped{Proxy: proxy.Name, Reason: "name contains a comma"})
+			skippedProxies[proxy.Name] = struct{}{}
+			continue
+		}
+		config.Proxies = append(config.Proxies, p)
+	}
+
+	rules, skippedRules := resolve(cfg, skippedProxies)
+	skipped = append(skipped, skippedRules...)
+
+	for _, rule := range rules {
+		target := rule.Proxy
+		if target == "" {
+			target = "DIRECT"
+		}
+		switch rule.Kind {
+		case Domain:
+			config.Rules = append(config.Rules, fmt.Sprintf("DOMAIN,%s,%s", rule.Value, target))
+		case DomainSuffix:
+			config.Rules = append(config.Rules, fmt.Sprintf("DOMAIN-SUFFIX,%s,%s", rule.Value, target))
+		case IPCIDR:
+			config.Rules = append(config.Rules, fmt.Sprintf("%s,%s,%s,no-resolve", ipCIDRType(rule.Value), rule.Value, target))
+		default:
+			skipped = append(skipped, Skipped{RuleID: rule.ID, Reason: "regular expressions are not supported"})
+		}
+	}
+	final, err := finalTarget(cfg, skippedProxies, "DIRECT")
+	if err != nil {
+		return nil, err
+	}
+	config.Rules = append(config.Rules, "MATCH,"+final)
+
+	encoder := yaml.NewEncoder(wr)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(config); err != nil {
+		return nil, err
+	}
+	return skipped, encoder.Close()
+}
@@ -0,0 +1,163 @@
+// Package export renders proxy profiles and routing rules into configuration formats
+// of proxy clients other than browsers (Clash, sing-box, Surge etc.).
+//
+// Every format is implemented as an Exporter registered under the file name it is served as,
+// so adding a new format boils down to a single file with an init function calling Register.
+package export
+
+import (
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Kind of the rule matcher.
+type Kind int
+
+const (
+	// Domain matches the host exactly.
+	Domain Kind = iota + 1
+	// DomainSuffix matches the domain and all of its subdomains.
+	DomainSuffix
+	// IPCIDR matches IP addresses within the network.
+	IPCIDR
+	// Regex matches the host against a regular expression.
+	Regex
+)
+
+// Proxy protocols supported by Proxy.
+const (
+	HTTP   = "http"
+	HTTPS  = "https"
+	SOCKS4 = "socks4"
+	SOCKS5 = "socks5"
+)
+
+type Proxy struct {
+	Name     string
+	Protocol string
+	Host     string
+	Port     int
+}
+
+type Rule struct {
+	ID    int
+	Kind  Kind
+	Value string
+	// Name of the proxy to route matched traffic through. Empty value means direct connection.
+	Proxy string
+}
+
+// Options are export parameters that are not stored along with the rules.
+type Options struct {
+	// PACURL is the externally visible url of the PAC file generated from the same rules.
+	PACURL string
+	// DefaultProxy is the name of the proxy for traffic not matched by any rule.
+	// Empty value means direct connection.
+	DefaultProxy string
+}
+
+type Config struct {
+	Options
+	Proxies []Proxy
+	// Rules in evaluation order.
+	Rules []Rule
+}
+
+// Skipped describes a rule or a proxy that cannot be expressed in the target format.
+type Skipped struct {
+	// ID of the skipped rule, zero if a proxy is skipped.
+	RuleID int
+	// Name of the skipped proxy, empty if a rule is skipped.
+	Proxy  string
+	Reason string
+}
+
+type Exporter interface {
+	// ContentType returns the media type of the exported document.
+	ContentType() string
+	// Export writes cfg to wr. Rules and proxies that cannot be expressed in the format are
+	// left out and reported in the returned slice.
+	Export(wr io.Writer, cfg Config) ([]Skipped, error)
+}
+
+// ErrDefaultProxySkipped is returned by exporters when the default proxy cannot be expressed in the format.
+var ErrDefaultProxySkipped = errors.New("default proxy cannot be exported")
+
+var (
+	mu        sync.RWMutex
+	exporters = make(map[string]Exporter)
+)
+
+// Register makes the exporter available by the given name. It panics if the name is already taken.
+func Register(name string, exporter Exporter) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, ok := exporters[name]; ok {
+		panic("export: Register called twice for exporter " + name)
+	}
+	exporters[name] = exporter
+}
+
+// Get returns the exporter registered by the given name.
+func Get(name string) (Exporter, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	exporter, ok := exporters[name]
+	return exporter, ok
+}
+
+// Names returns the sorted list of registered exporter names.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(exporters))
+	for name := range exporters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resolve filters out rules routed through proxies that are either skipped or absent from the config.
+func resolve(cfg Config, skippedProxies map[string]struct{}) (rules []Rule, skipped []Skipped) {
+	proxies := make(map[string]struct{}, len(cfg.Proxies))
+	for _, proxy := range cfg.Proxies {
+		if _, ok := skippedProxies[proxy.Name]; !ok {
+			proxies[proxy.Name] = struct{}{}
+		}
+	}
+
+	for _, rule := range cfg.Rules {
+		if _, ok := proxies[rule.Proxy]; !ok && rule.Proxy != "" {
+			skipped = append(skipped, Skipped{RuleID: rule.ID, Reason: "proxy " + rule.Proxy + " is not exported"})
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules, skipped
+}
+
+// finalTarget returns the name of the proxy for unmatched traffic or directTarget if it goes directly.
+func finalTarget(cfg Config, skippedProxies map[string]struct{}, directTarget string) (string, error) {
+	if cfg.DefaultProxy == "" {
+		return directTarget, nil
+	}
+	if _, ok := skippedProxies[cfg.DefaultProxy]; ok {
+		return "", ErrDefaultProxySkipped
+	}
+	return cfg.DefaultProxy, nil
+}
+
+// ipCIDRType returns the rule type used by Clash and Surge for the network.
+func ipCIDRType(cidr string) string {
+	if strings.Contains(cidr, ":") {
+		return "IP-CIDR6"
+	}
+	return "IP-CIDR"
+}
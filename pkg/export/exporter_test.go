@@ -0,0 +1,319 @@
+package export
+
+import (
+	"bytes"
+	"github.com/go-playground/assert/v2"
+	"testing"
+)
+
+func testConfig() Config {
+	return Config{
+		Proxies: []Proxy{
+			{Name: "tor", Protocol: SOCKS5, Host: "localhost", Port: 9050},
+			{Name: "corp", Protocol: HTTPS, Host: "proxy.corp", Port: 3128},
+			{Name: "legacy", Protocol: SOCKS4, Host: "10.0.0.1", Port: 1080},
+		},
+		Rules: []Rule{
+			{ID: 1, Kind: Domain, Value: "www.google.com", Proxy: "tor"},
+			{ID: 2, Kind: DomainSuffix, Value: "facebook.com", Proxy: "corp"},
+			{ID: 3, Kind: IPCIDR, Value: "1.2.3.4/32", Proxy: "tor"},
+			{ID: 4, Kind: Regex, Value: `^api\d+\.example\.com$`, Proxy: "tor"},
+			{ID: 5, Kind: Domain, Value: "old.example.com", Proxy: "legacy"},
+			{ID: 6, Kind: DomainSuffix, Value: "local", Proxy: ""},
+		},
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	want := []string{"chrome-bypass-list.txt", "chrome-policy.json", "clash.yaml", "sing-box.json", "surge.conf"}
+	assert.Equal(t, Names(), want)
+
+	_, ok := Get("unknown.txt")
+	assert.Equal(t, ok, false)
+}
+
+func TestClash_Export(t *testing.T) {
+	t.Parallel()
+
+	exporter, _ := Get("clash.yaml")
+	buf := bytes.Buffer{}
+
+	skipped, err := exporter.Export(&buf, testConfig())
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	want := `proxies:
+  - name: tor
+    type: socks5
+    server: localhost
+    port: 9050
+  - name: corp
+    type: http
+    server: proxy.corp
+    port: 3128
+    tls: true
+rules:
+  - DOMAIN,www.google.com,tor
+  - DOMAIN-SUFFIX,facebook.com,corp
+  - IP-CIDR,1.2.3.4/32,tor,no-resolve
+  - DOMAIN-SUFFIX,local,DIRECT
+  - MATCH,DIRECT
+`
+	wantSkipped := []Skipped{
+		{Proxy: "legacy", Reason: "unsupported protocol socks4"},
+		{RuleID: 5, Reason: "proxy legacy is not exported"},
+		{RuleID: 4, Reason: "regular expressions are not supported"},
+	}
+
+	assert.Equal(t, buf.String(), want)
+	assert.Equal(t, skipped, wantSkipped)
+}
+
+func TestSingBox_Export(t *testing.T) {
+	t.Parallel()
+
+	exporter, _ := Get("sing-box.json")
+	buf := bytes.Buffer{}
+
+	skipped, err := exporter.Export(&buf, testConfig())
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	want := `{
+  "outbounds": [
+    {
+      "type": "direct",
+      "tag": "direct"
+    },
+    {
+      "type": "socks",
+      "tag": "tor",
+      "server": "localhost",
+      "server_port": 9050,
+      "version": "5"
+    },
+    {
+      "type": "http",
+      "tag": "corp",
+      "server": "proxy.corp",
+      "server_port": 3128,
+      "tls": {
+        "enabled": true
+      }
+    },
+    {
+      "type": "socks",
+      "tag": "legacy",
+      "server": "10.0.0.1",
+      "server_port": 1080,
+      "version": "4"
+    }
+  ],
+  "route": {
+    "rules": [
+      {
+        "domain": [
+          "www.google.com"
+        ],
+        "outbound": "tor"
+      },
+      {
+        "domain_suffix": [
+          "facebook.com"
+        ],
+        "outbound": "corp"
+      },
+      {
+        "ip_cidr": [
+          "1.2.3.4/32"
+        ],
+        "outbound": "tor"
+      },
+      {
+        "domain_regex": [
+          "^api\\d+\\.example\\.com$"
+        ],
+        "outbound": "tor"
+      },
+      {
+        "domain": [
+          "old.example.com"
+        ],
+        "outbound": "legacy"
+      },
+      {
+        "domain_suffix": [
+          "local"
+        ],
+        "outbound": "direct"
+      }
+    ],
+    "final": "direct"
+  }
+}
+`
+
+	assert.Equal(t, buf.String(), want)
+	assert.Equal(t, len(skipped), 0)
+}
+
+func TestSurge_Export(t *testing.T) {
+	t.Parallel()
+
+	exporter, _ := Get("surge.conf")
+	buf := bytes.Buffer{}
+
+	skipped, err := exporter.Export(&buf, testConfig())
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	want := `[Proxy]
+tor = socks5, localhost, 9050
+corp = https, proxy.corp, 3128
+
+[Rule]
+DOMAIN,www.google.com,tor
+DOMAIN-SUFFIX,facebook.com,corp
+IP-CIDR,1.2.3.4/32,tor,no-resolve
+DOMAIN-SUFFIX,local,DIRECT
+FINAL,DIRECT
+`
+	wantSkipped := []Skipped{
+		{Proxy: "legacy", Reason: "unsupported protocol socks4"},
+		{RuleID: 5, Reason: "proxy legacy is not exported"},
+		{RuleID: 4, Reason: "regular expressions are not supported"},
+	}
+
+	assert.Equal(t, buf.String(), want)
+	assert.Equal(t, skipped, wantSkipped)
+}
+
+func TestClash_Export_DefaultProxySkipped(t *testing.T) {
+	t.Parallel()
+
+	exporter, _ := Get("clash.yaml")
+	cfg := testConfig()
+	cfg.DefaultProxy = "legacy"
+
+	_, err := exporter.Export(&bytes.Buffer{}, cfg)
+
+	assert.Equal(t, err, ErrDefaultProxySkipped)
+}
+
+func TestChromePolicy_Export_PACScript(t *testing.T) {
+	t.Parallel()
+
+	exporter, _ := Get("chrome-policy.json")
+	buf := bytes.Buffer{}
+	cfg := testConfig()
+	cfg.PACURL = "https://pacgen.corp/proxy.pac"
+	cfg.DefaultProxy = "corp"
+
+	skipped, err := exporter.Export(&buf, cfg)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	want := `{
+  "ProxySettings": {
+    "ProxyMode": "pac_script",
+    "ProxyPacUrl": "https://pacgen.corp/proxy.pac"
+  }
+}
+`
+
+	assert.Equal(t, buf.String(), want)
+	assert.Equal(t, len(skipped), 0)
+}
+
+func TestChromePolicy_Export_FixedServers(t *testing.T) {
+	t.Parallel()
+
+	exporter, _ := Get("chrome-policy.json")
+	buf := bytes.Buffer{}
+	cfg := Config{
+		Options: Options{PACURL: "https://pacgen.corp/proxy.pac", DefaultProxy: "corp"},
+		Proxies: []Proxy{{Name: "corp", Protocol: HTTPS, Host: "proxy.corp", Port: 3128}},
+		Rules: []Rule{
+			{ID: 1, Kind: Domain, Value: "intranet.corp"},
+			{ID: 2, Kind: DomainSuffix, Value: "local"},
+			{ID: 3, Kind: IPCIDR, Value: "10.0.0.0/8"},
+			{ID: 4, Kind: Regex, Value: `^host\d+$`},
+			{ID: 5, Kind: Domain, Value: "www.google.com", Proxy: "corp"},
+		},
+	}
+
+	skipped, err := exporter.Export(&buf, cfg)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	want := `{
+  "ProxySettings": {
+    "ProxyMode": "fixed_servers",
+    "ProxyServer": "https://proxy.corp:3128",
+    "ProxyBypassList": "intranet.corp,local,*.local,10.0.0.0/8"
+  }
+}
+`
+
+	assert.Equal(t, buf.String(), want)
+	assert.Equal(t, skipped, []Skipped{{RuleID: 4, Reason: "regular expressions are not supported"}})
+}
+
+func TestChromePolicy_Export_DefaultProxyRuleBeforeDirect(t *testing.T) {
+	t.Parallel()
+
+	exporter, _ := Get("chrome-policy.json")
+	buf := bytes.Buffer{}
+	cfg := Config{
+		Options: Options{PACURL: "https://pacgen.corp/proxy.pac", DefaultProxy: "corp"},
+		Proxies: []Proxy{{Name: "corp", Protocol: HTTP, Host: "proxy.corp", Port: 3128}},
+		Rules: []Rule{
+			{ID: 1, Kind: Domain, Value: "api.example.com", Proxy: "corp"},
+			{ID: 2, Kind: DomainSuffix, Value: "example.com"},
+		},
+	}
+
+	if _, err := exporter.Export(&buf, cfg); err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	want := `{
+  "ProxySettings": {
+    "ProxyMode": "pac_script",
+    "ProxyPacUrl": "https://pacgen.corp/proxy.pac"
+  }
+}
+`
+
+	assert.Equal(t, buf.String(), want)
+}
+
+func TestChromeBypassList_Export(t *testing.T) {
+	t.Parallel()
+
+	exporter, _ := Get("chrome-bypass-list.txt")
+	buf := bytes.Buffer{}
+
+	skipped, err := exporter.Export(&buf, testConfig())
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	wantSkipped := []Skipped{
+		{RuleID: 1, Reason: "routed through proxy tor"},
+		{RuleID: 2, Reason: "routed through proxy corp"},
+		{RuleID: 3, Reason: "routed through proxy tor"},
+		{RuleID: 4, Reason: "routed through proxy tor"},
+		{RuleID: 5, Reason: "routed through proxy legacy"},
+	}
+
+	assert.Equal(t, buf.String(), "local;*.local\n")
+	assert.Equal(t, skipped, wantSkipped)
+}
@@ -0,0 +1,118 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+)
+
+const singBoxDirectTag = "direct"
+
+func init() {
+	Register("sing-box.json", singBox{})
+}
+
+// singBox exports rules in sing-box configuration format.
+// See https://sing-box.sagernet.org/configuration/.
+type singBox struct{}
+
+type singBoxTLS struct {
+	Enabled bool `json:"enabled"`
+}
+
+type singBoxOutbound struct {
+	Type       string      `json:"type"`
+	Tag        string      `json:"tag"`
+	Server     string      `json:"server,omitempty"`
+	ServerPort int         `json:"server_port,omitempty"`
+	Version    string      `json:"version,omitempty"`
+	TLS        *singBoxTLS `json:"tls,omitempty"`
+}
+
+type singBoxRule struct {
+	Domain       []string `json:"domain,omitempty"`
+	DomainSuffix []string `json:"domain_suffix,omitempty"`
+	DomainRegex  []string `json:"domain_regex,omitempty"`
+	IPCIDR       []string `json:"ip_cidr,omitempty"`
+	Outbound     string   `json:"outbound"`
+}
+
+type singBoxRoute struct {
+	Rules []singBoxRule `json:"rules"`
+	Final string        `json:"final"`
+}
+
+type singBoxConfig struct {
+	Outbounds []singBoxOutbound `json:"outbounds"`
+	Route     singBoxRoute      `json:"route"`
+}
+
+func (singBox) ContentType() string {
+	return "application/json"
+}
+
+func (singBox) Export(wr io.Writer, cfg Config) ([]Skipped, error) {
+	var skipped []Skipped
+	skippedProxies := make(map[string]struct{})
+
+	config := singBoxConfig{
+		Outbounds: []singBoxOutbound{{Type: "direct", Tag: singBoxDirectTag}},
+		Route:     singBoxRoute{Rules: make([]singBoxRule, 0)},
+	}
+	for _, proxy := range cfg.Proxies {
+		if proxy.Name == singBoxDirectTag {
+			skipped = append(skipped, Skipped{Proxy: proxy.Name, Reason: "name is reserved for direct outbound"})
+			skippedProxies[proxy.Name] = struct{}{}
+			continue
+		}
+		o := singBoxOutbound{Tag: proxy.Name, Server: proxy.Host, ServerPort: proxy.Port}
+		switch proxy.Protocol {
+		case HTTP:
+			o.Type = "http"
+		case HTTPS:
+			o.Type, o.TLS = "http", &singBoxTLS{Enabled: true}
+		case SOCKS4:
+			o.Type, o.Version = "socks", "4"
+		case SOCKS5:
+			o.Type, o.Version = "socks", "5"
+		default:
+			skipped = append(skipped, Skipped{Proxy: proxy.Name, Reason: "unsupported protocol " + proxy.Protocol})
+			skippedProxies[proxy.Name] = struct{}{}
+			continue
+		}
+		config.Outbounds = append(config.Outbounds, o)
+	}
+
+	rules, skippedRules := resolve(cfg, skippedProxies)
+	skipped = append(skipped, skippedRules...)
+
+	for _, rule := range rules {
+		r := singBoxRule{Outbound: rule.Proxy}
+		if r.Outbound == "" {
+			r.Outbound = singBoxDirectTag
+		}
+		switch rule.Kind {
+		case Domain:
+			r.Domain = []string{rule.Value}
+		case DomainSuffix:
+			r.DomainSuffix = []string{rule.Value}
+		case IPCIDR:
+			r.IPCIDR = []string{rule.Value}
+		case Regex:
+			r.DomainRegex = []string{rule.Value}
+		}
+		config.Route.Rules = append(config.Route.Rules, r)
+	}
+
+	final, err := finalTarget(cfg, skippedProxies, singBoxDirectTag)
+	if err != nil {
+		return nil, err
+	}
+	config.Route.Final = final
+
+	encoder := json.NewEncoder(wr)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(config); err != nil {
+		return nil, err
+	}
+	return skipped, nil
+}
@@ -0,0 +1,75 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+func init() {
+	Register("surge.conf", surge{})
+}
+
+// surge exports rules in Surge configuration format.
+// See https://manual.nssurge.com/overview/configuration.html.
+type surge struct{}
+
+func (surge) ContentType() string {
+	return "text/plain; charset=utf-8"
+}
+
+func (surge) Export(wr io.Writer, cfg Config) ([]Skipped, error) {
+	var skipped []Skipped
+	skippedProxies := make(map[string]struct{})
+
+	w := bufio.NewWriter(wr)
+
+	fmt.Fprintln(w, "[Proxy]")
+	for _, proxy := range cfg.Proxies {
+		if strings.ContainsAny(proxy.Name, ",=") {
+			skipped = append(skipped, Skipped{Proxy: proxy.Name, Reason: "name contains a comma or an equals sign"})
+			skippedProxies[proxy.Name] = struct{}{}
+			continue
+		}
+		switch proxy.Protocol {
+		case HTTP, HTTPS, SOCKS5:
+			fmt.Fprintf(w, "%s = %s, %s, %d\n", proxy.Name, proxy.Protocol, proxy.Host, proxy.Port)
+		default:
+			skipped = append(skipped, Skipped{Proxy: proxy.Name, Reason: "unsupported protocol " + proxy.Protocol})
+			skippedProxies[proxy.Name] = struct{}{}
+		}
+	}
+
+	rules, skippedRules := resolve(cfg, skippedProxies)
+	skipped = append(skipped, skippedRules...)
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "[Rule]")
+	for _, rule := range rules {
+		target := rule.Proxy
+		if target == "" {
+			target = "DIRECT"
+		}
+		switch rule.Kind {
+		case Domain:
+			fmt.Fprintf(w, "DOMAIN,%s,%s\n", rule.Value, target)
+		case DomainSuffix:
+			fmt.Fprintf(w, "DOMAIN-SUFFIX,%s,%s\n", rule.Value, target)
+		case IPCIDR:
+			fmt.Fprintf(w, "%s,%s,%s,no-resolve\n", ipCIDRType(rule.Value), rule.Value, target)
+		default:
+			skipped = append(skipped, Skipped{RuleID: rule.ID, Reason: "regular expressions are not supported"})
+		}
+	}
+	final, err := finalTarget(cfg, skippedProxies, "DIRECT")
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintln(w, "FINAL,"+final)
+
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+	return skipped, nil
+}
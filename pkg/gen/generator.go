@@ -6,11 +6,62 @@ import (
 	"text/template"
 )
 
+// Condition matches the host against Regex or, if List is not zero, against the domain list with that id.
+// Matched hosts are routed through the pool with id Pool or, if it is zero, according to Action.
 type Condition struct {
 	Regex  string
+	List   int
+	Pool   int
 	Action string
 }
 
+// DomainList is rendered into a lookup object, so matching does not depend on the number of domains.
+type DomainList struct {
+	ID      int
+	Entries []DomainListEntry
+}
+
+type DomainListEntry struct {
+	Domain         string
+	WithSubdomains bool
+}
+
+// Pool picks a member by hash of the host, so a host always goes through the same member while it is up.
+// The rest of the members follow the picked one as a fallback.
+type Pool struct {
+	ID      int
+	Members []PoolMember
+}
+
+// PoolMember with zero weight is never picked and serves as a fallback only.
+type PoolMember struct {
+	Weight int
+	Action string
+}
+
+// Bypass is checked before the conditions, matched hosts are always reached directly. PlainHostNames matches host
+// names without dots, IPv6 addresses are not plain host names even though they have no dots either.
+// Networks match hosts given as IPv4 addresses, Regexes are tested against the host as is.
+type Bypass struct {
+	PlainHostNames bool
+	Domains        []DomainListEntry
+	Networks       []Network
+	Regexes        []string
+}
+
+// Network is an IPv4 range, Addr is the masked address and both are in host byte order.
+type Network struct {
+	Addr uint32
+	Mask uint32
+}
+
+type pac struct {
+	Bypass     Bypass
+	Lists      []DomainList
+	Pools      []Pool
+	Conditions []Condition
+}
+
 var (
 	//go:embed pac.tmpl
 	templStr string
@@ -22,7 +73,9 @@ func init() {
 	templ = template.Must(template.New("pac").Parse(templStr))
 }
 
-func Generate(wr io.Writer, data []Condition) error {
-	err := templ.Execute(wr, &data)
+// Generate writes PAC file that checks the bypass and then the conditions in order. Lists and pools referenced
+// by the conditions must be given.
+func Generate(wr io.Writer, bypass Bypass, lists []DomainList, pools []Pool, conditions []Condition) error {
+	err := templ.Execute(wr, &pac{Bypass: bypass, Lists: lists, Pools: pools, Conditions: conditions})
 	return err
 }
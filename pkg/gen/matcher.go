@@ -0,0 +1,205 @@
+package gen
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// Matcher evaluates hosts the same way as the PAC file generated from the same arguments does, so the outcome
+// can be checked on the server side. Regexes are compiled with Go syntax, which agrees with JavaScript on the
+// patterns rules are made of.
+type Matcher struct {
+	bypass        Bypass
+	bypassDomains map[string]int
+	bypassRegexes []*regexp.Regexp
+	lists         map[int]map[string]int
+	pools         map[int]Pool
+	conditions    []Condition
+	regexes       []*regexp.Regexp
+}
+
+// Match is a condition matching the host. Index is the position of the condition, Action is what PAC file
+// returns if the condition is applied.
+type Match struct {
+	Index  int
+	Action string
+}
+
+// Result of matching the host. Matches are all the conditions matching the host in order, the first one is applied
+// unless the host is bypassed. Proxy is what PAC file returns for the host.
+type Result struct {
+	Bypassed bool
+	Matches  []Match
+	Proxy    string
+}
+
+// NewMatcher compiles the bypass and the conditions. Lists and pools referenced by the conditions must be given.
+func NewMatcher(bypass Bypass, lists []DomainList, pools []Pool, conditions []Condition) (*Matcher, error) {
+	m := &Matcher{
+		bypass:        bypass,
+		bypassDomains: lookup(bypass.Domains),
+		lists:         make(map[int]map[string]int, len(lists)),
+		pools:         make(map[int]Pool, len(pools)),
+		conditions:    conditions,
+		regexes:       make([]*regexp.Regexp, len(conditions)),
+	}
+	for _, re := range bypass.Regexes {
+		compiled, err := regexp.Compile(re)
+		if err != nil {
+			return nil, err
+		}
+		m.bypassRegexes = append(m.bypassRegexes, compiled)
+	}
+	for _, list := range lists {
+		m.lists[list.ID] = lookup(list.Entries)
+	}
+	for _, pool := range pools {
+		m.pools[pool.ID] = pool
+	}
+	for i, condition := range conditions {
+		if condition.List != 0 {
+			continue
+		}
+		compiled, err := regexp.Compile(condition.Regex)
+		if err != nil {
+			return nil, err
+		}
+		m.regexes[i] = compiled
+	}
+	return m, nil
+}
+
+// Match returns the conditions matching the host given as browsers pass it to PAC file, i.e. lowercased and
+// without port.
+func (m *Matcher) Match(host string) Result {
+	result := Result{Bypassed: m.bypassed(host), Matches: make([]Match, 0)}
+	for i, condition := range m.conditions {
+		var ok bool
+		if condition.List != 0 {
+			ok = inList(m.lists[condition.List], host)
+		} else {
+			ok = m.regexes[i].MatchString(host)
+		}
+		if !ok {
+			continue
+		}
+		action := condition.Action
+		if condition.Pool != 0 {
+			action = pick(m.pools[condition.Pool], host)
+		}
+		result.Matches = append(result.Matches, Match{Index: i, Action: action})
+	}
+
+	result.Proxy = "DIRECT"
+	if !result.Bypassed && len(result.Matches) > 0 {
+		result.Proxy = result.Matches[0].Action
+	}
+	return result
+}
+
+func (m *Matcher) bypassed(host string) bool {
+	if m.bypass.PlainHostNames && !strings.Contains(host, ".") && !strings.Contains(host, ":") {
+		return true
+	}
+	if len(m.bypass.Domains) > 0 && inList(m.bypassDomains, host) {
+		return true
+	}
+	if inNets(m.bypass.Networks, host) {
+		return true
+	}
+	for _, re := range m.bypassRegexes {
+		if re.MatchString(host) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup mirrors the lookup object of PAC file: 2 marks domains that match with subdomains, later entries
+// override earlier ones.
+func lookup(entries []DomainListEntry) map[string]int {
+	list := make(map[string]int, len(entries))
+	for _, entry := range entries {
+		list[entry.Domain] = 1
+		if entry.WithSubdomains {
+			list[entry.Domain] = 2
+		}
+	}
+	return list
+}
+
+func inList(list map[string]int, host string) bool {
+	if _, ok := list[host]; ok {
+		return true
+	}
+	for i := strings.IndexByte(host, '.'); i != -1; {
+		if list[host[i+1:]] == 2 {
+			return true
+		}
+		next := strings.IndexByte(host[i+1:], '.')
+		if next == -1 {
+			break
+		}
+		i += next + 1
+	}
+	return false
+}
+
+var ipv4Re = regexp.MustCompile(`^(\d{1,3})\.(\d{1,3})\.(\d{1,3})\.(\d{1,3})$`)
+
+// inNets computes the address with 32-bit wraparound as PAC file does, octets above 255 are not rejected.
+func inNets(nets []Network, host string) bool {
+	if len(nets) == 0 {
+		return false
+	}
+	octets := ipv4Re.FindStringSubmatch(host)
+	if octets == nil {
+		return false
+	}
+	var ip uint32
+	for i, shift := range []int{24, 16, 8, 0} {
+		octet, _ := strconv.Atoi(octets[i+1])
+		ip |= uint32(octet) << shift
+	}
+	for _, n := range nets {
+		if ip&n.Mask == n.Addr {
+			return true
+		}
+	}
+	return false
+}
+
+// hash is 32-bit FNV-1a over UTF-16 code units, as charCodeAt returns them.
+func hash(s string) uint32 {
+	h := uint32(2166136261)
+	for _, c := range utf16.Encode([]rune(s)) {
+		h ^= uint32(c)
+		h *= 16777619
+	}
+	return h
+}
+
+// pick orders members of the pool by weighted rendezvous hashing of the host, ties keep the order of the members.
+func pick(pool Pool, host string) string {
+	type scored struct {
+		score  float64
+		action string
+	}
+	members := make([]scored, 0, len(pool.Members))
+	for _, member := range pool.Members {
+		h := hash(member.Action + " " + host)
+		score := float64(member.Weight) / -math.Log((float64(h)+1)/4294967297)
+		members = append(members, scored{score: score, action: member.Action})
+	}
+	sort.SliceStable(members, func(i, j int) bool { return members[i].score > members[j].score })
+
+	chain := make([]string, 0, len(members))
+	for _, member := range members {
+		chain = append(chain, member.action)
+	}
+	return strings.Join(chain, "; ")
+}
@@ -0,0 +1,131 @@
+package gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/go-playground/assert/v2"
+	"os/exec"
+	"testing"
+)
+
+var (
+	testBypass = Bypass{
+		PlainHostNames: true,
+		Domains:        []DomainListEntry{{Domain: "local", WithSubdomains: true}, {Domain: "intra.example.com"}},
+		Networks:       []Network{{Addr: 0x0A000000, Mask: 0xFF000000}, {Addr: 0xC0A80100, Mask: 0xFFFFFF00}},
+		Regexes:        []string{`^\[?(?:::1\]?$|f[cd][0-9a-f]{2}:|fe[89ab][0-9a-f]:)`},
+	}
+	testLists = []DomainList{
+		{ID: 1, Entries: []DomainListEntry{{Domain: "example.org", WithSubdomains: true}, {Domain: "exact.net"}}},
+		{ID: 2, Entries: []DomainListEntry{{Domain: "b.example.org"}, {Domain: "xn--e1afmkfd.xn--p1ai", WithSubdomains: true}}},
+	}
+	testPools = []Pool{
+		{ID: 7, Members: []PoolMember{
+			{Weight: 1, Action: "PROXY a:8080"},
+			{Weight: 3, Action: "PROXY b:8080"},
+			{Action: "PROXY c:8080"},
+		}},
+	}
+	testConditions = []Condition{
+		{Regex: `^www\.google\.com$`, Action: "SOCKS5 localhost:9050"},
+		{List: 2, Action: "HTTPS proxy.example:443"},
+		{List: 1, Pool: 7, Action: "PROXY a:8080"},
+		{Regex: `(?:^|\.)example\.org$`, Action: "DIRECT"},
+		{Regex: `(?:^|\.)intra\.example\.com$`, Action: "PROXY d:3128"},
+		{Regex: `^10\.1\.`, Action: "PROXY e:3128"},
+	}
+	testHosts = []string{
+		"www.google.com", "google.com", "example.org", "b.example.org", "a.b.example.org", "exact.net",
+		"sub.exact.net", "xn--e1afmkfd.xn--p1ai", "www.xn--e1afmkfd.xn--p1ai", "intra.example.com",
+		"www.intra.example.com", "printer.local", "localhost", "10.1.2.3", "192.168.1.20", "192.168.2.20",
+		"999.1.1.1", "::1", "[::1]", "fd12:3456::1", "2001:db8::1", "unknown.test", "", "example.org.evil.com",
+		"host-1.example.org", "host-2.example.org", "host-3.example.org", "host-4.example.org",
+	}
+)
+
+func TestMatcher_Match(t *testing.T) {
+	t.Parallel()
+
+	m, err := NewMatcher(testBypass, testLists, testPools, testConditions)
+	if err != nil {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+
+	cases := map[string]struct {
+		host     string
+		bypassed bool
+		indexes  []int
+		proxy    string
+	}{
+		"Regex":                 {host: "www.google.com", indexes: []int{0}, proxy: "SOCKS5 localhost:9050"},
+		"ListBeforeRegex":       {host: "b.example.org", indexes: []int{1, 2, 3}, proxy: "HTTPS proxy.example:443"},
+		"ListSubdomains":        {host: "a.b.example.org", indexes: []int{2, 3}, proxy: pick(testPools[0], "a.b.example.org")},
+		"ListExactOnly":         {host: "sub.exact.net", indexes: []int{}, proxy: "DIRECT"},
+		"BypassedDomain":        {host: "intra.example.com", bypassed: true, indexes: []int{4}, proxy: "DIRECT"},
+		"BypassedSubdomainOnly": {host: "www.intra.example.com", indexes: []int{4}, proxy: "PROXY d:3128"},
+		"BypassedNetwork":       {host: "10.1.2.3", bypassed: true, indexes: []int{5}, proxy: "DIRECT"},
+		"BypassedPlainHostName": {host: "localhost", bypassed: true, indexes: []int{}, proxy: "DIRECT"},
+		"BypassedIPv6":          {host: "fd12:3456::1", bypassed: true, indexes: []int{}, proxy: "DIRECT"},
+		"NoMatch":               {host: "unknown.test", indexes: []int{}, proxy: "DIRECT"},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got := m.Match(c.host)
+			indexes := make([]int, 0)
+			for _, match := range got.Matches {
+				indexes = append(indexes, match.Index)
+			}
+			assert.Equal(t, got.Bypassed, c.bypassed)
+			assert.Equal(t, indexes, c.indexes)
+			assert.Equal(t, got.Proxy, c.proxy)
+		})
+	}
+}
+
+func TestMatcher_NewMatcher_InvalidRegex(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewMatcher(Bypass{}, nil, nil, []Condition{{Regex: `(`, Action: "DIRECT"}})
+	assert.NotEqual(t, err, nil)
+}
+
+// TestMatcher_Parity evaluates the generated PAC file with node and compares its results with the matcher.
+func TestMatcher_Parity(t *testing.T) {
+	t.Parallel()
+
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node is not installed")
+	}
+
+	script := bytes.NewBufferString("function isPlainHostName(host) { return host.indexOf('.') === -1; }\n")
+	if err = Generate(script, testBypass, testLists, testPools, testConditions); err != nil {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+	hosts, _ := json.Marshal(testHosts)
+	script.WriteString("\nconsole.log(JSON.stringify(" + string(hosts) +
+		".map(function (host) { return FindProxyForURL('http://' + host + '/', host); })));\n")
+
+	cmd := exec.Command(node)
+	cmd.Stdin = script
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+	var want []string
+	if err = json.Unmarshal(out, &want); err != nil {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+
+	m, err := NewMatcher(testBypass, testLists, testPools, testConditions)
+	if err != nil {
+		t.Fatalf("Unexpected error: %#v", err)
+	}
+	for i, host := range testHosts {
+		assert.Equal(t, m.Match(host).Proxy, want[i])
+	}
+}
@@ -0,0 +1,112 @@
+// Package provision generates device configuration profiles pointing operating systems at the PAC file.
+package provision
+
+import (
+	"bytes"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"text/template"
+	"unicode/utf16"
+)
+
+var (
+	//go:embed mobileconfig.tmpl
+	mobileConfigTemplStr string
+	//go:embed windows.reg.tmpl
+	windowsRegTemplStr string
+
+	mobileConfigTempl *template.Template
+	windowsRegTempl   *template.Template
+)
+
+func init() {
+	mobileConfigTempl = template.Must(template.New("mobileconfig").Funcs(template.FuncMap{"xml": xmlEscape}).
+		Parse(mobileConfigTemplStr))
+	windowsRegTempl = template.Must(template.New("windows.reg").Funcs(template.FuncMap{"reg": regEscape}).
+		Parse(windowsRegTemplStr))
+}
+
+type mobileConfig struct {
+	PACURL           string
+	DisplayName      string
+	Identifier       string
+	UUID             string
+	ProxyPayloadUUID string
+}
+
+// MobileConfig writes an unsigned Apple configuration profile that sets up automatic proxy configuration
+// from the given PAC url. Identifiers of the profile are derived from the url, so installing a profile
+// for the same url again replaces the previous one.
+func MobileConfig(wr io.Writer, pacURL string) error {
+	u, err := url.Parse(pacURL)
+	if err != nil {
+		return fmt.Errorf("provision: invalid pac url: %w", err)
+	}
+
+	data := mobileConfig{
+		PACURL:           pacURL,
+		DisplayName:      "Proxy (" + u.Host + ")",
+		Identifier:       identifier(u),
+		UUID:             uuid(pacURL),
+		ProxyPayloadUUID: uuid(pacURL + "#proxy"),
+	}
+	return mobileConfigTempl.Execute(wr, &data)
+}
+
+// WindowsRegistry writes a registry file setting WinINET AutoConfigURL of the current user to the given PAC url.
+// The file is encoded in UTF-16LE with BOM and CRLF line endings as regedit expects.
+func WindowsRegistry(wr io.Writer, pacURL string) error {
+	buf := bytes.Buffer{}
+	if err := windowsRegTempl.Execute(&buf, pacURL); err != nil {
+		return err
+	}
+
+	content := strings.ReplaceAll(buf.String(), "\n", "\r\n")
+	encoded := utf16.Encode([]rune("\ufeff" + content))
+
+	out := make([]byte, 0, len(encoded)*2)
+	for _, c := range encoded {
+		out = append(out, byte(c), byte(c>>8))
+	}
+	_, err := wr.Write(out)
+	return err
+}
+
+// identifier returns reverse-DNS payload identifier for the host of the url.
+func identifier(u *url.URL) string {
+	host := u.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	labels := strings.Split(strings.Trim(host, "[]"), ".")
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+	return strings.Join(labels, ".") + ".pacgen"
+}
+
+// uuid returns name-based (version 5 layout) UUID of the string.
+func uuid(s string) string {
+	h := sha1.Sum([]byte(s))
+	h[6] = (h[6] & 0x0f) | 0x50
+	h[8] = (h[8] & 0x3f) | 0x80
+	return strings.ToUpper(fmt.Sprintf("%x-%x-%x-%x-%x", h[0:4], h[4:6], h[6:8], h[8:10], h[10:16]))
+}
+
+func xmlEscape(s string) (string, error) {
+	buf := bytes.Buffer{}
+	if err := xml.EscapeText(&buf, []byte(s)); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func regEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
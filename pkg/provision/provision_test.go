@@ -0,0 +1,111 @@
+package provision
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/go-playground/assert/v2"
+	"go.mozilla.org/pkcs7"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+func TestMobileConfig(t *testing.T) {
+	t.Parallel()
+
+	buf := bytes.Buffer{}
+	if err := MobileConfig(&buf, "https://pacgen.corp:8443/proxy.pac?a=1&b=2"); err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	got := buf.String()
+
+	assert.Equal(t, strings.Contains(got, "<string>https://pacgen.corp:8443/proxy.pac?a=1&amp;b=2</string>"), true)
+	assert.Equal(t, strings.Contains(got, "<string>corp.pacgen.pacgen</string>"), true)
+	assert.Equal(t, strings.Contains(got, "<string>"+uuid("https://pacgen.corp:8443/proxy.pac?a=1&b=2")+"</string>"), true)
+
+	other := bytes.Buffer{}
+	if err := MobileConfig(&other, "https://pacgen.corp:8443/proxy.pac?a=1&b=2"); err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	assert.Equal(t, other.String(), got)
+}
+
+func TestUUID(t *testing.T) {
+	t.Parallel()
+
+	got := uuid("http://localhost/proxy.pac")
+
+	assert.Equal(t, len(got), 36)
+	assert.Equal(t, got[14], byte('5'))
+	assert.NotEqual(t, got, uuid("http://localhost:8080/proxy.pac"))
+}
+
+func TestWindowsRegistry(t *testing.T) {
+	t.Parallel()
+
+	buf := bytes.Buffer{}
+	if err := WindowsRegistry(&buf, `http://pacgen.corp/proxy.pac?q="x"`); err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	raw := buf.Bytes()
+	units := make([]uint16, 0, len(raw)/2)
+	for i := 0; i+1 < len(raw); i += 2 {
+		units = append(units, uint16(raw[i])|uint16(raw[i+1])<<8)
+	}
+	got := string(utf16.Decode(units))
+
+	want := "\ufeffWindows Registry Editor Version 5.00\r\n" +
+		"\r\n" +
+		`[HKEY_CURRENT_USER\Software\Microsoft\Windows\CurrentVersion\Internet Settings]` + "\r\n" +
+		`"AutoConfigURL"="http://pacgen.corp/proxy.pac?q=\"x\""` + "\r\n"
+
+	assert.Equal(t, got, want)
+}
+
+func TestSigner_Sign(t *testing.T) {
+	t.Parallel()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "pacgen"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := newSigner(tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	profile := []byte("<plist/>")
+	signed, err := signer.Sign(profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p7, err := pkcs7.Parse(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p7.Verify(); err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	assert.Equal(t, p7.Content, profile)
+}
@@ -0,0 +1,49 @@
+package provision
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"go.mozilla.org/pkcs7"
+)
+
+// Signer signs configuration profiles with a local certificate, so devices display the profile as verified.
+type Signer struct {
+	cert  *x509.Certificate
+	chain []*x509.Certificate
+	key   any
+}
+
+// LoadSigner reads PEM encoded certificate and private key. The certificate file may also contain
+// intermediate certificates following the leaf one.
+func LoadSigner(certFile, keyFile string) (*Signer, error) {
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("provision: failed to load signing certificate: %w", err)
+	}
+	return newSigner(pair)
+}
+
+func newSigner(pair tls.Certificate) (*Signer, error) {
+	certs := make([]*x509.Certificate, 0, len(pair.Certificate))
+	for _, der := range pair.Certificate {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("provision: failed to parse signing certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	return &Signer{cert: certs[0], chain: certs[1:], key: pair.PrivateKey}, nil
+}
+
+// Sign wraps the profile into PKCS #7 signed data.
+func (s *Signer) Sign(profile []byte) ([]byte, error) {
+	signedData, err := pkcs7.NewSignedData(profile)
+	if err != nil {
+		return nil, err
+	}
+	if err := signedData.AddSignerChain(s.cert, s.key, s.chain, pkcs7.SignerInfoConfig{}); err != nil {
+		return nil, err
+	}
+	return signedData.Finish()
+}
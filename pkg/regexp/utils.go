@@ -3,12 +3,54 @@ package regexp
 import (
 	"fmt"
 	"regexp"
+	"strings"
+)
+
+const (
+	domainAndSubdomainsPrefix = `(?:^|\.)`
+	domainPrefix              = `^`
+	suffix                    = `$`
 )
 
 func DomainAndSubdomains(domain string) string {
-	return fmt.Sprintf(`(?:^|\.)%s$`, regexp.QuoteMeta(domain))
+	return fmt.Sprintf(`%s%s%s`, domainAndSubdomainsPrefix, regexp.QuoteMeta(domain), suffix)
 }
 
 func Domain(domain string) string {
-	return fmt.Sprintf(`^%s$`, regexp.QuoteMeta(domain))
+	return fmt.Sprintf(`%s%s%s`, domainPrefix, regexp.QuoteMeta(domain), suffix)
+}
+
+// ParseDomain reverses Domain and DomainAndSubdomains. It returns the original domain, whether the regex
+// also matches subdomains and ok set to false if the regex was not produced by one of these functions.
+func ParseDomain(regex string) (domain string, withSubdomains bool, ok bool) {
+	if !strings.HasSuffix(regex, suffix) {
+		return "", false, false
+	}
+	quoted := strings.TrimSuffix(regex, suffix)
+
+	switch {
+	case strings.HasPrefix(quoted, domainAndSubdomainsPrefix):
+		quoted, withSubdomains = strings.TrimPrefix(quoted, domainAndSubdomainsPrefix), true
+	case strings.HasPrefix(quoted, domainPrefix):
+		quoted = strings.TrimPrefix(quoted, domainPrefix)
+	default:
+		return "", false, false
+	}
+
+	var sb strings.Builder
+	escaped := false
+	for _, c := range quoted {
+		if c == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+		escaped = false
+		sb.WriteRune(c)
+	}
+	domain = sb.String()
+
+	if domain == "" || regexp.QuoteMeta(domain) != quoted {
+		return "", false, false
+	}
+	return domain, withSubdomains, true
 }
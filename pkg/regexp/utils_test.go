@@ -43,3 +43,30 @@ func TestDomainAndSubdomains(t *testing.T) {
 		})
 	}
 }
+
+func TestParseDomain(t *testing.T) {
+	t.Parallel()
+
+	data := []struct {
+		name, input, domain string
+		withSubdomains, ok  bool
+	}{
+		{name: "domain", input: `^google\.com$`, domain: "google.com", ok: true},
+		{name: "subdomains", input: `(?:^|\.)aws\.com$`, domain: "aws.com", withSubdomains: true, ok: true},
+		{name: "port", input: `^localhost:80$`, domain: "localhost:80", ok: true},
+		{name: "unescaped dot", input: `^google.com$`},
+		{name: "wildcard", input: `^.*\.google\.com$`},
+		{name: "no anchor", input: `google\.com`},
+		{name: "empty", input: `^$`},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.name, func(t *testing.T) {
+			domain, withSubdomains, ok := ParseDomain(d.input)
+			assert.Equal(t, domain, d.domain)
+			assert.Equal(t, withSubdomains, d.withSubdomains)
+			assert.Equal(t, ok, d.ok)
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog/hlog"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IdempotencyKeyHeader is the header the client makes its request idempotent with, the key is unique
+// for every change the client makes and is sent again when the request is retried.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader marks the responses replayed for repeats of the request.
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+// maxIdempotencyKeyLength limits the length of the stored keys, UUIDs and the like fit easily.
+const maxIdempotencyKeyLength = 255
+
+// IdempotentResponse is the response stored for the idempotency key. Fingerprint identifies the request
+// the response was given to.
+type IdempotentResponse struct {
+	Fingerprint string
+	StatusCode  int
+	Header      http.Header
+	Body        []byte
+}
+
+// IdempotencyStore keeps the responses of the requests with idempotency keys.
+type IdempotencyStore interface {
+	// Get returns the response stored for the key, ok is false if there is none or it has expired.
+	Get(ctx context.Context, key string) (resp IdempotentResponse, ok bool, err error)
+	// Save stores the response for the key until the expiration time.
+	Save(ctx context.Context, key string, resp IdempotentResponse, expiresAt time.Time) error
+}
+
+// Idempotency makes POST, PUT, PATCH and DELETE requests with Idempotency-Key header idempotent: the response
+// is stored for the ttl and replayed for the repeats of the request, so the retries of the client do not apply
+// the change twice. The key reused for another request is rejected with 422, the repeat coming while the request
+// is still being processed with 409. Server errors are not stored, so the request can be retried after them.
+// Keys are scoped to the principal sending the request, so the response to one principal is never replayed
+// to another one that happens to send the same key. Responses with Cache-Control: no-store, e.g. the ones holding
+// secrets, are not stored either, their repeats are processed again.
+func Idempotency(
+	store IdempotencyStore, ttl time.Duration, principal func(r *http.Request) string,
+) func(next http.Handler) http.Handler {
+	var (
+		mu         sync.Mutex
+		processing = make(map[string]struct{})
+	)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" || !isIdempotentMethod(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			logger := hlog.FromRequest(r)
+
+			if len(key) > maxIdempotencyKeyLength {
+				detail := fmt.Sprintf("Idempotency-Key must be at most %d characters long", maxIdempotencyKeyLength)
+				writeProblem(w, r, BadRequestResponse(detail))
+				return
+			}
+
+			key = strconv.Quote(principal(r)) + " " + key
+
+			fingerprint, err := requestFingerprint(r)
+			if err != nil {
+				logger.Error().Err(err).Msg("Error occurred while reading request body")
+				writeProblem(w, r, InternalErrorResponse())
+				return
+			}
+
+			mu.Lock()
+			if _, ok := processing[key]; ok {
+				mu.Unlock()
+				writeProblem(w, r, ConflictResponse("Request with the same Idempotency-Key is being processed"))
+				return
+			}
+			processing[key] = struct{}{}
+			mu.Unlock()
+			defer func() {
+				mu.Lock()
+				delete(processing, key)
+				mu.Unlock()
+			}()
+
+			stored, ok, err := store.Get(r.Context(), key)
+			if err != nil {
+				logger.Error().Err(err).Msg("Error occurred while getting idempotent response")
+				writeProblem(w, r, InternalErrorResponse())
+				return
+			}
+			if ok {
+				if stored.Fingerprint != fingerprint {
+					logger.Debug().Str("idempotency-key", key).Msg("Idempotency key reused for another request")
+					detail := "Idempotency-Key has already been used for another request"
+					writeProblem(w, r, UnprocessableEntityResponse(detail))
+					return
+				}
+				logger.Debug().Str("idempotency-key", key).Msg("Replaying idempotent response")
+				replay(w, stored)
+				return
+			}
+
+			body := &bytes.Buffer{}
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			ww.Tee(body)
+
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if status >= http.StatusInternalServerError || isNoStore(w.Header()) {
+				return
+			}
+
+			// The request id belongs to the request, the repeats get their own.
+			header := w.Header().Clone()
+			header.Del("X-Request-Id")
+			resp := IdempotentResponse{Fingerprint: fingerprint, StatusCode: status, Header: header, Body: body.Bytes()}
+
+			// The response is stored even if the client has gone, the retry of the client is the point.
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := store.Save(ctx, key, resp, time.Now().Add(ttl)); err != nil {
+				logger.Error().Err(err).Msg("Error occurred while saving idempotent response")
+			}
+		})
+	}
+}
+
+func isIdempotentMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// isNoStore reports whether the response must not be stored.
+func isNoStore(header http.Header) bool {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		if strings.EqualFold(strings.TrimSpace(directive), "no-store") {
+			return true
+		}
+	}
+	return false
+}
+
+// requestFingerprint hashes the method, the URI and the body of the request, the body is left readable.
+func requestFingerprint(r *http.Request) (string, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "", err
+	}
+	if err := r.Body.Close(); err != nil {
+		return "", err
+	}
+	r.Body = io.NopCloser(bytes.NewBuffer(body))
+
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// replay writes the stored response.
+func replay(w http.ResponseWriter, resp IdempotentResponse) {
+	for name, values := range resp.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(resp.StatusCode)
+	_, _ = w.Write(resp.Body)
+}
+
+// writeProblem renders the problem, falling back to the bare status code if it cannot be rendered.
+func writeProblem(w http.ResponseWriter, r *http.Request, v Problem) {
+	if err := RenderProblem(w, r, v); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
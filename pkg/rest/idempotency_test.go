@@ -0,0 +1,260 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"github.com/go-playground/assert/v2"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryIdempotencyStore keeps the responses in memory, expiration is ignored.
+type memoryIdempotencyStore struct {
+	mu        sync.Mutex
+	responses map[string]IdempotentResponse
+	err       error
+}
+
+func newMemoryIdempotencyStore() *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{responses: make(map[string]IdempotentResponse)}
+}
+
+func (s *memoryIdempotencyStore) Get(_ context.Context, key string) (IdempotentResponse, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resp, ok := s.responses[key]
+	return resp, ok, s.err
+}
+
+func (s *memoryIdempotencyStore) Save(_ context.Context, key string, resp IdempotentResponse, _ time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses[key] = resp
+	return s.err
+}
+
+// testCountingHandler creates an entity on every call and responds with the given status code.
+func testCountingHandler(status int, calls *int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		w.Header().Set("Location", "/rules/"+strings.Repeat("1", *calls))
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"created":true}`))
+	})
+}
+
+// testIdempotency stores the responses for an hour, the principal is the user of basic auth.
+func testIdempotency(store IdempotencyStore) func(next http.Handler) http.Handler {
+	return Idempotency(store, time.Hour, func(r *http.Request) string {
+		user, _, _ := r.BasicAuth()
+		return user
+	})
+}
+
+func testIdempotentRequest(t *testing.T, method, key, body string) *http.Request {
+	req, err := http.NewRequest(method, "/rules", strings.NewReader(body))
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	return req
+}
+
+func TestIdempotency_Replay(t *testing.T) {
+	t.Parallel()
+
+	var calls int
+	handler := testIdempotency(newMemoryIdempotencyStore())(testCountingHandler(http.StatusCreated, &calls))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, testIdempotentRequest(t, http.MethodPost, "key-1", `{"domain":"google.com"}`))
+
+	assert.Equal(t, rr.Code, http.StatusCreated)
+	assert.Equal(t, rr.Header().Get(IdempotentReplayedHeader), "")
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, testIdempotentRequest(t, http.MethodPost, "key-1", `{"domain":"google.com"}`))
+
+	assert.Equal(t, calls, 1)
+	assert.Equal(t, rr.Code, http.StatusCreated)
+	assert.Equal(t, rr.Header().Get("Location"), "/rules/1")
+	assert.Equal(t, rr.Header().Get(IdempotentReplayedHeader), "true")
+	assert.Equal(t, rr.Body.String(), `{"created":true}`)
+}
+
+func TestIdempotency_AnotherPrincipal(t *testing.T) {
+	t.Parallel()
+
+	var calls int
+	handler := testIdempotency(newMemoryIdempotencyStore())(testCountingHandler(http.StatusCreated, &calls))
+
+	req := testIdempotentRequest(t, http.MethodPost, "key-1", `{"domain":"google.com"}`)
+	req.SetBasicAuth("alice", "secret")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Neither the same request is replayed nor another one is rejected for another principal.
+	for user, body := range map[string]string{"bob": `{"domain":"google.com"}`, "carol": `{"domain":"yahoo.com"}`} {
+		req := testIdempotentRequest(t, http.MethodPost, "key-1", body)
+		req.SetBasicAuth(user, "secret")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, rr.Code, http.StatusCreated)
+		assert.Equal(t, rr.Header().Get(IdempotentReplayedHeader), "")
+	}
+
+	assert.Equal(t, calls, 3)
+}
+
+func TestIdempotency_KeyReused(t *testing.T) {
+	t.Parallel()
+
+	var calls int
+	handler := testIdempotency(newMemoryIdempotencyStore())(testCountingHandler(http.StatusCreated, &calls))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, testIdempotentRequest(t, http.MethodPost, "key-1", `{"domain":"google.com"}`))
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, testIdempotentRequest(t, http.MethodPost, "key-1", `{"domain":"yahoo.com"}`))
+
+	assert.Equal(t, calls, 1)
+	assert.Equal(t, rr.Code, http.StatusUnprocessableEntity)
+	assert.Equal(t, strings.TrimSuffix(rr.Body.String(), "\n"),
+		`{"type":"about:blank","title":"Unprocessable Entity","status":422,`+
+			`"detail":"Idempotency-Key has already been used for another request","instance":"/rules"}`)
+}
+
+func TestIdempotency_Passthrough(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		method string
+		key    string
+	}{
+		"no key":         {method: http.MethodPost, key: ""},
+		"safe method":    {method: http.MethodGet, key: "key-1"},
+		"another method": {method: http.MethodOptions, key: "key-1"},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var calls int
+			handler := testIdempotency(newMemoryIdempotencyStore())(testCountingHandler(http.StatusOK, &calls))
+
+			for i := 0; i < 2; i++ {
+				rr := httptest.NewRecorder()
+				handler.ServeHTTP(rr, testIdempotentRequest(t, c.method, c.key, ""))
+				assert.Equal(t, rr.Code, http.StatusOK)
+			}
+
+			assert.Equal(t, calls, 2)
+		})
+	}
+}
+
+func TestIdempotency_ServerErrorNotStored(t *testing.T) {
+	t.Parallel()
+
+	var calls int
+	failing := testCountingHandler(http.StatusInternalServerError, &calls)
+	handler := testIdempotency(newMemoryIdempotencyStore())(failing)
+
+	for i := 0; i < 2; i++ {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, testIdempotentRequest(t, http.MethodDelete, "key-1", ""))
+		assert.Equal(t, rr.Code, http.StatusInternalServerError)
+	}
+
+	assert.Equal(t, calls, 2)
+}
+
+func TestIdempotency_NoStoreNotStored(t *testing.T) {
+	t.Parallel()
+
+	var calls int
+	store := newMemoryIdempotencyStore()
+	secret := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Cache-Control", "private, no-store")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"token":"secret"}`))
+	})
+	handler := testIdempotency(store)(secret)
+
+	for i := 0; i < 2; i++ {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, testIdempotentRequest(t, http.MethodPost, "key-1", "{}"))
+		assert.Equal(t, rr.Code, http.StatusCreated)
+		assert.Equal(t, rr.Header().Get(IdempotentReplayedHeader), "")
+	}
+
+	assert.Equal(t, calls, 2)
+	assert.Equal(t, len(store.responses), 0)
+}
+
+func TestIdempotency_KeyTooLong(t *testing.T) {
+	t.Parallel()
+
+	var calls int
+	handler := testIdempotency(newMemoryIdempotencyStore())(testCountingHandler(http.StatusCreated, &calls))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, testIdempotentRequest(t, http.MethodPost, strings.Repeat("k", 256), "{}"))
+
+	assert.Equal(t, calls, 0)
+	assert.Equal(t, rr.Code, http.StatusBadRequest)
+}
+
+func TestIdempotency_StoreError(t *testing.T) {
+	t.Parallel()
+
+	store := newMemoryIdempotencyStore()
+	store.err = errors.New("database is locked")
+
+	var calls int
+	handler := testIdempotency(store)(testCountingHandler(http.StatusCreated, &calls))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, testIdempotentRequest(t, http.MethodPost, "key-1", "{}"))
+
+	assert.Equal(t, calls, 0)
+	assert.Equal(t, rr.Code, http.StatusInternalServerError)
+}
+
+func TestIdempotency_Processing(t *testing.T) {
+	t.Parallel()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusNoContent)
+	})
+	handler := testIdempotency(newMemoryIdempotencyStore())(slow)
+
+	done := make(chan int)
+	go func() {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, testIdempotentRequest(t, http.MethodDelete, "key-1", ""))
+		done <- rr.Code
+	}()
+	<-started
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, testIdempotentRequest(t, http.MethodDelete, "key-1", ""))
+	close(release)
+
+	assert.Equal(t, rr.Code, http.StatusConflict)
+	assert.Equal(t, <-done, http.StatusNoContent)
+}
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"encoding/json"
 	"github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/render"
 	"github.com/mattn/go-isatty"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/hlog"
@@ -61,7 +60,7 @@ func ValidateJSONBody(next http.Handler) http.Handler {
 		}
 
 		if !json.Valid(body) {
-			if err := render.Render(w, r, BadRequestResponse("Invalid JSON")); err != nil {
+			if err := RenderProblem(w, r, BadRequestResponse("Invalid JSON")); err != nil {
 				w.WriteHeader(http.StatusInternalServerError)
 				middleware.GetLogEntry(r).Panic(err, debug.Stack())
 			}
@@ -109,7 +108,9 @@ func Recoverer(next http.Handler) http.Handler {
 					logger.Error().Stack().Err(errors.New(rvr.(string))).Send()
 				}
 
-				w.WriteHeader(http.StatusInternalServerError)
+				if err := RenderProblem(w, r, InternalErrorResponse()); err != nil {
+					w.WriteHeader(http.StatusInternalServerError)
+				}
 			}
 		}()
 
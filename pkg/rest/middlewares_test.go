@@ -73,4 +73,7 @@ func TestValidateJSONBody_Invalid(t *testing.T) {
 
 	validator.ServeHTTP(rr, req)
 	assert.Equal(t, rr.Code, http.StatusBadRequest)
+	assert.Equal(t, rr.Header().Get("Content-Type"), ContentTypeProblem)
+	assert.Equal(t, strings.TrimSuffix(rr.Body.String(), "\n"),
+		`{"type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid JSON","instance":"/rules"}`)
 }
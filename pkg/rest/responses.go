@@ -1,49 +1,146 @@
 package rest
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"github.com/go-chi/render"
+	"github.com/rs/zerolog/hlog"
 	"net/http"
 	url2 "net/url"
 )
 
 // Created returns an HTTP 201 Created response with Location header set to the corresponding url.
 func Created(w http.ResponseWriter, r *http.Request, id any) {
+	w.Header().Set("Location", location(r, id))
+	w.WriteHeader(http.StatusCreated)
+}
+
+// CreatedJSON is Created with the value as JSON body, e.g. for the secrets shown only once they are created.
+func CreatedJSON(w http.ResponseWriter, r *http.Request, id any, v any) {
+	w.Header().Set("Location", location(r, id))
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, v)
+}
+
+// location returns the url of the entity with the id created by the request.
+func location(r *http.Request, id any) string {
 	url := url2.URL{
 		Scheme: GetScheme(r),
 		Host:   GetHost(r),
 		Path:   fmt.Sprintf("%s/%v", GetPath(r), id),
 	}
-	w.Header().Set("Location", url.String())
-	w.WriteHeader(http.StatusCreated)
+	return url.String()
 }
 
+// ContentTypeProblem is the media type of problem details (RFC 7807).
+const ContentTypeProblem = "application/problem+json"
+
+// ErrorResponse is a problem details object (RFC 7807). Type is a URI identifying the kind of the problem,
+// about:blank if the status code says it all. RequestID is the id of the request the problem occurred in,
+// Errors list the invalid fields of the request body.
 type ErrorResponse struct {
-	StatusCode int    `json:"-"`
-	ErrorText  string `json:"error,omitempty"`
+	Type       string       `json:"type"`
+	Title      string       `json:"title"`
+	StatusCode int          `json:"status"`
+	Detail     string       `json:"detail,omitempty"`
+	Instance   string       `json:"instance,omitempty"`
+	RequestID  string       `json:"request_id,omitempty"`
+	Errors     []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError is an invalid field of the request body. Field is its path, e.g. entries[2].domain,
+// Code is the constraint the value does not satisfy, e.g. required or max.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
 }
 
 func (e *ErrorResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	if e.Type == "" {
+		e.Type = "about:blank"
+	}
+	if e.Title == "" {
+		e.Title = http.StatusText(e.StatusCode)
+	}
+	e.Instance = r.URL.Path
+	if id, ok := hlog.IDFromRequest(r); ok {
+		e.RequestID = id.String()
+	}
 	render.Status(r, e.StatusCode)
 	return nil
 }
 
-//func InternalErrorResponse(errorText string) *ErrorResponse {
-//	return &ErrorResponse{StatusCode: http.StatusInternalServerError, ErrorText: errorText}
-//}
+// Problem returns the problem details, types embedding ErrorResponse satisfy Problem with it.
+func (e *ErrorResponse) Problem() *ErrorResponse {
+	return e
+}
 
-func BadRequestResponse(errorText string) *ErrorResponse {
-	return &ErrorResponse{StatusCode: http.StatusBadRequest, ErrorText: errorText}
+// WithType sets the type of the problem.
+func (e *ErrorResponse) WithType(uri string) *ErrorResponse {
+	e.Type = uri
+	return e
 }
 
-func NotFoundResponse(errorText string) *ErrorResponse {
-	return &ErrorResponse{StatusCode: http.StatusNotFound, ErrorText: errorText}
+// Problem is a response rendered as problem details.
+type Problem interface {
+	render.Renderer
+	Problem() *ErrorResponse
 }
 
-func ConflictResponse(errorText string) *ErrorResponse {
-	return &ErrorResponse{StatusCode: http.StatusConflict, ErrorText: errorText}
+// RenderProblem renders the problem details with application/problem+json content type. The value is either
+// ErrorResponse or a type embedding it to add members specific to the type of the problem.
+func RenderProblem(w http.ResponseWriter, r *http.Request, v Problem) error {
+	if err := v.Render(w, r); err != nil {
+		return err
+	}
+
+	buf := &bytes.Buffer{}
+	if err := json.NewEncoder(buf).Encode(v); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", ContentTypeProblem)
+	if status, ok := r.Context().Value(render.StatusCtxKey).(int); ok {
+		w.WriteHeader(status)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
 }
 
-//func UnprocessableEntityResponse(errorText string) *ErrorResponse {
-//	return &ErrorResponse{StatusCode: http.StatusUnprocessableEntity, ErrorText: errorText}
-//}
+func InternalErrorResponse() *ErrorResponse {
+	return &ErrorResponse{StatusCode: http.StatusInternalServerError, Detail: "see the logs for the request id"}
+}
+
+func BadRequestResponse(detail string) *ErrorResponse {
+	return &ErrorResponse{StatusCode: http.StatusBadRequest, Detail: detail}
+}
+
+func UnauthorizedResponse(detail string) *ErrorResponse {
+	return &ErrorResponse{StatusCode: http.StatusUnauthorized, Detail: detail}
+}
+
+func ForbiddenResponse(detail string) *ErrorResponse {
+	return &ErrorResponse{StatusCode: http.StatusForbidden, Detail: detail}
+}
+
+func NotFoundResponse(detail string) *ErrorResponse {
+	return &ErrorResponse{StatusCode: http.StatusNotFound, Detail: detail}
+}
+
+func ConflictResponse(detail string) *ErrorResponse {
+	return &ErrorResponse{StatusCode: http.StatusConflict, Detail: detail}
+}
+
+func PreconditionFailedResponse(detail string) *ErrorResponse {
+	return &ErrorResponse{StatusCode: http.StatusPreconditionFailed, Detail: detail}
+}
+
+func PreconditionRequiredResponse(detail string) *ErrorResponse {
+	return &ErrorResponse{StatusCode: http.StatusPreconditionRequired, Detail: detail}
+}
+
+func UnprocessableEntityResponse(detail string, errors ...FieldError) *ErrorResponse {
+	return &ErrorResponse{StatusCode: http.StatusUnprocessableEntity, Detail: detail, Errors: errors}
+}
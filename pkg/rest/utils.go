@@ -1,6 +1,10 @@
 package rest
 
-import "net/http"
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
 
 func GetScheme(r *http.Request) string {
 	scheme := "http"
@@ -28,3 +32,15 @@ func GetPath(r *http.Request) string {
 	}
 	return path
 }
+
+// GetExternalURL returns the url of the given path as it is seen by the client, i.e. taking into account
+// the prefix the application is served under.
+func GetExternalURL(r *http.Request, path string) string {
+	prefix := strings.TrimSuffix(GetPath(r), r.URL.Path)
+	u := url.URL{
+		Scheme: GetScheme(r),
+		Host:   GetHost(r),
+		Path:   prefix + path,
+	}
+	return u.String()
+}
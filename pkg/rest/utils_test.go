@@ -0,0 +1,24 @@
+package rest
+
+import (
+	"github.com/go-playground/assert/v2"
+	"net/http"
+	"testing"
+)
+
+func TestGetExternalURL(t *testing.T) {
+	t.Parallel()
+
+	req, err := http.NewRequest(http.MethodGet, "http://localhost:8080/export/chrome-policy.json", nil)
+	if err != nil {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+
+	assert.Equal(t, GetExternalURL(req, "/proxy.pac"), "http://localhost:8080/proxy.pac")
+
+	req.Header.Set("X-Forwarded-Proto", "https")
+	req.Header.Set("X-Forwarded-Host", "pacgen.corp")
+	req.Header.Set("X-Forwarded-Prefix", "/pacgen")
+
+	assert.Equal(t, GetExternalURL(req, "/proxy.pac"), "https://pacgen.corp/pacgen/proxy.pac")
+}
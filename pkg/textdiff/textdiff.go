@@ -0,0 +1,177 @@
+// Package textdiff compares texts line by line and formats the differences as unified diffs.
+package textdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Context is the number of unchanged lines around the changes in the hunks.
+const Context = 3
+
+// maxCells limits the size of the table of the longest common subsequence. Larger changes are shown as the removal
+// of the old lines followed by the addition of the new ones.
+const maxCells = 1 << 22
+
+// op is the line of the edit script: unchanged (' '), removed ('-') or added ('+').
+type op struct {
+	kind byte
+	line string
+}
+
+// Unified returns the unified diff of the texts labeled with the names, empty if the texts are equal.
+func Unified(fromName, toName, from, to string) string {
+	if from == to {
+		return ""
+	}
+	ops := diff(lines(from), lines(to))
+
+	// fromLine[k] and toLine[k] are the numbers of the lines preceding ops[k] in the texts.
+	fromLine, toLine := make([]int, len(ops)+1), make([]int, len(ops)+1)
+	for k, o := range ops {
+		fromLine[k+1], toLine[k+1] = fromLine[k], toLine[k]
+		if o.kind != '+' {
+			fromLine[k+1]++
+		}
+		if o.kind != '-' {
+			toLine[k+1]++
+		}
+	}
+
+	sb := &strings.Builder{}
+	fmt.Fprintf(sb, "--- %s\n+++ %s\n", fromName, toName)
+	for k := 0; k < len(ops); {
+		if ops[k].kind == ' ' {
+			k++
+			continue
+		}
+
+		// Changes closer than twice the context to each other share the hunk.
+		end := k
+		for {
+			for end < len(ops) && ops[end].kind != ' ' {
+				end++
+			}
+			next := end
+			for next < len(ops) && ops[next].kind == ' ' {
+				next++
+			}
+			if next == len(ops) || next-end > 2*Context {
+				break
+			}
+			end = next
+		}
+
+		start, stop := k-Context, end+Context
+		if start < 0 {
+			start = 0
+		}
+		if stop > len(ops) {
+			stop = len(ops)
+		}
+		fmt.Fprintf(sb, "@@ -%s +%s @@\n",
+			hunkRange(fromLine[start], fromLine[stop]), hunkRange(toLine[start], toLine[stop]))
+		for _, o := range ops[start:stop] {
+			sb.WriteByte(o.kind)
+			sb.WriteString(o.line)
+			sb.WriteByte('\n')
+		}
+		k = stop
+	}
+	return sb.String()
+}
+
+// hunkRange formats the range of the lines following the line before and up to the line after. Empty range
+// starts at the line before it.
+func hunkRange(before, after int) string {
+	if before == after {
+		return fmt.Sprintf("%d,0", before)
+	}
+	return fmt.Sprintf("%d,%d", before+1, after-before)
+}
+
+func lines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diff returns the shortest edit script turning a into b.
+func diff(a, b []string) []op {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]op, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		ops = append(ops, op{' ', line})
+	}
+	ops = append(ops, middle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, op{' ', line})
+	}
+	return ops
+}
+
+// middle returns the edit script of the lines between the common prefix and suffix, built from their longest
+// common subsequence. Removals go before additions where both are possible.
+func middle(a, b []string) []op {
+	n, m := len(a), len(b)
+	ops := make([]op, 0, n+m)
+	if n*m > maxCells {
+		for _, line := range a {
+			ops = append(ops, op{'-', line})
+		}
+		for _, line := range b {
+			ops = append(ops, op{'+', line})
+		}
+		return ops
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, op{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, op{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, op{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, op{'-', a[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, op{'+', b[j]})
+	}
+	return ops
+}
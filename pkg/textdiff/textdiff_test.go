@@ -0,0 +1,53 @@
+package textdiff
+
+import (
+	"github.com/go-playground/assert/v2"
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	t.Parallel()
+
+	data := []struct{ name, from, to, want string }{
+		{name: "equal", from: "a\nb\n", to: "a\nb\n", want: ""},
+		{
+			name: "changed line",
+			from: "a\nb\nc\nd\ne\nf\n",
+			to:   "a\nb\nc\nD\ne\nf\n",
+			want: "--- from\n+++ to\n@@ -1,6 +1,6 @@\n a\n b\n c\n-d\n+D\n e\n f\n",
+		},
+		{
+			name: "added to empty",
+			from: "",
+			to:   "a\nb\n",
+			want: "--- from\n+++ to\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name: "removed in the middle",
+			from: "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			to:   "1\n2\n3\n4\n6\n7\n8\n9\n",
+			want: "--- from\n+++ to\n@@ -2,7 +2,6 @@\n 2\n 3\n 4\n-5\n 6\n 7\n 8\n",
+		},
+		{
+			name: "separate hunks",
+			from: "a\n1\n2\n3\n4\n5\n6\n7\nb\n",
+			to:   "A\n1\n2\n3\n4\n5\n6\n7\nB\n",
+			want: "--- from\n+++ to\n@@ -1,4 +1,4 @@\n-a\n+A\n 1\n 2\n 3\n@@ -6,4 +6,4 @@\n 5\n 6\n 7\n-b\n+B\n",
+		},
+		{
+			name: "joined hunks",
+			from: "a\n1\n2\n3\n4\n5\n6\nb\n",
+			to:   "A\n1\n2\n3\n4\n5\n6\nB\n",
+			want: "--- from\n+++ to\n@@ -1,8 +1,8 @@\n-a\n+A\n 1\n 2\n 3\n 4\n 5\n 6\n-b\n+B\n",
+		},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, Unified("from", "to", d.from, d.to), d.want)
+		})
+	}
+}